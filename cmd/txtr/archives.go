@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/richardwooding/txtr/internal/archive"
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// scanArchive scans each member of a ZIP, tar or gzip-compressed tar archive
+// (--archives) into s, named after the archive and the path of the member,
+// e.g. samples.zip!bin/a.exe. Offsets are relative to the member, and the
+// members of nested archives follow them (samples.zip!inner.tgz!a.exe).
+// Encrypted ZIP members are decrypted with the first of config.Passwords that
+// fits; members that cannot be read or decrypted, and those
+// config.ArchiveLimits skips or cuts short, are reported as warnings, with
+// their decryption status in JSON. With config.ChunkWorkers, the members are
+// scanned in parallel (see scanMembersParallel).
+func scanArchive(filename string, config extractor.Config, s sink) {
+	if !config.Archives {
+		return
+	}
+
+	// Errors opening the file were reported by its own scan
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer closeInput(file, filename, config)
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() || !archive.IsArchive(file) {
+		return
+	}
+
+	options := archive.Options{Passwords: config.Passwords, Limits: config.ArchiveLimits}
+	if config.ChunkWorkers < 2 {
+		err = archive.Walk(file, info.Size(), options, func(member archive.Member) error {
+			scanMember(filename, member, config, s)
+			return nil
+		})
+	} else {
+		err = scanMembersParallel(file, info.Size(), filename, options, config, s)
+	}
+	if err != nil {
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnArchive, File: filename,
+			Message: "cannot read archive", Err: err})
+	}
+}
+
+// scanMember scans a member of the archive filename into s and closes it
+func scanMember(filename string, member archive.Member, config extractor.Config, s sink) {
+	defer func() { _ = member.Close() }()
+	name := filename + "!" + member.Path
+	s.BeginFile(fileInfo{Name: name, Decryption: member.Decryption})
+	switch {
+	case errors.Is(member.Err, archive.ErrLimit):
+		warnArchiveLimit(name, member.Err, config)
+	case member.Err != nil:
+		code := extractor.WarnArchive
+		if member.Decryption != "" {
+			code = extractor.WarnEncrypted
+		}
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: code, File: name,
+			Message: "cannot read archive member", Err: member.Err})
+	default:
+		extractStrings(member.Reader, name, config, s.PrintString)
+		if err := member.Limit(); err != nil {
+			warnArchiveLimit(name, err, config)
+		}
+	}
+	s.EndFile(name, nil)
+}
+
+// memberScan is a member being scanned by scanMembersParallel
+type memberScan struct {
+	recording *recordingSink
+	metrics   *extractor.Metrics // Metrics of the member's scan, merged once it is replayed
+	done      chan struct{}
+}
+
+// scanMembersParallel scans the members of the archive in r with up to
+// config.ChunkWorkers workers, so that a single archive of many members does
+// not bottleneck on one. Workers record their members, which are replayed into
+// s in archive order. With a memory budget, the members scanned at once are
+// bounded by their total size as well.
+func scanMembersParallel(r io.ReaderAt, size int64, filename string, options archive.Options, config extractor.Config, s sink) error {
+	workers := config.ChunkWorkers
+	gate := newMemoryGate(config.MaxMemory)
+	memberConfig := workerConfig(config, workers)
+
+	// Replay each member as soon as it and all earlier members are done
+	pending := make(chan memberScan, workers)
+	replayed := make(chan struct{})
+	go func() {
+		defer close(replayed)
+		for scan := range pending {
+			<-scan.done
+			scan.recording.replay(s)
+			if scan.metrics != nil {
+				config.Metrics.BytesRead += scan.metrics.BytesRead
+				config.Metrics.ReadTime += scan.metrics.ReadTime
+				config.Metrics.FilterTime += scan.metrics.FilterTime
+				config.Metrics.OutputTime += scan.metrics.OutputTime
+			}
+		}
+	}()
+
+	slots := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	err := archive.Walk(r, size, options, func(member archive.Member) error {
+		scan := memberScan{recording: &recordingSink{}, done: make(chan struct{})}
+		jobConfig := memberConfig
+		jobConfig.Warnings = scan.recording.Warn
+		if config.Rejected != nil {
+			jobConfig.Rejected = scan.recording.RejectString
+		}
+		if config.Metrics != nil {
+			scan.metrics = &extractor.Metrics{}
+			jobConfig.Metrics = scan.metrics
+		}
+		pending <- scan
+
+		slots <- struct{}{}
+		weight := gate.acquire(member.Size)
+		wg.Go(func() {
+			defer func() {
+				gate.release(weight)
+				<-slots
+				close(scan.done)
+			}()
+			scanMember(filename, member, jobConfig, scan.recording)
+		})
+		return nil
+	})
+	wg.Wait()
+	close(pending)
+	<-replayed
+	return err
+}
+
+// memoryGate bounds the total size of the members scanned at once by the
+// memory budget
+type memoryGate struct {
+	mu     sync.Mutex
+	freed  *sync.Cond
+	budget int64 // 0 = unlimited
+	used   int64
+}
+
+// newMemoryGate returns a gate for a memory budget of budget bytes (0 =
+// unlimited)
+func newMemoryGate(budget int64) *memoryGate {
+	g := &memoryGate{budget: budget}
+	g.freed = sync.NewCond(&g.mu)
+	return g
+}
+
+// acquire waits until a member of size bytes fits in the budget, returning the
+// weight to release once it was scanned. A member larger than the budget
+// waits for all others (it is streamed, so it fits on its own).
+func (g *memoryGate) acquire(size int64) int64 {
+	if g.budget <= 0 {
+		return 0
+	}
+	weight := min(max(size, 0), g.budget)
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for g.used > 0 && g.used+weight > g.budget {
+		g.freed.Wait()
+	}
+	g.used += weight
+	return weight
+}
+
+// release returns weight acquired by acquire to the budget
+func (g *memoryGate) release(weight int64) {
+	if weight == 0 {
+		return
+	}
+	g.mu.Lock()
+	g.used -= weight
+	g.mu.Unlock()
+	g.freed.Broadcast()
+}
+
+// warnArchiveLimit reports an archive member skipped or cut short, or a nested
+// archive not expanded, by the archive limits
+func warnArchiveLimit(name string, err error, config extractor.Config) {
+	extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnArchiveLimit, File: name,
+		Message: err.Error()})
+}
+
+// readPasswords returns the passwords of a password file, one per line
+func readPasswords(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var passwords []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if password := strings.TrimSuffix(scanner.Text(), "\r"); password != "" {
+			passwords = append(passwords, password)
+		}
+	}
+	return passwords, scanner.Err()
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/evtx"
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/memimage"
+	"github.com/richardwooding/txtr/internal/ntfs"
+	"github.com/richardwooding/txtr/internal/partition"
+	"github.com/richardwooding/txtr/internal/prefetch"
+	"github.com/richardwooding/txtr/internal/printer"
+	"github.com/richardwooding/txtr/internal/registry"
+)
+
+// artifactText is text found by parsing a forensic artifact, with what the
+// artifact says about it
+type artifactText struct {
+	cell  printer.ArtifactCell // Offset, Size and attribution
+	data  []byte
+	utf16 bool
+}
+
+// artifactParser parses a kind of forensic artifact (see artifactParsers)
+type artifactParser struct {
+	format  string // Reported as the file's format
+	enabled func(config extractor.Config) bool
+	detect  func(header []byte) bool // Given the first 8 bytes of the file
+	walk    func(data []byte, add func(artifactText)) error
+}
+
+// artifactParsers are the artifacts scanned by their structure
+// (--registry, --evtx, --prefetch)
+var artifactParsers = []artifactParser{
+	{
+		format:  "registry",
+		enabled: func(config extractor.Config) bool { return config.Registry },
+		detect:  registry.IsHive,
+		walk: func(data []byte, add func(artifactText)) error {
+			return registry.Walk(data, func(text registry.Text) error {
+				add(artifactText{
+					cell: printer.ArtifactCell{Offset: text.Offset, Size: int64(len(text.Data)), RegistryKey: text.Key, RegistryValue: text.Value},
+					data: text.Data, utf16: text.UTF16,
+				})
+				return nil
+			})
+		},
+	},
+	{
+		format:  "evtx",
+		enabled: func(config extractor.Config) bool { return config.Evtx },
+		detect:  evtx.IsEventLog,
+		walk: func(data []byte, add func(artifactText)) error {
+			return evtx.Walk(data, func(text evtx.Text) error {
+				cell := printer.ArtifactCell{Offset: text.Offset, Size: int64(len(text.Data)), EventRecordID: text.RecordID}
+				if !text.Time.IsZero() {
+					cell.EventTime = text.Time.Format(time.RFC3339Nano)
+				}
+				add(artifactText{cell: cell, data: text.Data, utf16: text.UTF16})
+				return nil
+			})
+		},
+	},
+	{
+		format:  "prefetch",
+		enabled: func(config extractor.Config) bool { return config.Prefetch },
+		detect:  prefetch.IsPrefetch,
+		walk: func(data []byte, add func(artifactText)) error {
+			return prefetch.Walk(data, func(text prefetch.Text) error {
+				add(artifactText{
+					cell: printer.ArtifactCell{Offset: text.Offset, Size: int64(len(text.Data)), PrefetchField: text.Field},
+					data: text.Data, utf16: true,
+				})
+				return nil
+			})
+		},
+	},
+}
+
+// scanArtifact scans a registry hive (--registry), event log (--evtx) or
+// prefetch file (--prefetch) into s by its structure: the key and value names
+// and string data of a hive, the string values of each event record, the
+// paths listed by a prefetch file. Each string is attributed to its key,
+// record or field, rather than scanned from the raw bytes with the headers
+// around it. The offsets of compressed prefetch files are in the
+// decompressed data. It returns false, leaving the file to the usual scan,
+// for files that are none of the enabled artifacts. An artifact that can only
+// be read in part is reported as a warning after the strings found.
+func scanArtifact(filename string, config extractor.Config, regions []printer.Region, s sink) bool {
+	if !config.Registry && !config.Evtx && !config.Prefetch {
+		return false
+	}
+	header := readHeader(filename, 8)
+	var parser *artifactParser
+	for i := range artifactParsers {
+		if artifactParsers[i].enabled(config) && artifactParsers[i].detect(header) {
+			parser = &artifactParsers[i]
+			break
+		}
+	}
+	if parser == nil {
+		return false
+	}
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return false
+	}
+
+	var texts []artifactText
+	walkErr := parser.walk(data, func(text artifactText) {
+		texts = append(texts, text)
+	})
+	if walkErr != nil && len(texts) == 0 {
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnArtifact, File: filename,
+			Message: fmt.Sprintf("cannot read the %s file, scanning it as a plain file", parser.format), Err: walkErr})
+		return false
+	}
+
+	cells := make(printer.ArtifactCells, len(texts))
+	for i, text := range texts {
+		cells[i] = text.cell
+	}
+	sort.Slice(cells, func(i, j int) bool { return cells[i].Offset < cells[j].Offset })
+
+	s.BeginFile(fileInfo{Name: filename, Format: parser.format, Regions: regions, Artifact: cells})
+	if walkErr != nil {
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnArtifact, File: filename,
+			Message: fmt.Sprintf("%s file could only be read in part (%d strings)", parser.format, len(texts)), Err: walkErr})
+	}
+
+	// Names are Latin-1 or ANSI unless stored as UTF-16, like most text
+	utf16Config, byteConfig := config, config
+	utf16Config.Encoding = "l"
+	if config.Encoding != "S" {
+		byteConfig.Encoding = "s"
+	}
+	for _, text := range texts {
+		textConfig := byteConfig
+		if text.utf16 {
+			textConfig = utf16Config
+		}
+		extractor.ExtractFromSection(text.data, "", text.cell.Offset, filename, textConfig, s.PrintString)
+	}
+	s.EndFile(filename, nil)
+	return true
+}
+
+// readHeader returns the first n bytes of filename, or fewer if it is shorter
+// or cannot be read
+func readHeader(filename string, n int) []byte {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil
+	}
+	defer func() { _ = file.Close() }()
+	header := make([]byte, n)
+	n, _ = io.ReadFull(file, header)
+	return header[:n]
+}
+
+// findNTFSFiles reads the master file table of an NTFS partition image, or of
+// the NTFS partitions of a disk image (--ntfs), also inside an evidence
+// container or virtual disk (--evidence, --vdisk), to attribute the strings of
+// its usual scan to the files whose names and data hold them. It returns nil
+// if the option is off or there is no NTFS filesystem; an MFT that cannot be
+// read is reported as a warning.
+func findNTFSFiles(filename string, config extractor.Config) printer.ArtifactCells {
+	if !config.NTFS {
+		return nil
+	}
+	media, size, closeMedia, err := openMedia(filename, config)
+	if err != nil {
+		return nil
+	}
+	defer closeMedia()
+
+	// The filesystems: the whole image or its NTFS partitions
+	volumes := []partition.Partition{{Size: size}}
+	boot := make([]byte, 11)
+	if _, err := media.ReadAt(boot, 0); err != nil || !ntfs.IsNTFS(boot) {
+		volumes = volumes[:0]
+		for _, p := range readPartitions(media, size) {
+			if _, err := media.ReadAt(boot, p.Offset); err == nil && ntfs.IsNTFS(boot) {
+				volumes = append(volumes, p)
+			}
+		}
+	}
+
+	var cells printer.ArtifactCells
+	for _, volume := range volumes {
+		volumeSize := min(volume.Size, size-volume.Offset)
+		extents, err := ntfs.Extents(io.NewSectionReader(media, volume.Offset, volumeSize), volumeSize)
+		if err != nil {
+			extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnArtifact, File: filename,
+				Message: fmt.Sprintf("cannot read the NTFS master file table at 0x%x", volume.Offset), Err: err})
+			continue
+		}
+		for _, extent := range extents {
+			cells = append(cells, printer.ArtifactCell{Offset: volume.Offset + extent.Offset, Size: extent.Size,
+				MFTRecord: extent.Record, NTFSPath: extent.Path, NTFSAttribute: extent.Attribute, NTFSDeleted: extent.Deleted})
+		}
+	}
+	sort.Slice(cells, func(i, j int) bool { return cells[i].Offset < cells[j].Offset })
+	return cells
+}
+
+// findMemoryRegions attributes the strings of a raw memory image to its
+// regions: those of the map of --memory-map, else those found by walking its
+// page tables (--memory-profile). It returns nil if both options are off; a
+// map or image that cannot be read is reported as a warning.
+func findMemoryRegions(filename string, config extractor.Config) printer.ArtifactCells {
+	if config.MemoryMap == "" && config.MemoryProfile == "" {
+		return nil
+	}
+	var regions []memimage.Region
+	var err error
+	if config.MemoryMap != "" {
+		var file *os.File
+		if file, err = os.Open(config.MemoryMap); err == nil {
+			regions, err = memimage.ParseMap(file)
+			_ = file.Close()
+		}
+	} else {
+		var media io.ReaderAt
+		var size int64
+		var closeMedia func()
+		if media, size, closeMedia, err = openMedia(filename, config); err == nil {
+			regions, err = memimage.Analyze(media, size, config.MemoryProfile)
+			closeMedia()
+		}
+	}
+	if err != nil {
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnArtifact, File: filename,
+			Message: "cannot find the regions of the memory image", Err: err})
+		return nil
+	}
+	cells := make(printer.ArtifactCells, len(regions))
+	for i, region := range regions {
+		cells[i] = printer.ArtifactCell{Offset: region.Offset, Size: region.Size,
+			MemoryRegion: region.Label, MemoryAddress: region.Address, MemoryHasAddress: region.HasAddress}
+	}
+	return cells
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/ignore"
+	"github.com/richardwooding/txtr/internal/walk"
+	"github.com/richardwooding/txtr/internal/winpath"
+)
+
+// CheckIgnoresCLI defines the command-line interface of the check-ignores subcommand
+type CheckIgnoresCLI struct {
+	MinLength  int      `short:"n" name:"bytes" default:"4" help:"Minimum string length when scanning samples"`
+	Follow     bool     `name:"follow-symlinks" help:"Follow symlinks below sample directories (loops are detected)"`
+	Encoding   string   `short:"e" name:"encoding" enum:"s,S,b,l,B,L" default:"s" help:"Character encoding when scanning samples (see txtr --help)"`
+	IgnoreFile string   `arg:"" name:"ignore-file" type:"existingfile" help:"Ignore file to check"`
+	Samples    []string `arg:"" optional:"" name:"sample" type:"path" help:"Files or directories (scanned recursively) to find dead rules with"`
+}
+
+// runCheckIgnores implements "txtr check-ignores": it validates an ignore file
+// and reports duplicate and overlapping rules, and with samples, rules that
+// match nothing or only strings other rules already match. It returns the
+// process exit code: 1 if the file is invalid or has findings.
+func runCheckIgnores(args []string) int {
+	var cli CheckIgnoresCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr check-ignores"),
+		kong.Description("Validate an --ignore-file and report dead and overlapping rules."),
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	list, err := ignore.Load(cli.IgnoreFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	findings := list.Overlaps()
+
+	scanned := 0
+	if len(cli.Samples) > 0 {
+		config := extractor.Config{MinLength: cli.MinLength, Encoding: cli.Encoding, MmapThreshold: 1024 * 1024}
+		coverage := list.NewCoverage()
+		walker := &walk.Walker{FollowSymlinks: cli.Follow}
+		for _, sample := range cli.Samples {
+			scanned += scanIgnoreSamples(sample, config, coverage, walker)
+		}
+
+		// Report each rule once, preferring the static finding
+		reported := make(map[string]bool, len(findings))
+		for _, finding := range findings {
+			reported[finding.Rule.Position()] = true
+		}
+		for _, finding := range coverage.Findings() {
+			if !reported[finding.Rule.Position()] {
+				findings = append(findings, finding)
+			}
+		}
+	}
+
+	slices.SortStableFunc(findings, func(a, b ignore.Finding) int {
+		return cmp.Compare(a.Rule.Line, b.Rule.Line)
+	})
+	writeIgnoreFindings(os.Stdout, list, findings, scanned)
+	if len(findings) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// scanIgnoreSamples adds the strings of a file, or of every regular file below
+// a directory, to the coverage count. Files the walker has already visited are
+// skipped. It returns the number of files scanned.
+func scanIgnoreSamples(input string, config extractor.Config, coverage *ignore.Coverage, walker *walk.Walker) int {
+	add := func(str []byte, _ string, _ int64, _ extractor.Config) {
+		coverage.Add(str)
+	}
+
+	scanned := 0
+	walker.Walk(input, func(path string, err error) {
+		if err == nil {
+			err = extractor.ExtractStringsFromFile(winpath.Input(path), config, add)
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", path, err)
+			return
+		}
+		scanned++
+	})
+	return scanned
+}
+
+// writeIgnoreFindings writes the findings as "file:line: problem: rule (detail)"
+// lines followed by a summary
+//
+//nolint:errcheck // Writing report to stdout, errors are not critical
+func writeIgnoreFindings(w io.Writer, list *ignore.List, findings []ignore.Finding, scanned int) {
+	var b strings.Builder
+	for _, finding := range findings {
+		fmt.Fprintf(&b, "%s: %s: %s (%s)\n", finding.Rule.Position(), finding.Problem, finding.Rule, finding.Detail)
+	}
+
+	fmt.Fprintf(&b, "%d rules, %d findings", len(list.Rules), len(findings))
+	if scanned > 0 {
+		fmt.Fprintf(&b, " (%d sample files scanned)", scanned)
+	} else {
+		b.WriteString(" (pass sample files to find dead rules)")
+	}
+	b.WriteString("\n")
+	io.WriteString(w, b.String())
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/cluster"
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+	"github.com/richardwooding/txtr/internal/walk"
+	"github.com/richardwooding/txtr/internal/winpath"
+)
+
+// ClusterCLI defines the command-line interface of the cluster subcommand
+type ClusterCLI struct {
+	Threshold float64  `name:"threshold" default:"0.5" help:"Minimum estimated Jaccard similarity (0-1) for grouping two samples"`
+	Matrix    bool     `name:"matrix" help:"Also output the pairwise similarity matrix"`
+	JSON      bool     `short:"j" name:"json" help:"Output the clusters in JSON format"`
+	MinLength int      `short:"n" name:"bytes" default:"4" help:"Minimum string length when scanning files"`
+	Follow    bool     `name:"follow-symlinks" help:"Follow symlinks below directories (loops are detected)"`
+	Inputs    []string `arg:"" name:"input" type:"path" help:"txtr JSON or NDJSON result files, files to scan, or directories to scan recursively"`
+}
+
+// clusterSample is a sample in the JSON output
+type clusterSample struct {
+	Name    string `json:"name"`
+	Strings int    `json:"strings"`
+}
+
+// clusterMember is a cluster member in the JSON output
+type clusterMember struct {
+	Name       string  `json:"name"`
+	Similarity float64 `json:"similarity"` // Highest similarity to another sample
+}
+
+// clusterOutput is the JSON output of the cluster subcommand
+type clusterOutput struct {
+	Threshold   float64           `json:"threshold"`
+	Samples     []clusterSample   `json:"samples"`
+	Clusters    [][]clusterMember `json:"clusters"`
+	Unclustered []clusterMember   `json:"unclustered"`
+	Matrix      [][]float64       `json:"matrix,omitempty"`
+}
+
+// runCluster implements "txtr cluster": it groups samples by the strings they
+// share, reading txtr result files or scanning files and directories. It
+// returns the process exit code.
+func runCluster(args []string) int {
+	var cli ClusterCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr cluster"),
+		kong.Description("Group samples by shared strings (Jaccard similarity over MinHash signatures)."),
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	if cli.Threshold <= 0 || cli.Threshold > 1 {
+		fmt.Fprintf(os.Stderr, "error: --threshold must be greater than 0 and at most 1\n")
+		return 1
+	}
+	if cli.MinLength < 1 {
+		fmt.Fprintf(os.Stderr, "error: minimum string length must be at least 1\n")
+		return 1
+	}
+
+	config := extractor.Config{MinLength: cli.MinLength, Encoding: "s", MmapThreshold: 1024 * 1024}
+	var samples []cluster.Sample
+	walker := &walk.Walker{FollowSymlinks: cli.Follow}
+	for _, input := range cli.Inputs {
+		loaded, err := loadClusterSamples(input, config, walker)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", input, err)
+			continue
+		}
+		samples = append(samples, loaded...)
+	}
+	if len(samples) < 2 {
+		fmt.Fprintf(os.Stderr, "error: clustering needs at least two samples (found %d)\n", len(samples))
+		return 1
+	}
+
+	matrix := cluster.Matrix(samples)
+	groups := cluster.Group(matrix, cli.Threshold)
+
+	if cli.JSON {
+		err = writeClustersJSON(os.Stdout, samples, matrix, groups, cli)
+	} else {
+		err = writeClusters(os.Stdout, samples, matrix, groups, cli)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// loadClusterSamples returns the samples of an input: every file of a txtr
+// JSON or NDJSON result file, the strings of any other file, or every regular
+// file below a directory. Files the walker has already visited are skipped.
+func loadClusterSamples(input string, config extractor.Config, walker *walk.Walker) ([]cluster.Sample, error) {
+	input = winpath.Input(input)
+	info, err := os.Stat(input)
+	if err != nil {
+		return nil, err
+	}
+
+	if !info.IsDir() {
+		if samples, ok := readResultSamples(input); ok {
+			return samples, nil
+		}
+	}
+
+	var samples []cluster.Sample
+	walker.Walk(input, func(path string, err error) {
+		if err == nil {
+			var sample cluster.Sample
+			if sample, err = scanClusterSample(winpath.Input(path), config); err == nil {
+				samples = append(samples, sample)
+				return
+			}
+		}
+		fmt.Fprintf(os.Stderr, "strings: %s: %v\n", path, err)
+	})
+	return samples, nil
+}
+
+// scanClusterSample extracts the strings of a file into a sample
+func scanClusterSample(path string, config extractor.Config) (cluster.Sample, error) {
+	sample := cluster.Sample{Name: path}
+	err := extractor.ExtractStringsFromFile(path, config, func(str []byte, _ string, _ int64, _ extractor.Config) {
+		sample.Signature.Add(str)
+	})
+	return sample, err
+}
+
+// readResultSamples reads a txtr --json or NDJSON result file, returning one
+// sample per scanned file. It reports false if path is not a result file.
+func readResultSamples(path string) ([]cluster.Sample, bool) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, false
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	reader := bufio.NewReader(file)
+	start, err := reader.Peek(1)
+	if err != nil || start[0] != '{' {
+		return nil, false
+	}
+
+	// A JSON document with a files array (--json), else one string per line (ndjson)
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, false
+	}
+	var output printer.JSONOutput
+	if err := json.Unmarshal(data, &output); err == nil && output.Files != nil {
+		samples := make([]cluster.Sample, len(output.Files))
+		for i, fileResult := range output.Files {
+			samples[i].Name = fileResult.File
+			if samples[i].Name == "" {
+				samples[i].Name = resultSampleName(path, i, len(output.Files))
+			}
+			for _, result := range fileResult.Strings {
+				samples[i].Signature.Add([]byte(result.Value))
+			}
+		}
+		return samples, true
+	}
+
+	var samples []cluster.Sample
+	index := make(map[string]int)
+	for line := range bytes.Lines(data) {
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+		var result printer.StringResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			return nil, false
+		}
+		name := result.File
+		if name == "" {
+			name = path
+		}
+		i, ok := index[name]
+		if !ok {
+			i = len(samples)
+			index[name] = i
+			samples = append(samples, cluster.Sample{Name: name})
+		}
+		samples[i].Signature.Add([]byte(result.Value))
+	}
+	return samples, len(samples) > 0
+}
+
+// resultSampleName names an unnamed file of a result file (stdin scans)
+func resultSampleName(path string, i, files int) string {
+	if files == 1 {
+		return path
+	}
+	return fmt.Sprintf("%s#%d", path, i+1)
+}
+
+// writeClusters writes the clusters (and optionally the matrix) as text
+func writeClusters(w io.Writer, samples []cluster.Sample, matrix [][]float64, groups [][]cluster.Member, cli ClusterCLI) error {
+	var b strings.Builder
+
+	var clustered, unclustered [][]cluster.Member
+	for _, group := range groups {
+		if len(group) > 1 {
+			clustered = append(clustered, group)
+		} else {
+			unclustered = append(unclustered, group)
+		}
+	}
+
+	fmt.Fprintf(&b, "Samples: %d, clusters: %d (threshold %.2f)\n", len(samples), len(clustered), cli.Threshold)
+
+	for i, group := range clustered {
+		fmt.Fprintf(&b, "\nCluster %d (samples: %d):\n", i+1, len(group))
+		for _, member := range group {
+			fmt.Fprintf(&b, "  %.2f  %s\n", member.Similarity, samples[member.Index].Name)
+		}
+	}
+
+	if len(unclustered) > 0 {
+		fmt.Fprintf(&b, "\nUnclustered (samples: %d):\n", len(unclustered))
+		for _, group := range unclustered {
+			fmt.Fprintf(&b, "  %.2f  %s\n", group[0].Similarity, samples[group[0].Index].Name)
+		}
+	}
+
+	if cli.Matrix {
+		b.WriteString("\nSimilarity matrix:\n")
+		for i, sample := range samples {
+			fmt.Fprintf(&b, "  [%d] %s\n", i+1, sample.Name)
+		}
+		b.WriteString("\n       ")
+		for i := range samples {
+			fmt.Fprintf(&b, " %5s", fmt.Sprintf("[%d]", i+1))
+		}
+		b.WriteString("\n")
+		for i, row := range matrix {
+			fmt.Fprintf(&b, "  %-5s", fmt.Sprintf("[%d]", i+1))
+			for _, similarity := range row {
+				fmt.Fprintf(&b, " %5.2f", similarity)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeClustersJSON writes the clusters (and optionally the matrix) as JSON
+func writeClustersJSON(w io.Writer, samples []cluster.Sample, matrix [][]float64, groups [][]cluster.Member, cli ClusterCLI) error {
+	output := clusterOutput{
+		Threshold:   cli.Threshold,
+		Samples:     make([]clusterSample, len(samples)),
+		Clusters:    make([][]clusterMember, 0),
+		Unclustered: make([]clusterMember, 0),
+	}
+	for i, sample := range samples {
+		output.Samples[i] = clusterSample{Name: sample.Name, Strings: sample.Signature.Count()}
+	}
+
+	for _, group := range groups {
+		members := make([]clusterMember, len(group))
+		for i, member := range group {
+			members[i] = clusterMember{Name: samples[member.Index].Name, Similarity: roundSimilarity(member.Similarity)}
+		}
+		if len(members) > 1 {
+			output.Clusters = append(output.Clusters, members)
+		} else {
+			output.Unclustered = append(output.Unclustered, members[0])
+		}
+	}
+
+	if cli.Matrix {
+		output.Matrix = make([][]float64, len(matrix))
+		for i, row := range matrix {
+			output.Matrix[i] = make([]float64, len(row))
+			for j, similarity := range row {
+				output.Matrix[i][j] = roundSimilarity(similarity)
+			}
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
+
+// roundSimilarity rounds a similarity to three decimals for output
+func roundSimilarity(similarity float64) float64 {
+	return math.Round(similarity*1000) / 1000
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/conformance"
+)
+
+// ConformanceCLI defines the command-line interface of the conformance subcommand
+type ConformanceCLI struct {
+	GNU     string `name:"gnu" placeholder:"PATH" help:"GNU strings executable (default: strings or gstrings from PATH)"`
+	Corpus  string `name:"corpus" placeholder:"DIR" type:"path" help:"Write the generated corpus to DIR and keep it (default: a temporary directory)"`
+	Verbose bool   `short:"v" name:"verbose" help:"List passing checks too"`
+}
+
+// runConformance implements "txtr conformance": it runs GNU strings and this
+// txtr side by side over a generated corpus with every supported flag
+// combination and reports differing output. It returns the process exit code:
+// 1 if any check fails.
+func runConformance(args []string) int {
+	var cli ConformanceCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr conformance"),
+		kong.Description("Check that txtr output is identical to GNU strings."),
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	gnu := cli.GNU
+	if gnu == "" {
+		gnu, err = conformance.FindGNUStrings()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+	} else if !conformance.IsGNUStrings(gnu) {
+		fmt.Fprintf(os.Stderr, "error: %s is not GNU strings\n", gnu)
+		return 1
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: cannot locate txtr executable: %v\n", err)
+		return 1
+	}
+
+	dir := cli.Corpus
+	if dir == "" {
+		dir, err = os.MkdirTemp("", "txtr-conformance-")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		defer func() { _ = os.RemoveAll(dir) }()
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	samples := conformance.Corpus()
+	paths, err := conformance.WriteCorpus(dir, samples)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: cannot write corpus: %v\n", err)
+		return 1
+	}
+
+	runner := conformance.Runner{Reference: []string{gnu}, Candidate: []string{self}}
+	if writeConformance(os.Stdout, runner.Run(conformance.Cases, samples, paths), gnu, cli.Verbose) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// writeConformance writes failed (and with verbose, passed) checks and a
+// summary to w, and returns the number of failures
+func writeConformance(w io.Writer, results []conformance.Result, gnu string, verbose bool) int {
+	failures := 0
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			failures++
+			fmt.Fprintf(w, "FAIL %s: %s\n  %v\n", result.Sample, result.Case, result.Err)
+		case result.Diff != "":
+			failures++
+			fmt.Fprintf(w, "FAIL %s: %s\n  %s\n", result.Sample, result.Case, result.Diff)
+		case verbose:
+			fmt.Fprintf(w, "ok   %s: %s\n", result.Sample, result.Case)
+		}
+	}
+
+	fmt.Fprintf(w, "%d checks, %d failed (reference: %s)\n", len(results), failures, gnu)
+	return failures
+}
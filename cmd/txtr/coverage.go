@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/richardwooding/txtr/internal/binary"
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/sample"
+)
+
+// sampleSections returns the windows --coverage scans of a file as sections
+// named by their range (like rescanSections), with the size of the file
+func sampleSections(filename string, config extractor.Config) ([]binary.Section, int64, bool) {
+	if config.Sampling.Percent <= 0 {
+		return nil, 0, false
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, false
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		return nil, 0, false
+	}
+
+	windows := sample.Windows(file, info.Size(), config.Sampling)
+	if len(windows) == 0 {
+		return nil, 0, false
+	}
+	sections := make([]binary.Section, len(windows))
+	for i, w := range windows {
+		sections[i] = binary.Section{Name: fmt.Sprintf("0x%x-0x%x", w.Offset, w.Offset+w.Size), Offset: w.Offset, Size: w.Size}
+	}
+	return sections, info.Size(), true
+}
+
+// warnSampled labels the results of a file as sampled by --coverage, with how
+// much of it its windows cover. Windows covering the whole file (small files,
+// or 100%) are a full scan and not labeled.
+func warnSampled(filename string, sections []binary.Section, size int64, config extractor.Config) {
+	var scanned int64
+	for _, section := range sections {
+		scanned += section.Size
+	}
+	if scanned >= size {
+		return
+	}
+	extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityInfo, Code: extractor.WarnSampled, File: filename,
+		Message: fmt.Sprintf("sampled %d of %d bytes (%.1f%%) in %d window(s) (--coverage), the rest was not scanned", scanned, size, 100*float64(scanned)/float64(size), len(sections))})
+}
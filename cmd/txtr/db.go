@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/noise"
+)
+
+// DBCLI defines the command-line interface of the db subcommand
+type DBCLI struct {
+	Update DBUpdateCmd `cmd:"" help:"Download the latest common strings database"`
+	Info   DBInfoCmd   `cmd:"" help:"Show the common strings database used by --filter-common"`
+}
+
+// DBUpdateCmd refreshes the cached common strings database
+type DBUpdateCmd struct {
+	URL     string        `name:"url" default:"${db_url}" help:"Database URL (e.g. an internal mirror)"`
+	Force   bool          `name:"force" help:"Cache the downloaded database even if it is not newer"`
+	Timeout time.Duration `name:"timeout" default:"1m" help:"Timeout for downloading the database"`
+}
+
+// DBInfoCmd shows the active common strings database
+type DBInfoCmd struct{}
+
+// runDB implements "txtr db": it manages the common strings database used by
+// --filter-common. It returns the process exit code.
+func runDB(args []string) int {
+	var cli DBCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr db"),
+		kong.Description("Manage the common strings database used by --filter-common."),
+		kong.Vars{"db_url": noise.DefaultURL},
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	ctx, err := parser.Parse(args)
+	if err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	switch ctx.Command() {
+	case "update":
+		return cli.Update.run()
+	default:
+		return cli.Info.run()
+	}
+}
+
+// run downloads the database and caches it if it is newer than the active one
+func (c *DBUpdateCmd) run() int {
+	current, _, err := noise.Load()
+	if err != nil {
+		// An invalid cache is replaced by the download
+		current = noise.Embedded()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+
+	data, db, err := noise.Fetch(ctx, &http.Client{}, c.URL)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if db.Version <= current.Version && !c.Force {
+		fmt.Printf("Common strings database %s is up to date (latest: %s)\n", current.Version, db.Version)
+		return 0
+	}
+
+	path, err := noise.CachePath()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: cannot locate cache directory: %v\n", err)
+		return 1
+	}
+	if err := noise.Save(path, data); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Updated common strings database from %s to %s (%d entries, %s)\n", current.Version, db.Version, db.Len(), path)
+	return 0
+}
+
+// run prints the version, size and source of the active database
+func (c *DBInfoCmd) run() int {
+	db, source, err := noise.Load()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Version: %s\nEntries: %d\nSource:  %s\n", db.Version, db.Len(), source)
+	return 0
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+)
+
+// Codes of per-file errors in the --errors-json stream
+const (
+	errorNotFound   = "not-found"         // The file does not exist
+	errorPermission = "permission-denied" // The file cannot be opened
+	errorFailed     = "scan-failed"       // Any other error
+)
+
+// diagnosticRecord is a line of the --errors-json stream: a per-file error
+// or a warning, told apart by its type field
+type diagnosticRecord struct {
+	Type string `json:"type"` // "error" or "warning"
+	File string `json:"file,omitempty"`
+	printer.WarningResult
+}
+
+// diagnostics writes per-file errors and warnings, as text on stderr or as
+// NDJSON records with --errors-json. Writes are serialized, since workers
+// report concurrently.
+var diagnostics = struct {
+	sync.Mutex
+	encoder *json.Encoder // nil: text on stderr
+}{}
+
+// openErrorsJSON sends errors and warnings to path as NDJSON instead of
+// stderr ("-" for NDJSON on stderr). The file is written unbuffered, so it is
+// complete whenever the process exits.
+func openErrorsJSON(path string) error {
+	var w io.Writer = os.Stderr
+	if path != "-" {
+		file, err := os.Create(path)
+		if err != nil {
+			return err
+		}
+		w = file
+	}
+	diagnostics.encoder = json.NewEncoder(w)
+	return nil
+}
+
+// reportError reports that scanning filename failed with err
+func reportError(filename string, err error) {
+	diagnostics.Lock()
+	defer diagnostics.Unlock()
+	if diagnostics.encoder == nil {
+		fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
+		return
+	}
+	_ = diagnostics.encoder.Encode(diagnosticRecord{Type: "error", File: filename, WarningResult: printer.WarningResult{
+		Severity: extractor.SeverityError, Code: errorCode(err), Message: err.Error()}})
+}
+
+// reportWarning reports a problem that did not stop a scan. It is the
+// Config.Warnings callback of scans without structured output.
+func reportWarning(w extractor.Warning) {
+	diagnostics.Lock()
+	defer diagnostics.Unlock()
+	if diagnostics.encoder == nil {
+		fmt.Fprintln(os.Stderr, w)
+		return
+	}
+	_ = diagnostics.encoder.Encode(diagnosticRecord{Type: "warning", File: w.File, WarningResult: printer.NewWarningResult(w)})
+}
+
+// errorCode classifies a per-file error, so failures worth retrying can be
+// told from missing files
+func errorCode(err error) string {
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return errorNotFound
+	case errors.Is(err, fs.ErrPermission):
+		return errorPermission
+	default:
+		return errorFailed
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+)
+
+// execOptions configures the command run for each string reported
+// (--exec-per-match)
+type execOptions struct {
+	Command string        // Command template ("" = none)
+	Args    []string      // Arguments of Command, set by validate
+	Jobs    int           // Commands running at once (--exec-jobs)
+	Rate    float64       // Commands started per second (--exec-rate, 0 = unlimited)
+	Timeout time.Duration // Limit on each command (--exec-timeout)
+}
+
+// validate checks the options given on the command line and splits the
+// command template into Args
+func (o *execOptions) validate() error {
+	if o.Command == "" {
+		return nil
+	}
+	args, err := splitCommand(o.Command)
+	if err != nil {
+		return fmt.Errorf("invalid --exec-per-match command: %w", err)
+	}
+	if len(args) == 0 {
+		return errors.New("--exec-per-match command is empty")
+	}
+	if o.Jobs < 1 {
+		return errors.New("--exec-jobs must be at least 1")
+	}
+	if o.Rate < 0 {
+		return errors.New("--exec-rate cannot be negative")
+	}
+	if o.Timeout <= 0 {
+		return errors.New("--exec-timeout must be positive")
+	}
+	o.Args = args
+	return nil
+}
+
+// splitCommand splits a command line into its arguments at unquoted spaces.
+// Single quotes keep their content as is, and double quotes and backslashes
+// escape the next character, as in the shell, but nothing else is expanded.
+func splitCommand(command string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool
+		quote   rune // Quote open, 0 if none
+		escaped bool
+	)
+	for _, r := range command {
+		switch {
+		case escaped:
+			arg.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\\' && quote == 0, r == '\\' && quote == '"':
+			escaped, inArg = true, true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, errors.New("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
+
+// execSink runs a command for each string reported (--exec-per-match), such
+// as a lookup of each domain found. The placeholders {value}, {offset},
+// {offset_hex}, {file} and {encoding} are replaced in the arguments of the
+// command template, which is not run by a shell, so strings from untrusted
+// inputs cannot inject commands. Up to options.Jobs commands run at once,
+// started at most options.Rate times per second; scanning waits while all
+// are busy. The output of each command is written once it exits, so
+// commands running at once do not interleave.
+type execSink struct {
+	options execOptions
+	jobs    chan execJob
+	wg      sync.WaitGroup
+	output  sync.Mutex // Serializes the output of commands
+
+	mu   sync.Mutex // Guards next
+	next time.Time  // When the next command may start (--exec-rate)
+}
+
+// execJob is a command to run for a string of file
+type execJob struct {
+	argv []string
+	file string
+}
+
+// newExecSink starts the workers running the commands of options (validated)
+func newExecSink(options execOptions) *execSink {
+	s := &execSink{options: options, jobs: make(chan execJob, options.Jobs)}
+	for range options.Jobs {
+		s.wg.Go(func() {
+			for job := range s.jobs {
+				s.run(job)
+			}
+		})
+	}
+	return s
+}
+
+func (s *execSink) BeginFile(fileInfo) {}
+
+func (s *execSink) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	replacer := strings.NewReplacer(
+		"{value}", string(str),
+		"{offset}", strconv.FormatInt(offset, 10),
+		"{offset_hex}", fmt.Sprintf("0x%x", offset),
+		"{file}", filename,
+		"{encoding}", printer.EncodingName(config.Encoding),
+	)
+	argv := make([]string, len(s.options.Args))
+	for i, arg := range s.options.Args {
+		argv[i] = replacer.Replace(arg)
+	}
+	s.jobs <- execJob{argv: argv, file: filename}
+}
+
+func (s *execSink) EndFile(string, error) {}
+
+// Close waits for the commands to complete
+func (s *execSink) Close() error {
+	close(s.jobs)
+	s.wg.Wait()
+	return nil
+}
+
+// wait waits until a command may start under --exec-rate
+func (s *execSink) wait() {
+	if s.options.Rate <= 0 {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if s.next.After(now) {
+		time.Sleep(s.next.Sub(now))
+		now = s.next
+	}
+	s.next = now.Add(time.Duration(float64(time.Second) / s.options.Rate))
+}
+
+// run runs the command of job, reporting a command that fails as a warning
+// about its file
+func (s *execSink) run(job execJob) {
+	argv := job.argv
+	s.wait()
+	ctx, cancel := context.WithTimeout(context.Background(), s.options.Timeout)
+	defer cancel()
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, argv[0], argv[1:]...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	err := cmd.Run()
+
+	s.output.Lock()
+	_, _ = os.Stdout.Write(stdout.Bytes())
+	_, _ = os.Stderr.Write(stderr.Bytes())
+	s.output.Unlock()
+	if err != nil {
+		if ctx.Err() != nil {
+			err = fmt.Errorf("%w after %v", ctx.Err(), s.options.Timeout)
+		}
+		reportWarning(extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnExec, File: job.file,
+			Message: fmt.Sprintf("command %q failed (--exec-per-match)", strings.Join(argv, " ")), Err: err})
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/fsimage"
+	"github.com/richardwooding/txtr/internal/signature"
+)
+
+// scanFilesystems scans each file of the filesystems embedded in a file
+// (--extract-fs) into s, named after the file, the UBI volume if any and the
+// path in the filesystem, e.g. firmware.bin!/etc/passwd. Offsets are relative
+// to the contained file. Filesystems that cannot be read, and files that can
+// only be read in part (e.g. LZO compressed data), are reported as warnings.
+func scanFilesystems(filename string, config extractor.Config, s sink) {
+	if !config.ExtractFS {
+		return
+	}
+
+	// Errors opening the file were reported by its own scan
+	file, err := os.Open(filename)
+	if err != nil {
+		return
+	}
+	defer closeInput(file, filename, config)
+	info, err := file.Stat()
+	if err != nil {
+		return
+	}
+
+	warn := func(message string, err error) {
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnExtractFS, File: filename,
+			Message: message, Err: err})
+	}
+
+	regions, err := signature.Scan(file, info.Size())
+	if err != nil {
+		warn("cannot scan for filesystems", err)
+		return
+	}
+
+	for _, region := range regions {
+		if !fsimage.Supported(region.Type) {
+			continue
+		}
+
+		err := fsimage.Walk(file, region, func(f fsimage.File) error {
+			name := filename + "!" + f.Volume + f.Path
+			s.BeginFile(fileInfo{Name: name})
+			if f.Err != nil {
+				extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnExtractFS, File: name,
+					Message: "file could only be read in part", Err: f.Err})
+			}
+			extractor.ExtractFromSection(f.Data, "", 0, name, config, s.PrintString)
+			s.EndFile(name, nil)
+			return nil
+		})
+		if err != nil {
+			warn(fmt.Sprintf("cannot read the %s filesystem at 0x%x", region.Type, region.Offset), err)
+		}
+	}
+}
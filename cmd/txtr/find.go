@@ -0,0 +1,263 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/binary"
+	"github.com/richardwooding/txtr/internal/printer"
+	"github.com/richardwooding/txtr/internal/search"
+	"github.com/richardwooding/txtr/internal/storage"
+	"github.com/richardwooding/txtr/internal/walk"
+	"github.com/richardwooding/txtr/internal/winpath"
+)
+
+// FindCLI defines the command-line interface of the find subcommand
+type FindCLI struct {
+	Recursive  bool     `short:"r" name:"recursive" help:"Search directories recursively"`
+	Encodings  []string `short:"E" name:"encodings" default:"${encodings}" help:"Encodings to search the literal in (${encodings})"`
+	Context    int      `short:"C" name:"context" default:"32" help:"Printable characters of context to show on each side of a match"`
+	JSON       bool     `short:"j" name:"json" help:"Output matches in JSON format"`
+	Parallel   int      `short:"P" name:"parallel" default:"0" help:"Number of parallel workers (0=auto-detect CPUs)"`
+	IOProfile  string   `name:"io-profile" enum:"auto,hdd,ssd,net" default:"auto" help:"Storage the files are on, adapting the default -P: hdd, ssd, net or auto (detect)"`
+	Color      string   `name:"color" enum:"auto,always,never" default:"auto" help:"When to highlight matches (auto/always/never)"`
+	Hyperlinks bool     `name:"hyperlinks" help:"Make file names clickable file:// links (OSC 8) when writing to a terminal"`
+	Follow     bool     `name:"follow-symlinks" help:"Follow symlinks below directories with -r (loops are detected)"`
+	Literal    string   `arg:"" name:"literal" help:"String to search for"`
+	Paths      []string `arg:"" name:"path" type:"path" help:"Files, or directories with -r, to search"`
+}
+
+// findMatch is a match in the JSON output
+type findMatch struct {
+	File      string `json:"file"`
+	Offset    int64  `json:"offset"`
+	OffsetHex string `json:"offset_hex"`
+	Encoding  string `json:"encoding"`
+	Section   string `json:"section,omitempty"`
+	Context   string `json:"context"`
+
+	contextOffset int64 // Offset of the start of Context in the file
+}
+
+// findResult holds the matches of one file
+type findResult struct {
+	file    string
+	matches []findMatch
+	spans   [][2]int // Start and end of the literal in each match's context
+	err     error
+}
+
+// runFind implements "txtr find": it searches files for a literal in several
+// encodings and reports each occurrence with its offset, section and context.
+// It returns the process exit code: 0 if the literal was found, 1 if not.
+func runFind(args []string) int {
+	var cli FindCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr find"),
+		kong.Description("Find which files contain a string, and where (searches ASCII/UTF-8, UTF-16 and UTF-32 representations)."),
+		kong.Vars{"encodings": strings.Join(search.Encodings, ",")},
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	for _, encoding := range cli.Encodings {
+		if !slices.Contains(search.Encodings, encoding) {
+			fmt.Fprintf(os.Stderr, "error: unknown encoding %q (valid: %s)\n", encoding, strings.Join(search.Encodings, ", "))
+			return 1
+		}
+	}
+	needles := search.Needles(cli.Literal, cli.Encodings)
+	if len(needles) == 0 {
+		fmt.Fprintf(os.Stderr, "error: the string to search for must not be empty\n")
+		return 1
+	}
+
+	files := findFiles(cli.Paths, cli.Recursive, cli.Follow)
+	workers := cli.Parallel
+	if workers <= 0 {
+		ioProfile := storage.Profile(cli.IOProfile)
+		if ioProfile == storage.ProfileAuto {
+			ioProfile = storage.DetectFiles(files)
+		}
+		workers = ioProfile.Workers(runtime.NumCPU())
+	}
+	results := searchFiles(files, needles, max(cli.Context, 0), workers)
+
+	found := false
+	var matches []findMatch
+	useColor := printer.ShouldUseColor(parseColorMode(cli.Color))
+	hyperlinks := cli.Hyperlinks && printer.ShouldUseHyperlinks()
+	for _, r := range results {
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", r.file, r.err)
+			continue
+		}
+		found = found || len(r.matches) > 0
+		if cli.JSON {
+			matches = append(matches, r.matches...)
+			continue
+		}
+		writeFindMatches(os.Stdout, r, useColor, hyperlinks)
+	}
+
+	if cli.JSON {
+		if matches == nil {
+			matches = make([]findMatch, 0)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(matches); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+
+	if !found {
+		return 1
+	}
+	return 0
+}
+
+// findFiles expands the paths to the files to search: regular files below
+// directories with recursive, else directories are reported and skipped. Each
+// file is searched once, however many hard links or paths lead to it.
+func findFiles(paths []string, recursive, followSymlinks bool) []string {
+	var files []string
+	walker := &walk.Walker{FollowSymlinks: followSymlinks}
+	for _, path := range paths {
+		path = winpath.Input(path)
+		info, err := os.Stat(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", path, err)
+			continue
+		}
+		if info.IsDir() && !recursive {
+			fmt.Fprintf(os.Stderr, "strings: %s: is a directory (use -r to search it)\n", path)
+			continue
+		}
+		walker.Walk(path, func(path string, err error) {
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "strings: %s: %v\n", path, err)
+				return
+			}
+			files = append(files, winpath.Input(path))
+		})
+	}
+	return files
+}
+
+// searchFiles searches the files with a worker pool, returning results in input order
+func searchFiles(files []string, needles []search.Needle, context, workers int) []findResult {
+	jobs := make(chan job, len(files))
+	results := make([]findResult, len(files))
+
+	var wg sync.WaitGroup
+	for range min(workers, max(len(files), 1)) {
+		wg.Go(func() {
+			for j := range jobs {
+				results[j.index] = searchFile(j.filename, needles, context)
+			}
+		})
+	}
+	for _, j := range scheduleJobs(files) {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// searchFile searches one file, attributing matches to the data sections of
+// executables
+func searchFile(filename string, needles []search.Needle, context int) findResult {
+	result := findResult{file: filename}
+
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		result.err = err
+		return result
+	}
+
+	matches := search.Find(data, needles, context)
+	if len(matches) == 0 {
+		return result
+	}
+
+	var sections []binary.Section
+	if format, err := binary.DetectFormat(filename); err == nil {
+		sections, _ = binary.ParseSectionHeaders(filename, format)
+	}
+	return collectMatches(filename, matches, sections)
+}
+
+// collectMatches builds the result of a file from its matches
+func collectMatches(filename string, matches []search.Match, sections []binary.Section) findResult {
+	result := findResult{file: filename}
+	for _, match := range matches {
+		result.matches = append(result.matches, findMatch{
+			File:          filename,
+			Offset:        match.Offset,
+			OffsetHex:     fmt.Sprintf("0x%x", match.Offset),
+			Encoding:      match.Encoding,
+			Section:       sectionAt(sections, match.Offset),
+			Context:       match.Context,
+			contextOffset: match.ContextOffset,
+		})
+		result.spans = append(result.spans, [2]int{match.Start, match.End})
+	}
+	return result
+}
+
+// sectionAt returns the name of the section containing offset, if any
+func sectionAt(sections []binary.Section, offset int64) string {
+	for _, section := range sections {
+		if offset >= section.Offset && offset < section.Offset+section.Size {
+			return section.Name
+		}
+	}
+	return ""
+}
+
+// writeFindMatches writes the matches of a file as
+// "file:offset [encoding, section] context" lines, optionally linking the file
+// names (OSC 8)
+//
+//nolint:errcheck // Writing matches to stdout, errors are not critical
+func writeFindMatches(w io.Writer, r findResult, useColor, hyperlinks bool) {
+	name := r.file
+	if hyperlinks {
+		name = printer.Hyperlink(name, r.file)
+	}
+
+	var b strings.Builder
+	for i, match := range r.matches {
+		tags := match.Encoding
+		if match.Section != "" {
+			tags += ", " + match.Section
+		}
+		span := r.spans[i]
+		context := match.Context[:span[0]] +
+			printer.ColorString(match.Context[span[0]:span[1]], printer.AnsiBold+printer.AnsiYellow, useColor) +
+			match.Context[span[1]:]
+		fmt.Fprintf(&b, "%s:%s [%s] %s\n",
+			name,
+			printer.ColorString(match.OffsetHex, printer.AnsiYellow, useColor),
+			printer.ColorString(tags, printer.AnsiCyan, useColor),
+			context)
+	}
+	io.WriteString(w, b.String())
+}
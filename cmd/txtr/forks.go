@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/forks"
+)
+
+// scanForks scans each alternate data stream (Windows) or resource fork
+// (macOS) of a file (--ads) into s, named after the path that opens it, e.g.
+// report.txt:payload.exe or app/..namedfork/rsrc. Streams that cannot be
+// listed are reported as warnings, and streams that cannot be read as errors.
+func scanForks(filename string, config extractor.Config, s sink) {
+	if !config.ADS {
+		return
+	}
+
+	streams, err := forks.List(filename)
+	if err != nil {
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnStreams, File: filename,
+			Message: "cannot list alternate data streams", Err: err})
+	}
+	for _, stream := range streams {
+		s.BeginFile(fileInfo{Name: stream.Path})
+		err := extractor.ExtractStringsFromFile(stream.Path, config, s.PrintString)
+		if err != nil {
+			reportError(stream.Path, err)
+		}
+		s.EndFile(stream.Path, err)
+	}
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+	"github.com/richardwooding/txtr/internal/search"
+)
+
+// grepContext caps the characters of the enclosing string reported on each
+// side of a --grep match
+const grepContext = 1024
+
+// grepEncodings maps the search encodings to the -e encodings --grep matches
+// are reported in
+var grepEncodings = map[string]string{
+	search.EncodingASCII:   "s",
+	search.EncodingUTF16LE: "l",
+	search.EncodingUTF16BE: "b",
+	search.EncodingUTF32LE: "L",
+	search.EncodingUTF32BE: "B",
+}
+
+// processWithGrep implements --grep: instead of extracting every string, it
+// searches files or stdin for a literal in every encoding and reports the
+// string enclosing each match, tagged with the encoding it was found in.
+// Match, exclude and ignore filters apply to the enclosing strings.
+func processWithGrep(files []string, workers int, literal string, config extractor.Config, format string) {
+	needles := search.Needles(literal, search.Encodings)
+	if len(needles) == 0 {
+		fmt.Fprintf(os.Stderr, "error: --grep requires a non-empty string\n")
+		os.Exit(1)
+	}
+
+	var results []findResult
+	if len(files) == 0 {
+		data, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "strings: error reading stdin: %v\n", err)
+			os.Exit(1)
+		}
+		results = []findResult{collectMatches("", search.Find(data, needles, grepContext), nil)}
+	} else {
+		results = searchFiles(files, needles, grepContext, workers)
+	}
+
+	if format == formatText {
+		stdout := printer.NewTextPrinter(os.Stdout, config)
+		for _, r := range results {
+			if r.err != nil {
+				reportError(r.file, r.err)
+				continue
+			}
+			for _, match := range r.matches {
+				if matchConfig, ok := grepFilter(match, config); ok {
+					tagged := "[" + match.Encoding + "] " + match.Context
+					stdout.PrintString([]byte(tagged), r.file, match.contextOffset, matchConfig)
+				}
+			}
+		}
+		return
+	}
+
+	jsonPrinter := printer.NewJSONPrinter(config, os.Stdout)
+	for _, r := range results {
+		if r.err != nil {
+			reportError(r.file, r.err)
+			jsonPrinter.AddFileResult(r.file, "", nil, nil, r.err)
+			continue
+		}
+		fileResult := printer.FileResult{File: r.file}
+		for _, match := range r.matches {
+			if matchConfig, ok := grepFilter(match, config); ok {
+				result := printer.NewStringResult([]byte(match.Context), r.file, match.contextOffset, matchConfig)
+				result.Section = match.Section
+				fileResult.Strings = append(fileResult.Strings, result)
+			}
+		}
+		jsonPrinter.AppendFileResult(fileResult)
+	}
+	flushStructured(jsonPrinter, format)
+}
+
+// grepFilter returns the config to report a match with (its encoding set to
+// the one the match was found in) and whether the enclosing string passes the
+// minimum length and the string filters
+func grepFilter(match findMatch, config extractor.Config) (extractor.Config, bool) {
+	config.Encoding = grepEncodings[match.Encoding]
+	if len(match.Context) < config.MinLength {
+		return config, false
+	}
+	return config, extractor.ShouldPrintString([]byte(match.Context), config)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+)
+
+// description is the one-line summary shown in --help and the man page
+const description = "Extract printable strings from binary files. GNU strings compatible."
+
+// helpGroups defines the flag groups (see the group tags on CLI) in display order
+var helpGroups = []kong.Group{
+	{Key: "output", Title: "Output options:"},
+	{Key: "filtering", Title: "Filtering options:"},
+	{Key: "encoding", Title: "Encoding options:"},
+	{Key: "scan", Title: "Scan options:"},
+	{Key: "stats", Title: "Statistics options:"},
+	{Key: "performance", Title: "Performance options:"},
+}
+
+// helpExample is a usage example shown in --help and the man page
+type helpExample struct {
+	description string
+	command     string
+}
+
+// helpExamples lists common invocations
+var helpExamples = []helpExample{
+	{"Extract strings of at least 8 characters", "txtr -n 8 file.bin"},
+	{"Print hex offsets with the file name", "txtr -f -t x *.bin"},
+	{"Scan only the data sections of an executable", "txtr -d /usr/bin/ls"},
+	{"Find URLs, ignoring case", "txtr -i -m 'https?://\\S+' firmware.img"},
+	{"Extract UTF-16LE strings (Windows binaries)", "txtr -e l app.exe"},
+	{"Machine-readable output", "txtr --json -d app.exe"},
+	{"Summarize a file instead of listing strings", "txtr --stats malware.exe"},
+	{"Triage an unfamiliar binary: its 20 most interesting strings", "txtr --top 20 sample.exe"},
+	{"List the strings of a sample rarest first against an index of a corpus (txtr index)", "txtr --background corpus.idx --sort rarity suspect.exe"},
+	{"Scan each file of a firmware image's SquashFS/JFFS2/UBIFS filesystems", "txtr --extract-fs -f firmware.img"},
+	{"List the strings of a registry hive with the key each is stored under", "txtr --registry --json NTUSER.DAT"},
+	{"List the event data and loaded files of event logs and prefetch files", "txtr --evtx --prefetch --json Security.evtx *.pf"},
+	{"Tell which file of an NTFS partition image each string belongs to", "txtr --ntfs --json partition.img"},
+	{"Scan the disk image inside an EnCase evidence file (finds case.E02, ... itself)", "txtr --evidence -t x case.E01"},
+	{"Scan a VM's disk with its partitions and NTFS files, without converting it", "txtr --vdisk --partitions --ntfs --json disk.vmdk"},
+	{"Tell which process or the kernel each string of a memory dump belongs to", "txtr --memory-profile windows-x64 --json memory.raw"},
+	{"Look again around the strings of an earlier scan with a lower minimum length", "txtr --rescan results.json -n 3 --json"},
+	{"List the lines of log files mixed in with binaries, with line numbers", "txtr --lines -f -t d dumps/*"},
+	{"Take a quick look at a huge disk image by scanning 10% of it", "txtr --coverage 10% --json disk.img"},
+	{"Keep the exact bytes of 8-bit strings in JSON", "txtr -e S --value-encoding base64 --json firmware.bin"},
+	{"Keep the input bytes of escaped Unicode strings in JSON", "txtr -U escape --emit-raw --json app.bin"},
+	{"Keep failures apart from the data for a batch job to retry", "txtr --errors-json errors.ndjson --format csv samples/* > strings.csv"},
+	{"Write an HTML report of the strings of some samples to browse and share", "txtr --format html -d samples/* > report.html"},
+	{"Write JSON to stdout and the same strings as CSV and a Markdown report in one scan", "txtr --format json,csv,markdown --output-prefix scan samples/* > scan.json"},
+	{"Write a report of the findings of a build to paste into an issue", "txtr --format markdown -d build/app > report.md"},
+	{"Draw which members of a firmware archive share URLs, domains and keys", "txtr --archives --format dot firmware.zip | dot -Tsvg > relations.svg"},
+	{"Post a nightly scan report to a Slack channel", "txtr --notify-webhook https://hooks.slack.com/services/T000/B000/XXXX --notify-format slack -d --output ndjson=nightly.ndjson builds/* > /dev/null"},
+	{"Look up the reputation of each domain found, two requests a second", "txtr -m '\\.(com|net|org)$' --exec-per-match 'curl -s https://reputation.example.com/lookup?domain={value}' --exec-rate 2 sample.exe"},
+	{"Alert a webhook with a summary and the secrets found once a nightly scan completes", "txtr --notify-webhook https://alerts.example.com/txtr --notify-findings --output ndjson=nightly.ndjson corpus/* > /dev/null"},
+	{"Survive transient I/O errors on a network share", "txtr --retries 5 --retry-backoff 250ms -P 4 --json /mnt/nfs/corpus/*"},
+	{"Scan each distinct sample of a corpus once", "txtr --dedupe-inputs --json corpus/*"},
+	{"Journal the progress of a long batch scan, and continue it after an interruption", "txtr -f --journal scan.ndjson --resume corpus/* >> strings.txt"},
+	{"Scan a huge disk image in chunks on 8 CPUs", "txtr --strategy chunked -P 8 disk.img"},
+	{"Look for payloads hidden in alternate data streams (Windows) or resource forks (macOS)", "txtr --ads --json invoice.pdf"},
+	{"Find where a download came from", "txtr --xattrs --scan-xattrs --json ~/Downloads/invoice.pdf"},
+	{"Check a release build for leaked credentials", "txtr --secrets -f --json dist/*"},
+	{"Decode proprietary containers with your own tool, scanning its output", "txtr -f --pre-filter 'vendor-unpack --stdout -' firmware/*.pkg"},
+	{"Scan malware samples in password-protected ZIP files", "txtr --archives --password infected --json samples.zip"},
+	{"Scan untrusted nested archives with tight bomb limits", "txtr --archives --max-depth 2 --max-expansion 100 --max-extracted 1G upload.zip"},
+	{"Bookmark the found strings in Ghidra (run the script in the Script Manager)", "txtr --format ghidra -d sample.exe > txtr_strings.py"},
+}
+
+// helpCommands lists the subcommands shown in --help and the man page
+var helpCommands = []helpExample{
+	{"Find which files contain a string (ASCII or UTF-16)", "txtr find -r c2.example.com samples/"},
+	{"Index the strings of a corpus, then list the samples likely containing an IOC", "txtr index samples/ -o corpus.idx && txtr query corpus.idx c2.example.com"},
+	{"Create a tantivy index for ranked full-text search of the strings of a corpus", "txtr search-schema tantivy > schema.json && txtr --output search=docs.ndjson samples/* > /dev/null"},
+	{"Serve extraction over HTTP (POST a file to /extract for its strings as JSON)", "txtr serve --listen :8080"},
+	{"Extract the strings of a file on a txtr serve service, printed as locally", "txtr remote --server strings.example:8080 -f -t x sample.exe"},
+	{"Group samples by shared strings", "txtr cluster samples/"},
+	{"Recommend the options to scan a corpus with", "txtr tune samples/"},
+	{"Update txtr to the latest release", "txtr update"},
+	{"Refresh the common strings database for --filter-common", "txtr db update"},
+	{"Find dead and overlapping rules of an --ignore-file", "txtr check-ignores ignore.txt samples/"},
+	{"Compare output with GNU strings on a generated corpus", "txtr conformance"},
+	{"Print the man page (roff)", "txtr man"},
+}
+
+// cliOptions returns the Kong options shared by the parser and man page generation
+func cliOptions() []kong.Option {
+	return []kong.Option{
+		kong.Name("txtr"),
+		kong.Description(description),
+		kong.ExplicitGroups(helpGroups),
+		kong.Help(helpPrinter),
+		kong.UsageOnError(),
+	}
+}
+
+// helpPrinter prints Kong's grouped help followed by examples and subcommands
+//
+//nolint:errcheck // Writing help to stdout, errors are not critical
+func helpPrinter(options kong.HelpOptions, ctx *kong.Context) error {
+	if err := kong.DefaultHelpPrinter(options, ctx); err != nil {
+		return err
+	}
+	if options.Summary {
+		return nil
+	}
+
+	fmt.Fprintln(ctx.Stdout)
+	fmt.Fprintln(ctx.Stdout, "Examples:")
+	writeHelpExamples(ctx.Stdout, helpExamples)
+
+	fmt.Fprintln(ctx.Stdout)
+	fmt.Fprintln(ctx.Stdout, "Commands:")
+	writeHelpExamples(ctx.Stdout, helpCommands)
+	return nil
+}
+
+// writeHelpExamples writes examples as a comment line followed by the command
+//
+//nolint:errcheck // Writing help to stdout, errors are not critical
+func writeHelpExamples(w io.Writer, examples []helpExample) {
+	for i, example := range examples {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "  # %s\n  %s\n", example.description, example.command)
+	}
+}
+
+// runMan implements "txtr man": it writes a man page generated from the CLI
+// model to stdout. It returns the process exit code.
+func runMan(args []string) int {
+	if len(args) > 0 {
+		fmt.Fprintf(os.Stderr, "error: txtr man takes no arguments (redirect output to a file, e.g. txtr man > txtr.1)\n")
+		return 1
+	}
+
+	var cli CLI
+	parser, err := kong.New(&cli, cliOptions()...)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if err := writeManPage(os.Stdout, parser.Model, time.Now()); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeManPage writes a man page in roff format for the application model
+func writeManPage(w io.Writer, app *kong.Application, date time.Time) error {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, ".TH TXTR 1 %q %q \"User Commands\"\n", date.Format("2006-01-02"), "txtr "+version)
+
+	b.WriteString(".SH NAME\n")
+	fmt.Fprintf(&b, "txtr \\- %s\n", roffEscape(strings.TrimSuffix(description, ".")))
+
+	b.WriteString(".SH SYNOPSIS\n")
+	b.WriteString(".B txtr\n[\\fIOPTIONS\\fR] [\\fIFILE\\fR...]\n.br\n.B txtr cluster\n[\\fIOPTIONS\\fR] \\fIINPUT\\fR...\n.br\n.B txtr update\n[\\fIOPTIONS\\fR]\n.br\n.B txtr man\n")
+
+	b.WriteString(".SH DESCRIPTION\n")
+	b.WriteString(roffEscape(description) + "\n.PP\n")
+	b.WriteString("With no \\fIFILE\\fR, standard input is read.\n")
+
+	// Group flags in helpGroups order; ungrouped flags (help, version) come first
+	grouped := make(map[string][]*kong.Flag)
+	for _, levelFlags := range app.AllFlags(true) {
+		for _, flag := range levelFlags {
+			key := ""
+			if flag.Group != nil {
+				key = flag.Group.Key
+			}
+			grouped[key] = append(grouped[key], flag)
+		}
+	}
+
+	b.WriteString(".SH OPTIONS\n")
+	writeManFlags(&b, grouped[""])
+	for _, group := range helpGroups {
+		if len(grouped[group.Key]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, ".SS %s\n", roffEscape(strings.TrimSuffix(group.Title, ":")))
+		writeManFlags(&b, grouped[group.Key])
+	}
+
+	b.WriteString(".SH COMMANDS\n")
+	writeManExamples(&b, helpCommands)
+
+	b.WriteString(".SH EXAMPLES\n")
+	writeManExamples(&b, helpExamples)
+
+	b.WriteString(".SH SEE ALSO\n")
+	b.WriteString(".BR strings (1)\n.PP\nhttps://github.com/richardwooding/txtr\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// writeManFlags writes a tagged paragraph for each flag
+func writeManFlags(b *strings.Builder, flags []*kong.Flag) {
+	for _, flag := range flags {
+		b.WriteString(".TP\n")
+		if flag.Short != 0 {
+			fmt.Fprintf(b, "\\fB\\-%c\\fR, ", flag.Short)
+		}
+		fmt.Fprintf(b, "\\fB\\-\\-%s\\fR", roffEscape(flag.Name))
+		if !flag.IsBool() && !flag.IsCounter() {
+			fmt.Fprintf(b, "=\\fI%s\\fR", roffEscape(manPlaceHolder(flag)))
+		}
+		b.WriteString("\n")
+
+		help := flag.Help
+		if flag.HasDefault && flag.Default != "" && !flag.IsBool() && !strings.Contains(help, "(default") {
+			help += " (default: " + flag.Default + ")"
+		}
+		b.WriteString(roffEscape(help) + "\n")
+	}
+}
+
+// manPlaceHolder returns the value placeholder for a flag: its explicit
+// placeholder, its enum values, or its upper-cased name
+func manPlaceHolder(flag *kong.Flag) string {
+	if flag.PlaceHolder != "" {
+		return flag.PlaceHolder
+	}
+	if flag.Enum != "" {
+		var values []string
+		for _, value := range flag.EnumSlice() {
+			if value != "" {
+				values = append(values, value)
+			}
+		}
+		return strings.Join(values, "|")
+	}
+	return strings.ToUpper(strings.ReplaceAll(flag.Name, "-", "_"))
+}
+
+// writeManExamples writes examples as a description followed by an indented command
+func writeManExamples(b *strings.Builder, examples []helpExample) {
+	for _, example := range examples {
+		fmt.Fprintf(b, ".PP\n%s:\n.PP\n.RS 4\n.nf\n%s\n.fi\n.RE\n", roffEscape(example.description), roffEscape(example.command))
+	}
+}
+
+// roffEscape escapes text for use in a roff document
+func roffEscape(text string) string {
+	text = strings.ReplaceAll(text, "\\", "\\e")
+	text = strings.ReplaceAll(text, "-", "\\-")
+	// Lines starting with a control character must be protected
+	if strings.HasPrefix(text, ".") || strings.HasPrefix(text, "'") {
+		text = "\\&" + text
+	}
+	return text
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/bloom"
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/storage"
+)
+
+// indexEncodings lists the encodings "txtr index" can scan
+var indexEncodings = []string{"s", "S", "b", "l", "B", "L"}
+
+// IndexCLI defines the command-line interface of the index subcommand
+type IndexCLI struct {
+	Output            string   `short:"o" name:"output" required:"" placeholder:"FILE" type:"path" help:"Index file to write"`
+	MinLength         int      `short:"n" name:"bytes" default:"4" help:"Minimum string length when scanning samples"`
+	Encodings         []string `short:"e" name:"encodings" default:"S,l" help:"Encodings of the strings indexed (s, S, b, l, B, L; see txtr --help)"`
+	FalsePositiveRate float64  `name:"false-positive-rate" default:"0.01" help:"Rate at which a sample's filter wrongly reports an n-gram; lower rates make larger indexes"`
+	Parallel          int      `short:"P" name:"parallel" default:"0" help:"Number of parallel workers (0=auto-detect CPUs)"`
+	IOProfile         string   `name:"io-profile" enum:"auto,hdd,ssd,net" default:"auto" help:"Storage the files are on, adapting the default -P: hdd, ssd, net or auto (detect)"`
+	Follow            bool     `name:"follow-symlinks" help:"Follow symlinks below directories (loops are detected)"`
+	Paths             []string `arg:"" name:"path" type:"path" help:"Files, or directories to index recursively"`
+}
+
+// QueryCLI defines the command-line interface of the query subcommand
+type QueryCLI struct {
+	JSON   bool   `short:"j" name:"json" help:"Output the samples in JSON format"`
+	Index  string `arg:"" name:"index" type:"existingfile" help:"Index written by txtr index"`
+	String string `arg:"" name:"string" help:"String to look up, e.g. an IOC (at least 4 bytes)"`
+}
+
+// runIndex implements "txtr index": it scans the files of a corpus and writes
+// a Bloom filter of the n-grams of each one's strings to an index file, for
+// "txtr query". It returns the process exit code.
+func runIndex(args []string) int {
+	var cli IndexCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr index"),
+		kong.Description("Build a compact probabilistic index of the strings of a corpus for txtr query (a Bloom filter per sample)."),
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	for _, encoding := range cli.Encodings {
+		if !slices.Contains(indexEncodings, encoding) {
+			fmt.Fprintf(os.Stderr, "error: unknown encoding %q (valid: %s)\n", encoding, strings.Join(indexEncodings, ", "))
+			return 1
+		}
+	}
+	if cli.MinLength < 1 {
+		fmt.Fprintf(os.Stderr, "error: minimum string length must be at least 1\n")
+		return 1
+	}
+	if cli.FalsePositiveRate <= 0 || cli.FalsePositiveRate >= 1 {
+		fmt.Fprintf(os.Stderr, "error: --false-positive-rate must be between 0 and 1\n")
+		return 1
+	}
+
+	files := findFiles(cli.Paths, true, cli.Follow)
+	workers := cli.Parallel
+	if workers <= 0 {
+		ioProfile := storage.Profile(cli.IOProfile)
+		if ioProfile == storage.ProfileAuto {
+			ioProfile = storage.DetectFiles(files)
+		}
+		workers = ioProfile.Workers(runtime.NumCPU())
+	}
+
+	config := extractor.Config{MinLength: cli.MinLength, MmapThreshold: 1024 * 1024}
+	index := indexFiles(files, cli.Encodings, cli.FalsePositiveRate, config, workers)
+	if err := index.Save(cli.Output); err != nil {
+		fmt.Fprintf(os.Stderr, "error: cannot write index: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// indexFiles builds the filters of the files with a worker pool, keeping them
+// in input order. Files that cannot be scanned are reported and left out.
+func indexFiles(files, encodings []string, rate float64, config extractor.Config, workers int) *bloom.Index {
+	jobs := make(chan job, len(files))
+	samples := make([]*bloom.Sample, len(files))
+
+	var wg sync.WaitGroup
+	for range min(workers, max(len(files), 1)) {
+		wg.Go(func() {
+			for j := range jobs {
+				sample, err := indexFile(j.filename, encodings, rate, config)
+				if err != nil {
+					fmt.Fprintf(os.Stderr, "strings: %s: %v\n", j.filename, err)
+					continue
+				}
+				samples[j.index] = sample
+			}
+		})
+	}
+	for _, j := range scheduleJobs(files) {
+		jobs <- j
+	}
+	close(jobs)
+	wg.Wait()
+
+	index := &bloom.Index{}
+	for _, sample := range samples {
+		if sample != nil {
+			index.Samples = append(index.Samples, *sample)
+		}
+	}
+	return index
+}
+
+// indexFile scans a file in each encoding and returns the filter of the
+// n-grams of its strings
+func indexFile(filename string, encodings []string, rate float64, config extractor.Config) (*bloom.Sample, error) {
+	grams := bloom.GramSet{}
+	for _, encoding := range encodings {
+		config.Encoding = encoding
+		err := extractor.ExtractStringsFromFile(filename, config, func(str []byte, _ string, _ int64, _ extractor.Config) {
+			grams.Add(str)
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &bloom.Sample{Name: filename, Filter: grams.Filter(rate)}, nil
+}
+
+// runQuery implements "txtr query": it lists the samples of an index that
+// likely contain a string. It returns the process exit code: 0 if a sample
+// likely contains the string, 1 if none does.
+func runQuery(args []string) int {
+	var cli QueryCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr query"),
+		kong.Description("List the samples of a txtr index that likely contain a string (confirm with txtr find)."),
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	index, err := bloom.Load(cli.Index)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %s: %v\n", cli.Index, err)
+		return 1
+	}
+	samples, err := index.Query([]byte(cli.String))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if cli.JSON {
+		if samples == nil {
+			samples = make([]string, 0)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(samples); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+	} else {
+		for _, sample := range samples {
+			fmt.Println(sample)
+		}
+	}
+
+	if len(samples) == 0 {
+		return 1
+	}
+	return 0
+}
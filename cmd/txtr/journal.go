@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// Events of --journal records
+const (
+	journalStarted  = "started"  // Scanning the file began
+	journalFinished = "finished" // The file and the files within it were scanned
+	journalError    = "error"    // Scanning the file failed
+)
+
+// journalRecord is a line of the --journal file
+type journalRecord struct {
+	Time     time.Time `json:"time"`
+	Event    string    `json:"event"`
+	File     string    `json:"file"`
+	Strings  int       `json:"strings,omitempty"`          // Strings reported, when the file is done
+	Duration float64   `json:"duration_seconds,omitempty"` // Time scanning the file, when it is done
+	Error    string    `json:"error,omitempty"`
+}
+
+// scanJournal is the append-only journal of a batch run (--journal): when
+// each input file started and finished scanning, or failed, so its progress
+// can be followed from another terminal (tail -f) and a stalled run examined
+// afterwards. Records are written unbuffered as they happen, from the
+// workers scanning the files, so the journal is complete whenever the
+// process exits.
+type scanJournal struct {
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+}
+
+// openJournal opens the journal at path for appending, creating it if needed
+func openJournal(path string) (*scanJournal, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &scanJournal{file: file, encoder: json.NewEncoder(file)}, nil
+}
+
+// record appends a record, reporting a journal that cannot be written as a
+// warning once
+func (j *scanJournal) record(r journalRecord) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.encoder == nil {
+		return
+	}
+	if err := j.encoder.Encode(r); err != nil {
+		reportWarning(extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnJournal,
+			Message: "cannot write the scan journal (--journal), no longer recording", Err: err})
+		j.encoder = nil
+	}
+}
+
+// Close closes the journal file
+func (j *scanJournal) Close() error {
+	return j.file.Close()
+}
+
+// scanFileJournaled scans a file into s (see scanFileToSink), recording in
+// the journal when it started. It returns the entry of the file, to record
+// with finish when it is written out, or nil without a journal.
+func scanFileJournaled(filename string, config extractor.Config, s sink, journal *scanJournal) *journalEntry {
+	if journal == nil {
+		scanFileToSink(filename, config, s)
+		return nil
+	}
+	entry := &journalEntry{journal: journal, filename: filename, start: time.Now()}
+	journal.record(journalRecord{Time: entry.start, Event: journalStarted, File: filename})
+	scanFileToSink(filename, config, multiSink{s, entry})
+	entry.end = time.Now()
+	return entry
+}
+
+// journalEntry is a sink following the scan of one journaled file: the
+// strings reported and the error of the file
+type journalEntry struct {
+	journal    *scanJournal
+	filename   string
+	start, end time.Time // Scan of the file
+	strings    int
+	err        error
+}
+
+// finish records in the journal that the file, with the files within it
+// (archive members, embedded filesystems...), finished or failed, once the
+// output s received its strings is written: --resume skips finished files,
+// so a run killed with strings still buffered would lose them. A nil entry
+// (no journal) records nothing.
+func (je *journalEntry) finish(s sink) {
+	if je == nil {
+		return
+	}
+	err := je.err
+	if f, ok := s.(flusher); ok && err == nil {
+		err = f.Flush()
+	}
+	record := journalRecord{Time: time.Now(), Event: journalFinished, File: je.filename, Strings: je.strings,
+		Duration: je.end.Sub(je.start).Seconds()}
+	if err != nil {
+		record.Event, record.Error = journalError, err.Error()
+	}
+	je.journal.record(record)
+}
+
+func (je *journalEntry) BeginFile(fileInfo) {}
+
+func (je *journalEntry) PrintString([]byte, string, int64, extractor.Config) {
+	je.strings++
+}
+
+func (je *journalEntry) EndFile(filename string, err error) {
+	if filename == je.filename && err != nil {
+		je.err = err
+	}
+}
+
+func (je *journalEntry) Close() error {
+	return nil
+}
+
+// finishedFiles returns the files recorded as finished in the journal at
+// path, for --resume. A journal that does not exist yet has none; lines that
+// are not records, such as one cut off when a run was killed, are skipped.
+func finishedFiles(path string) (map[string]bool, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	finished := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		var r journalRecord
+		if json.Unmarshal(scanner.Bytes(), &r) != nil {
+			continue
+		}
+		// Files that failed are scanned again
+		if r.Event == journalFinished {
+			finished[r.File] = true
+		}
+	}
+	return finished, scanner.Err()
+}
@@ -4,17 +4,42 @@ package main
 
 import (
 	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
+	"strconv"
+	"strings"
 	"sync"
+	"text/template"
+	"time"
 
 	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/affinity"
+	"github.com/richardwooding/txtr/internal/archive"
 	"github.com/richardwooding/txtr/internal/binary"
+	"github.com/richardwooding/txtr/internal/bloom"
+	"github.com/richardwooding/txtr/internal/console"
+	"github.com/richardwooding/txtr/internal/dedupe"
 	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/forks"
+	"github.com/richardwooding/txtr/internal/ignore"
+	"github.com/richardwooding/txtr/internal/noise"
 	"github.com/richardwooding/txtr/internal/printer"
+	"github.com/richardwooding/txtr/internal/rescan"
+	"github.com/richardwooding/txtr/internal/sample"
 	"github.com/richardwooding/txtr/internal/stats"
+	"github.com/richardwooding/txtr/internal/storage"
+	"github.com/richardwooding/txtr/internal/winpath"
+	"github.com/richardwooding/txtr/internal/xattr"
 )
 
 // Build information (set by goreleaser via ldflags)
@@ -25,38 +50,155 @@ var (
 	builtBy = "unknown"
 )
 
-// CLI defines the command-line interface structure
+// CLI defines the command-line interface structure. Flags are tagged with a
+// help group (see helpGroups) and ordered by group for --help and the man page.
 type CLI struct {
-	MinLength            int      `short:"n" name:"bytes" default:"4" help:"Minimum string length"`
-	PrintFileName        bool     `short:"f" name:"print-file-name" help:"Print file name before each string"`
-	Radix                string   `short:"t" name:"radix" enum:"o,d,x," default:"" help:"Print offset in radix (o=octal, d=decimal, x=hex)"`
-	OctalOffset          bool     `short:"o" help:"Print offset in octal (alias for -t o)"`
-	Encoding             string   `short:"e" name:"encoding" enum:"s,S,b,l,B,L," default:"s" help:"Character encoding (s=7-bit, S=8-bit, b=16-bit BE, l=16-bit LE, B=32-bit BE, L=32-bit LE)"`
-	Unicode              string   `short:"U" name:"unicode" enum:"default,invalid,locale,escape,hex,highlight," default:"default" help:"How to handle UTF-8 sequences (default/invalid/locale/escape/hex/highlight)"`
-	OutputSeparator      string   `short:"s" name:"output-separator" default:"\\n" help:"Output record separator (default: newline)"`
-	IncludeAllWhitespace bool     `short:"w" name:"include-all-whitespace" help:"Include all whitespace characters in strings"`
-	ScanAll              bool     `short:"a" name:"all" help:"Scan entire file"`
-	ScanDataOnly         bool     `short:"d" name:"data" help:"Scan only initialized data sections of binary files"`
-	TargetFormat         string   `short:"T" name:"target" enum:"elf,pe,macho,binary," default:"" help:"Specify binary format (elf/pe/macho/binary)"`
-	JSON                 bool     `short:"j" name:"json" help:"Output results in JSON format for automation"`
-	Color                string   `name:"color" enum:"auto,always,never," default:"auto" help:"When to use colored output (auto/always/never)"`
-	Parallel             int      `short:"P" name:"parallel" default:"0" help:"Number of parallel workers (0=auto-detect CPUs, 1=sequential)"`
-	MatchPatterns        []string `short:"m" name:"match" help:"Only show strings matching pattern (can be specified multiple times)"`
-	ExcludePatterns      []string `short:"M" name:"exclude" help:"Exclude strings matching pattern (can be specified multiple times)"`
-	IgnoreCase           bool     `short:"i" name:"ignore-case" help:"Case-insensitive pattern matching"`
-	Stats                bool     `name:"stats" help:"Output statistics summary instead of strings"`
-	StatsPerFile         bool     `name:"stats-per-file" help:"Show per-file statistics instead of aggregated (requires --stats)"`
-	DisableMmap          bool     `name:"no-mmap" help:"Disable memory-mapped I/O optimization"`
-	MmapThreshold        int64    `name:"mmap-threshold" default:"1048576" help:"Minimum file size (bytes) for using mmap (default: 1MB)"`
-	Version              bool     `short:"v" name:"version" help:"Display version information"`
-	VersionAlt           bool     `short:"V" hidden:"" help:"Display version information (alias)"`
-	Files                []string `arg:"" optional:"" name:"file" help:"Files to extract strings from" type:"path"`
+	PrintFileName   bool          `short:"f" name:"print-file-name" group:"output" help:"Print file name before each string"`
+	Radix           string        `short:"t" name:"radix" enum:"o,d,x," default:"" group:"output" help:"Print offset in radix (o=octal, d=decimal, x=hex)"`
+	OctalOffset     bool          `short:"o" group:"output" help:"Print offset in octal (alias for -t o)"`
+	OutputSeparator string        `short:"s" name:"output-separator" default:"\\n" group:"output" help:"Output record separator (default: newline)"`
+	JSON            bool          `short:"j" name:"json" group:"output" help:"Output results in JSON format for automation (alias for --format json)"`
+	Format          []string      `name:"format" placeholder:"FORMAT" group:"output" help:"Output format (several, comma-separated, with --output-prefix): text, json, csv, cyclonedx, rizin, ghidra, idapython, html, markdown, dot, mermaid, slack or teams (applies to strings and --stats; csv is strings only; cyclonedx writes a CycloneDX BOM of the detected components; rizin writes the JSON of rizin's izj; ghidra and idapython write a script marking the strings in a disassembler; html writes a standalone report with sortable, filterable tables and charts; markdown writes a report for issues and tickets; dot and mermaid write a graph of the files and the notable strings they share; slack and teams write a chat message summarizing the scan)"`
+	OutputPrefix    string        `name:"output-prefix" placeholder:"PREFIX" group:"output" help:"With several --format values, write the first to stdout and each other to PREFIX.EXT in the same scan (e.g. --format json,csv --output-prefix scan writes scan.csv)"`
+	Output          []string      `name:"output" placeholder:"KIND=PATH" group:"output" help:"Also write results to files in one scan (kinds: text, json, ndjson, search, sqlite, csv, cyclonedx, html, markdown, dot, mermaid, stats, stats-json, slack, teams; e.g. ndjson=out.ndjson,stats=stats.txt)"`
+	Unique          bool          `name:"unique" group:"output" help:"Report each distinct string once per file, at its first offset (JSON and CSV)"`
+	Count           bool          `name:"count" group:"output" help:"Like --unique, also reporting how often each string occurs (JSON and CSV)"`
+	DedupeFoldCase  bool          `name:"dedupe-fold-case" group:"output" help:"Like --unique, also collapsing strings that differ only in case and listing their spellings (JSON and CSV)"`
+	ValueEncoding   string        `name:"value-encoding" enum:"utf8,base64,hex" default:"utf8" group:"output" help:"How string values are written in JSON, NDJSON and CSV: utf8 (bytes that are not UTF-8 are replaced), or base64 or hex of their exact bytes, marked by value_encoding in JSON"`
+	WideInfo        bool          `name:"wide-info" group:"output" help:"Add the byte order, alignment (even or odd start) and whether a byte order mark precedes each UTF-16 and UTF-32 string as wide in JSON, to tell genuine wide strings from coincidental byte patterns"`
+	EmitRaw         bool          `name:"emit-raw" group:"output" help:"Add each string's bytes as found in the input, base64 encoded, as raw in JSON (kept when -U escape or hex changes how strings are displayed)"`
+	AllOffsets      bool          `name:"all-offsets" group:"output" help:"List the offsets of every occurrence of each string (requires --unique, --count or --dedupe-fold-case)"`
+	MaxOffsets      int           `name:"max-offsets" placeholder:"N" default:"0" group:"output" help:"Maximum offsets listed per string with --all-offsets (0=unlimited)"`
+	Color           string        `name:"color" enum:"auto,always,never," default:"auto" group:"output" help:"When to use colored output (auto/always/never)"`
+	Sanitize        string        `name:"sanitize" enum:"auto,always,never" default:"auto" group:"output" help:"When to replace terminal control characters and bidirectional overrides in strings with ? in text output (auto=when writing to a terminal)"`
+	EscapeNonPrint  bool          `name:"escape-nonprint" group:"output" help:"Show control characters, invalid UTF-8 and other non-printable characters as C-style escapes (\\n, \\x1b) in text output"`
+	Hyperlinks      bool          `name:"hyperlinks" group:"output" help:"Make file names clickable file:// links (OSC 8) in text output to a terminal"`
+	MaxWidth        int           `name:"max-width" placeholder:"N" default:"0" group:"output" help:"Truncate strings longer than N characters in text output, ending them with … (0=unlimited)"`
+	ClusterByOffset int64         `name:"cluster-by-offset" placeholder:"GAP" default:"0" group:"output" help:"Group strings into neighborhoods of strings at most GAP bytes apart, under headers with their offset range (text; clusters in JSON and NDJSON)"`
+	Background      string        `name:"background" placeholder:"INDEX" type:"existingfile" group:"output" help:"Score how rare each string is against a corpus index built by txtr index, from 0 (in every sample) to 1 (in none), shown before strings in text and as rarity in JSON"`
+	Sort            string        `name:"sort" enum:"offset,rarity" default:"offset" group:"output" help:"Order of the strings of each file: offset (as found) or rarity (rarest first, requires --background)"`
+	Top             int           `name:"top" placeholder:"K" default:"0" group:"output" help:"Print only the K most interesting distinct strings of all files, ranked by artifact category (URLs, commands, keys...), readability and rarity, with the reasons (text or JSON)"`
+	ErrorsJSON      string        `name:"errors-json" placeholder:"FILE" group:"output" help:"Write per-file errors and warnings to FILE as NDJSON records (type, file, severity, code, message) instead of text on stderr (- for NDJSON on stderr)"`
+	NotifyWebhook   string        `name:"notify-webhook" placeholder:"URL" group:"output" help:"POST a JSON summary of the scan (files, strings, failures, warnings) to URL when it completes"`
+	ReportTemplate  string        `name:"report-template" placeholder:"FILE" type:"existingfile" group:"output" help:"Go text/template for --format markdown reports (see the README for its data)"`
+	NotifyFormat    string        `name:"notify-format" enum:"json,slack,teams" default:"json" group:"output" help:"Payload posted by --notify-webhook: json (the summary), slack (a Slack message) or teams (a Microsoft Teams Adaptive Card)"`
+	NotifyFindings  bool          `name:"notify-findings" group:"output" help:"Include the secrets found (private keys, access tokens), masked, in the --notify-webhook summary"`
+	NotifyTimeout   time.Duration `name:"notify-timeout" placeholder:"DURATION" default:"10s" group:"output" help:"Give up posting the --notify-webhook summary after DURATION"`
+	ExecPerMatch    string        `name:"exec-per-match" placeholder:"CMD" group:"output" help:"Run CMD for each string reported, replacing {value}, {offset}, {offset_hex}, {file} and {encoding} in its arguments (not run by a shell, so strings cannot inject commands)"`
+	ExecJobs        int           `name:"exec-jobs" placeholder:"N" default:"4" group:"output" help:"Most --exec-per-match commands running at once"`
+	ExecRate        float64       `name:"exec-rate" placeholder:"N" default:"0" group:"output" help:"Most --exec-per-match commands started per second, e.g. to respect the rate limit of an API (0=unlimited)"`
+	ExecTimeout     time.Duration `name:"exec-timeout" placeholder:"DURATION" default:"30s" group:"output" help:"Kill --exec-per-match commands running longer than DURATION"`
+
+	MatchPatterns   []string `short:"m" name:"match" placeholder:"PATTERN" group:"filtering" help:"Only show strings matching pattern (can be specified multiple times)"`
+	ExcludePatterns []string `short:"M" name:"exclude" placeholder:"PATTERN" group:"filtering" help:"Exclude strings matching pattern (can be specified multiple times)"`
+	IgnoreCase      bool     `short:"i" name:"ignore-case" group:"filtering" help:"Case-insensitive pattern matching"`
+	FormatStrings   bool     `name:"format-strings" group:"filtering" help:"Only show printf-style format strings (e.g. \"open %s failed: %d\"), listing their directives in JSON"`
+	Secrets         bool     `name:"secrets" group:"filtering" help:"Only show likely credentials (private keys, AWS keys, GitHub, GitLab, Slack, Google and Stripe tokens, JWTs, connection strings with passwords), prefixed by the rule they matched (secret_rule in JSON and CSV)"`
+	MaxStrings      int      `name:"max-strings" placeholder:"N" default:"0" group:"filtering" help:"Stop reporting the strings of a file after the first N, e.g. to protect downstream systems from pathological inputs, flagging truncated_results in the JSON summary (0=unlimited)"`
+	IgnoreFiles     []string `name:"ignore-file" placeholder:"FILE" type:"existingfile" group:"filtering" help:"Drop strings matching the rules of an ignore file: exact strings, glob:PATTERN or re:REGEX per line, # comments (can be specified multiple times; see txtr check-ignores)"`
+	Grep            string   `name:"grep" placeholder:"STRING" group:"filtering" help:"Search for a literal in ASCII/UTF-8, UTF-16 and UTF-32 at once instead of extracting every string, reporting the string around each match with the encoding it was found in"`
+	FilterCommon    bool     `name:"filter-common" group:"filtering" help:"Drop strings found in the common strings database (runtime banners, loader paths, C library imports; see txtr db)"`
+
+	MinLength            int    `short:"n" name:"bytes" default:"4" group:"encoding" help:"Minimum string length"`
+	Encoding             string `short:"e" name:"encoding" enum:"s,S,b,l,B,L," default:"s" group:"encoding" help:"Character encoding (s=7-bit, S=8-bit, b=16-bit BE, l=16-bit LE, B=32-bit BE, L=32-bit LE)"`
+	Unicode              string `short:"U" name:"unicode" enum:"default,invalid,locale,escape,hex,highlight," default:"default" group:"encoding" help:"How to handle UTF-8 sequences (default/invalid/locale/escape/hex/highlight)"`
+	IncludeAllWhitespace bool   `short:"w" name:"include-all-whitespace" group:"encoding" help:"Include all whitespace characters in strings"`
+	NoBOMDetect          bool   `name:"no-bom-detect" group:"encoding" help:"Keep -e s for inputs starting with a byte order mark instead of scanning them in the UTF-8, UTF-16 or UTF-32 encoding it marks"`
+	Scripts              bool   `name:"scripts" group:"encoding" help:"Keep embedded scripts (shebang scripts, PowerShell, JavaScript and shell loaders) together as multi-line strings, tagged with script_type in JSON"`
+	Lines                bool   `name:"lines" group:"encoding" help:"Report the whole lines of plain text inputs (logs, source, configuration) with their line numbers instead of printable runs; other inputs are scanned as usual"`
+
+	ScanAll       bool     `short:"a" name:"all" group:"scan" help:"Scan entire file"`
+	ScanDataOnly  bool     `short:"d" name:"data" group:"scan" help:"Scan only initialized data sections of binary files"`
+	TargetFormat  string   `short:"T" name:"target" enum:"elf,pe,macho,binary," default:"" group:"scan" help:"Binary format hint for files whose format is not detected (elf/pe/macho/binary)"`
+	Unpack        string   `name:"unpack" enum:"never,auto,external" default:"never" group:"scan" help:"Unpack UPX-packed binaries with upx before scanning with --data (never/auto/external)"`
+	UPXPath       string   `name:"upx-path" placeholder:"FILE" default:"upx" group:"scan" help:"upx executable used by --unpack"`
+	PreFilter     string   `name:"pre-filter" placeholder:"CMD" group:"scan" help:"Decode each input with the shell command CMD, which gets the input on stdin and its file name in TXTR_FILE, and scan its output instead (e.g. proprietary containers; offsets are in the decoded data)"`
+	LiteralPools  bool     `name:"literal-pools" group:"scan" help:"Resolve ARM literal pool references to strings (requires --data and --json)"`
+	Xrefs         bool     `name:"xrefs" group:"scan" help:"Count references to each string from other sections (requires --data and --json)"`
+	DryRun        bool     `name:"dry-run" group:"scan" help:"Show which files would be scanned and how (format, strategy, workers, bytes) without extracting"`
+	Relocs        bool     `name:"relocs" group:"scan" help:"Report strings pointed to by relocation entries as reloc_strings (requires --data and --json)"`
+	Signatures    bool     `name:"signatures" group:"scan" help:"Find embedded filesystems and compressed data (SquashFS, cramfs, JFFS2, UBI, UBIFS, LZMA, gzip, xz) and report them as regions (requires --json)"`
+	ExtractFS     bool     `name:"extract-fs" group:"scan" help:"Also scan the files of embedded SquashFS, JFFS2, UBIFS and UBI filesystems one by one, named image!/path"`
+	Archives      bool     `name:"archives" group:"scan" help:"Also scan the members of ZIP, tar and tar.gz archives one by one, named archive.zip!path"`
+	Passwords     []string `name:"password" sep:"none" group:"scan" help:"Password to try on encrypted archive members (repeatable, tried in order; requires --archives)"`
+	PasswordFile  string   `name:"password-file" placeholder:"FILE" type:"existingfile" group:"scan" help:"File of passwords to try on encrypted archive members, one per line, after --password (requires --archives)"`
+	MaxDepth      int      `name:"max-depth" default:"3" group:"scan" help:"Deepest nesting of archives expanded with --archives (1 = only the members of the inputs)"`
+	MaxExpansion  float64  `name:"max-expansion" default:"200" group:"scan" help:"Largest ratio of an archive member's size to its compressed size; larger members are skipped or truncated (0 = unlimited)"`
+	MaxExtracted  string   `name:"max-extracted" placeholder:"SIZE" default:"16G" group:"scan" help:"Most data extracted from each input archive with --archives, nested archives included (e.g. 512M, 16G; 0 = unlimited)"`
+	Xattrs        bool     `name:"xattrs" group:"scan" help:"Report the extended attributes of each file (e.g. com.apple.quarantine, user.xdg.origin.url) as xattrs in JSON (Linux and macOS, requires --json)"`
+	ScanXattrs    bool     `name:"scan-xattrs" group:"scan" help:"Also scan the values of the extended attributes of files (Linux and macOS), named file@attribute"`
+	ADS           bool     `name:"ads" group:"scan" help:"Also scan the alternate data streams of files (NTFS on Windows, named file:stream) and their resource forks (macOS, named file/..namedfork/rsrc)"`
+	Registry      bool     `name:"registry" group:"scan" help:"Scan Windows registry hives by their key and value cells, reporting each string's registry_key and registry_value in JSON"`
+	Evtx          bool     `name:"evtx" group:"scan" help:"Scan Windows event logs record by record, reporting each string's event_record_id and event_time in JSON"`
+	NTFS          bool     `name:"ntfs" group:"scan" help:"Attribute the strings of NTFS partition images to the files whose names and data hold them, reporting mft_record and ntfs_path in JSON"`
+	Evidence      bool     `name:"evidence" group:"scan" help:"Scan the media held by EnCase (.E01) images and AFF4 volumes, decompressing their chunks as they are read"`
+	VirtualDisk   bool     `name:"vdisk" group:"scan" help:"Scan the disk a virtual machine sees from VMDK, QCOW2 and VHDX files, following their grain, cluster and block tables"`
+	Partitions    bool     `name:"partitions" group:"scan" help:"Report the MBR or GPT partitions of disk images as regions (requires --json)"`
+	MemoryMap     string   `name:"memory-map" placeholder:"FILE" type:"existingfile" group:"scan" help:"Attribute the strings of raw memory images to the regions of a map (Volatility 3 memmap output, /proc/iomem, or START-END LABEL lines), reporting memory_region and memory_address in JSON"`
+	MemoryProfile string   `name:"memory-profile" enum:"windows-x64,linux-x64," default:"" group:"scan" help:"Attribute the strings of raw memory images to the kernel and processes by walking the page tables found in them (windows-x64/linux-x64)"`
+	Rescan        string   `name:"rescan" placeholder:"FILE" type:"existingfile" group:"scan" help:"Scan the files of previous --json or NDJSON results again (those given, else all of them) only around the strings they list, in the format they recorded (implies --data)"`
+	RescanContext int64    `name:"rescan-context" placeholder:"BYTES" default:"256" group:"scan" help:"Bytes before and after each previous string scanned again by --rescan"`
+	Coverage      string   `name:"coverage" placeholder:"PERCENT" group:"scan" help:"Quick look at huge files: scan only windows spread over each file covering PERCENT of it (e.g. 10%), reported as sampled with the window ranges as sections (implies --data)"`
+	SampleWindow  string   `name:"sample-window" placeholder:"SIZE" default:"1M" group:"scan" help:"Size of each window scanned by --coverage (e.g. 64K, 4M)"`
+	Sampling      string   `name:"sampling" enum:"even,content" default:"even" group:"scan" help:"Where --coverage places its windows: even (at regular intervals, the first at the start of the file) or content (at content-defined boundaries near them, sampling the same data alike wherever it sits)"`
+	OnlySections  []string `name:"only-sections" placeholder:"NAMES" group:"scan" help:"Scan only the data sections of these names, e.g. .rodata,.data (requires --data or --rescan, whose ranges it replaces)"`
+	Journal       string   `name:"journal" placeholder:"FILE" type:"path" group:"scan" help:"Append a record to FILE (NDJSON: time, event, file) when each file starts scanning and when it finishes or fails, to follow long batch runs from another terminal (tail -f) and examine stalls afterwards"`
+	Resume        bool     `name:"resume" group:"scan" help:"Skip the files the --journal of an interrupted run records as finished, scanning the rest (the journal is appended to)"`
+	DedupeInputs  bool     `name:"dedupe-inputs" group:"scan" help:"Scan inputs with the same content (compared by size, then hash) once, listing the others as duplicates of the one scanned (JSON duplicates, NDJSON duplicate records)"`
+	Prefetch      bool     `name:"prefetch" group:"scan" help:"Scan Windows prefetch files (also compressed) by their executable, file and volume paths, reporting each string's prefetch field in JSON"`
+
+	Stats        bool   `name:"stats" group:"stats" help:"Output statistics summary instead of strings"`
+	StatsPerFile bool   `name:"stats-per-file" group:"stats" help:"Show per-file statistics instead of aggregated (requires --stats)"`
+	StatsTiming  bool   `name:"stats-timing" group:"stats" help:"Include wall/CPU time, bytes read and per-stage/per-worker timings in statistics (requires --stats)"`
+	Locale       string `name:"locale" placeholder:"LOCALE" group:"stats" help:"Language and number format of text statistics, e.g. de or fr_FR.UTF-8 (default: from LC_ALL, LC_MESSAGES or LANG)"`
+
+	Parallel       int           `short:"P" name:"parallel" default:"0" group:"performance" help:"Number of parallel workers (0=auto-detect CPUs, 1=sequential)"`
+	IOProfile      string        `name:"io-profile" enum:"auto,hdd,ssd,net" default:"auto" group:"performance" help:"Storage the files are on, adapting the default -P and read strategy: hdd (one reader), ssd, net (at most 4 readers, no mmap), or auto (detect)"`
+	GOMAXPROCS     int           `name:"gomaxprocs" placeholder:"N" default:"0" group:"performance" help:"Maximum number of CPUs executing Go code at once (0=Go default)"`
+	PinWorkers     bool          `name:"pin-workers" group:"performance" help:"Pin each parallel worker to one CPU, keeping its buffers NUMA-local (Linux)"`
+	CPUs           string        `name:"cpus" placeholder:"LIST" default:"" group:"performance" help:"CPUs to pin workers to, e.g. 0-31,64-95 (implies --pin-workers; -P 0 starts one worker per CPU listed)"`
+	Unordered      bool          `name:"unordered" group:"performance" help:"Print each file's strings as soon as it is scanned instead of in input order (implies -f; text output only)"`
+	DisableMmap    bool          `name:"no-mmap" group:"performance" help:"Disable memory-mapped I/O optimization"`
+	MmapThreshold  int64         `name:"mmap-threshold" default:"1048576" group:"performance" help:"Minimum file size (bytes) for using mmap (default: 1MB)"`
+	Strategy       string        `name:"strategy" enum:"auto,read,buffered,mmap,chunked" default:"auto" group:"performance" help:"How files are read: auto (by size: read whole up to 64KB, buffered below --mmap-threshold, mmap, chunked from --chunk-threshold), or one forced for all files"`
+	ChunkThreshold int64         `name:"chunk-threshold" default:"268435456" group:"performance" help:"Minimum file size (bytes) for scanning a single file in chunks with parallel workers (default: 256MB)"`
+	MaxThroughput  string        `name:"max-throughput" placeholder:"RATE" default:"" group:"performance" help:"Cap the combined read bandwidth of all workers (e.g. 100MB/s, 512K), for scanning shared storage"`
+	Retries        int           `name:"retries" placeholder:"N" default:"0" group:"performance" help:"Retry opens and reads failing with transient I/O errors (EIO, ESTALE...) up to N times, for scanning flaky network storage"`
+	RetryBackoff   time.Duration `name:"retry-backoff" placeholder:"DURATION" default:"100ms" group:"performance" help:"Wait before the first retry of --retries, doubled for each next one"`
+	MaxMemory      string        `name:"max-memory" placeholder:"SIZE" default:"" group:"performance" help:"Memory budget (e.g. 512M, 2G); streams large files/sections and spills JSON output to disk"`
+	ProfileCPU     string        `name:"profile-cpu" placeholder:"FILE" type:"path" group:"performance" help:"Write a pprof CPU profile of the run to file"`
+	ProfileMem     string        `name:"profile-mem" placeholder:"FILE" type:"path" group:"performance" help:"Write a pprof memory (allocation) profile of the run to file"`
+	Trace          string        `name:"trace" placeholder:"FILE" type:"path" group:"performance" help:"Write a runtime execution trace of the run to file"`
+
+	Version    bool     `short:"v" name:"version" help:"Display version information"`
+	VersionAlt bool     `short:"V" hidden:"" help:"Display version information (alias)"`
+	Files      []string `arg:"" optional:"" name:"file" help:"Files to extract strings from" type:"path"`
 }
 
 // job represents a file processing job with its position in the input list
 type job struct {
 	filename string
 	index    int
+	size     int64 // File size for scheduling (0 if unknown)
+}
+
+// scheduleJobs returns the jobs for files, largest first. Workers take jobs
+// from a shared queue as they become free, so starting big files early keeps
+// one large file from running alone at the end while the others idle. Files
+// that cannot be stat'ed are scheduled last; their errors are reported when
+// processed.
+func scheduleJobs(files []string) []job {
+	jobs := make([]job, len(files))
+	for i, filename := range files {
+		jobs[i] = job{filename: filename, index: i}
+		if info, err := os.Stat(filename); err == nil {
+			jobs[i].size = info.Size()
+		}
+	}
+	slices.SortStableFunc(jobs, func(a, b job) int {
+		return cmp.Compare(b.size, a.size)
+	})
+	return jobs
 }
 
 // result represents the output from processing a file
@@ -68,22 +210,60 @@ type result struct {
 
 // jsonFileResult represents the result from processing a file for JSON output
 type jsonFileResult struct {
-	index    int
-	filename string
-	format   string
-	sections []string
-	strings  []printer.StringResult
-	err      error
+	index        int
+	filename     string
+	format       string
+	sections     []string
+	strategy     string
+	strings      []printer.StringResult
+	relocStrings []printer.StringResult
+	clusters     []printer.OffsetCluster
+	layout       []printer.SectionRange
+	regions      []printer.Region
+	xattrs       []printer.Xattr
+	warnings     []extractor.Warning
+	err          error
 }
 
 func main() {
+	// Subcommands are dispatched before flag parsing so that file arguments
+	// keep working as before (use ./update, ./man, ./cluster, ./db, ./check-ignores,
+	// ./find, ./index, ./query, ./serve, ./remote, ./tune or ./conformance to scan files
+	// with those names)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "update":
+			os.Exit(runUpdate(os.Args[2:]))
+		case "cluster":
+			os.Exit(runCluster(os.Args[2:]))
+		case "man":
+			os.Exit(runMan(os.Args[2:]))
+		case "db":
+			os.Exit(runDB(os.Args[2:]))
+		case "check-ignores":
+			os.Exit(runCheckIgnores(os.Args[2:]))
+		case "find":
+			os.Exit(runFind(os.Args[2:]))
+		case "index":
+			os.Exit(runIndex(os.Args[2:]))
+		case "query":
+			os.Exit(runQuery(os.Args[2:]))
+		case "serve":
+			os.Exit(runServe(os.Args[2:]))
+		case "remote":
+			os.Exit(runRemote(os.Args[2:]))
+		case "search-schema":
+			os.Exit(runSearchSchema(os.Args[2:]))
+		case "tune":
+			os.Exit(runTune(os.Args[2:]))
+		case "conformance":
+			os.Exit(runConformance(os.Args[2:]))
+		}
+	}
+
 	var cli CLI
 
-	kong.Parse(&cli,
-		kong.Name("txtr"),
-		kong.Description("Extract printable strings from binary files. GNU strings compatible."),
-		kong.UsageOnError(),
-	)
+	kong.Parse(&cli, cliOptions()...)
 
 	// Handle version flag
 	if cli.Version || cli.VersionAlt {
@@ -117,38 +297,213 @@ func main() {
 		outputSep = "\r"
 	}
 
-	// Validate -d flag can only be used with files, not stdin
-	if cli.ScanDataOnly && len(cli.Files) == 0 {
-		fmt.Fprintf(os.Stderr, "error: -d/--data flag requires file arguments (cannot be used with stdin)\n")
+	// Scan the files of previous results again, around their strings
+	var rescanPlan *rescan.Plan
+	if cli.Rescan != "" {
+		var err error
+		if rescanPlan, err = rescan.Load(cli.Rescan, cli.RescanContext); err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid --rescan results: %v\n", err)
+			os.Exit(1)
+		}
+		if len(cli.Files) == 0 {
+			cli.Files = rescanPlan.Names()
+		}
+		cli.ScanDataOnly = true
+	}
+
+	// Sample windows of each file instead of scanning it whole
+	var sampling sample.Options
+	if cli.Coverage != "" {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(cli.Coverage, "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			fmt.Fprintf(os.Stderr, "error: invalid --coverage value %q (a percentage above 0, e.g. 10%%)\n", cli.Coverage)
+			os.Exit(1)
+		}
+		window, err := parseByteSize(cli.SampleWindow)
+		if err != nil || window <= 0 {
+			fmt.Fprintf(os.Stderr, "error: invalid --sample-window value %q (examples: 64K, 4M)\n", cli.SampleWindow)
+			os.Exit(1)
+		}
+		sampling = sample.Options{Percent: percent, Window: window, Mode: sample.Mode(cli.Sampling)}
+		cli.ScanDataOnly = true
+	}
+
+	// Open files named after Windows devices (aux.c) rather than the device
+	for i, file := range cli.Files {
+		cli.Files[i] = winpath.Input(file)
+	}
+
+	// Parse additional output sinks
+	sinkSpecs, err := parseSinkSpecs(cli.Output)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid --output: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Resolve output format and validate output option combinations
+	mode, err := resolveOutputMode(outputOptions{
+		Stdin:        len(cli.Files) == 0,
+		Formats:      cli.Format,
+		OutputPrefix: cli.OutputPrefix != "",
+		JSON:         cli.JSON,
+		Stats:        cli.Stats,
+		StatsPerFile: cli.StatsPerFile,
+		StatsTiming:  cli.StatsTiming,
+		ScanDataOnly: cli.ScanDataOnly,
+		LiteralPools: cli.LiteralPools,
+		Xrefs:        cli.Xrefs,
+		Relocs:       cli.Relocs,
+		Signatures:   cli.Signatures,
+		Partitions:   cli.Partitions,
+		OnlySections: len(cli.OnlySections) > 0,
+		Rescan:       cli.Rescan != "",
+		Coverage:     cli.Coverage != "",
+		ExtractFS:    cli.ExtractFS,
+		Archives:     cli.Archives,
+		ADS:          cli.ADS,
+		Xattrs:       cli.Xattrs,
+		ScanXattrs:   cli.ScanXattrs,
+		Artifacts:    cli.Registry || cli.Evtx || cli.Prefetch || cli.NTFS || cli.MemoryMap != "" || cli.MemoryProfile != "",
+		Outputs:      len(cli.Output) > 0,
+		Unique:       cli.Unique || cli.Count || cli.DedupeFoldCase,
+		FoldCase:     cli.DedupeFoldCase,
+		ValueEncoded: cli.ValueEncoding != "utf8",
+		EmitRaw:      cli.EmitRaw,
+		WideInfo:     cli.WideInfo,
+		AllOffsets:   cli.AllOffsets,
+		MaxMemory:    cli.MaxMemory != "",
+		Grep:         cli.Grep != "",
+		Unordered:    cli.Unordered,
+		Lines:        cli.Lines,
+		Background:   cli.Background != "",
+		SortRarity:   cli.Sort == "rarity",
+		Top:          cli.Top,
+		ClusterGap:   cli.ClusterByOffset,
+		Notify:       cli.NotifyWebhook != "",
+		Exec:         cli.ExecPerMatch != "",
+		Journal:      cli.Journal != "",
+		Resume:       cli.Resume,
+		PreFilter:    cli.PreFilter != "",
+		Media:        cli.Evidence || cli.VirtualDisk,
+		Passwords:    len(cli.Passwords) > 0 || cli.PasswordFile != "",
+		MaxDepth:     cli.MaxDepth,
+		MaxExpansion: cli.MaxExpansion,
+		Scripts:      cli.Scripts,
+		Template:     cli.ReportTemplate != "",
+		Markdown:     slices.ContainsFunc(sinkSpecs, func(spec sinkSpec) bool { return spec.Kind == sinkMarkdown }),
+		ByteText:     (cli.Encoding == "s" || cli.Encoding == "S") && (cli.Unicode == "default" || cli.Unicode == "invalid" || cli.Unicode == ""),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Skip the files an interrupted run finished
+	if cli.Resume {
+		finished, err := finishedFiles(cli.Journal)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: cannot read --journal: %v\n", err)
+			os.Exit(1)
+		}
+		remaining := cli.Files[:0]
+		for _, file := range cli.Files {
+			if !finished[file] {
+				remaining = append(remaining, file)
+			}
+		}
+		if len(remaining) == 0 && len(cli.Files) > 0 {
+			fmt.Fprintf(os.Stderr, "strings: all files are finished in %s, nothing to resume\n", cli.Journal)
+			return
+		}
+		cli.Files = remaining
+	}
+
+	// Write the further --format values to files
+	sinkSpecs, err = extraFormatSpecs(mode, cli.OutputPrefix, sinkSpecs)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid --output-prefix: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Validate --stats-per-file requires --stats
-	if cli.StatsPerFile && !cli.Stats {
-		fmt.Fprintf(os.Stderr, "error: --stats-per-file requires --stats flag\n")
+	// Load the template of Markdown reports
+	var reportTemplate *template.Template
+	if cli.ReportTemplate != "" {
+		text, err := os.ReadFile(cli.ReportTemplate)
+		if err == nil {
+			reportTemplate, err = printer.ParseReportTemplate(filepath.Base(cli.ReportTemplate), string(text))
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid --report-template: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Post a summary when the scan completes
+	notify := notifyOptions{URL: cli.NotifyWebhook, Format: cli.NotifyFormat, Findings: cli.NotifyFindings, Timeout: cli.NotifyTimeout}
+	if err := notify.validate(mode.Format == formatSlack || mode.Format == formatTeams); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	execPerMatch := execOptions{Command: cli.ExecPerMatch, Jobs: cli.ExecJobs, Rate: cli.ExecRate, Timeout: cli.ExecTimeout}
+	if err := execPerMatch.validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Report errors and warnings as NDJSON
+	if cli.ErrorsJSON != "" {
+		if err := openErrorsJSON(cli.ErrorsJSON); err != nil {
+			fmt.Fprintf(os.Stderr, "error: cannot create --errors-json file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Parse memory budget
+	maxMemory, err := parseByteSize(cli.MaxMemory)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid --max-memory value: %v\n", err)
 		os.Exit(1)
 	}
+	var throttle *extractor.Throttle
+	if cli.MaxThroughput != "" {
+		rate, err := parseByteSize(strings.TrimSuffix(strings.ToLower(cli.MaxThroughput), "/s"))
+		if err != nil || rate == 0 {
+			fmt.Fprintf(os.Stderr, "error: invalid --max-throughput value %q (examples: 100MB/s, 512K)\n", cli.MaxThroughput)
+			os.Exit(1)
+		}
+		throttle = extractor.NewThrottle(rate)
+	}
 
-	// Validate --stats and --json cannot be used together (for now)
-	if cli.Stats && cli.JSON {
-		fmt.Fprintf(os.Stderr, "error: --stats and --json cannot be used together (use one or the other)\n")
+	// Retry transient I/O errors
+	var retry *extractor.Retry
+	if cli.Retries < 0 || cli.RetryBackoff < 0 {
+		fmt.Fprintf(os.Stderr, "error: --retries and --retry-backoff cannot be negative\n")
 		os.Exit(1)
 	}
+	if cli.Retries > 0 {
+		retry = extractor.NewRetry(cli.Retries, cli.RetryBackoff)
+	}
 
 	// Parse color mode
-	var colorMode extractor.ColorMode
-	switch cli.Color {
-	case "always":
-		colorMode = extractor.ColorAlways
-	case "never":
-		colorMode = extractor.ColorNever
-	default: // "auto" or empty
-		colorMode = extractor.ColorAuto
+	colorMode := parseColorMode(cli.Color)
+	if cli.Unicode == "highlight" {
+		// -U highlight writes escape sequences whatever --color says; colors
+		// and hyperlinks enable them when they are used (see printer)
+		console.VirtualTerminal()
+	}
+
+	// Resolve the locale of text statistics
+	locale := stats.LocaleFromEnv()
+	if cli.Locale != "" {
+		locale, err = stats.ParseLocale(cli.Locale)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid --locale value: %v\n", err)
+			os.Exit(1)
+		}
 	}
 
 	// Compile regex patterns
 	var matchPatterns, excludePatterns []*regexp.Regexp
-	var err error
 
 	if len(cli.MatchPatterns) > 0 {
 		matchPatterns, err = extractor.CompilePatterns(cli.MatchPatterns, cli.IgnoreCase)
@@ -166,55 +521,279 @@ func main() {
 		}
 	}
 
+	// Load ignore lists
+	var ignoreList *ignore.List
+	if len(cli.IgnoreFiles) > 0 {
+		ignoreList, err = ignore.Load(cli.IgnoreFiles...)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid ignore file: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load the common strings database
+	var commonStrings *noise.Database
+	if cli.FilterCommon {
+		commonStrings, _, err = noise.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: cannot load common strings database: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load the corpus index scoring rarity
+	var background *bloom.Index
+	if cli.Background != "" {
+		background, err = bloom.Load(cli.Background)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: cannot load --background index: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
 	// Build config from CLI args
 	config := extractor.Config{
 		MinLength:            cli.MinLength,
-		PrintFileName:        cli.PrintFileName,
+		PrintFileName:        cli.PrintFileName || cli.Unordered,
 		Radix:                cli.Radix,
 		PrintOffset:          cli.Radix != "",
 		Encoding:             cli.Encoding,
 		Unicode:              cli.Unicode,
+		NoBOMDetect:          cli.NoBOMDetect || cli.Rescan != "" || sampling.Percent > 0,
 		OutputSeparator:      outputSep,
 		IncludeAllWhitespace: cli.IncludeAllWhitespace,
+		Scripts:              cli.Scripts,
+		Lines:                cli.Lines,
 		ScanAll:              cli.ScanAll,
 		ScanDataOnly:         cli.ScanDataOnly,
 		TargetFormat:         cli.TargetFormat,
+		Unpack:               cli.Unpack,
+		UPXPath:              cli.UPXPath,
+		PreFilter:            cli.PreFilter,
 		ColorMode:            colorMode,
+		Sanitize:             parseColorMode(cli.Sanitize),
+		EscapeNonPrint:       cli.EscapeNonPrint,
+		MaxWidth:             cli.MaxWidth,
+		Hyperlinks:           cli.Hyperlinks,
+		ClusterGap:           cli.ClusterByOffset,
+		ReportTemplate:       reportTemplate,
+		Unordered:            cli.Unordered,
+		Locale:               locale,
 		MatchPatterns:        matchPatterns,
 		ExcludePatterns:      excludePatterns,
+		FormatStrings:        cli.FormatStrings,
+		Secrets:              cli.Secrets,
+		MaxStrings:           cli.MaxStrings,
+		Unique:               cli.Unique || cli.Count || cli.DedupeFoldCase,
+		Count:                cli.Count,
+		FoldCase:             cli.DedupeFoldCase,
+		ValueEncoding:        cli.ValueEncoding,
+		EmitRaw:              cli.EmitRaw,
+		WideInfo:             cli.WideInfo,
+		AllOffsets:           cli.AllOffsets,
+		MaxOffsets:           cli.MaxOffsets,
+		IgnoreList:           ignoreList,
+		CommonStrings:        commonStrings,
+		Background:           background,
+		SortRarity:           cli.Sort == "rarity",
 		DisableMmap:          cli.DisableMmap,
 		MmapThreshold:        cli.MmapThreshold,
+		ChunkThreshold:       cli.ChunkThreshold,
+		LiteralPools:         cli.LiteralPools,
+		Xrefs:                cli.Xrefs,
+		Relocs:               cli.Relocs,
+		Signatures:           cli.Signatures,
+		ExtractFS:            cli.ExtractFS,
+		Archives:             cli.Archives,
+		ADS:                  cli.ADS,
+		Xattrs:               cli.Xattrs,
+		ScanXattrs:           cli.ScanXattrs,
+		Registry:             cli.Registry,
+		Evtx:                 cli.Evtx,
+		Prefetch:             cli.Prefetch,
+		NTFS:                 cli.NTFS,
+		Evidence:             cli.Evidence,
+		VirtualDisk:          cli.VirtualDisk,
+		Partitions:           cli.Partitions,
+		MemoryMap:            cli.MemoryMap,
+		MemoryProfile:        cli.MemoryProfile,
+		Rescan:               rescanPlan,
+		Sampling:             sampling,
+		OnlySections:         cli.OnlySections,
+		MaxMemory:            maxMemory,
+		Throttle:             throttle,
+		Retry:                retry,
+		Warnings:             reportWarning,
+	}
+
+	// CPU scheduling knobs
+	if cli.GOMAXPROCS > 0 {
+		runtime.GOMAXPROCS(cli.GOMAXPROCS)
+	}
+	config.Passwords = cli.Passwords
+	if cli.PasswordFile != "" {
+		passwords, err := readPasswords(cli.PasswordFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: invalid --password-file: %v\n", err)
+			os.Exit(1)
+		}
+		config.Passwords = append(config.Passwords, passwords...)
+	}
+	maxExtracted, err := parseByteSize(cli.MaxExtracted)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid --max-extracted value: %v\n", err)
+		os.Exit(1)
+	}
+	config.ArchiveLimits = archive.Limits{MaxDepth: cli.MaxDepth, MaxExpansion: cli.MaxExpansion, MaxExtracted: maxExtracted}
+	if cli.ADS && !forks.Supported {
+		fmt.Fprintf(os.Stderr, "error: --ads is only supported on Windows and macOS\n")
+		os.Exit(1)
+	}
+	if (cli.Xattrs || cli.ScanXattrs) && !xattr.Supported {
+		fmt.Fprintf(os.Stderr, "error: --xattrs and --scan-xattrs are only supported on Linux and macOS\n")
+		os.Exit(1)
+	}
+	if cli.PinWorkers || cli.CPUs != "" {
+		if !affinity.Supported {
+			fmt.Fprintf(os.Stderr, "error: --pin-workers and --cpus are only supported on Linux\n")
+			os.Exit(1)
+		}
+		if cli.CPUs != "" {
+			config.WorkerCPUs, err = affinity.ParseCPUList(cli.CPUs)
+		} else {
+			config.WorkerCPUs, err = affinity.Allowed()
+		}
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	cpus := runtime.NumCPU()
+	if cli.CPUs != "" {
+		cpus = len(config.WorkerCPUs)
+	}
+
+	// Scan each distinct content once
+	if cli.DedupeInputs {
+		cli.Files, config.Duplicates = dedupe.Files(cli.Files)
+		for _, original := range cli.Files {
+			for _, duplicate := range config.Duplicates[original] {
+				reportWarning(extractor.Warning{Severity: extractor.SeverityInfo, Code: extractor.WarnDuplicate, File: duplicate,
+					Message: fmt.Sprintf("same content as %s, not scanned", original)})
+			}
+		}
+	}
+
+	// Adapt defaults to the storage the files are on
+	ioProfile := storage.Profile(cli.IOProfile)
+	if ioProfile == storage.ProfileAuto {
+		ioProfile = storage.DetectFiles(cli.Files)
+	}
+	if ioProfile.DisableMmap() {
+		config.DisableMmap = true
 	}
 
 	// Determine number of parallel workers
 	workers := cli.Parallel
 	if workers == 0 {
-		workers = runtime.NumCPU()
+		workers = ioProfile.Workers(cpus)
+	}
+
+	// Read strategy; the workers scan the chunks of huge files and the members
+	// of archives if there is a single file to share them
+	if cli.Strategy != "auto" {
+		config.Strategy = extractor.Strategy(cli.Strategy)
+	}
+	if len(cli.Files) <= 1 || config.Strategy == extractor.StrategyChunked {
+		config.ChunkWorkers = workers
+	}
+
+	// Dry run: report the plan without extracting
+	if cli.DryRun {
+		if len(cli.Files) == 0 {
+			fmt.Fprintf(os.Stderr, "error: --dry-run requires file arguments (cannot be used with stdin)\n")
+			os.Exit(1)
+		}
+
+		planMode := mode.Format
+		if mode.Stats {
+			planMode = "stats"
+		}
+
+		plan := buildPlan(cli.Files, workers, config, planMode)
+		plan.IOProfile = string(ioProfile)
+		if mode.Format == formatJSON {
+			if err := writePlanJSON(os.Stdout, plan); err != nil {
+				fmt.Fprintf(os.Stderr, "error: %v\n", err)
+				os.Exit(1)
+			}
+		} else {
+			writePlan(os.Stdout, plan)
+		}
+		if plan.Errors > 0 {
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Start profiling if requested
+	prof, err := startProfiling(cli.ProfileCPU, cli.ProfileMem, cli.Trace)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		os.Exit(1)
+	}
+	defer prof.stop()
+
+	// Record the progress of the run
+	var journal *scanJournal
+	if cli.Journal != "" {
+		journal, err = openJournal(cli.Journal)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: cannot open --journal: %v\n", err)
+			os.Exit(1)
+		}
+		defer func() { _ = journal.Close() }()
 	}
 
 	// Process files or stdin
-	if cli.Stats {
+	if cli.Grep != "" {
+		// Literal search across encodings
+		processWithGrep(cli.Files, workers, cli.Grep, config, mode.Format)
+	} else if len(sinkSpecs) > 0 || notify.URL != "" || execPerMatch.Args != nil || mode.Format == formatSlack || mode.Format == formatTeams || journal != nil || config.PreFilter != "" || mode.Top > 0 || mode.Format == formatText && cli.ClusterByOffset > 0 || cli.ExtractFS || cli.Archives || cli.ADS || cli.ScanXattrs || cli.Registry || cli.Evtx || cli.Prefetch || cli.NTFS || cli.MemoryMap != "" || cli.MemoryProfile != "" {
+		// Fan out one scan to stdout and the --output sinks (--top ranks
+		// the strings of all files on stdout, --cluster-by-offset groups them,
+		// --extract-fs adds the files of embedded filesystems, --archives
+		// the members of archives, --ads the
+		// alternate data streams of files, --scan-xattrs their extended
+		// attributes, --registry, --evtx, --prefetch and --ntfs parse
+		// forensic artifacts, --memory-map and --memory-profile attribute
+		// memory images, --notify-webhook and --format slack and teams
+		// summarize the scan, --exec-per-match runs a command per string,
+		// --journal records its progress, --pre-filter decodes the inputs)
+		processWithSinks(cli.Files, workers, config, mode, sinkSpecs, notify, execPerMatch, journal)
+	} else if mode.Stats {
 		// Statistics output mode
-		processWithStats(cli.Files, workers, config, cli.StatsPerFile)
-	} else if cli.JSON {
-		// JSON output mode
-		processWithJSON(cli.Files, workers, config)
+		processWithStats(cli.Files, workers, config, mode)
+	} else if mode.Format != formatText {
+		// Structured (JSON/CSV/CycloneDX/rizin/disassembler script/report/graph) output mode
+		processWithJSON(cli.Files, workers, config, mode.Format)
 	} else if len(cli.Files) == 0 {
 		// Read from stdin
-		extractor.ExtractStrings(os.Stdin, "", config, printer.PrintString)
+		extractStrings(os.Stdin, "", config, printer.NewTextPrinter(os.Stdout, config).PrintString)
 	} else if len(cli.Files) > 1 && workers > 1 {
 		// Process multiple files in parallel
 		processFilesParallel(cli.Files, workers, config)
 	} else {
 		// Process each file sequentially (single file or workers=1)
+		stdout := printer.NewTextPrinter(os.Stdout, config)
 		for _, filename := range cli.Files {
 			if config.ScanDataOnly {
 				// Parse binary and extract from data sections only
-				processFileWithBinaryParsing(filename, config)
+				processFileWithBinaryParsing(filename, config, stdout.PrintString)
 			} else {
 				// Regular full-file scanning with automatic mmap optimization
-				if err := extractor.ExtractStringsFromFile(filename, config, printer.PrintString); err != nil {
-					fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
+				if err := extractor.ExtractStringsFromFile(filename, config, stdout.PrintString); err != nil {
+					reportError(filename, err)
 					continue
 				}
 			}
@@ -222,22 +801,28 @@ func main() {
 	}
 }
 
-// processWithJSON processes files or stdin with JSON output
+// processWithJSON processes files or stdin with JSON (or CSV, CycloneDX, rizin,
+// disassembler script, report or graph) output
 // Supports parallel processing for multiple files with automatic error handling
-func processWithJSON(files []string, workers int, config extractor.Config) {
+func processWithJSON(files []string, workers int, config extractor.Config, format string) {
 	var jsonPrinter *printer.JSONPrinter
 
 	if len(files) == 0 {
 		// Read from stdin
 		jsonPrinter = printer.NewJSONPrinter(config, os.Stdout)
+		jsonPrinter.SetMemoryLimit(config.MaxMemory)
 		jsonPrinter.SetFileInfo("", "", nil)
-		extractor.ExtractStrings(os.Stdin, "", config, jsonPrinter.PrintString)
-	} else if len(files) > 1 && workers > 1 {
-		// Process multiple files in parallel
+		config.Warnings = warnToJSON(jsonPrinter)
+		extractStrings(os.Stdin, "", config, jsonPrinter.PrintString)
+	} else if len(files) > 1 && workers > 1 && config.MaxMemory == 0 {
+		// Process multiple files in parallel (workers buffer whole files,
+		// so a memory budget forces sequential processing)
 		jsonPrinter = processFilesParallelJSON(files, workers, config)
 	} else {
-		// Process files sequentially (single file or workers=1)
+		// Process files sequentially (single file, workers=1 or memory budget)
 		jsonPrinter = printer.NewJSONPrinter(config, os.Stdout)
+		jsonPrinter.SetMemoryLimit(config.MaxMemory)
+		config.Warnings = warnToJSON(jsonPrinter)
 
 		for _, filename := range files {
 			if config.ScanDataOnly {
@@ -246,17 +831,92 @@ func processWithJSON(files []string, workers int, config extractor.Config) {
 			} else {
 				// Regular full-file scanning with automatic mmap optimization
 				jsonPrinter.SetFileInfo(filename, "", nil)
+				jsonPrinter.SetStrategy(string(extractor.ChooseStrategy(filename, config)))
 				if err := extractor.ExtractStringsFromFile(filename, config, jsonPrinter.PrintString); err != nil {
-					fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
+					reportError(filename, err)
 					// Add error result to JSON
 					jsonPrinter.AddFileResult(filename, "", nil, nil, err)
 					continue
 				}
 			}
+			jsonPrinter.SetRegions(findRegions(filename, config))
+			jsonPrinter.SetXattrs(findXattrs(filename, config))
 		}
 	}
 
-	// Flush JSON output
+	// Flush collected output
+	flushStructured(jsonPrinter, format)
+}
+
+// warnToJSON returns a Config.Warnings callback that reports warnings (see
+// reportWarning) and adds them to the file results of jsonPrinter
+func warnToJSON(jsonPrinter *printer.JSONPrinter) func(extractor.Warning) {
+	return func(w extractor.Warning) {
+		reportWarning(w)
+		jsonPrinter.AddWarning(w)
+	}
+}
+
+// flushStructured writes the collected results in the structured format
+// (JSON, CSV, CycloneDX, rizin or a disassembler script), exiting on write errors
+func flushStructured(jsonPrinter *printer.JSONPrinter, format string) {
+	if format == formatCSV {
+		if err := jsonPrinter.FlushCSV(); err != nil {
+			fmt.Fprintf(os.Stderr, "strings: error writing CSV output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if format == formatCycloneDX {
+		if err := jsonPrinter.FlushCycloneDX(version); err != nil {
+			fmt.Fprintf(os.Stderr, "strings: error writing CycloneDX output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if format == formatGhidra || format == formatIDAPython {
+		flush := jsonPrinter.FlushGhidra
+		if format == formatIDAPython {
+			flush = jsonPrinter.FlushIDAPython
+		}
+		if err := flush(version); err != nil {
+			fmt.Fprintf(os.Stderr, "strings: error writing %s script: %v\n", format, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if format == formatDOT || format == formatMermaid {
+		flush := jsonPrinter.FlushDOT
+		if format == formatMermaid {
+			flush = jsonPrinter.FlushMermaid
+		}
+		if err := flush(version); err != nil {
+			fmt.Fprintf(os.Stderr, "strings: error writing relationship graph: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if format == formatMarkdown {
+		if err := jsonPrinter.FlushMarkdown(version); err != nil {
+			fmt.Fprintf(os.Stderr, "strings: error writing Markdown report: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if format == formatHTML {
+		if err := jsonPrinter.FlushHTML(version); err != nil {
+			fmt.Fprintf(os.Stderr, "strings: error writing HTML report: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+	if format == formatRizin {
+		if err := jsonPrinter.FlushRizin(); err != nil {
+			fmt.Fprintf(os.Stderr, "strings: error writing rizin output: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
 	if err := jsonPrinter.Flush(); err != nil {
 		fmt.Fprintf(os.Stderr, "strings: error writing JSON output: %v\n", err)
 		os.Exit(1)
@@ -265,51 +925,37 @@ func processWithJSON(files []string, workers int, config extractor.Config) {
 
 // processFileWithBinaryParsingJSON handles binary parsing with JSON output
 func processFileWithBinaryParsingJSON(filename string, config extractor.Config, jsonPrinter *printer.JSONPrinter) {
-	// Determine format
-	var format binary.Format
-	var err error
-
-	if config.TargetFormat != "" && config.TargetFormat != "binary" {
-		// User specified a format
-		switch config.TargetFormat {
-		case "elf":
-			format = binary.FormatELF
-		case "pe":
-			format = binary.FormatPE
-		case "macho":
-			format = binary.FormatMachO
-		default:
-			format = binary.FormatRaw
-		}
-	} else {
-		// Auto-detect format
-		format, err = binary.DetectFormat(filename)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
-			os.Exit(1)
-		}
+	// Determine format (-T only applies to files that are not detected)
+	format, source, err := resolveFormat(filename, config)
+	if err != nil {
+		reportError(filename, err)
+		os.Exit(1)
 	}
 
+	packing, path, format, cleanup, err := maybeUnpack(filename, format, config)
+	if err != nil {
+		reportError(filename, err)
+		os.Exit(1)
+	}
+	defer cleanup()
+
 	// Parse binary to get sections
-	sections, err := binary.ParseBinary(filename, format)
+	sections, err := loadSections(path, format, config)
 	if err != nil {
 		// Fall back to regular scanning if parsing fails
-		fmt.Fprintf(os.Stderr, "strings: %s: warning: cannot parse as %v, falling back to full scan: %v\n",
-			filename, format, err)
+		warnParseFallback(filename, format, err, config)
 
-		file, err := os.Open(filename)
+		file, err := os.Open(path)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
+			reportError(filename, err)
 			os.Exit(1)
 		}
-		defer func() {
-			if err := file.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "strings: %s: error closing file: %v\n", filename, err)
-			}
-		}()
+		defer closeInput(file, filename, config)
 
 		jsonPrinter.SetFileInfo(filename, format.String(), nil)
-		extractor.ExtractStrings(file, filename, config, jsonPrinter.PrintString)
+		jsonPrinter.SetFormatSource(string(source))
+		jsonPrinter.SetPacking(packing)
+		extractStrings(file, filename, config, jsonPrinter.PrintString)
 		return
 	}
 
@@ -321,128 +967,432 @@ func processFileWithBinaryParsingJSON(filename string, config extractor.Config,
 
 	// Set file info
 	jsonPrinter.SetFileInfo(filename, format.String(), sectionNames)
+	jsonPrinter.SetFormatSource(string(source))
+	jsonPrinter.SetPacking(packing)
+	jsonPrinter.SetSectionLayout(sectionLayout(sections))
+	attachReferences(jsonPrinter, path, filename, format, config)
 
 	// If no sections found (raw binary), scan the whole file
 	if len(sections) == 0 {
-		file, err := os.Open(filename)
+		file, err := os.Open(path)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
+			reportError(filename, err)
 			os.Exit(1)
 		}
-		defer func() {
-			if err := file.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "strings: %s: error closing file: %v\n", filename, err)
-			}
-		}()
+		defer closeInput(file, filename, config)
 
-		extractor.ExtractStrings(file, filename, config, jsonPrinter.PrintString)
+		extractStrings(file, filename, config, jsonPrinter.PrintString)
 		return
 	}
 
 	// Extract strings from each data section
-	for _, section := range sections {
-		extractor.ExtractFromSection(section.Data, section.Name, section.Offset, filename, config, jsonPrinter.PrintString)
+	extractSections(sections, path, filename, config, jsonPrinter.PrintString)
+	extractRelocStrings(sections, path, filename, format, config, jsonPrinter.PrintRelocString)
+}
+
+// parseColorMode converts a --color (or --sanitize) value to a color mode
+func parseColorMode(value string) extractor.ColorMode {
+	switch value {
+	case "always":
+		return extractor.ColorAlways
+	case "never":
+		return extractor.ColorNever
+	default: // "auto" or empty
+		return extractor.ColorAuto
 	}
 }
 
-// processFileWithBinaryParsing handles binary format detection and section extraction
-func processFileWithBinaryParsing(filename string, config extractor.Config) {
-	// Determine format
-	var format binary.Format
-	var err error
+// parseByteSize parses a size such as "2G", "512M", "64k" or "1048576" into
+// bytes (binary units). An empty string means no limit.
+func parseByteSize(s string) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
 
-	if config.TargetFormat != "" && config.TargetFormat != "binary" {
-		// User specified a format
-		switch config.TargetFormat {
-		case "elf":
-			format = binary.FormatELF
-		case "pe":
-			format = binary.FormatPE
-		case "macho":
-			format = binary.FormatMachO
-		default:
-			format = binary.FormatRaw
+	// Strip optional "B"/"iB" unit suffix (e.g. "2GB", "2GiB")
+	upper := strings.ToUpper(s)
+	upper = strings.TrimSuffix(upper, "IB")
+	if len(upper) == len(s) {
+		upper = strings.TrimSuffix(upper, "B")
+	}
+
+	multiplier := int64(1)
+	if upper != "" {
+		switch upper[len(upper)-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
 		}
-	} else {
-		// Auto-detect format
-		format, err = binary.DetectFormat(filename)
+		if multiplier > 1 {
+			upper = upper[:len(upper)-1]
+		}
+	}
+
+	n, err := strconv.ParseInt(upper, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("%q is not a valid size (examples: 512M, 2G)", s)
+	}
+	if n > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("%q is too large", s)
+	}
+
+	return n * multiplier, nil
+}
+
+// workerConfig returns the config for one of workers parallel workers,
+// splitting the memory budget between them
+func workerConfig(config extractor.Config, workers int) extractor.Config {
+	if config.MaxMemory > 0 && workers > 1 {
+		config.MaxMemory = max(config.MaxMemory/int64(workers), 1)
+	}
+	return config
+}
+
+// pinWorker pins the calling worker goroutine to its CPU (--pin-workers). The
+// goroutine stays locked to its thread, which exits with it.
+func pinWorker(config extractor.Config, worker int) {
+	if len(config.WorkerCPUs) == 0 {
+		return
+	}
+	runtime.LockOSThread()
+	cpu := affinity.WorkerCPU(config.WorkerCPUs, worker)
+	if err := affinity.Pin(cpu); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: cannot pin worker %d to CPU %d: %v\n", worker, cpu, err)
+	}
+}
+
+// attachReferences resolves code references (--literal-pools) and cross-reference
+// counts (--xrefs) for a parsed binary read from path and attaches them to the
+// JSON printer's current file. If the binary has a symbol table, referenced
+// strings are also attributed to the function containing their first reference.
+func attachReferences(jsonPrinter *printer.JSONPrinter, path, filename string, format binary.Format, config extractor.Config) {
+	var refs *binary.ReferenceIndex
+	if config.LiteralPools && format == binary.FormatELF {
+		index, err := binary.ResolveLiteralPools(path)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
-			return
+			extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnLiteralPools, File: filename,
+				Message: "cannot resolve literal pools", Err: err})
+		} else {
+			jsonPrinter.SetReferenceResolver(index.Lookup)
+			refs = index
+		}
+	}
+
+	if config.Xrefs {
+		index, err := binary.ScanPointerRefs(path, format)
+		if err != nil {
+			extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnXrefs, File: filename,
+				Message: "cannot scan cross-references", Err: err})
+		} else {
+			jsonPrinter.SetXrefCounter(index.Count)
+			if refs == nil {
+				refs = index
+			}
+		}
+	}
+
+	if refs == nil {
+		return
+	}
+	// Stripped binaries have no function names to report
+	functions, err := binary.LoadFunctions(path, format)
+	if err != nil {
+		return
+	}
+	jsonPrinter.SetFunctionResolver(func(offset int64) string {
+		for _, addr := range refs.Lookup(offset) {
+			if name, ok := functions.Lookup(addr); ok {
+				return name
+			}
+		}
+		return ""
+	})
+}
+
+// loadSections parses the data sections of a binary, only those named by
+// --only-sections if given (see onlySections). With --rescan, a file the
+// previous scan found strings in has the ranges around them instead (see
+// rescanSections), and with --coverage the windows it samples (see
+// sampleSections). If their combined size exceeds the memory
+// budget, only the section headers are loaded and extractSections streams the
+// contents from the file instead.
+func loadSections(filename string, format binary.Format, config extractor.Config) ([]binary.Section, error) {
+	if sections, ok := rescanSections(filename, config); ok {
+		return sections, nil
+	}
+	if sections, size, ok := sampleSections(filename, config); ok {
+		warnSampled(filename, sections, size, config)
+		return sections, nil
+	}
+	sections, err := loadDataSections(filename, format, config)
+	if err != nil {
+		return nil, err
+	}
+	return onlySections(sections, config)
+}
+
+// rescanSections returns the ranges --rescan scans of a file as sections, if
+// the previous scan found strings in it and --only-sections is not given
+func rescanSections(filename string, config extractor.Config) ([]binary.Section, bool) {
+	file, ok := config.Rescan.Lookup(filename)
+	if !ok || len(file.Ranges) == 0 || len(config.OnlySections) > 0 {
+		return nil, false
+	}
+	sections := make([]binary.Section, len(file.Ranges))
+	for i, r := range file.Ranges {
+		sections[i] = binary.Section{Name: fmt.Sprintf("0x%x-0x%x", r.Offset, r.Offset+r.Size), Offset: r.Offset, Size: r.Size}
+	}
+	return sections, true
+}
+
+// onlySections returns the sections named by --only-sections (all of them if
+// it is not given), or an error if none is
+func onlySections(sections []binary.Section, config extractor.Config) ([]binary.Section, error) {
+	if len(config.OnlySections) == 0 {
+		return sections, nil
+	}
+	selected := sections[:0:0]
+	for _, section := range sections {
+		if slices.Contains(config.OnlySections, section.Name) {
+			selected = append(selected, section)
+		}
+	}
+	if len(selected) == 0 && len(sections) > 0 {
+		return nil, fmt.Errorf("no data section named %s", strings.Join(config.OnlySections, ", "))
+	}
+	return selected, nil
+}
+
+// loadDataSections parses the data sections of a binary for loadSections
+func loadDataSections(filename string, format binary.Format, config extractor.Config) ([]binary.Section, error) {
+	if config.MaxMemory <= 0 {
+		return parseBinary(filename, format, config)
+	}
+
+	headers, err := binary.ParseSectionHeaders(filename, format)
+	if err != nil {
+		return nil, err
+	}
+
+	var total int64
+	for _, section := range headers {
+		total += section.Size
+	}
+	if total > config.MaxMemory {
+		return headers, nil
+	}
+
+	return parseBinary(filename, format, config)
+}
+
+// parseBinary loads the data sections of a binary, parsing it again after
+// transient read errors (--retries) and recording the time taken and bytes
+// loaded if metrics are enabled
+func parseBinary(filename string, format binary.Format, config extractor.Config) ([]binary.Section, error) {
+	var sections []binary.Section
+	parse := func() (err error) {
+		sections, err = binary.ParseBinary(filename, format)
+		return err
+	}
+	if config.Metrics == nil {
+		err := config.Retry.Do(filename, config, parse)
+		return sections, err
+	}
+
+	start := time.Now()
+	err := config.Retry.Do(filename, config, parse)
+	config.Metrics.ReadTime += time.Since(start)
+	for _, section := range sections {
+		config.Metrics.BytesRead += int64(len(section.Data))
+	}
+	return sections, err
+}
+
+// extractSections extracts strings from each data section, streaming sections
+// whose contents were not loaded into memory (see loadSections) from path.
+// Strings are reported under filename, at most --max-strings of them, rarest
+// first with --sort rarity.
+func extractSections(sections []binary.Section, path, filename string, config extractor.Config, printFunc func([]byte, string, int64, extractor.Config)) {
+	printFunc, flush := extractor.ScoreRarity(extractor.LimitStrings(printFunc, filename, config), config)
+	defer flush()
+
+	var file *os.File
+	defer func() {
+		if file != nil {
+			closeInput(file, filename, config)
+		}
+	}()
+
+	for _, section := range sections {
+		if section.Data != nil || section.Size == 0 {
+			extractor.ExtractFromSection(section.Data, section.Name, section.Offset, filename, config, printFunc)
+			continue
+		}
+
+		if file == nil {
+			var err error
+			if file, err = os.Open(path); err != nil {
+				reportError(filename, err)
+				return
+			}
+		}
+
+		reader := io.NewSectionReader(file, section.Offset, section.Size)
+		var readErr *extractor.ReadError
+		if err := extractor.ExtractFromSectionReaderContext(context.Background(), reader, section.Offset, filename, config, printFunc); errors.As(err, &readErr) {
+			extractor.Warn(config, readErr.Warning())
 		}
 	}
+}
+
+// sectionLayout returns where the sections are in the file and in memory, for
+// the JSON printer to map offsets to virtual addresses
+func sectionLayout(sections []binary.Section) []printer.SectionRange {
+	layout := make([]printer.SectionRange, len(sections))
+	for i, section := range sections {
+		layout[i] = printer.SectionRange{Name: section.Name, Offset: section.Offset, Size: section.Size, Addr: section.Addr}
+	}
+	return layout
+}
+
+// extractRelocStrings extracts the strings pointed to by the relocation entries
+// of the binary at path (no-op unless --relocs)
+func extractRelocStrings(sections []binary.Section, path, filename string, format binary.Format, config extractor.Config, printFunc func([]byte, string, int64, extractor.Config)) {
+	if !config.Relocs {
+		return
+	}
+
+	targets, err := binary.RelocationTargets(path, format)
+	if err != nil {
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnRelocs, File: filename,
+			Message: "cannot read relocations", Err: err})
+		return
+	}
+
+	for _, offset := range targets {
+		for _, section := range sections {
+			if offset >= section.Offset && offset < section.Offset+int64(len(section.Data)) {
+				extractor.ExtractAt(section.Data[offset-section.Offset:], offset, filename, config, printFunc)
+				break
+			}
+		}
+	}
+}
+
+// closeInput closes an input file, reporting errors as warnings about filename
+func closeInput(file *os.File, filename string, config extractor.Config) {
+	if err := file.Close(); err != nil {
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnCloseFailed, File: filename,
+			Message: "error closing file", Err: err})
+	}
+}
+
+// extractStrings extracts the strings of reader, at most --max-strings of
+// them and rarest first with --sort rarity, reporting a read error as a
+// warning about filename: the strings before it are still printed
+func extractStrings(reader io.Reader, filename string, config extractor.Config, printFunc func([]byte, string, int64, extractor.Config)) {
+	printFunc, flush := extractor.ScoreRarity(extractor.LimitStrings(printFunc, filename, config), config)
+	defer flush()
+	var readErr *extractor.ReadError
+	if err := extractor.ExtractStringsContext(context.Background(), reader, filename, config, printFunc); errors.As(err, &readErr) {
+		extractor.Warn(config, readErr.Warning())
+	}
+}
+
+// warnParseFallback reports that filename could not be parsed as format and is
+// scanned in full
+func warnParseFallback(filename string, format binary.Format, err error, config extractor.Config) {
+	extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnParseFallback, File: filename,
+		Message: fmt.Sprintf("cannot parse as %v, falling back to full scan", format), Err: err})
+}
+
+// processFileWithBinaryParsing handles binary format detection and section
+// extraction, printing strings with printFunc
+func processFileWithBinaryParsing(filename string, config extractor.Config, printFunc func([]byte, string, int64, extractor.Config)) {
+	// Determine format (-T only applies to files that are not detected)
+	format, _, err := resolveFormat(filename, config)
+	if err != nil {
+		reportError(filename, err)
+		return
+	}
+
+	_, path, format, cleanup, err := maybeUnpack(filename, format, config)
+	if err != nil {
+		reportError(filename, err)
+		return
+	}
+	defer cleanup()
 
 	// Parse binary to get sections
-	sections, err := binary.ParseBinary(filename, format)
+	sections, err := loadSections(path, format, config)
 	if err != nil {
 		// Fall back to regular scanning if parsing fails
-		fmt.Fprintf(os.Stderr, "strings: %s: warning: cannot parse as %v, falling back to full scan: %v\n",
-			filename, format, err)
+		warnParseFallback(filename, format, err, config)
 
-		file, err := os.Open(filename)
+		file, err := os.Open(path)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
+			reportError(filename, err)
 			return
 		}
-		defer func() {
-			if err := file.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "strings: %s: error closing file: %v\n", filename, err)
-			}
-		}()
+		defer closeInput(file, filename, config)
 
-		extractor.ExtractStrings(file, filename, config, printer.PrintString)
+		extractStrings(file, filename, config, printFunc)
 		return
 	}
 
 	// If no sections found (raw binary), scan the whole file
 	if len(sections) == 0 {
-		file, err := os.Open(filename)
+		file, err := os.Open(path)
 		if err != nil {
-			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
+			reportError(filename, err)
 			return
 		}
-		defer func() {
-			if err := file.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "strings: %s: error closing file: %v\n", filename, err)
-			}
-		}()
+		defer closeInput(file, filename, config)
 
-		extractor.ExtractStrings(file, filename, config, printer.PrintString)
+		extractStrings(file, filename, config, printFunc)
 		return
 	}
 
 	// Extract strings from each data section
-	for _, section := range sections {
-		extractor.ExtractFromSection(section.Data, section.Name, section.Offset, filename, config, printer.PrintString)
-	}
+	extractSections(sections, path, filename, config, printFunc)
 }
 
 // processFilesParallel processes multiple files in parallel using a worker pool
 func processFilesParallel(filenames []string, workers int, config extractor.Config) {
+	config = workerConfig(config, workers)
+
 	// Create channels for jobs and results
 	jobs := make(chan job, len(filenames))
 	results := make(chan result, len(filenames))
 
+	// Resolve colors and the like once: the buffers are written to stdout
+	style := printer.NewStyle(config)
+
 	// Start worker goroutines
 	var wg sync.WaitGroup
-	for range workers {
+	for worker := range workers {
 		wg.Go(func() {
-			for j := range jobs {
-				// Create a buffer to capture output for this file
-				var buf bytes.Buffer
+			pinWorker(config, worker)
 
-				// Create a print function that writes to the buffer
-				printFunc := func(str []byte, filename string, offset int64, cfg extractor.Config) {
-					printer.PrintStringToWriter(&buf, str, filename, offset, cfg)
-				}
+			// Capture each file's output in a buffer reused by the worker
+			var buf bytes.Buffer
+			text := &printer.TextPrinter{Writer: &buf, Style: style}
+			for j := range jobs {
+				buf.Reset()
 
 				// Process the file
 				var err error
 				if config.ScanDataOnly {
-					err = processFileWithBinaryParsingToWriter(&buf, j.filename, config)
+					err = processFileWithBinaryParsingToWriter(text, j.filename, config)
 				} else {
 					// Use ExtractStringsFromFile with automatic mmap optimization
-					err = extractor.ExtractStringsFromFile(j.filename, config, printFunc)
+					err = extractor.ExtractStringsFromFile(j.filename, config, text.PrintString)
 				}
 
 				// Send result
@@ -452,8 +1402,8 @@ func processFilesParallel(filenames []string, workers int, config extractor.Conf
 	}
 
 	// Send jobs
-	for i, filename := range filenames {
-		jobs <- job{filename: filename, index: i}
+	for _, j := range scheduleJobs(filenames) {
+		jobs <- j
 	}
 	close(jobs)
 
@@ -463,6 +1413,18 @@ func processFilesParallel(filenames []string, workers int, config extractor.Conf
 		close(results)
 	}()
 
+	// Print results as they complete
+	if config.Unordered {
+		for r := range results {
+			if r.err != nil {
+				reportError(filenames[r.index], r.err)
+				continue
+			}
+			fmt.Print(r.output)
+		}
+		return
+	}
+
 	// Collect results in order
 	outputs := make([]result, len(filenames))
 	for r := range results {
@@ -472,82 +1434,58 @@ func processFilesParallel(filenames []string, workers int, config extractor.Conf
 	// Print results in order
 	for _, r := range outputs {
 		if r.err != nil {
-			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filenames[r.index], r.err)
+			reportError(filenames[r.index], r.err)
 			continue
 		}
 		fmt.Print(r.output)
 	}
 }
 
-// processFileWithBinaryParsingToWriter handles binary parsing and writes output to a buffer
-func processFileWithBinaryParsingToWriter(buf *bytes.Buffer, filename string, config extractor.Config) error {
-	// Create a print function that writes to the buffer
-	printFunc := func(str []byte, fname string, offset int64, cfg extractor.Config) {
-		printer.PrintStringToWriter(buf, str, fname, offset, cfg)
-	}
+// processFileWithBinaryParsingToWriter handles binary parsing and writes output
+// with text, e.g. to a buffer
+func processFileWithBinaryParsingToWriter(text *printer.TextPrinter, filename string, config extractor.Config) error {
+	printFunc := text.PrintString
 
-	// Determine format
-	var format binary.Format
-	var err error
+	// Determine format (-T only applies to files that are not detected)
+	format, _, err := resolveFormat(filename, config)
+	if err != nil {
+		return err
+	}
 
-	if config.TargetFormat != "" && config.TargetFormat != "binary" {
-		// User specified a format
-		switch config.TargetFormat {
-		case "elf":
-			format = binary.FormatELF
-		case "pe":
-			format = binary.FormatPE
-		case "macho":
-			format = binary.FormatMachO
-		default:
-			format = binary.FormatRaw
-		}
-	} else {
-		// Auto-detect format
-		format, err = binary.DetectFormat(filename)
-		if err != nil {
-			return err
-		}
+	_, path, format, cleanup, err := maybeUnpack(filename, format, config)
+	if err != nil {
+		return err
 	}
+	defer cleanup()
 
 	// Parse binary to get sections
-	sections, err := binary.ParseBinary(filename, format)
+	sections, err := loadSections(path, format, config)
 	if err != nil {
 		// Fall back to regular scanning if parsing fails
-		file, openErr := os.Open(filename)
+		file, openErr := os.Open(path)
 		if openErr != nil {
 			return openErr
 		}
-		defer func() {
-			if closeErr := file.Close(); closeErr != nil {
-				fmt.Fprintf(os.Stderr, "strings: %s: error closing file: %v\n", filename, closeErr)
-			}
-		}()
+		defer closeInput(file, filename, config)
 
-		extractor.ExtractStrings(file, filename, config, printFunc)
+		extractStrings(file, filename, config, printFunc)
 		return nil
 	}
 
 	// If no sections found (raw binary), scan the whole file
 	if len(sections) == 0 {
-		file, openErr := os.Open(filename)
+		file, openErr := os.Open(path)
 		if openErr != nil {
 			return openErr
 		}
-		defer func() {
-			if closeErr := file.Close(); closeErr != nil {
-				fmt.Fprintf(os.Stderr, "strings: %s: error closing file: %v\n", filename, closeErr)
-			}
-		}()
+		defer closeInput(file, filename, config)
 
-		extractor.ExtractStrings(file, filename, config, printFunc)
+		extractStrings(file, filename, config, printFunc)
 		return nil
 	}
 
 	// Extract strings from each data section
-	for _, section := range sections {
-		extractor.ExtractFromSection(section.Data, section.Name, section.Offset, filename, config, printFunc)
-	}
+	extractSections(sections, path, filename, config, printFunc)
 	return nil
 }
 
@@ -559,29 +1497,44 @@ func processFilesParallelJSON(filenames []string, workers int, config extractor.
 
 	// Start worker goroutines
 	var wg sync.WaitGroup
-	for range workers {
+	for worker := range workers {
 		wg.Go(func() {
+			pinWorker(config, worker)
 			for j := range jobs {
 				// Create a temporary JSON printer for this file
 				var buf bytes.Buffer
 				tempPrinter := printer.NewJSONPrinter(config, &buf)
 
-				var format string
+				var format, strategy string
 				var sections []string
-				var strings []printer.StringResult
+				var strings, relocStrings []printer.StringResult
+				var clusters []printer.OffsetCluster
+				var layout []printer.SectionRange
+				var warnings []extractor.Warning
 				var err error
 
+				// Warnings are reported with the file's result, in input order
+				config := config
+				config.Warnings = func(w extractor.Warning) {
+					warnings = append(warnings, w)
+				}
+
 				if config.ScanDataOnly {
 					// Process with binary parsing
-					format, sections, strings, err = processFileForJSON(j.filename, config)
+					var fileRes printer.FileResult
+					fileRes, err = processFileForJSON(j.filename, config)
+					format, sections, strings, relocStrings = fileRes.Format, fileRes.Sections, fileRes.Strings, fileRes.RelocStrings
+					clusters, layout = fileRes.Clusters, fileRes.Layout
 				} else {
 					// Regular full-file scanning with automatic mmap optimization
 					tempPrinter.SetFileInfo(j.filename, "", nil)
+					strategy = string(extractor.ChooseStrategy(j.filename, config))
 					err = extractor.ExtractStringsFromFile(j.filename, config, tempPrinter.PrintString)
 					if err != nil {
 						results <- jsonFileResult{
 							index:    j.index,
 							filename: j.filename,
+							warnings: warnings,
 							err:      err,
 						}
 						continue
@@ -594,6 +1547,7 @@ func processFilesParallelJSON(filenames []string, workers int, config extractor.
 						strings = fileRes.Strings
 						format = fileRes.Format
 						sections = fileRes.Sections
+						clusters = fileRes.Clusters
 					}
 				}
 
@@ -601,21 +1555,30 @@ func processFilesParallelJSON(filenames []string, workers int, config extractor.
 				if strings == nil {
 					strings = make([]printer.StringResult, 0)
 				}
+				regions := findRegions(j.filename, config)
+				xattrs := findXattrs(j.filename, config)
 				results <- jsonFileResult{
-					index:    j.index,
-					filename: j.filename,
-					format:   format,
-					sections: sections,
-					strings:  strings,
-					err:      err,
+					index:        j.index,
+					filename:     j.filename,
+					format:       format,
+					sections:     sections,
+					strategy:     strategy,
+					strings:      strings,
+					relocStrings: relocStrings,
+					clusters:     clusters,
+					layout:       layout,
+					regions:      regions,
+					xattrs:       xattrs,
+					warnings:     warnings,
+					err:          err,
 				}
 			}
 		})
 	}
 
 	// Send jobs
-	for i, filename := range filenames {
-		jobs <- job{filename: filename, index: i}
+	for _, j := range scheduleJobs(filenames) {
+		jobs <- j
 	}
 	close(jobs)
 
@@ -634,66 +1597,70 @@ func processFilesParallelJSON(filenames []string, workers int, config extractor.
 	// Build final JSON output
 	jsonPrinter := printer.NewJSONPrinter(config, os.Stdout)
 	for _, r := range outputs {
+		fileResult := printer.FileResult{
+			File:         r.filename,
+			Format:       r.format,
+			Sections:     r.sections,
+			Strategy:     r.strategy,
+			Strings:      r.strings,
+			RelocStrings: r.relocStrings,
+			Clusters:     r.clusters,
+			Layout:       r.layout,
+			Regions:      r.regions,
+			Xattrs:       r.xattrs,
+		}
+		for _, w := range r.warnings {
+			reportWarning(w)
+			fileResult.Warnings = append(fileResult.Warnings, printer.NewWarningResult(w))
+		}
 		if r.err != nil {
 			// Print error to stderr as well
-			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", r.filename, r.err)
+			reportError(r.filename, r.err)
+			fileResult.Error = r.err.Error()
 		}
 		// Add file result (with error if present)
-		jsonPrinter.AddFileResult(r.filename, r.format, r.sections, r.strings, r.err)
+		jsonPrinter.AppendFileResult(fileResult)
 	}
 
 	return jsonPrinter
 }
 
 // processFileForJSON processes a single file with binary parsing for JSON output
-func processFileForJSON(filename string, config extractor.Config) (string, []string, []printer.StringResult, error) {
-	// Determine format
-	var format binary.Format
-	var err error
-
-	if config.TargetFormat != "" && config.TargetFormat != "binary" {
-		switch config.TargetFormat {
-		case "elf":
-			format = binary.FormatELF
-		case "pe":
-			format = binary.FormatPE
-		case "macho":
-			format = binary.FormatMachO
-		default:
-			format = binary.FormatRaw
-		}
-	} else {
-		format, err = binary.DetectFormat(filename)
-		if err != nil {
-			return "", nil, nil, err
-		}
+func processFileForJSON(filename string, config extractor.Config) (printer.FileResult, error) {
+	// Determine format (-T only applies to files that are not detected)
+	format, source, err := resolveFormat(filename, config)
+	if err != nil {
+		return printer.FileResult{}, err
+	}
+
+	packing, path, format, cleanup, err := maybeUnpack(filename, format, config)
+	if err != nil {
+		return printer.FileResult{}, err
 	}
+	defer cleanup()
 
 	// Parse binary to get sections
-	sections, err := binary.ParseBinary(filename, format)
+	sections, err := loadSections(path, format, config)
 	if err != nil {
 		// Fall back to regular scanning
-		file, openErr := os.Open(filename)
+		file, openErr := os.Open(path)
 		if openErr != nil {
-			return "", nil, nil, openErr
+			return printer.FileResult{}, openErr
 		}
-		defer func() {
-			if err := file.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "strings: %s: error closing file: %v\n", filename, err)
-			}
-		}()
+		defer closeInput(file, filename, config)
 
 		var buf bytes.Buffer
 		tempPrinter := printer.NewJSONPrinter(config, &buf)
 		tempPrinter.SetFileInfo(filename, format.String(), nil)
-		extractor.ExtractStrings(file, filename, config, tempPrinter.PrintString)
+		tempPrinter.SetFormatSource(string(source))
+		tempPrinter.SetPacking(packing)
+		extractStrings(file, filename, config, tempPrinter.PrintString)
 		tempPrinter.FinalizeCurrentFile()
 
 		if len(tempPrinter.FileResults) > 0 {
-			fileRes := tempPrinter.FileResults[0]
-			return fileRes.Format, fileRes.Sections, fileRes.Strings, nil
+			return tempPrinter.FileResults[0], nil
 		}
-		return format.String(), nil, nil, nil
+		return printer.FileResult{Format: format.String(), FormatSource: string(source)}, nil
 	}
 
 	// Collect section names
@@ -704,155 +1671,143 @@ func processFileForJSON(filename string, config extractor.Config) (string, []str
 
 	// If no sections found, scan whole file
 	if len(sections) == 0 {
-		file, openErr := os.Open(filename)
+		file, openErr := os.Open(path)
 		if openErr != nil {
-			return "", nil, nil, openErr
+			return printer.FileResult{}, openErr
 		}
-		defer func() {
-			if err := file.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "strings: %s: error closing file: %v\n", filename, err)
-			}
-		}()
+		defer closeInput(file, filename, config)
 
 		var buf bytes.Buffer
 		tempPrinter := printer.NewJSONPrinter(config, &buf)
 		tempPrinter.SetFileInfo(filename, format.String(), sectionNames)
-		extractor.ExtractStrings(file, filename, config, tempPrinter.PrintString)
+		tempPrinter.SetFormatSource(string(source))
+		tempPrinter.SetPacking(packing)
+		extractStrings(file, filename, config, tempPrinter.PrintString)
 		tempPrinter.FinalizeCurrentFile()
 
 		if len(tempPrinter.FileResults) > 0 {
-			fileRes := tempPrinter.FileResults[0]
-			return fileRes.Format, fileRes.Sections, fileRes.Strings, nil
+			return tempPrinter.FileResults[0], nil
 		}
-		return format.String(), sectionNames, nil, nil
+		return printer.FileResult{Format: format.String(), FormatSource: string(source), Sections: sectionNames}, nil
 	}
 
 	// Extract strings from data sections
 	var buf bytes.Buffer
 	tempPrinter := printer.NewJSONPrinter(config, &buf)
 	tempPrinter.SetFileInfo(filename, format.String(), sectionNames)
+	tempPrinter.SetFormatSource(string(source))
+	tempPrinter.SetPacking(packing)
+	tempPrinter.SetSectionLayout(sectionLayout(sections))
+	attachReferences(tempPrinter, path, filename, format, config)
 
-	for _, section := range sections {
-		extractor.ExtractFromSection(section.Data, section.Name, section.Offset, filename, config, tempPrinter.PrintString)
-	}
+	extractSections(sections, path, filename, config, tempPrinter.PrintString)
+	extractRelocStrings(sections, path, filename, format, config, tempPrinter.PrintRelocString)
 
 	tempPrinter.FinalizeCurrentFile()
 	if len(tempPrinter.FileResults) > 0 {
-		fileRes := tempPrinter.FileResults[0]
-		return fileRes.Format, fileRes.Sections, fileRes.Strings, nil
+		return tempPrinter.FileResults[0], nil
 	}
 
-	return format.String(), sectionNames, nil, nil
+	return printer.FileResult{Format: format.String(), FormatSource: string(source), Sections: sectionNames}, nil
 }
 
 // processWithStats processes files or stdin with statistics output
-func processWithStats(files []string, workers int, config extractor.Config, perFile bool) {
+func processWithStats(files []string, workers int, config extractor.Config, mode outputMode) {
+	startTime := time.Now()
+	timing := mode.Timing
+
 	// stdin case
 	if len(files) == 0 {
 		s := stats.New(config.MinLength)
 
-		// Create wrapper function for filter tracking if needed
-		collectFunc := s.Add
-		if len(config.MatchPatterns) > 0 || len(config.ExcludePatterns) > 0 {
-			collectFunc = makeFilterTrackingFunc(s, config)
-		}
+		// Count strings dropped by the filters too if needed
+		collectFunc, config := trackFilters(s, config)
 
-		extractor.ExtractStrings(os.Stdin, "", config, collectFunc)
-		s.Format(os.Stdout, config.ColorMode)
+		if timing {
+			_ = recordTiming(s, 0, config, func(config extractor.Config) error {
+				extractStrings(os.Stdin, "", config, collectFunc)
+				return nil
+			})
+		} else {
+			extractStrings(os.Stdin, "", config, collectFunc)
+		}
+		writeStats(s, mode, config)
 		return
 	}
 
 	// Per-file statistics mode
-	if perFile {
+	if mode.PerFile {
+		var perFileJSON []json.RawMessage
 		for _, filename := range files {
 			s := stats.New(config.MinLength)
+			s.SetFileInfo(filename, "", nil)
 
-			// Create wrapper function for filter tracking if needed
-			collectFunc := s.Add
-			if len(config.MatchPatterns) > 0 || len(config.ExcludePatterns) > 0 {
-				collectFunc = makeFilterTrackingFunc(s, config)
+			if err := scanFileForStats(filename, config, s, 0, timing); err != nil {
+				reportError(filename, err)
+				continue
 			}
 
-			// Process file with binary parsing if needed
-			if config.ScanDataOnly {
-				if err := processFileWithStatsAndBinaryParsing(filename, config, s); err != nil {
-					fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
-					continue
-				}
-			} else {
-				// Use ExtractStringsFromFile with automatic mmap optimization
-				s.SetFileInfo(filename, "", nil)
-				if err := extractor.ExtractStringsFromFile(filename, config, collectFunc); err != nil {
-					fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
+			// Output statistics for this file (JSON is written as one array)
+			s.TrackPatterns(config.MatchPatterns)
+			if mode.Format == formatJSON {
+				data, err := s.ToJSON()
+				if err != nil {
+					reportError(filename, err)
 					continue
 				}
+				perFileJSON = append(perFileJSON, data)
+				continue
 			}
-
-			// Output statistics for this file
+			s.Locale = config.Locale
 			s.Format(os.Stdout, config.ColorMode)
 			if filename != files[len(files)-1] {
 				fmt.Println() // Blank line between files
 			}
 		}
+
+		if mode.Format == formatJSON {
+			if perFileJSON == nil {
+				perFileJSON = make([]json.RawMessage, 0)
+			}
+			data, err := json.MarshalIndent(perFileJSON, "", "  ")
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "strings: error writing JSON output: %v\n", err)
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+		}
 		return
 	}
 
 	// Aggregated statistics mode (default)
 	aggregated := stats.New(config.MinLength)
 
-	// Create wrapper function for filter tracking if needed
-	collectFunc := aggregated.Add
-	if len(config.MatchPatterns) > 0 || len(config.ExcludePatterns) > 0 {
-		collectFunc = makeFilterTrackingFunc(aggregated, config)
-	}
-
 	// Sequential processing
 	if len(files) == 1 || workers == 1 {
 		for _, filename := range files {
-			if config.ScanDataOnly {
-				if err := processFileWithStatsAndBinaryParsing(filename, config, aggregated); err != nil {
-					fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
-					continue
-				}
-			} else {
-				// Use ExtractStringsFromFile with automatic mmap optimization
-				if err := extractor.ExtractStringsFromFile(filename, config, collectFunc); err != nil {
-					fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
-					continue
-				}
+			if err := scanFileForStats(filename, config, aggregated, 0, timing); err != nil {
+				reportError(filename, err)
+				continue
 			}
 		}
 	} else {
 		// Parallel processing
+		config := workerConfig(config, workers)
 		jobs := make(chan job, len(files))
 		results := make(chan *stats.Statistics, len(files))
 		var wg sync.WaitGroup
 
 		// Start workers
-		for range workers {
+		for worker := range workers {
 			wg.Go(func() {
+				pinWorker(config, worker)
 				for j := range jobs {
 					s := stats.New(config.MinLength)
 
-					// Create wrapper function for filter tracking if needed
-					localCollectFunc := s.Add
-					if len(config.MatchPatterns) > 0 || len(config.ExcludePatterns) > 0 {
-						localCollectFunc = makeFilterTrackingFunc(s, config)
-					}
-
-					if config.ScanDataOnly {
-						if err := processFileWithStatsAndBinaryParsing(j.filename, config, s); err != nil {
-							fmt.Fprintf(os.Stderr, "strings: %s: %v\n", j.filename, err)
-							results <- nil
-							continue
-						}
-					} else {
-						// Use ExtractStringsFromFile with automatic mmap optimization
-						if err := extractor.ExtractStringsFromFile(j.filename, config, localCollectFunc); err != nil {
-							fmt.Fprintf(os.Stderr, "strings: %s: %v\n", j.filename, err)
-							results <- nil
-							continue
-						}
+					if err := scanFileForStats(j.filename, config, s, worker, timing); err != nil {
+						reportError(j.filename, err)
+						results <- nil
+						continue
 					}
 
 					results <- s
@@ -861,8 +1816,8 @@ func processWithStats(files []string, workers int, config extractor.Config, perF
 		}
 
 		// Send jobs
-		for _, filename := range files {
-			jobs <- job{filename: filename}
+		for _, j := range scheduleJobs(files) {
+			jobs <- j
 		}
 		close(jobs)
 
@@ -880,71 +1835,122 @@ func processWithStats(files []string, workers int, config extractor.Config, perF
 		}
 	}
 
+	if timing {
+		aggregated.SetElapsed(time.Since(startTime))
+	}
+
 	// Output aggregated statistics
-	aggregated.Format(os.Stdout, config.ColorMode)
+	writeStats(aggregated, mode, config)
 }
 
-// makeFilterTrackingFunc creates a wrapper function that tracks both filtered and unfiltered counts
-func makeFilterTrackingFunc(s *stats.Statistics, _ extractor.Config) func([]byte, string, int64, extractor.Config) {
-	return func(str []byte, filename string, offset int64, cfg extractor.Config) {
-		// Track unfiltered count
-		s.AddUnfiltered()
+// writeStats outputs statistics as text or JSON
+func writeStats(s *stats.Statistics, mode outputMode, config extractor.Config) {
+	s.TrackPatterns(config.MatchPatterns)
+	s.Retries = config.Retry.Count()
+	if mode.Format != formatJSON {
+		s.Locale = config.Locale
+		s.Format(os.Stdout, config.ColorMode)
+		return
+	}
 
-		// Check if string should be included (filtering logic)
-		if extractor.ShouldPrintString(str, cfg) {
-			// String passed filters, add to statistics
-			s.Add(str, filename, offset, cfg)
+	data, err := s.ToJSON()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "strings: error writing JSON output: %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println(string(data))
+}
+
+// scanFileForStats extracts strings from a file into s, recording the file's
+// timing against the given worker if timing is enabled
+func scanFileForStats(filename string, config extractor.Config, s *stats.Statistics, worker int, timing bool) error {
+	scan := func(config extractor.Config) error {
+		// Process file with binary parsing if needed
+		if config.ScanDataOnly {
+			return processFileWithStatsAndBinaryParsing(filename, config, s)
 		}
+
+		// Count strings dropped by the filters too if needed
+		collectFunc, config := trackFilters(s, config)
+
+		// Use ExtractStringsFromFile with automatic mmap optimization
+		return extractor.ExtractStringsFromFile(filename, config, collectFunc)
+	}
+
+	if !timing {
+		return scan(config)
+	}
+	return recordTiming(s, worker, config, scan)
+}
+
+// recordTiming runs scan with metrics enabled and records its wall time, CPU
+// time and stage metrics in s. The goroutine is locked to its OS thread so the
+// thread's CPU time is attributable to the scan.
+func recordTiming(s *stats.Statistics, worker int, config extractor.Config, scan func(extractor.Config) error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	config.Metrics = &extractor.Metrics{}
+	start := time.Now()
+	cpuStart := stats.ThreadCPUTime()
+
+	err := scan(config)
+
+	cpu := stats.ThreadCPUTime() - cpuStart
+	wall := time.Since(start)
+	if err == nil {
+		s.RecordTiming(worker, stats.NewFileTiming(wall, cpu, config.Metrics))
+	}
+	return err
+}
+
+// trackFilters returns the function collecting strings into s and the config
+// to extract them with. With match or exclude patterns, strings dropped by the
+// filters are counted as well (see extractor.Config.Rejected).
+func trackFilters(s *stats.Statistics, config extractor.Config) (func([]byte, string, int64, extractor.Config), extractor.Config) {
+	if len(config.MatchPatterns) == 0 && len(config.ExcludePatterns) == 0 {
+		return s.Add, config
+	}
+
+	config.Rejected = func([]byte, string, int64, extractor.Config) {
+		s.AddUnfiltered()
 	}
+	return func(str []byte, filename string, offset int64, cfg extractor.Config) {
+		s.AddUnfiltered()
+		s.Add(str, filename, offset, cfg)
+	}, config
 }
 
 // processFileWithStatsAndBinaryParsing processes a file with binary parsing for statistics
 func processFileWithStatsAndBinaryParsing(filename string, config extractor.Config, s *stats.Statistics) error {
-	// Determine format
-	var format binary.Format
-	var err error
-
-	if config.TargetFormat != "" && config.TargetFormat != "binary" {
-		switch config.TargetFormat {
-		case "elf":
-			format = binary.FormatELF
-		case "pe":
-			format = binary.FormatPE
-		case "macho":
-			format = binary.FormatMachO
-		default:
-			format = binary.FormatRaw
-		}
-	} else {
-		format, err = binary.DetectFormat(filename)
-		if err != nil {
-			return err
-		}
+	// Determine format (-T only applies to files that are not detected)
+	format, _, err := resolveFormat(filename, config)
+	if err != nil {
+		return err
+	}
+
+	_, path, format, cleanup, err := maybeUnpack(filename, format, config)
+	if err != nil {
+		return err
 	}
+	defer cleanup()
 
 	// Parse binary to get sections
-	sections, err := binary.ParseBinary(filename, format)
+	sections, err := loadSections(path, format, config)
 	if err != nil {
 		// Fall back to regular scanning
-		file, openErr := os.Open(filename)
+		file, openErr := os.Open(path)
 		if openErr != nil {
 			return openErr
 		}
-		defer func() {
-			if err := file.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "strings: %s: error closing file: %v\n", filename, err)
-			}
-		}()
+		defer closeInput(file, filename, config)
 
 		s.SetFileInfo(filename, format.String(), nil)
 
-		// Create wrapper function for filter tracking if needed
-		collectFunc := s.Add
-		if len(config.MatchPatterns) > 0 || len(config.ExcludePatterns) > 0 {
-			collectFunc = makeFilterTrackingFunc(s, config)
-		}
+		// Count strings dropped by the filters too if needed
+		collectFunc, config := trackFilters(s, config)
 
-		extractor.ExtractStrings(file, filename, config, collectFunc)
+		extractStrings(file, filename, config, collectFunc)
 		return nil
 	}
 
@@ -958,36 +1964,24 @@ func processFileWithStatsAndBinaryParsing(filename string, config extractor.Conf
 
 	// If no sections found, scan whole file
 	if len(sections) == 0 {
-		file, openErr := os.Open(filename)
+		file, openErr := os.Open(path)
 		if openErr != nil {
 			return openErr
 		}
-		defer func() {
-			if err := file.Close(); err != nil {
-				fmt.Fprintf(os.Stderr, "strings: %s: error closing file: %v\n", filename, err)
-			}
-		}()
+		defer closeInput(file, filename, config)
 
-		// Create wrapper function for filter tracking if needed
-		collectFunc := s.Add
-		if len(config.MatchPatterns) > 0 || len(config.ExcludePatterns) > 0 {
-			collectFunc = makeFilterTrackingFunc(s, config)
-		}
+		// Count strings dropped by the filters too if needed
+		collectFunc, config := trackFilters(s, config)
 
-		extractor.ExtractStrings(file, filename, config, collectFunc)
+		extractStrings(file, filename, config, collectFunc)
 		return nil
 	}
 
-	// Create wrapper function for filter tracking if needed
-	collectFunc := s.Add
-	if len(config.MatchPatterns) > 0 || len(config.ExcludePatterns) > 0 {
-		collectFunc = makeFilterTrackingFunc(s, config)
-	}
+	// Count strings dropped by the filters too if needed
+	collectFunc, config := trackFilters(s, config)
 
 	// Extract strings from data sections
-	for _, section := range sections {
-		extractor.ExtractFromSection(section.Data, section.Name, section.Offset, filename, config, collectFunc)
-	}
+	extractSections(sections, path, filename, config, collectFunc)
 
 	return nil
 }
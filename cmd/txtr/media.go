@@ -0,0 +1,42 @@
+package main
+
+import (
+	"io"
+	"os"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/partition"
+)
+
+// openMedia opens the bytes the strings of filename are found in: the media
+// of an evidence container or virtual disk (--evidence, --vdisk; see
+// extractor.OpenImage), else the file itself. closeMedia releases it.
+func openMedia(filename string, config extractor.Config) (media io.ReaderAt, size int64, closeMedia func(), err error) {
+	img, err := extractor.OpenImage(filename, config)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	if img != nil {
+		return img, img.Size(), func() { _ = img.Close() }, nil
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return nil, 0, nil, err
+	}
+	return file, info.Size(), func() { _ = file.Close() }, nil
+}
+
+// readPartitions returns the partitions of a disk image, or nil if it has no
+// partition table
+func readPartitions(media io.ReaderAt, size int64) []partition.Partition {
+	partitions, err := partition.Read(media, size)
+	if err != nil {
+		return nil
+	}
+	return partitions
+}
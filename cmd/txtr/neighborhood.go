@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+)
+
+// clusterTextSink writes strings in the text format grouped into neighborhoods
+// of strings at most config.ClusterGap bytes apart (--cluster-by-offset). A
+// cluster is written once complete, under a header with its offset range.
+type clusterTextSink struct {
+	textSink
+	clusters    printer.OffsetClusterer
+	pending     []recordedString // Strings of the open cluster
+	pendingID   int
+	pendingFile string
+	written     int // Clusters written so far
+}
+
+func (cs *clusterTextSink) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	if len(cs.pending) > 0 && filename != cs.pendingFile {
+		cs.flush()
+	}
+	id := cs.clusters.Add(filename, offset, extractor.EncodedLen(str, config.Encoding))
+	if len(cs.pending) > 0 && id != cs.pendingID {
+		cs.flush()
+	}
+	// The extractor reuses its buffers, so the string must be copied
+	cs.pending = append(cs.pending, recordedString{str: slices.Clone(str), filename: filename, offset: offset, config: config})
+	cs.pendingID, cs.pendingFile = id, filename
+}
+
+func (cs *clusterTextSink) EndFile(string, error) {
+	cs.flush()
+}
+
+func (cs *clusterTextSink) Close() error {
+	cs.flush()
+	return cs.textSink.Close()
+}
+
+// flush writes the open cluster, separated from the previous one by a blank line
+func (cs *clusterTextSink) flush() {
+	if len(cs.pending) == 0 {
+		return
+	}
+	cluster := cs.clusters.Clusters[cs.pendingID-1]
+	config := cs.pending[0].config
+
+	if cs.written > 0 {
+		cs.writer.WriteByte('\n')
+	}
+	noun := "strings"
+	if cluster.Strings == 1 {
+		noun = "string"
+	}
+	header := fmt.Sprintf("--- cluster %d: %s-%s (%d %s, %d bytes) ---", cluster.ID,
+		formatClusterOffset(cluster.Start, config.Radix), formatClusterOffset(cluster.End, config.Radix),
+		cluster.Strings, noun, cluster.End-cluster.Start+1)
+	header = printer.ColorString(header, printer.AnsiBold+printer.AnsiCyan, cs.text.Style.Color)
+	if config.PrintFileName && cs.pendingFile != "" {
+		header = cs.pendingFile + ": " + header
+	}
+	cs.writer.WriteString(header + "\n")
+
+	for _, r := range cs.pending {
+		cs.textSink.PrintString(r.str, r.filename, r.offset, r.config)
+	}
+	cs.written++
+	cs.pending = cs.pending[:0]
+}
+
+// formatClusterOffset formats a cluster boundary in the -t radix (hex by default)
+func formatClusterOffset(offset int64, radix string) string {
+	switch radix {
+	case "d":
+		return fmt.Sprintf("%d", offset)
+	case "o":
+		return fmt.Sprintf("0%o", offset)
+	default:
+		return fmt.Sprintf("0x%x", offset)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/notify"
+	"github.com/richardwooding/txtr/internal/rank"
+)
+
+// notifyOptions configures the summary posted when a scan completes
+// (--notify-webhook)
+type notifyOptions struct {
+	URL      string        // Webhook to post to ("" = no summary)
+	Format   string        // Payload format: notify.FormatJSON, FormatSlack or FormatTeams (--notify-format)
+	Findings bool          // Include the secrets found, masked (--notify-findings)
+	Timeout  time.Duration // Limit on posting the summary (--notify-timeout)
+}
+
+// validate checks the options given on the command line. Secrets may also be
+// listed by the summary written with --format slack or teams (chat).
+func (o notifyOptions) validate(chat bool) error {
+	if o.URL == "" {
+		if o.Findings && !chat {
+			return errors.New("--notify-findings requires --notify-webhook or --format slack or teams")
+		}
+		return nil
+	}
+	u, err := url.Parse(o.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid --notify-webhook URL %q (expected http:// or https://)", o.URL)
+	}
+	if o.Timeout <= 0 {
+		return errors.New("--notify-timeout must be positive")
+	}
+	return nil
+}
+
+// summarySink summarizes the scan and delivers the summary on close: posted
+// to the webhook of --notify-webhook, or written as a Slack or Teams message
+// (--format slack or teams)
+type summarySink struct {
+	summary  *notify.Summary
+	findings bool // List the secrets found
+	deliver  func(*notify.Summary) error
+}
+
+// newNotifySink creates a sink posting the summary to the webhook. A summary
+// that cannot be posted is reported as a warning, leaving the scan's own
+// output and exit status alone.
+func newNotifySink(options notifyOptions) *summarySink {
+	return newSummarySink(options.Findings, func(summary *notify.Summary) error {
+		payload, err := notify.Payload(summary, options.Format)
+		if err == nil {
+			ctx, cancel := context.WithTimeout(context.Background(), options.Timeout)
+			defer cancel()
+			err = notify.Post(ctx, &http.Client{}, options.URL, payload)
+		}
+		if err != nil {
+			reportWarning(extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnNotify,
+				Message: "cannot post the scan summary (--notify-webhook)", Err: err})
+		}
+		return nil
+	})
+}
+
+// newChatSink creates a sink writing the summary to w as a message in format
+// (notify.FormatSlack or FormatTeams), for posting by other tools
+func newChatSink(w io.Writer, format string, findings bool) *summarySink {
+	return newSummarySink(findings, func(summary *notify.Summary) error {
+		payload, err := notify.Payload(summary, format)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(payload)
+	})
+}
+
+// newSummarySink creates a summary sink, timing the scan from now
+func newSummarySink(findings bool, deliver func(*notify.Summary) error) *summarySink {
+	return &summarySink{summary: notify.NewSummary(version, time.Now()), findings: findings, deliver: deliver}
+}
+
+func (ss *summarySink) BeginFile(fileInfo) {
+	ss.summary.Files++
+}
+
+func (ss *summarySink) PrintString(str []byte, filename string, offset int64, _ extractor.Config) {
+	ss.summary.Strings++
+	for _, kind := range rank.Categories(str) {
+		if kind != "secret" {
+			ss.summary.AddArtifact(kind, string(str))
+			continue
+		}
+		// Secrets are counted, but only listed masked
+		ss.summary.AddArtifact(kind, "")
+		if ss.findings {
+			ss.summary.AddFinding(filename, offset, kind, string(str))
+		}
+	}
+}
+
+func (ss *summarySink) Warn(extractor.Warning) {
+	ss.summary.Warnings++
+}
+
+func (ss *summarySink) EndFile(filename string, err error) {
+	if err != nil {
+		ss.summary.AddError(filename, err)
+	}
+}
+
+func (ss *summarySink) Close() error {
+	ss.summary.Finish(time.Now())
+	return ss.deliver(ss.summary)
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Output formats selectable with --format
+const (
+	formatText      = "text"
+	formatJSON      = "json"
+	formatCSV       = "csv"
+	formatCycloneDX = "cyclonedx"
+	formatRizin     = "rizin"
+	formatGhidra    = "ghidra"
+	formatIDAPython = "idapython"
+	formatHTML      = "html"
+	formatMarkdown  = "markdown"
+	formatDOT       = "dot"
+	formatMermaid   = "mermaid"
+	formatSlack     = "slack"
+	formatTeams     = "teams"
+)
+
+// outputFormats lists the supported --format values
+var outputFormats = []string{formatText, formatJSON, formatCSV, formatCycloneDX, formatRizin, formatGhidra, formatIDAPython, formatHTML, formatMarkdown, formatDOT, formatMermaid, formatSlack, formatTeams}
+
+// outputOptions holds the CLI flags that influence output selection
+type outputOptions struct {
+	Stdin        bool     // No file arguments (stdin is scanned; --rescan supplies its own)
+	Formats      []string // --format values
+	OutputPrefix bool     // --output-prefix set
+	JSON         bool     // -j/--json (alias for --format json)
+	Stats        bool
+	StatsPerFile bool
+	StatsTiming  bool
+	ScanDataOnly bool
+	LiteralPools bool
+	Xrefs        bool
+	Relocs       bool
+	Signatures   bool
+	Partitions   bool
+	OnlySections bool
+	Rescan       bool
+	Coverage     bool
+	ExtractFS    bool
+	Archives     bool
+	ADS          bool
+	Xattrs       bool
+	ScanXattrs   bool
+	Artifacts    bool // --registry, --evtx, --prefetch, --ntfs, --memory-map or --memory-profile
+	Outputs      bool // --output sinks requested
+	Unique       bool // --unique, --count or --dedupe-fold-case
+	FoldCase     bool // --dedupe-fold-case
+	ValueEncoded bool // --value-encoding base64 or hex
+	EmitRaw      bool
+	WideInfo     bool
+	AllOffsets   bool
+	MaxMemory    bool    // --max-memory set
+	Grep         bool    // --grep set
+	Unordered    bool    // --unordered set
+	Lines        bool    // --lines set
+	Background   bool    // --background set
+	SortRarity   bool    // --sort rarity
+	Top          int     // --top K
+	ClusterGap   int64   // --cluster-by-offset GAP
+	Notify       bool    // --notify-webhook
+	Exec         bool    // --exec-per-match
+	Journal      bool    // --journal set
+	Resume       bool    // --resume set
+	PreFilter    bool    // --pre-filter set
+	Media        bool    // --evidence or --vdisk
+	Passwords    bool    // --password or --password-file set
+	MaxDepth     int     // --max-depth (checked with --archives)
+	MaxExpansion float64 // --max-expansion (checked with --archives)
+	Scripts      bool
+	ByteText     bool // -e s or -e S with no -U display mode
+	Template     bool // --report-template set
+	Markdown     bool // --output markdown=FILE
+}
+
+// outputMode is the resolved output selection of a run
+type outputMode struct {
+	Format  string // One of outputFormats
+	Stats   bool   // Statistics instead of strings
+	PerFile bool   // Per-file statistics
+	Timing  bool   // Performance accounting in statistics
+	Top     int    // Print the K highest-ranked strings (0 = all strings)
+
+	// Further --format values, written to files named by --output-prefix
+	Extra []string
+}
+
+// outputRule is one entry of the option conflict matrix: if conflict reports
+// true for the requested options and resolved format, message is the error
+type outputRule struct {
+	conflict func(o outputOptions, format string) bool
+	message  string
+}
+
+// outputRules is the option conflict matrix, checked in order. Formats holds
+// the normalized --format values, the first of which is format; the others are
+// written like --output files.
+var outputRules = []outputRule{
+	{
+		func(o outputOptions, _ string) bool { return o.Coverage && o.Stdin },
+		"--coverage requires file arguments (cannot be used with stdin)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.ScanDataOnly && o.Stdin },
+		"-d/--data flag requires file arguments (cannot be used with stdin)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return len(o.Formats) > 1 && !o.OutputPrefix },
+		"several --format values require --output-prefix (the first format is written to stdout, the others to files named after the prefix)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.OutputPrefix && len(o.Formats) < 2 },
+		"--output-prefix requires several --format values",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.StatsPerFile && !o.Stats },
+		"--stats-per-file requires --stats flag",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.StatsTiming && !o.Stats },
+		"--stats-timing requires --stats flag",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.Stats && slices.ContainsFunc(o.Formats, func(f string) bool { return f != formatText && f != formatJSON })
+		},
+		"--stats supports --format text or json (not csv, cyclonedx, rizin, ghidra, idapython, html, markdown, dot, mermaid, slack or teams)",
+	},
+	{
+		// Script merging maps lines to byte offsets, so it needs a single-byte encoding
+		func(o outputOptions, _ string) bool { return o.Scripts && !o.ByteText },
+		"--scripts requires -e s or -e S (and no -U display mode)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.OnlySections && !o.ScanDataOnly },
+		"--only-sections requires --data or --rescan",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Coverage && (o.Rescan || o.OnlySections || o.Grep) },
+		"--coverage cannot be combined with --rescan, --only-sections or --grep",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.LiteralPools && (!o.ScanDataOnly || format != formatJSON || o.Stats)
+		},
+		"--literal-pools requires --data and --json flags (and cannot be used with --stats)",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.Xrefs && (!o.ScanDataOnly || format != formatJSON || o.Stats)
+		},
+		"--xrefs requires --data and --json flags (and cannot be used with --stats)",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.Relocs && (!o.ScanDataOnly || format != formatJSON || o.Stats)
+		},
+		"--relocs requires --data and --json flags (and cannot be used with --stats)",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.Signatures && (o.Stats || o.Grep || o.Top > 0 || format != formatJSON)
+		},
+		"--signatures requires --json (and cannot be combined with --stats, --grep or --top)",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.Partitions && (o.Stats || o.Grep || o.Top > 0 || format != formatJSON)
+		},
+		"--partitions requires --json (and cannot be combined with --stats, --grep or --top)",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.Xattrs && (o.Stats || o.Grep || o.Top > 0 || format != formatJSON)
+		},
+		"--xattrs requires --json (and cannot be combined with --stats, --grep or --top)",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return (slices.Contains(o.Formats, formatSlack) || slices.Contains(o.Formats, formatTeams)) && (o.Grep || o.StatsPerFile || o.StatsTiming)
+		},
+		"--format slack and teams cannot be combined with --grep, --stats-per-file or --stats-timing",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.Notify && (o.StatsPerFile || o.StatsTiming || o.LiteralPools || o.Xrefs || o.Relocs || o.Unique || o.Grep || o.Unordered)
+		},
+		"--notify-webhook cannot be combined with --stats-per-file, --stats-timing, --literal-pools, --xrefs, --relocs, --unique, --count, --dedupe-fold-case, --grep or --unordered",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.Exec && (o.StatsPerFile || o.StatsTiming || o.LiteralPools || o.Xrefs || o.Relocs || o.Unique || o.Grep || o.Unordered)
+		},
+		"--exec-per-match cannot be combined with --stats-per-file, --stats-timing, --literal-pools, --xrefs, --relocs, --unique, --count, --dedupe-fold-case, --grep or --unordered",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Outputs && (o.StatsPerFile || o.StatsTiming) },
+		"--output and several --format values cannot be combined with --stats-per-file or --stats-timing",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Outputs && (o.LiteralPools || o.Xrefs || o.Relocs) },
+		"--output and several --format values cannot be combined with --literal-pools, --xrefs or --relocs",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.ExtractFS && (o.StatsPerFile || o.StatsTiming || o.LiteralPools || o.Xrefs || o.Relocs || o.Unique || o.Grep || o.Unordered)
+		},
+		"--extract-fs cannot be combined with --stats-per-file, --stats-timing, --literal-pools, --xrefs, --relocs, --unique, --count, --dedupe-fold-case, --grep or --unordered",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.Archives && (o.StatsPerFile || o.StatsTiming || o.LiteralPools || o.Xrefs || o.Relocs || o.Unique || o.Grep || o.Unordered)
+		},
+		"--archives cannot be combined with --stats-per-file, --stats-timing, --literal-pools, --xrefs, --relocs, --unique, --count, --dedupe-fold-case, --grep or --unordered",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.ADS && (o.StatsPerFile || o.StatsTiming || o.LiteralPools || o.Xrefs || o.Relocs || o.Unique || o.Grep || o.Unordered)
+		},
+		"--ads cannot be combined with --stats-per-file, --stats-timing, --literal-pools, --xrefs, --relocs, --unique, --count, --dedupe-fold-case, --grep or --unordered",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.ScanXattrs && (o.StatsPerFile || o.StatsTiming || o.LiteralPools || o.Xrefs || o.Relocs || o.Unique || o.Grep || o.Unordered)
+		},
+		"--scan-xattrs cannot be combined with --stats-per-file, --stats-timing, --literal-pools, --xrefs, --relocs, --unique, --count, --dedupe-fold-case, --grep or --unordered",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Archives && (o.MaxDepth < 1 || o.MaxExpansion < 0) },
+		"--max-depth must be at least 1 and --max-expansion cannot be negative",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Passwords && !o.Archives },
+		"--password and --password-file require --archives",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.Artifacts && (o.LiteralPools || o.Xrefs || o.Relocs || o.Unique || o.Grep || o.Unordered)
+		},
+		"--registry, --evtx, --prefetch, --ntfs, --memory-map and --memory-profile cannot be combined with --literal-pools, --xrefs, --relocs, --unique, --count, --dedupe-fold-case, --grep or --unordered",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.Unique && (o.Stats || format != formatJSON && format != formatCSV)
+		},
+		"--unique, --count and --dedupe-fold-case require --format json or csv (and cannot be used with --stats)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Unique && (o.Outputs || o.MaxMemory) },
+		"--unique, --count and --dedupe-fold-case cannot be combined with --output or --max-memory",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.ValueEncoded && (o.Stats || o.FoldCase || format != formatJSON && format != formatCSV && !o.Outputs)
+		},
+		"--value-encoding base64 and hex require --format json or csv or --output (and cannot be combined with --stats or --dedupe-fold-case)",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.EmitRaw && (o.Stats || o.Top > 0 || format != formatJSON && !o.Outputs)
+		},
+		"--emit-raw requires --json or --output (and cannot be combined with --stats or --top)",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.WideInfo && (o.Stats || o.Top > 0 || format != formatJSON && !o.Outputs)
+		},
+		"--wide-info requires --json or --output (and cannot be combined with --stats or --top)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.AllOffsets && !o.Unique },
+		"--all-offsets requires --unique, --count or --dedupe-fold-case",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.Grep && (o.Stats || o.Outputs || o.Unique || o.ScanDataOnly || format == formatCycloneDX)
+		},
+		"--grep cannot be combined with --stats, --output, --unique, --count, --dedupe-fold-case, --data or --format cyclonedx",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.Unordered && (o.Stats || o.Outputs || o.Grep || format != formatText)
+		},
+		"--unordered requires text output (and cannot be combined with --stats, --output or --grep)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Journal && o.Stdin },
+		"--journal requires file arguments (cannot be used with stdin)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Resume && !o.Journal },
+		"--resume requires --journal",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.Resume && (o.Stats || o.Top > 0 || o.Outputs || format != formatText)
+		},
+		"--resume requires text output (and cannot be combined with --stats, --top, --output or other --format values, which are written when the run ends)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Journal && (o.Grep || o.Unordered) },
+		"--journal cannot be combined with --grep or --unordered",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.PreFilter && (o.Stats || o.Grep || o.Unordered || o.Unique || o.ScanDataOnly || o.LiteralPools || o.Xrefs || o.Relocs ||
+				o.Signatures || o.Partitions || o.OnlySections || o.Rescan || o.Coverage || o.ExtractFS || o.Archives || o.ADS || o.ScanXattrs ||
+				o.Artifacts || o.Media)
+		},
+		"--pre-filter cannot be combined with --stats, --grep, --unordered, --unique, --count, --dedupe-fold-case, or the options reading the input file itself (--data, --literal-pools, --xrefs, --relocs, --signatures, --partitions, --only-sections, --rescan, --coverage, --extract-fs, --archives, --ads, --scan-xattrs, --registry, --evtx, --prefetch, --ntfs, --memory-map, --memory-profile, --evidence and --vdisk)",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.Lines && (o.ScanDataOnly || o.Rescan || o.Coverage || o.Grep)
+		},
+		"--lines cannot be combined with --data, --rescan, --coverage or --grep",
+	},
+	{
+		func(o outputOptions, _ string) bool {
+			return o.Template && !o.Markdown && !slices.Contains(o.Formats, formatMarkdown)
+		},
+		"--report-template requires --format markdown or --output markdown=FILE",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Background && (o.Stats || o.Grep || o.Top > 0) },
+		"--background cannot be combined with --stats, --grep or --top",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.SortRarity && (!o.Background || o.ClusterGap > 0) },
+		"--sort rarity requires --background (and cannot be combined with --cluster-by-offset)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.Top < 0 },
+		"--top requires a positive number of strings",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.Top > 0 && (o.Stats || o.Unique || o.Grep || o.Unordered || o.LiteralPools || o.Xrefs || o.Relocs || format != formatText && format != formatJSON)
+		},
+		"--top requires --format text or json (and cannot be combined with --stats, --unique, --count, --dedupe-fold-case, --grep, --unordered, --literal-pools, --xrefs or --relocs)",
+	},
+	{
+		func(o outputOptions, _ string) bool { return o.ClusterGap < 0 },
+		"--cluster-by-offset requires a positive gap in bytes",
+	},
+	{
+		func(o outputOptions, format string) bool {
+			return o.ClusterGap > 0 && (o.Stats || o.Top > 0 || o.Unique || o.Grep || o.Unordered || format != formatText && format != formatJSON)
+		},
+		"--cluster-by-offset requires --format text or json (and cannot be combined with --stats, --top, --unique, --count, --dedupe-fold-case, --grep or --unordered)",
+	},
+}
+
+// resolveOutputMode validates the output-related options against the conflict
+// matrix and returns the resulting output mode
+func resolveOutputMode(o outputOptions) (outputMode, error) {
+	// Normalize --format values (comma-separated and/or repeated)
+	var formats []string
+	for _, value := range o.Formats {
+		for f := range strings.SplitSeq(value, ",") {
+			f = strings.ToLower(strings.TrimSpace(f))
+			if f == "" {
+				continue
+			}
+			if !slices.Contains(outputFormats, f) {
+				return outputMode{}, fmt.Errorf("unknown output format %q (valid: %s)", f, strings.Join(outputFormats, ", "))
+			}
+			if !slices.Contains(formats, f) {
+				formats = append(formats, f)
+			}
+		}
+	}
+
+	// --json is an alias for --format json
+	if o.JSON {
+		if len(formats) > 0 && !slices.Contains(formats, formatJSON) {
+			return outputMode{}, fmt.Errorf("--json conflicts with --format %s", strings.Join(formats, ","))
+		}
+		if len(formats) == 0 {
+			formats = []string{formatJSON}
+		}
+	}
+
+	format := formatText
+	if len(formats) > 0 {
+		format = formats[0]
+	}
+	var extra []string
+	if len(formats) > 1 {
+		// The other formats are written to files, like --output sinks
+		extra = formats[1:]
+		o.Outputs = true
+	}
+	o.Formats = formats
+
+	for _, rule := range outputRules {
+		if rule.conflict(o, format) {
+			return outputMode{}, errors.New(rule.message)
+		}
+	}
+
+	return outputMode{
+		Format:  format,
+		Stats:   o.Stats,
+		PerFile: o.StatsPerFile,
+		Timing:  o.StatsTiming,
+		Top:     o.Top,
+		Extra:   extra,
+	}, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/richardwooding/txtr/internal/binary"
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+)
+
+// checkPacking analyzes the entropy of a binary scanned with -d and warns on
+// stderr when it appears packed or encrypted, since such files yield few
+// strings (UPX-packed files that --unpack will decompress are not warned about).
+// It returns nil for raw files or when the file cannot be analyzed.
+func checkPacking(filename string, format binary.Format, config extractor.Config) *printer.PackingInfo {
+	if format != binary.FormatELF && format != binary.FormatPE && format != binary.FormatMachO {
+		return nil
+	}
+
+	info, err := binary.AnalyzePacking(filename, format)
+	if err != nil {
+		// Parse errors are reported by the section scan that follows
+		return nil
+	}
+
+	unpacking := info.Packer == "UPX" && config.Unpack != "" && config.Unpack != unpackNever
+	if info.Packed && !unpacking {
+		packer := "packed or encrypted"
+		if info.Packer != "" {
+			packer = "packed with " + info.Packer
+		}
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnPacked, File: filename,
+			Message: fmt.Sprintf("binary appears %s (%s); few strings may be found", packer, info.Reason)})
+	}
+
+	return packingInfo(info)
+}
+
+// packingInfo converts a packing analysis to its JSON form
+func packingInfo(info binary.PackInfo) *printer.PackingInfo {
+	result := &printer.PackingInfo{
+		Packed:  info.Packed,
+		Packer:  info.Packer,
+		Reason:  info.Reason,
+		Entropy: roundEntropy(info.Entropy),
+	}
+	for _, section := range info.Sections {
+		result.Sections = append(result.Sections, printer.SectionEntropy{
+			Name:       section.Name,
+			Size:       section.Size,
+			Entropy:    roundEntropy(section.Entropy),
+			Executable: section.Executable,
+		})
+	}
+	return result
+}
+
+// roundEntropy rounds an entropy value to three decimals for output
+func roundEntropy(entropy float64) float64 {
+	return math.Round(entropy*1000) / 1000
+}
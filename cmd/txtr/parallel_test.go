@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -80,6 +81,65 @@ func TestParallelProcessingOrder(t *testing.T) {
 	}
 }
 
+// TestParallelProcessingUnordered tests that unordered output keeps each
+// file's strings together
+func TestParallelProcessingUnordered(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var filePaths []string
+	for _, name := range []string{"file1.bin", "file2.bin", "file3.bin", "file4.bin"} {
+		path := filepath.Join(tmpDir, name)
+		if err := os.WriteFile(path, []byte("FirstString\x00\x00SecondString\x00"), 0644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", name, err)
+		}
+		filePaths = append(filePaths, path)
+	}
+
+	config := extractor.Config{
+		MinLength:       4,
+		PrintFileName:   true,
+		Encoding:        "s",
+		OutputSeparator: "\n",
+		Unordered:       true,
+	}
+
+	// Capture output
+	var buf bytes.Buffer
+	oldStdout := os.Stdout
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatalf("Failed to create pipe: %v", err)
+	}
+	os.Stdout = w
+
+	processFilesParallel(filePaths, 3, config)
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close pipe writer: %v", err)
+	}
+	os.Stdout = oldStdout
+	if _, err := buf.ReadFrom(r); err != nil {
+		t.Fatalf("Failed to read from pipe: %v", err)
+	}
+
+	// Every file appears once, with its two strings on consecutive lines
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 8 {
+		t.Fatalf("Expected 8 lines, got %d: %q", len(lines), lines)
+	}
+	seen := make(map[string]bool)
+	for i := 0; i < len(lines); i += 2 {
+		file, _, _ := strings.Cut(lines[i], ": ")
+		if seen[file] || lines[i] != file+": FirstString" || lines[i+1] != file+": SecondString" {
+			t.Errorf("Lines %d-%d = %q, want both strings of one file", i+1, i+2, lines[i:i+2])
+		}
+		seen[file] = true
+	}
+	if len(seen) != len(filePaths) {
+		t.Errorf("Got output for %d files, want %d", len(seen), len(filePaths))
+	}
+}
+
 // TestParallelProcessingErrorHandling tests that errors in one file don't stop processing others
 func TestParallelProcessingErrorHandling(t *testing.T) {
 	tmpDir := t.TempDir()
@@ -166,7 +226,7 @@ func TestSequentialVsParallel(t *testing.T) {
 		if err != nil {
 			t.Fatalf("Failed to open file %s: %v", filename, err)
 		}
-		extractor.ExtractStrings(file, filename, config, func(str []byte, fname string, _ int64, cfg extractor.Config) {
+		extractStrings(file, filename, config, func(str []byte, fname string, _ int64, cfg extractor.Config) {
 			if cfg.PrintFileName && fname != "" {
 				seqBuf.WriteString(fname + ": ")
 			}
@@ -350,7 +410,7 @@ func TestJSONOutputStructure(t *testing.T) {
 		}
 	}()
 
-	extractor.ExtractStrings(file, testFile, config, tempPrinter.PrintString)
+	extractStrings(file, testFile, config, tempPrinter.PrintString)
 
 	if err := tempPrinter.Flush(); err != nil {
 		t.Fatalf("Failed to flush JSON: %v", err)
@@ -375,3 +435,38 @@ func TestJSONOutputStructure(t *testing.T) {
 		t.Errorf("Expected min_length=4, got %d", output.Summary.MinLength)
 	}
 }
+
+// TestScheduleJobs tests that jobs are scheduled largest file first
+func TestScheduleJobs(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	var filePaths []string
+	for _, f := range []struct {
+		name string
+		size int
+	}{
+		{"small.bin", 10},
+		{"large.bin", 1000},
+		{"medium.bin", 100},
+		{"same.bin", 100},
+	} {
+		path := filepath.Join(tmpDir, f.name)
+		if err := os.WriteFile(path, make([]byte, f.size), 0644); err != nil {
+			t.Fatalf("Failed to create test file %s: %v", f.name, err)
+		}
+		filePaths = append(filePaths, path)
+	}
+	filePaths = append(filePaths, filepath.Join(tmpDir, "missing.bin"))
+
+	// Equal sizes keep their input order; unknown sizes go last
+	var order []int
+	for _, j := range scheduleJobs(filePaths) {
+		if j.filename != filePaths[j.index] {
+			t.Errorf("job %d has file %s, want %s", j.index, j.filename, filePaths[j.index])
+		}
+		order = append(order, j.index)
+	}
+	if want := []int{1, 2, 3, 0, 4}; !slices.Equal(order, want) {
+		t.Errorf("scheduleJobs() order = %v, want %v", order, want)
+	}
+}
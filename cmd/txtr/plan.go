@@ -0,0 +1,240 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/richardwooding/txtr/internal/binary"
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// Read strategies reported by --dry-run, besides those of whole files (see
+// extractor.ChooseStrategy)
+const (
+	strategyStreamed       = "streamed"
+	strategySections       = "sections"
+	strategySectionsStream = "sections-streamed"
+	strategyFullScan       = "full-scan"
+	strategyFallback       = "full-scan-fallback"
+)
+
+// filePlan describes how a single file would be scanned
+type filePlan struct {
+	File      string   `json:"file"`
+	Size      int64    `json:"size"`
+	Format    string   `json:"format,omitempty"`
+	Source    string   `json:"format_source,omitempty"`
+	Strategy  string   `json:"strategy,omitempty"`
+	Sections  []string `json:"sections,omitempty"`
+	ScanBytes int64    `json:"scan_bytes"`
+	Note      string   `json:"note,omitempty"`
+	Error     string   `json:"error,omitempty"`
+}
+
+// scanPlan describes a whole run (see --dry-run)
+type scanPlan struct {
+	Files      []filePlan `json:"files"`
+	Mode       string     `json:"mode"`
+	Workers    int        `json:"workers"`
+	Parallel   bool       `json:"parallel"`
+	IOProfile  string     `json:"io_profile,omitempty"`
+	TotalBytes int64      `json:"total_bytes"`
+	Errors     int        `json:"errors"`
+}
+
+// resolveFormat returns the binary format of a file and how it was decided.
+// Detection is per file; -T/--target is a hint used only for files that are
+// not recognized as an executable. With --rescan, the format recorded by the
+// previous scan is used without detecting it.
+func resolveFormat(filename string, config extractor.Config) (binary.Format, binary.FormatSource, error) {
+	if file, ok := config.Rescan.Lookup(filename); ok {
+		if format := binary.ParseFormat(file.Format); format != binary.FormatUnknown {
+			return format, binary.SourceRecorded, nil
+		}
+	}
+	return binary.ResolveFormat(filename, targetHint(config.TargetFormat))
+}
+
+// targetHint converts a -T/--target value to a format hint
+func targetHint(target string) binary.Format {
+	switch target {
+	case "elf":
+		return binary.FormatELF
+	case "pe":
+		return binary.FormatPE
+	case "macho":
+		return binary.FormatMachO
+	case "binary":
+		return binary.FormatRaw
+	default:
+		return binary.FormatUnknown
+	}
+}
+
+// buildPlan determines how each file would be scanned without extracting strings
+func buildPlan(files []string, workers int, config extractor.Config, mode string) scanPlan {
+	plan := scanPlan{Mode: mode, Workers: 1}
+
+	// Mirror the worker selection of the processing functions
+	if len(files) > 1 && workers > 1 && (mode != "json" || config.MaxMemory == 0) {
+		plan.Workers = workers
+		plan.Parallel = true
+		config = workerConfig(config, workers)
+	}
+
+	for _, filename := range files {
+		fp := planFile(filename, config)
+		if fp.Error != "" {
+			plan.Errors++
+		}
+		plan.TotalBytes += fp.ScanBytes
+		plan.Files = append(plan.Files, fp)
+	}
+
+	return plan
+}
+
+// planFile determines the format and read strategy for a single file
+func planFile(filename string, config extractor.Config) filePlan {
+	fp := filePlan{File: filename}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		fp.Error = err.Error()
+		return fp
+	}
+	if info.IsDir() {
+		fp.Error = "is a directory"
+		return fp
+	}
+	fp.Size = info.Size()
+
+	format, source, err := resolveFormat(filename, config)
+	if err != nil {
+		fp.Error = err.Error()
+		return fp
+	}
+	fp.Format = format.String()
+	fp.Source = string(source)
+
+	if sections, ok := rescanSections(filename, config); ok {
+		return planSections(fp, sections, config)
+	}
+	if sections, _, ok := sampleSections(filename, config); ok {
+		return planSections(fp, sections, config)
+	}
+	if !config.ScanDataOnly || format == binary.FormatRaw || format == binary.FormatUnknown {
+		fp.Strategy = fileStrategy(filename, fp.Size, config)
+		fp.ScanBytes = fp.Size
+		return fp
+	}
+
+	sections, err := binary.ParseSectionHeaders(filename, format)
+	if err == nil {
+		sections, err = onlySections(sections, config)
+	}
+	if err != nil {
+		fp.Strategy = strategyFallback
+		fp.ScanBytes = fp.Size
+		fp.Note = fmt.Sprintf("cannot parse as %v: %v", format, err)
+		return fp
+	}
+	if len(sections) == 0 {
+		fp.Strategy = strategyFullScan
+		fp.ScanBytes = fp.Size
+		fp.Note = "no data sections found"
+		return fp
+	}
+	return planSections(fp, sections, config)
+}
+
+// planSections completes the plan of a file scanned by sections
+func planSections(fp filePlan, sections []binary.Section, config extractor.Config) filePlan {
+	for _, section := range sections {
+		fp.Sections = append(fp.Sections, section.Name)
+		fp.ScanBytes += section.Size
+	}
+	fp.Strategy = strategySections
+	if config.MaxMemory > 0 && fp.ScanBytes > config.MaxMemory {
+		fp.Strategy = strategySectionsStream
+	}
+	return fp
+}
+
+// fileStrategy returns how a whole file would be read
+func fileStrategy(filename string, size int64, config extractor.Config) string {
+	if config.MaxMemory > 0 && size > config.MaxMemory {
+		return strategyStreamed
+	}
+	return string(extractor.ChooseStrategy(filename, config))
+}
+
+// writePlan writes a human-readable plan
+//
+//nolint:errcheck // Writing to stdout, errors are not critical
+func writePlan(w io.Writer, plan scanPlan) {
+	fmt.Fprintf(w, "Dry run: %d file(s), nothing will be extracted\n\n", len(plan.Files))
+
+	for _, fp := range plan.Files {
+		if fp.Error != "" {
+			fmt.Fprintf(w, "  %s: error: %s\n", fp.File, fp.Error)
+			continue
+		}
+
+		fmt.Fprintf(w, "  %s\n", fp.File)
+		fmt.Fprintf(w, "    size:      %s bytes\n", formatCount(fp.Size))
+		fmt.Fprintf(w, "    format:    %s (%s)\n", fp.Format, fp.Source)
+		fmt.Fprintf(w, "    strategy:  %s\n", fp.Strategy)
+		if len(fp.Sections) > 0 {
+			fmt.Fprintf(w, "    sections:  %s\n", strings.Join(fp.Sections, ", "))
+		}
+		fmt.Fprintf(w, "    scan:      %s bytes\n", formatCount(fp.ScanBytes))
+		if fp.Note != "" {
+			fmt.Fprintf(w, "    note:      %s\n", fp.Note)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "Mode:        %s\n", plan.Mode)
+	if plan.Parallel {
+		fmt.Fprintf(w, "Workers:     %d (parallel)\n", plan.Workers)
+	} else {
+		fmt.Fprintf(w, "Workers:     1 (sequential)\n")
+	}
+	if plan.IOProfile != "" {
+		fmt.Fprintf(w, "I/O profile: %s\n", plan.IOProfile)
+	}
+	fmt.Fprintf(w, "Total scan:  %s bytes\n", formatCount(plan.TotalBytes))
+	if plan.Errors > 0 {
+		fmt.Fprintf(w, "Errors:      %d file(s) would fail\n", plan.Errors)
+	}
+}
+
+// writePlanJSON writes the plan as JSON
+func writePlanJSON(w io.Writer, plan scanPlan) error {
+	if plan.Files == nil {
+		plan.Files = make([]filePlan, 0)
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(plan)
+}
+
+// formatCount formats a number with thousands separators
+func formatCount(n int64) string {
+	s := fmt.Sprintf("%d", n)
+	if n < 0 {
+		return s
+	}
+	var b strings.Builder
+	for i, c := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// preFilterStderr bounds the error output of a --pre-filter command kept for
+// its error message
+const preFilterStderr = 4 << 10
+
+// scanPreFiltered scans what the --pre-filter command decodes input to: the
+// command gets input on stdin, and the name of the file in TXTR_FILE (empty
+// for stdin), and its stdout is scanned as the content of filename, so
+// strings are attributed to the file and their offsets are in the decoded
+// bytes. A command that fails is an error of the file; the strings it wrote
+// before are still reported.
+func scanPreFiltered(input io.Reader, filename string, config extractor.Config, printFunc func([]byte, string, int64, extractor.Config)) error {
+	cmd := preFilterCommand(config.PreFilter)
+	cmd.Stdin = input
+	cmd.Env = append(os.Environ(), "TXTR_FILE="+filename)
+	stderr := &cappedBuffer{limit: preFilterStderr}
+	cmd.Stderr = stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("--pre-filter: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("--pre-filter: %w", err)
+	}
+
+	extractStrings(stdout, filename, config, printFunc)
+	// Drain what was not scanned, so the command is not blocked writing
+	_, _ = io.Copy(io.Discard, stdout)
+
+	if err := cmd.Wait(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("--pre-filter: %w: %s", err, msg)
+		}
+		return fmt.Errorf("--pre-filter: %w", err)
+	}
+	return nil
+}
+
+// scanPreFilteredFile scans what the --pre-filter command decodes the file
+// at filename to (see scanPreFiltered)
+func scanPreFilteredFile(filename string, config extractor.Config, printFunc func([]byte, string, int64, extractor.Config)) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer closeInput(file, filename, config)
+	return scanPreFiltered(file, filename, config, printFunc)
+}
+
+// preFilterCommand returns the command running a --pre-filter command line
+// with the shell, so it may have arguments and pipes
+func preFilterCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
+
+// cappedBuffer keeps the first limit bytes written to it and discards the
+// rest
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+// Write implements io.Writer
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := b.limit - b.Len(); room > 0 {
+		b.Buffer.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
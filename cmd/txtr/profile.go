@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+)
+
+// profiler holds the output files of an in-progress profiling session
+type profiler struct {
+	cpuFile   *os.File
+	traceFile *os.File
+	memPath   string
+}
+
+// startProfiling starts CPU profiling and execution tracing for the paths that
+// are set. The heap profile is written by stop. Call stop before exiting to
+// flush all profiles.
+func startProfiling(cpuPath, memPath, tracePath string) (*profiler, error) {
+	p := &profiler{memPath: memPath}
+
+	if cpuPath != "" {
+		file, err := os.Create(cpuPath)
+		if err != nil {
+			return nil, fmt.Errorf("error creating CPU profile: %w", err)
+		}
+		if err := pprof.StartCPUProfile(file); err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("error starting CPU profile: %w", err)
+		}
+		p.cpuFile = file
+	}
+
+	if tracePath != "" {
+		file, err := os.Create(tracePath)
+		if err != nil {
+			p.stop()
+			return nil, fmt.Errorf("error creating trace: %w", err)
+		}
+		if err := trace.Start(file); err != nil {
+			_ = file.Close()
+			p.stop()
+			return nil, fmt.Errorf("error starting trace: %w", err)
+		}
+		p.traceFile = file
+	}
+
+	return p, nil
+}
+
+// stop finishes CPU profiling and tracing and writes the heap profile.
+// Errors are reported as warnings since the run itself succeeded.
+func (p *profiler) stop() {
+	if p.cpuFile != nil {
+		pprof.StopCPUProfile()
+		if err := p.cpuFile.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "strings: warning: error writing CPU profile: %v\n", err)
+		}
+		p.cpuFile = nil
+	}
+
+	if p.traceFile != nil {
+		trace.Stop()
+		if err := p.traceFile.Close(); err != nil {
+			fmt.Fprintf(os.Stderr, "strings: warning: error writing trace: %v\n", err)
+		}
+		p.traceFile = nil
+	}
+
+	if p.memPath != "" {
+		if err := writeHeapProfile(p.memPath); err != nil {
+			fmt.Fprintf(os.Stderr, "strings: warning: %v\n", err)
+		}
+		p.memPath = ""
+	}
+}
+
+// writeHeapProfile writes a heap profile reflecting all allocations of the run
+func writeHeapProfile(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating memory profile: %w", err)
+	}
+
+	// Run a GC so the profile includes up-to-date allocation statistics
+	runtime.GC()
+	if err := pprof.Lookup("allocs").WriteTo(file, 0); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("error writing memory profile: %w", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// apiKeys are the keys clients of "txtr serve" authenticate with
+// (--api-keys), by their SHA-256 digest so they are not compared byte by
+// byte
+type apiKeys map[[sha256.Size]byte]bool
+
+// loadAPIKeys reads the keys of an API key file: one key per line, blank
+// lines and # comments ignored
+func loadAPIKeys(path string) (apiKeys, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	keys := make(apiKeys)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key := strings.TrimSpace(scanner.Text())
+		if key == "" || strings.HasPrefix(key, "#") {
+			continue
+		}
+		keys[sha256.Sum256([]byte(key))] = true
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%s has no keys", path)
+	}
+	return keys, nil
+}
+
+// requestKey returns the API key of a request: the token of an
+// "Authorization: Bearer" header, or the X-API-Key header
+func requestKey(r *http.Request) string {
+	if token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+		return strings.TrimSpace(token)
+	}
+	return r.Header.Get("X-API-Key")
+}
+
+// client identifies the client of a request for its limits: by its API key
+// if keys are required, so clients behind one proxy are told apart, and by
+// its address otherwise
+func (keys apiKeys) client(r *http.Request) (string, bool) {
+	if keys == nil {
+		host, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			host = r.RemoteAddr
+		}
+		return host, true
+	}
+	digest := sha256.Sum256([]byte(requestKey(r)))
+	if !keys[digest] {
+		return "", false
+	}
+	// Name the client by a digest prefix, never by the key itself
+	return "key " + hex.EncodeToString(digest[:4]), true
+}
+
+// clientLimiter limits the requests each client has in progress
+// (--max-concurrent), so one heavy client cannot take all the workers of
+// the service
+type clientLimiter struct {
+	mu     sync.Mutex
+	max    int // Requests in progress per client (0 = unlimited)
+	active map[string]int
+}
+
+// acquire reserves a request of client, reporting false if the client is at
+// its limit. Requests acquired must be released.
+func (l *clientLimiter) acquire(client string) bool {
+	if l.max <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[client] >= l.max {
+		return false
+	}
+	if l.active == nil {
+		l.active = make(map[string]int)
+	}
+	l.active[client]++
+	return true
+}
+
+// release ends a request acquired by client
+func (l *clientLimiter) release(client string) {
+	if l.max <= 0 {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[client]--; l.active[client] <= 0 {
+		delete(l.active, client)
+	}
+}
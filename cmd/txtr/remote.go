@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+)
+
+// RemoteCLI defines the command-line interface of the remote subcommand
+type RemoteCLI struct {
+	Server          string        `name:"server" required:"" placeholder:"HOST:PORT" help:"Address of the txtr serve service (host:port, or an http:// or https:// URL)"`
+	APIKey          string        `name:"api-key" env:"TXTR_API_KEY" placeholder:"KEY" help:"API key of the service (see txtr serve --api-keys); prefer the environment variable, which other users cannot list"`
+	MinLength       int           `short:"n" name:"bytes" default:"0" help:"Minimum string length (0=the default of the service)"`
+	Encoding        string        `short:"e" name:"encoding" enum:"s,S,b,l,B,L," default:"" help:"Character encoding (s, S, b, l, B, L; see txtr --help; default: the service's)"`
+	PrintFileName   bool          `short:"f" name:"print-file-name" help:"Print file name before each string"`
+	Radix           string        `short:"t" name:"radix" enum:"o,d,x," default:"" help:"Print offset in radix (o=octal, d=decimal, x=hex)"`
+	OctalOffset     bool          `short:"o" help:"Print offset in octal (alias for -t o)"`
+	OutputSeparator string        `short:"s" name:"output-separator" default:"\\n" help:"Output record separator (default: newline)"`
+	JSON            bool          `short:"j" name:"json" help:"Output the results of the service in JSON format, as txtr --json"`
+	ValueEncoding   string        `name:"value-encoding" enum:"utf8,base64,hex" default:"utf8" help:"How string values are written in JSON (see txtr --help)"`
+	Color           string        `name:"color" enum:"auto,always,never" default:"auto" help:"When to use colored output (auto/always/never)"`
+	Timeout         time.Duration `name:"timeout" placeholder:"DURATION" default:"0" help:"Give up on a file whose upload and scan take longer than DURATION (0=wait)"`
+	Shared          bool          `name:"shared" help:"Send the paths of the files, relative to the --root directory of the service on shared storage, instead of uploading them"`
+	Files           []string      `arg:"" name:"file" help:"Files to send to the service (with --shared, their paths under its --root)"`
+}
+
+// runRemote implements "txtr remote": it sends each file to a "txtr serve"
+// service and prints the strings it returns as txtr prints them locally, so
+// analysts can scan on a shared service without handling HTTP. With --shared
+// the service reads the files from storage it shares with the client instead
+// of having them uploaded. It returns the
+// process exit code: 1 if any file could not be scanned.
+func runRemote(args []string) int {
+	var cli RemoteCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr remote"),
+		kong.Description("Extract the strings of files on a txtr serve service, printing them like txtr does."),
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	if cli.MinLength < 0 {
+		fmt.Fprintf(os.Stderr, "error: minimum string length cannot be negative\n")
+		return 1
+	}
+	endpoint, err := remoteEndpoint(cli.Server)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: invalid --server: %v\n", err)
+		return 1
+	}
+	if cli.OctalOffset {
+		cli.Radix = "o"
+	}
+	outputSep := cli.OutputSeparator
+	switch outputSep {
+	case "\\n":
+		outputSep = "\n"
+	case "\\t":
+		outputSep = "\t"
+	case "\\r":
+		outputSep = "\r"
+	}
+	config := extractor.Config{
+		PrintFileName:   cli.PrintFileName,
+		Radix:           cli.Radix,
+		PrintOffset:     cli.Radix != "",
+		OutputSeparator: outputSep,
+		ColorMode:       parseColorMode(cli.Color),
+	}
+
+	// Text output needs the exact bytes of the strings, which UTF-8 values
+	// of 8-bit strings would not keep
+	query := url.Values{}
+	if cli.MinLength > 0 {
+		query.Set("min_length", strconv.Itoa(cli.MinLength))
+	}
+	if cli.Encoding != "" {
+		query.Set("encoding", cli.Encoding)
+	}
+	switch {
+	case !cli.JSON:
+		query.Set("value_encoding", printer.ValueBase64)
+	case cli.ValueEncoding != "utf8":
+		query.Set("value_encoding", cli.ValueEncoding)
+	}
+
+	client := &http.Client{Timeout: cli.Timeout}
+	out := bufio.NewWriter(os.Stdout)
+	output := printer.JSONOutput{Files: []printer.FileResult{}}
+	status := 0
+	for i, filename := range cli.Files {
+		result, err := remoteExtract(client, endpoint, filename, query, cli.APIKey, cli.Shared)
+		if err != nil {
+			_ = out.Flush()
+			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
+			status = 1
+			continue
+		}
+		if cli.JSON {
+			output.Files = append(output.Files, result.Files...)
+			output.Summary.TotalStrings += result.Summary.TotalStrings
+			output.Summary.TotalBytes += result.Summary.TotalBytes
+			output.Summary.TruncatedResults = output.Summary.TruncatedResults || result.Summary.TruncatedResults
+			if i == 0 {
+				output.Summary.MinLength, output.Summary.Encoding = result.Summary.MinLength, result.Summary.Encoding
+			}
+			continue
+		}
+		if err := printRemoteResult(out, result, config); err != nil {
+			_ = out.Flush()
+			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", filename, err)
+			status = 1
+		}
+	}
+	if err := out.Flush(); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if cli.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(output); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+	}
+	return status
+}
+
+// remoteEndpoint returns the URL of the /extract endpoint of the service at
+// server, a host:port (served over HTTP) or a URL
+func remoteEndpoint(server string) (*url.URL, error) {
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+	base, err := url.Parse(server)
+	if err != nil {
+		return nil, err
+	}
+	if base.Scheme != "http" && base.Scheme != "https" || base.Host == "" {
+		return nil, fmt.Errorf("%q is not a host:port or an http:// or https:// URL", server)
+	}
+	return base.JoinPath("extract"), nil
+}
+
+// remoteExtract sends a file to the /extract endpoint of a service with the
+// query parameters given, returning the results of its scan. A shared file is
+// named by its path under the --root directory of the service rather than
+// uploaded.
+func remoteExtract(client *http.Client, endpoint *url.URL, filename string, query url.Values, apiKey string, shared bool) (printer.JSONOutput, error) {
+	var result printer.JSONOutput
+	q := url.Values{"name": {filename}}
+	for key, values := range query {
+		q[key] = values
+	}
+	var body io.Reader
+	var size int64
+	if shared {
+		q.Set("path", filepath.ToSlash(filename))
+	} else {
+		file, err := os.Open(filename)
+		if err != nil {
+			return result, err
+		}
+		defer func() { _ = file.Close() }()
+		info, err := file.Stat()
+		if err != nil {
+			return result, err
+		}
+		body, size = file, info.Size()
+	}
+
+	u := *endpoint
+	u.RawQuery = q.Encode()
+	req, err := http.NewRequest(http.MethodPost, u.String(), body)
+	if err != nil {
+		return result, err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", "application/octet-stream")
+	if apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+apiKey)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode != http.StatusOK {
+		// Errors of the service are a line of text
+		message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return result, fmt.Errorf("server: %s (%s)", strings.TrimSpace(string(message)), resp.Status)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return result, fmt.Errorf("invalid response from the server: %w", err)
+	}
+	return result, nil
+}
+
+// printRemoteResult prints the strings of a scan by the service as text to
+// out, and reports its warnings
+func printRemoteResult(out *bufio.Writer, result printer.JSONOutput, config extractor.Config) error {
+	tp := printer.NewTextPrinter(out, config)
+	for _, file := range result.Files {
+		for _, str := range file.Strings {
+			value, err := str.Bytes()
+			if err != nil {
+				return fmt.Errorf("invalid response from the server: %w", err)
+			}
+			strConfig := config
+			strConfig.Encoding = printer.EncodingCode(str.Encoding)
+			tp.PrintString(value, file.File, str.Offset, strConfig)
+		}
+		if len(file.Warnings) == 0 {
+			continue
+		}
+		// Report warnings after the strings, as a local scan does
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		for _, w := range file.Warnings {
+			reportWarning(extractor.Warning{Severity: w.Severity, Code: w.Code, File: file.File, Message: w.Message})
+		}
+	}
+	return out.Flush()
+}
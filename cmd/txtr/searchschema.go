@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/printer"
+)
+
+// SearchSchemaCLI defines the command-line interface of the search-schema
+// subcommand
+type SearchSchemaCLI struct {
+	Engine string `arg:"" name:"engine" enum:"tantivy,bleve" help:"Search engine: tantivy (schema.json of tantivy-cli) or bleve (index mapping for bleve create -m)"`
+}
+
+// runSearchSchema implements "txtr search-schema": it writes the schema of
+// the documents of --output search=PATH for a search engine to stdout. It
+// returns the process exit code.
+func runSearchSchema(args []string) int {
+	var cli SearchSchemaCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr search-schema"),
+		kong.Description("Print the index schema for the string documents written by --output search=PATH (tantivy or bleve)."),
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	write := printer.WriteTantivySchema
+	if cli.Engine == "bleve" {
+		write = printer.WriteBleveMapping
+	}
+	if err := write(os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	return 0
+}
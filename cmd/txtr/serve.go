@@ -0,0 +1,414 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/ignore"
+	"github.com/richardwooding/txtr/internal/noise"
+	"github.com/richardwooding/txtr/internal/printer"
+	"github.com/richardwooding/txtr/internal/service"
+)
+
+// ServeCLI defines the command-line interface of the serve subcommand
+type ServeCLI struct {
+	Listen          string        `name:"listen" default:"localhost:8080" placeholder:"ADDR" help:"Address to listen on, unless sockets are passed by systemd socket activation"`
+	MinLength       int           `short:"n" name:"bytes" default:"4" help:"Minimum string length of requests not setting min_length"`
+	Encoding        string        `short:"e" name:"encoding" enum:"s,S,b,l,B,L" default:"s" help:"Character encoding of requests not setting encoding (s, S, b, l, B, L; see txtr --help)"`
+	MatchPatterns   []string      `short:"m" name:"match" placeholder:"PATTERN" help:"Only report strings matching pattern (can be specified multiple times)"`
+	ExcludePatterns []string      `short:"M" name:"exclude" placeholder:"PATTERN" help:"Drop strings matching pattern (can be specified multiple times)"`
+	MatchFiles      []string      `name:"match-file" placeholder:"FILE" type:"existingfile" help:"Only report strings matching a pattern of FILE, one per line (reloaded on SIGHUP; can be specified multiple times)"`
+	ExcludeFiles    []string      `name:"exclude-file" placeholder:"FILE" type:"existingfile" help:"Drop strings matching a pattern of FILE, one per line (reloaded on SIGHUP; can be specified multiple times)"`
+	IgnoreCase      bool          `short:"i" name:"ignore-case" help:"Case-insensitive pattern matching"`
+	IgnoreFiles     []string      `name:"ignore-file" placeholder:"FILE" type:"existingfile" help:"Drop strings matching the rules of an ignore file (reloaded on SIGHUP; can be specified multiple times)"`
+	FilterCommon    bool          `name:"filter-common" help:"Drop strings found in the common strings database (reloaded on SIGHUP, e.g. after txtr db update)"`
+	ShutdownTimeout time.Duration `name:"shutdown-timeout" placeholder:"DURATION" default:"30s" help:"On SIGTERM or SIGINT, wait this long for requests in progress to complete"`
+	APIKeys         string        `name:"api-keys" placeholder:"FILE" type:"existingfile" help:"Require requests to /extract to send one of the keys in FILE (one per line) as Authorization: Bearer KEY or X-API-Key (reloaded on SIGHUP)"`
+	MaxUpload       string        `name:"max-upload" placeholder:"SIZE" default:"" help:"Reject files (uploaded or named by path) larger than SIZE (e.g. 100M) with 413"`
+	MaxStrings      int           `name:"max-strings" placeholder:"N" default:"0" help:"Report at most N strings per request, flagging truncated_results (0=unlimited)"`
+	MaxDuration     time.Duration `name:"max-duration" placeholder:"DURATION" default:"0" help:"Abort requests taking longer than DURATION to upload and scan with 503 (0=unlimited)"`
+	MaxConcurrent   int           `name:"max-concurrent" placeholder:"N" default:"0" help:"Requests each client (API key, or address without --api-keys) may have in progress; more get 429 (0=unlimited)"`
+	Root            string        `name:"root" placeholder:"DIR" type:"existingdir" help:"Let requests name a file under DIR with the path query parameter instead of uploading it, for storage shared with clients"`
+}
+
+// extractServer is the HTTP service of "txtr serve": POST /extract returns
+// the strings of the request body as the JSON of txtr --json, GET /healthz
+// reports that the process is alive and GET /readyz that it takes requests.
+// With --root, requests may name a file under that directory instead of
+// uploading it; they cannot reach files outside it.
+// Requests are held to the limits of the service (--max-upload,
+// --max-strings, --max-duration, --max-concurrent and --api-keys), so one
+// heavy client cannot starve the others.
+type extractServer struct {
+	cli       ServeCLI
+	maxUpload int64                      // Largest request body (0 = unlimited)
+	state     atomic.Pointer[serveState] // Rules of requests, replaced on reload
+	ready     atomic.Bool                // Taking requests (false while starting and stopping)
+	limiter   clientLimiter
+	root      *os.Root // Directory of files named by requests (nil = uploads only)
+}
+
+// serveState is what "txtr serve" reloads on SIGHUP
+type serveState struct {
+	config extractor.Config // Configuration of requests
+	keys   apiKeys          // Keys clients authenticate with (nil = none required)
+}
+
+// newExtractServer creates the service of the options given, loading its
+// rules
+func newExtractServer(cli ServeCLI) (*extractServer, error) {
+	if cli.MinLength < 1 {
+		return nil, errors.New("minimum string length must be at least 1")
+	}
+	if cli.MaxStrings < 0 || cli.MaxDuration < 0 || cli.MaxConcurrent < 0 {
+		return nil, errors.New("--max-strings, --max-duration and --max-concurrent cannot be negative")
+	}
+	maxUpload, err := parseByteSize(cli.MaxUpload)
+	if err != nil {
+		return nil, fmt.Errorf("invalid --max-upload value: %w", err)
+	}
+	s := &extractServer{cli: cli, maxUpload: maxUpload, limiter: clientLimiter{max: cli.MaxConcurrent}}
+	if cli.Root != "" {
+		if s.root, err = os.OpenRoot(cli.Root); err != nil {
+			return nil, fmt.Errorf("invalid --root: %w", err)
+		}
+	}
+	return s, s.load()
+}
+
+// runServe implements "txtr serve": it serves extraction over HTTP on the
+// sockets passed by systemd or on --listen until SIGTERM or SIGINT,
+// reloading its pattern files, ignore files, common strings database and API
+// keys on SIGHUP. It returns the process exit code.
+func runServe(args []string) int {
+	var cli ServeCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr serve"),
+		kong.Description("Serve string extraction over HTTP: POST a file to /extract for its strings as JSON. Supports systemd socket activation and readiness notification, reloads its pattern, ignore and API key files on SIGHUP and reports health on /healthz and /readyz."),
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	s, err := newExtractServer(cli)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	listeners, err := service.Listeners()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if len(listeners) == 0 {
+		listener, err := net.Listen("tcp", cli.Listen)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		listeners = append(listeners, listener)
+	}
+
+	// Handle signals before taking requests, so an early SIGHUP does not
+	// kill the process
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+
+	server := &http.Server{Handler: s.routes(), ReadHeaderTimeout: 10 * time.Second}
+	serveErrors := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		fmt.Fprintf(os.Stderr, "txtr serve: listening on %s\n", listener.Addr())
+		go func() { serveErrors <- server.Serve(listener) }()
+	}
+	s.ready.Store(true)
+	notifyService(service.Ready)
+
+	for {
+		select {
+		case err := <-serveErrors:
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		case sig := <-signals:
+			if sig == syscall.SIGHUP {
+				notifyService(service.Reloading)
+				if err := s.load(); err != nil {
+					fmt.Fprintf(os.Stderr, "txtr serve: reload failed, keeping the previous rules: %v\n", err)
+				} else {
+					fmt.Fprintf(os.Stderr, "txtr serve: reloaded\n")
+				}
+				notifyService(service.Ready)
+				continue
+			}
+
+			// Fail readiness checks, so load balancers stop sending
+			// requests, and let those in progress complete
+			s.ready.Store(false)
+			notifyService(service.Stopping)
+			ctx, cancel := context.WithTimeout(context.Background(), cli.ShutdownTimeout)
+			err := server.Shutdown(ctx)
+			cancel()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: shutting down: %v\n", err)
+				return 1
+			}
+			return 0
+		}
+	}
+}
+
+// notifyService reports the state of the service to systemd, warning if it
+// cannot be reported
+func notifyService(state string) {
+	if err := service.Notify(state); err != nil {
+		fmt.Fprintf(os.Stderr, "txtr serve: warning: cannot notify systemd: %v\n", err)
+	}
+}
+
+// load builds the configuration of requests, reading the pattern files, the
+// ignore files, the common strings database and the API keys again. The new
+// rules replace those in use all at once, so no request sees part of them; on
+// error the rules in use are kept.
+func (s *extractServer) load() error {
+	config := extractor.Config{
+		MinLength:       s.cli.MinLength,
+		Encoding:        s.cli.Encoding,
+		OutputSeparator: "\n",
+		MaxStrings:      s.cli.MaxStrings,
+	}
+	var err error
+	if config.MatchPatterns, err = s.patterns(s.cli.MatchPatterns, s.cli.MatchFiles); err != nil {
+		return fmt.Errorf("invalid match pattern: %w", err)
+	}
+	if config.ExcludePatterns, err = s.patterns(s.cli.ExcludePatterns, s.cli.ExcludeFiles); err != nil {
+		return fmt.Errorf("invalid exclude pattern: %w", err)
+	}
+	if len(s.cli.IgnoreFiles) > 0 {
+		list, err := ignore.Load(s.cli.IgnoreFiles...)
+		if err != nil {
+			return fmt.Errorf("invalid ignore file: %w", err)
+		}
+		config.IgnoreList = list
+	}
+	if s.cli.FilterCommon {
+		common, _, err := noise.Load()
+		if err != nil {
+			return fmt.Errorf("cannot load common strings database: %w", err)
+		}
+		config.CommonStrings = common
+	}
+	var keys apiKeys
+	if s.cli.APIKeys != "" {
+		if keys, err = loadAPIKeys(s.cli.APIKeys); err != nil {
+			return fmt.Errorf("invalid API key file: %w", err)
+		}
+	}
+	s.state.Store(&serveState{config: config, keys: keys})
+	return nil
+}
+
+// patterns compiles the patterns given and those of the pattern files, nil if
+// there are none
+func (s *extractServer) patterns(patterns, files []string) ([]*regexp.Regexp, error) {
+	patterns = slices.Clone(patterns)
+	for _, path := range files {
+		filePatterns, err := readPatterns(path)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, filePatterns...)
+	}
+	if len(patterns) == 0 {
+		return nil, nil
+	}
+	return extractor.CompilePatterns(patterns, s.cli.IgnoreCase)
+}
+
+// readPatterns returns the patterns of a pattern file, one per line; blank
+// lines and lines starting with # are skipped
+func readPatterns(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		pattern := strings.TrimSuffix(scanner.Text(), "\r")
+		if strings.TrimSpace(pattern) == "" || strings.HasPrefix(pattern, "#") {
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, scanner.Err()
+}
+
+// routes returns the handler of the service's endpoints
+func (s *extractServer) routes() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /extract", s.handleExtract)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("GET /readyz", func(w http.ResponseWriter, _ *http.Request) {
+		if !s.ready.Load() {
+			http.Error(w, "not ready", http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = fmt.Fprintln(w, "ready")
+	})
+	return mux
+}
+
+// handleExtract returns the strings of the request body, or of the file under
+// --root named by the path query parameter, as the JSON of txtr --json. The
+// query parameters name (the file name reported), min_length and encoding
+// override the defaults of the service, and value_encoding (base64 or hex)
+// encodes the values as --value-encoding does.
+func (s *extractServer) handleExtract(w http.ResponseWriter, r *http.Request) {
+	state := s.state.Load()
+	client, ok := state.keys.client(r)
+	if !ok {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "a valid API key is required", http.StatusUnauthorized)
+		return
+	}
+	if !s.limiter.acquire(client) {
+		http.Error(w, "too many requests in progress", http.StatusTooManyRequests)
+		return
+	}
+	defer s.limiter.release(client)
+
+	if s.maxUpload > 0 {
+		if r.ContentLength > s.maxUpload {
+			http.Error(w, "file too large", http.StatusRequestEntityTooLarge)
+			return
+		}
+		r.Body = http.MaxBytesReader(w, r.Body, s.maxUpload)
+	}
+	ctx := r.Context()
+	if s.cli.MaxDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.cli.MaxDuration)
+		defer cancel()
+		// The deadline also stops waiting for a slow upload
+		_ = http.NewResponseController(w).SetReadDeadline(time.Now().Add(s.cli.MaxDuration))
+	}
+
+	config := state.config
+	query := r.URL.Query()
+	if value := query.Get("min_length"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			http.Error(w, "min_length must be a positive number", http.StatusBadRequest)
+			return
+		}
+		config.MinLength = n
+	}
+	if value := query.Get("encoding"); value != "" {
+		if !slices.Contains(indexEncodings, value) {
+			http.Error(w, fmt.Sprintf("unknown encoding %q", value), http.StatusBadRequest)
+			return
+		}
+		config.Encoding = value
+	}
+	if value := query.Get("value_encoding"); value != "" {
+		if value != printer.ValueBase64 && value != printer.ValueHex {
+			http.Error(w, fmt.Sprintf("unknown value_encoding %q", value), http.StatusBadRequest)
+			return
+		}
+		config.ValueEncoding = value
+	}
+	name := query.Get("name")
+	input := io.Reader(r.Body)
+	if path := query.Get("path"); path != "" {
+		file, status, err := s.openShared(path)
+		if err != nil {
+			http.Error(w, err.Error(), status)
+			return
+		}
+		defer func() { _ = file.Close() }()
+		input = file
+		if name == "" {
+			name = path
+		}
+	}
+
+	// Results are collected until the body is read, so a failed read is
+	// still answered with an error status
+	jsonPrinter := printer.NewJSONPrinter(config, w)
+	jsonPrinter.SetFileInfo(name, "", nil)
+	config.Warnings = jsonPrinter.AddWarning
+	printFunc := extractor.LimitStrings(jsonPrinter.PrintString, name, config)
+	if err := extractor.ExtractStringsContext(ctx, input, name, config, printFunc); err != nil {
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			http.Error(w, "file too large", http.StatusRequestEntityTooLarge)
+		case errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded):
+			http.Error(w, "request took too long", http.StatusServiceUnavailable)
+		default:
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = jsonPrinter.Flush()
+}
+
+// openShared opens the file under --root named by the path of a request
+// (slash separated, relative to the root), returning the status to answer if
+// it cannot be scanned. Paths leaving the root, including through symbolic
+// links, are refused.
+func (s *extractServer) openShared(path string) (*os.File, int, error) {
+	if s.root == nil {
+		return nil, http.StatusForbidden, errors.New("path references are not enabled (see txtr serve --root)")
+	}
+	file, err := s.root.Open(filepath.FromSlash(path))
+	if err != nil {
+		// The error would tell where the root is
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, http.StatusNotFound, fmt.Errorf("%s: no such file", path)
+		}
+		return nil, http.StatusForbidden, fmt.Errorf("%s: cannot be opened", path)
+	}
+	info, err := file.Stat()
+	switch {
+	case err != nil:
+		_ = file.Close()
+		return nil, http.StatusInternalServerError, fmt.Errorf("%s: cannot be read", path)
+	case !info.Mode().IsRegular():
+		_ = file.Close()
+		return nil, http.StatusBadRequest, fmt.Errorf("%s: not a regular file", path)
+	case s.maxUpload > 0 && info.Size() > s.maxUpload:
+		_ = file.Close()
+		return nil, http.StatusRequestEntityTooLarge, errors.New("file too large")
+	}
+	return file, 0, nil
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+	"github.com/richardwooding/txtr/internal/signature"
+)
+
+// regionPartition is the type of the regions of partitions (--partitions)
+const regionPartition = "partition"
+
+// findRegions scans a file, or the media of an evidence container or virtual
+// disk, for embedded filesystems and compressed data (--signatures) and reads
+// its partition table (--partitions). It returns nil if the options are off
+// or the scan fails, which is reported as a warning.
+func findRegions(filename string, config extractor.Config) []printer.Region {
+	if !config.Signatures && !config.Partitions {
+		return nil
+	}
+	media, size, closeMedia, err := openMedia(filename, config)
+	if err != nil {
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnSignatures, File: filename,
+			Message: "cannot scan for signatures", Err: err})
+		return nil
+	}
+	defer closeMedia()
+
+	var regions []printer.Region
+	if config.Signatures {
+		found, err := signature.Scan(media, size)
+		if err != nil {
+			extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnSignatures, File: filename,
+				Message: "cannot scan for signatures", Err: err})
+			return nil
+		}
+		for _, region := range found {
+			regions = append(regions, printer.Region{
+				Type:        region.Type,
+				Offset:      region.Offset,
+				OffsetHex:   fmt.Sprintf("0x%x", region.Offset),
+				Size:        region.Size,
+				Description: region.Description,
+			})
+		}
+	}
+	if config.Partitions {
+		for _, p := range readPartitions(media, size) {
+			regions = append(regions, printer.Region{
+				Type:        regionPartition,
+				Offset:      p.Offset,
+				OffsetHex:   fmt.Sprintf("0x%x", p.Offset),
+				Size:        p.Size,
+				Description: p.Description(),
+			})
+		}
+		sort.SliceStable(regions, func(i, j int) bool { return regions[i].Offset < regions[j].Offset })
+	}
+	return regions
+}
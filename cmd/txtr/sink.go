@@ -0,0 +1,825 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+	"github.com/richardwooding/txtr/internal/stats"
+)
+
+// Sink kinds accepted by --output
+const (
+	sinkText      = "text"
+	sinkJSON      = "json"
+	sinkNDJSON    = "ndjson"
+	sinkSearch    = "search"
+	sinkSQLite    = "sqlite"
+	sinkCSV       = "csv"
+	sinkCycloneDX = "cyclonedx"
+	sinkRizin     = "rizin"
+	sinkGhidra    = "ghidra"
+	sinkIDAPython = "idapython"
+	sinkHTML      = "html"
+	sinkMarkdown  = "markdown"
+	sinkDOT       = "dot"
+	sinkMermaid   = "mermaid"
+	sinkStats     = "stats"
+	sinkStatsJSON = "stats-json"
+	sinkSlack     = "slack"
+	sinkTeams     = "teams"
+)
+
+// sinkKinds lists the supported --output kinds
+var sinkKinds = []string{sinkText, sinkJSON, sinkNDJSON, sinkSearch, sinkSQLite, sinkCSV, sinkCycloneDX, sinkRizin, sinkGhidra, sinkIDAPython, sinkHTML, sinkMarkdown, sinkDOT, sinkMermaid, sinkStats, sinkStatsJSON, sinkSlack, sinkTeams}
+
+// sinkSpec is a parsed --output entry
+type sinkSpec struct {
+	Kind string
+	Path string
+}
+
+// fileInfo describes a file at the start of its scan
+type fileInfo struct {
+	Name     string
+	Format   string // Binary format (-d only)
+	Source   string // How Format was decided (see binary.FormatSource)
+	Sections []string
+	Strategy string                 // How the file was read (see extractor.ChooseStrategy, full scans only)
+	Layout   []printer.SectionRange // Where Sections are loaded (-d only)
+	Packing  *printer.PackingInfo   // Entropy analysis (-d only)
+	Regions  []printer.Region       // Embedded filesystems and compressed data (--signatures)
+	Xattrs   []printer.Xattr        // Extended attributes (--xattrs)
+	Artifact printer.ArtifactCells  // Cells of a parsed registry hive, event log or prefetch file, NTFS image or memory image
+
+	// Decryption status of an encrypted archive member (see archive.Member)
+	Decryption string
+}
+
+// sink receives the results of a scan. A scan calls BeginFile, PrintString for
+// each string, EndFile for every file in input order, then Close.
+type sink interface {
+	BeginFile(info fileInfo)
+	PrintString(str []byte, filename string, offset int64, config extractor.Config)
+	EndFile(filename string, err error)
+	Close() error
+}
+
+// rejectTracker is implemented by sinks that count strings dropped by the
+// filters (see extractor.Config.Rejected)
+type rejectTracker interface {
+	RejectString(str []byte, filename string, offset int64, config extractor.Config)
+}
+
+// flusher is implemented by sinks that stream their output, writing out what
+// they buffered so far
+type flusher interface {
+	Flush() error
+}
+
+// warningReporter is implemented by sinks that include warnings in their
+// output (see extractor.Config.Warnings)
+type warningReporter interface {
+	Warn(w extractor.Warning)
+}
+
+// parseSinkSpecs parses --output values of the form kind=path (comma-separated
+// and/or repeated)
+func parseSinkSpecs(values []string) ([]sinkSpec, error) {
+	var specs []sinkSpec
+	paths := make(map[string]bool)
+
+	for _, value := range values {
+		for entry := range strings.SplitSeq(value, ",") {
+			entry = strings.TrimSpace(entry)
+			if entry == "" {
+				continue
+			}
+
+			kind, path, ok := strings.Cut(entry, "=")
+			kind = strings.ToLower(strings.TrimSpace(kind))
+			path = strings.TrimSpace(path)
+			if !ok || path == "" {
+				return nil, fmt.Errorf("invalid output %q (expected kind=path, e.g. json=out.json)", entry)
+			}
+			if !slices.Contains(sinkKinds, kind) {
+				return nil, fmt.Errorf("unknown output kind %q (valid: %s)", kind, strings.Join(sinkKinds, ", "))
+			}
+			if path == "-" {
+				return nil, fmt.Errorf("output %q: stdout receives the primary output, use a file path", entry)
+			}
+			if paths[path] {
+				return nil, fmt.Errorf("output path %s used more than once", path)
+			}
+			paths[path] = true
+
+			specs = append(specs, sinkSpec{Kind: kind, Path: path})
+		}
+	}
+
+	return specs, nil
+}
+
+// formatExtensions are the file name extensions of the files of further
+// --format values that are not the format name
+var formatExtensions = map[string]string{
+	formatText:      "txt",
+	formatCycloneDX: "cdx.json",
+	formatRizin:     "rizin.json",
+	formatGhidra:    "ghidra.py",
+	formatIDAPython: "py",
+	formatMarkdown:  "md",
+	formatMermaid:   "mmd",
+	formatSlack:     "slack.json",
+	formatTeams:     "teams.json",
+}
+
+// extraFormatSpecs returns the sinks of the further --format values of mode,
+// written to prefix.EXT (e.g. --format json,csv --output-prefix scan writes
+// scan.csv), and checks their paths against the --output sinks of specs
+func extraFormatSpecs(mode outputMode, prefix string, specs []sinkSpec) ([]sinkSpec, error) {
+	for _, format := range mode.Extra {
+		ext, ok := formatExtensions[format]
+		if !ok {
+			ext = format
+		}
+		spec := sinkSpec{Kind: format, Path: prefix + "." + ext}
+		if mode.Stats {
+			spec.Kind = primarySinkSpec(outputMode{Format: format, Stats: true}).Kind
+		}
+		if slices.ContainsFunc(specs, func(s sinkSpec) bool { return s.Path == spec.Path }) {
+			return nil, fmt.Errorf("output path %s used more than once", spec.Path)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// primarySinkSpec returns the sink for the output selected by --format/--stats,
+// written to stdout
+func primarySinkSpec(mode outputMode) sinkSpec {
+	switch {
+	case mode.Stats && mode.Format == formatJSON:
+		return sinkSpec{Kind: sinkStatsJSON}
+	case mode.Stats:
+		return sinkSpec{Kind: sinkStats}
+	case mode.Format == formatJSON:
+		return sinkSpec{Kind: sinkJSON}
+	case mode.Format == formatCSV:
+		return sinkSpec{Kind: sinkCSV}
+	case mode.Format == formatCycloneDX:
+		return sinkSpec{Kind: sinkCycloneDX}
+	case mode.Format == formatRizin:
+		return sinkSpec{Kind: sinkRizin}
+	case mode.Format == formatGhidra:
+		return sinkSpec{Kind: sinkGhidra}
+	case mode.Format == formatIDAPython:
+		return sinkSpec{Kind: sinkIDAPython}
+	case mode.Format == formatHTML:
+		return sinkSpec{Kind: sinkHTML}
+	case mode.Format == formatMarkdown:
+		return sinkSpec{Kind: sinkMarkdown}
+	case mode.Format == formatDOT:
+		return sinkSpec{Kind: sinkDOT}
+	case mode.Format == formatMermaid:
+		return sinkSpec{Kind: sinkMermaid}
+	case mode.Format == formatSlack:
+		return sinkSpec{Kind: sinkSlack}
+	case mode.Format == formatTeams:
+		return sinkSpec{Kind: sinkTeams}
+	default:
+		return sinkSpec{Kind: sinkText}
+	}
+}
+
+// newSink creates a sink writing to w. Sinks written to files never use colors
+// or hyperlinks and are only sanitized with --sanitize always.
+func newSink(spec sinkSpec, w io.Writer, config extractor.Config, singleFile bool) sink {
+	if spec.Path != "" {
+		config.ColorMode = extractor.ColorNever
+		config.Hyperlinks = false
+		if config.Sanitize == extractor.ColorAuto {
+			config.Sanitize = extractor.ColorNever
+		}
+	}
+
+	switch spec.Kind {
+	case sinkJSON, sinkCSV, sinkCycloneDX, sinkRizin, sinkGhidra, sinkIDAPython, sinkHTML, sinkMarkdown, sinkDOT, sinkMermaid:
+		jsonPrinter := printer.NewJSONPrinter(config, w)
+		jsonPrinter.SetMemoryLimit(config.MaxMemory)
+		return &jsonSink{printer: jsonPrinter, kind: spec.Kind}
+	case sinkNDJSON:
+		return &ndjsonSink{printer: printer.NewNDJSONPrinter(w), duplicates: config.Duplicates}
+	case sinkSearch:
+		return &searchSink{printer: printer.NewSearchPrinter(w)}
+	case sinkStats, sinkStatsJSON:
+		return newStatsSink(w, config, spec.Kind == sinkStatsJSON, singleFile)
+	case sinkSlack, sinkTeams:
+		return newChatSink(w, spec.Kind, false)
+	default:
+		writer := bufio.NewWriter(w)
+		text := textSink{writer: writer, text: printer.NewTextPrinter(writer, config)}
+		if config.ClusterGap > 0 {
+			return &clusterTextSink{textSink: text, clusters: printer.OffsetClusterer{Gap: config.ClusterGap}}
+		}
+		return &text
+	}
+}
+
+// textSink writes strings in the regular text format, styled by the
+// configuration of the sink
+type textSink struct {
+	writer *bufio.Writer
+	text   *printer.TextPrinter
+}
+
+func (ts *textSink) BeginFile(fileInfo) {}
+
+func (ts *textSink) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	ts.text.PrintString(str, filename, offset, config)
+}
+
+func (ts *textSink) EndFile(string, error) {}
+
+func (ts *textSink) Flush() error {
+	return ts.writer.Flush()
+}
+
+func (ts *textSink) Close() error {
+	return ts.writer.Flush()
+}
+
+// jsonSink collects strings into a JSONPrinter and writes JSON, CSV, a
+// CycloneDX BOM, rizin JSON, a disassembler script, an HTML or Markdown report
+// or a relationship graph on close
+type jsonSink struct {
+	printer *printer.JSONPrinter
+	kind    string // sinkJSON, sinkCSV, sinkCycloneDX, sinkRizin, sinkGhidra, sinkIDAPython, sinkHTML, sinkMarkdown, sinkDOT or sinkMermaid
+}
+
+func (js *jsonSink) BeginFile(info fileInfo) {
+	js.printer.SetFileInfo(info.Name, info.Format, info.Sections)
+	js.printer.SetFormatSource(info.Source)
+	js.printer.SetStrategy(info.Strategy)
+	js.printer.SetPacking(info.Packing)
+	js.printer.SetSectionLayout(info.Layout)
+	js.printer.SetRegions(info.Regions)
+	js.printer.SetXattrs(info.Xattrs)
+	js.printer.SetDecryption(info.Decryption)
+	js.printer.SetArtifactCells(info.Artifact)
+}
+
+func (js *jsonSink) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	js.printer.PrintString(str, filename, offset, config)
+}
+
+func (js *jsonSink) Warn(w extractor.Warning) {
+	js.printer.AddWarning(w)
+}
+
+func (js *jsonSink) EndFile(filename string, err error) {
+	js.printer.FinalizeCurrentFile()
+	if err != nil {
+		// Replace the empty result of the failed file with an error result
+		js.printer.FileResults = js.printer.FileResults[:len(js.printer.FileResults)-1]
+		js.printer.AddFileResult(filename, "", nil, nil, err)
+	}
+}
+
+func (js *jsonSink) Close() error {
+	switch js.kind {
+	case sinkCSV:
+		return js.printer.FlushCSV()
+	case sinkCycloneDX:
+		return js.printer.FlushCycloneDX(version)
+	case sinkRizin:
+		return js.printer.FlushRizin()
+	case sinkGhidra:
+		return js.printer.FlushGhidra(version)
+	case sinkIDAPython:
+		return js.printer.FlushIDAPython(version)
+	case sinkHTML:
+		return js.printer.FlushHTML(version)
+	case sinkMarkdown:
+		return js.printer.FlushMarkdown(version)
+	case sinkDOT:
+		return js.printer.FlushDOT(version)
+	case sinkMermaid:
+		return js.printer.FlushMermaid(version)
+	default:
+		return js.printer.Flush()
+	}
+}
+
+// ndjsonSink streams strings as newline-delimited JSON
+type ndjsonSink struct {
+	printer    *printer.NDJSONPrinter
+	duplicates map[string][]string // Inputs not scanned, by the input they duplicate (--dedupe-inputs)
+}
+
+func (ns *ndjsonSink) BeginFile(info fileInfo) {
+	for _, duplicate := range ns.duplicates[info.Name] {
+		ns.printer.PrintDuplicate(duplicate, info.Name)
+	}
+	for _, region := range info.Regions {
+		ns.printer.PrintRegion(info.Name, region)
+	}
+	ns.printer.SetArtifactCells(info.Artifact)
+}
+
+func (ns *ndjsonSink) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	ns.printer.PrintString(str, filename, offset, config)
+}
+
+func (ns *ndjsonSink) Warn(w extractor.Warning) {
+	ns.printer.PrintWarning(w)
+}
+
+func (ns *ndjsonSink) EndFile(string, error) {}
+
+func (ns *ndjsonSink) Close() error {
+	return ns.printer.Flush()
+}
+
+// searchSink streams strings as documents for a full-text search engine
+type searchSink struct {
+	printer *printer.SearchPrinter
+}
+
+func (ss *searchSink) BeginFile(info fileInfo) {
+	ss.printer.SetFormat(info.Format)
+}
+
+func (ss *searchSink) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	ss.printer.PrintString(str, filename, offset, config)
+}
+
+func (ss *searchSink) EndFile(string, error) {}
+
+func (ss *searchSink) Close() error {
+	return ss.printer.Flush()
+}
+
+// sqliteSink writes files, their sections and strings into a SQLite database
+type sqliteSink struct {
+	printer *printer.SQLitePrinter
+}
+
+// newSQLiteSink creates the SQLite database at path, recording the version
+// of txtr with the settings of config. The command line is not recorded: the
+// database is meant to be shared, and --password values and --notify-webhook
+// URLs are credentials.
+func newSQLiteSink(path string, config extractor.Config) (*sqliteSink, error) {
+	sqlitePrinter, err := printer.NewSQLitePrinter(path, config)
+	if err != nil {
+		return nil, err
+	}
+	sqlitePrinter.SetMetadata("version", version)
+	return &sqliteSink{printer: sqlitePrinter}, nil
+}
+
+func (ss *sqliteSink) BeginFile(info fileInfo) {
+	ss.printer.BeginFile(info.Name, info.Format, info.Layout)
+}
+
+func (ss *sqliteSink) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	ss.printer.PrintString(str, filename, offset, config)
+}
+
+func (ss *sqliteSink) EndFile(_ string, err error) {
+	ss.printer.EndFile(err)
+}
+
+func (ss *sqliteSink) Close() error {
+	return ss.printer.Close()
+}
+
+// statsSink aggregates statistics and writes them as text or JSON on close
+type statsSink struct {
+	stats      *stats.Statistics
+	collect    func([]byte, string, int64, extractor.Config)
+	reject     func([]byte, string, int64, extractor.Config) // nil unless filters are tracked
+	writer     io.Writer
+	colorMode  extractor.ColorMode
+	json       bool
+	singleFile bool
+	retry      *extractor.Retry // Counts the retries reported (--retries)
+}
+
+// newStatsSink creates a statistics sink
+func newStatsSink(w io.Writer, config extractor.Config, asJSON, singleFile bool) *statsSink {
+	s := stats.New(config.MinLength)
+	s.Locale = config.Locale
+	s.TrackPatterns(config.MatchPatterns)
+	collect, tracked := trackFilters(s, config)
+	return &statsSink{stats: s, collect: collect, reject: tracked.Rejected, writer: w, colorMode: config.ColorMode, json: asJSON, singleFile: singleFile, retry: config.Retry}
+}
+
+func (ss *statsSink) BeginFile(info fileInfo) {
+	// File metadata is only meaningful for a single file
+	if ss.singleFile {
+		ss.stats.SetFileInfo(info.Name, info.Format, info.Sections)
+	}
+}
+
+func (ss *statsSink) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	ss.collect(str, filename, offset, config)
+}
+
+func (ss *statsSink) RejectString(str []byte, filename string, offset int64, config extractor.Config) {
+	if ss.reject != nil {
+		ss.reject(str, filename, offset, config)
+	}
+}
+
+func (ss *statsSink) EndFile(string, error) {}
+
+func (ss *statsSink) Close() error {
+	ss.stats.Retries = ss.retry.Count()
+	if !ss.json {
+		ss.stats.Format(ss.writer, ss.colorMode)
+		return nil
+	}
+	data, err := ss.stats.ToJSON()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(ss.writer, "%s\n", data)
+	return err
+}
+
+// multiSink fans every event out to several sinks
+type multiSink []sink
+
+func (ms multiSink) BeginFile(info fileInfo) {
+	for _, s := range ms {
+		s.BeginFile(info)
+	}
+}
+
+func (ms multiSink) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	for _, s := range ms {
+		s.PrintString(str, filename, offset, config)
+	}
+}
+
+func (ms multiSink) RejectString(str []byte, filename string, offset int64, config extractor.Config) {
+	for _, s := range ms {
+		if tracker, ok := s.(rejectTracker); ok {
+			tracker.RejectString(str, filename, offset, config)
+		}
+	}
+}
+
+// Warn reports w (see reportWarning) and passes it to the sinks that report warnings
+func (ms multiSink) Warn(w extractor.Warning) {
+	reportWarning(w)
+	for _, s := range ms {
+		if reporter, ok := s.(warningReporter); ok {
+			reporter.Warn(w)
+		}
+	}
+}
+
+// tracksRejected reports whether any sink counts strings dropped by the filters
+func (ms multiSink) tracksRejected() bool {
+	return slices.ContainsFunc(ms, func(s sink) bool {
+		stats, ok := s.(*statsSink)
+		return ok && stats.reject != nil
+	})
+}
+
+func (ms multiSink) EndFile(filename string, err error) {
+	for _, s := range ms {
+		s.EndFile(filename, err)
+	}
+}
+
+// Flush writes out the output of the sinks streaming it, returning the first
+// error
+func (ms multiSink) Flush() error {
+	for _, s := range ms {
+		if f, ok := s.(flusher); ok {
+			if err := f.Flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Close closes all sinks and returns the first error
+func (ms multiSink) Close() error {
+	var firstErr error
+	for _, s := range ms {
+		if err := s.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// recordedString is a string (or warning) captured by a recordingSink
+type recordedString struct {
+	str      []byte
+	filename string
+	offset   int64
+	config   extractor.Config
+	rejected bool               // Dropped by the filters
+	warning  *extractor.Warning // Set if the event is a warning rather than a string
+}
+
+// recordedEvent is a call to a recordingSink: a string or warning, or the
+// start (begin set) or end (end set) of a file
+type recordedEvent struct {
+	recordedString
+	begin *fileInfo
+	end   bool
+	err   error // Error of the file that ends
+}
+
+// recordingSink captures the scan of one file (and with --extract-fs, of the
+// files in its filesystems) so it can be replayed in input order (used by
+// parallel workers)
+type recordingSink struct {
+	events []recordedEvent
+}
+
+func (rs *recordingSink) BeginFile(info fileInfo) {
+	// Warnings recorded since the last file ended belong to this one
+	i := len(rs.events)
+	for i > 0 && !rs.events[i-1].end {
+		i--
+	}
+	rs.events = slices.Insert(rs.events, i, recordedEvent{begin: &info})
+}
+
+func (rs *recordingSink) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	// The extractor reuses its buffers, so the string must be copied
+	rs.events = append(rs.events, recordedEvent{recordedString: recordedString{str: slices.Clone(str), filename: filename, offset: offset, config: config}})
+}
+
+func (rs *recordingSink) RejectString(str []byte, filename string, offset int64, config extractor.Config) {
+	rs.events = append(rs.events, recordedEvent{recordedString: recordedString{str: slices.Clone(str), filename: filename, offset: offset, config: config, rejected: true}})
+}
+
+func (rs *recordingSink) Warn(w extractor.Warning) {
+	rs.events = append(rs.events, recordedEvent{recordedString: recordedString{warning: &w}})
+}
+
+func (rs *recordingSink) EndFile(_ string, err error) {
+	rs.events = append(rs.events, recordedEvent{end: true, err: err})
+}
+
+func (rs *recordingSink) Close() error {
+	return nil
+}
+
+// replay sends the recorded files to s
+func (rs *recordingSink) replay(s sink) {
+	tracker, _ := s.(rejectTracker)
+	reporter, _ := s.(warningReporter)
+	name := ""
+	for _, r := range rs.events {
+		switch {
+		case r.begin != nil:
+			name = r.begin.Name
+			s.BeginFile(*r.begin)
+		case r.end:
+			s.EndFile(name, r.err)
+		case r.warning != nil:
+			if reporter != nil {
+				reporter.Warn(*r.warning)
+			}
+		case !r.rejected:
+			s.PrintString(r.str, r.filename, r.offset, r.config)
+		case tracker != nil:
+			tracker.RejectString(r.str, r.filename, r.offset, r.config)
+		}
+	}
+}
+
+// processWithSinks scans files (or stdin) once and feeds the results to the
+// primary output on stdout, to every --output sink, to the summary posted
+// with --notify-webhook and to the --exec-per-match commands, recording the
+// progress of files in journal if non-nil
+func processWithSinks(files []string, workers int, config extractor.Config, mode outputMode, specs []sinkSpec, notify notifyOptions, exec execOptions, journal *scanJournal) {
+	// The files of embedded filesystems, archive members, streams and
+	// extended attributes are scanned as files of their own
+	singleFile := len(files) <= 1 && !config.ExtractFS && !config.Archives && !config.ADS && !config.ScanXattrs
+	primary := newSink(primarySinkSpec(mode), os.Stdout, config, singleFile)
+	if mode.Top > 0 {
+		primary = newTopSink(os.Stdout, config, mode.Top, mode.Format == formatJSON, singleFile)
+	}
+	sinks := multiSink{primary}
+
+	var outputFiles []*os.File
+	for _, spec := range specs {
+		if spec.Kind == sinkSQLite {
+			// The database is written by SQLite itself
+			s, err := newSQLiteSink(spec.Path, config)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "error: cannot create output %s: %v\n", spec.Path, err)
+				os.Exit(1)
+			}
+			sinks = append(sinks, s)
+			continue
+		}
+		file, err := os.Create(spec.Path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "error: cannot create output %s: %v\n", spec.Path, err)
+			os.Exit(1)
+		}
+		outputFiles = append(outputFiles, file)
+		sinks = append(sinks, newSink(spec, file, config, singleFile))
+	}
+	for _, s := range sinks {
+		// Slack and Teams messages list secrets like the webhook summary
+		if chat, ok := s.(*summarySink); ok {
+			chat.findings = notify.Findings
+		}
+	}
+	if notify.URL != "" {
+		sinks = append(sinks, newNotifySink(notify))
+	}
+	if exec.Args != nil {
+		sinks = append(sinks, newExecSink(exec))
+	}
+	if sinks.tracksRejected() {
+		config.Rejected = sinks.RejectString
+	}
+	config.Warnings = sinks.Warn
+
+	if len(files) == 0 && config.PreFilter != "" {
+		sinks.BeginFile(fileInfo{})
+		err := scanPreFiltered(os.Stdin, "", config, sinks.PrintString)
+		if err != nil {
+			reportError("{standard input}", err)
+		}
+		sinks.EndFile("", err)
+	} else if len(files) == 0 {
+		sinks.BeginFile(fileInfo{})
+		extractStrings(os.Stdin, "", config, sinks.PrintString)
+		sinks.EndFile("", nil)
+	} else if len(files) > 1 && workers > 1 && config.MaxMemory == 0 {
+		// Workers record whole files which are replayed in input order
+		// (a memory budget forces sequential processing)
+		config := workerConfig(config, workers)
+		jobs := make(chan job, len(files))
+		recordings := make([]*recordingSink, len(files))
+		entries := make([]*journalEntry, len(files))
+		done := make([]chan struct{}, len(files))
+		for i := range done {
+			done[i] = make(chan struct{})
+		}
+
+		var wg sync.WaitGroup
+		for worker := range workers {
+			wg.Go(func() {
+				pinWorker(config, worker)
+				for j := range jobs {
+					recording := &recordingSink{}
+					jobConfig := config
+					if config.Rejected != nil {
+						jobConfig.Rejected = recording.RejectString
+					}
+					jobConfig.Warnings = recording.Warn
+					entries[j.index] = scanFileJournaled(j.filename, jobConfig, recording, journal)
+					recordings[j.index] = recording
+					close(done[j.index])
+				}
+			})
+		}
+		for _, j := range scheduleJobs(files) {
+			jobs <- j
+		}
+		close(jobs)
+
+		// Replay each file as soon as it and all earlier files are done
+		for i := range files {
+			<-done[i]
+			recordings[i].replay(sinks)
+			entries[i].finish(sinks)
+			recordings[i] = nil
+		}
+		wg.Wait()
+	} else {
+		for _, filename := range files {
+			scanFileJournaled(filename, config, sinks, journal).finish(sinks)
+		}
+	}
+
+	err := sinks.Close()
+	for _, file := range outputFiles {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "strings: error writing output: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+// scanFileToSink scans a file (its data sections with -d) into s, reporting
+// errors (see reportError) and to the sink. With --ads, its alternate data
+// streams follow it, then with --scan-xattrs its extended attributes, with
+// --archives its members if it is an archive and with --extract-fs the files of
+// its filesystems.
+// Forensic artifacts are scanned by scanArtifact. With --pre-filter, the
+// output of the command is scanned instead (see scanPreFiltered).
+func scanFileToSink(filename string, config extractor.Config, s sink) {
+	// Scan what the --pre-filter command decodes the file to instead
+	if config.PreFilter != "" {
+		s.BeginFile(fileInfo{Name: filename, Xattrs: findXattrs(filename, config)})
+		err := scanPreFilteredFile(filename, config, s.PrintString)
+		if err != nil {
+			reportError(filename, err)
+		}
+		s.EndFile(filename, err)
+		return
+	}
+
+	defer scanFilesystems(filename, config, s)
+	defer scanArchive(filename, config, s)
+	defer scanXattrs(filename, config, s)
+	defer scanForks(filename, config, s)
+
+	regions := findRegions(filename, config)
+	xattrs := findXattrs(filename, config)
+	if scanArtifact(filename, config, regions, s) {
+		return
+	}
+	files := findNTFSFiles(filename, config)
+	if memory := findMemoryRegions(filename, config); memory != nil {
+		files = memory
+	}
+	if !config.ScanDataOnly {
+		s.BeginFile(fileInfo{Name: filename, Strategy: string(extractor.ChooseStrategy(filename, config)), Regions: regions, Xattrs: xattrs, Artifact: files})
+		err := extractor.ExtractStringsFromFile(filename, config, s.PrintString)
+		if err != nil {
+			reportError(filename, err)
+		}
+		s.EndFile(filename, err)
+		return
+	}
+
+	format, source, err := resolveFormat(filename, config)
+	if err != nil {
+		reportError(filename, err)
+		s.BeginFile(fileInfo{Name: filename, Regions: regions, Xattrs: xattrs, Artifact: files})
+		s.EndFile(filename, err)
+		return
+	}
+
+	packing, path, format, cleanup, err := maybeUnpack(filename, format, config)
+	if err != nil {
+		reportError(filename, err)
+		s.BeginFile(fileInfo{Name: filename, Format: format.String(), Source: string(source), Packing: packing, Regions: regions, Xattrs: xattrs, Artifact: files})
+		s.EndFile(filename, err)
+		return
+	}
+	defer cleanup()
+
+	sections, err := loadSections(path, format, config)
+	if err != nil {
+		warnParseFallback(filename, format, err, config)
+	}
+	if err != nil || len(sections) == 0 {
+		s.BeginFile(fileInfo{Name: filename, Format: format.String(), Source: string(source), Packing: packing, Regions: regions, Xattrs: xattrs, Artifact: files})
+		err := scanWholeFile(path, filename, config, s.PrintString)
+		if err != nil {
+			reportError(filename, err)
+		}
+		s.EndFile(filename, err)
+		return
+	}
+
+	sectionNames := make([]string, len(sections))
+	for i, section := range sections {
+		sectionNames[i] = section.Name
+	}
+
+	s.BeginFile(fileInfo{Name: filename, Format: format.String(), Source: string(source), Sections: sectionNames, Layout: sectionLayout(sections), Packing: packing, Regions: regions, Xattrs: xattrs, Artifact: files})
+	extractSections(sections, path, filename, config, s.PrintString)
+	s.EndFile(filename, nil)
+}
+
+// scanWholeFile extracts strings from the entire file at path with buffered
+// I/O, reporting them under filename
+func scanWholeFile(path, filename string, config extractor.Config, printFunc func([]byte, string, int64, extractor.Config)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer closeInput(file, filename, config)
+
+	extractStrings(file, filename, config, printFunc)
+	return nil
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/noise"
+	"github.com/richardwooding/txtr/internal/printer"
+	"github.com/richardwooding/txtr/internal/rank"
+)
+
+// topSink ranks the strings of all files and writes the K most interesting
+// ones, with the reasons for their rank, on close (--top)
+type topSink struct {
+	ranker *rank.Ranker
+	k      int
+	writer io.Writer
+	config extractor.Config
+	json   bool
+}
+
+// newTopSink creates a sink printing the k highest-ranked strings as text or
+// JSON. Strings in the common strings database rank lower; with
+// --filter-common they are dropped before ranking.
+func newTopSink(w io.Writer, config extractor.Config, k int, asJSON, singleFile bool) *topSink {
+	common := config.CommonStrings
+	if common == nil {
+		var err error
+		common, _, err = noise.Load()
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "strings: warning: cannot load common strings database, using the built-in one: %v\n", err)
+			common = noise.Embedded()
+		}
+	}
+	// Ranked strings of several files are only meaningful with their file
+	if !singleFile {
+		config.PrintFileName = true
+	}
+	return &topSink{ranker: rank.New(common), k: k, writer: w, config: config, json: asJSON}
+}
+
+func (ts *topSink) BeginFile(fileInfo) {}
+
+func (ts *topSink) PrintString(str []byte, filename string, offset int64, _ extractor.Config) {
+	ts.ranker.Add(str, filename, offset)
+}
+
+func (ts *topSink) EndFile(string, error) {}
+
+func (ts *topSink) Close() error {
+	top := ts.ranker.Top(ts.k)
+	if ts.json {
+		output := printer.TopOutput{Top: make([]printer.StringResult, len(top))}
+		for i, r := range top {
+			result := printer.NewStringResult(r.Value, r.File, r.Offset, ts.config)
+			result.File = r.File
+			result.Count = r.Count
+			result.Score = math.Round(r.Score*10) / 10 // As precise as the text output
+			result.Reasons = r.Reasons
+			output.Top[i] = result
+		}
+		encoder := json.NewEncoder(ts.writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(output)
+	}
+
+	writer := bufio.NewWriter(ts.writer)
+	text := printer.NewTextPrinter(writer, ts.config)
+	for _, r := range top {
+		// The score and reasons precede the string, like the encoding tag of --grep
+		tag := fmt.Sprintf("%.1f", r.Score)
+		if len(r.Reasons) > 0 {
+			tag += " " + strings.Join(r.Reasons, ", ")
+		}
+		tagged := "[" + tag + "] " + string(r.Value)
+		text.PrintString([]byte(tagged), r.File, r.Offset, ts.config)
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/sample"
+	"github.com/richardwooding/txtr/internal/tune"
+	"github.com/richardwooding/txtr/internal/walk"
+	"github.com/richardwooding/txtr/internal/winpath"
+)
+
+// TuneCLI defines the command-line interface of the tune subcommand
+type TuneCLI struct {
+	Files      int      `name:"files" default:"200" help:"Number of files sampled, spread over the corpus"`
+	SampleSize string   `name:"sample-size" default:"1M" help:"Bytes sampled from each file, in windows spread over larger files"`
+	Follow     bool     `name:"follow-symlinks" help:"Follow symlinks below corpus directories (loops are detected)"`
+	JSON       bool     `short:"j" name:"json" help:"Output the measurements and recommendation as JSON"`
+	Corpus     []string `arg:"" name:"path" type:"path" help:"Files or directories (scanned recursively) of the corpus"`
+}
+
+// corpusFile is a file of the corpus
+type corpusFile struct {
+	path string
+	size int64
+}
+
+// tuneReport is the JSON output of txtr tune
+type tuneReport struct {
+	CorpusFiles    int                  `json:"corpus_files"`
+	CorpusBytes    int64                `json:"corpus_bytes"`
+	SampledFiles   int                  `json:"sampled_files"`
+	SampledBytes   int64                `json:"sampled_bytes"`
+	Encodings      []tuneEncoding       `json:"encodings"`
+	Filters        []tuneFilter         `json:"filters"`
+	ThroughputMBps float64              `json:"throughput_mb_per_second"` // Per worker, -e s
+	Recommendation tuneRecommendation   `json:"recommendation"`
+	Commands       []string             `json:"commands"`
+	Lengths        map[string][]float64 `json:"readable_share_by_length"` // Per encoding, from tune.MinLength
+}
+
+// tuneEncoding is the measurements of an encoding in the JSON report
+type tuneEncoding struct {
+	Encoding      string  `json:"encoding"`
+	Strings       int     `json:"strings"` // From the recommended minimum length
+	StringsPerKB  float64 `json:"strings_per_kb"`
+	ReadableShare float64 `json:"readable_share"`
+}
+
+// tuneFilter is the measurements of a filter in the JSON report
+type tuneFilter struct {
+	Name            string  `json:"name"`
+	Option          string  `json:"option"`
+	DroppedShare    float64 `json:"dropped_share"`
+	UnreadableShare float64 `json:"unreadable_share"`
+	Recommended     bool    `json:"recommended"`
+}
+
+// tuneRecommendation is the recommendation in the JSON report
+type tuneRecommendation struct {
+	MinLength       int      `json:"min_length"`
+	Encoding        string   `json:"encoding"`
+	AlsoEncodings   []string `json:"also_encodings,omitempty"`
+	FilterCommon    bool     `json:"filter_common"`
+	Exclude         []string `json:"exclude,omitempty"`
+	Workers         int      `json:"workers"`
+	EstimateSeconds float64  `json:"estimate_seconds,omitempty"`
+}
+
+// encodingNames are the descriptions of the encodings of -e
+var encodingNames = map[string]string{
+	"s": "7-bit", "S": "8-bit", "l": "UTF-16LE", "b": "UTF-16BE", "L": "UTF-32LE", "B": "UTF-32BE",
+}
+
+// runTune implements "txtr tune": it samples files of a corpus, measures the
+// density and readability of their strings in each encoding and the share of
+// strings common filters drop, and recommends the options of a scan of the
+// corpus. It returns the process exit code.
+func runTune(args []string) int {
+	var cli TuneCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr tune"),
+		kong.Description("Sample a corpus and recommend the minimum length, encodings, filters and workers to scan it with."),
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+	sampleSize, err := parseByteSize(cli.SampleSize)
+	if err != nil || sampleSize <= 0 || cli.Files <= 0 {
+		fmt.Fprintf(os.Stderr, "error: --files and --sample-size must be positive\n")
+		return 1
+	}
+
+	// List the corpus, then sample files spread over it
+	var corpus []corpusFile
+	var corpusBytes int64
+	walker := &walk.Walker{FollowSymlinks: cli.Follow}
+	for _, root := range cli.Corpus {
+		walker.Walk(winpath.Input(root), func(path string, err error) {
+			var info os.FileInfo
+			if err == nil {
+				info, err = os.Stat(path)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "strings: %s: %v\n", path, err)
+				return
+			}
+			corpus = append(corpus, corpusFile{path: path, size: info.Size()})
+			corpusBytes += info.Size()
+		})
+	}
+	if len(corpus) == 0 {
+		fmt.Fprintf(os.Stderr, "error: no files to sample\n")
+		return 1
+	}
+
+	profile := tune.NewProfile(nil)
+	for _, file := range sampleFiles(corpus, cli.Files) {
+		chunks, err := readSample(file, sampleSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "strings: %s: %v\n", file.path, err)
+			continue
+		}
+		profile.Add(chunks...)
+	}
+	if profile.Files == 0 {
+		fmt.Fprintf(os.Stderr, "error: no files could be sampled\n")
+		return 1
+	}
+
+	report := newTuneReport(profile, profile.Recommend(runtime.NumCPU(), len(corpus), corpusBytes), len(corpus), corpusBytes, cli.Corpus)
+	if cli.JSON {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+	writeTuneReport(os.Stdout, report)
+	return 0
+}
+
+// sampleFiles returns up to n files spread evenly over the corpus, so runs
+// sample the same files
+func sampleFiles(corpus []corpusFile, n int) []corpusFile {
+	if len(corpus) <= n {
+		return corpus
+	}
+	sampled := make([]corpusFile, n)
+	for i := range sampled {
+		sampled[i] = corpus[i*len(corpus)/n]
+	}
+	return sampled
+}
+
+// sampleWindows is the number of windows a sample of a larger file is read in
+const sampleWindows = 16
+
+// readSample reads up to size bytes of a file, in windows spread over files
+// larger than that (see sample.Windows)
+func readSample(file corpusFile, size int64) ([][]byte, error) {
+	f, err := os.Open(file.path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	windows := []sample.Window{{Offset: 0, Size: file.size}}
+	if file.size > size {
+		window := min(max(size/sampleWindows, 4096), sample.DefaultWindow)
+		windows = sample.Windows(f, file.size, sample.Options{Percent: float64(size) * 100 / float64(file.size), Window: window})
+	}
+	chunks := make([][]byte, 0, len(windows))
+	for _, window := range windows {
+		chunk := make([]byte, min(window.Size, file.size-window.Offset))
+		n, err := f.ReadAt(chunk, window.Offset)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		chunks = append(chunks, chunk[:n])
+	}
+	return chunks, nil
+}
+
+// newTuneReport gathers the measurements and recommendation of a profile
+func newTuneReport(profile *tune.Profile, r tune.Recommendation, corpusFiles int, corpusBytes int64, paths []string) tuneReport {
+	report := tuneReport{
+		CorpusFiles:  corpusFiles,
+		CorpusBytes:  corpusBytes,
+		SampledFiles: profile.Files,
+		SampledBytes: profile.Bytes,
+		Lengths:      make(map[string][]float64, len(tune.Encodings)),
+		Recommendation: tuneRecommendation{
+			MinLength:       r.MinLength,
+			Encoding:        r.Encoding,
+			AlsoEncodings:   r.Also,
+			FilterCommon:    r.FilterCommon,
+			Exclude:         r.Exclude,
+			Workers:         r.Workers,
+			EstimateSeconds: round2(r.Estimate.Seconds()),
+		},
+	}
+	if profile.ScanTime > 0 {
+		report.ThroughputMBps = round2(float64(profile.Bytes) / (1 << 20) / profile.ScanTime.Seconds())
+	}
+
+	for _, encoding := range tune.Encodings {
+		stats := profile.Encodings[encoding]
+		count := stats.Strings.From(r.MinLength)
+		report.Encodings = append(report.Encodings, tuneEncoding{
+			Encoding:      encoding,
+			Strings:       count,
+			StringsPerKB:  round2(float64(count) * 1024 / float64(max(profile.Bytes, 1))),
+			ReadableShare: roundShare(stats.Share(r.MinLength)),
+		})
+		shares := make([]float64, tune.MaxLength-tune.MinLength+1)
+		for i := range shares {
+			if count := stats.Strings[i]; count > 0 {
+				shares[i] = roundShare(float64(stats.Readable[i]) / float64(count))
+			}
+		}
+		report.Lengths[encoding] = shares
+	}
+
+	primary := profile.Encodings[r.Encoding]
+	for i, filter := range tune.Filters {
+		dropped, unreadable := primary.FilterShare(i, r.MinLength)
+		option, recommended := "--filter-common", r.FilterCommon
+		if filter.Pattern != "" {
+			option = "--exclude " + shellQuote(filter.Pattern)
+			recommended = slices.Contains(r.Exclude, filter.Pattern)
+		}
+		report.Filters = append(report.Filters, tuneFilter{
+			Name: filter.Name, Option: option, DroppedShare: roundShare(dropped), UnreadableShare: roundShare(unreadable), Recommended: recommended,
+		})
+	}
+
+	// The recommended commands: the scan, then a scan per wide encoding
+	var options []string
+	if r.MinLength != 4 {
+		options = append(options, "-n "+strconv.Itoa(r.MinLength))
+	}
+	var filters []string
+	if r.FilterCommon {
+		filters = append(filters, "--filter-common")
+	}
+	for _, pattern := range r.Exclude {
+		filters = append(filters, "--exclude "+shellQuote(pattern))
+	}
+	if r.Workers > 1 {
+		filters = append(filters, "-P "+strconv.Itoa(r.Workers))
+	}
+	quoted := make([]string, len(paths))
+	for i, path := range paths {
+		quoted[i] = shellQuote(path)
+	}
+	for _, encoding := range append([]string{r.Encoding}, r.Also...) {
+		command := append([]string{"txtr"}, options...)
+		if encoding != "s" {
+			command = append(command, "-e "+encoding)
+		}
+		command = append(command, filters...)
+		if len(r.Also) > 0 || len(paths) != 1 {
+			// Scans of several encodings or paths tell the files apart
+			command = append(command, "-f")
+		}
+		report.Commands = append(report.Commands, strings.Join(append(command, quoted...), " "))
+	}
+	return report
+}
+
+// writeTuneReport writes the measurements and recommendation for reading
+//
+//nolint:errcheck // Writing report to stdout, errors are not critical
+func writeTuneReport(w io.Writer, report tuneReport) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Sampled %d of %d files (%s of %s)\n\n", report.SampledFiles, report.CorpusFiles,
+		formatSize(report.SampledBytes), formatSize(report.CorpusBytes))
+
+	r := report.Recommendation
+	fmt.Fprintf(&b, "Encodings (strings of %d+ characters):\n", r.MinLength)
+	for _, encoding := range report.Encodings {
+		fmt.Fprintf(&b, "  -e %s  %-9s %8.2f per KB  %3.0f%% readable\n", encoding.Encoding, encodingNames[encoding.Encoding],
+			encoding.StringsPerKB, encoding.ReadableShare*100)
+	}
+
+	fmt.Fprintf(&b, "\nReadable share by length (-e %s):\n ", r.Encoding)
+	for i, share := range report.Lengths[r.Encoding] {
+		label := strconv.Itoa(tune.MinLength + i)
+		if tune.MinLength+i == tune.MaxLength {
+			label += "+"
+		}
+		fmt.Fprintf(&b, " %s: %.0f%%", label, share*100)
+	}
+
+	fmt.Fprintf(&b, "\n\nFilters (share of strings dropped, unreadable share of those):\n")
+	for _, filter := range report.Filters {
+		mark := " "
+		if filter.Recommended {
+			mark = "*"
+		}
+		fmt.Fprintf(&b, " %s %-19s %5.1f%%  %3.0f%% unreadable  %s\n", mark, filter.Name, filter.DroppedShare*100, filter.UnreadableShare*100, filter.Option)
+	}
+
+	if report.ThroughputMBps > 0 {
+		fmt.Fprintf(&b, "\nThroughput: %.1f MB/s per worker; a scan of the corpus takes about %s with -P %d\n",
+			report.ThroughputMBps, time.Duration(r.EstimateSeconds*float64(time.Second)).Round(time.Second), r.Workers)
+	}
+
+	b.WriteString("\nRecommended:\n")
+	for _, command := range report.Commands {
+		fmt.Fprintf(&b, "  %s\n", command)
+	}
+	if len(r.AlsoEncodings) > 0 {
+		fmt.Fprintf(&b, "  (wide strings are common: scan once more for each of -e %s)\n", strings.Join(r.AlsoEncodings, ", -e "))
+	}
+	io.WriteString(w, b.String())
+}
+
+// round2 rounds rates to two decimals for JSON
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// roundShare rounds shares to four decimals (hundredths of a percent) for JSON
+func roundShare(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+// formatSize formats a byte count with a binary unit
+func formatSize(n int64) string {
+	const units = "KMGTPE"
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+	value, unit := float64(n)/1024, 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %ciB", value, units[unit])
+}
+
+// shellQuote quotes s for a POSIX shell if it contains anything but safe
+// characters
+func shellQuote(s string) string {
+	if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./=:,+@%") == "" {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/binary"
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+)
+
+// Unpacking modes selectable with --unpack
+const (
+	unpackNever    = "never"    // Scan packed binaries as they are
+	unpackAuto     = "auto"     // Unpack with upx when it is installed, otherwise scan as is
+	unpackExternal = "external" // Unpack with upx; failing to unpack is an error
+)
+
+// unpackTimeout bounds a single upx run
+const unpackTimeout = 2 * time.Minute
+
+// maybeUnpack prepares a binary scanned with -d: it analyzes its packing (see
+// checkPacking) and, with --unpack, decompresses it if it is UPX-packed (see
+// unpackFile). It returns the packing analysis, the path and format to scan
+// and a cleanup function, to call once the scan is done.
+func maybeUnpack(filename string, format binary.Format, config extractor.Config) (*printer.PackingInfo, string, binary.Format, func(), error) {
+	packing := checkPacking(filename, format, config)
+	path, format, cleanup, err := unpackFile(filename, format, packing, config)
+	return packing, path, format, cleanup, err
+}
+
+// unpackFile decompresses a UPX-packed binary (see checkPacking) according to
+// the --unpack mode. It returns the path to scan, its format and a cleanup
+// function that removes the unpacked copy. Files that are not UPX-packed, or
+// cannot be unpacked in auto mode, are returned unchanged. Unpacking is
+// recorded in packing so JSON output labels the strings as coming from the
+// unpacked image.
+func unpackFile(filename string, format binary.Format, packing *printer.PackingInfo, config extractor.Config) (string, binary.Format, func(), error) {
+	noop := func() {}
+	if config.Unpack == "" || config.Unpack == unpackNever || packing == nil || packing.Packer != "UPX" {
+		return filename, format, noop, nil
+	}
+
+	upx := config.UPXPath
+	if upx == "" {
+		upx = "upx"
+	}
+
+	path, cleanup, err := runUPX(upx, filename)
+	if err == nil {
+		format, err = binary.DetectFormat(path)
+		if err != nil {
+			cleanup()
+		}
+	}
+	if err != nil {
+		if config.Unpack == unpackExternal {
+			return "", format, noop, fmt.Errorf("cannot unpack: %w", err)
+		}
+		extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnUnpackFailed, File: filename,
+			Message: "cannot unpack, scanning packed image", Err: err})
+		return filename, format, noop, nil
+	}
+
+	packing.Unpacked = true
+	extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityInfo, Code: extractor.WarnUnpacked, File: filename,
+		Message: fmt.Sprintf("unpacked with %s; strings and offsets are from the unpacked image", upx)})
+	return path, format, cleanup, nil
+}
+
+// runUPX decompresses filename with the upx executable into a temporary
+// directory. It returns the unpacked path and a function that removes it.
+func runUPX(upx, filename string) (string, func(), error) {
+	upxPath, err := exec.LookPath(upx)
+	if err != nil {
+		return "", nil, err
+	}
+
+	dir, err := os.MkdirTemp("", "txtr-unpack-*")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() {
+		_ = os.RemoveAll(dir)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), unpackTimeout)
+	defer cancel()
+
+	output := filepath.Join(dir, filepath.Base(filename))
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, upxPath, "-d", "-q", "-o", output, filename)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		cleanup()
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", nil, fmt.Errorf("%s: %w: %s", upx, err, msg)
+		}
+		return "", nil, fmt.Errorf("%s: %w", upx, err)
+	}
+
+	if _, err := os.Stat(output); err != nil {
+		cleanup()
+		return "", nil, errors.New(upx + " did not write an unpacked file")
+	}
+	return output, cleanup, nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/alecthomas/kong"
+	"github.com/richardwooding/txtr/internal/update"
+)
+
+// UpdateCLI defines the command-line interface of the update subcommand
+type UpdateCLI struct {
+	Check   bool          `name:"check" help:"Only check whether a newer release is available"`
+	Force   bool          `name:"force" help:"Install the latest release even if it is not newer (e.g. over a dev build)"`
+	APIURL  string        `name:"api-url" default:"${api_url}" help:"Latest release API endpoint (e.g. an internal mirror)"`
+	Timeout time.Duration `name:"timeout" default:"2m" help:"Timeout for contacting the release server"`
+}
+
+// runUpdate implements "txtr update": it checks the latest GitHub release,
+// verifies the platform archive against the release checksums and their
+// signature and atomically replaces the running binary. It returns the
+// process exit code.
+func runUpdate(args []string) int {
+	var cli UpdateCLI
+	parser, err := kong.New(&cli,
+		kong.Name("txtr update"),
+		kong.Description("Update txtr to the latest GitHub release."),
+		kong.Vars{"api_url": update.DefaultAPIURL},
+		kong.UsageOnError(),
+	)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+	if _, err := parser.Parse(args); err != nil {
+		parser.FatalIfErrorf(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cli.Timeout)
+	defer cancel()
+
+	updater := &update.Updater{
+		APIURL: cli.APIURL,
+		Client: &http.Client{},
+		Token:  os.Getenv("GITHUB_TOKEN"),
+	}
+
+	release, err := updater.LatestRelease(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	newer := update.IsNewer(release.Version(), version)
+	if cli.Check {
+		if newer {
+			fmt.Printf("txtr %s is available (current: %s)\n", release.Version(), version)
+		} else {
+			fmt.Printf("txtr %s is up to date (latest: %s)\n", version, release.Version())
+		}
+		return 0
+	}
+	if !newer && !cli.Force {
+		fmt.Printf("txtr %s is up to date (latest: %s; use --force to reinstall)\n", version, release.Version())
+		return 0
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: cannot locate running binary: %v\n", err)
+		return 1
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	platform := update.Platform{OS: runtime.GOOS, Arch: runtime.GOARCH, Arm: goarm()}
+	data, err := updater.Download(ctx, release, platform)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	if err := update.ReplaceExecutable(executable, data, runtime.GOOS); err != nil {
+		fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		return 1
+	}
+
+	fmt.Printf("Updated %s from %s to %s (checksum and signature verified)\n", executable, version, release.Version())
+	return 0
+}
+
+// goarm returns the GOARM level the binary was built with (arm only)
+func goarm() string {
+	if runtime.GOARCH != "arm" {
+		return ""
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == "GOARM" {
+				// Settings may include a float ABI suffix (e.g. "7,softfloat")
+				level, _, _ := strings.Cut(setting.Value, ",")
+				return level
+			}
+		}
+	}
+	return ""
+}
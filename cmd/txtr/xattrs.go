@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+	"github.com/richardwooding/txtr/internal/xattr"
+)
+
+// findXattrs returns the extended attributes of a file for JSON (--xattrs),
+// reporting attributes that cannot be read as warnings
+func findXattrs(filename string, config extractor.Config) []printer.Xattr {
+	if !config.Xattrs {
+		return nil
+	}
+	attrs, err := xattr.List(filename)
+	if err != nil {
+		warnXattrs(filename, err, config)
+	}
+	var xattrs []printer.Xattr
+	for _, attr := range attrs {
+		xattrs = append(xattrs, printer.NewXattr(attr.Name, attr.Value))
+	}
+	return xattrs
+}
+
+// scanXattrs scans the value of each extended attribute of a file
+// (--scan-xattrs) into s, named after the file and the attribute, e.g.
+// report.pdf@com.apple.metadata:kMDItemWhereFroms. Offsets are relative to
+// the value.
+func scanXattrs(filename string, config extractor.Config, s sink) {
+	if !config.ScanXattrs {
+		return
+	}
+	attrs, err := xattr.List(filename)
+	if err != nil {
+		warnXattrs(filename, err, config)
+	}
+	for _, attr := range attrs {
+		name := filename + "@" + attr.Name
+		s.BeginFile(fileInfo{Name: name})
+		extractor.ExtractFromSection(attr.Value, "", 0, name, config, s.PrintString)
+		s.EndFile(name, nil)
+	}
+}
+
+// warnXattrs reports that the extended attributes of a file could not be read
+func warnXattrs(filename string, err error, config extractor.Config) {
+	extractor.Warn(config, extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnXattrs, File: filename,
+		Message: "cannot read extended attributes", Err: err})
+}
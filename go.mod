@@ -1,7 +1,23 @@
 module github.com/richardwooding/txtr
 
-go 1.26
+go 1.26.0
 
 require github.com/alecthomas/kong v1.14.0
 
 require golang.org/x/exp v0.0.0-20251113190631-e25ba8c21ef6
+
+require golang.org/x/text v0.42.0
+
+require modernc.org/sqlite v1.38.2
+
+require (
+	github.com/dustin/go-humanize v1.0.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/ncruces/go-strftime v0.1.9 // indirect
+	github.com/remyoudompheng/bigfft v0.0.0-20230129092748-24d4a6f8daec // indirect
+	golang.org/x/sys v0.34.0 // indirect
+	modernc.org/libc v1.66.3 // indirect
+	modernc.org/mathutil v1.7.1 // indirect
+	modernc.org/memory v1.11.0 // indirect
+)
@@ -0,0 +1,62 @@
+// Package affinity pins worker threads to CPUs. On large multi-socket
+// machines, a worker that stays on one CPU keeps its buffers in that CPU's
+// NUMA node (Linux allocates memory on the node of the thread that first
+// touches it) instead of migrating away from them.
+package affinity
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupported is returned by Pin on platforms without thread affinity
+var ErrUnsupported = errors.New("CPU affinity is not supported on this platform")
+
+// maxCPUs bounds the CPU numbers in a CPU list (and the affinity mask size)
+const maxCPUs = 8192
+
+// ParseCPUList parses a CPU list in the Linux cpuset format, e.g. "0-3,8,10-11",
+// returning the CPUs in ascending order without duplicates
+func ParseCPUList(list string) ([]int, error) {
+	var cpus []int
+	for part := range strings.SplitSeq(list, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		lo, err := parseCPU(first)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CPU list %q: %w", list, err)
+		}
+		hi := lo
+		if isRange {
+			if hi, err = parseCPU(last); err != nil {
+				return nil, fmt.Errorf("invalid CPU list %q: %w", list, err)
+			}
+			if hi < lo {
+				return nil, fmt.Errorf("invalid CPU list %q: range %s is reversed", list, part)
+			}
+		}
+		for cpu := lo; cpu <= hi; cpu++ {
+			cpus = append(cpus, cpu)
+		}
+	}
+	slices.Sort(cpus)
+	return slices.Compact(cpus), nil
+}
+
+// parseCPU parses a CPU number
+func parseCPU(s string) (int, error) {
+	cpu, err := strconv.Atoi(strings.TrimSpace(s))
+	if err != nil || cpu < 0 || cpu >= maxCPUs {
+		return 0, fmt.Errorf("%q is not a CPU number (0-%d)", s, maxCPUs-1)
+	}
+	return cpu, nil
+}
+
+// WorkerCPU returns the CPU worker number worker is pinned to: workers are
+// assigned to cpus round-robin
+func WorkerCPU(cpus []int, worker int) int {
+	return cpus[worker%len(cpus)]
+}
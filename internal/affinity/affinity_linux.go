@@ -0,0 +1,43 @@
+//go:build linux
+
+package affinity
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Supported reports whether Pin is implemented on this platform
+const Supported = true
+
+// cpuMask is a kernel cpu_set_t covering maxCPUs CPUs
+type cpuMask [maxCPUs / 64]uint64
+
+// Allowed returns the CPUs the process may run on
+func Allowed() ([]int, error) {
+	var mask cpuMask
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_GETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return nil, errno
+	}
+	var cpus []int
+	for cpu := range maxCPUs {
+		if mask[cpu/64]&(1<<(cpu%64)) != 0 {
+			cpus = append(cpus, cpu)
+		}
+	}
+	return cpus, nil
+}
+
+// Pin restricts the calling OS thread to one CPU. Callers must lock the
+// goroutine to its thread (runtime.LockOSThread) first and keep it locked, so
+// that the thread exits with the goroutine instead of being reused unpinned.
+func Pin(cpu int) error {
+	var mask cpuMask
+	mask[cpu/64] = 1 << (cpu % 64)
+	_, _, errno := syscall.RawSyscall(syscall.SYS_SCHED_SETAFFINITY, 0, unsafe.Sizeof(mask), uintptr(unsafe.Pointer(&mask)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,22 @@
+//go:build !linux
+
+package affinity
+
+import "runtime"
+
+// Supported reports whether Pin is implemented on this platform
+const Supported = false
+
+// Allowed returns the CPUs the process may run on, assumed to be all CPUs
+func Allowed() ([]int, error) {
+	cpus := make([]int, runtime.NumCPU())
+	for i := range cpus {
+		cpus[i] = i
+	}
+	return cpus, nil
+}
+
+// Pin reports ErrUnsupported: thread affinity is only implemented on Linux
+func Pin(int) error {
+	return ErrUnsupported
+}
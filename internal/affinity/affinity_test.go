@@ -0,0 +1,72 @@
+package affinity
+
+import (
+	"runtime"
+	"slices"
+	"testing"
+)
+
+// TestParseCPUList tests parsing cpuset-style CPU lists
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		list    string
+		want    []int
+		wantErr bool
+	}{
+		{"0", []int{0}, false},
+		{"0-3", []int{0, 1, 2, 3}, false},
+		{"8, 0-1,10-11", []int{0, 1, 8, 10, 11}, false},
+		{"2,1-3", []int{1, 2, 3}, false},
+		{"3-1", nil, true},
+		{"-1", nil, true},
+		{"a-b", nil, true},
+		{"0,,1", nil, true},
+		{"8192", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseCPUList(tt.list)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ParseCPUList(%q) error = %v, wantErr %v", tt.list, err, tt.wantErr)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParseCPUList(%q) = %v, want %v", tt.list, got, tt.want)
+		}
+	}
+}
+
+// TestWorkerCPU tests round-robin assignment of workers to CPUs
+func TestWorkerCPU(t *testing.T) {
+	cpus := []int{4, 5, 64}
+	var got []int
+	for worker := range 5 {
+		got = append(got, WorkerCPU(cpus, worker))
+	}
+	if want := []int{4, 5, 64, 4, 5}; !slices.Equal(got, want) {
+		t.Errorf("WorkerCPU() = %v, want %v", got, want)
+	}
+}
+
+// TestPin tests pinning a thread to an allowed CPU
+func TestPin(t *testing.T) {
+	cpus, err := Allowed()
+	if err != nil || len(cpus) == 0 {
+		t.Fatalf("Allowed() = %v, %v, want at least one CPU", cpus, err)
+	}
+	if !Supported {
+		if err := Pin(cpus[0]); err != ErrUnsupported {
+			t.Errorf("Pin() error = %v, want ErrUnsupported", err)
+		}
+		return
+	}
+
+	// Pin a throwaway thread: it exits with the locked goroutine
+	done := make(chan error)
+	go func() {
+		runtime.LockOSThread()
+		done <- Pin(cpus[len(cpus)-1])
+	}()
+	if err := <-done; err != nil {
+		t.Errorf("Pin(%d) error = %v", cpus[len(cpus)-1], err)
+	}
+}
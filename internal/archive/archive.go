@@ -0,0 +1,437 @@
+// Package archive reads the members of ZIP, tar and gzip-compressed tar
+// archives as streams, so their strings can be scanned member by member and
+// attributed to their paths without unpacking the archive to disk. Encrypted
+// ZIP members are decrypted with the passwords given (traditional PKWARE
+// encryption and WinZip AES), as malware samples are commonly shared in ZIP
+// files protected by "infected".
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"slices"
+	"sync/atomic"
+)
+
+// Decryption statuses of encrypted members (Member.Decryption)
+const (
+	Decrypted     = "decrypted"      // Decrypted with one of the passwords
+	NoPassword    = "no-password"    // Encrypted, and no password was given
+	WrongPassword = "wrong-password" // None of the passwords decrypts it
+	Unsupported   = "unsupported"    // Encrypted with a method this package cannot decrypt
+)
+
+// ErrUnsupported is returned (wrapped) for members using a compression or
+// encryption method this package cannot read
+var ErrUnsupported = errors.New("not supported")
+
+// Member is a file in an archive
+type Member struct {
+	Path       string    // Path in the archive, e.g. bin/sample.exe (nested.zip!bin/sample.exe in nested archives)
+	Size       int64     // Uncompressed size
+	Decryption string    // Decryption status if the member is encrypted, "" otherwise
+	Reader     io.Reader // Content of the member, nil if it cannot be read
+	Err        error     // Why Reader is nil
+
+	limited *limitedReader // Applies the limits to Reader
+	limit   error          // Limit reached before Reader was read
+	closer  io.Closer      // Releases Reader
+}
+
+// Close releases the member once Reader was read
+func (m Member) Close() error {
+	if m.closer == nil {
+		return nil
+	}
+	return m.closer.Close()
+}
+
+// Limit returns the limit (wrapping ErrLimit) that cut the member short or
+// kept a nested archive from being expanded, once Reader was read
+func (m Member) Limit() error {
+	if m.limited != nil && m.limited.err != nil {
+		return m.limited.err
+	}
+	return m.limit
+}
+
+// Options control reading archives
+type Options struct {
+	Passwords []string // Tried in order on encrypted members
+	Limits    Limits
+}
+
+// Limits bound the data expanded from an archive, so that archive bombs
+// (small archives expanding to huge data, or nesting archives over and over)
+// cannot exhaust the memory, disk or time of a scan
+type Limits struct {
+	MaxDepth     int     // Deepest nesting of archives expanded (1 = only the members of the archive, 0 = DefaultMaxDepth)
+	MaxExpansion float64 // Largest ratio of a member's size to its compressed size (0 = unlimited, see expansionFloor)
+	MaxExtracted int64   // Most bytes extracted from an archive in all, nested archives included (0 = unlimited)
+}
+
+// Default limits
+const (
+	DefaultMaxDepth     = 3
+	DefaultMaxExpansion = 200
+	DefaultMaxExtracted = 16 << 30
+)
+
+// ErrFormat is returned by Walk for data that is not an archive it reads
+var ErrFormat = errors.New("not a ZIP or tar archive")
+
+// ErrLimit is returned (wrapped) by Member.Limit when a limit was reached
+var ErrLimit = errors.New("archive limit reached")
+
+// expansionFloor is the size members may always expand to, whatever their
+// ratio, so that small, highly compressible files are not cut short
+const expansionFloor = 1 << 20
+
+// zipMagic starts ZIP archives (a local file header, or the end of the
+// central directory of an empty archive)
+var zipMagic = [][]byte{[]byte("PK\x03\x04"), []byte("PK\x05\x06")}
+
+// Formats of archives
+const (
+	formatNone = iota
+	formatZip
+	formatTar
+	formatTarGzip
+)
+
+// headerSize is the start of a file read to tell its format: enough for the
+// first tar header, compressed or not
+const headerSize = 4096
+
+// IsArchive reports whether r starts like an archive Walk reads
+func IsArchive(r io.ReaderAt) bool {
+	header := make([]byte, headerSize)
+	n, _ := r.ReadAt(header, 0)
+	return detect(header[:n]) != formatNone
+}
+
+// detect returns the format of the archive header starts, formatNone if it is
+// not an archive
+func detect(header []byte) int {
+	switch {
+	case slices.ContainsFunc(zipMagic, func(magic []byte) bool { return bytes.HasPrefix(header, magic) }):
+		return formatZip
+	case isTarHeader(header):
+		return formatTar
+	case bytes.HasPrefix(header, gzipMagic):
+		// Decompress the start of the stream for a tar header; plain
+		// gzip-compressed files are not archives
+		reader, err := gzip.NewReader(bytes.NewReader(header))
+		if err != nil {
+			return formatNone
+		}
+		start := make([]byte, tarMagicOffset+len(tarMagic))
+		if _, err := io.ReadFull(reader, start); err == nil && isTarHeader(start) {
+			return formatTarGzip
+		}
+	}
+	return formatNone
+}
+
+// Walk calls fn with each file of the archive in r, a ZIP (in the order of
+// its central directory), tar or gzip-compressed tar archive, trying the
+// passwords in order on encrypted ZIP members. Members that are archives
+// themselves are followed by their own members, down to
+// options.Limits.MaxDepth. fn must close each member (Member.Close) once it
+// was read. ZIP members may be read after fn returns and in another goroutine
+// if r supports concurrent reads, so members can be read in parallel; tar
+// members are stored one after the other, so Walk waits for each to be closed
+// before reading the next. Walk stops at the first error fn returns; problems
+// with single members are reported in Member.Err and Member.Limit, and Walk
+// fails if the archive as a whole cannot be read.
+func Walk(r io.ReaderAt, size int64, options Options, fn func(Member) error) error {
+	if options.Limits.MaxDepth <= 0 {
+		options.Limits.MaxDepth = DefaultMaxDepth
+	}
+	w := &walker{options: options, fn: fn}
+	if options.Limits.MaxExtracted > 0 {
+		w.budget = &budget{}
+		w.budget.left.Store(options.Limits.MaxExtracted)
+	}
+	return w.walk(r, size, "", 1)
+}
+
+// walker walks an archive and the archives nested in it
+type walker struct {
+	options Options
+	fn      func(Member) error
+	budget  *budget // Data left to extract for Limits.MaxExtracted, nil if unlimited
+}
+
+// budget is the data left to extract from an archive, shared by its members,
+// which may be read concurrently
+type budget struct {
+	left atomic.Int64
+}
+
+// take reserves up to n bytes of the budget, returning the bytes reserved
+func (b *budget) take(n int64) int64 {
+	for {
+		left := b.left.Load()
+		taken := min(n, left)
+		if taken <= 0 {
+			return 0
+		}
+		if b.left.CompareAndSwap(left, left-taken) {
+			return taken
+		}
+	}
+}
+
+// give returns reserved bytes that were not used to the budget
+func (b *budget) give(n int64) {
+	b.left.Add(n)
+}
+
+// walk walks the archive in r, found at depth (1 for the archive walked),
+// prefixing the paths of its members with prefix
+func (w *walker) walk(r io.ReaderAt, size int64, prefix string, depth int) error {
+	header := make([]byte, headerSize)
+	n, _ := r.ReadAt(header, 0)
+	switch detect(header[:n]) {
+	case formatZip:
+		return w.walkZip(r, size, prefix, depth)
+	case formatTar:
+		return w.walkTar(io.NewSectionReader(r, 0, size), size, prefix, depth)
+	case formatTarGzip:
+		reader, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return err
+		}
+		return w.walkTar(reader, size, prefix, depth)
+	}
+	return ErrFormat
+}
+
+// walkZip walks the ZIP archive in r (see walk)
+func (w *walker) walkZip(r io.ReaderAt, size int64, prefix string, depth int) error {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	for _, file := range archive.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+		// Members over the limits are skipped before they are decrypted
+		member := Member{Path: file.Name, Size: int64(file.UncompressedSize64)}
+		if _, err := w.admit(member.Size, int64(file.CompressedSize64)); err != nil {
+			member.Err = err
+		} else {
+			member = openMember(file, w.options.Passwords)
+		}
+		member.Path = prefix + member.Path
+		nested := w.limit(&member, int64(file.CompressedSize64), depth)
+		if err := w.fn(member); err != nil {
+			return err
+		}
+		if err := w.walkNested(nested, member.Path, depth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkNested walks the nested archive limit returned for the member at path,
+// if any. Nested archives that turn out to be malformed are only scanned as
+// members.
+func (w *walker) walkNested(nested *bytes.Reader, path string, depth int) error {
+	if nested == nil {
+		return nil
+	}
+	err := w.walk(nested, nested.Size(), path+"!", depth+1)
+	if errors.Is(err, ErrFormat) || errors.Is(err, zip.ErrFormat) || errors.Is(err, tar.ErrHeader) ||
+		errors.Is(err, gzip.ErrHeader) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return nil
+	}
+	return err
+}
+
+// limit applies the limits to a member stored in compressed bytes. If the
+// member is an archive to expand, it is read into memory and returned for
+// walking once the member itself was reported.
+func (w *walker) limit(member *Member, compressed int64, depth int) *bytes.Reader {
+	if member.Reader == nil {
+		return nil
+	}
+	limits := w.options.Limits
+	allowed, err := w.admit(member.Size, compressed)
+	if err != nil {
+		member.skip(err)
+		return nil
+	}
+	member.limited = &limitedReader{r: member.Reader, n: allowed, budget: w.budget, limits: limits}
+	member.Reader = member.limited
+
+	// Read nested archives into memory to walk them
+	buffered := bufio.NewReaderSize(member.Reader, headerSize)
+	member.Reader = buffered
+	if header, _ := buffered.Peek(headerSize); detect(header) == formatNone {
+		return nil
+	}
+	if depth >= limits.MaxDepth {
+		member.limit = fmt.Errorf("%w: nested archive not expanded, it is deeper than %d archives", ErrLimit, limits.MaxDepth)
+		return nil
+	}
+	data, err := io.ReadAll(buffered)
+	if err != nil {
+		member.skip(err)
+		return nil
+	}
+	member.Reader = bytes.NewReader(data)
+	if member.limited.err != nil {
+		return nil // A truncated archive cannot be walked
+	}
+	return bytes.NewReader(data)
+}
+
+// admit checks the declared size of a member stored in compressed bytes
+// against the limits, returning the bytes it may expand to
+func (w *walker) admit(size, compressed int64) (int64, error) {
+	limits := w.options.Limits
+	allowed := int64(math.MaxInt64)
+	if limits.MaxExpansion > 0 {
+		allowed = max(int64(float64(compressed)*limits.MaxExpansion), expansionFloor)
+		if size > allowed {
+			return 0, fmt.Errorf("%w: member skipped, it expands %d bytes to %d (more than %gx)", ErrLimit, compressed, size, limits.MaxExpansion)
+		}
+	}
+	if w.budget != nil && w.budget.left.Load() <= 0 {
+		return 0, fmt.Errorf("%w: member skipped, %d bytes were extracted from the archive", ErrLimit, limits.MaxExtracted)
+	}
+	return allowed, nil
+}
+
+// skip releases a member that will not be read, reporting err
+func (m *Member) skip(err error) {
+	_ = m.Close()
+	m.Reader, m.Err, m.limited, m.closer = nil, err, nil, nil
+}
+
+// limitedReader reads a member up to the limits, then reports the end of the
+// member and records the limit reached if there was more
+type limitedReader struct {
+	r      io.Reader
+	n      int64   // Bytes left before the expansion limit
+	budget *budget // Data left to extract from the archive, nil if unlimited
+	limits Limits
+	err    error // Limit that cut the member short
+}
+
+// Read implements io.Reader
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	want := min(int64(len(p)), l.n)
+	if l.budget != nil {
+		want = l.budget.take(want)
+	}
+	if want <= 0 {
+		if l.err == nil {
+			var probe [1]byte
+			if n, _ := io.ReadFull(l.r, probe[:]); n > 0 {
+				l.err = l.limitReached()
+			}
+		}
+		return 0, io.EOF
+	}
+	n, err := l.r.Read(p[:want])
+	l.n -= int64(n)
+	if l.budget != nil {
+		l.budget.give(want - int64(n))
+	}
+	return n, err
+}
+
+// limitReached returns the limit that stopped reading the member
+func (l *limitedReader) limitReached() error {
+	if l.n <= 0 {
+		return fmt.Errorf("%w: member cut short, it expands more than %gx", ErrLimit, l.limits.MaxExpansion)
+	}
+	return fmt.Errorf("%w: member cut short, %d bytes were extracted from the archive", ErrLimit, l.limits.MaxExtracted)
+}
+
+// openMember opens a file of an archive, decrypting it if needed
+func openMember(file *zip.File, passwords []string) Member {
+	member := Member{Path: file.Name, Size: int64(file.UncompressedSize64)}
+	if file.Flags&flagEncrypted == 0 {
+		reader, err := file.Open()
+		if err != nil {
+			member.Err = err
+			return member
+		}
+		member.Reader, member.closer = reader, reader
+		return member
+	}
+
+	open := openZipCrypto
+	method := file.Method
+	if file.Method == methodAES {
+		open = openAES
+		var err error
+		if _, method, err = aesField(file); err != nil {
+			member.Decryption, member.Err = Unsupported, err
+			return member
+		}
+	}
+	if file.Flags&flagStrongEncryption != 0 {
+		member.Decryption, member.Err = Unsupported, fmt.Errorf("strong encryption: %w", ErrUnsupported)
+		return member
+	}
+	if len(passwords) == 0 {
+		member.Decryption, member.Err = NoPassword, errors.New("encrypted, no password given")
+		return member
+	}
+
+	for _, password := range passwords {
+		reader, err := open(file, password)
+		if errors.Is(err, errWrongPassword) {
+			continue
+		}
+		if err != nil {
+			member.Decryption, member.Err = Unsupported, err
+			return member
+		}
+		reader, closer, err := decompress(reader, method)
+		if err != nil {
+			member.Decryption, member.Err = Unsupported, err
+			return member
+		}
+		if file.Method != methodAES {
+			// The header of the traditional encryption only checks one
+			// byte of the password, the CRC the rest as the member is read
+			reader = &crcReader{r: reader, hash: crc32.NewIEEE(), want: file.CRC32}
+		}
+		member.Decryption, member.Reader, member.closer = Decrypted, reader, closer
+		return member
+	}
+	member.Decryption, member.Err = WrongPassword, errors.New("none of the passwords decrypts it")
+	return member
+}
+
+// decompress returns the decompressed content of a member whose data r reads
+func decompress(r io.Reader, method uint16) (io.Reader, io.Closer, error) {
+	switch method {
+	case zip.Store:
+		return r, nil, nil
+	case zip.Deflate:
+		reader := flate.NewReader(r)
+		return reader, reader, nil
+	}
+	return nil, nil, fmt.Errorf("compression method %d: %w", method, ErrUnsupported)
+}
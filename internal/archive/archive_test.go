@@ -0,0 +1,515 @@
+package archive
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand/v2"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// deflateTestData compresses data with deflate
+func deflateTestData(t testing.TB, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w, err := flate.NewWriter(&buf, flate.DefaultCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// zipCryptoTestData encrypts compressed member data with the traditional
+// PKWARE encryption
+func zipCryptoTestData(password string, crc uint32, compressed []byte) []byte {
+	crypto := newZipCrypto(password)
+	plain := append([]byte("random head"), byte(crc>>24))
+	plain = append(plain, compressed...)
+	encrypted := make([]byte, len(plain))
+	for i, p := range plain {
+		temp := crypto.keys[2] | 2
+		encrypted[i] = p ^ byte(temp*(temp^1)>>8)
+		crypto.update(p)
+	}
+	return encrypted
+}
+
+// aesTestData encrypts compressed member data with WinZip AES-256,
+// returning the member data and its AES extra field
+func aesTestData(t testing.TB, password string, method uint16, compressed []byte) (data, extra []byte) {
+	t.Helper()
+	salt := []byte("0123456789abcdef")
+	keys, err := pbkdf2.Key(sha1.New, password, salt, 1000, 66)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block, err := aes.NewCipher(keys[:32])
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted := make([]byte, len(compressed))
+	newWinZipCTR(block).XORKeyStream(encrypted, compressed)
+
+	data = append(slices.Concat(salt, keys[64:], encrypted), make([]byte, aesAuthSize)...)
+	extra = binary.LittleEndian.AppendUint16(nil, aesExtraID)
+	extra = binary.LittleEndian.AppendUint16(extra, 7)
+	extra = append(extra, 2, 0, 'A', 'E', 3)
+	extra = binary.LittleEndian.AppendUint16(extra, method)
+	return data, extra
+}
+
+// zipTestArchive returns a ZIP archive with a directory, a stored and a
+// deflated member, and members encrypted with "infected"
+func zipTestArchive(t testing.TB) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	if _, err := w.Create("docs/"); err != nil {
+		t.Fatal(err)
+	}
+	f, err := w.CreateHeader(&zip.FileHeader{Name: "docs/stored.txt", Method: zip.Store})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("stored member")); err != nil {
+		t.Fatal(err)
+	}
+	f, err = w.Create("deflated.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("deflated member")); err != nil {
+		t.Fatal(err)
+	}
+
+	raw := func(header *zip.FileHeader, data []byte) {
+		f, err := w.CreateRaw(header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	content := []byte("http://c2.example/beacon")
+	crc := crc32.ChecksumIEEE(content)
+	compressed := deflateTestData(t, content)
+
+	encrypted := zipCryptoTestData("infected", crc, compressed)
+	raw(&zip.FileHeader{Name: "zipcrypto.bin", Method: zip.Deflate, Flags: flagEncrypted, CRC32: crc,
+		CompressedSize64: uint64(len(encrypted)), UncompressedSize64: uint64(len(content))}, encrypted)
+
+	data, extra := aesTestData(t, "infected", zip.Deflate, compressed)
+	raw(&zip.FileHeader{Name: "aes.bin", Method: methodAES, Flags: flagEncrypted, Extra: extra,
+		CompressedSize64: uint64(len(data)), UncompressedSize64: uint64(len(content))}, data)
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestWalkZip tests reading plain and encrypted members
+func TestWalkZip(t *testing.T) {
+	data := zipTestArchive(t)
+	if !IsArchive(bytes.NewReader(data)) || IsArchive(bytes.NewReader([]byte("MZ\x90\x00"))) {
+		t.Fatal("IsArchive() does not tell ZIP archives apart")
+	}
+
+	type result struct {
+		decryption string
+		content    string
+	}
+	tests := []struct {
+		name      string
+		passwords []string
+		want      map[string]result
+	}{
+		{"right password", []string{"wrong", "infected"}, map[string]result{
+			"docs/stored.txt": {"", "stored member"},
+			"deflated.txt":    {"", "deflated member"},
+			"zipcrypto.bin":   {Decrypted, "http://c2.example/beacon"},
+			"aes.bin":         {Decrypted, "http://c2.example/beacon"},
+		}},
+		{"wrong password", []string{"malware"}, map[string]result{
+			"docs/stored.txt": {"", "stored member"},
+			"deflated.txt":    {"", "deflated member"},
+			"zipcrypto.bin":   {WrongPassword, ""},
+			"aes.bin":         {WrongPassword, ""},
+		}},
+		{"no password", nil, map[string]result{
+			"docs/stored.txt": {"", "stored member"},
+			"deflated.txt":    {"", "deflated member"},
+			"zipcrypto.bin":   {NoPassword, ""},
+			"aes.bin":         {NoPassword, ""},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]result)
+			err := Walk(bytes.NewReader(data), int64(len(data)), Options{Passwords: tt.passwords}, func(m Member) error {
+				var content []byte
+				if m.Reader != nil {
+					var err error
+					if content, err = io.ReadAll(m.Reader); err != nil {
+						t.Errorf("%s: read error = %v", m.Path, err)
+					}
+				} else if m.Err == nil {
+					t.Errorf("%s: no reader and no error", m.Path)
+				}
+				got[m.Path] = result{m.Decryption, string(content)}
+				return m.Close()
+			})
+			if err != nil {
+				t.Fatalf("Walk() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("members = %v, want %v", got, tt.want)
+			}
+			for path, want := range tt.want {
+				if got[path] != want {
+					t.Errorf("%s = %+v, want %+v", path, got[path], want)
+				}
+			}
+		})
+	}
+}
+
+// TestWalkZipCrypto tests that members of the traditional encryption are
+// checked against the limits before they are decrypted, that a wrong
+// password passing the one byte check of the header is passed over, and that
+// the CRC of the member is checked as it is read
+func TestWalkZipCrypto(t *testing.T) {
+	content := []byte("http://c2.example/beacon")
+	crc := crc32.ChecksumIEEE(content)
+	compressed := deflateTestData(t, content)
+	encrypted := zipCryptoTestData("infected", crc, compressed)
+
+	// A wrong password whose header check passes
+	var lucky string
+	for i := 0; lucky == ""; i++ {
+		header := slices.Clone(encrypted[:zipCryptoHeaderSize])
+		newZipCrypto(fmt.Sprint("guess", i)).decrypt(header)
+		if header[zipCryptoHeaderSize-1] == byte(crc>>24) {
+			lucky = fmt.Sprint("guess", i)
+		}
+	}
+
+	archive := func(header zip.FileHeader, data []byte) []byte {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		header.Flags |= flagEncrypted
+		header.CompressedSize64 = uint64(len(data))
+		f, err := w.CreateRaw(&header)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(data); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	walk := func(data []byte, options Options) (Member, []byte, error) {
+		var member Member
+		var content []byte
+		var readErr error
+		err := Walk(bytes.NewReader(data), int64(len(data)), options, func(m Member) error {
+			member = m
+			if m.Reader != nil {
+				content, readErr = io.ReadAll(m.Reader)
+			}
+			return m.Close()
+		})
+		if err != nil {
+			t.Fatalf("Walk() error = %v", err)
+		}
+		return member, content, readErr
+	}
+
+	deflated := archive(zip.FileHeader{Name: "member.bin", Method: zip.Deflate, CRC32: crc, UncompressedSize64: uint64(len(content))}, encrypted)
+	m, got, err := walk(deflated, Options{Passwords: []string{lucky, "infected"}})
+	if m.Decryption != Decrypted || string(got) != string(content) || err != nil {
+		t.Errorf("member with a lucky wrong password first = %s, %q, %v, want %q decrypted", m.Decryption, got, err, content)
+	}
+
+	// Declared expanding past the limit: skipped without trying passwords
+	bomb := archive(zip.FileHeader{Name: "member.bin", Method: zip.Deflate, CRC32: crc, UncompressedSize64: 1 << 40}, encrypted)
+	m, _, _ = walk(bomb, Options{Passwords: []string{"infected"}, Limits: Limits{MaxExpansion: 100}})
+	if !errors.Is(m.Err, ErrLimit) || m.Decryption != "" || m.Reader != nil {
+		t.Errorf("member over the expansion limit = %s, %v, want skipped before decryption", m.Decryption, m.Err)
+	}
+
+	// Stored with a CRC sharing the byte the header checks
+	stored := zipCryptoTestData("infected", crc^1, content)
+	m, got, err = walk(archive(zip.FileHeader{Name: "member.bin", Method: zip.Store, CRC32: crc ^ 1, UncompressedSize64: uint64(len(content))}, stored),
+		Options{Passwords: []string{"infected"}})
+	if m.Decryption != Decrypted || string(got) != string(content) || !errors.Is(err, errWrongPassword) {
+		t.Errorf("member with a wrong CRC = %s, %q, %v, want its content and a CRC error", m.Decryption, got, err)
+	}
+}
+
+// zipTestMembers returns a ZIP archive of deflated members
+func zipTestMembers(t testing.TB, members ...struct {
+	name string
+	data []byte
+}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, member := range members {
+		f, err := w.Create(member.name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := f.Write(member.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestWalkZipLimits tests expanding nested archives and the limits on the
+// data expanded
+func TestWalkZipLimits(t *testing.T) {
+	type member = struct {
+		name string
+		data []byte
+	}
+	inner := zipTestMembers(t, member{"deep.txt", []byte("deepest string")})
+	middle := zipTestMembers(t, member{"inner.zip", inner}, member{"middle.txt", []byte("middle string")})
+	random := func() []byte {
+		data := make([]byte, 1500<<10)
+		for i := range data {
+			data[i] = byte(rand.Uint32())
+		}
+		return data
+	}
+	data := zipTestMembers(t,
+		member{"middle.zip", middle},
+		member{"zeros.bin", make([]byte, 3<<20)},
+		member{"a.bin", random()},
+		member{"b.bin", random()},
+		member{"c.txt", []byte("c")},
+	)
+
+	type result struct {
+		size  int
+		limit bool // Member.Limit or Member.Err wraps ErrLimit
+	}
+	tests := []struct {
+		name   string
+		limits Limits
+		want   map[string]result
+	}{
+		{"defaults", Limits{}, map[string]result{
+			"middle.zip": {len(middle), false}, "middle.zip!inner.zip": {len(inner), false},
+			"middle.zip!inner.zip!deep.txt": {14, false}, "middle.zip!middle.txt": {13, false},
+			"zeros.bin": {3 << 20, false}, "a.bin": {1500 << 10, false}, "b.bin": {1500 << 10, false}, "c.txt": {1, false},
+		}},
+		{"depth and expansion", Limits{MaxDepth: 2, MaxExpansion: 100}, map[string]result{
+			"middle.zip": {len(middle), false}, "middle.zip!inner.zip": {len(inner), true},
+			"middle.zip!middle.txt": {13, false},
+			"zeros.bin":             {0, true}, "a.bin": {1500 << 10, false}, "b.bin": {1500 << 10, false}, "c.txt": {1, false},
+		}},
+		{"extracted", Limits{MaxDepth: 1, MaxExtracted: 5 << 20}, map[string]result{
+			"middle.zip": {len(middle), true},
+			"zeros.bin":  {3 << 20, false}, "a.bin": {1500 << 10, false}, "b.bin": {5<<20 - 3<<20 - 1500<<10 - len(middle), true}, "c.txt": {0, true},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string]result)
+			err := Walk(bytes.NewReader(data), int64(len(data)), Options{Limits: tt.limits}, func(m Member) error {
+				var content []byte
+				if m.Reader != nil {
+					var err error
+					if content, err = io.ReadAll(m.Reader); err != nil {
+						t.Errorf("%s: read error = %v", m.Path, err)
+					}
+				}
+				got[m.Path] = result{len(content), errors.Is(m.Limit(), ErrLimit) || errors.Is(m.Err, ErrLimit)}
+				return m.Close()
+			})
+			if err != nil {
+				t.Fatalf("Walk() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("members = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestWalkZipConcurrent tests reading members in parallel after fn returns,
+// sharing the extracted budget between them
+func TestWalkZipConcurrent(t *testing.T) {
+	type member = struct {
+		name string
+		data []byte
+	}
+	var members []member
+	for i := range 8 {
+		members = append(members, member{string(rune('a'+i)) + ".bin", make([]byte, 256<<10)})
+	}
+	data := zipTestMembers(t, members...)
+
+	const limit = 1 << 20
+	var (
+		wg    sync.WaitGroup
+		total atomic.Int64
+	)
+	err := Walk(bytes.NewReader(data), int64(len(data)), Options{Limits: Limits{MaxExtracted: limit}}, func(m Member) error {
+		wg.Go(func() {
+			defer func() { _ = m.Close() }()
+			if m.Reader == nil {
+				return
+			}
+			n, err := io.Copy(io.Discard, m.Reader)
+			if err != nil {
+				t.Errorf("%s: read error = %v", m.Path, err)
+			}
+			total.Add(n)
+		})
+		return nil
+	})
+	wg.Wait()
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if got := total.Load(); got != limit {
+		t.Errorf("extracted %d bytes, want the budget of %d", got, limit)
+	}
+}
+
+// tarTestArchive returns a tar archive of a directory, a symbolic link and
+// the members given, compressed with gzip if compress is set
+func tarTestArchive(t testing.TB, compress bool, members ...struct {
+	name string
+	data []byte
+}) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	var out io.Writer = &buf
+	var compressed *gzip.Writer
+	if compress {
+		compressed = gzip.NewWriter(&buf)
+		out = compressed
+	}
+	w := tar.NewWriter(out)
+	if err := w.WriteHeader(&tar.Header{Typeflag: tar.TypeDir, Name: "bin/", Mode: 0o755}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteHeader(&tar.Header{Typeflag: tar.TypeSymlink, Name: "bin/link", Linkname: "a.exe"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, member := range members {
+		if err := w.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: member.name, Mode: 0o644, Size: int64(len(member.data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(member.data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if compressed != nil {
+		if err := compressed.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// TestWalkTar tests reading the members of tar and gzip-compressed tar
+// archives, nested in each other and in ZIP archives, after fn returns
+func TestWalkTar(t *testing.T) {
+	type member = struct {
+		name string
+		data []byte
+	}
+	inner := tarTestArchive(t, true, member{"deep.txt", []byte("deepest string")})
+	tarData := tarTestArchive(t, false, member{"bin/a.exe", []byte("http://c2.example/beacon")}, member{"inner.tgz", inner})
+	tgzData := tarTestArchive(t, true, member{"bin/a.exe", []byte("http://c2.example/beacon")}, member{"inner.tgz", inner})
+	zipData := zipTestMembers(t, member{"upload.tar", tarData})
+
+	var plainGzip bytes.Buffer
+	gz := gzip.NewWriter(&plainGzip)
+	_, _ = gz.Write(bytes.Repeat([]byte("not an archive "), 100))
+	_ = gz.Close()
+	if IsArchive(bytes.NewReader(plainGzip.Bytes())) || !IsArchive(bytes.NewReader(tarData)) || !IsArchive(bytes.NewReader(tgzData)) {
+		t.Fatal("IsArchive() does not tell tar archives apart from other gzip data")
+	}
+
+	members := map[string]string{
+		"bin/a.exe": "http://c2.example/beacon", "inner.tgz": string(inner), "inner.tgz!deep.txt": "deepest string",
+	}
+	tests := []struct {
+		name string
+		data []byte
+		want map[string]string
+	}{
+		{"tar", tarData, members},
+		{"tar.gz", tgzData, members},
+		{"nested in ZIP", zipData, map[string]string{
+			"upload.tar": string(tarData), "upload.tar!bin/a.exe": "http://c2.example/beacon",
+			"upload.tar!inner.tgz": string(inner), "upload.tar!inner.tgz!deep.txt": "deepest string",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var (
+				mu sync.Mutex
+				wg sync.WaitGroup
+			)
+			got := make(map[string]string)
+			err := Walk(bytes.NewReader(tt.data), int64(len(tt.data)), Options{}, func(m Member) error {
+				// Read in another goroutine, as parallel scans do
+				wg.Go(func() {
+					defer func() { _ = m.Close() }()
+					content, err := io.ReadAll(m.Reader)
+					if err != nil {
+						t.Errorf("%s: read error = %v", m.Path, err)
+					}
+					mu.Lock()
+					got[m.Path] = string(content)
+					mu.Unlock()
+				})
+				return nil
+			})
+			wg.Wait()
+			if err != nil {
+				t.Fatalf("Walk() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("members = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package archive
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+)
+
+// General purpose flags of encrypted members
+const (
+	flagEncrypted        = 0x1
+	flagDataDescriptor   = 0x8  // CRC and sizes follow the data, the traditional header checks the time instead
+	flagStrongEncryption = 0x40 // PKWARE strong encryption
+)
+
+// methodAES marks members encrypted with WinZip AES, whose real compression
+// method is recorded in their AES extra field
+const methodAES = 99
+
+// errWrongPassword is returned by the decryptors when the password check fails
+var errWrongPassword = errors.New("wrong password")
+
+// zipCryptoHeaderSize is the size of the encryption header of the traditional
+// PKWARE encryption
+const zipCryptoHeaderSize = 12
+
+// zipCrypto is the key state of the traditional PKWARE encryption
+type zipCrypto struct {
+	keys [3]uint32
+}
+
+// newZipCrypto returns the key state initialized with password
+func newZipCrypto(password string) *zipCrypto {
+	z := &zipCrypto{keys: [3]uint32{0x12345678, 0x23456789, 0x34567890}}
+	for i := range len(password) {
+		z.update(password[i])
+	}
+	return z
+}
+
+// update mixes a plaintext byte into the keys
+func (z *zipCrypto) update(b byte) {
+	z.keys[0] = crc32Update(z.keys[0], b)
+	z.keys[1] = (z.keys[1]+z.keys[0]&0xff)*134775813 + 1
+	z.keys[2] = crc32Update(z.keys[2], byte(z.keys[1]>>24))
+}
+
+// decrypt decrypts buf in place
+func (z *zipCrypto) decrypt(buf []byte) {
+	for i, c := range buf {
+		temp := z.keys[2] | 2
+		buf[i] = c ^ byte(temp*(temp^1)>>8)
+		z.update(buf[i])
+	}
+}
+
+// crc32Update adds a byte to a CRC-32 in the form the cipher uses (without
+// the pre and post inversion of crc32.Update)
+func crc32Update(crc uint32, b byte) uint32 {
+	return crc32.IEEETable[byte(crc)^b] ^ crc>>8
+}
+
+// zipCryptoReader decrypts a member encrypted with the traditional PKWARE
+// encryption
+type zipCryptoReader struct {
+	r      io.Reader
+	crypto *zipCrypto
+}
+
+// Read implements io.Reader
+func (r *zipCryptoReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.crypto.decrypt(p[:n])
+	return n, err
+}
+
+// zipCryptoProbe is how much of a deflated member is decompressed to check a
+// password passing the one byte check of the encryption header
+const zipCryptoProbe = 64 << 10
+
+// openZipCrypto returns the decrypted data of a member encrypted with the
+// traditional PKWARE encryption. Its header only checks one byte of the
+// password: a deflated member decrypted with a wrong password passing it
+// fails to decompress almost at once, so the start of the member is
+// decompressed to try the next password. The CRC of the whole member is
+// checked as it is read (see crcReader), within the limits of the archive.
+func openZipCrypto(file *zip.File, password string) (io.Reader, error) {
+	open := func() (io.Reader, error) {
+		raw, err := file.OpenRaw()
+		if err != nil {
+			return nil, err
+		}
+		crypto := newZipCrypto(password)
+		header := make([]byte, zipCryptoHeaderSize)
+		if _, err := io.ReadFull(raw, header); err != nil {
+			return nil, err
+		}
+		crypto.decrypt(header)
+		check := byte(file.CRC32 >> 24)
+		if file.Flags&flagDataDescriptor != 0 {
+			check = byte(file.ModifiedTime >> 8)
+		}
+		if header[zipCryptoHeaderSize-1] != check {
+			return nil, errWrongPassword
+		}
+		return &zipCryptoReader{r: raw, crypto: crypto}, nil
+	}
+
+	reader, err := open()
+	if err != nil || file.Method != zip.Deflate {
+		return reader, err
+	}
+	probe := flate.NewReader(reader)
+	_, err = io.CopyN(io.Discard, probe, zipCryptoProbe)
+	_ = probe.Close()
+	if err != nil && err != io.EOF {
+		return nil, errWrongPassword
+	}
+	return open()
+}
+
+// crcReader checks the CRC-32 of a decrypted member as it is read, failing
+// at its end if it does not match
+type crcReader struct {
+	r    io.Reader
+	hash hash.Hash32
+	want uint32
+}
+
+// Read implements io.Reader
+func (c *crcReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.hash.Write(p[:n])
+	if err == io.EOF && c.hash.Sum32() != c.want {
+		return n, fmt.Errorf("%w or corrupt member: CRC-32 mismatch", errWrongPassword)
+	}
+	return n, err
+}
+
+// aesExtraID identifies the WinZip AES extra field
+const aesExtraID = 0x9901
+
+// aesAuthSize is the size of the authentication code following AES data
+const aesAuthSize = 10
+
+// aesField returns the key strength (1 to 3 for AES-128 to AES-256) and the
+// compression method recorded in the WinZip AES extra field of a member
+func aesField(file *zip.File) (strength byte, method uint16, err error) {
+	extra := file.Extra
+	for len(extra) >= 4 {
+		id, size := binary.LittleEndian.Uint16(extra), int(binary.LittleEndian.Uint16(extra[2:]))
+		if len(extra) < 4+size {
+			break
+		}
+		if data := extra[4 : 4+size]; id == aesExtraID && size >= 7 && string(data[2:4]) == "AE" {
+			return data[4], binary.LittleEndian.Uint16(data[5:]), nil
+		}
+		extra = extra[4+size:]
+	}
+	return 0, 0, errors.New("missing AES extra field")
+}
+
+// openAES returns the decrypted data of a member encrypted with WinZip AES
+func openAES(file *zip.File, password string) (io.Reader, error) {
+	strength, _, err := aesField(file)
+	if err != nil {
+		return nil, err
+	}
+	if strength < 1 || strength > 3 {
+		return nil, fmt.Errorf("AES strength %d: %w", strength, ErrUnsupported)
+	}
+	keySize := 8 + 8*int(strength)
+	saltSize := keySize / 2
+	dataSize := int64(file.CompressedSize64) - int64(saltSize) - 2 - aesAuthSize
+	if dataSize < 0 {
+		return nil, errors.New("AES data too short")
+	}
+
+	raw, err := file.OpenRaw()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, saltSize+2)
+	if _, err := io.ReadFull(raw, header); err != nil {
+		return nil, err
+	}
+	keys, err := pbkdf2.Key(sha1.New, password, header[:saltSize], 1000, 2*keySize+2)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(keys[2*keySize:], header[saltSize:]) != 1 {
+		return nil, errWrongPassword
+	}
+	block, err := aes.NewCipher(keys[:keySize])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.StreamReader{S: newWinZipCTR(block), R: io.LimitReader(raw, dataSize)}, nil
+}
+
+// winZipCTR is the counter mode of WinZip AES, whose counter is little endian
+// and starts at 1 (unlike cipher.NewCTR)
+type winZipCTR struct {
+	block     cipher.Block
+	counter   [aes.BlockSize]byte
+	keystream [aes.BlockSize]byte
+	used      int
+}
+
+// newWinZipCTR returns the WinZip AES counter mode stream of block
+func newWinZipCTR(block cipher.Block) *winZipCTR {
+	return &winZipCTR{block: block, used: aes.BlockSize}
+}
+
+// XORKeyStream implements cipher.Stream
+func (c *winZipCTR) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if c.used == aes.BlockSize {
+			for j := range c.counter {
+				c.counter[j]++
+				if c.counter[j] != 0 {
+					break
+				}
+			}
+			c.block.Encrypt(c.keystream[:], c.counter[:])
+			c.used = 0
+		}
+		dst[i] = src[i] ^ c.keystream[c.used]
+		c.used++
+	}
+}
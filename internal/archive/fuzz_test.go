@@ -0,0 +1,59 @@
+package archive
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+// FuzzWalk tests reading archives with random inputs
+func FuzzWalk(f *testing.F) {
+	// Seed corpus: the archives of the Walk tests (plain and encrypted ZIP
+	// members, tar and gzip-compressed tar archives nested in each other)
+	// and bare signatures
+	type member = struct {
+		name string
+		data []byte
+	}
+	inner := tarTestArchive(f, true, member{"deep.txt", []byte("deepest string")})
+	tarData := tarTestArchive(f, false, member{"bin/a.exe", []byte("http://c2.example/beacon")}, member{"inner.tgz", inner})
+	f.Add(zipTestArchive(f))
+	f.Add(zipTestMembers(f, member{"upload.tar", tarData}))
+	f.Add(tarData)
+	f.Add(tarTestArchive(f, true, member{"bin/a.exe", []byte("http://c2.example/beacon")}, member{"inner.tgz", inner}))
+	f.Add([]byte("PK\x03\x04"))
+	f.Add([]byte("PK\x05\x06"))
+	f.Add(gzipMagic)
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		options := Options{
+			Passwords: []string{"infected"},
+			Limits:    Limits{MaxExpansion: DefaultMaxExpansion, MaxExtracted: 64 << 20},
+		}
+		// Errors are expected for invalid input
+		_ = Walk(bytes.NewReader(data), int64(len(data)), options, func(m Member) error {
+			// Invariant: members without a reader say why
+			if m.Reader == nil {
+				if m.Err == nil {
+					t.Errorf("%s: no reader and no error", m.Path)
+				}
+				return m.Close()
+			}
+			_, _ = io.Copy(io.Discard, m.Reader)
+			return m.Close()
+		})
+	})
+}
@@ -0,0 +1,69 @@
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"strings"
+	"sync"
+)
+
+// gzipMagic starts gzip streams
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// tarMagic is found at tarMagicOffset in the first header of POSIX (ustar)
+// and GNU tar archives
+var tarMagic = []byte("ustar")
+
+const tarMagicOffset = 257
+
+// isTarHeader reports whether header starts like a tar archive
+func isTarHeader(header []byte) bool {
+	return len(header) >= tarMagicOffset+len(tarMagic) && bytes.Equal(header[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic)
+}
+
+// walkTar walks the tar archive r reads, stored in compressed bytes (see
+// walk). Members of compressed archives have no compressed size of their
+// own, so they are held to the expansion ratio of the whole archive.
+func (w *walker) walkTar(r io.Reader, compressed int64, prefix string, depth int) error {
+	archive := tar.NewReader(r)
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !header.FileInfo().Mode().IsRegular() {
+			continue
+		}
+		// The member is read from the archive stream, which cannot move on
+		// until it is closed
+		closer := &tarMember{closed: make(chan struct{})}
+		// Archives made of a directory (tar -C dir .) name members ./path
+		name := strings.TrimPrefix(header.Name, "./")
+		member := Member{Path: prefix + name, Size: header.Size, Reader: archive, closer: closer}
+		nested := w.limit(&member, compressed, depth)
+		if err := w.fn(member); err != nil {
+			return err
+		}
+		<-closer.closed
+		if err := w.walkNested(nested, member.Path, depth); err != nil {
+			return err
+		}
+	}
+}
+
+// tarMember is closed once a tar member was read, so the walk can move on to
+// the next member
+type tarMember struct {
+	once   sync.Once
+	closed chan struct{}
+}
+
+// Close implements io.Closer
+func (m *tarMember) Close() error {
+	m.once.Do(func() { close(m.closed) })
+	return nil
+}
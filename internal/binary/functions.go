@@ -0,0 +1,220 @@
+package binary
+
+import (
+	"cmp"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+)
+
+// Symbol table constants used to recognize function symbols in PE and Mach-O files
+const (
+	peSymTypeFunction = 0x20 // IMAGE_SYM_DTYPE_FUNCTION << 4
+	machoTypeMask     = 0x0e // N_TYPE
+	machoTypeSection  = 0x0e // N_SECT: defined in a section
+	machoStabMask     = 0xe0 // N_STAB: debugging entry
+)
+
+// errNoFunctions is returned when a binary has no function symbols
+var errNoFunctions = errors.New("no function symbols (stripped binary?)")
+
+// Function is a named range of code in a binary
+type Function struct {
+	Name string
+	Addr uint64 // Virtual address of the first instruction
+	Size uint64
+}
+
+// FunctionIndex finds the function containing a code address
+type FunctionIndex struct {
+	functions []Function // Sorted by address
+}
+
+// Lookup returns the name of the function containing addr
+func (fi *FunctionIndex) Lookup(addr uint64) (string, bool) {
+	if fi == nil {
+		return "", false
+	}
+	// Last function starting at or before addr
+	i := sort.Search(len(fi.functions), func(i int) bool { return fi.functions[i].Addr > addr }) - 1
+	if i < 0 || addr >= fi.functions[i].Addr+fi.functions[i].Size {
+		return "", false
+	}
+	return fi.functions[i].Name, true
+}
+
+// Len returns the number of functions in the index
+func (fi *FunctionIndex) Len() int {
+	return len(fi.functions)
+}
+
+// functionSymbol is a function symbol with the end of the section it is in,
+// which bounds its size if the symbol table does not record one
+type functionSymbol struct {
+	Function
+	sectionEnd uint64
+}
+
+// newFunctionIndex sorts the symbols and sizes those without a size up to
+// the next function or the end of their section. Aliases (symbols at the
+// same address) keep the first name seen.
+func newFunctionIndex(symbols []functionSymbol) (*FunctionIndex, error) {
+	if len(symbols) == 0 {
+		return nil, errNoFunctions
+	}
+	slices.SortStableFunc(symbols, func(a, b functionSymbol) int {
+		return cmp.Compare(a.Addr, b.Addr)
+	})
+	symbols = slices.CompactFunc(symbols, func(a, b functionSymbol) bool {
+		return a.Addr == b.Addr
+	})
+
+	index := &FunctionIndex{functions: make([]Function, len(symbols))}
+	for i, sym := range symbols {
+		if sym.Size == 0 {
+			end := sym.sectionEnd
+			if i+1 < len(symbols) {
+				end = min(end, symbols[i+1].Addr)
+			}
+			sym.Size = end - sym.Addr
+		}
+		index.functions[i] = sym.Function
+	}
+	return index, nil
+}
+
+// LoadFunctions reads the function symbols of an ELF, PE (COFF symbol table,
+// e.g. MinGW builds) or Mach-O binary. It returns an error if the binary has
+// none, e.g. because it is stripped.
+func LoadFunctions(path string, format Format) (*FunctionIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	switch format {
+	case FormatELF:
+		return elfFunctions(file)
+	case FormatPE:
+		return peFunctions(file)
+	case FormatMachO:
+		return machoFunctions(file)
+	default:
+		return nil, fmt.Errorf("function symbols require an ELF, PE or Mach-O binary, got %v", format)
+	}
+}
+
+// elfFunctions collects the STT_FUNC symbols of the static and dynamic symbol
+// tables of an ELF file
+func elfFunctions(file *os.File) (*FunctionIndex, error) {
+	elfFile, err := elf.NewFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid ELF file: %w", err)
+	}
+	defer func() {
+		_ = elfFile.Close()
+	}()
+
+	var symbols []functionSymbol
+	add := func(syms []elf.Symbol) {
+		for _, sym := range syms {
+			if elf.ST_TYPE(sym.Info) != elf.STT_FUNC || sym.Value == 0 || sym.Name == "" ||
+				sym.Section == elf.SHN_UNDEF || int(sym.Section) >= len(elfFile.Sections) {
+				continue
+			}
+			addr := sym.Value
+			if elfFile.Machine == elf.EM_ARM {
+				addr &^= 1 // The low bit marks Thumb code
+			}
+			sect := elfFile.Sections[sym.Section]
+			symbols = append(symbols, functionSymbol{Function{sym.Name, addr, sym.Size}, sect.Addr + sect.Size})
+		}
+	}
+	// The static symbol table comes first, so its names win for aliases
+	if syms, err := elfFile.Symbols(); err == nil {
+		add(syms)
+	}
+	if syms, err := elfFile.DynamicSymbols(); err == nil {
+		add(syms)
+	}
+
+	return newFunctionIndex(symbols)
+}
+
+// peFunctions collects the function symbols of the COFF symbol table of a PE file
+func peFunctions(file *os.File) (*FunctionIndex, error) {
+	peFile, err := pe.NewFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid PE file: %w", err)
+	}
+	defer func() {
+		_ = peFile.Close()
+	}()
+
+	imageBase, _ := peImageInfo(peFile)
+
+	var symbols []functionSymbol
+	for _, sym := range peFile.Symbols {
+		if sym.Type&0xf0 != peSymTypeFunction || sym.SectionNumber <= 0 || int(sym.SectionNumber) > len(peFile.Sections) {
+			continue
+		}
+		sect := peFile.Sections[sym.SectionNumber-1]
+		start := imageBase + uint64(sect.VirtualAddress)
+		symbols = append(symbols, functionSymbol{Function{sym.Name, start + uint64(sym.Value), 0}, start + uint64(sect.VirtualSize)})
+	}
+
+	return newFunctionIndex(symbols)
+}
+
+// machoFunctions collects the symbols defined in the instruction sections of
+// a Mach-O file (the first architecture of a universal binary)
+func machoFunctions(file *os.File) (*FunctionIndex, error) {
+	var machoFile *macho.File
+	if fatFile, err := macho.NewFatFile(file); err == nil {
+		defer func() {
+			_ = fatFile.Close()
+		}()
+		if len(fatFile.Arches) == 0 {
+			return nil, fmt.Errorf("universal binary has no architectures")
+		}
+		machoFile = fatFile.Arches[0].File
+	} else {
+		if _, err := file.Seek(0, 0); err != nil {
+			return nil, fmt.Errorf("failed to seek: %w", err)
+		}
+		machoFile, err = macho.NewFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid Mach-O file: %w", err)
+		}
+		defer func() {
+			_ = machoFile.Close()
+		}()
+	}
+	if machoFile.Symtab == nil {
+		return nil, errNoFunctions
+	}
+
+	var symbols []functionSymbol
+	for _, sym := range machoFile.Symtab.Syms {
+		// Section numbers start at 1
+		if sym.Type&machoStabMask != 0 || sym.Type&machoTypeMask != machoTypeSection ||
+			sym.Sect == 0 || int(sym.Sect) > len(machoFile.Sections) || sym.Name == "" {
+			continue
+		}
+		sect := machoFile.Sections[sym.Sect-1]
+		if sect.Flags&(machoAttrPureInstructions|machoAttrSomeInstructions) == 0 {
+			continue
+		}
+		symbols = append(symbols, functionSymbol{Function{sym.Name, sym.Value, 0}, sect.Addr + sect.Size})
+	}
+
+	return newFunctionIndex(symbols)
+}
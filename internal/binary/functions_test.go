@@ -0,0 +1,117 @@
+package binary
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+// TestNewFunctionIndex tests sizing of unsized symbols, alias removal and lookups
+func TestNewFunctionIndex(t *testing.T) {
+	index, err := newFunctionIndex([]functionSymbol{
+		{Function{"tail", 0x1080, 0}, 0x1100},
+		{Function{"main", 0x1000, 0x40}, 0x1100},
+		{Function{"helper", 0x1040, 0}, 0x1100},
+		{Function{"helper_alias", 0x1040, 0x10}, 0x1100},
+	})
+	if err != nil {
+		t.Fatalf("newFunctionIndex() error = %v", err)
+	}
+
+	want := []Function{
+		{"main", 0x1000, 0x40},
+		{"helper", 0x1040, 0x40}, // Up to tail
+		{"tail", 0x1080, 0x80},   // Up to the end of the section
+	}
+	if !reflect.DeepEqual(index.functions, want) {
+		t.Errorf("functions = %+v, want %+v", index.functions, want)
+	}
+
+	tests := []struct {
+		addr uint64
+		want string
+	}{
+		{0xfff, ""},
+		{0x1000, "main"},
+		{0x103f, "main"},
+		{0x1040, "helper"},
+		{0x10ff, "tail"},
+		{0x1100, ""},
+	}
+	for _, tt := range tests {
+		got, ok := index.Lookup(tt.addr)
+		if got != tt.want || ok != (tt.want != "") {
+			t.Errorf("Lookup(0x%x) = %q, %v, want %q", tt.addr, got, ok, tt.want)
+		}
+	}
+}
+
+// TestNewFunctionIndexGap tests that addresses between sized functions are not attributed
+func TestNewFunctionIndexGap(t *testing.T) {
+	index, err := newFunctionIndex([]functionSymbol{
+		{Function{"a", 0x100, 0x10}, 0x1000},
+		{Function{"b", 0x200, 0x10}, 0x1000},
+	})
+	if err != nil {
+		t.Fatalf("newFunctionIndex() error = %v", err)
+	}
+	if name, ok := index.Lookup(0x180); ok {
+		t.Errorf("Lookup(0x180) = %q, want no function", name)
+	}
+}
+
+// TestNewFunctionIndexEmpty tests that stripped binaries are reported as errors
+func TestNewFunctionIndexEmpty(t *testing.T) {
+	if _, err := newFunctionIndex(nil); err == nil {
+		t.Error("newFunctionIndex(nil) expected error, got nil")
+	}
+}
+
+// TestFunctionIndexNilLookup tests that a nil index is safe to query
+func TestFunctionIndexNilLookup(t *testing.T) {
+	var index *FunctionIndex
+	if name, ok := index.Lookup(0x1000); ok {
+		t.Errorf("nil FunctionIndex.Lookup() = %q, want no function", name)
+	}
+}
+
+// TestLoadFunctionsCurrentBinary tests loading the symbols of the running test binary
+func TestLoadFunctionsCurrentBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("skipping test: cannot locate test binary: %v", err)
+	}
+	format, err := DetectFormat(exe)
+	if err != nil || format == FormatRaw {
+		t.Skip("skipping test: test binary format not supported")
+	}
+
+	index, err := LoadFunctions(exe, format)
+	if err != nil {
+		t.Skipf("skipping test: test binary has no symbols: %v", err)
+	}
+	if index.Len() == 0 {
+		t.Fatal("LoadFunctions() returned an empty index")
+	}
+
+	// Every function contains its own start address
+	for _, fn := range index.functions[:min(index.Len(), 100)] {
+		if fn.Size == 0 {
+			continue
+		}
+		if name, ok := index.Lookup(fn.Addr); !ok || name != fn.Name {
+			t.Errorf("Lookup(0x%x) = %q, %v, want %q", fn.Addr, name, ok, fn.Name)
+		}
+	}
+}
+
+// TestLoadFunctionsRawFormat tests that raw binaries are rejected
+func TestLoadFunctionsRawFormat(t *testing.T) {
+	path := t.TempDir() + "/raw.bin"
+	if err := os.WriteFile(path, []byte("not a binary"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+	if _, err := LoadFunctions(path, FormatRaw); err == nil {
+		t.Error("LoadFunctions() with FormatRaw expected error, got nil")
+	}
+}
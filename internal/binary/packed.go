@@ -0,0 +1,311 @@
+package binary
+
+import (
+	"bytes"
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+// PackedEntropyThreshold is the Shannon entropy (bits per byte) above which
+// content is considered compressed or encrypted. Machine code and data are
+// typically between 4.5 and 6.5; compressed data approaches 8.
+const PackedEntropyThreshold = 7.2
+
+// minEntropySectionSize is the smallest section whose entropy is used to flag
+// a binary as packed (tiny sections give meaningless values)
+const minEntropySectionSize = 512
+
+// upxMagic is the signature UPX writes into the headers of packed files
+var upxMagic = []byte("UPX!")
+
+// upxProbeSize is the number of bytes at the start and end of a file that are
+// searched for the UPX signature
+const upxProbeSize = 4096
+
+// SectionEntropy is the entropy of one loaded section (or segment) of a binary
+type SectionEntropy struct {
+	Name       string
+	Offset     int64
+	Size       int64
+	Entropy    float64
+	Executable bool
+}
+
+// PackInfo describes whether a binary appears packed or encrypted
+type PackInfo struct {
+	Packed   bool
+	Packer   string  // Name of the detected packer (e.g. "UPX"), if known
+	Reason   string  // Why the binary was flagged
+	Entropy  float64 // Entropy of all loaded sections combined
+	Sections []SectionEntropy
+}
+
+// packRegion is a loaded region of a binary file
+type packRegion struct {
+	name   string
+	offset int64
+	size   int64
+	exec   bool
+}
+
+// AnalyzePacking computes the entropy of the loaded sections of a binary and
+// reports whether it appears packed or encrypted. UPX is recognized by its
+// signature and section names; other packers are flagged by high entropy.
+func AnalyzePacking(path string, format Format) (PackInfo, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return PackInfo{}, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var regions []packRegion
+	switch format {
+	case FormatELF:
+		regions, err = elfRegions(file)
+	case FormatPE:
+		regions, err = peRegions(file)
+	case FormatMachO:
+		regions, err = machoRegions(file)
+	default:
+		return PackInfo{}, fmt.Errorf("unsupported format: %v", format)
+	}
+	if err != nil {
+		return PackInfo{}, err
+	}
+
+	info := PackInfo{}
+	var total [256]int64
+	for _, region := range regions {
+		var counts [256]int64
+		if err := countBytes(io.NewSectionReader(file, region.offset, region.size), &counts); err != nil {
+			return PackInfo{}, fmt.Errorf("reading %s: %w", region.name, err)
+		}
+		for i, c := range counts {
+			total[i] += c
+		}
+		info.Sections = append(info.Sections, SectionEntropy{
+			Name:       region.name,
+			Offset:     region.offset,
+			Size:       region.size,
+			Entropy:    histogramEntropy(&counts),
+			Executable: region.exec,
+		})
+	}
+	info.Entropy = histogramEntropy(&total)
+
+	// UPX: signature in the headers or trailer, or its section names
+	upx, err := hasUPXSignature(file)
+	if err != nil {
+		return PackInfo{}, err
+	}
+	for _, region := range regions {
+		if strings.HasPrefix(region.name, "UPX") {
+			upx = true
+		}
+	}
+	if upx {
+		info.Packed, info.Packer, info.Reason = true, "UPX", "UPX signature"
+		return info, nil
+	}
+
+	for _, section := range info.Sections {
+		if section.Executable && section.Size >= minEntropySectionSize && section.Entropy >= PackedEntropyThreshold {
+			info.Packed = true
+			info.Reason = fmt.Sprintf("high entropy executable section %s (%.2f)", section.Name, section.Entropy)
+			return info, nil
+		}
+	}
+	if info.Entropy >= PackedEntropyThreshold {
+		info.Packed = true
+		info.Reason = fmt.Sprintf("high overall entropy (%.2f)", info.Entropy)
+	}
+
+	return info, nil
+}
+
+// Entropy returns the Shannon entropy of data in bits per byte (0 to 8)
+func Entropy(data []byte) float64 {
+	var counts [256]int64
+	for _, b := range data {
+		counts[b]++
+	}
+	return histogramEntropy(&counts)
+}
+
+// histogramEntropy returns the Shannon entropy of a byte histogram
+func histogramEntropy(counts *[256]int64) float64 {
+	var total int64
+	for _, c := range counts {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+
+	entropy := 0.0
+	for _, c := range counts {
+		if c == 0 {
+			continue
+		}
+		p := float64(c) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// countBytes adds the byte histogram of r to counts
+func countBytes(r io.Reader, counts *[256]int64) error {
+	buf := make([]byte, 32*1024)
+	for {
+		n, err := r.Read(buf)
+		for _, b := range buf[:n] {
+			counts[b]++
+		}
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// hasUPXSignature searches the start and end of a file for the UPX signature
+func hasUPXSignature(file *os.File) (bool, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return false, err
+	}
+	size := info.Size()
+
+	probe := func(offset, length int64) (bool, error) {
+		buf := make([]byte, length)
+		n, err := file.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return false, err
+		}
+		return bytes.Contains(buf[:n], upxMagic), nil
+	}
+
+	found, err := probe(0, min(size, upxProbeSize))
+	if err != nil || found || size <= upxProbeSize {
+		return found, err
+	}
+	tail := min(size-upxProbeSize, upxProbeSize)
+	return probe(size-tail, tail)
+}
+
+// elfRegions returns the allocated sections of an ELF file, or its loadable
+// segments if the section headers were stripped (as packers do)
+func elfRegions(file *os.File) ([]packRegion, error) {
+	elfFile, err := elf.NewFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid ELF file: %w", err)
+	}
+
+	var regions []packRegion
+	for _, sect := range elfFile.Sections {
+		// Only sections loaded at run time; debug info may be compressed
+		if sect.Flags&elf.SHF_ALLOC == 0 || sect.Type == elf.SHT_NOBITS || sect.Size == 0 {
+			continue
+		}
+		regions = append(regions, packRegion{
+			name:   sect.Name,
+			offset: int64(sect.Offset),
+			size:   int64(sect.Size),
+			exec:   sect.Flags&elf.SHF_EXECINSTR != 0,
+		})
+	}
+	if len(regions) > 0 {
+		return regions, nil
+	}
+
+	for i, prog := range elfFile.Progs {
+		if prog.Type != elf.PT_LOAD || prog.Filesz == 0 {
+			continue
+		}
+		regions = append(regions, packRegion{
+			name:   fmt.Sprintf("LOAD%d", i),
+			offset: int64(prog.Off),
+			size:   int64(prog.Filesz),
+			exec:   prog.Flags&elf.PF_X != 0,
+		})
+	}
+	return regions, nil
+}
+
+// peRegions returns the sections of a PE file that stay loaded at run time
+func peRegions(file *os.File) ([]packRegion, error) {
+	peFile, err := pe.NewFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid PE file: %w", err)
+	}
+
+	var regions []packRegion
+	for _, sect := range peFile.Sections {
+		// Discardable sections hold relocations and (compressed) debug info
+		if sect.Size == 0 || sect.Characteristics&pe.IMAGE_SCN_MEM_DISCARDABLE != 0 {
+			continue
+		}
+		regions = append(regions, packRegion{
+			name:   sect.Name,
+			offset: int64(sect.Offset),
+			size:   int64(sect.Size),
+			exec:   sect.Characteristics&pe.IMAGE_SCN_MEM_EXECUTE != 0,
+		})
+	}
+	return regions, nil
+}
+
+// machoRegions returns the loaded sections of a Mach-O file (the first
+// architecture of a universal binary)
+func machoRegions(file *os.File) ([]packRegion, error) {
+	var machoFile *macho.File
+	var base int64
+
+	if fatFile, err := macho.NewFatFile(file); err == nil {
+		if len(fatFile.Arches) == 0 {
+			return nil, fmt.Errorf("universal binary has no architectures")
+		}
+		machoFile = fatFile.Arches[0].File
+		base = int64(fatFile.Arches[0].Offset)
+	} else {
+		if _, err := file.Seek(0, 0); err != nil {
+			return nil, fmt.Errorf("failed to seek: %w", err)
+		}
+		machoFile, err = macho.NewFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid Mach-O file: %w", err)
+		}
+	}
+
+	const (
+		sectionTypeMask          = 0xff
+		sectionZerofill          = 0x1
+		sectionPureInstructions  = 0x80000000
+		sectionSomeInstructions  = 0x00000400
+		sectionInstructionsFlags = sectionPureInstructions | sectionSomeInstructions
+	)
+
+	var regions []packRegion
+	for _, sect := range machoFile.Sections {
+		if sect.Seg == "__DWARF" || sect.Flags&sectionTypeMask == sectionZerofill || sect.Offset == 0 || sect.Size == 0 {
+			continue
+		}
+		regions = append(regions, packRegion{
+			name:   sect.Seg + "." + sect.Name,
+			offset: base + int64(sect.Offset),
+			size:   int64(sect.Size),
+			exec:   sect.Flags&sectionInstructionsFlags != 0,
+		})
+	}
+	return regions, nil
+}
@@ -0,0 +1,110 @@
+package binary
+
+import (
+	"crypto/rand"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestEntropy tests Shannon entropy calculation
+func TestEntropy(t *testing.T) {
+	uniform := make([]byte, 256*4)
+	for i := range uniform {
+		uniform[i] = byte(i)
+	}
+
+	tests := []struct {
+		name string
+		data []byte
+		want float64
+	}{
+		{"empty", nil, 0},
+		{"constant", make([]byte, 100), 0},
+		{"two symbols", []byte("aaaabbbb"), 1},
+		{"uniform", uniform, 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Entropy(tt.data); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Entropy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestAnalyzePacking tests packing detection on the test binary and on
+// modified copies of it
+func TestAnalyzePacking(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip("skipping test: test executable not found")
+	}
+	format, err := DetectFormat(exe)
+	if err != nil || format == FormatRaw {
+		t.Skip("skipping test: test executable format not detected")
+	}
+	original, err := os.ReadFile(exe)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := AnalyzePacking(exe, format)
+	if err != nil {
+		t.Fatalf("AnalyzePacking() error = %v", err)
+	}
+	if info.Packed {
+		t.Errorf("AnalyzePacking(test binary) packed = true (%s), want false", info.Reason)
+	}
+	if len(info.Sections) == 0 || info.Entropy <= 0 || info.Entropy >= PackedEntropyThreshold {
+		t.Errorf("AnalyzePacking(test binary) entropy = %.2f over %d sections", info.Entropy, len(info.Sections))
+	}
+
+	dir := t.TempDir()
+	write := func(name string, data []byte) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+
+	// Encrypted code: overwrite the largest executable section with random bytes
+	var text *SectionEntropy
+	for i, section := range info.Sections {
+		if section.Executable && (text == nil || section.Size > text.Size) {
+			text = &info.Sections[i]
+		}
+	}
+	if text == nil {
+		t.Fatal("no executable section found")
+	}
+	encrypted := append([]byte(nil), original...)
+	if _, err := rand.Read(encrypted[text.Offset : text.Offset+text.Size]); err != nil {
+		t.Fatal(err)
+	}
+	info, err = AnalyzePacking(write("encrypted", encrypted), format)
+	if err != nil {
+		t.Fatalf("AnalyzePacking(encrypted) error = %v", err)
+	}
+	if !info.Packed || info.Packer != "" || !strings.Contains(info.Reason, text.Name) {
+		t.Errorf("AnalyzePacking(encrypted) = packed %v, packer %q, reason %q", info.Packed, info.Packer, info.Reason)
+	}
+
+	// UPX leaves its signature in the file trailer
+	upx := append(append([]byte(nil), original...), upxMagic...)
+	info, err = AnalyzePacking(write("upx", upx), format)
+	if err != nil {
+		t.Fatalf("AnalyzePacking(upx) error = %v", err)
+	}
+	if !info.Packed || info.Packer != "UPX" {
+		t.Errorf("AnalyzePacking(upx) = packed %v, packer %q, want UPX", info.Packed, info.Packer)
+	}
+
+	if _, err := AnalyzePacking(write("raw", []byte("not a binary")), FormatELF); err == nil {
+		t.Error("AnalyzePacking(raw as ELF) succeeded, want error")
+	}
+}
@@ -44,11 +44,22 @@ func (f Format) String() string {
 	}
 }
 
+// ParseFormat returns the format String names, or FormatUnknown
+func ParseFormat(name string) Format {
+	for _, format := range []Format{FormatELF, FormatPE, FormatMachO, FormatRaw} {
+		if format.String() == name {
+			return format
+		}
+	}
+	return FormatUnknown
+}
+
 // Section represents a section in a binary file
 type Section struct {
 	Name   string
 	Offset int64
 	Size   int64
+	Addr   uint64 // Virtual address the section is loaded at
 	Data   []byte
 }
 
@@ -104,8 +115,48 @@ func DetectFormat(path string) (Format, error) {
 	return FormatRaw, nil
 }
 
+// FormatSource describes how the format of a file was decided
+type FormatSource string
+
+const (
+	// SourceDetected indicates the format was detected from the file contents
+	SourceDetected FormatSource = "detected"
+	// SourceHint indicates the file was not recognized and the hint was used
+	SourceHint FormatSource = "hint"
+	// SourceRecorded indicates the format was taken from the results of a
+	// previous scan (--rescan)
+	SourceRecorded FormatSource = "recorded"
+)
+
+// ResolveFormat detects the format of a file. The hint is only used for files
+// that are not recognized as an executable (detected as raw); a detected
+// format always takes precedence, so a single hint can be applied to a mix of
+// files. A hint of FormatUnknown or FormatRaw means no hint.
+func ResolveFormat(path string, hint Format) (Format, FormatSource, error) {
+	format, err := DetectFormat(path)
+	if err != nil {
+		return FormatUnknown, "", err
+	}
+	if format == FormatRaw && hint != FormatUnknown && hint != FormatRaw {
+		return hint, SourceHint, nil
+	}
+	return format, SourceDetected, nil
+}
+
+// elfDataSectionNames lists the ELF data sections to extract
+var elfDataSectionNames = []string{
+	".data",        // Initialized data
+	".rodata",      // Read-only data
+	".data.rel.ro", // Read-only after relocation
+}
+
 // ParseELF extracts data sections from an ELF file
 func ParseELF(path string) ([]Section, error) {
+	return parseELF(path, true)
+}
+
+// parseELF locates data sections in an ELF file, loading their contents if withData is set
+func parseELF(path string, withData bool) ([]Section, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -124,28 +175,25 @@ func ParseELF(path string) ([]Section, error) {
 
 	var sections []Section
 
-	// Data section names to extract
-	dataSectionNames := []string{
-		".data",        // Initialized data
-		".rodata",      // Read-only data
-		".data.rel.ro", // Read-only after relocation
-	}
-
-	for _, name := range dataSectionNames {
+	for _, name := range elfDataSectionNames {
 		sect := elfFile.Section(name)
 		if sect == nil {
 			continue
 		}
 
-		data, err := sect.Data()
-		if err != nil {
-			continue // Skip sections we can't read
+		var data []byte
+		if withData {
+			data, err = sect.Data()
+			if err != nil {
+				continue // Skip sections we can't read
+			}
 		}
 
 		sections = append(sections, Section{
 			Name:   sect.Name,
 			Offset: int64(sect.Offset),
 			Size:   int64(sect.Size),
+			Addr:   sect.Addr,
 			Data:   data,
 		})
 	}
@@ -153,8 +201,19 @@ func ParseELF(path string) ([]Section, error) {
 	return sections, nil
 }
 
+// peDataSections lists the PE data sections to extract
+var peDataSections = map[string]bool{
+	".data":  true, // Initialized data
+	".rdata": true, // Read-only data
+}
+
 // ParsePE extracts data sections from a PE file
 func ParsePE(path string) ([]Section, error) {
+	return parsePE(path, true)
+}
+
+// parsePE locates data sections in a PE file, loading their contents if withData is set
+func parsePE(path string, withData bool) ([]Section, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -171,21 +230,27 @@ func ParsePE(path string) ([]Section, error) {
 		_ = peFile.Close()
 	}()
 
+	imageBase, _ := peImageInfo(peFile)
+
 	var sections []Section
 
 	// Look for data sections
 	for _, sect := range peFile.Sections {
 		// Include .data and .rdata (read-only data) sections
-		if sect.Name == ".data" || sect.Name == ".rdata" {
-			data, err := sect.Data()
-			if err != nil {
-				continue
+		if peDataSections[sect.Name] {
+			var data []byte
+			if withData {
+				data, err = sect.Data()
+				if err != nil {
+					continue
+				}
 			}
 
 			sections = append(sections, Section{
 				Name:   sect.Name,
 				Offset: int64(sect.Offset),
 				Size:   int64(sect.Size),
+				Addr:   imageBase + uint64(sect.VirtualAddress),
 				Data:   data,
 			})
 		}
@@ -194,8 +259,33 @@ func ParsePE(path string) ([]Section, error) {
 	return sections, nil
 }
 
+// peImageInfo returns the image base and pointer size of a PE file
+func peImageInfo(peFile *pe.File) (uint64, int) {
+	switch oh := peFile.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		return uint64(oh.ImageBase), 4
+	case *pe.OptionalHeader64:
+		return oh.ImageBase, 8
+	default:
+		return 0, 4
+	}
+}
+
+// machoDataSections lists the Mach-O data sections (Segment.Section) to extract
+var machoDataSections = map[string]bool{
+	"__DATA.__data":    true, // Initialized data
+	"__DATA.__const":   true, // Constant data
+	"__TEXT.__cstring": true, // C strings
+	"__TEXT.__const":   true, // Constants in text
+}
+
 // ParseMachO extracts data sections from a Mach-O file
 func ParseMachO(path string) ([]Section, error) {
+	return parseMachO(path, true)
+}
+
+// parseMachO locates data sections in a Mach-O file, loading their contents if withData is set
+func parseMachO(path string, withData bool) ([]Section, error) {
 	file, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -204,14 +294,6 @@ func ParseMachO(path string) ([]Section, error) {
 		_ = file.Close()
 	}()
 
-	// Data section patterns to extract
-	dataPatterns := map[string]bool{
-		"__DATA.__data":    true, // Initialized data
-		"__DATA.__const":   true, // Constant data
-		"__TEXT.__cstring": true, // C strings
-		"__TEXT.__const":   true, // Constants in text
-	}
-
 	// Helper function to extract sections from a Mach-O file
 	extractSections := func(machoFile *macho.File) []Section {
 		var sections []Section
@@ -219,16 +301,21 @@ func ParseMachO(path string) ([]Section, error) {
 			// Construct full section name (Segment.Section)
 			fullName := sect.Seg + "." + sect.Name
 
-			if dataPatterns[fullName] {
-				data, err := sect.Data()
-				if err != nil {
-					continue
+			if machoDataSections[fullName] {
+				var data []byte
+				if withData {
+					var err error
+					data, err = sect.Data()
+					if err != nil {
+						continue
+					}
 				}
 
 				sections = append(sections, Section{
 					Name:   fullName,
 					Offset: int64(sect.Offset),
 					Size:   int64(sect.Size),
+					Addr:   sect.Addr,
 					Data:   data,
 				})
 			}
@@ -268,6 +355,24 @@ func ParseMachO(path string) ([]Section, error) {
 	return extractSections(machoFile), nil
 }
 
+// ParseSectionHeaders locates the data sections of a binary file like
+// ParseBinary, but without loading their contents (Data is nil). Callers can
+// stream large sections from the file using Offset and Size instead.
+func ParseSectionHeaders(path string, format Format) ([]Section, error) {
+	switch format {
+	case FormatELF:
+		return parseELF(path, false)
+	case FormatPE:
+		return parsePE(path, false)
+	case FormatMachO:
+		return parseMachO(path, false)
+	case FormatRaw, FormatUnknown:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unsupported format: %d", format)
+	}
+}
+
 // ParseBinary parses a binary file based on the specified format
 func ParseBinary(path string, format Format) ([]Section, error) {
 	switch format {
@@ -90,3 +90,51 @@ func TestFormatString(t *testing.T) {
 		})
 	}
 }
+
+// TestResolveFormat tests that the format hint only applies to unrecognized files
+func TestResolveFormat(t *testing.T) {
+	raw := t.TempDir() + "/raw.bin"
+	if err := os.WriteFile(raw, []byte("just some data, not an executable"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip("skipping test: test executable not found")
+	}
+	detected, err := DetectFormat(exe)
+	if err != nil || detected == FormatRaw {
+		t.Skip("skipping test: test executable format not detected")
+	}
+
+	tests := []struct {
+		name       string
+		path       string
+		hint       Format
+		wantFormat Format
+		wantSource FormatSource
+	}{
+		{"raw without hint", raw, FormatUnknown, FormatRaw, SourceDetected},
+		{"raw with raw hint", raw, FormatRaw, FormatRaw, SourceDetected},
+		{"raw with PE hint", raw, FormatPE, FormatPE, SourceHint},
+		{"executable without hint", exe, FormatUnknown, detected, SourceDetected},
+		{"executable ignores PE hint", exe, FormatPE, detected, SourceDetected},
+		{"executable ignores Mach-O hint", exe, FormatMachO, detected, SourceDetected},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			format, source, err := ResolveFormat(tt.path, tt.hint)
+			if err != nil {
+				t.Fatalf("ResolveFormat() error = %v", err)
+			}
+			if format != tt.wantFormat || source != tt.wantSource {
+				t.Errorf("ResolveFormat() = %v, %v, want %v, %v", format, source, tt.wantFormat, tt.wantSource)
+			}
+		})
+	}
+
+	if _, _, err := ResolveFormat(t.TempDir()+"/missing", FormatELF); err == nil {
+		t.Error("ResolveFormat(missing) succeeded, want error")
+	}
+}
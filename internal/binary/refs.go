@@ -0,0 +1,336 @@
+package binary
+
+import (
+	"debug/elf"
+	"debug/macho"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// Mach-O section attributes marking sections that contain machine instructions
+const (
+	machoAttrPureInstructions = 0x80000000 // S_ATTR_PURE_INSTRUCTIONS
+	machoAttrSomeInstructions = 0x00000400 // S_ATTR_SOME_INSTRUCTIONS
+)
+
+// ReferenceIndex maps strings in a binary's data sections to the code
+// addresses that reference them.
+type ReferenceIndex struct {
+	sections []Section           // Data sections that may contain referenced strings
+	refs     map[uint64][]uint64 // Target virtual address -> referencing code addresses
+}
+
+// Lookup returns the code addresses that reference the string starting at the
+// given file offset, or nil if the string is not referenced.
+func (ri *ReferenceIndex) Lookup(offset int64) []uint64 {
+	if ri == nil {
+		return nil
+	}
+
+	for _, sect := range ri.sections {
+		if offset >= sect.Offset && offset < sect.Offset+sect.Size {
+			return ri.refs[sect.Addr+uint64(offset-sect.Offset)]
+		}
+	}
+
+	return nil
+}
+
+// Count returns the number of references to the string starting at the given
+// file offset.
+func (ri *ReferenceIndex) Count(offset int64) int {
+	return len(ri.Lookup(offset))
+}
+
+// ResolveLiteralPools scans the executable sections of an ARM ELF binary for
+// literal pool entries that point into its data sections.
+//
+// ARM and Thumb code load string addresses from word-aligned literal pools
+// placed after each function, so every aligned word in a code section whose
+// value falls inside a data section is treated as a reference from that
+// code address.
+func ResolveLiteralPools(path string) (*ReferenceIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	elfFile, err := elf.NewFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid ELF file: %w", err)
+	}
+	defer func() {
+		_ = elfFile.Close()
+	}()
+
+	if elfFile.Machine != elf.EM_ARM {
+		return nil, fmt.Errorf("literal pool resolution requires an ARM binary, got %v", elfFile.Machine)
+	}
+
+	index := &ReferenceIndex{refs: make(map[uint64][]uint64)}
+
+	for _, name := range elfDataSectionNames {
+		sect := elfFile.Section(name)
+		if sect == nil || sect.Type == elf.SHT_NOBITS {
+			continue
+		}
+
+		index.sections = append(index.sections, Section{
+			Name:   sect.Name,
+			Offset: int64(sect.Offset),
+			Size:   int64(sect.Size),
+			Addr:   sect.Addr,
+		})
+	}
+
+	for _, sect := range elfFile.Sections {
+		if sect.Type != elf.SHT_PROGBITS || sect.Flags&elf.SHF_EXECINSTR == 0 {
+			continue
+		}
+
+		code, err := sect.Data()
+		if err != nil {
+			continue // Skip sections we can't read
+		}
+
+		// Literal pool entries are always word-aligned 32-bit values
+		index.scanPointers(Section{Name: sect.Name, Addr: sect.Addr, Data: code}, 4, 4, elfFile.ByteOrder)
+	}
+
+	return index, nil
+}
+
+// ScanPointerRefs builds a reference index by scanning every loaded section of
+// an executable for pointer-width values equal to addresses in its data sections.
+//
+// Data sections are scanned at pointer alignment, while executable sections are
+// scanned at every byte because absolute addresses embedded in instructions
+// (e.g. x86 immediates) are not aligned. References from a data section to
+// itself are ignored.
+func ScanPointerRefs(path string, format Format) (*ReferenceIndex, error) {
+	switch format {
+	case FormatELF:
+		return scanELFPointerRefs(path)
+	case FormatPE:
+		return scanPEPointerRefs(path)
+	case FormatMachO:
+		return scanMachOPointerRefs(path)
+	default:
+		return nil, fmt.Errorf("cross-references require an ELF, PE or Mach-O binary, got %v", format)
+	}
+}
+
+// scanELFPointerRefs builds a pointer reference index for an ELF file
+func scanELFPointerRefs(path string) (*ReferenceIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	elfFile, err := elf.NewFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid ELF file: %w", err)
+	}
+	defer func() {
+		_ = elfFile.Close()
+	}()
+
+	ptrSize := 4
+	if elfFile.Class == elf.ELFCLASS64 {
+		ptrSize = 8
+	}
+
+	index := &ReferenceIndex{refs: make(map[uint64][]uint64)}
+
+	for _, name := range elfDataSectionNames {
+		sect := elfFile.Section(name)
+		if sect == nil || sect.Type == elf.SHT_NOBITS {
+			continue
+		}
+
+		index.sections = append(index.sections, Section{
+			Name:   sect.Name,
+			Offset: int64(sect.Offset),
+			Size:   int64(sect.Size),
+			Addr:   sect.Addr,
+		})
+	}
+
+	for _, sect := range elfFile.Sections {
+		if sect.Type == elf.SHT_NOBITS || sect.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+
+		data, err := sect.Data()
+		if err != nil {
+			continue // Skip sections we can't read
+		}
+
+		step := ptrSize
+		if sect.Flags&elf.SHF_EXECINSTR != 0 {
+			step = 1
+		}
+		index.scanPointers(Section{Name: sect.Name, Addr: sect.Addr, Data: data}, ptrSize, step, elfFile.ByteOrder)
+	}
+
+	return index, nil
+}
+
+// scanPEPointerRefs builds a pointer reference index for a PE file
+func scanPEPointerRefs(path string) (*ReferenceIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	peFile, err := pe.NewFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid PE file: %w", err)
+	}
+	defer func() {
+		_ = peFile.Close()
+	}()
+
+	imageBase, ptrSize := peImageInfo(peFile)
+
+	index := &ReferenceIndex{refs: make(map[uint64][]uint64)}
+
+	for _, sect := range peFile.Sections {
+		if peDataSections[sect.Name] {
+			index.sections = append(index.sections, Section{
+				Name:   sect.Name,
+				Offset: int64(sect.Offset),
+				Size:   int64(sect.Size),
+				Addr:   imageBase + uint64(sect.VirtualAddress),
+			})
+		}
+	}
+
+	for _, sect := range peFile.Sections {
+		data, err := sect.Data()
+		if err != nil {
+			continue
+		}
+
+		step := ptrSize
+		if sect.Characteristics&pe.IMAGE_SCN_MEM_EXECUTE != 0 {
+			step = 1
+		}
+		index.scanPointers(Section{Name: sect.Name, Addr: imageBase + uint64(sect.VirtualAddress), Data: data}, ptrSize, step, binary.LittleEndian)
+	}
+
+	return index, nil
+}
+
+// scanMachOPointerRefs builds a pointer reference index for a Mach-O file
+// (the first architecture of a universal binary)
+func scanMachOPointerRefs(path string) (*ReferenceIndex, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var machoFile *macho.File
+	if fatFile, err := macho.NewFatFile(file); err == nil {
+		defer func() {
+			_ = fatFile.Close()
+		}()
+		if len(fatFile.Arches) == 0 {
+			return nil, fmt.Errorf("universal binary has no architectures")
+		}
+		machoFile = fatFile.Arches[0].File
+	} else {
+		if _, err := file.Seek(0, 0); err != nil {
+			return nil, fmt.Errorf("failed to seek: %w", err)
+		}
+		machoFile, err = macho.NewFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("not a valid Mach-O file: %w", err)
+		}
+		defer func() {
+			_ = machoFile.Close()
+		}()
+	}
+
+	ptrSize := 4
+	if machoFile.Magic == macho.Magic64 {
+		ptrSize = 8
+	}
+
+	index := &ReferenceIndex{refs: make(map[uint64][]uint64)}
+
+	for _, sect := range machoFile.Sections {
+		fullName := sect.Seg + "." + sect.Name
+		if machoDataSections[fullName] {
+			index.sections = append(index.sections, Section{
+				Name:   fullName,
+				Offset: int64(sect.Offset),
+				Size:   int64(sect.Size),
+				Addr:   sect.Addr,
+			})
+		}
+	}
+
+	for _, sect := range machoFile.Sections {
+		// Zero-fill sections have no file contents
+		if sect.Offset == 0 {
+			continue
+		}
+
+		data, err := sect.Data()
+		if err != nil {
+			continue
+		}
+
+		step := ptrSize
+		if sect.Flags&(machoAttrPureInstructions|machoAttrSomeInstructions) != 0 {
+			step = 1
+		}
+		index.scanPointers(Section{Name: sect.Seg + "." + sect.Name, Addr: sect.Addr, Data: data}, ptrSize, step, machoFile.ByteOrder)
+	}
+
+	return index, nil
+}
+
+// scanPointers records every ptrSize-wide value in src, sampled every step bytes
+// from the first ptrSize boundary, that points into one of the index's data
+// sections other than src itself
+func (ri *ReferenceIndex) scanPointers(src Section, ptrSize, step int, byteOrder binary.ByteOrder) {
+	// Align to the first pointer boundary within the section
+	start := 0
+	if step > 1 {
+		start = int((uint64(step) - src.Addr%uint64(step)) % uint64(step))
+	}
+
+	for i := start; i+ptrSize <= len(src.Data); i += step {
+		var target uint64
+		if ptrSize == 8 {
+			target = byteOrder.Uint64(src.Data[i : i+8])
+		} else {
+			target = uint64(byteOrder.Uint32(src.Data[i : i+4]))
+		}
+
+		for _, sect := range ri.sections {
+			if target >= sect.Addr && target < sect.Addr+uint64(sect.Size) {
+				if sect.Name != src.Name {
+					ri.refs[target] = append(ri.refs[target], src.Addr+uint64(i))
+				}
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package binary
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// TestScanPointersLiteralPool tests that aligned words pointing into data sections are recorded
+func TestScanPointersLiteralPool(t *testing.T) {
+	index := &ReferenceIndex{
+		sections: []Section{
+			{Name: ".rodata", Offset: 0x1000, Size: 0x100, Addr: 0x9000},
+		},
+		refs: make(map[uint64][]uint64),
+	}
+
+	// Code at 0x8000: an instruction, a literal pool entry pointing at
+	// .rodata+0x10, an unrelated constant and a second reference to the same string
+	code := make([]byte, 16)
+	binary.LittleEndian.PutUint32(code[0:], 0xe59f0004)
+	binary.LittleEndian.PutUint32(code[4:], 0x9010)
+	binary.LittleEndian.PutUint32(code[8:], 0x12345678)
+	binary.LittleEndian.PutUint32(code[12:], 0x9010)
+
+	index.scanPointers(Section{Name: ".text", Addr: 0x8000, Data: code}, 4, 4, binary.LittleEndian)
+
+	got := index.Lookup(0x1010)
+	want := []uint64{0x8004, 0x800c}
+	if len(got) != len(want) {
+		t.Fatalf("Lookup(0x1010) = %x, want %x", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Lookup(0x1010)[%d] = 0x%x, want 0x%x", i, got[i], want[i])
+		}
+	}
+
+	if refs := index.Lookup(0x1020); refs != nil {
+		t.Errorf("Lookup(0x1020) = %x, want nil", refs)
+	}
+	if refs := index.Lookup(0x50); refs != nil {
+		t.Errorf("Lookup(0x50) outside data sections = %x, want nil", refs)
+	}
+}
+
+// TestScanPointersUnalignedSection tests that scanning starts at the first word boundary
+func TestScanPointersUnalignedSection(t *testing.T) {
+	index := &ReferenceIndex{
+		sections: []Section{
+			{Name: ".rodata", Offset: 0x200, Size: 0x10, Addr: 0x4000},
+		},
+		refs: make(map[uint64][]uint64),
+	}
+
+	// Section starts at 0x1002, so the first aligned word is at byte 2
+	code := make([]byte, 6)
+	binary.BigEndian.PutUint32(code[2:], 0x4004)
+
+	index.scanPointers(Section{Name: ".text", Addr: 0x1002, Data: code}, 4, 4, binary.BigEndian)
+
+	refs := index.Lookup(0x204)
+	if len(refs) != 1 || refs[0] != 0x1004 {
+		t.Errorf("Lookup(0x204) = %x, want [1004]", refs)
+	}
+}
+
+// TestScanPointers64BitUnaligned tests byte-granular scanning for 64-bit pointers in code
+func TestScanPointers64BitUnaligned(t *testing.T) {
+	index := &ReferenceIndex{
+		sections: []Section{
+			{Name: ".rodata", Offset: 0x2000, Size: 0x100, Addr: 0x402000},
+		},
+		refs: make(map[uint64][]uint64),
+	}
+
+	// movabs-style immediate at an odd offset
+	code := make([]byte, 16)
+	code[0] = 0x48
+	code[1] = 0xb8
+	binary.LittleEndian.PutUint64(code[2:], 0x402040)
+
+	index.scanPointers(Section{Name: ".text", Addr: 0x401000, Data: code}, 8, 1, binary.LittleEndian)
+
+	if got := index.Count(0x2040); got != 1 {
+		t.Errorf("Count(0x2040) = %d, want 1", got)
+	}
+	if refs := index.Lookup(0x2040); len(refs) != 1 || refs[0] != 0x401002 {
+		t.Errorf("Lookup(0x2040) = %x, want [401002]", refs)
+	}
+}
+
+// TestScanPointersSkipsSelfReferences tests that a section's pointers to itself are ignored
+func TestScanPointersSkipsSelfReferences(t *testing.T) {
+	index := &ReferenceIndex{
+		sections: []Section{
+			{Name: ".rodata", Offset: 0x100, Size: 0x10, Addr: 0x1000},
+			{Name: ".data", Offset: 0x200, Size: 0x10, Addr: 0x2000},
+		},
+		refs: make(map[uint64][]uint64),
+	}
+
+	rodata := make([]byte, 8)
+	binary.LittleEndian.PutUint32(rodata[0:], 0x1008) // self reference
+	binary.LittleEndian.PutUint32(rodata[4:], 0x2004) // reference into .data
+
+	index.scanPointers(Section{Name: ".rodata", Addr: 0x1000, Data: rodata}, 4, 4, binary.LittleEndian)
+
+	if got := index.Count(0x108); got != 0 {
+		t.Errorf("Count(0x108) self reference = %d, want 0", got)
+	}
+	if got := index.Count(0x204); got != 1 {
+		t.Errorf("Count(0x204) = %d, want 1", got)
+	}
+}
+
+// TestScanPointerRefsCurrentBinary tests cross-reference scanning on the running test binary
+func TestScanPointerRefsCurrentBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("skipping test: cannot locate test binary: %v", err)
+	}
+	format, err := DetectFormat(exe)
+	if err != nil || format == FormatRaw {
+		t.Skip("skipping test: test binary format not supported")
+	}
+
+	index, err := ScanPointerRefs(exe, format)
+	if err != nil {
+		t.Fatalf("ScanPointerRefs() error = %v", err)
+	}
+	if len(index.refs) == 0 {
+		t.Error("ScanPointerRefs() found no references in test binary")
+	}
+}
+
+// TestScanPointerRefsRawFormat tests that raw binaries are rejected
+func TestScanPointerRefsRawFormat(t *testing.T) {
+	if _, err := ScanPointerRefs("unused", FormatRaw); err == nil {
+		t.Error("ScanPointerRefs() with FormatRaw expected error, got nil")
+	}
+}
+
+// TestResolveLiteralPoolsNonARM tests that non-ARM binaries are rejected
+func TestResolveLiteralPoolsNonARM(t *testing.T) {
+	if runtime.GOARCH == "arm" {
+		t.Skip("skipping test: test binary is ARM")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("skipping test: cannot locate test binary: %v", err)
+	}
+	if format, err := DetectFormat(exe); err != nil || format != FormatELF {
+		t.Skip("skipping test: test binary is not ELF")
+	}
+
+	if _, err := ResolveLiteralPools(exe); err == nil {
+		t.Error("ResolveLiteralPools() on non-ARM binary expected error, got nil")
+	}
+}
+
+// TestResolveLiteralPoolsInvalidFile tests that non-ELF input is rejected
+func TestResolveLiteralPoolsInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raw.bin")
+	if err := os.WriteFile(path, []byte("not an elf file"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	if _, err := ResolveLiteralPools(path); err == nil {
+		t.Error("ResolveLiteralPools() on raw file expected error, got nil")
+	}
+}
+
+// TestReferenceIndexNilLookup tests that a nil index is safe to query
+func TestReferenceIndexNilLookup(t *testing.T) {
+	var index *ReferenceIndex
+	if refs := index.Lookup(0); refs != nil {
+		t.Errorf("nil ReferenceIndex.Lookup() = %x, want nil", refs)
+	}
+}
@@ -0,0 +1,329 @@
+package binary
+
+import (
+	"debug/elf"
+	"debug/pe"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// PE base relocation types that patch absolute pointers
+const (
+	peRelBasedHighLow = 3  // IMAGE_REL_BASED_HIGHLOW (32-bit pointer)
+	peRelBasedDir64   = 10 // IMAGE_REL_BASED_DIR64 (64-bit pointer)
+)
+
+// peBaseRelocDirectory is the data directory index of the base relocation table
+const peBaseRelocDirectory = 5
+
+// RelocationTargets returns the sorted file offsets of data section locations
+// that relocation entries point at.
+//
+// Relocated pointers into data sections are almost always string table or
+// constant references, so the pointed-to locations are high-confidence string
+// starts even when surrounded by binary data. ELF dynamic relocations (REL and
+// RELA) and the PE base relocation table are supported.
+func RelocationTargets(path string, format Format) ([]int64, error) {
+	switch format {
+	case FormatELF:
+		return elfRelocationTargets(path)
+	case FormatPE:
+		return peRelocationTargets(path)
+	default:
+		return nil, fmt.Errorf("relocation scanning requires an ELF or PE binary, got %v", format)
+	}
+}
+
+// elfRelocationTargets collects relocation targets from the loaded REL/RELA
+// sections of an ELF file
+func elfRelocationTargets(path string) ([]int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	elfFile, err := elf.NewFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid ELF file: %w", err)
+	}
+	defer func() {
+		_ = elfFile.Close()
+	}()
+
+	is64 := elfFile.Class == elf.ELFCLASS64
+	order := elfFile.ByteOrder
+
+	var dataSections []Section
+	for _, name := range elfDataSectionNames {
+		sect := elfFile.Section(name)
+		if sect == nil || sect.Type == elf.SHT_NOBITS {
+			continue
+		}
+		dataSections = append(dataSections, Section{
+			Name:   sect.Name,
+			Offset: int64(sect.Offset),
+			Size:   int64(sect.Size),
+			Addr:   sect.Addr,
+		})
+	}
+
+	// readPointer reads the implicit addend stored at a virtual address (REL entries)
+	readPointer := func(addr uint64) (uint64, bool) {
+		for _, sect := range elfFile.Sections {
+			if sect.Type == elf.SHT_NOBITS || sect.Flags&elf.SHF_ALLOC == 0 {
+				continue
+			}
+			if addr < sect.Addr || addr >= sect.Addr+sect.Size {
+				continue
+			}
+			var buf [8]byte
+			size := 4
+			if is64 {
+				size = 8
+			}
+			if _, err := sect.ReadAt(buf[:size], int64(addr-sect.Addr)); err != nil {
+				return 0, false
+			}
+			if is64 {
+				return order.Uint64(buf[:]), true
+			}
+			return uint64(order.Uint32(buf[:])), true
+		}
+		return 0, false
+	}
+
+	// Symbol tables are loaded on demand (index 0 is the null symbol and is
+	// omitted by debug/elf)
+	var symbols, dynSymbols []elf.Symbol
+	symbolValue := func(link uint32, index uint64) uint64 {
+		if index == 0 || int(link) >= len(elfFile.Sections) {
+			return 0
+		}
+		var syms []elf.Symbol
+		if elfFile.Sections[link].Type == elf.SHT_DYNSYM {
+			if dynSymbols == nil {
+				dynSymbols, _ = elfFile.DynamicSymbols()
+			}
+			syms = dynSymbols
+		} else {
+			if symbols == nil {
+				symbols, _ = elfFile.Symbols()
+			}
+			syms = symbols
+		}
+		if index-1 >= uint64(len(syms)) {
+			return 0
+		}
+		return syms[index-1].Value
+	}
+
+	var targets []int64
+	for _, sect := range elfFile.Sections {
+		if sect.Type != elf.SHT_RELA && sect.Type != elf.SHT_REL {
+			continue
+		}
+		// Only dynamic relocations carry virtual addresses
+		if sect.Flags&elf.SHF_ALLOC == 0 {
+			continue
+		}
+
+		data, err := sect.Data()
+		if err != nil {
+			continue // Skip sections we can't read
+		}
+
+		rela := sect.Type == elf.SHT_RELA
+		entrySize := relocEntrySize(is64, rela)
+
+		for i := 0; i+entrySize <= len(data); i += entrySize {
+			entry := data[i : i+entrySize]
+
+			var offset, info, addend uint64
+			var symIndex uint64
+			if is64 {
+				offset = order.Uint64(entry[0:8])
+				info = order.Uint64(entry[8:16])
+				symIndex = info >> 32
+				if rela {
+					addend = order.Uint64(entry[16:24])
+				}
+			} else {
+				offset = uint64(order.Uint32(entry[0:4]))
+				info = uint64(order.Uint32(entry[4:8]))
+				symIndex = info >> 8
+				if rela {
+					addend = uint64(int64(int32(order.Uint32(entry[8:12]))))
+				}
+			}
+
+			if !rela {
+				stored, ok := readPointer(offset)
+				if !ok {
+					continue
+				}
+				addend = stored
+			}
+
+			target := symbolValue(sect.Link, symIndex) + addend
+			if fileOffset, ok := addrToOffset(dataSections, target); ok {
+				targets = append(targets, fileOffset)
+			}
+		}
+	}
+
+	return uniqueSorted(targets), nil
+}
+
+// relocEntrySize returns the size in bytes of an ELF relocation entry
+func relocEntrySize(is64, rela bool) int {
+	switch {
+	case is64 && rela:
+		return 24
+	case is64:
+		return 16
+	case rela:
+		return 12
+	default:
+		return 8
+	}
+}
+
+// peRelocationTargets collects the targets of absolute pointers listed in the
+// base relocation table of a PE file
+func peRelocationTargets(path string) ([]int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	peFile, err := pe.NewFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid PE file: %w", err)
+	}
+	defer func() {
+		_ = peFile.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	imageBase, _ := peImageInfo(peFile)
+
+	// debug/pe accepts more than the 16 directories its headers hold (and
+	// keeps the first 16), so the count is clamped
+	var dirs []pe.DataDirectory
+	switch oh := peFile.OptionalHeader.(type) {
+	case *pe.OptionalHeader32:
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, uint32(len(oh.DataDirectory)))]
+	case *pe.OptionalHeader64:
+		dirs = oh.DataDirectory[:min(oh.NumberOfRvaAndSizes, uint32(len(oh.DataDirectory)))]
+	}
+	if len(dirs) <= peBaseRelocDirectory || dirs[peBaseRelocDirectory].Size == 0 {
+		return nil, nil // Image has no base relocations (e.g. fixed base)
+	}
+
+	var dataSections []Section
+	for _, sect := range peFile.Sections {
+		if peDataSections[sect.Name] {
+			dataSections = append(dataSections, Section{
+				Name:   sect.Name,
+				Offset: int64(sect.Offset),
+				Size:   int64(sect.Size),
+				Addr:   imageBase + uint64(sect.VirtualAddress),
+			})
+		}
+	}
+
+	// readRVA reads size bytes at a relative virtual address. The bounds are
+	// checked in 64 bits, as forged sizes wrap around in 32, and against the
+	// file size, so a forged section size cannot force a huge allocation.
+	readRVA := func(rva uint32, size uint32) []byte {
+		for _, sect := range peFile.Sections {
+			if rva < sect.VirtualAddress {
+				continue
+			}
+			start := uint64(rva - sect.VirtualAddress)
+			if size > sect.Size || start+uint64(size) > uint64(sect.Size) ||
+				uint64(sect.Offset)+start+uint64(size) > uint64(info.Size()) {
+				continue
+			}
+			buf := make([]byte, size)
+			if _, err := sect.ReadAt(buf, int64(rva-sect.VirtualAddress)); err != nil {
+				return nil
+			}
+			return buf
+		}
+		return nil
+	}
+
+	relocDir := dirs[peBaseRelocDirectory]
+	table := readRVA(relocDir.VirtualAddress, relocDir.Size)
+	if table == nil {
+		return nil, fmt.Errorf("cannot read base relocation table")
+	}
+
+	var targets []int64
+	for pos := 0; pos+8 <= len(table); {
+		pageRVA := binary.LittleEndian.Uint32(table[pos : pos+4])
+		blockSize := int(binary.LittleEndian.Uint32(table[pos+4 : pos+8]))
+		if blockSize < 8 || pos+blockSize > len(table) {
+			break // Malformed block
+		}
+
+		for i := pos + 8; i+2 <= pos+blockSize; i += 2 {
+			entry := binary.LittleEndian.Uint16(table[i : i+2])
+			rva := pageRVA + uint32(entry&0x0fff)
+
+			var target uint64
+			switch entry >> 12 {
+			case peRelBasedHighLow:
+				ptr := readRVA(rva, 4)
+				if ptr == nil {
+					continue
+				}
+				target = uint64(binary.LittleEndian.Uint32(ptr))
+			case peRelBasedDir64:
+				ptr := readRVA(rva, 8)
+				if ptr == nil {
+					continue
+				}
+				target = binary.LittleEndian.Uint64(ptr)
+			default:
+				continue // Padding (ABSOLUTE) or unsupported type
+			}
+
+			if fileOffset, ok := addrToOffset(dataSections, target); ok {
+				targets = append(targets, fileOffset)
+			}
+		}
+
+		pos += blockSize
+	}
+
+	return uniqueSorted(targets), nil
+}
+
+// addrToOffset converts a virtual address inside one of the sections to a file offset
+func addrToOffset(sections []Section, addr uint64) (int64, bool) {
+	for _, sect := range sections {
+		if addr >= sect.Addr && addr < sect.Addr+uint64(sect.Size) {
+			return sect.Offset + int64(addr-sect.Addr), true
+		}
+	}
+	return 0, false
+}
+
+// uniqueSorted sorts offsets and removes duplicates
+func uniqueSorted(offsets []int64) []int64 {
+	slices.Sort(offsets)
+	return slices.Compact(offsets)
+}
@@ -0,0 +1,187 @@
+package binary
+
+import (
+	"debug/pe"
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// TestAddrToOffset tests virtual address to file offset conversion
+func TestAddrToOffset(t *testing.T) {
+	sections := []Section{
+		{Name: ".rodata", Offset: 0x1000, Size: 0x100, Addr: 0x401000},
+		{Name: ".data", Offset: 0x2000, Size: 0x80, Addr: 0x603000},
+	}
+
+	tests := []struct {
+		addr       uint64
+		wantOffset int64
+		wantOK     bool
+	}{
+		{0x401000, 0x1000, true},
+		{0x4010ff, 0x10ff, true},
+		{0x401100, 0, false}, // One past the end
+		{0x603010, 0x2010, true},
+		{0x0, 0, false},
+	}
+
+	for _, tt := range tests {
+		offset, ok := addrToOffset(sections, tt.addr)
+		if ok != tt.wantOK || offset != tt.wantOffset {
+			t.Errorf("addrToOffset(0x%x) = (0x%x, %v), want (0x%x, %v)", tt.addr, offset, ok, tt.wantOffset, tt.wantOK)
+		}
+	}
+}
+
+// TestUniqueSorted tests sorting and deduplication of relocation targets
+func TestUniqueSorted(t *testing.T) {
+	got := uniqueSorted([]int64{30, 10, 20, 10, 30})
+	want := []int64{10, 20, 30}
+	if !slices.Equal(got, want) {
+		t.Errorf("uniqueSorted() = %v, want %v", got, want)
+	}
+}
+
+// TestRelocEntrySize tests ELF relocation entry sizes
+func TestRelocEntrySize(t *testing.T) {
+	tests := []struct {
+		is64, rela bool
+		want       int
+	}{
+		{true, true, 24},
+		{true, false, 16},
+		{false, true, 12},
+		{false, false, 8},
+	}
+
+	for _, tt := range tests {
+		if got := relocEntrySize(tt.is64, tt.rela); got != tt.want {
+			t.Errorf("relocEntrySize(%v, %v) = %d, want %d", tt.is64, tt.rela, got, tt.want)
+		}
+	}
+}
+
+// TestRelocationTargetsUnsupportedFormat tests that only ELF and PE are accepted
+func TestRelocationTargetsUnsupportedFormat(t *testing.T) {
+	for _, format := range []Format{FormatMachO, FormatRaw, FormatUnknown} {
+		if _, err := RelocationTargets("unused", format); err == nil {
+			t.Errorf("RelocationTargets() with %v expected error, got nil", format)
+		}
+	}
+}
+
+// TestRelocationTargetsInvalidFile tests that malformed input is rejected
+func TestRelocationTargetsInvalidFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "raw.bin")
+	if err := os.WriteFile(path, []byte("definitely not an executable"), 0644); err != nil {
+		t.Fatalf("Failed to create test file: %v", err)
+	}
+
+	for _, format := range []Format{FormatELF, FormatPE} {
+		if _, err := RelocationTargets(path, format); err == nil {
+			t.Errorf("RelocationTargets() on raw file as %v expected error, got nil", format)
+		}
+	}
+}
+
+// TestRelocationTargetsCurrentBinary tests that targets of the running test binary
+// are sorted, unique file offsets
+func TestRelocationTargetsCurrentBinary(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skipf("skipping test: cannot locate test binary: %v", err)
+	}
+	format, err := DetectFormat(exe)
+	if err != nil || (format != FormatELF && format != FormatPE) {
+		t.Skip("skipping test: test binary is not ELF or PE")
+	}
+
+	targets, err := RelocationTargets(exe, format)
+	if err != nil {
+		t.Fatalf("RelocationTargets() error = %v", err)
+	}
+	for i := 1; i < len(targets); i++ {
+		if targets[i] <= targets[i-1] {
+			t.Fatalf("targets not strictly increasing at %d: %d <= %d", i, targets[i], targets[i-1])
+		}
+	}
+}
+
+// buildPE64 builds a PE32+ image with dirCount data directories, the base
+// relocation one set to relocDir, and a single .reloc section at RVA 0x1000
+// holding data
+func buildPE64(t *testing.T, dirCount uint32, relocDir pe.DataDirectory, data []byte) string {
+	t.Helper()
+	const (
+		peOffset    = 0x40
+		ohFixedSize = 112 // PE32+ optional header without data directories
+		sectionRVA  = 0x1000
+	)
+	le := binary.LittleEndian
+
+	buf := make([]byte, peOffset)
+	copy(buf, "MZ")
+	le.PutUint32(buf[0x3c:], peOffset)
+	buf = append(buf, "PE\x00\x00"...)
+
+	ohSize := ohFixedSize + 8*int(dirCount)
+	buf = le.AppendUint16(buf, pe.IMAGE_FILE_MACHINE_AMD64)
+	buf = le.AppendUint16(buf, 1) // NumberOfSections
+	buf = append(buf, make([]byte, 12)...)
+	buf = le.AppendUint16(buf, uint16(ohSize))
+	buf = le.AppendUint16(buf, pe.IMAGE_FILE_EXECUTABLE_IMAGE)
+
+	oh := make([]byte, ohSize)
+	le.PutUint16(oh, 0x20b)            // Magic (PE32+)
+	le.PutUint64(oh[24:], 0x140000000) // ImageBase
+	le.PutUint32(oh[108:], dirCount)   // NumberOfRvaAndSizes
+	le.PutUint32(oh[ohFixedSize+8*peBaseRelocDirectory:], relocDir.VirtualAddress)
+	le.PutUint32(oh[ohFixedSize+8*peBaseRelocDirectory+4:], relocDir.Size)
+	buf = append(buf, oh...)
+
+	rawOffset := len(buf) + 40
+	sect := make([]byte, 40)
+	copy(sect, ".reloc")
+	le.PutUint32(sect[8:], uint32(len(data)))  // VirtualSize
+	le.PutUint32(sect[12:], sectionRVA)        // VirtualAddress
+	le.PutUint32(sect[16:], uint32(len(data))) // SizeOfRawData
+	le.PutUint32(sect[20:], uint32(rawOffset)) // PointerToRawData
+	buf = append(buf, sect...)
+	buf = append(buf, data...)
+
+	path := filepath.Join(t.TempDir(), "test.exe")
+	if err := os.WriteFile(path, buf, 0644); err != nil {
+		t.Fatalf("Failed to create PE file: %v", err)
+	}
+	return path
+}
+
+// TestPERelocationTargetsExtraDirectories tests that a PE declaring more than
+// the 16 data directories debug/pe keeps is scanned without panicking
+func TestPERelocationTargetsExtraDirectories(t *testing.T) {
+	// One empty relocation block
+	block := []byte{0x00, 0x10, 0x00, 0x00, 0x08, 0x00, 0x00, 0x00}
+	path := buildPE64(t, 17, pe.DataDirectory{VirtualAddress: 0x1000, Size: uint32(len(block))}, block)
+
+	targets, err := RelocationTargets(path, FormatPE)
+	if err != nil {
+		t.Fatalf("RelocationTargets() error = %v", err)
+	}
+	if len(targets) != 0 {
+		t.Errorf("RelocationTargets() = %v, want none", targets)
+	}
+}
+
+// TestPERelocationTargetsForgedSize tests that a relocation directory size
+// chosen to wrap the 32-bit bounds check is rejected rather than allocated
+func TestPERelocationTargetsForgedSize(t *testing.T) {
+	block := make([]byte, 0x20)
+	path := buildPE64(t, 16, pe.DataDirectory{VirtualAddress: 0x1010, Size: 0xfffffff8}, block)
+
+	if _, err := RelocationTargets(path, FormatPE); err == nil {
+		t.Error("RelocationTargets() error = nil, want unreadable relocation table")
+	}
+}
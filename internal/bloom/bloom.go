@@ -0,0 +1,107 @@
+// Package bloom builds the corpus index of "txtr index": a Bloom filter per
+// sample of the n-grams of its strings, saved in one file, so that which
+// samples likely contain a string (an IOC) is answered without scanning the
+// corpus again ("txtr query"). Indexing n-grams rather than whole strings
+// finds a string inside longer ones, e.g. a domain inside a URL. A Bloom
+// filter never misses a string it holds, but may report one it does not hold
+// (a false positive), so samples found are candidates to confirm with
+// "txtr find".
+package bloom
+
+import "math"
+
+// GramSize is the length in bytes of the n-grams indexed. Strings shorter
+// than GramSize are not indexed and cannot be queried.
+const GramSize = 4
+
+// DefaultFalsePositiveRate is the default rate at which a filter reports an
+// n-gram it does not hold. A query needs all of its n-grams to match, so its
+// own false positive rate is lower.
+const DefaultFalsePositiveRate = 0.01
+
+// Filter is a Bloom filter of n-gram hashes
+type Filter struct {
+	bits   []uint64
+	hashes int // Bits set per n-gram
+}
+
+// NewFilter returns a filter sized for n n-grams at the false positive rate
+// given
+func NewFilter(n int, rate float64) *Filter {
+	n = max(n, 1)
+	bits := math.Ceil(-float64(n) * math.Log(rate) / (math.Ln2 * math.Ln2))
+	words := max(int((bits+63)/64), 1)
+	hashes := max(int(math.Round(float64(words*64)/float64(n)*math.Ln2)), 1)
+	return &Filter{bits: make([]uint64, words), hashes: hashes}
+}
+
+// Add adds an n-gram hash (see Grams)
+func (f *Filter) Add(h uint64) {
+	m := uint64(len(f.bits)) * 64
+	h2 := mix(h) | 1
+	for i := range uint64(f.hashes) {
+		bit := (h + i*h2) % m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// Contains reports whether an n-gram hash was likely added
+func (f *Filter) Contains(h uint64) bool {
+	m := uint64(len(f.bits)) * 64
+	h2 := mix(h) | 1
+	for i := range uint64(f.hashes) {
+		bit := (h + i*h2) % m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Grams calls fn with the hash (64-bit FNV-1a) of each n-gram of str
+func Grams(str []byte, fn func(uint64)) {
+	for i := 0; i+GramSize <= len(str); i++ {
+		h := uint64(fnvOffset)
+		for _, b := range str[i : i+GramSize] {
+			h ^= uint64(b)
+			h *= fnvPrime
+		}
+		fn(h)
+	}
+}
+
+// 64-bit FNV-1a parameters (as in hash/fnv, inlined as n-grams are hashed by
+// the million)
+const (
+	fnvOffset = 14695981039346656037
+	fnvPrime  = 1099511628211
+)
+
+// GramSet collects the distinct n-grams of the strings of a sample, to size
+// its filter once they are known
+type GramSet map[uint64]struct{}
+
+// Add adds the n-grams of str
+func (s GramSet) Add(str []byte) {
+	Grams(str, func(h uint64) { s[h] = struct{}{} })
+}
+
+// Filter returns a filter holding the n-grams of the set
+func (s GramSet) Filter(rate float64) *Filter {
+	f := NewFilter(len(s), rate)
+	for h := range s {
+		f.Add(h)
+	}
+	return f
+}
+
+// mix is the splitmix64 finalizer, deriving the second hash of double hashing
+// from the first
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
@@ -0,0 +1,130 @@
+package bloom
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestFilter tests that filters hold what was added and rarely more
+func TestFilter(t *testing.T) {
+	set := GramSet{}
+	for i := range 10000 {
+		set.Add(fmt.Appendf(nil, "string %d", i))
+	}
+	filter := set.Filter(DefaultFalsePositiveRate)
+	for h := range set {
+		if !filter.Contains(h) {
+			t.Fatalf("filter misses n-gram %x", h)
+		}
+	}
+
+	falsePositives := 0
+	const probes = 100000
+	for i := range probes {
+		if filter.Contains(mix(uint64(i) + 1<<40)) {
+			falsePositives++
+		}
+	}
+	if rate := float64(falsePositives) / probes; rate > 2*DefaultFalsePositiveRate {
+		t.Errorf("false positive rate = %.4f, want about %.2f", rate, DefaultFalsePositiveRate)
+	}
+}
+
+// TestIndex tests querying an index and reading it back
+func TestIndex(t *testing.T) {
+	samples := map[string][]string{
+		"dropper.exe": {"http://c2.example.com/beacon", "CreateRemoteThread"},
+		"loader.dll":  {"c2.example.com", "kernel32.dll"},
+		"clean.exe":   {"Hello, world", "kernel32.dll"},
+	}
+	ix := &Index{}
+	for _, name := range []string{"dropper.exe", "loader.dll", "clean.exe"} {
+		set := GramSet{}
+		for _, str := range samples[name] {
+			set.Add([]byte(str))
+		}
+		ix.Samples = append(ix.Samples, Sample{Name: name, Filter: set.Filter(DefaultFalsePositiveRate)})
+	}
+
+	var buf bytes.Buffer
+	if _, err := ix.WriteTo(&buf); err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+	read, err := Read(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	tests := []struct {
+		query   string
+		want    []string
+		wantErr bool
+	}{
+		{"c2.example.com", []string{"dropper.exe", "loader.dll"}, false},
+		{"kernel32", []string{"loader.dll", "clean.exe"}, false},
+		{"RemoteThread", []string{"dropper.exe"}, false},
+		{"not in any sample", nil, false},
+		{"c2", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			for _, index := range []*Index{ix, read} {
+				got, err := index.Query([]byte(tt.query))
+				if (err != nil) != tt.wantErr {
+					t.Fatalf("Query() error = %v, wantErr %v", err, tt.wantErr)
+				}
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("Query() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+
+	for _, data := range []string{"", "not an index", buf.String()[:buf.Len()-3]} {
+		if _, err := Read(strings.NewReader(data)); err == nil {
+			t.Errorf("Read(%.12q) succeeded, want an error", data)
+		}
+	}
+}
+
+// TestRarity tests that strings in fewer samples of the corpus score higher
+func TestRarity(t *testing.T) {
+	ix := &Index{}
+	for _, strs := range [][]string{
+		{"kernel32.dll", "http://c2.example.com/beacon"},
+		{"kernel32.dll", "Hello, world"},
+		{"kernel32.dll", "Hello, world"},
+	} {
+		set := GramSet{}
+		for _, str := range strs {
+			set.Add([]byte(str))
+		}
+		ix.Samples = append(ix.Samples, Sample{Name: strs[1], Filter: set.Filter(DefaultFalsePositiveRate)})
+	}
+
+	tests := []struct {
+		str  string
+		want float64
+		ok   bool
+	}{
+		{"kernel32.dll", 0, true},
+		{"Hello, world", 0.2075, true}, // log(4/3) / log(4)
+		{"c2.example.com", 0.5, true},  // log(4/2) / log(4)
+		{"not in any sample", 1, true},
+		{"c2", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := ix.Rarity([]byte(tt.str))
+		if ok != tt.ok || math.Abs(got-tt.want) > 0.0001 {
+			t.Errorf("Rarity(%q) = %.4f, %v, want %.4f, %v", tt.str, got, ok, tt.want, tt.ok)
+		}
+	}
+
+	if _, ok := (&Index{}).Rarity([]byte("kernel32.dll")); ok {
+		t.Error("Rarity() of an empty index succeeded, want no score")
+	}
+}
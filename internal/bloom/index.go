@@ -0,0 +1,202 @@
+package bloom
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// magic starts index files, ending in the version of the format
+const magic = "TXTRIDX1"
+
+// Limits on the fields of index files read, against corrupt files
+const (
+	maxNameSize = 64 << 10
+	maxWords    = 1 << 32
+	maxHashes   = 64
+)
+
+// Sample is an indexed sample
+type Sample struct {
+	Name   string
+	Filter *Filter
+}
+
+// Index is the filters of the samples of a corpus, in the order they were
+// indexed
+type Index struct {
+	Samples []Sample
+}
+
+// Query returns the names of the samples likely containing str, which must
+// be at least GramSize bytes long
+func (ix *Index) Query(str []byte) ([]string, error) {
+	if len(str) < GramSize {
+		return nil, fmt.Errorf("the string must be at least %d bytes long", GramSize)
+	}
+	var grams []uint64
+	Grams(str, func(h uint64) { grams = append(grams, h) })
+
+	var names []string
+	for _, sample := range ix.Samples {
+		if containsAll(sample.Filter, grams) {
+			names = append(names, sample.Name)
+		}
+	}
+	return names, nil
+}
+
+// Rarity returns how rare str is in the corpus, an inverse document
+// frequency scaled from 0 (likely in every sample) to 1 (in none):
+// log((N+1)/(n+1)) / log(N+1) for n of the N samples likely containing it.
+// Strings shorter than GramSize, and any string if the index has no samples,
+// cannot be scored (false).
+func (ix *Index) Rarity(str []byte) (float64, bool) {
+	if len(str) < GramSize || len(ix.Samples) == 0 {
+		return 0, false
+	}
+	var grams []uint64
+	Grams(str, func(h uint64) { grams = append(grams, h) })
+
+	found := 0
+	for _, sample := range ix.Samples {
+		if containsAll(sample.Filter, grams) {
+			found++
+		}
+	}
+	total := float64(len(ix.Samples) + 1)
+	return math.Log(total/float64(found+1)) / math.Log(total), true
+}
+
+// containsAll reports whether f likely holds all the n-grams
+func containsAll(f *Filter, grams []uint64) bool {
+	for _, h := range grams {
+		if !f.Contains(h) {
+			return false
+		}
+	}
+	return true
+}
+
+// Save writes the index to path
+func (ix *Index) Save(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if _, err := ix.WriteTo(file); err != nil {
+		_ = file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// WriteTo writes the index: the magic, the n-gram size and the number of
+// samples, then the name, hash count and filter bits of each sample, sizes as
+// uvarints and bits as little endian 64-bit words. It implements io.WriterTo.
+func (ix *Index) WriteTo(w io.Writer) (int64, error) {
+	bw := bufio.NewWriter(w)
+	var written int64
+	write := func(data []byte) {
+		n, _ := bw.Write(data)
+		written += int64(n)
+	}
+	uvarint := func(v uint64) {
+		write(binary.AppendUvarint(nil, v))
+	}
+
+	write([]byte(magic))
+	uvarint(GramSize)
+	uvarint(uint64(len(ix.Samples)))
+	word := make([]byte, 8)
+	for _, sample := range ix.Samples {
+		uvarint(uint64(len(sample.Name)))
+		write([]byte(sample.Name))
+		uvarint(uint64(sample.Filter.hashes))
+		uvarint(uint64(len(sample.Filter.bits)))
+		for _, bits := range sample.Filter.bits {
+			binary.LittleEndian.PutUint64(word, bits)
+			write(word)
+		}
+	}
+	return written, bw.Flush()
+}
+
+// Load reads an index from path
+func Load(path string) (*Index, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return Read(file)
+}
+
+// Read reads an index written by WriteTo
+func Read(r io.Reader) (*Index, error) {
+	br := bufio.NewReader(r)
+	header := make([]byte, len(magic))
+	if _, err := io.ReadFull(br, header); err != nil || string(header) != magic {
+		return nil, errors.New("not a txtr index")
+	}
+	gramSize, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, corrupt(err)
+	}
+	if gramSize != GramSize {
+		return nil, fmt.Errorf("index of %d-grams, this txtr reads %d-grams", gramSize, GramSize)
+	}
+	count, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, corrupt(err)
+	}
+
+	ix := &Index{}
+	word := make([]byte, 8)
+	for range count {
+		var sample Sample
+		size, err := binary.ReadUvarint(br)
+		if err != nil || size > maxNameSize {
+			return nil, corrupt(err)
+		}
+		name := make([]byte, size)
+		if _, err := io.ReadFull(br, name); err != nil {
+			return nil, corrupt(err)
+		}
+		sample.Name = string(name)
+
+		hashes, err := binary.ReadUvarint(br)
+		if err != nil || hashes == 0 || hashes > maxHashes {
+			return nil, corrupt(err)
+		}
+		words, err := binary.ReadUvarint(br)
+		if err != nil || words == 0 || words > maxWords {
+			return nil, corrupt(err)
+		}
+		filter := &Filter{hashes: int(hashes)}
+		for range words {
+			if _, err := io.ReadFull(br, word); err != nil {
+				return nil, corrupt(err)
+			}
+			filter.bits = append(filter.bits, binary.LittleEndian.Uint64(word))
+		}
+		sample.Filter = filter
+		ix.Samples = append(ix.Samples, sample)
+	}
+	return ix, nil
+}
+
+// corrupt returns the error of an index that cannot be read in full
+func corrupt(err error) error {
+	if err == nil {
+		return errors.New("corrupt txtr index")
+	}
+	if errors.Is(err, io.EOF) {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("corrupt txtr index: %w", err)
+}
@@ -0,0 +1,77 @@
+// Package bufpool provides size-classed byte buffers and buffered readers
+// shared by the extraction and output paths, so that scanning many files
+// reuses buffers instead of allocating new ones for every file and string.
+package bufpool
+
+import (
+	"bufio"
+	"io"
+	"sync"
+)
+
+// Common buffer sizes
+const (
+	StringSize = 256      // A string being extracted or formatted
+	ReaderSize = 64 << 10 // A buffered reader over a file
+)
+
+// classes are the capacities buffers are pooled by. Larger buffers are not
+// pooled, so a single huge string does not stay in memory.
+var classes = [...]int{StringSize, 4 << 10, ReaderSize, 1 << 20}
+
+// pools holds the free buffers of each class
+var pools [len(classes)]sync.Pool
+
+// readers holds free buffered readers of ReaderSize
+var readers = sync.Pool{
+	New: func() any { return bufio.NewReaderSize(nil, ReaderSize) },
+}
+
+// Get returns an empty buffer with a capacity of at least size. Return it with
+// Put when done; appending beyond the capacity is fine, but the buffer must
+// then be stored back (*buf = grown) before Put.
+func Get(size int) *[]byte {
+	for i, class := range classes {
+		if size > class {
+			continue
+		}
+		if buf, ok := pools[i].Get().(*[]byte); ok {
+			*buf = (*buf)[:0]
+			return buf
+		}
+		buf := make([]byte, 0, class)
+		return &buf
+	}
+	buf := make([]byte, 0, size)
+	return &buf
+}
+
+// Put returns a buffer from Get to its pool. The caller must not use it
+// afterwards, including slices of it passed to callbacks.
+func Put(buf *[]byte) {
+	size := cap(*buf)
+	if size > classes[len(classes)-1] {
+		return
+	}
+	for i := len(classes) - 1; i >= 0; i-- {
+		if size >= classes[i] {
+			pools[i].Put(buf)
+			return
+		}
+	}
+}
+
+// GetReader returns a buffered reader of ReaderSize reading from r. Return it
+// with PutReader when done.
+func GetReader(r io.Reader) *bufio.Reader {
+	br := readers.Get().(*bufio.Reader)
+	br.Reset(r)
+	return br
+}
+
+// PutReader returns a reader from GetReader to the pool, releasing the
+// underlying reader
+func PutReader(br *bufio.Reader) {
+	br.Reset(nil)
+	readers.Put(br)
+}
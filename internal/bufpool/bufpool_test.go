@@ -0,0 +1,58 @@
+package bufpool
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+// TestGet tests that buffers come from the smallest fitting size class
+func TestGet(t *testing.T) {
+	tests := []struct {
+		size    int
+		wantCap int
+	}{
+		{0, StringSize},
+		{StringSize, StringSize},
+		{StringSize + 1, 4 << 10},
+		{ReaderSize, ReaderSize},
+		{2 << 20, 2 << 20},
+	}
+	for _, tt := range tests {
+		buf := Get(tt.size)
+		if len(*buf) != 0 || cap(*buf) < tt.wantCap {
+			t.Errorf("Get(%d) = len %d cap %d, want len 0 cap >= %d", tt.size, len(*buf), cap(*buf), tt.wantCap)
+		}
+		Put(buf)
+	}
+}
+
+// TestPutGrown tests returning a buffer that grew beyond its class
+func TestPutGrown(t *testing.T) {
+	buf := Get(StringSize)
+	*buf = append(*buf, strings.Repeat("x", 5000)...)
+	Put(buf)
+
+	// Whichever buffer is returned, it is empty and large enough
+	for _, size := range []int{StringSize, 4 << 10} {
+		got := Get(size)
+		if len(*got) != 0 || cap(*got) < size {
+			t.Errorf("Get(%d) = len %d cap %d", size, len(*got), cap(*got))
+		}
+	}
+}
+
+// TestGetReader tests pooled buffered readers
+func TestGetReader(t *testing.T) {
+	for _, input := range []string{"first reader", "second"} {
+		br := GetReader(strings.NewReader(input))
+		data, err := io.ReadAll(br)
+		if err != nil || string(data) != input {
+			t.Errorf("read %q, %v, want %q", data, err, input)
+		}
+		if br.Size() != ReaderSize {
+			t.Errorf("reader size = %d, want %d", br.Size(), ReaderSize)
+		}
+		PutReader(br)
+	}
+}
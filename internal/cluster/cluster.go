@@ -0,0 +1,154 @@
+// Package cluster groups samples by the strings they share. Each sample's set
+// of strings is summarized by a MinHash signature, whose agreement estimates
+// the Jaccard similarity of two sets; samples are then grouped by single
+// linkage above a similarity threshold.
+package cluster
+
+import (
+	"hash/fnv"
+	"math"
+	"sort"
+)
+
+// NumHashes is the number of hash functions of a signature. The standard error
+// of the similarity estimate is about 1/sqrt(NumHashes).
+const NumHashes = 128
+
+// Signature is the MinHash signature of a string set. The zero value is the
+// signature of the empty set.
+type Signature struct {
+	mins  [NumHashes]uint64
+	count int // Strings added (including duplicates)
+}
+
+// Add adds a string to the set
+func (s *Signature) Add(str []byte) {
+	if s.count == 0 {
+		for i := range s.mins {
+			s.mins[i] = math.MaxUint64
+		}
+	}
+	s.count++
+
+	h := fnv.New64a()
+	_, _ = h.Write(str)
+	base := h.Sum64()
+	for i := range s.mins {
+		if v := mix(base + uint64(i)*0x9e3779b97f4a7c15); v < s.mins[i] {
+			s.mins[i] = v
+		}
+	}
+}
+
+// Count returns the number of strings added, including duplicates
+func (s *Signature) Count() int {
+	return s.count
+}
+
+// mix is the splitmix64 finalizer, deriving independent hash functions from one
+func mix(x uint64) uint64 {
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// Similarity estimates the Jaccard similarity (shared strings over all
+// distinct strings) of the sets of two signatures. Empty sets are similar to
+// nothing.
+func Similarity(a, b *Signature) float64 {
+	if a.count == 0 || b.count == 0 {
+		return 0
+	}
+	equal := 0
+	for i := range a.mins {
+		if a.mins[i] == b.mins[i] {
+			equal++
+		}
+	}
+	return float64(equal) / NumHashes
+}
+
+// Sample is a named string set
+type Sample struct {
+	Name      string
+	Signature Signature
+}
+
+// Matrix returns the pairwise similarities of the samples
+func Matrix(samples []Sample) [][]float64 {
+	matrix := make([][]float64, len(samples))
+	for i := range samples {
+		matrix[i] = make([]float64, len(samples))
+		matrix[i][i] = 1
+		if samples[i].Signature.count == 0 {
+			matrix[i][i] = 0
+		}
+	}
+	for i := range samples {
+		for j := i + 1; j < len(samples); j++ {
+			similarity := Similarity(&samples[i].Signature, &samples[j].Signature)
+			matrix[i][j] = similarity
+			matrix[j][i] = similarity
+		}
+	}
+	return matrix
+}
+
+// Member is a sample in a cluster with its highest similarity to another member
+type Member struct {
+	Index      int // Index of the sample
+	Similarity float64
+}
+
+// Group clusters samples by single linkage: two samples are in the same
+// cluster if a chain of samples with pairwise similarity of at least threshold
+// connects them. Clusters are ordered by size, then by first sample; samples
+// similar to no other sample form clusters of one.
+func Group(matrix [][]float64, threshold float64) [][]Member {
+	parent := make([]int, len(matrix))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+
+	best := make([]float64, len(matrix))
+	for i := range matrix {
+		for j := i + 1; j < len(matrix); j++ {
+			similarity := matrix[i][j]
+			best[i] = max(best[i], similarity)
+			best[j] = max(best[j], similarity)
+			if similarity >= threshold {
+				if ri, rj := find(i), find(j); ri != rj {
+					parent[max(ri, rj)] = min(ri, rj)
+				}
+			}
+		}
+	}
+
+	index := make(map[int]int)
+	var clusters [][]Member
+	for i := range matrix {
+		root := find(i)
+		c, ok := index[root]
+		if !ok {
+			c = len(clusters)
+			index[root] = c
+			clusters = append(clusters, nil)
+		}
+		clusters[c] = append(clusters[c], Member{Index: i, Similarity: best[i]})
+	}
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		return len(clusters[i]) > len(clusters[j])
+	})
+	return clusters
+}
@@ -0,0 +1,92 @@
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"testing"
+)
+
+// sample returns a sample holding strings start..start+n-1 of a family
+func sample(name, family string, start, n int) Sample {
+	s := Sample{Name: name}
+	for i := start; i < start+n; i++ {
+		s.Signature.Add(fmt.Appendf(nil, "%s %d", family, i))
+	}
+	return s
+}
+
+// TestSimilarity tests the Jaccard estimate of MinHash signatures
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b Sample
+		want float64
+	}{
+		{"identical", sample("a", "x", 0, 1000), sample("b", "x", 0, 1000), 1},
+		{"disjoint", sample("a", "x", 0, 1000), sample("b", "y", 0, 1000), 0},
+		{"half shared", sample("a", "x", 0, 1000), sample("b", "x", 500, 1000), 1.0 / 3}, // 500 of 1500
+		{"subset", sample("a", "x", 0, 1000), sample("b", "x", 0, 250), 0.25},
+		{"empty", sample("a", "x", 0, 0), sample("b", "x", 0, 0), 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Similarity(&tt.a.Signature, &tt.b.Signature)
+			// Allow about three standard errors of the estimate
+			if math.Abs(got-tt.want) > 3/math.Sqrt(NumHashes) {
+				t.Errorf("Similarity() = %.3f, want about %.3f", got, tt.want)
+			}
+		})
+	}
+
+	// Duplicates do not change the set
+	a := sample("a", "x", 0, 100)
+	b := sample("b", "x", 0, 100)
+	b.Signature.Add([]byte("x 1"))
+	if got := Similarity(&a.Signature, &b.Signature); got != 1 {
+		t.Errorf("Similarity() with a duplicate = %.3f, want 1", got)
+	}
+	if b.Signature.Count() != 101 {
+		t.Errorf("Count() = %d, want 101", b.Signature.Count())
+	}
+}
+
+// TestGroup tests single-linkage clustering of the similarity matrix
+func TestGroup(t *testing.T) {
+	samples := []Sample{
+		sample("family1-a", "f1", 0, 1000),
+		sample("other", "o", 0, 1000),
+		sample("family2-a", "f2", 0, 1000),
+		sample("family1-b", "f1", 100, 1000),
+		sample("family2-b", "f2", 50, 1000),
+		sample("family1-c", "f1", 200, 1000), // Linked to family1-a through family1-b
+	}
+
+	matrix := Matrix(samples)
+	if matrix[0][0] != 1 || matrix[0][3] != matrix[3][0] {
+		t.Errorf("matrix is not symmetric with a unit diagonal: %v", matrix)
+	}
+
+	groups := Group(matrix, 0.7)
+	var names [][]string
+	for _, group := range groups {
+		var members []string
+		for _, member := range group {
+			members = append(members, samples[member.Index].Name)
+		}
+		names = append(names, members)
+	}
+
+	want := [][]string{
+		{"family1-a", "family1-b", "family1-c"},
+		{"family2-a", "family2-b"},
+		{"other"},
+	}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("Group() = %v, want %v", names, want)
+	}
+
+	if groups[2][0].Similarity > 0.1 {
+		t.Errorf("unclustered sample similarity = %.3f, want about 0", groups[2][0].Similarity)
+	}
+}
@@ -0,0 +1,117 @@
+package conformance
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestCorpus tests that the corpus is deterministic and its names are unique
+func TestCorpus(t *testing.T) {
+	first, second := Corpus(), Corpus()
+	if len(first) != len(second) {
+		t.Fatalf("Corpus() returned %d and %d samples", len(first), len(second))
+	}
+
+	names := make(map[string]bool)
+	for i, sample := range first {
+		if names[sample.Name] {
+			t.Errorf("duplicate sample name %q", sample.Name)
+		}
+		names[sample.Name] = true
+		if !bytes.Equal(sample.Data, second[i].Data) {
+			t.Errorf("sample %q differs between calls", sample.Name)
+		}
+	}
+
+	for _, c := range Cases {
+		for _, name := range c.Samples {
+			if !names[name] {
+				t.Errorf("case %q names unknown sample %q", c, name)
+			}
+		}
+	}
+}
+
+// TestDiff tests the description of the first differing line
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name      string
+		want, got string
+		diff      string
+	}{
+		{"identical", "a\nb\n", "a\nb\n", ""},
+		{"empty", "", "", ""},
+		{"changed line", "a\nb\nc\n", "a\nx\nc\n", `line 2: want "b\n", got "x\n"`},
+		{"missing line", "a\nb\n", "a\n", `line 2: want "b\n", got ""`},
+		{"extra line", "a\n", "a\nb\n", `line 2: want "", got "b\n"`},
+		{"no newline", "a", "a\n", `line 1: want "a", got "a\n"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff([]byte(tt.want), []byte(tt.got)); got != tt.diff {
+				t.Errorf("Diff() = %q, want %q", got, tt.diff)
+			}
+		})
+	}
+}
+
+// TestRunner tests side-by-side runs with stand-in tools
+func TestRunner(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("skipping test: uses shell scripts as tools")
+	}
+
+	// Both tools print their flags and the input (the last argument or stdin)
+	dir := t.TempDir()
+	script := func(name, suffix string) string {
+		path := filepath.Join(dir, name)
+		body := "#!/bin/sh\nfor last; do :; done\necho \"$*\"\nif [ -f \"$last\" ]; then cat \"$last\"; else cat; fi\necho " + suffix + "\n"
+		if err := os.WriteFile(path, []byte(body), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	reference, same, different := script("reference", "end"), script("same", "end"), script("different", "END")
+
+	samples := []Sample{{"one", []byte("first\n")}, {"two", []byte("second\n")}}
+	paths, err := WriteCorpus(dir, samples)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []Case{{Args: []string{"-a"}}, {Stdin: true}, {Args: []string{"-n", "8"}, Samples: []string{"two"}}}
+
+	results := Runner{Reference: []string{reference}, Candidate: []string{same}}.Run(cases, samples, paths)
+	if len(results) != 5 {
+		t.Fatalf("Run() returned %d results, want 5 (one case is limited to one sample)", len(results))
+	}
+	for _, result := range results {
+		if !result.Passed() {
+			t.Errorf("%s %s: Diff = %q, Err = %v", result.Sample, result.Case, result.Diff, result.Err)
+		}
+	}
+
+	results = Runner{Reference: []string{reference}, Candidate: []string{different}}.Run(cases[:1], samples, paths)
+	if results[0].Passed() || !strings.Contains(results[0].Diff, `got "END\n"`) {
+		t.Errorf("differing output: Diff = %q, Err = %v", results[0].Diff, results[0].Err)
+	}
+
+	results = Runner{Reference: []string{reference}, Candidate: []string{filepath.Join(dir, "missing")}}.Run(cases[:1], samples[:1], paths)
+	if results[0].Err == nil || !strings.HasPrefix(results[0].Err.Error(), "candidate: ") {
+		t.Errorf("missing candidate: Err = %v", results[0].Err)
+	}
+}
+
+// TestCaseString tests the display of cases
+func TestCaseString(t *testing.T) {
+	got := []string{Case{}.String(), Case{Stdin: true}.String(), Case{Args: []string{"-e", "l"}}.String()}
+	want := []string{"FILE", "< FILE", "-e l FILE"}
+	if !slices.Equal(got, want) {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,109 @@
+// Package conformance checks that txtr is a drop-in replacement for GNU
+// strings: it generates a corpus of binaries, runs both tools over it with
+// each supported flag combination and compares their output byte for byte.
+package conformance
+
+import (
+	"encoding/binary"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"unicode/utf16"
+)
+
+// Sample is a generated input file
+type Sample struct {
+	Name string
+	Data []byte
+}
+
+// corpusSeed seeds the random samples, so the corpus is identical on every run
+const corpusSeed = 0x747874
+
+// Corpus returns the generated samples. The corpus is deterministic.
+func Corpus() []Sample {
+	rng := rand.New(rand.NewPCG(corpusSeed, corpusSeed))
+
+	return []Sample{
+		{"empty", nil},
+		{"ascii", []byte("\x00\x01hello world\x00\x7fcopyright (c) 2024\x00\xffshort\x00abc\x00end of file")},
+		{"run-lengths", runLengths()},
+		{"whitespace", []byte("\x00tab\there\x00line\nbreak\x00carriage\rreturn\x00vertical\vtab\x00form\ffeed\x00  spaces  \x00")},
+		{"8-bit", []byte("\x00caf\xe9 cr\xe8me\x00\x80\x81\x82\x83\x84\x00na\xefve text\xff\xfe\x00")},
+		{"utf-8", []byte("\x00h\xc3\xa9llo w\xc3\xb6rld\x00\xe6\x97\xa5\xe6\x9c\xac\xe8\xaa\x9e text\x00bad \xc3 seq\x00")},
+		{"utf-16le", encodeUTF16("\x00wide string\x00another one\x00x\x00", binary.LittleEndian)},
+		{"utf-16be", encodeUTF16("\x00wide string\x00another one\x00x\x00", binary.BigEndian)},
+		{"utf-32le", encodeUTF32("\x00wider string\x00more\x00ab\x00", binary.LittleEndian)},
+		{"utf-32be", encodeUTF32("\x00wider string\x00more\x00ab\x00", binary.BigEndian)},
+		{"random", randomBytes(rng, 64<<10)},
+		{"text-heavy", textHeavy(rng, 64<<10)},
+	}
+}
+
+// WriteCorpus writes the samples to dir and returns their paths
+func WriteCorpus(dir string, samples []Sample) ([]string, error) {
+	paths := make([]string, len(samples))
+	for i, sample := range samples {
+		paths[i] = filepath.Join(dir, sample.Name+".bin")
+		if err := os.WriteFile(paths[i], sample.Data, 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return paths, nil
+}
+
+// runLengths returns printable runs of every length from 1 to 20, separated by
+// NUL bytes, to exercise the minimum length boundary
+func runLengths() []byte {
+	var data []byte
+	for n := 1; n <= 20; n++ {
+		for i := range n {
+			data = append(data, 'a'+byte(i%26))
+		}
+		data = append(data, 0)
+	}
+	return data
+}
+
+// encodeUTF16 encodes s as UTF-16 in the given byte order
+func encodeUTF16(s string, order binary.AppendByteOrder) []byte {
+	var data []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		data = order.AppendUint16(data, u)
+	}
+	return data
+}
+
+// encodeUTF32 encodes s as UTF-32 in the given byte order
+func encodeUTF32(s string, order binary.AppendByteOrder) []byte {
+	var data []byte
+	for _, r := range s {
+		data = order.AppendUint32(data, uint32(r))
+	}
+	return data
+}
+
+// randomBytes returns n uniformly random bytes
+func randomBytes(rng *rand.Rand, n int) []byte {
+	data := make([]byte, n)
+	for i := range data {
+		data[i] = byte(rng.UintN(256))
+	}
+	return data
+}
+
+// textHeavy returns n bytes of printable runs of random length (including
+// whitespace) separated by short random binary gaps
+func textHeavy(rng *rand.Rand, n int) []byte {
+	const printable = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 .,:;/_-\t\n"
+	data := make([]byte, 0, n)
+	for len(data) < n {
+		for range rng.IntN(40) {
+			data = append(data, printable[rng.IntN(len(printable))])
+		}
+		for range 1 + rng.IntN(4) {
+			data = append(data, byte(rng.UintN(32)))
+		}
+	}
+	return data[:n]
+}
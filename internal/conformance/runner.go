@@ -0,0 +1,191 @@
+package conformance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// Case is a flag combination both tools are run with
+type Case struct {
+	Args    []string
+	Stdin   bool     // Read the sample from standard input instead of a file argument
+	Samples []string // Names of the samples the case runs on (nil = all)
+}
+
+// String returns the case as a command-line fragment
+func (c Case) String() string {
+	args := strings.Join(c.Args, " ")
+	if c.Stdin {
+		return strings.TrimSpace(args + " < FILE")
+	}
+	return strings.TrimSpace(args + " FILE")
+}
+
+// appliesTo reports whether the case runs on the named sample
+func (c Case) appliesTo(sample string) bool {
+	return c.Samples == nil || slices.Contains(c.Samples, sample)
+}
+
+// Cases lists the flag combinations txtr supports identically to GNU strings.
+// Known differences are left out:
+//   - -NUM (use -n NUM)
+//   - wide encodings (-e b/l/B/L) decode any Unicode character at aligned
+//     offsets, where GNU strings accepts only 8-bit characters but at any byte
+//     offset, so they run on the samples of their encoding only
+//   - -U escape rejects overlong UTF-8 sequences, which GNU strings decodes,
+//     so it runs on samples without random bytes only
+//   - -U hex prints code points, not the bytes of the sequence
+//   - -U locale depends on the locale GNU strings runs in
+var Cases = []Case{
+	{},
+	{Stdin: true},
+	{Args: []string{"-a"}},
+	{Args: []string{"-n", "1"}},
+	{Args: []string{"-n", "8"}},
+	{Args: []string{"--bytes=6"}},
+	{Args: []string{"-f"}},
+	{Args: []string{"-t", "d"}},
+	{Args: []string{"-t", "o"}},
+	{Args: []string{"-t", "x"}},
+	{Args: []string{"-o"}},
+	{Args: []string{"-f", "-t", "x"}},
+	{Args: []string{"-w"}},
+	{Args: []string{"-w", "-t", "x"}},
+	{Args: []string{"-s", "|"}},
+	{Args: []string{"--output-separator=, "}},
+	{Args: []string{"-e", "s"}},
+	{Args: []string{"-e", "S"}},
+	{Args: []string{"-e", "S", "-t", "x"}},
+	{Args: []string{"-e", "b"}, Samples: []string{"utf-16be"}},
+	{Args: []string{"-e", "l"}, Samples: []string{"utf-16le"}},
+	{Args: []string{"-e", "l", "-t", "x"}, Samples: []string{"utf-16le"}},
+	{Args: []string{"-e", "B"}, Samples: []string{"utf-32be"}},
+	{Args: []string{"-e", "L"}, Samples: []string{"utf-32le"}},
+	{Args: []string{"-e", "L", "-t", "d"}, Samples: []string{"utf-32le"}},
+	{Args: []string{"-U", "default"}},
+	{Args: []string{"-U", "invalid"}},
+	{Args: []string{"-U", "escape"}, Samples: []string{"ascii", "whitespace", "8-bit", "utf-8", "text-heavy"}},
+}
+
+// Result is the outcome of one case on one sample
+type Result struct {
+	Sample string
+	Case   Case
+	Diff   string // First difference between the outputs ("" if identical)
+	Err    error  // Failure to run either tool
+}
+
+// Passed reports whether both tools ran and produced identical output
+func (r Result) Passed() bool {
+	return r.Err == nil && r.Diff == ""
+}
+
+// Runner runs a reference and a candidate command side by side. Commands are
+// argument lists; the case's flags and the sample path are appended.
+type Runner struct {
+	Reference []string // GNU strings
+	Candidate []string // txtr
+}
+
+// Run runs every case on every sample. paths and samples correspond.
+func (r Runner) Run(cases []Case, samples []Sample, paths []string) []Result {
+	var results []Result
+	for i, sample := range samples {
+		for _, c := range cases {
+			if !c.appliesTo(sample.Name) {
+				continue
+			}
+			result := Result{Sample: sample.Name, Case: c}
+			want, err := run(r.Reference, c, paths[i])
+			if err != nil {
+				result.Err = fmt.Errorf("reference: %w", err)
+			} else if got, err := run(r.Candidate, c, paths[i]); err != nil {
+				result.Err = fmt.Errorf("candidate: %w", err)
+			} else {
+				result.Diff = Diff(want, got)
+			}
+			results = append(results, result)
+		}
+	}
+	return results
+}
+
+// run runs command with the case's flags on the file at path and returns its
+// standard output
+func run(command []string, c Case, path string) ([]byte, error) {
+	args := append(slices.Clone(command[1:]), c.Args...)
+	var stdin []byte
+	if c.Stdin {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		stdin = data
+	} else {
+		args = append(args, path)
+	}
+
+	cmd := exec.Command(command[0], args...)
+	cmd.Stdin = bytes.NewReader(stdin)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && stderr.Len() > 0 {
+			return nil, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+		}
+		return nil, err
+	}
+	return stdout.Bytes(), nil
+}
+
+// Diff describes the first line where got differs from want, or returns "" if
+// they are identical
+func Diff(want, got []byte) string {
+	if bytes.Equal(want, got) {
+		return ""
+	}
+
+	wantLines := bytes.SplitAfter(want, []byte("\n"))
+	gotLines := bytes.SplitAfter(got, []byte("\n"))
+	for i := range max(len(wantLines), len(gotLines)) {
+		var w, g []byte
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if !bytes.Equal(w, g) {
+			return fmt.Sprintf("line %d: want %q, got %q", i+1, w, g)
+		}
+	}
+	return ""
+}
+
+// FindGNUStrings returns the path of GNU strings, trying the usual names of
+// the binutils program. It returns an error if none is installed.
+func FindGNUStrings() (string, error) {
+	for _, name := range []string{"strings", "gstrings", "x86_64-linux-gnu-strings", "aarch64-linux-gnu-strings"} {
+		path, err := exec.LookPath(name)
+		if err != nil {
+			continue
+		}
+		if IsGNUStrings(path) {
+			return path, nil
+		}
+	}
+	return "", errors.New("GNU strings not found (install binutils)")
+}
+
+// IsGNUStrings reports whether the program at path is GNU strings
+func IsGNUStrings(path string) bool {
+	out, err := exec.Command(path, "--version").Output()
+	return err == nil && bytes.HasPrefix(out, []byte("GNU strings"))
+}
@@ -0,0 +1,15 @@
+// Package console prepares the Windows console for the text output of txtr.
+//
+// Consoles take UTF-16 text, not bytes in a code page. When stdout is a
+// console, os.File converts what txtr writes from UTF-8 to UTF-16 and calls
+// WriteConsoleW, so UTF-8 strings display whatever the code page of cmd.exe
+// or PowerShell (chcp), and the code page is never changed. Output redirected
+// to a file or a pipe stays the UTF-8 bytes written.
+//
+// The console host (conhost) only interprets the ANSI escape sequences of
+// colors, -U highlight and hyperlinks once virtual terminal processing is
+// enabled: without it they show as ←[1;32m around each string. Callers enable
+// it with VirtualTerminal when they write such sequences, so plain output
+// leaves the console mode alone. Terminals of other platforms, and Windows
+// Terminal, interpret them anyway.
+package console
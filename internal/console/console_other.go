@@ -0,0 +1,9 @@
+//go:build !windows
+
+package console
+
+// VirtualTerminal reports whether stdout interprets ANSI escape sequences
+// when it is a terminal: terminals outside of Windows always do
+func VirtualTerminal() bool {
+	return true
+}
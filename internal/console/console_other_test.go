@@ -0,0 +1,31 @@
+//go:build !windows
+
+package console
+
+import (
+	"os"
+	"testing"
+)
+
+// TestVirtualTerminal tests that terminals outside of Windows are reported
+// as interpreting escape sequences, without touching stdout
+func TestVirtualTerminal(t *testing.T) {
+	stdout := os.Stdout
+	defer func() { os.Stdout = stdout }()
+
+	// Whatever stdout is, a file here, nothing is done with it
+	file, err := os.CreateTemp(t.TempDir(), "stdout")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = file.Close() }()
+	os.Stdout = file
+	for range 2 {
+		if !VirtualTerminal() {
+			t.Error("VirtualTerminal() = false, want true")
+		}
+	}
+	if info, err := file.Stat(); err != nil || info.Size() != 0 {
+		t.Errorf("stdout after VirtualTerminal() = %v, %v, want an empty file", info, err)
+	}
+}
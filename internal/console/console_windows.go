@@ -0,0 +1,41 @@
+//go:build windows
+
+package console
+
+import (
+	"os"
+	"sync"
+	"syscall"
+)
+
+// enableVirtualTerminalProcessing is ENABLE_VIRTUAL_TERMINAL_PROCESSING, the
+// console mode interpreting ANSI escape sequences (Windows 10 and later)
+const enableVirtualTerminalProcessing = 0x0004
+
+var (
+	kernel32       = syscall.NewLazyDLL("kernel32.dll")
+	setConsoleMode = kernel32.NewProc("SetConsoleMode")
+)
+
+// VirtualTerminal makes the console of stdout interpret ANSI escape
+// sequences, reporting whether it does: false on Windows versions before 10,
+// and if stdout is a file, a pipe or the NUL device. The console mode is only
+// changed on the first call, and stays enabled after txtr exits like in
+// Windows Terminal.
+func VirtualTerminal() bool {
+	return virtualTerminal()
+}
+
+// virtualTerminal enables escape sequences on the console of stdout once
+var virtualTerminal = sync.OnceValue(func() bool {
+	handle := syscall.Handle(os.Stdout.Fd())
+	var mode uint32
+	if err := syscall.GetConsoleMode(handle, &mode); err != nil {
+		return false
+	}
+	if mode&enableVirtualTerminalProcessing != 0 {
+		return true
+	}
+	ok, _, _ := setConsoleMode.Call(uintptr(handle), uintptr(mode|enableVirtualTerminalProcessing))
+	return ok != 0
+})
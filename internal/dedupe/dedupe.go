@@ -0,0 +1,108 @@
+// Package dedupe finds input files with the same content, so that a corpus
+// holding the same sample under several names is scanned once per content
+// (--dedupe-inputs). Files are compared by size first, then by a hash of
+// their first PrefixSize bytes, and only files still alike are hashed in
+// full.
+package dedupe
+
+import (
+	"crypto/sha256"
+	"io"
+	"os"
+)
+
+// PrefixSize is the number of leading bytes hashed to tell files of the same
+// size apart before hashing them in full
+const PrefixSize = 64 << 10
+
+// Files groups paths by content. It returns the first path of each distinct
+// content, in input order, and the later paths with the same content as
+// each (duplicates[first]). Paths that are not regular files or cannot be
+// read are kept, so their scan reports the problem.
+func Files(paths []string) (unique []string, duplicates map[string][]string) {
+	// Only files sharing their size can be duplicates
+	sizes := make([]int64, len(paths))
+	bySize := make(map[int64]int)
+	for i, path := range paths {
+		sizes[i] = -1
+		if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+			sizes[i] = info.Size()
+			bySize[sizes[i]]++
+		}
+	}
+
+	// Hash the prefix of candidates, then the whole of those still alike
+	keys := make([]string, len(paths))
+	byPrefix := make(map[string]int)
+	for i, path := range paths {
+		if sizes[i] < 0 || bySize[sizes[i]] < 2 {
+			continue
+		}
+		if sum, err := hashFile(path, PrefixSize); err == nil {
+			keys[i] = string(sum)
+			byPrefix[keys[i]]++
+		}
+	}
+	for i, path := range paths {
+		switch {
+		case keys[i] == "":
+			continue
+		case byPrefix[keys[i]] < 2:
+			keys[i] = "" // Unique
+			continue
+		case sizes[i] <= PrefixSize:
+			continue // The prefix is the whole file
+		}
+		sum, err := hashFile(path, -1)
+		if err != nil {
+			keys[i] = ""
+			continue
+		}
+		keys[i] = string(sum)
+	}
+
+	first := make(map[content]string)
+	for i, path := range paths {
+		if keys[i] == "" {
+			unique = append(unique, path)
+			continue
+		}
+		key := content{size: sizes[i], sum: keys[i]}
+		if original, ok := first[key]; ok {
+			if duplicates == nil {
+				duplicates = make(map[string][]string)
+			}
+			duplicates[original] = append(duplicates[original], path)
+			continue
+		}
+		first[key] = path
+		unique = append(unique, path)
+	}
+	return unique, duplicates
+}
+
+// content identifies the content of a file
+type content struct {
+	size int64
+	sum  string // SHA-256 of the whole file
+}
+
+// hashFile returns the SHA-256 of the first limit bytes of path (all of it
+// if limit is negative)
+func hashFile(path string, limit int64) ([]byte, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+
+	var reader io.Reader = file
+	if limit >= 0 {
+		reader = io.LimitReader(file, limit)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, reader); err != nil {
+		return nil, err
+	}
+	return hash.Sum(nil), nil
+}
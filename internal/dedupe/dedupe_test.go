@@ -0,0 +1,59 @@
+package dedupe
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+// TestFiles tests grouping inputs by content
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	large := bytes.Repeat([]byte("x"), PrefixSize+10)
+	largeTail := slices.Clone(large)
+	largeTail[len(largeTail)-1] = 'y'
+	contents := map[string][]byte{
+		"a":       []byte("sample"),
+		"a-copy":  []byte("sample"),
+		"b":       []byte("sampel"), // Same size, other content
+		"c":       []byte("other content"),
+		"big":     large,
+		"big-tip": largeTail, // Same prefix, other content
+		"big-2":   large,
+		"empty":   nil,
+		"empty-2": nil,
+	}
+	for name, data := range contents {
+		if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := func(names ...string) []string {
+		paths := make([]string, len(names))
+		for i, name := range names {
+			paths[i] = filepath.Join(dir, name)
+		}
+		return paths
+	}
+
+	// Directories are kept for the scan to report
+	unique, duplicates := Files(path("a", "b", "missing", "a-copy", "big", "c", "big-tip", "big-2", "a", ".", "empty", "empty-2"))
+	if want := path("a", "b", "missing", "big", "c", "big-tip", ".", "empty"); !slices.Equal(unique, want) {
+		t.Errorf("unique = %q, want %q", unique, want)
+	}
+	wantDuplicates := map[string][]string{
+		filepath.Join(dir, "a"):     path("a-copy", "a"),
+		filepath.Join(dir, "big"):   path("big-2"),
+		filepath.Join(dir, "empty"): path("empty-2"),
+	}
+	if !reflect.DeepEqual(duplicates, wantDuplicates) {
+		t.Errorf("duplicates = %q, want %q", duplicates, wantDuplicates)
+	}
+
+	if unique, duplicates := Files(path("a", "c")); len(unique) != 2 || duplicates != nil {
+		t.Errorf("Files() without duplicates = %q, %q", unique, duplicates)
+	}
+}
@@ -0,0 +1,543 @@
+package evidence
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AFF4 defaults and limits
+const (
+	aff4ChunkSize       = 32 << 10
+	aff4ChunksPerBevy   = 1024
+	aff4MaxChunkSize    = 64 << 20
+	aff4MaxBevyInMemory = 1 << 30 // Bevies compressed by the ZIP file are read whole
+	aff4MapEntry        = 28
+	aff4IndexEntry      = 12
+)
+
+// openAFF4 reads the metadata (information.turtle) of the AFF4 volume in
+// file and opens its image: its map if it has one, which places ranges of
+// image streams (or zeros) in the media, else its image stream
+func openAFF4(file *os.File) (*Image, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(file, info.Size())
+	if err != nil {
+		return nil, ErrNotEvidence
+	}
+	v := &aff4Volume{file: file, members: make(map[string]*zip.File)}
+	var turtle *zip.File
+	for _, member := range zr.File {
+		if member.Name == "information.turtle" {
+			turtle = member
+		}
+	}
+	if turtle == nil {
+		return nil, ErrNotEvidence
+	}
+	data, err := readMember(turtle, 16<<20)
+	if err != nil {
+		return nil, fmt.Errorf("%w: information.turtle: %v", errCorrupt, err)
+	}
+	v.graph = parseTurtle(data)
+
+	// Members are named by the URNs of their objects, percent-encoded and
+	// relative to the volume's URN if they are in it
+	volumes := v.graph.subjects("ZipVolume")
+	if strings.HasPrefix(zr.Comment, "aff4:") {
+		volumes = append(volumes, strings.TrimSpace(zr.Comment))
+	}
+	for _, member := range zr.File {
+		name, err := url.PathUnescape(member.Name)
+		if err != nil {
+			name = member.Name
+		}
+		v.members[name] = member
+		for _, volume := range volumes {
+			v.members[volume+"/"+name] = member
+		}
+	}
+
+	img := &Image{Format: FormatAFF4, files: []*os.File{file}}
+	if maps := v.graph.subjects("Map"); len(maps) > 0 {
+		img.r, img.size, err = v.openMap(maps[0])
+	} else if streams := v.graph.subjects("ImageStream"); len(streams) > 0 {
+		var stream *chunkedStream
+		stream, err = v.openStream(streams[0])
+		if stream != nil {
+			img.r, img.size = stream, stream.size
+		}
+	} else {
+		err = fmt.Errorf("%w: no image stream", errCorrupt)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// aff4Volume is an AFF4 volume being read
+type aff4Volume struct {
+	file    *os.File
+	graph   turtleGraph
+	members map[string]*zip.File // By URN
+	streams map[string]*chunkedStream
+}
+
+// openStream opens the image stream urn, stored in bevies (members urn/00000000,
+// urn/00000001, ...) of chunks, each with an index (urn/00000000.index) of
+// where its chunks are
+func (v *aff4Volume) openStream(urn string) (*chunkedStream, error) {
+	if stream := v.streams[urn]; stream != nil {
+		return stream, nil
+	}
+	size, ok := v.graph.integer(urn, "size")
+	if !ok || size < 0 {
+		return nil, fmt.Errorf("%w: stream %s has no size", errCorrupt, urn)
+	}
+	chunkSize, ok := v.graph.integer(urn, "chunkSize")
+	if !ok {
+		chunkSize = aff4ChunkSize
+	}
+	perBevy, ok := v.graph.integer(urn, "chunksInSegment")
+	if !ok {
+		perBevy = aff4ChunksPerBevy
+	}
+	if chunkSize <= 0 || chunkSize > aff4MaxChunkSize || perBevy <= 0 {
+		return nil, fmt.Errorf("%w: stream %s has chunks of %d bytes, %d per bevy", errCorrupt, urn, chunkSize, perBevy)
+	}
+	decompress, err := aff4Decompressor(v.graph.value(urn, "compressionMethod"))
+	if err != nil {
+		return nil, err
+	}
+
+	// The bevy of the last chunk read
+	bevyNumber := int64(-1)
+	var bevy io.ReaderAt
+	var bevySize int64
+	var index [][2]int64 // Offset and length of each chunk
+	stream := newChunkedStream(size, chunkSize, func(i int64) ([]byte, error) {
+		if number := i / perBevy; number != bevyNumber {
+			name := fmt.Sprintf("%s/%08d", urn, number)
+			var err error
+			if bevy, bevySize, err = v.open(name); err != nil {
+				return nil, err
+			}
+			indexData, err := v.read(name+".index", 12*perBevy+12)
+			if err != nil {
+				return nil, err
+			}
+			index = aff4Index(indexData, bevySize)
+			bevyNumber = number
+		}
+		entry := i % perBevy
+		if entry >= int64(len(index)) {
+			return nil, fmt.Errorf("%w: bevy %d has no chunk %d", errCorrupt, bevyNumber, entry)
+		}
+		offset, length := index[entry][0], index[entry][1]
+		if length > 2*chunkSize+1024 {
+			return nil, fmt.Errorf("%w: chunk of %d bytes", errCorrupt, length)
+		}
+		data := make([]byte, length)
+		if _, err := bevy.ReadAt(data, offset); err != nil {
+			return nil, err
+		}
+		// Chunks that do not compress are stored as they are
+		if length == chunkSize {
+			return data, nil
+		}
+		return decompress(data, int(chunkSize))
+	})
+	if v.streams == nil {
+		v.streams = make(map[string]*chunkedStream)
+	}
+	v.streams[urn] = stream
+	return stream, nil
+}
+
+// aff4Index decodes the index of a bevy of bevySize bytes: 12-byte entries of
+// the offset and length of each chunk, or in older volumes 4-byte offsets
+func aff4Index(data []byte, bevySize int64) [][2]int64 {
+	le := binary.LittleEndian
+	if len(data)%aff4IndexEntry == 0 {
+		index := make([][2]int64, 0, len(data)/aff4IndexEntry)
+		valid := true
+		for pos := 0; pos < len(data); pos += aff4IndexEntry {
+			offset, length := int64(le.Uint64(data[pos:])), int64(le.Uint32(data[pos+8:]))
+			if offset < 0 || offset+length > bevySize {
+				valid = false
+				break
+			}
+			index = append(index, [2]int64{offset, length})
+		}
+		if valid {
+			return index
+		}
+	}
+	index := make([][2]int64, len(data)/4)
+	for i := range index {
+		index[i][0] = int64(le.Uint32(data[i*4:]))
+		end := bevySize
+		if i+1 < len(index) {
+			end = int64(le.Uint32(data[i*4+4:]))
+		}
+		index[i][1] = max(end-index[i][0], 0)
+	}
+	return index
+}
+
+// aff4Decompressor returns the function decompressing chunks compressed by
+// method, the IRI of a compression algorithm
+func aff4Decompressor(method string) (func(data []byte, size int) ([]byte, error), error) {
+	name := strings.ToLower(method)
+	switch {
+	case strings.Contains(name, "snappy"):
+		return decompressSnappy, nil
+	case strings.Contains(name, "lz4"):
+		return decompressLZ4, nil
+	case strings.Contains(name, "rfc1950") || strings.Contains(name, "zlib"):
+		return func(data []byte, size int) ([]byte, error) {
+			zr, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			return readLimited(zr, size)
+		}, nil
+	case strings.Contains(name, "rfc1951") || strings.Contains(name, "deflate"):
+		return func(data []byte, size int) ([]byte, error) {
+			return readLimited(flate.NewReader(bytes.NewReader(data)), size)
+		}, nil
+	case name == "" || strings.Contains(name, "null") || strings.Contains(name, "stored"):
+		return func(data []byte, _ int) ([]byte, error) { return data, nil }, nil
+	}
+	return nil, fmt.Errorf("unsupported AFF4 compression %s", method)
+}
+
+// readLimited reads r up to size bytes
+func readLimited(r io.Reader, size int) ([]byte, error) {
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(io.LimitReader(r, int64(size))); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// open returns a reader of the member named by urn. Members stored
+// uncompressed, as bevies usually are, are read in place.
+func (v *aff4Volume) open(urn string) (io.ReaderAt, int64, error) {
+	member := v.members[urn]
+	if member == nil {
+		return nil, 0, fmt.Errorf("%w: no member %s", errCorrupt, urn)
+	}
+	size := int64(member.UncompressedSize64)
+	if member.Method == zip.Store {
+		offset, err := member.DataOffset()
+		if err != nil {
+			return nil, 0, err
+		}
+		return io.NewSectionReader(v.file, offset, size), size, nil
+	}
+	data, err := readMember(member, aff4MaxBevyInMemory)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(data), int64(len(data)), nil
+}
+
+// read reads the member named by urn, up to limit bytes
+func (v *aff4Volume) read(urn string, limit int64) ([]byte, error) {
+	member := v.members[urn]
+	if member == nil {
+		return nil, fmt.Errorf("%w: no member %s", errCorrupt, urn)
+	}
+	return readMember(member, limit)
+}
+
+// readMember reads a ZIP member, up to limit bytes
+func readMember(member *zip.File, limit int64) ([]byte, error) {
+	rc, err := member.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rc.Close() }()
+	var buf bytes.Buffer
+	_, err = buf.ReadFrom(io.LimitReader(rc, limit))
+	return buf.Bytes(), err
+}
+
+// aff4Target is a range of the media of a map, read from a target
+type aff4Target struct {
+	mapped, length, offset int64
+	r                      io.ReaderAt // nil for zeros
+	fill                   byte        // Byte of symbolic streams without r
+}
+
+// aff4Map is media made of ranges of other streams, sorted by offset. Gaps
+// read as zeros.
+type aff4Map struct {
+	ranges []aff4Target
+}
+
+// openMap opens the map urn: its map member lists the ranges of the media,
+// each the offset and length in the media, the offset in its target and the
+// number of the target in its idx member, which lists the target URNs
+func (v *aff4Volume) openMap(urn string) (io.ReaderAt, int64, error) {
+	entries, err := v.read(urn+"/map", 1<<30)
+	if err != nil {
+		return nil, 0, err
+	}
+	idx, err := v.read(urn+"/idx", 16<<20)
+	if err != nil {
+		return nil, 0, err
+	}
+	targets := strings.Fields(string(idx))
+
+	le := binary.LittleEndian
+	m := &aff4Map{}
+	size, hasSize := v.graph.integer(urn, "size")
+	end := int64(0)
+	for pos := 0; pos+aff4MapEntry <= len(entries); pos += aff4MapEntry {
+		r := aff4Target{
+			mapped: int64(le.Uint64(entries[pos:])),
+			length: int64(le.Uint64(entries[pos+8:])),
+			offset: int64(le.Uint64(entries[pos+16:])),
+		}
+		number := int(le.Uint32(entries[pos+24:]))
+		if r.mapped < 0 || r.length <= 0 || r.offset < 0 || number >= len(targets) {
+			continue
+		}
+		target := targets[number]
+		switch local := localName(target); {
+		case strings.HasPrefix(local, "SymbolicStream"):
+			if b, err := strconv.ParseUint(strings.TrimPrefix(local, "SymbolicStream"), 16, 8); err == nil {
+				r.fill = byte(b)
+			}
+		case local == "Zero" || local == "UnknownData" || local == "UnreadableData":
+		default:
+			stream, err := v.openStream(target)
+			if err != nil {
+				return nil, 0, err
+			}
+			r.r = stream
+		}
+		m.ranges = append(m.ranges, r)
+		end = max(end, r.mapped+r.length)
+	}
+	sort.Slice(m.ranges, func(i, j int) bool { return m.ranges[i].mapped < m.ranges[j].mapped })
+	if !hasSize {
+		size = end
+	}
+	return m, size, nil
+}
+
+// ReadAt reads the media of the map at offset off
+func (m *aff4Map) ReadAt(p []byte, off int64) (int, error) {
+	clear(p)
+	end := off + int64(len(p))
+	i := sort.Search(len(m.ranges), func(i int) bool { return m.ranges[i].mapped+m.ranges[i].length > off })
+	for ; i < len(m.ranges) && m.ranges[i].mapped < end; i++ {
+		r := m.ranges[i]
+		from, to := max(off, r.mapped), min(end, r.mapped+r.length)
+		dst := p[from-off : to-off]
+		if r.r == nil {
+			for j := range dst {
+				dst[j] = r.fill
+			}
+			continue
+		}
+		if n, err := r.r.ReadAt(dst, r.offset+from-r.mapped); err != nil && !(err == io.EOF && n == len(dst)) {
+			return int(from - off), err
+		}
+	}
+	return len(p), nil
+}
+
+// turtleGraph is what an RDF Turtle document says about its subjects: the
+// values of each property, keyed by the local name of the property (after
+// its namespace). Values are IRIs or the text of literals.
+type turtleGraph map[string]map[string][]string
+
+// subjects returns the subjects of the type named kind (a local name), sorted
+func (g turtleGraph) subjects(kind string) []string {
+	var found []string
+	for subject, properties := range g {
+		for _, t := range properties["type"] {
+			if localName(t) == kind {
+				found = append(found, subject)
+				break
+			}
+		}
+	}
+	sort.Strings(found)
+	return found
+}
+
+// value returns the first value of a property of subject, or ""
+func (g turtleGraph) value(subject, property string) string {
+	if values := g[subject][property]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// integer returns the first value of a property of subject as an integer
+func (g turtleGraph) integer(subject, property string) (int64, bool) {
+	n, err := strconv.ParseInt(g.value(subject, property), 10, 64)
+	return n, err == nil
+}
+
+// localName returns the part of an IRI after its namespace (# or the last /)
+func localName(iri string) string {
+	return iri[strings.LastIndexAny(iri, "#/")+1:]
+}
+
+// parseTurtle reads the triples of an RDF Turtle document, as written by AFF4
+// tools: @prefix directives and statements of a subject followed by
+// properties separated by ";", each with values separated by ",". Blank nodes
+// and collections are skipped.
+func parseTurtle(data []byte) turtleGraph {
+	tokens := turtleTokens(string(data))
+	prefixes := map[string]string{}
+	resolve := func(token string) string {
+		switch {
+		case strings.HasPrefix(token, "<"):
+			return strings.TrimSuffix(token[1:], ">")
+		case strings.HasPrefix(token, `"`):
+			return token[1:]
+		case token == "a":
+			return "http://www.w3.org/1999/02/22-rdf-syntax-ns#type"
+		}
+		if prefix, local, ok := strings.Cut(token, ":"); ok {
+			if ns, ok := prefixes[prefix]; ok {
+				return ns + local
+			}
+		}
+		return token
+	}
+
+	g := turtleGraph{}
+	for i := 0; i < len(tokens); {
+		if tokens[i] == "@prefix" || strings.EqualFold(tokens[i], "prefix") {
+			if i+2 < len(tokens) {
+				prefixes[strings.TrimSuffix(tokens[i+1], ":")] = resolve(tokens[i+2])
+			}
+			i += 3
+			if i < len(tokens) && tokens[i] == "." {
+				i++
+			}
+			continue
+		}
+		subject := resolve(tokens[i])
+		properties := g[subject]
+		if properties == nil {
+			properties = map[string][]string{}
+			g[subject] = properties
+		}
+		i++
+		for i+1 < len(tokens) && tokens[i] != "." {
+			property := localName(resolve(tokens[i]))
+			i++
+			for i < len(tokens) {
+				properties[property] = append(properties[property], resolve(tokens[i]))
+				i++
+				if i >= len(tokens) || tokens[i] != "," {
+					break
+				}
+				i++
+			}
+			if i < len(tokens) && tokens[i] == ";" {
+				i++
+			}
+		}
+		i++
+	}
+	return g
+}
+
+// turtleTokens splits a Turtle document into tokens: IRIs with their angle
+// brackets, literals as `"` and their unescaped text (datatypes and language
+// tags dropped), punctuation and names
+func turtleTokens(s string) []string {
+	var tokens []string
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			i++
+		case c == '#':
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+		case c == '<':
+			end := strings.IndexByte(s[i:], '>')
+			if end < 0 {
+				return tokens
+			}
+			tokens = append(tokens, s[i:i+end+1])
+			i += end + 1
+		case c == '"' || c == '\'':
+			var text strings.Builder
+			i++
+			for i < len(s) && s[i] != c {
+				if s[i] == '\\' && i+1 < len(s) {
+					i++
+				}
+				text.WriteByte(s[i])
+				i++
+			}
+			i++
+			// Datatype or language tag
+			for i < len(s) && (s[i] == '^' || s[i] == '@') {
+				for i < len(s) && !strings.ContainsRune(" \t\r\n;,", rune(s[i])) && !(s[i] == '.' && (i+1 == len(s) || strings.ContainsRune(" \t\r\n", rune(s[i+1])))) {
+					if s[i] == '<' {
+						i += max(strings.IndexByte(s[i:], '>'), 0)
+					}
+					i++
+				}
+			}
+			tokens = append(tokens, `"`+text.String())
+		case c == '[' || c == '(':
+			// Blank nodes and collections: skip to the matching bracket
+			depth := 0
+			for ; i < len(s); i++ {
+				if s[i] == '[' || s[i] == '(' {
+					depth++
+				} else if s[i] == ']' || s[i] == ')' {
+					depth--
+					if depth == 0 {
+						i++
+						break
+					}
+				}
+			}
+			tokens = append(tokens, `"`)
+		case c == ';' || c == ',':
+			tokens = append(tokens, string(c))
+			i++
+		default:
+			start := i
+			for i < len(s) && !strings.ContainsRune(" \t\r\n;,<\"", rune(s[i])) {
+				i++
+			}
+			word := s[start:i]
+			if strings.HasSuffix(word, ".") && word != "." {
+				tokens = append(tokens, strings.TrimSuffix(word, "."), ".")
+			} else {
+				tokens = append(tokens, word)
+			}
+		}
+	}
+	return tokens
+}
@@ -0,0 +1,166 @@
+package evidence
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testTurtle = `@prefix aff4: <http://aff4.org/Schema#> .
+@prefix xsd: <http://www.w3.org/2001/XMLSchema#> .
+
+# The stream of chunks
+<aff4://s1>
+    a aff4:ImageStream ;
+    aff4:chunkSize "16"^^xsd:int ;
+    aff4:chunksInSegment 2 ;
+    aff4:compressionMethod <https://tools.ietf.org/html/rfc1950> ;
+    aff4:size "40"^^xsd:long .
+`
+
+const testMapTurtle = `<aff4://vol/map1> a aff4:Map, aff4:Image ;
+    aff4:size 48 .
+`
+
+// testStream is the data of stream aff4://s1
+const testStream = "0123456789abcdefghijklmnopqrstuvwxyzABCD"
+
+// testAFF4 builds an AFF4 volume holding aff4://s1 in two bevies, its first
+// chunk stored as it is and the others zlib compressed, and with withMap the
+// map aff4://vol/map1 of parts of the stream, zeros and a symbolic stream
+func testAFF4(t testing.TB, withMap bool) string {
+	le := binary.LittleEndian
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name string, data []byte) {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Store})
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = w.Write(data)
+	}
+
+	turtle := testTurtle
+	if withMap {
+		turtle += testMapTurtle
+	}
+	add("information.turtle", []byte(turtle))
+	for number, chunks := range [][]string{{testStream[:16], testStream[16:32]}, {testStream[32:]}} {
+		var bevy, index []byte
+		for i, chunk := range chunks {
+			data := []byte(chunk)
+			if number > 0 || i > 0 {
+				var compressed bytes.Buffer
+				w := zlib.NewWriter(&compressed)
+				_, _ = w.Write(data)
+				_ = w.Close()
+				data = compressed.Bytes()
+			}
+			index = le.AppendUint64(index, uint64(len(bevy)))
+			index = le.AppendUint32(index, uint32(len(data)))
+			bevy = append(bevy, data...)
+		}
+		name := "aff4%3A%2F%2Fs1/0000000" + string(rune('0'+number))
+		add(name, bevy)
+		add(name+".index", index)
+	}
+	if withMap {
+		var entries []byte
+		for _, e := range [][4]uint64{{0, 16, 16, 0}, {16, 8, 0, 1}, {24, 8, 0, 2}, {32, 16, 0, 0}} {
+			for _, field := range e[:3] {
+				entries = le.AppendUint64(entries, field)
+			}
+			entries = le.AppendUint32(entries, uint32(e[3]))
+		}
+		add("map1/map", entries)
+		add("map1/idx", []byte("aff4://s1\nhttp://aff4.org/Schema#Zero\nhttp://aff4.org/Schema#SymbolicStream41\n"))
+	}
+	if err := zw.SetComment("aff4://vol"); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "image.aff4")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestOpenAFF4 tests reading the media of an image stream and of a map
+func TestOpenAFF4(t *testing.T) {
+	tests := []struct {
+		name    string
+		withMap bool
+		want    string
+	}{
+		{"stream", false, testStream},
+		{"map", true, testStream[16:32] + "\x00\x00\x00\x00\x00\x00\x00\x00AAAAAAAA" + testStream[:16]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img, err := Open(testAFF4(t, tt.withMap))
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer func() { _ = img.Close() }()
+			if img.Format != FormatAFF4 {
+				t.Errorf("Format = %s, want %s", img.Format, FormatAFF4)
+			}
+			got, err := io.ReadAll(io.NewSectionReader(img, 0, img.Size()))
+			if err != nil || string(got) != tt.want {
+				t.Errorf("media = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+
+	// ZIP files without AFF4 metadata are not evidence
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	w, _ := zw.Create("readme.txt")
+	_, _ = w.Write([]byte("hello"))
+	_ = zw.Close()
+	path := filepath.Join(t.TempDir(), "plain.zip")
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); !errors.Is(err, ErrNotEvidence) {
+		t.Errorf("Open(zip) error = %v, want %v", err, ErrNotEvidence)
+	}
+}
+
+// TestDecompress tests decoding Snappy and LZ4 blocks with literals and
+// matches overlapping their own output
+func TestDecompress(t *testing.T) {
+	tests := []struct {
+		name       string
+		decompress func([]byte, int) ([]byte, error)
+		block      []byte
+		want       string
+	}{
+		// Literal "abc", then copies of 9 bytes from 3 back (2-byte offset)
+		// and 4 bytes from 1 back (1-byte offset)
+		{"snappy", decompressSnappy, []byte{16, 2 << 2, 'a', 'b', 'c', 8<<2 | 2, 3, 0, 0<<2 | 1, 1}, "abcabcabcabccccc"},
+		// Literal "abc" with a match of 9 bytes from 3 back, then literal "xyz"
+		{"lz4", decompressLZ4, []byte{3<<4 | 5, 'a', 'b', 'c', 3, 0, 3 << 4, 'x', 'y', 'z'}, "abcabcabcabcxyz"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.decompress(tt.block, 64)
+			if err != nil || string(got) != tt.want {
+				t.Errorf("decompress() = %q, %v, want %q", got, err, tt.want)
+			}
+			if _, err := tt.decompress(tt.block[:len(tt.block)-2], 64); !errors.Is(err, errCorrupt) {
+				t.Errorf("decompress(truncated) error = %v, want %v", err, errCorrupt)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package evidence
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// decompressSnappy decodes a Snappy block (not the framed format), of up to
+// size bytes
+func decompressSnappy(src []byte, size int) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 || length > uint64(size) {
+		return nil, fmt.Errorf("%w: snappy block of %d bytes", errCorrupt, length)
+	}
+	dst := make([]byte, 0, length)
+	for pos := n; pos < len(src); {
+		tag := src[pos]
+		pos++
+		var literal, copyLength, offset int
+		switch tag & 3 {
+		case 0:
+			literal = int(tag>>2) + 1
+			if extra := literal - 60; extra > 0 {
+				if pos+extra > len(src) {
+					return nil, fmt.Errorf("%w: truncated snappy literal", errCorrupt)
+				}
+				literal = 0
+				for i := extra - 1; i >= 0; i-- {
+					literal = literal<<8 | int(src[pos+i])
+				}
+				literal++
+				pos += extra
+			}
+		case 1:
+			if pos+1 > len(src) {
+				return nil, fmt.Errorf("%w: truncated snappy copy", errCorrupt)
+			}
+			copyLength = 4 + int(tag>>2&7)
+			offset = int(tag&0xe0)<<3 | int(src[pos])
+			pos++
+		case 2:
+			if pos+2 > len(src) {
+				return nil, fmt.Errorf("%w: truncated snappy copy", errCorrupt)
+			}
+			copyLength = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[pos:]))
+			pos += 2
+		case 3:
+			if pos+4 > len(src) {
+				return nil, fmt.Errorf("%w: truncated snappy copy", errCorrupt)
+			}
+			copyLength = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[pos:]))
+			pos += 4
+		}
+		if literal > 0 {
+			if literal > len(src)-pos || len(dst)+literal > int(length) {
+				return nil, fmt.Errorf("%w: snappy literal overruns the block", errCorrupt)
+			}
+			dst = append(dst, src[pos:pos+literal]...)
+			pos += literal
+			continue
+		}
+		if offset <= 0 || offset > len(dst) || len(dst)+copyLength > int(length) {
+			return nil, fmt.Errorf("%w: snappy copy overruns the block", errCorrupt)
+		}
+		dst = appendMatch(dst, offset, copyLength)
+	}
+	if len(dst) != int(length) {
+		return nil, fmt.Errorf("%w: snappy block is %d bytes, want %d", errCorrupt, len(dst), length)
+	}
+	return dst, nil
+}
+
+// decompressLZ4 decodes an LZ4 block (not the frame format), of up to size
+// bytes
+func decompressLZ4(src []byte, size int) ([]byte, error) {
+	dst := make([]byte, 0, size)
+	// lz4Length extends a length of 15 with the bytes after it
+	lz4Length := func(length int, pos *int) (int, error) {
+		if length != 15 {
+			return length, nil
+		}
+		for {
+			if *pos >= len(src) {
+				return 0, fmt.Errorf("%w: truncated LZ4 length", errCorrupt)
+			}
+			b := src[*pos]
+			*pos++
+			length += int(b)
+			if b != 255 {
+				return length, nil
+			}
+		}
+	}
+
+	for pos := 0; pos < len(src); {
+		token := src[pos]
+		pos++
+		literal, err := lz4Length(int(token>>4), &pos)
+		if err != nil {
+			return nil, err
+		}
+		if literal > len(src)-pos || len(dst)+literal > size {
+			return nil, fmt.Errorf("%w: LZ4 literal overruns the block", errCorrupt)
+		}
+		dst = append(dst, src[pos:pos+literal]...)
+		pos += literal
+		// The last sequence has literals only
+		if pos == len(src) {
+			break
+		}
+		if pos+2 > len(src) {
+			return nil, fmt.Errorf("%w: truncated LZ4 match", errCorrupt)
+		}
+		offset := int(binary.LittleEndian.Uint16(src[pos:]))
+		pos += 2
+		length, err := lz4Length(int(token&15), &pos)
+		if err != nil {
+			return nil, err
+		}
+		length += 4
+		if offset == 0 || offset > len(dst) || len(dst)+length > size {
+			return nil, fmt.Errorf("%w: LZ4 match overruns the block", errCorrupt)
+		}
+		dst = appendMatch(dst, offset, length)
+	}
+	return dst, nil
+}
+
+// appendMatch appends length bytes copied from offset bytes back in dst,
+// which may overlap the bytes appended
+func appendMatch(dst []byte, offset, length int) []byte {
+	start := len(dst) - offset
+	for i := range length {
+		dst = append(dst, dst[start+i])
+	}
+	return dst
+}
@@ -0,0 +1,153 @@
+// Package evidence reads the media held by forensic evidence containers,
+// EnCase (EWF, .E01) images and AFF4 volumes, decompressing their chunks as
+// they are read, so the media can be scanned without converting it to a raw
+// image first.
+package evidence
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+// Formats reported in Image.Format
+const (
+	FormatEWF  = "ewf"
+	FormatAFF4 = "aff4"
+)
+
+// ErrNotEvidence is returned for files that are not evidence containers
+var ErrNotEvidence = errors.New("not an evidence container")
+
+var errCorrupt = errors.New("corrupt evidence container")
+
+// ewfSignature starts each segment file of an EWF image
+const ewfSignature = "EVF\x09\x0d\x0a\xff\x00"
+
+// Detect returns the format of the container starting with header, at least
+// its first 8 bytes, or "" if it is none. AFF4 volumes are ZIP files, so a
+// ZIP header is reported as FormatAFF4 until Open finds its metadata.
+func Detect(header []byte) string {
+	switch {
+	case len(header) >= 8 && string(header[:8]) == ewfSignature:
+		return FormatEWF
+	case len(header) >= 4 && string(header[:4]) == "PK\x03\x04":
+		return FormatAFF4
+	}
+	return ""
+}
+
+// Image is the media of an evidence container. It reads the container's
+// chunks as they are needed, keeping the last one decompressed, so reading
+// it in order decompresses each chunk once.
+type Image struct {
+	Format string // FormatEWF or FormatAFF4
+	r      io.ReaderAt
+	size   int64
+	files  []*os.File
+}
+
+// Open opens the evidence container at path. An EWF image is opened by its
+// first segment (.E01), from which the other segments (.E02, ...) are found.
+// It returns ErrNotEvidence for other files, including ZIP files that are not
+// AFF4 volumes.
+func Open(path string) (*Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 8)
+	n, _ := io.ReadFull(file, header)
+
+	var img *Image
+	switch Detect(header[:n]) {
+	case FormatEWF:
+		img, err = openEWF(path, file)
+	case FormatAFF4:
+		img, err = openAFF4(file)
+	default:
+		err = ErrNotEvidence
+	}
+	if err != nil {
+		_ = file.Close()
+		return nil, err
+	}
+	return img, nil
+}
+
+// Size returns the size of the media in bytes
+func (img *Image) Size() int64 {
+	return img.size
+}
+
+// ReadAt reads the media at offset off
+func (img *Image) ReadAt(p []byte, off int64) (int, error) {
+	if off >= img.size {
+		return 0, io.EOF
+	}
+	want := len(p)
+	if remaining := img.size - off; int64(want) > remaining {
+		p = p[:remaining]
+	}
+	n, err := img.r.ReadAt(p, off)
+	if err == nil && n < want {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Close closes the files of the container
+func (img *Image) Close() error {
+	var err error
+	for _, file := range img.files {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// chunkedStream is media stored in chunks of chunkSize bytes, each decoded by
+// decode. The last chunk read is cached.
+type chunkedStream struct {
+	size      int64
+	chunkSize int64
+	decode    func(index int64) ([]byte, error)
+
+	mu     sync.Mutex
+	cached int64 // Index of data, -1 if none
+	data   []byte
+}
+
+func newChunkedStream(size, chunkSize int64, decode func(index int64) ([]byte, error)) *chunkedStream {
+	return &chunkedStream{size: size, chunkSize: chunkSize, decode: decode, cached: -1}
+}
+
+// ReadAt reads the stream at offset off, decoding the chunks holding it
+func (s *chunkedStream) ReadAt(p []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= s.size {
+			return n, io.EOF
+		}
+		index := pos / s.chunkSize
+		if index != s.cached {
+			data, err := s.decode(index)
+			if err != nil {
+				return n, fmt.Errorf("chunk %d: %w", index, err)
+			}
+			s.cached, s.data = index, data
+		}
+		within := pos - index*s.chunkSize
+		if within >= int64(len(s.data)) {
+			return n, fmt.Errorf("%w: chunk %d is %d bytes, want %d", errCorrupt, index, len(s.data), min(s.chunkSize, s.size-index*s.chunkSize))
+		}
+		n += copy(p[n:], s.data[within:min(int64(len(s.data)), s.size-index*s.chunkSize)])
+	}
+	return n, nil
+}
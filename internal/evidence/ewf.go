@@ -0,0 +1,179 @@
+package evidence
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// EWF layout: a 13-byte file header, then sections of a 76-byte descriptor
+// (type, offset of the next section, size) followed by their data
+const (
+	ewfFileHeader   = 13
+	ewfDescriptor   = 76
+	ewfMaxSegments  = 14971 // .E01 to .ZZZ
+	ewfMaxChunkSize = 64 << 20
+	ewfCompressed   = 1 << 31 // Table entry flag: the chunk is zlib compressed
+	ewfTableEntries = 24      // Table section header size
+	ewfMaxTableSize = 1 << 24 // Entries read from one table section
+)
+
+// ewfChunk is where a chunk of an EWF image is
+type ewfChunk struct {
+	file       *os.File
+	offset     int64
+	compressed bool
+}
+
+// openEWF reads the sections of the segments of the EWF image whose first
+// segment is file: the volume section, which gives the media and chunk sizes,
+// and the table sections, which list the chunks in order
+func openEWF(path string, file *os.File) (*Image, error) {
+	img := &Image{Format: FormatEWF, files: []*os.File{file}}
+	le := binary.LittleEndian
+	header := make([]byte, ewfFileHeader)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return nil, fmt.Errorf("%w: %v", errCorrupt, err)
+	}
+	if segment := le.Uint16(header[9:]); segment != 1 {
+		return nil, fmt.Errorf("segment %d of an EnCase image, scan its first segment (.E01) instead", segment)
+	}
+
+	var chunks []ewfChunk
+	chunkSize, mediaSize := int64(0), int64(0)
+	for number := 1; number <= ewfMaxSegments; number++ {
+		if number > 1 {
+			next, err := os.Open(ewfSegmentPath(path, number))
+			if err != nil {
+				break
+			}
+			img.files = append(img.files, next)
+			file = next
+		}
+		last, err := readEWFSegment(file, func(kind string, data []byte) error {
+			switch kind {
+			case "volume", "disk", "data":
+				if chunkSize > 0 || len(data) < 24 {
+					return nil
+				}
+				chunkSize = int64(le.Uint32(data[8:])) * int64(le.Uint32(data[12:]))
+				mediaSize = int64(le.Uint64(data[16:])) * int64(le.Uint32(data[12:]))
+			case "table":
+				if len(data) < ewfTableEntries {
+					return fmt.Errorf("%w: short table section", errCorrupt)
+				}
+				count := int(min(le.Uint32(data), ewfMaxTableSize))
+				base := int64(le.Uint64(data[8:]))
+				for i := 0; i < count && ewfTableEntries+i*4+4 <= len(data); i++ {
+					entry := le.Uint32(data[ewfTableEntries+i*4:])
+					chunks = append(chunks, ewfChunk{file: file, offset: base + int64(entry&^ewfCompressed), compressed: entry&ewfCompressed != 0})
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			_ = img.Close()
+			return nil, err
+		}
+		if last {
+			break
+		}
+	}
+	if chunkSize <= 0 || chunkSize > ewfMaxChunkSize || mediaSize < 0 {
+		_ = img.Close()
+		return nil, fmt.Errorf("%w: no volume section", errCorrupt)
+	}
+	// The media ends with the last chunk if the volume section disagrees
+	mediaSize = min(mediaSize, int64(len(chunks))*chunkSize)
+
+	img.size = mediaSize
+	img.r = newChunkedStream(mediaSize, chunkSize, func(index int64) ([]byte, error) {
+		if index >= int64(len(chunks)) {
+			return nil, fmt.Errorf("%w: no such chunk", errCorrupt)
+		}
+		chunk := chunks[index]
+		size := min(chunkSize, mediaSize-index*chunkSize)
+		if !chunk.compressed {
+			data := make([]byte, size)
+			if _, err := chunk.file.ReadAt(data, chunk.offset); err != nil {
+				return nil, err
+			}
+			return data, nil
+		}
+		zr, err := zlib.NewReader(io.NewSectionReader(chunk.file, chunk.offset, 2*chunkSize+1024))
+		if err != nil {
+			return nil, err
+		}
+		var data bytes.Buffer
+		if _, err := data.ReadFrom(io.LimitReader(zr, chunkSize)); err != nil {
+			return nil, err
+		}
+		return data.Bytes(), nil
+	})
+	return img, nil
+}
+
+// readEWFSegment calls fn with the type and data of each section of a segment
+// file, other than the sectors sections holding the chunks. It reports whether
+// the segment is the last one (it ends with a "done" section).
+func readEWFSegment(file *os.File, fn func(kind string, data []byte) error) (bool, error) {
+	le := binary.LittleEndian
+	descriptor := make([]byte, ewfDescriptor)
+	pos := int64(ewfFileHeader)
+	for {
+		if _, err := file.ReadAt(descriptor, pos); err != nil {
+			return false, fmt.Errorf("%w: section at 0x%x: %v", errCorrupt, pos, err)
+		}
+		kind := string(bytes.TrimRight(descriptor[:16], "\x00"))
+		next, size := int64(le.Uint64(descriptor[16:])), int64(le.Uint64(descriptor[24:]))
+		switch kind {
+		case "done":
+			return true, nil
+		case "next":
+			return false, nil
+		case "volume", "disk", "data", "table":
+			if size < ewfDescriptor || size > ewfDescriptor+ewfTableEntries+4*ewfMaxTableSize+4 {
+				return false, fmt.Errorf("%w: %s section of %d bytes", errCorrupt, kind, size)
+			}
+			data := make([]byte, size-ewfDescriptor)
+			if _, err := file.ReadAt(data, pos+ewfDescriptor); err != nil {
+				return false, fmt.Errorf("%w: %s section: %v", errCorrupt, kind, err)
+			}
+			if err := fn(kind, data); err != nil {
+				return false, err
+			}
+		}
+		if next <= pos {
+			return false, fmt.Errorf("%w: section at 0x%x has no next section", errCorrupt, pos)
+		}
+		pos = next
+	}
+}
+
+// ewfSegmentPath returns the path of segment number of the image whose first
+// segment is path: .E01 to .E99, then .EAA to .EZZ, .FAA and so on, in the
+// case of the first segment's extension
+func ewfSegmentPath(path string, number int) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	first := byte('E')
+	if len(ext) == 4 {
+		first = ext[1]
+	}
+	var suffix string
+	if number < 100 {
+		suffix = fmt.Sprintf("%c%02d", first, number)
+	} else {
+		n := number - 100
+		suffix = string([]byte{first + byte(n/676), 'A' + byte(n/26%26), 'A' + byte(n%26)})
+	}
+	if len(ext) == 4 && ext[1] >= 'a' && ext[1] <= 'z' {
+		suffix = strings.ToLower(suffix)
+	}
+	return base + "." + suffix
+}
@@ -0,0 +1,145 @@
+package evidence
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// Geometry of the test images: chunks of 4 sectors of 16 bytes
+const (
+	testSectorSize      = 16
+	testSectorsPerChunk = 4
+)
+
+// ewfSection encodes a section descriptor of kind at pos followed by data
+func ewfSection(kind string, pos int, data []byte, last bool) []byte {
+	le := binary.LittleEndian
+	section := make([]byte, ewfDescriptor, ewfDescriptor+len(data))
+	copy(section, kind)
+	next := pos + ewfDescriptor + len(data)
+	if last {
+		next = pos
+	}
+	le.PutUint64(section[16:], uint64(next))
+	le.PutUint64(section[24:], uint64(ewfDescriptor+len(data)))
+	return append(section, data...)
+}
+
+// ewfSegment encodes segment number of an EWF image holding chunks, each
+// compressed if its flag is set, with a volume section in the first segment
+func ewfSegment(number int, sectors uint64, chunks [][]byte, compressed []bool, last bool) []byte {
+	le := binary.LittleEndian
+	segment := []byte(ewfSignature)
+	segment = append(segment, 1)
+	segment = le.AppendUint16(segment, uint16(number))
+	segment = append(segment, 0, 0)
+
+	if number == 1 {
+		volume := make([]byte, 1052)
+		le.PutUint32(volume[8:], testSectorsPerChunk)
+		le.PutUint32(volume[12:], testSectorSize)
+		le.PutUint64(volume[16:], sectors)
+		segment = append(segment, ewfSection("volume", len(segment), volume, false)...)
+	}
+
+	var data []byte
+	table := make([]byte, ewfTableEntries)
+	le.PutUint32(table, uint32(len(chunks)))
+	sectorsStart := len(segment) + ewfDescriptor
+	le.PutUint64(table[8:], uint64(sectorsStart))
+	for i, chunk := range chunks {
+		entry := uint32(len(data))
+		if compressed[i] {
+			var buf bytes.Buffer
+			zw := zlib.NewWriter(&buf)
+			_, _ = zw.Write(chunk)
+			_ = zw.Close()
+			data = append(data, buf.Bytes()...)
+			entry |= ewfCompressed
+		} else {
+			data = append(data, chunk...)
+			data = append(data, 0, 0, 0, 0) // Adler-32
+		}
+		table = le.AppendUint32(table, entry)
+	}
+	segment = append(segment, ewfSection("sectors", len(segment), data, false)...)
+	segment = append(segment, ewfSection("table", len(segment), table, false)...)
+	kind := "next"
+	if last {
+		kind = "done"
+	}
+	return append(segment, ewfSection(kind, len(segment), nil, true)...)
+}
+
+// TestOpenEWF tests reading the media of an image split across two segments,
+// with compressed and uncompressed chunks and a short last chunk
+func TestOpenEWF(t *testing.T) {
+	media := []byte(strings.Repeat("0123456789abcdef", 10)) // 10 sectors
+	dir := t.TempDir()
+	first := filepath.Join(dir, "disk.E01")
+	segments := map[string][]byte{
+		first:                          ewfSegment(1, 10, [][]byte{media[:64], media[64:128]}, []bool{true, false}, false),
+		filepath.Join(dir, "disk.E02"): ewfSegment(2, 0, [][]byte{media[128:]}, []bool{true}, true),
+	}
+	for path, data := range segments {
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	img, err := Open(first)
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer func() { _ = img.Close() }()
+	if img.Format != FormatEWF || img.Size() != int64(len(media)) {
+		t.Errorf("Open() = %s of %d bytes, want %s of %d", img.Format, img.Size(), FormatEWF, len(media))
+	}
+	got, err := io.ReadAll(io.NewSectionReader(img, 0, img.Size()))
+	if err != nil || !bytes.Equal(got, media) {
+		t.Errorf("media = %q, %v, want %q", got, err, media)
+	}
+	part := make([]byte, 20)
+	if n, err := img.ReadAt(part, 60); err != nil || string(part[:n]) != string(media[60:80]) {
+		t.Errorf("ReadAt(60) = %q, %v, want %q", part[:n], err, media[60:80])
+	}
+
+	if _, err := Open(filepath.Join(dir, "disk.E02")); err == nil || errors.Is(err, ErrNotEvidence) {
+		t.Errorf("Open(second segment) error = %v, want a segment error", err)
+	}
+	plain := filepath.Join(dir, "plain.bin")
+	if err := os.WriteFile(plain, []byte("just text"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(plain); !errors.Is(err, ErrNotEvidence) {
+		t.Errorf("Open(plain) error = %v, want %v", err, ErrNotEvidence)
+	}
+}
+
+// TestEWFSegmentPath tests the names of the segments after the first
+func TestEWFSegmentPath(t *testing.T) {
+	tests := []struct {
+		path   string
+		number int
+		want   string
+	}{
+		{"disk.E01", 2, "disk.E02"},
+		{"disk.E01", 99, "disk.E99"},
+		{"disk.E01", 100, "disk.EAA"},
+		{"disk.E01", 126, "disk.EBA"},
+		{"disk.E01", 776, "disk.FAA"},
+		{"disk.e01", 101, "disk.eab"},
+	}
+	for _, tt := range tests {
+		if got := ewfSegmentPath(tt.path, tt.number); got != tt.want {
+			t.Errorf("ewfSegmentPath(%q, %d) = %q, want %q", tt.path, tt.number, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package evidence
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// FuzzOpen tests opening evidence containers with random inputs
+func FuzzOpen(f *testing.F) {
+	// Seed corpus: the images of the Open tests (EWF in one segment, AFF4
+	// with and without a map) and bare signatures
+	media := []byte(strings.Repeat("0123456789abcdef", 10))
+	f.Add(ewfSegment(1, 10, [][]byte{media[:64], media[64:128], media[128:]}, []bool{true, false, true}, true))
+	for _, withMap := range []bool{false, true} {
+		data, err := os.ReadFile(testAFF4(f, withMap))
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte(ewfSignature))
+	f.Add([]byte("PK\x03\x04"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		// Open reads the container from its path, as the first segment of an
+		// EWF image
+		path := filepath.Join(t.TempDir(), "image.E01")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		img, err := Open(path)
+		if err != nil {
+			// Errors are expected for invalid input
+			return
+		}
+		defer func() { _ = img.Close() }()
+
+		// Invariant: the start of the media can be read up to its size
+		// (errors are expected for corrupt chunks)
+		want := min(img.Size(), 1<<20)
+		got, err := io.ReadAll(io.NewSectionReader(img, 0, want))
+		if err == nil && int64(len(got)) != want {
+			t.Errorf("read %d bytes of the media, want %d", len(got), want)
+		}
+	})
+}
+
+// FuzzDecompress tests decoding Snappy and LZ4 blocks with random inputs
+func FuzzDecompress(f *testing.F) {
+	// Seed corpus: the blocks of the Decompress tests
+	f.Add(false, []byte{16, 2 << 2, 'a', 'b', 'c', 8<<2 | 2, 3, 0, 0<<2 | 1, 1})
+	f.Add(true, []byte{3<<4 | 5, 'a', 'b', 'c', 3, 0, 3 << 4, 'x', 'y', 'z'})
+	f.Add(false, []byte{0xff, 0xff, 0xff, 0xff, 0x0f})
+	f.Add(true, []byte{0xff, 0xff, 0xff})
+
+	f.Fuzz(func(t *testing.T, lz4 bool, data []byte) {
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		decompress := decompressSnappy
+		if lz4 {
+			decompress = decompressLZ4
+		}
+		const size = 64 << 10
+		got, err := decompress(data, size)
+
+		// Invariant: blocks do not decode to more than size bytes
+		if err == nil && len(got) > size {
+			t.Errorf("decompress() = %d bytes, want at most %d", len(got), size)
+		}
+	})
+}
@@ -0,0 +1,190 @@
+// Package evtx reads Windows event logs (.evtx) record by record, so the
+// strings of each event can be reported with its record ID and time rather
+// than as UTF-16 text scanned from the middle of the binary XML around it.
+package evtx
+
+import (
+	"encoding/binary"
+	"errors"
+	"time"
+)
+
+const (
+	headerSize      = 4096  // File header block; chunks follow it
+	chunkSize       = 65536 // Each chunk holds records and their string and template tables
+	recordsStart    = 512   // Offset of the first record in a chunk
+	recordHeader    = 24    // Signature, size, record ID and time
+	maxNesting      = 32    // BinXml values nested in BinXml values
+	filetimeToEpoch = 116444736000000000
+)
+
+// BinXml tokens and value types used to find the values of an event
+const (
+	tokenTemplateInstance = 0x0c
+	tokenFragmentHeader   = 0x0f
+	typeString            = 0x01 // UTF-16
+	typeAnsiString        = 0x02
+	typeBinXML            = 0x21
+	typeArray             = 0x80 // Flag of arrays of the type in the low bits
+)
+
+// Text is a string value of an event record
+type Text struct {
+	RecordID uint64
+	Time     time.Time // When the record was written
+	Offset   int64     // File offset of Data
+	Data     []byte
+	UTF16    bool // Data is UTF-16LE; otherwise it is an ANSI string
+}
+
+// ErrNotEventLog is returned for files that do not start with an event log
+// header
+var ErrNotEventLog = errors.New("not an event log")
+
+var errUnsupported = errors.New("unsupported binary XML")
+
+// IsEventLog reports whether data starts with the "ElfFile" signature of an
+// event log
+func IsEventLog(data []byte) bool {
+	return len(data) >= 8 && string(data[:8]) == "ElfFile\x00"
+}
+
+// Walk calls fn with the string values of each record of an event log, in
+// file order: the substitution values of the event's template, which hold
+// its data (user names, command lines, paths...) without the element and
+// attribute names of the template. Records whose binary XML cannot be parsed
+// are reported whole, as UTF-16 text, so their strings are still found.
+// Chunks and records with bad headers are skipped. It stops at the first
+// error fn returns.
+func Walk(data []byte, fn func(Text) error) error {
+	if !IsEventLog(data) {
+		return ErrNotEventLog
+	}
+	for base := headerSize; base+chunkSize <= len(data); base += chunkSize {
+		chunk := data[base : base+chunkSize]
+		if string(chunk[:8]) != "ElfChnk\x00" {
+			continue
+		}
+		if err := walkChunk(chunk, int64(base), fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkChunk reports the records of a chunk at file offset base
+func walkChunk(chunk []byte, base int64, fn func(Text) error) error {
+	le := binary.LittleEndian
+	end := min(int(le.Uint32(chunk[48:])), len(chunk)) // Free space offset
+	for pos := recordsStart; pos+recordHeader+4 <= end; {
+		size := int(le.Uint32(chunk[pos+4:]))
+		if string(chunk[pos:pos+4]) != "**\x00\x00" || size < recordHeader+4 || pos+size > end {
+			break
+		}
+		r := &record{
+			chunk: chunk,
+			base:  base,
+			id:    le.Uint64(chunk[pos+8:]),
+			time:  filetime(le.Uint64(chunk[pos+16:])),
+		}
+		xmlStart, xmlEnd := pos+recordHeader, pos+size-4
+		if err := r.fragment(xmlStart, xmlEnd, 0); err != nil {
+			r.texts = nil
+			r.emit(xmlStart, xmlEnd, true)
+		}
+		for _, text := range r.texts {
+			if err := fn(text); err != nil {
+				return err
+			}
+		}
+		pos += size
+	}
+	return nil
+}
+
+// record is an event record being parsed. Positions are relative to its
+// chunk, as are the offsets of template definitions in binary XML.
+type record struct {
+	chunk []byte
+	base  int64 // File offset of the chunk
+	id    uint64
+	time  time.Time
+	texts []Text // Values found so far
+}
+
+// emit adds chunk[start:end] to the string values of the record
+func (r *record) emit(start, end int, utf16 bool) {
+	if start < end {
+		r.texts = append(r.texts, Text{RecordID: r.id, Time: r.time, Offset: r.base + int64(start), Data: r.chunk[start:end], UTF16: utf16})
+	}
+}
+
+// fragment adds the values of the binary XML fragment at chunk[pos:end]: a
+// fragment header and a template instance. Fragments that are not template
+// instances fail with errUnsupported.
+func (r *record) fragment(pos, end, depth int) error {
+	if depth > maxNesting {
+		return errUnsupported
+	}
+	if pos+4 <= end && r.chunk[pos] == tokenFragmentHeader {
+		pos += 4
+	}
+	if pos+10 > end || r.chunk[pos] != tokenTemplateInstance {
+		return errUnsupported
+	}
+
+	le := binary.LittleEndian
+	definition := int(le.Uint32(r.chunk[pos+6:]))
+	pos += 10
+	// The template is defined inline when it is first used in a chunk; it is
+	// skipped, since its text is element and attribute names
+	if definition == pos {
+		if pos+24 > end {
+			return errUnsupported
+		}
+		pos += 24 + int(le.Uint32(r.chunk[pos+20:]))
+	}
+
+	if pos+4 > end {
+		return errUnsupported
+	}
+	count := int(le.Uint32(r.chunk[pos:]))
+	descriptors := pos + 4
+	values := descriptors + count*4
+	if count < 0 || values > end {
+		return errUnsupported
+	}
+	for i := range count {
+		size := int(le.Uint16(r.chunk[descriptors+i*4:]))
+		valueType := r.chunk[descriptors+i*4+2]
+		if values+size > end {
+			return errUnsupported
+		}
+
+		switch valueType &^ typeArray {
+		case typeString:
+			r.emit(values, values+size, true)
+		case typeAnsiString:
+			r.emit(values, values+size, false)
+		case typeBinXML:
+			// Nested events (e.g. UserData) that cannot be parsed are
+			// reported whole
+			found := len(r.texts)
+			if valueType != typeBinXML || r.fragment(values, values+size, depth+1) != nil {
+				r.texts = r.texts[:found]
+				r.emit(values, values+size, true)
+			}
+		}
+		values += size
+	}
+	return nil
+}
+
+// filetime converts a Windows FILETIME (100ns intervals since 1601) to a time
+func filetime(ft uint64) time.Time {
+	if ft < filetimeToEpoch {
+		return time.Time{}
+	}
+	ns := (ft - filetimeToEpoch) * 100
+	return time.Unix(int64(ns/1e9), int64(ns%1e9)).UTC()
+}
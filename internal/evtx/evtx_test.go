@@ -0,0 +1,140 @@
+package evtx
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+	"unicode/utf16"
+)
+
+// utf16le encodes s as UTF-16LE
+func utf16le(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// testValue is a substitution value of a template instance
+type testValue struct {
+	valueType byte
+	data      []byte
+}
+
+// templateInstance encodes a fragment holding a template instance at chunk
+// offset pos, with an inline definition if inline is set
+func templateInstance(pos int, inline bool, values ...testValue) []byte {
+	le := binary.LittleEndian
+	b := []byte{tokenFragmentHeader, 1, 1, 0, tokenTemplateInstance, 1}
+	b = le.AppendUint32(b, 7) // Template ID
+	if inline {
+		b = le.AppendUint32(b, uint32(pos+len(b)+4))
+		definition := append([]byte{0x01, 0xff, 0xff}, utf16le("EventData")...) // Element names
+		b = le.AppendUint32(b, 0)                                               // Next definition
+		b = append(b, make([]byte, 16)...)                                      // GUID
+		b = le.AppendUint32(b, uint32(len(definition)))
+		b = append(b, definition...)
+	} else {
+		b = le.AppendUint32(b, 0x200)
+	}
+	b = le.AppendUint32(b, uint32(len(values)))
+	for _, v := range values {
+		b = le.AppendUint16(b, uint16(len(v.data)))
+		b = append(b, v.valueType, 0)
+	}
+	for _, v := range values {
+		b = append(b, v.data...)
+	}
+	return b
+}
+
+// testRecord encodes an event record at chunk offset pos whose binary XML is
+// built by xml from the offset it starts at
+func testRecord(pos int, id uint64, written time.Time, xml func(pos int) []byte) []byte {
+	le := binary.LittleEndian
+	body := xml(pos + recordHeader)
+	size := uint32(recordHeader + len(body) + 4)
+	b := le.AppendUint32([]byte("**\x00\x00"), size)
+	b = le.AppendUint64(b, id)
+	b = le.AppendUint64(b, uint64(written.UnixNano()/100)+filetimeToEpoch)
+	b = append(b, body...)
+	return le.AppendUint32(b, size)
+}
+
+// testLog builds an event log of one chunk holding records
+func testLog(records ...func(pos int) []byte) []byte {
+	le := binary.LittleEndian
+	chunk := make([]byte, chunkSize)
+	copy(chunk, "ElfChnk\x00")
+	pos := recordsStart
+	for _, record := range records {
+		pos += copy(chunk[pos:], record(pos))
+	}
+	le.PutUint32(chunk[48:], uint32(pos))
+
+	header := make([]byte, headerSize)
+	copy(header, "ElfFile\x00")
+	return append(header, chunk...)
+}
+
+// TestWalk tests reporting the string values of event records, including
+// values of nested events, with fallback to the whole record when its
+// binary XML cannot be parsed
+func TestWalk(t *testing.T) {
+	written := time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+	log := testLog(
+		func(pos int) []byte {
+			return testRecord(pos, 41, written, func(pos int) []byte {
+				// The nested event's template was defined by an earlier record
+				nested := templateInstance(0, false, testValue{typeString | typeArray, utf16le("first\x00second\x00")})
+				return templateInstance(pos, true,
+					testValue{typeString, utf16le("powershell.exe -enc ZQBjAGgAbwA=")},
+					testValue{0x08, []byte{4, 16, 0, 0}}, // UInt32
+					testValue{typeAnsiString, []byte("ANSI text")},
+					testValue{typeBinXML, nested},
+				)
+			})
+		},
+		func(pos int) []byte {
+			return testRecord(pos, 42, written, func(int) []byte {
+				return append([]byte{0x01}, utf16le("unparsed")...)
+			})
+		},
+	)
+
+	var texts []Text
+	if err := Walk(log, func(text Text) error {
+		texts = append(texts, text)
+		return nil
+	}); err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+
+	want := []struct {
+		id    uint64
+		data  string
+		utf16 bool
+	}{
+		{41, string(utf16le("powershell.exe -enc ZQBjAGgAbwA=")), true},
+		{41, "ANSI text", false},
+		{41, string(utf16le("first\x00second\x00")), true},
+		{42, string(append([]byte{0x01}, utf16le("unparsed")...)), true},
+	}
+	if len(texts) != len(want) {
+		t.Fatalf("Walk() = %d texts, want %d", len(texts), len(want))
+	}
+	for i, text := range texts {
+		if text.RecordID != want[i].id || string(text.Data) != want[i].data || text.UTF16 != want[i].utf16 || !text.Time.Equal(written) {
+			t.Errorf("text %d = record %d %q (utf16 %v) at %v, want record %d %q", i, text.RecordID, text.Data, text.UTF16, text.Time, want[i].id, want[i].data)
+		}
+		if got := string(log[text.Offset : text.Offset+int64(len(text.Data))]); got != string(text.Data) {
+			t.Errorf("text %d: offset 0x%x holds %q, want %q", i, text.Offset, got, text.Data)
+		}
+	}
+
+	if err := Walk([]byte("regf"), func(Text) error { return nil }); !errors.Is(err, ErrNotEventLog) {
+		t.Errorf("Walk(not a log) error = %v, want %v", err, ErrNotEventLog)
+	}
+}
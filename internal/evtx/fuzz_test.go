@@ -0,0 +1,56 @@
+package evtx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// FuzzWalk tests reading event logs with random inputs
+func FuzzWalk(f *testing.F) {
+	// Seed corpus: logs with a parsed and an unparsed record, a bare header
+	// and malformed data
+	written := time.Date(2026, 3, 14, 9, 26, 53, 0, time.UTC)
+	f.Add(testLog(func(pos int) []byte {
+		return testRecord(pos, 41, written, func(pos int) []byte {
+			nested := templateInstance(0, false, testValue{typeString | typeArray, utf16le("first\x00second\x00")})
+			return templateInstance(pos, true,
+				testValue{typeString, utf16le("powershell.exe -enc ZQBjAGgAbwA=")},
+				testValue{typeAnsiString, []byte("ANSI text")},
+				testValue{typeBinXML, nested},
+			)
+		})
+	}))
+	f.Add(testLog(func(pos int) []byte {
+		return testRecord(pos, 42, written, func(int) []byte {
+			return append([]byte{0x01}, utf16le("unparsed")...)
+		})
+	}))
+	f.Add(append([]byte("ElfFile\x00"), make([]byte, headerSize)...))
+	f.Add([]byte("ElfFile\x00"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		// Errors are expected for invalid input
+		_ = Walk(data, func(text Text) error {
+			// Invariant: each text is the data at its offset
+			if text.Offset < 0 || text.Offset+int64(len(text.Data)) > int64(len(data)) ||
+				!bytes.Equal(data[text.Offset:text.Offset+int64(len(text.Data))], text.Data) {
+				t.Errorf("Offset 0x%x does not hold %q", text.Offset, text.Data)
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,64 @@
+package extractor
+
+import "bytes"
+
+// BOM is a byte order mark, which starts text files in Unicode encodings
+type BOM struct {
+	Name     string // Name of the encoding, e.g. "UTF-16LE"
+	Mark     []byte
+	Encoding string // -e encoding of the text it starts
+	Unicode  string // -U mode of the text it starts, if one is needed
+}
+
+// boms lists the byte order marks recognized, UTF-32LE before the UTF-16LE
+// mark it starts with
+var boms = []BOM{
+	{Name: "UTF-32LE", Mark: []byte{0xFF, 0xFE, 0x00, 0x00}, Encoding: "L"},
+	{Name: "UTF-32BE", Mark: []byte{0x00, 0x00, 0xFE, 0xFF}, Encoding: "B"},
+	{Name: "UTF-8", Mark: []byte{0xEF, 0xBB, 0xBF}, Encoding: "s", Unicode: "locale"},
+	{Name: "UTF-16LE", Mark: []byte{0xFF, 0xFE}, Encoding: "l"},
+	{Name: "UTF-16BE", Mark: []byte{0xFE, 0xFF}, Encoding: "b"},
+}
+
+// maxBOMLen is the length of the longest byte order mark
+const maxBOMLen = 4
+
+// DetectBOM returns the byte order mark data starts with, if any
+func DetectBOM(data []byte) (BOM, bool) {
+	for _, bom := range boms {
+		if bytes.HasPrefix(data, bom.Mark) {
+			return bom, true
+		}
+	}
+	return BOM{}, false
+}
+
+// detectsBOM reports whether extraction with config switches encodings at a
+// byte order mark: unless disabled, only with the default encoding (an
+// explicit -e other than s is kept)
+func detectsBOM(config Config) bool {
+	return !config.NoBOMDetect && (config.Encoding == "s" || config.Encoding == "")
+}
+
+// applyBOM switches config to the encoding of the byte order mark data
+// starts with, reporting the switch as an informational warning about
+// filename, and returns the length of the mark, which is not part of any
+// string (config.BOMEnd is set to it). Without a mark, or if detection does
+// not apply, config is returned unchanged with 0.
+func applyBOM(data []byte, filename string, config Config) (Config, int) {
+	if !detectsBOM(config) {
+		return config, 0
+	}
+	bom, ok := DetectBOM(data)
+	if !ok {
+		return config, 0
+	}
+	config.Encoding = bom.Encoding
+	config.BOMEnd = int64(len(bom.Mark))
+	if bom.Unicode != "" && (config.Unicode == "" || config.Unicode == "default" || config.Unicode == "invalid") {
+		config.Unicode = bom.Unicode
+	}
+	Warn(config, Warning{Severity: SeverityInfo, Code: WarnBOM, File: filename,
+		Message: "byte order mark found, scanned as " + bom.Name + " (--no-bom-detect to keep -e s)"})
+	return config, len(bom.Mark)
+}
@@ -0,0 +1,139 @@
+package extractor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestDetectBOM(t *testing.T) {
+	tests := []struct {
+		data []byte
+		want string
+	}{
+		{[]byte{0xFF, 0xFE, 0x00, 0x00, 'a', 0, 0, 0}, "UTF-32LE"},
+		{[]byte{0x00, 0x00, 0xFE, 0xFF}, "UTF-32BE"},
+		{[]byte("\xEF\xBB\xBFtext"), "UTF-8"},
+		{[]byte{0xFF, 0xFE, 'a', 0x00}, "UTF-16LE"},
+		{[]byte{0xFE, 0xFF, 0x00, 'a'}, "UTF-16BE"},
+		{[]byte("plain text"), ""},
+		{[]byte{0xFF}, ""},
+	}
+
+	for _, tt := range tests {
+		bom, ok := DetectBOM(tt.data)
+		if ok != (tt.want != "") || bom.Name != tt.want {
+			t.Errorf("DetectBOM(%q) = %q, %v, want %q", tt.data, bom.Name, ok, tt.want)
+		}
+	}
+}
+
+// TestExtractBOM tests that text starting with a byte order mark is scanned
+// in its encoding unless detection is disabled or -e is explicit
+func TestExtractBOM(t *testing.T) {
+	data := append([]byte{0xFF, 0xFE}, utf16LE("hello world\x00second line")...)
+
+	type found struct {
+		str    string
+		offset int64
+	}
+
+	tests := []struct {
+		name     string
+		config   Config
+		want     []found
+		warnings int
+	}{
+		{"detected", Config{MinLength: 4, Encoding: "s"}, []found{{"hello world", 2}, {"second line", 26}}, 1},
+		{"no-bom-detect", Config{MinLength: 4, Encoding: "s", NoBOMDetect: true}, nil, 0},
+		{"explicit encoding", Config{MinLength: 4, Encoding: "S"}, nil, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var warnings []Warning
+			tt.config.Warnings = func(w Warning) { warnings = append(warnings, w) }
+
+			var got []found
+			err := ExtractStringsContext(context.Background(), strings.NewReader(string(data)), "notes.txt", tt.config, func(str []byte, _ string, offset int64, _ Config) {
+				got = append(got, found{string(str), offset})
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("strings = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("string %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+			if len(warnings) != tt.warnings {
+				t.Fatalf("warnings = %v, want %d", warnings, tt.warnings)
+			}
+			if tt.warnings > 0 && (warnings[0].Code != WarnBOM || warnings[0].Severity != SeverityInfo || warnings[0].File != "notes.txt") {
+				t.Errorf("warning = %+v, want info %q about notes.txt", warnings[0], WarnBOM)
+			}
+		})
+	}
+}
+
+// TestExtractBOMWideInfo tests that only the string directly after a byte
+// order mark is reported as following one
+func TestExtractBOMWideInfo(t *testing.T) {
+	data := append([]byte{0xFF, 0xFE}, utf16LE("hello world\x00second line\x00\ufeffmarked")...)
+
+	var got []bool
+	config := Config{MinLength: 4, Encoding: "s", WideInfo: true}
+	err := ExtractStringsContext(context.Background(), strings.NewReader(string(data)), "notes.txt", config, func(_ []byte, _ string, _ int64, config Config) {
+		got = append(got, config.AfterBOM)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []bool{true, false, true}
+	if !slices.Equal(got, want) {
+		t.Errorf("AfterBOM = %v, want %v", got, want)
+	}
+}
+
+// TestExtractBOMPaths tests that files and sections starting with a byte
+// order mark are scanned alike
+func TestExtractBOMPaths(t *testing.T) {
+	data := append([]byte{0xFF, 0xFE}, utf16LE("hello world")...)
+	config := Config{MinLength: 4, Encoding: "s", Warnings: func(Warning) {}}
+
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var fromFile []string
+	if err := ExtractStringsFromFile(path, config, func(str []byte, _ string, offset int64, _ Config) {
+		fromFile = append(fromFile, string(str))
+		if offset != 2 {
+			t.Errorf("file offset = %d, want 2", offset)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var fromSection []string
+	ExtractFromSection(data, ".rsrc", 0x400, "", config, func(str []byte, _ string, offset int64, _ Config) {
+		fromSection = append(fromSection, string(str))
+		if offset != 0x402 {
+			t.Errorf("section offset = 0x%x, want 0x402", offset)
+		}
+	})
+
+	if len(fromFile) != 1 || fromFile[0] != "hello world" {
+		t.Errorf("ExtractStringsFromFile() = %q, want [\"hello world\"]", fromFile)
+	}
+	if len(fromSection) != 1 || fromSection[0] != "hello world" {
+		t.Errorf("ExtractFromSection() = %q, want [\"hello world\"]", fromSection)
+	}
+}
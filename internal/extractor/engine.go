@@ -0,0 +1,296 @@
+package extractor
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/richardwooding/txtr/internal/bufpool"
+)
+
+// scanner extracts strings from input presented as consecutive chunks. The
+// streaming (io.Reader) and in-memory ([]byte) paths both drive a scanner, so
+// each encoding has a single implementation whatever the input source.
+type scanner interface {
+	// write processes data, the input starting at offset
+	write(data []byte, offset int64)
+	// end reports the last string and returns the scanner's buffers to the pool
+	end()
+}
+
+// newScanner returns the scanner for config.Encoding (7-bit ASCII if unknown),
+// or the line scanner of text inputs with config.Lines (see applyLines)
+func newScanner(filename string, config Config, printFunc func([]byte, string, int64, Config)) scanner {
+	if config.Lines {
+		return newLineScanner(filename, config, printFunc)
+	}
+	switch config.Encoding {
+	case "S": // 8-bit ASCII
+		return newASCIIScanner(filename, config, printFunc, true)
+	case "b": // 16-bit big-endian (UTF-16BE)
+		return newWideScanner(filename, config, printFunc, 2, binary.BigEndian)
+	case "l": // 16-bit little-endian (UTF-16LE)
+		return newWideScanner(filename, config, printFunc, 2, binary.LittleEndian)
+	case "B": // 32-bit big-endian (UTF-32BE)
+		return newWideScanner(filename, config, printFunc, 4, binary.BigEndian)
+	case "L": // 32-bit little-endian (UTF-32LE)
+		return newWideScanner(filename, config, printFunc, 4, binary.LittleEndian)
+	default: // 7-bit ASCII
+		return newASCIIScanner(filename, config, printFunc, false)
+	}
+}
+
+// knownEncoding reports whether newScanner supports the encoding
+func knownEncoding(encoding string) bool {
+	switch encoding {
+	case "s", "S", "b", "l", "B", "L":
+		return true
+	default:
+		return false
+	}
+}
+
+// EncodedLen returns the number of input bytes a string reported for encoding
+// occupies. Wide strings are reported as UTF-8, so their length differs from
+// len(str).
+func EncodedLen(str []byte, encoding string) int64 {
+	switch encoding {
+	case "b", "l":
+		n := int64(0)
+		for _, r := range string(str) {
+			n += int64(utf16.RuneLen(r)) * 2
+		}
+		return n
+	case "B", "L":
+		return int64(utf8.RuneCount(str)) * 4
+	default:
+		return int64(len(str))
+	}
+}
+
+// EncodeString returns the input bytes of a string reported for encoding:
+// wide strings are encoded again from the UTF-8 they are reported as (see
+// EncodedLen), others are returned as they are.
+func EncodeString(str []byte, encoding string) []byte {
+	var order binary.AppendByteOrder = binary.LittleEndian
+	if encoding == "b" || encoding == "B" {
+		order = binary.BigEndian
+	}
+	switch encoding {
+	case "b", "l":
+		encoded := make([]byte, 0, len(str)*2)
+		for _, unit := range utf16.Encode([]rune(string(str))) {
+			encoded = order.AppendUint16(encoded, unit)
+		}
+		return encoded
+	case "B", "L":
+		encoded := make([]byte, 0, len(str)*4)
+		for _, r := range string(str) {
+			encoded = order.AppendUint32(encoded, uint32(r))
+		}
+		return encoded
+	default:
+		return str
+	}
+}
+
+// scanReader feeds s with chunks read from reader, the input from offset
+// start, until EOF, then ends it. It stops early on a read error, returned as a *ReadError about filename,
+// or when ctx is canceled, returning its cause. Strings read up to then are
+// reported.
+func scanReader(ctx context.Context, s scanner, reader io.Reader, filename string, start int64) error {
+	defer s.end()
+	chunk := bufpool.Get(bufpool.ReaderSize)
+	defer bufpool.Put(chunk)
+	data := (*chunk)[:bufpool.ReaderSize]
+
+	offset := start
+	for {
+		if ctx.Err() != nil {
+			return context.Cause(ctx)
+		}
+		n, err := reader.Read(data)
+		s.write(data[:n], offset)
+		offset += int64(n)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return &ReadError{File: filename, Offset: offset, Err: err}
+		}
+	}
+}
+
+// scanBytes feeds s with data, reporting offsets relative to baseOffset, then
+// ends it
+func scanBytes(s scanner, data []byte, baseOffset int64) {
+	s.write(data, baseOffset)
+	s.end()
+}
+
+// stringRun is the string a scanner is accumulating
+type stringRun struct {
+	filename  string
+	config    Config
+	printFunc func([]byte, string, int64, Config)
+
+	buf     *[]byte // Pooled buffer of current
+	current []byte  // UTF-8 encoding of the current string
+	runes   int     // Characters in current
+	start   int64   // Offset of the current string
+}
+
+// init prepares r for reporting strings to printFunc
+func (r *stringRun) init(filename string, config Config, printFunc func([]byte, string, int64, Config)) {
+	r.filename, r.config, r.printFunc = filename, config, printFunc
+	r.buf = bufpool.Get(bufpool.StringSize)
+	r.current = *r.buf
+}
+
+// flush reports the current string if it is long enough and starts a new one
+func (r *stringRun) flush() {
+	if r.runes >= r.config.MinLength {
+		report(r.current, r.filename, r.start, r.config, r.printFunc)
+	}
+	r.current = r.current[:0]
+	r.runes = 0
+}
+
+// end reports the last string and returns the buffer to the pool
+func (r *stringRun) end() {
+	r.flush()
+	*r.buf = r.current
+	bufpool.Put(r.buf)
+}
+
+// asciiScanner extracts 7-bit or 8-bit ASCII strings
+type asciiScanner struct {
+	stringRun
+	allow8bit bool
+}
+
+// newASCIIScanner returns an ASCII scanner, or a UTF-8 scanner if a -U display
+// mode is selected
+func newASCIIScanner(filename string, config Config, printFunc func([]byte, string, int64, Config), allow8bit bool) scanner {
+	if config.Unicode != "default" && config.Unicode != "invalid" && config.Unicode != "" {
+		return newUTF8Scanner(filename, config, printFunc)
+	}
+	s := &asciiScanner{allow8bit: allow8bit}
+	s.init(filename, config, printFunc)
+	return s
+}
+
+func (s *asciiScanner) write(data []byte, offset int64) {
+	for i, b := range data {
+		if isPrintableASCII(b, s.allow8bit, s.config.IncludeAllWhitespace) {
+			if len(s.current) == 0 {
+				s.start = offset + int64(i)
+			}
+			s.current = append(s.current, b)
+			s.runes++
+		} else {
+			s.flush()
+		}
+	}
+}
+
+// wideScanner extracts UTF-16 or UTF-32 strings. Code units split across
+// writes are carried over to the next one.
+type wideScanner struct {
+	stringRun
+	width     int // Code unit size: 2 (UTF-16) or 4 (UTF-32)
+	byteOrder binary.ByteOrder
+
+	partial    [4]byte // Start of a code unit split across writes
+	partialLen int     // Bytes in partial
+	high       rune    // Pending UTF-16 high surrogate (0 = none)
+	highOffset int64   // Offset of high
+}
+
+// newWideScanner returns a scanner for width-byte code units
+func newWideScanner(filename string, config Config, printFunc func([]byte, string, int64, Config), width int, byteOrder binary.ByteOrder) *wideScanner {
+	s := &wideScanner{width: width, byteOrder: byteOrder}
+	s.init(filename, config, printFunc)
+	return s
+}
+
+func (s *wideScanner) write(data []byte, offset int64) {
+	if s.partialLen > 0 {
+		unitOffset := offset - int64(s.partialLen)
+		n := copy(s.partial[s.partialLen:s.width], data)
+		s.partialLen += n
+		if s.partialLen < s.width {
+			return
+		}
+		s.partialLen = 0
+		s.unit(s.partial[:s.width], unitOffset)
+		data = data[n:]
+		offset += int64(n)
+	}
+
+	i := 0
+	for ; i+s.width <= len(data); i += s.width {
+		s.unit(data[i:i+s.width], offset+int64(i))
+	}
+	s.partialLen = copy(s.partial[:], data[i:])
+}
+
+// unit processes the code unit b at offset
+func (s *wideScanner) unit(b []byte, offset int64) {
+	if s.width == 4 {
+		s.addRune(rune(s.byteOrder.Uint32(b)), offset)
+		return
+	}
+
+	r := rune(s.byteOrder.Uint16(b))
+	if s.high != 0 {
+		high := s.high
+		s.high = 0
+		if r >= 0xDC00 && r <= 0xDFFF {
+			s.addRune(utf16.DecodeRune(high, r), s.highOffset)
+			return
+		}
+		// An unpaired high surrogate ends the string
+		s.flush()
+	}
+	if r >= 0xD800 && r <= 0xDBFF {
+		s.high, s.highOffset = r, offset
+		return
+	}
+	s.addRune(r, offset)
+}
+
+// addRune appends r to the current string, or ends the string if r is not
+// printable (which includes unpaired surrogates and values above U+10FFFF)
+func (s *wideScanner) addRune(r rune, offset int64) {
+	if !isPrintableRune(r, s.config.IncludeAllWhitespace) {
+		s.flush()
+		return
+	}
+	if s.runes == 0 {
+		s.start = offset
+	}
+	s.current = utf8.AppendRune(s.current, r)
+	s.runes++
+}
+
+// flush reports the current string, with config.WideInfo noting whether a
+// byte order mark precedes it: the mark the input starts with, or U+FEFF as
+// its first character (which is printable, so part of the string)
+func (s *wideScanner) flush() {
+	if s.config.WideInfo && s.runes > 0 {
+		first, _ := utf8.DecodeRune(s.current)
+		s.config.AfterBOM = s.config.BOMEnd > 0 && s.start == s.config.BOMEnd || first == '\uFEFF'
+	}
+	s.stringRun.flush()
+}
+
+// end drops a trailing partial code unit or unpaired surrogate and reports the
+// last string
+func (s *wideScanner) end() {
+	s.high = 0
+	s.flush()
+	s.stringRun.end()
+}
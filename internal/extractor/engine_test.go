@@ -0,0 +1,126 @@
+package extractor
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"testing"
+	"testing/iotest"
+	"unicode/utf16"
+)
+
+// utf16LE encodes s as UTF-16LE
+func utf16LE(s string) []byte {
+	var out []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+// TestScannerChunking tests that every encoding gives the same strings whether
+// the input arrives in one piece, one byte at a time or in odd-sized chunks
+func TestScannerChunking(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		unicode  string
+		input    []byte
+		want     []string
+	}{
+		{"ascii", "s", "", []byte("\x00hello\x01\x02world!\x00ab\x00"), []string{"1:hello", "8:world!"}},
+		{"8-bit", "S", "", []byte("\x00caf\xe9\x00"), []string{"1:caf\xe9"}},
+		{"utf-8", "s", "locale", []byte("\x00héllo\x00"), []string{"1:héllo"}},
+		{"utf-16le", "l", "", append([]byte{0, 0}, utf16LE("hello\x00world")...), []string{"2:hello", "14:world"}},
+		{"utf-16be", "b", "", []byte{0, 't', 0, 'e', 0, 's', 0, 't'}, []string{"0:test"}},
+		{"utf-16 surrogate pair", "l", "", utf16LE("ab😀cd"), []string{"0:ab😀cd"}},
+		{"utf-16 unpaired high surrogate", "l", "", append(utf16LE("abcd"), append([]byte{0x00, 0xd8}, utf16LE("efgh")...)...), []string{"0:abcd", "10:efgh"}},
+		{"utf-16 unpaired low surrogate", "l", "", append(utf16LE("abcd"), append([]byte{0x00, 0xdc}, utf16LE("efgh")...)...), []string{"0:abcd", "10:efgh"}},
+		{"utf-16 trailing byte", "l", "", append(utf16LE("abcd"), 'x'), []string{"0:abcd"}},
+		{"utf-32le", "L", "", []byte{'t', 0, 0, 0, 'e', 0, 0, 0, 's', 0, 0, 0, 't', 0, 0, 0, 0, 0, 0, 0}, []string{"0:test"}},
+		{"utf-32be invalid", "B", "", []byte{0, 0, 0, 'a', 0, 0, 0, 'b', 0, 0, 0, 'c', 0, 0, 0, 'd', 0, 0x11, 0, 0}, []string{"0:abcd"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{MinLength: 4, Encoding: tt.encoding, Unicode: tt.unicode}
+			collect := func(got *[]string) func([]byte, string, int64, Config) {
+				return func(str []byte, _ string, offset int64, _ Config) {
+					*got = append(*got, fmt.Sprintf("%d:%s", offset, str))
+				}
+			}
+
+			var whole, oneByte, section []string
+			ExtractStrings(bytes.NewReader(tt.input), "", config, collect(&whole))
+			ExtractStrings(iotest.OneByteReader(bytes.NewReader(tt.input)), "", config, collect(&oneByte))
+			ExtractFromSection(tt.input, "", 0, "", config, collect(&section))
+
+			if !slices.Equal(whole, tt.want) {
+				t.Errorf("reader = %q, want %q", whole, tt.want)
+			}
+			if !slices.Equal(oneByte, tt.want) {
+				t.Errorf("one byte at a time = %q, want %q", oneByte, tt.want)
+			}
+			if !slices.Equal(section, tt.want) {
+				t.Errorf("byte slice = %q, want %q", section, tt.want)
+			}
+
+			for split := 1; split < len(tt.input); split++ {
+				var got []string
+				s := newScanner("", config, collect(&got))
+				s.write(tt.input[:split], 0)
+				s.write(tt.input[split:], int64(split))
+				s.end()
+				if !slices.Equal(got, tt.want) {
+					t.Errorf("split at %d = %q, want %q", split, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+// TestEncodedLen tests the input size of strings reported for each encoding
+func TestEncodedLen(t *testing.T) {
+	tests := []struct {
+		str      string
+		encoding string
+		want     int64
+	}{
+		{"hello", "s", 5},
+		{"caf\xe9", "S", 4},
+		{"héllo", "s", 6},
+		{"héllo", "l", 10},
+		{"ab😀", "b", 8},
+		{"ab😀", "L", 12},
+	}
+
+	for _, tt := range tests {
+		if got := EncodedLen([]byte(tt.str), tt.encoding); got != tt.want {
+			t.Errorf("EncodedLen(%q, %q) = %d, want %d", tt.str, tt.encoding, got, tt.want)
+		}
+	}
+}
+
+// TestEncodeString tests re-encoding decoded strings to the bytes of the
+// input
+func TestEncodeString(t *testing.T) {
+	tests := []struct {
+		str      string
+		encoding string
+		want     string
+	}{
+		{"hello", "s", "hello"},
+		{"caf\xe9", "S", "caf\xe9"},
+		{"hé", "l", "h\x00\xe9\x00"},
+		{"hé", "b", "\x00h\x00\xe9"},
+		{"a😀", "l", "a\x00\x3d\xd8\x00\xde"},
+		{"a😀", "L", "a\x00\x00\x00\x00\xf6\x01\x00"},
+		{"a", "B", "\x00\x00\x00a"},
+	}
+
+	for _, tt := range tests {
+		if got := EncodeString([]byte(tt.str), tt.encoding); string(got) != tt.want {
+			t.Errorf("EncodeString(%q, %q) = %q, want %q", tt.str, tt.encoding, got, tt.want)
+		}
+	}
+}
@@ -4,13 +4,19 @@ package extractor
 
 import (
 	"bufio"
+	"context"
 	"encoding/binary"
-	"fmt"
 	"io"
-	"os"
 	"regexp"
-	"unicode/utf16"
-	"unicode/utf8"
+	"text/template"
+
+	"github.com/richardwooding/txtr/internal/archive"
+	"github.com/richardwooding/txtr/internal/bloom"
+	"github.com/richardwooding/txtr/internal/ignore"
+	"github.com/richardwooding/txtr/internal/noise"
+	"github.com/richardwooding/txtr/internal/rescan"
+	"github.com/richardwooding/txtr/internal/sample"
+	"golang.org/x/text/language"
 )
 
 // ColorMode specifies when to use colored output.
@@ -33,303 +39,163 @@ type Config struct {
 	PrintOffset          bool
 	Encoding             string
 	Unicode              string // UTF-8 handling mode: default/invalid/locale/escape/hex/highlight
+	NoBOMDetect          bool   // Keep -e s for inputs starting with a byte order mark (see DetectBOM)
 	OutputSeparator      string
 	IncludeAllWhitespace bool
-	ScanAll              bool             // Scan entire file
-	ScanDataOnly         bool             // Scan only data sections (requires binary format detection)
-	TargetFormat         string           // Target binary format: elf/pe/macho/binary
-	ColorMode            ColorMode        // When to use colored output
-	MatchPatterns        []*regexp.Regexp // Patterns to match (include filter)
-	ExcludePatterns      []*regexp.Regexp // Patterns to exclude (blacklist filter)
-	DisableMmap          bool             // Disable memory-mapped I/O optimization
-	MmapThreshold        int64            // Minimum file size (bytes) for using mmap
+	Scripts              bool               // Keep embedded scripts together as multi-line strings (see DetectScriptType)
+	Lines                bool               // Report the whole lines of plain text inputs instead of printable runs (see IsText)
+	Line                 int64              // With Lines, the line number of the string reported (set per string, 0 = not a line)
+	ScanAll              bool               // Scan entire file
+	ScanDataOnly         bool               // Scan only data sections (requires binary format detection)
+	TargetFormat         string             // Target binary format: elf/pe/macho/binary
+	Unpack               string             // Unpacking of UPX-packed binaries: never/auto/external
+	UPXPath              string             // upx executable used for unpacking
+	PreFilter            string             // Shell command decoding each input, whose output is scanned instead (--pre-filter)
+	ColorMode            ColorMode          // When to use colored output
+	Sanitize             ColorMode          // When to replace terminal control characters in text output
+	EscapeNonPrint       bool               // C-style escapes for non-printable characters in text output
+	MaxWidth             int                // Truncate strings in text output to this many characters (0 = unlimited)
+	Hyperlinks           bool               // OSC 8 hyperlinks for file names in text output to a terminal
+	ClusterGap           int64              // Group output into clusters of strings at most this many bytes apart (0 = off)
+	ReportTemplate       *template.Template // Template of Markdown reports (nil = the default, see printer.DefaultReportTemplate)
+	Locale               language.Tag       // Language and number format of text statistics
+	MatchPatterns        []*regexp.Regexp   // Patterns to match (include filter)
+	ExcludePatterns      []*regexp.Regexp   // Patterns to exclude (blacklist filter)
+	FormatStrings        bool               // Only printf-style format strings (see FormatDirectives)
+	Secrets              bool               // Only strings that look like credentials (see DetectSecret)
+	MaxStrings           int                // Report at most this many strings per file (0 = unlimited, see LimitStrings)
+	IgnoreList           *ignore.List       // Drop strings matching the ignore list if non-nil
+	CommonStrings        *noise.Database    // Drop strings in the common strings database if non-nil
+	Background           *bloom.Index       // Corpus index scoring the rarity of strings if non-nil (see ScoreRarity)
+	Rarity               float64            // With Background, the rarity of the string reported, 0 to 1 (set per string)
+	SortRarity           bool               // With Background, report the strings of a file rarest first
+	Unique               bool               // Report each distinct string once per file (structured output)
+	Count                bool               // Report occurrences of each unique string (implies Unique)
+	FoldCase             bool               // Collapse unique strings differing only in case (implies Unique)
+	ValueEncoding        string             // Structured output of string values: utf8 (or empty), base64 or hex
+	EmitRaw              bool               // Report the input bytes of each string in structured output (JSON raw)
+	Raw                  []byte             // With EmitRaw, the input bytes of the string reported when the -U display mode changed them (set per string)
+	WideInfo             bool               // Report the byte order, alignment and preceding byte order mark of UTF-16 and UTF-32 strings (JSON wide)
+	BOMEnd               int64              // Offset after the byte order mark the input starts with, set by applyBOM (0 = none)
+	AfterBOM             bool               // With WideInfo, whether a byte order mark directly precedes the wide string reported (set per string)
+	AllOffsets           bool               // List the offsets of every occurrence of each unique string
+	MaxOffsets           int                // Maximum offsets listed per unique string (0 = unlimited)
+	Unordered            bool               // Output each file's strings as soon as it is scanned, not in input order
+	WorkerCPUs           []int              // CPUs parallel workers are pinned to, round-robin (nil = no pinning)
+	DisableMmap          bool               // Disable memory-mapped I/O optimization
+	MmapThreshold        int64              // Minimum file size (bytes) for using mmap
+	Strategy             Strategy           // Read strategy forced for all files (StrategyAuto = by size, see ChooseStrategy)
+	ChunkThreshold       int64              // Minimum file size (bytes) for chunked scanning (0 = DefaultChunkThreshold)
+	ChunkWorkers         int                // Workers sharing the scan of one file: the chunks of a huge file or the members of an archive (below 2 = one)
+	LiteralPools         bool               // Resolve ARM literal pool references to strings (JSON referenced_from)
+	Xrefs                bool               // Count pointer references to strings from other sections (JSON xref_count)
+	Relocs               bool               // Report strings pointed to by relocation entries (JSON reloc_strings)
+	Signatures           bool               // Report embedded filesystems and compressed data (JSON regions)
+	ExtractFS            bool               // Also scan the files of embedded filesystems, one by one
+	ADS                  bool               // Also scan the alternate data streams and resource forks of files, one by one
+	Xattrs               bool               // Report the extended attributes of files (JSON xattrs)
+	ScanXattrs           bool               // Also scan the values of the extended attributes of files, one by one
+	Archives             bool               // Also scan the members of ZIP, tar and tar.gz archives, one by one
+	Passwords            []string           // Passwords tried in order on encrypted archive members
+	ArchiveLimits        archive.Limits     // Limits on the data expanded from archives (nesting depth, expansion ratio, total size)
+	Registry             bool               // Scan registry hives by their key and value cells
+	Evtx                 bool               // Scan event logs record by record
+	Prefetch             bool               // Scan prefetch files by the paths they list
+	NTFS                 bool               // Attribute the strings of NTFS images to their files
+	Evidence             bool               // Scan the media of EnCase and AFF4 evidence containers
+	VirtualDisk          bool               // Scan the guest disk of VMDK, QCOW2 and VHDX virtual disks
+	Partitions           bool               // Report the partitions of disk images (JSON regions)
+	MemoryMap            string             // Map of memory images attributing their regions (see memimage.ParseMap)
+	MemoryProfile        string             // Attribute the regions of memory images by their page tables (see memimage.Analyze)
+	Rescan               *rescan.Plan       // Scan only around the strings of a previous scan, in the format it recorded, if non-nil
+	Sampling             sample.Options     // Scan only windows covering a share of each file, if Sampling.Percent is set
+	OnlySections         []string           // Scan only the data sections of these names (nil = all)
+	MaxMemory            int64              // Memory budget in bytes (0 = unlimited)
+	Metrics              *Metrics           // Collects I/O and stage timings if non-nil
+	Throttle             *Throttle          // Limits read bandwidth if non-nil
+	Retry                *Retry             // Retries transient open and read errors if non-nil
+
+	// Duplicates lists the inputs not scanned because they have the content
+	// of the input they are listed under (--dedupe-inputs)
+	Duplicates map[string][]string
+
+	// Rejected receives the strings long enough to print but dropped by the
+	// filters (see ShouldPrintString) if non-nil, e.g. to count them
+	Rejected func([]byte, string, int64, Config)
+
+	// Warnings receives problems that did not stop the scan if non-nil;
+	// otherwise they are written to standard error (see Warn)
+	Warnings func(Warning)
 }
 
-// ExtractStrings reads from reader and extracts printable strings
+// ExtractStrings reads from reader and extracts printable strings. A read
+// error is reported as a warning (see Warn).
+//
+// Deprecated: Use ExtractStringsContext, which returns read errors and can be
+// canceled.
 func ExtractStrings(reader io.Reader, filename string, config Config, printFunc func([]byte, string, int64, Config)) {
-	switch config.Encoding {
-	case "s": // 7-bit ASCII
-		extractASCII(reader, filename, config, printFunc, false)
-	case "S": // 8-bit ASCII
-		extractASCII(reader, filename, config, printFunc, true)
-	case "b": // 16-bit big-endian (UTF-16BE)
-		extractUTF16(reader, filename, config, printFunc, binary.BigEndian)
-	case "l": // 16-bit little-endian (UTF-16LE)
-		extractUTF16(reader, filename, config, printFunc, binary.LittleEndian)
-	case "B": // 32-bit big-endian (UTF-32BE)
-		extractUTF32(reader, filename, config, printFunc, binary.BigEndian)
-	case "L": // 32-bit little-endian (UTF-32LE)
-		extractUTF32(reader, filename, config, printFunc, binary.LittleEndian)
-	default:
-		extractASCII(reader, filename, config, printFunc, false)
-	}
+	warnRead(config, ExtractStringsContext(context.Background(), reader, filename, config, printFunc))
 }
 
-// extractASCII extracts 7-bit or 8-bit ASCII strings
-func extractASCII(reader io.Reader, filename string, config Config, printFunc func([]byte, string, int64, Config), allow8bit bool) {
-	// If Unicode mode is not default/invalid, use UTF-8 aware extraction
-	if config.Unicode != "default" && config.Unicode != "invalid" && config.Unicode != "" {
-		extractUTF8Aware(reader, filename, config, printFunc)
-		return
-	}
-
-	bufReader := bufio.NewReader(reader)
-	var currentString []byte
-	var offset int64
-	var stringStartOffset int64
-
-	for {
-		b, err := bufReader.ReadByte()
-		if err != nil {
-			if err == io.EOF {
-				// Print the last string if it meets the criteria
-				if len(currentString) >= config.MinLength && ShouldPrintString(currentString, config) {
-					printFunc(currentString, filename, stringStartOffset, config)
-				}
-				break
-			}
-			fmt.Fprintf(os.Stderr, "strings: error reading: %v\n", err)
-			return
+// ExtractStringsContext reads from reader and extracts printable strings,
+// reporting each to printFunc. It returns a *ReadError if reading fails, or
+// the cause of the cancellation of ctx (checked between reads, see
+// context.Cause). Strings read before either were reported.
+//
+// Input starting with a byte order mark is scanned in its encoding (see
+// DetectBOM), unless config.NoBOMDetect is set or config.Encoding is not s.
+// With config.Lines, plain text is reported line by line (see IsText).
+func ExtractStringsContext(ctx context.Context, reader io.Reader, filename string, config Config, printFunc func([]byte, string, int64, Config)) error {
+	reader = meterReader(throttleReader(retryReader(reader, filename, config), config), config)
+	printFunc = meterPrintFunc(printFunc, config)
+
+	var skip int
+	if detectsBOM(config) || config.Lines {
+		// A read error while peeking is returned again by the first read
+		buffered := bufio.NewReaderSize(reader, textSniffLen)
+		prefix, _ := buffered.Peek(maxBOMLen)
+		config, skip = applyBOM(prefix, filename, config)
+		_, _ = buffered.Discard(skip)
+		if config.Lines {
+			prefix, _ = buffered.Peek(textSniffLen)
+			config = applyLines(prefix, config)
 		}
-
-		if isPrintableASCII(b, allow8bit, config.IncludeAllWhitespace) {
-			if len(currentString) == 0 {
-				stringStartOffset = offset
-			}
-			currentString = append(currentString, b)
-		} else {
-			// Non-printable character, check if we have a valid string
-			if len(currentString) >= config.MinLength && ShouldPrintString(currentString, config) {
-				printFunc(currentString, filename, stringStartOffset, config)
-			}
-			currentString = currentString[:0]
-		}
-
-		offset++
+		reader = buffered
 	}
-}
-
-// extractUTF8Aware extracts strings with UTF-8 awareness and special display modes
-func extractUTF8Aware(reader io.Reader, filename string, config Config, printFunc func([]byte, string, int64, Config)) {
-	bufReader := bufio.NewReader(reader)
-	var currentString []byte
-	var currentOutput []byte // May differ from currentString based on Unicode mode
-	var offset int64
-	var stringStartOffset int64
-
-	for {
-		b, err := bufReader.ReadByte()
-		if err != nil {
-			if err == io.EOF {
-				// Print the last string if it meets the criteria
-				if len(currentString) >= config.MinLength && ShouldPrintString(currentOutput, config) {
-					printFunc(currentOutput, filename, stringStartOffset, config)
-				}
-				break
-			}
-			fmt.Fprintf(os.Stderr, "strings: error reading: %v\n", err)
-			return
-		}
+	printFunc, config = mergeScripts(printFunc, config)
 
-		// Check if this starts a UTF-8 sequence
-		if b < 128 {
-			// ASCII character
-			if isPrintableASCII(b, false, config.IncludeAllWhitespace) {
-				if len(currentString) == 0 {
-					stringStartOffset = offset
-				}
-				currentString = append(currentString, b)
-				currentOutput = append(currentOutput, b)
-			} else {
-				// Non-printable, flush current string
-				if len(currentString) >= config.MinLength && ShouldPrintString(currentOutput, config) {
-					printFunc(currentOutput, filename, stringStartOffset, config)
-				}
-				currentString = currentString[:0]
-				currentOutput = currentOutput[:0]
-			}
-		} else {
-			// Potential UTF-8 multi-byte sequence
-			runeBytes := []byte{b}
-			expectedBytes := 0
-
-			// Determine how many bytes this UTF-8 character should have
-			if b&0xE0 == 0xC0 {
-				expectedBytes = 1 // 2-byte sequence
-			} else if b&0xF0 == 0xE0 {
-				expectedBytes = 2 // 3-byte sequence
-			} else if b&0xF8 == 0xF0 {
-				expectedBytes = 3 // 4-byte sequence
-			}
-
-			// Read the continuation bytes
-			valid := true
-			for i := 0; i < expectedBytes; i++ {
-				nextByte, err := bufReader.ReadByte()
-				if err != nil || (nextByte&0xC0) != 0x80 {
-					valid = false
-					if err == nil {
-						_ = bufReader.UnreadByte()
-					}
-					break
-				}
-				runeBytes = append(runeBytes, nextByte)
-				offset++
-			}
-
-			if valid && utf8.Valid(runeBytes) {
-				r, _ := utf8.DecodeRune(runeBytes)
-				if isPrintableRune(r, config.IncludeAllWhitespace) {
-					if len(currentString) == 0 {
-						stringStartOffset = offset - int64(len(runeBytes)) + 1
-					}
-					currentString = append(currentString, runeBytes...)
-
-					// Format based on Unicode mode
-					switch config.Unicode {
-					case "locale":
-						currentOutput = append(currentOutput, runeBytes...)
-					case "escape":
-						currentOutput = fmt.Appendf(currentOutput, "\\u%04x", r)
-					case "hex":
-						currentOutput = fmt.Appendf(currentOutput, "<%02x>", r)
-					case "highlight":
-						currentOutput = fmt.Appendf(currentOutput, "\033[1m\\u%04x\033[0m", r)
-					default:
-						currentOutput = append(currentOutput, runeBytes...)
-					}
-				} else {
-					// Non-printable rune
-					if len(currentString) >= config.MinLength && ShouldPrintString(currentOutput, config) {
-						printFunc(currentOutput, filename, stringStartOffset, config)
-					}
-					currentString = currentString[:0]
-					currentOutput = currentOutput[:0]
-				}
-			} else {
-				// Invalid UTF-8 sequence, treat as non-printable
-				if len(currentString) >= config.MinLength && ShouldPrintString(currentOutput, config) {
-					printFunc(currentOutput, filename, stringStartOffset, config)
-				}
-				currentString = currentString[:0]
-				currentOutput = currentOutput[:0]
-			}
-		}
+	return scanReader(ctx, newScanner(filename, config, printFunc), reader, filename, int64(skip))
+}
 
-		offset++
-	}
+// extractASCII extracts 7-bit or 8-bit ASCII strings from reader
+func extractASCII(reader io.Reader, filename string, config Config, printFunc func([]byte, string, int64, Config), allow8bit bool) {
+	warnRead(config, scanReader(context.Background(), newASCIIScanner(filename, config, printFunc, allow8bit), reader, filename, 0))
 }
 
-// extractUTF16 extracts UTF-16 encoded strings
+// extractUTF16 extracts UTF-16 encoded strings from reader
 func extractUTF16(reader io.Reader, filename string, config Config, printFunc func([]byte, string, int64, Config), byteOrder binary.ByteOrder) {
-	bufReader := bufio.NewReader(reader)
-	var currentRunes []rune
-	var offset int64
-	var stringStartOffset int64
-
-	for {
-		var rawBytes [2]byte
-		n, err := io.ReadFull(bufReader, rawBytes[:])
-		if err != nil {
-			if err == io.EOF || err == io.ErrUnexpectedEOF {
-				// Print the last string if it meets the criteria
-				str := []byte(string(currentRunes))
-				if len(currentRunes) >= config.MinLength && ShouldPrintString(str, config) {
-					printFunc(str, filename, stringStartOffset, config)
-				}
-				break
-			}
-			fmt.Fprintf(os.Stderr, "strings: error reading: %v\n", err)
-			return
-		}
-
-		if n == 2 {
-			u16 := byteOrder.Uint16(rawBytes[:])
-			r := rune(u16)
-
-			// Handle surrogate pairs
-			if utf16.IsSurrogate(r) {
-				var nextBytes [2]byte
-				n2, err2 := io.ReadFull(bufReader, nextBytes[:])
-				if err2 == nil && n2 == 2 {
-					u16_2 := byteOrder.Uint16(nextBytes[:])
-					r = utf16.DecodeRune(r, rune(u16_2))
-					offset += 2
-				}
-			}
-
-			if isPrintableRune(r, config.IncludeAllWhitespace) {
-				if len(currentRunes) == 0 {
-					stringStartOffset = offset
-				}
-				currentRunes = append(currentRunes, r)
-			} else {
-				str := []byte(string(currentRunes))
-				if len(currentRunes) >= config.MinLength && ShouldPrintString(str, config) {
-					printFunc(str, filename, stringStartOffset, config)
-				}
-				currentRunes = currentRunes[:0]
-			}
-
-			offset += 2
-		}
-	}
+	warnRead(config, scanReader(context.Background(), newWideScanner(filename, config, printFunc, 2, byteOrder), reader, filename, 0))
 }
 
-// extractUTF32 extracts UTF-32 encoded strings
+// extractUTF32 extracts UTF-32 encoded strings from reader
 func extractUTF32(reader io.Reader, filename string, config Config, printFunc func([]byte, string, int64, Config), byteOrder binary.ByteOrder) {
-	bufReader := bufio.NewReader(reader)
-	var currentRunes []rune
-	var offset int64
-	var stringStartOffset int64
-
-	for {
-		var rawBytes [4]byte
-		n, err := io.ReadFull(bufReader, rawBytes[:])
-		if err != nil {
-			if err == io.EOF || err == io.ErrUnexpectedEOF {
-				// Print the last string if it meets the criteria
-				str := []byte(string(currentRunes))
-				if len(currentRunes) >= config.MinLength && ShouldPrintString(str, config) {
-					printFunc(str, filename, stringStartOffset, config)
-				}
-				break
-			}
-			fmt.Fprintf(os.Stderr, "strings: error reading: %v\n", err)
-			return
-		}
-
-		if n == 4 {
-			u32 := byteOrder.Uint32(rawBytes[:])
-			r := rune(u32)
-
-			if isPrintableRune(r, config.IncludeAllWhitespace) && utf8.ValidRune(r) {
-				if len(currentRunes) == 0 {
-					stringStartOffset = offset
-				}
-				currentRunes = append(currentRunes, r)
-			} else {
-				str := []byte(string(currentRunes))
-				if len(currentRunes) >= config.MinLength && ShouldPrintString(str, config) {
-					printFunc(str, filename, stringStartOffset, config)
-				}
-				currentRunes = currentRunes[:0]
-			}
-
-			offset += 4
-		}
-	}
+	warnRead(config, scanReader(context.Background(), newWideScanner(filename, config, printFunc, 4, byteOrder), reader, filename, 0))
 }
 
 // IsPrintable returns true if the byte is a printable ASCII character (7-bit)
 func IsPrintable(b byte) bool {
-	return isPrintableASCII(b, false, false)
+	return b >= 32 && b <= 126
 }
 
 // isPrintableASCII checks if a byte is printable with options for 8-bit and whitespace
 func isPrintableASCII(b byte, allow8bit bool, includeAllWhitespace bool) bool {
+	// Tab is always part of strings, as in GNU strings
+	if b == '\t' {
+		return true
+	}
+
 	// Include all whitespace if requested
-	if includeAllWhitespace && (b == '\t' || b == '\n' || b == '\r' || b == '\v' || b == '\f') {
+	if includeAllWhitespace && (b == '\n' || b == '\r' || b == '\v' || b == '\f') {
 		return true
 	}
 
@@ -348,8 +214,13 @@ func isPrintableASCII(b byte, allow8bit bool, includeAllWhitespace bool) bool {
 
 // isPrintableRune checks if a rune is printable
 func isPrintableRune(r rune, includeAllWhitespace bool) bool {
+	// Tab is always part of strings, as in GNU strings
+	if r == '\t' {
+		return true
+	}
+
 	// Include all whitespace if requested
-	if includeAllWhitespace && (r == '\t' || r == '\n' || r == '\r' || r == '\v' || r == '\f') {
+	if includeAllWhitespace && (r == '\n' || r == '\r' || r == '\v' || r == '\f') {
 		return true
 	}
 
@@ -375,104 +246,73 @@ func isPrintableRune(r rune, includeAllWhitespace bool) bool {
 
 // ExtractFromSection extracts strings from a specific section's data
 func ExtractFromSection(data []byte, _ string, sectionOffset int64, filename string, config Config, printFunc func([]byte, string, int64, Config)) {
-	// Use appropriate extraction based on encoding
-	switch config.Encoding {
-	case "s": // 7-bit ASCII
-		extractASCIIFromBytes(data, sectionOffset, filename, config, printFunc, false)
-	case "S": // 8-bit ASCII
-		extractASCIIFromBytes(data, sectionOffset, filename, config, printFunc, true)
-	case "b": // UTF-16BE
-		extractUTF16FromBytes(data, sectionOffset, filename, config, printFunc, binary.BigEndian)
-	case "l": // UTF-16LE
-		extractUTF16FromBytes(data, sectionOffset, filename, config, printFunc, binary.LittleEndian)
-	case "B": // UTF-32BE
-		extractUTF32FromBytes(data, sectionOffset, filename, config, printFunc, binary.BigEndian)
-	case "L": // UTF-32LE
-		extractUTF32FromBytes(data, sectionOffset, filename, config, printFunc, binary.LittleEndian)
-	default:
-		extractASCIIFromBytes(data, sectionOffset, filename, config, printFunc, false)
+	printFunc = meterPrintFunc(printFunc, config)
+	config, skip := applyBOM(data, filename, config)
+	if skip > 0 {
+		config.BOMEnd += sectionOffset
 	}
+	config = applyLines(data[skip:min(len(data), skip+textSniffLen)], config)
+	printFunc, config = mergeScripts(printFunc, config)
+
+	scanBytes(newScanner(filename, config, printFunc), data[skip:], sectionOffset+int64(skip))
 }
 
-// extractASCIIFromBytes is a helper for extracting from byte slices
-func extractASCIIFromBytes(data []byte, baseOffset int64, filename string, config Config, printFunc func([]byte, string, int64, Config), allow8bit bool) {
-	var currentString []byte
-	var stringStartOffset int64
-
-	for i, b := range data {
-		if isPrintableASCII(b, allow8bit, config.IncludeAllWhitespace) {
-			if len(currentString) == 0 {
-				stringStartOffset = baseOffset + int64(i)
-			}
-			currentString = append(currentString, b)
-		} else {
-			if len(currentString) >= config.MinLength && ShouldPrintString(currentString, config) {
-				printFunc(currentString, filename, stringStartOffset, config)
-			}
-			currentString = currentString[:0]
-		}
-	}
+// ExtractFromSectionReader is like ExtractFromSection but streams the section
+// contents from reader instead of holding them in memory. Offsets are reported
+// relative to sectionOffset. A read error is reported as a warning.
+//
+// Deprecated: Use ExtractFromSectionReaderContext, which returns read errors
+// and can be canceled.
+func ExtractFromSectionReader(reader io.Reader, sectionOffset int64, filename string, config Config, printFunc func([]byte, string, int64, Config)) {
+	warnRead(config, ExtractFromSectionReaderContext(context.Background(), reader, sectionOffset, filename, config, printFunc))
+}
 
-	// Handle last string
-	if len(currentString) >= config.MinLength && ShouldPrintString(currentString, config) {
-		printFunc(currentString, filename, stringStartOffset, config)
-	}
+// ExtractFromSectionReaderContext is like ExtractStringsContext, reporting
+// offsets relative to sectionOffset. The offset of a *ReadError is within
+// the section.
+func ExtractFromSectionReaderContext(ctx context.Context, reader io.Reader, sectionOffset int64, filename string, config Config, printFunc func([]byte, string, int64, Config)) error {
+	return ExtractStringsContext(ctx, reader, filename, config, func(str []byte, fname string, offset int64, cfg Config) {
+		printFunc(str, fname, sectionOffset+offset, cfg)
+	})
 }
 
-// extractUTF16FromBytes extracts UTF-16 from byte slice
-func extractUTF16FromBytes(data []byte, baseOffset int64, filename string, config Config, printFunc func([]byte, string, int64, Config), byteOrder binary.ByteOrder) {
-	var currentRunes []rune
-	var stringStartOffset int64
-
-	for i := 0; i < len(data)-1; i += 2 {
-		u16 := byteOrder.Uint16(data[i : i+2])
-		r := rune(u16)
-
-		if isPrintableRune(r, config.IncludeAllWhitespace) {
-			if len(currentRunes) == 0 {
-				stringStartOffset = baseOffset + int64(i)
-			}
-			currentRunes = append(currentRunes, r)
-		} else {
-			str := []byte(string(currentRunes))
-			if len(currentRunes) >= config.MinLength && ShouldPrintString(str, config) {
-				printFunc(str, filename, stringStartOffset, config)
-			}
-			currentRunes = currentRunes[:0]
-		}
+// ExtractAt extracts the string that starts at the beginning of data (e.g. the
+// target of a pointer), using the configured encoding. Scanning stops at the
+// first NUL code unit, and only the printable run starting at offset is printed.
+func ExtractAt(data []byte, offset int64, filename string, config Config, printFunc func([]byte, string, int64, Config)) {
+	// Determine code unit width for the terminator
+	unit := 1
+	switch config.Encoding {
+	case "b", "l":
+		unit = 2
+	case "B", "L":
+		unit = 4
 	}
 
-	str := []byte(string(currentRunes))
-	if len(currentRunes) >= config.MinLength && ShouldPrintString(str, config) {
-		printFunc(str, filename, stringStartOffset, config)
+	// Find the terminating NUL code unit
+	end := len(data) - len(data)%unit
+	for i := 0; i+unit <= end; i += unit {
+		if isZero(data[i : i+unit]) {
+			end = i
+			break
+		}
 	}
-}
 
-// extractUTF32FromBytes extracts UTF-32 from byte slice
-func extractUTF32FromBytes(data []byte, baseOffset int64, filename string, config Config, printFunc func([]byte, string, int64, Config), byteOrder binary.ByteOrder) {
-	var currentRunes []rune
-	var stringStartOffset int64
-
-	for i := 0; i < len(data)-3; i += 4 {
-		u32 := byteOrder.Uint32(data[i : i+4])
-		r := rune(u32)
-
-		if isPrintableRune(r, config.IncludeAllWhitespace) && utf8.ValidRune(r) {
-			if len(currentRunes) == 0 {
-				stringStartOffset = baseOffset + int64(i)
-			}
-			currentRunes = append(currentRunes, r)
-		} else {
-			str := []byte(string(currentRunes))
-			if len(currentRunes) >= config.MinLength && ShouldPrintString(str, config) {
-				printFunc(str, filename, stringStartOffset, config)
-			}
-			currentRunes = currentRunes[:0]
+	// A pointer target is not the start of a text
+	config.NoBOMDetect, config.Lines = true, false
+	ExtractFromSection(data[:end], "", offset, filename, config, func(str []byte, fname string, strOffset int64, cfg Config) {
+		if strOffset == offset {
+			printFunc(str, fname, strOffset, cfg)
 		}
-	}
+	})
+}
 
-	str := []byte(string(currentRunes))
-	if len(currentRunes) >= config.MinLength && ShouldPrintString(str, config) {
-		printFunc(str, filename, stringStartOffset, config)
+// isZero reports whether all bytes are zero
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
 	}
+	return true
 }
@@ -2,7 +2,13 @@ package extractor
 
 import (
 	"bytes"
+	"context"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
 	"testing"
+	"testing/iotest"
 )
 
 func TestIsPrintable(t *testing.T) {
@@ -100,3 +106,157 @@ func TestExtractStrings(t *testing.T) {
 		})
 	}
 }
+
+// TestExtractStringsContextReadError tests that a read error is returned as
+// a *ReadError after the strings read before it
+func TestExtractStringsContextReadError(t *testing.T) {
+	errDevice := errors.New("device not ready")
+	reader := io.MultiReader(strings.NewReader("hello world\x00"), iotest.ErrReader(errDevice))
+
+	var got []string
+	err := ExtractStringsContext(context.Background(), reader, "disk.img", Config{MinLength: 4, Encoding: "s"}, func(str []byte, _ string, _ int64, _ Config) {
+		got = append(got, string(str))
+	})
+
+	if len(got) != 1 || got[0] != "hello world" {
+		t.Errorf("strings = %q, want [\"hello world\"]", got)
+	}
+	var readErr *ReadError
+	if !errors.As(err, &readErr) || !errors.Is(err, errDevice) {
+		t.Fatalf("ExtractStringsContext() error = %v, want a *ReadError wrapping %v", err, errDevice)
+	}
+	if readErr.File != "disk.img" || readErr.Offset != 12 {
+		t.Errorf("ReadError = %+v, want File disk.img, Offset 12", readErr)
+	}
+	if want := "error reading disk.img at offset 12: device not ready"; err.Error() != want {
+		t.Errorf("Error() = %q, want %q", err, want)
+	}
+}
+
+// TestExtractStringsContextCanceled tests that extraction stops between
+// reads once the context is canceled
+func TestExtractStringsContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// One read per string, canceled by the first
+	reader := iotest.OneByteReader(strings.NewReader("first\x00second\x00"))
+	var got []string
+	err := ExtractStringsContext(ctx, reader, "", Config{MinLength: 4, Encoding: "s"}, func(str []byte, _ string, _ int64, _ Config) {
+		got = append(got, string(str))
+		cancel()
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ExtractStringsContext() error = %v, want context.Canceled", err)
+	}
+	if len(got) != 1 || got[0] != "first" {
+		t.Errorf("strings = %q, want [\"first\"]", got)
+	}
+
+	if err := ExtractFromSectionReaderContext(ctx, strings.NewReader("never read\x00"), 0, "", Config{MinLength: 4}, func([]byte, string, int64, Config) {
+		t.Error("string reported after cancellation")
+	}); !errors.Is(err, context.Canceled) {
+		t.Errorf("ExtractFromSectionReaderContext() error = %v, want context.Canceled", err)
+	}
+}
+
+func TestExtractAt(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		encoding string
+		want     []string
+	}{
+		{"terminated string", []byte("hello\x00world\x00"), "s", []string{"hello"}},
+		{"unterminated string", []byte("hello"), "s", []string{"hello"}},
+		{"too short", []byte("hi\x00longer string"), "s", nil},
+		{"leading binary", []byte("\x01hello\x00"), "s", nil},
+		{"binary after run", []byte("hello\x01xy\x00"), "s", []string{"hello"}},
+		{"UTF-16LE", []byte("h\x00e\x00l\x00l\x00o\x00\x00\x00w\x00"), "l", []string{"hello"}},
+		{"UTF-32BE", []byte("\x00\x00\x00t\x00\x00\x00e\x00\x00\x00s\x00\x00\x00t\x00\x00\x00\x00"), "B", []string{"test"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{MinLength: 4, Encoding: tt.encoding}
+			var got []string
+			ExtractAt(tt.data, 0x100, "", config, func(str []byte, _ string, offset int64, _ Config) {
+				if offset != 0x100 {
+					t.Errorf("offset = 0x%x, want 0x100", offset)
+				}
+				got = append(got, string(str))
+			})
+
+			if len(got) != len(tt.want) {
+				t.Fatalf("ExtractAt() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("ExtractAt()[%d] = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestExtractFromSectionReader(t *testing.T) {
+	data := []byte("\x00\x00first\x00\x01second string\x00")
+	config := Config{MinLength: 4, Encoding: "s"}
+
+	type found struct {
+		str    string
+		offset int64
+	}
+
+	var fromBytes, fromReader []found
+	ExtractFromSection(data, ".rodata", 0x400, "", config, func(str []byte, _ string, offset int64, _ Config) {
+		fromBytes = append(fromBytes, found{string(str), offset})
+	})
+	ExtractFromSectionReader(bytes.NewReader(data), 0x400, "", config, func(str []byte, _ string, offset int64, _ Config) {
+		fromReader = append(fromReader, found{string(str), offset})
+	})
+
+	if len(fromReader) != 2 || fromReader[0] != (found{"first", 0x402}) || fromReader[1] != (found{"second string", 0x409}) {
+		t.Errorf("ExtractFromSectionReader() = %v", fromReader)
+	}
+	if len(fromBytes) != len(fromReader) {
+		t.Fatalf("byte-slice and streaming extraction differ: %v vs %v", fromBytes, fromReader)
+	}
+	for i := range fromBytes {
+		if fromBytes[i] != fromReader[i] {
+			t.Errorf("result %d: ExtractFromSection() = %v, ExtractFromSectionReader() = %v", i, fromBytes[i], fromReader[i])
+		}
+	}
+}
+
+func TestExtractStringsMetrics(t *testing.T) {
+	data := []byte("hello\x00world\x00skip me\x00")
+	metrics := &Metrics{}
+	config := Config{MinLength: 4, Encoding: "s", Metrics: metrics, ExcludePatterns: []*regexp.Regexp{regexp.MustCompile("skip")}}
+
+	var found []string
+	ExtractStrings(bytes.NewReader(data), "", config, func(str []byte, _ string, _ int64, _ Config) {
+		found = append(found, string(str))
+	})
+
+	if len(found) != 2 {
+		t.Errorf("ExtractStrings() found %v, want [hello world]", found)
+	}
+	if metrics.BytesRead != int64(len(data)) {
+		t.Errorf("BytesRead = %d, want %d", metrics.BytesRead, len(data))
+	}
+	if metrics.FilterTime <= 0 {
+		t.Error("FilterTime not recorded with exclude patterns")
+	}
+
+	// Without metrics nothing is recorded and output is unchanged
+	config.Metrics = nil
+	found = nil
+	ExtractStrings(bytes.NewReader(data), "", config, func(str []byte, _ string, _ int64, _ Config) {
+		found = append(found, string(str))
+	})
+	if len(found) != 2 {
+		t.Errorf("ExtractStrings() without metrics found %v", found)
+	}
+}
@@ -37,7 +37,31 @@ func CompilePatterns(patterns []string, ignoreCase bool) ([]*regexp.Regexp, erro
 // 1. If exclude patterns exist and any match, return false (exclude takes precedence)
 // 2. If match patterns exist, at least one must match to return true
 // 3. If no patterns are defined, return true (no filtering)
+//
+// With FormatStrings, only printf-style format strings pass, and with Secrets
+// only strings matching one of SecretRules. Strings matched
+// by IgnoreList or in CommonStrings never pass.
 func ShouldPrintString(str []byte, config Config) bool {
+	// Format string harvesting keeps only printf-style strings
+	if config.FormatStrings && !IsFormatString(str) {
+		return false
+	}
+
+	// The secrets preset keeps only likely credentials
+	if config.Secrets && DetectSecret(str) == "" {
+		return false
+	}
+
+	// Drop strings the analyst chose to ignore
+	if config.IgnoreList != nil && config.IgnoreList.Contains(str) {
+		return false
+	}
+
+	// Drop well-known runtime and library strings
+	if config.CommonStrings != nil && config.CommonStrings.Contains(str) {
+		return false
+	}
+
 	// Check exclude patterns first (blacklist has priority)
 	if len(config.ExcludePatterns) > 0 {
 		for _, pattern := range config.ExcludePatterns {
@@ -60,3 +84,50 @@ func ShouldPrintString(str []byte, config Config) bool {
 	// No filtering configured, allow all strings
 	return true
 }
+
+// report passes str to printFunc if it passes the filters, or to
+// config.Rejected otherwise. Every extraction path reports strings through it,
+// so the filters apply whatever the encoding or input source.
+func report(str []byte, filename string, offset int64, config Config, printFunc func([]byte, string, int64, Config)) {
+	if shouldPrint(str, config) {
+		printFunc(str, filename, offset, config)
+	} else if config.Rejected != nil {
+		config.Rejected(str, filename, offset, config)
+	}
+}
+
+// withoutFilters returns config with all filters removed, for extraction
+// passes whose output is filtered later
+func withoutFilters(config Config) Config {
+	config.MatchPatterns = nil
+	config.ExcludePatterns = nil
+	config.FormatStrings = false
+	config.Secrets = false
+	config.IgnoreList = nil
+	config.CommonStrings = nil
+	config.Rejected = nil
+	return config
+}
+
+// LimitStrings wraps printFunc for --max-strings: it passes on the first
+// config.MaxStrings strings of filename, then drops the rest, reporting the
+// truncation once as an informational warning. Wrap once per file, around the
+// final print function (after ordering, e.g. of chunked scans). printFunc is
+// returned unchanged if config.MaxStrings is not set.
+func LimitStrings(printFunc func([]byte, string, int64, Config), filename string, config Config) func([]byte, string, int64, Config) {
+	if config.MaxStrings <= 0 {
+		return printFunc
+	}
+	count := 0
+	return func(str []byte, fname string, offset int64, cfg Config) {
+		count++
+		if count <= config.MaxStrings {
+			printFunc(str, fname, offset, cfg)
+			return
+		}
+		if count == config.MaxStrings+1 {
+			Warn(config, Warning{Severity: SeverityInfo, Code: WarnMaxStrings, File: filename,
+				Message: fmt.Sprintf("stopped after %d strings (--max-strings), later strings are not reported", config.MaxStrings)})
+		}
+	}
+}
@@ -1,8 +1,16 @@
 package extractor
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"regexp"
+	"slices"
 	"testing"
+	"unicode/utf16"
+
+	"github.com/richardwooding/txtr/internal/ignore"
+	"github.com/richardwooding/txtr/internal/noise"
 )
 
 // TestCompilePatterns tests pattern compilation
@@ -333,3 +341,124 @@ func TestShouldPrintStringSpecialPatterns(t *testing.T) {
 		})
 	}
 }
+
+// TestShouldPrintStringCommonStrings tests the --filter-common filter
+func TestShouldPrintStringCommonStrings(t *testing.T) {
+	config := Config{CommonStrings: noise.Embedded()}
+	for _, str := range []string{"GLIBC_2.34", "__libc_start_main", ".text"} {
+		if ShouldPrintString([]byte(str), config) {
+			t.Errorf("common string %q passed the filter", str)
+		}
+	}
+	if !ShouldPrintString([]byte("http://c2.example.com/beacon"), config) {
+		t.Error("sample-specific string was filtered out")
+	}
+}
+
+// TestShouldPrintStringIgnoreList tests the --ignore-file filter
+func TestShouldPrintStringIgnoreList(t *testing.T) {
+	list, err := ignore.Parse("ignore.txt", []byte("exact noise\nglob:tmp*\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := Config{IgnoreList: list}
+	for _, str := range []string{"exact noise", "tmpfile"} {
+		if ShouldPrintString([]byte(str), config) {
+			t.Errorf("ignored string %q passed the filter", str)
+		}
+	}
+	if !ShouldPrintString([]byte("kept string"), config) {
+		t.Error("string not matched by any rule was filtered out")
+	}
+}
+
+// TestFiltersAllPaths tests that every extraction path applies -m/-M and
+// passes dropped strings to Config.Rejected
+func TestFiltersAllPaths(t *testing.T) {
+	text := "\x00keep one\x00drop two\x00keep three\x00"
+	var wide []byte
+	for _, u := range utf16.Encode([]rune(text)) {
+		wide = append(wide, byte(u), byte(u>>8))
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		input  []byte
+	}{
+		{"ascii", Config{Encoding: "s"}, []byte(text)},
+		{"8-bit", Config{Encoding: "S"}, []byte(text)},
+		{"utf-8 display", Config{Encoding: "s", Unicode: "escape"}, []byte(text)},
+		{"utf-16le", Config{Encoding: "l"}, wide},
+		{"scripts", Config{Encoding: "s", Scripts: true}, []byte(text)},
+	}
+
+	path := filepath.Join(t.TempDir(), "input")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.WriteFile(path, tt.input, 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			var printed, rejected []string
+			config := tt.config
+			config.MinLength = 4
+			config.MatchPatterns = []*regexp.Regexp{regexp.MustCompile("keep|drop")}
+			config.ExcludePatterns = []*regexp.Regexp{regexp.MustCompile("drop")}
+			config.Rejected = func(str []byte, _ string, _ int64, _ Config) {
+				rejected = append(rejected, string(str))
+			}
+			collect := func(str []byte, _ string, _ int64, _ Config) {
+				printed = append(printed, string(str))
+			}
+
+			paths := map[string]func(){
+				"reader":  func() { ExtractStrings(bytes.NewReader(tt.input), "", config, collect) },
+				"section": func() { ExtractFromSection(tt.input, "", 0, "", config, collect) },
+				"mmap": func() {
+					mmapConfig := config
+					mmapConfig.MmapThreshold = 0
+					if err := ExtractStringsFromFile(path, mmapConfig, collect); err != nil {
+						t.Fatal(err)
+					}
+				},
+			}
+			for _, name := range []string{"reader", "section", "mmap"} {
+				printed, rejected = nil, nil
+				paths[name]()
+				if want := []string{"keep one", "keep three"}; !slices.Equal(printed, want) {
+					t.Errorf("%s: printed %q, want %q", name, printed, want)
+				}
+				if want := []string{"drop two"}; !slices.Equal(rejected, want) {
+					t.Errorf("%s: rejected %q, want %q", name, rejected, want)
+				}
+			}
+		})
+	}
+}
+
+// TestLimitStrings tests that strings after the first MaxStrings are dropped
+// with a single warning
+func TestLimitStrings(t *testing.T) {
+	var warnings []Warning
+	config := Config{MinLength: 4, Encoding: "s", MaxStrings: 2, Warnings: func(w Warning) { warnings = append(warnings, w) }}
+
+	var got []string
+	data := []byte("one1\x00two2\x00three\x00four\x00")
+	path := filepath.Join(t.TempDir(), "many.bin")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ExtractStringsFromFile(path, config, func(str []byte, _ string, _ int64, _ Config) {
+		got = append(got, string(str))
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(got, []string{"one1", "two2"}) {
+		t.Errorf("strings = %q, want [one1 two2]", got)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarnMaxStrings || warnings[0].File != path {
+		t.Errorf("warnings = %v, want one %q warning about %s", warnings, WarnMaxStrings, path)
+	}
+}
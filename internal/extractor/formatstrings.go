@@ -0,0 +1,160 @@
+package extractor
+
+// formatFlags are the printf flag characters
+const formatFlags = "-+ #0'"
+
+// formatConversions are the printf conversion characters, including Go's
+// %v, %q, %T, %t and %w (rare ones such as %a and %n are left out as they
+// mostly match noise)
+const formatConversions = "diouxXeEfFgGcspvqTtw"
+
+// FormatDirectives returns the printf-style conversion directives in str
+// (e.g. "%s", "%08x", "%-10.3f", "%1$s", "%lld", "%+v"). It returns nil unless
+// str looks like a format string:
+//   - every '%' starts a valid directive or is an escaped "%%"
+//   - at least one directive is followed by a non-alphanumeric character or
+//     the end of the string (so "%dzo4" in random data does not count)
+//   - the text around the directives contains a word of three or more
+//     letters, or only punctuation and spaces between two or more directives
+//     (as in "%s/%s" or "%d.%d")
+func FormatDirectives(str []byte) []string {
+	var directives []string
+	var text []byte
+	delimited := false
+
+	for i := 0; i < len(str); i++ {
+		if str[i] != '%' {
+			text = append(text, str[i])
+			continue
+		}
+		if i+1 < len(str) && str[i+1] == '%' {
+			i++
+			continue
+		}
+
+		end := parseFormatDirective(str, i+1)
+		if end < 0 {
+			return nil
+		}
+		if end == len(str) || !isAlphanumeric(str[end]) {
+			delimited = true
+		}
+		directives = append(directives, string(str[i:end]))
+		text = append(text, ' ')
+		i = end - 1
+	}
+
+	if !delimited || !isFormatText(text, len(directives)) {
+		return nil
+	}
+	return directives
+}
+
+// isFormatText reports whether the text of a format string (directives
+// replaced by spaces) contains a word, or only punctuation and spaces
+// between several directives
+func isFormatText(text []byte, directives int) bool {
+	run := 0
+	alphanumeric := false
+	for _, b := range text {
+		switch {
+		case b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z':
+			run++
+			alphanumeric = true
+			if run >= 3 {
+				return true
+			}
+		case b >= '0' && b <= '9':
+			run = 0
+			alphanumeric = true
+		default:
+			run = 0
+		}
+	}
+	return !alphanumeric && directives >= 2
+}
+
+// isAlphanumeric reports whether b is an ASCII letter or digit
+func isAlphanumeric(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
+
+// IsFormatString reports whether str is a printf-style format string (see
+// FormatDirectives)
+func IsFormatString(str []byte) bool {
+	return FormatDirectives(str) != nil
+}
+
+// parseFormatDirective parses a directive following a '%' at position i and
+// returns the index after it, or -1 if the text is not a valid directive
+func parseFormatDirective(str []byte, i int) int {
+	start := i
+	digits := func() {
+		for i < len(str) && str[i] >= '0' && str[i] <= '9' {
+			i++
+		}
+	}
+
+	// Positional argument: %1$s
+	if i < len(str) && str[i] >= '1' && str[i] <= '9' {
+		digits()
+		if i < len(str) && str[i] == '$' {
+			i++
+		} else {
+			i = start
+		}
+	}
+
+	for i < len(str) && contains(formatFlags, str[i]) {
+		i++
+	}
+
+	// Width and precision, either of which may be '*'
+	if i < len(str) && str[i] == '*' {
+		i++
+	} else {
+		digits()
+	}
+	if i < len(str) && str[i] == '.' {
+		i++
+		if i < len(str) && str[i] == '*' {
+			i++
+		} else {
+			digits()
+		}
+	}
+	// Real widths and precisions are short
+	if i-start > 8 {
+		return -1
+	}
+
+	// Length modifiers: hh, h, l, ll, L, q, j, z, t, I64, I32, I
+	switch {
+	case hasPrefixAt(str, i, "hh"), hasPrefixAt(str, i, "ll"):
+		i += 2
+	case hasPrefixAt(str, i, "I64"), hasPrefixAt(str, i, "I32"):
+		i += 3
+	case i < len(str) && contains("hlLqjztI", str[i]):
+		i++
+	}
+
+	if i >= len(str) || !contains(formatConversions, str[i]) {
+		return -1
+	}
+	return i + 1
+}
+
+// contains reports whether set contains the byte b
+func contains(set string, b byte) bool {
+	for j := 0; j < len(set); j++ {
+		if set[j] == b {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPrefixAt reports whether str has prefix at position i
+func hasPrefixAt(str []byte, i int, prefix string) bool {
+	return len(str)-i >= len(prefix) && string(str[i:i+len(prefix)]) == prefix
+}
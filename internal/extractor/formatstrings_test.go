@@ -0,0 +1,59 @@
+package extractor
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestFormatDirectives tests printf format string detection
+func TestFormatDirectives(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"cannot open %s: %d", []string{"%s", "%d"}},
+		{"offset 0x%08x size %-10.3f", []string{"%08x", "%-10.3f"}},
+		{"%1$s was set to %2$lld", []string{"%1$s", "%2$lld"}},
+		{"value %+v (type %T): %w", []string{"%+v", "%T", "%w"}},
+		{"read %zu bytes (%I64d total)", []string{"%zu", "%I64d"}},
+		{"%*d items in %.*s", []string{"%*d", "%.*s"}},
+		{"100%% done, %d left", []string{"%d"}},
+		{"mime%d = NULL;", []string{"%d"}},
+		{"%s/%s", []string{"%s", "%s"}},
+		{"%d.%d.%d", []string{"%d", "%d", "%d"}},
+
+		// Not format strings
+		{"no directives here", nil},
+		{"100%% done", nil},
+		{"50% of files", nil},
+		{"progress %z bad", nil},
+		{"%dzo4", nil},
+		{"ab%x", nil},
+		{"+]4%x", nil},
+		{"%.1Lf", nil},
+		{"width %123456789d too wide", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := FormatDirectives([]byte(tt.input))
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("FormatDirectives(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+			if IsFormatString([]byte(tt.input)) != (tt.want != nil) {
+				t.Errorf("IsFormatString(%q) = %v, want %v", tt.input, !(tt.want != nil), tt.want != nil)
+			}
+		})
+	}
+}
+
+// TestShouldPrintStringFormatStrings tests the --format-strings filter
+func TestShouldPrintStringFormatStrings(t *testing.T) {
+	config := Config{FormatStrings: true}
+	if !ShouldPrintString([]byte("error %d in %s"), config) {
+		t.Error("format string was filtered out")
+	}
+	if ShouldPrintString([]byte("plain string"), config) {
+		t.Error("plain string passed the format string filter")
+	}
+}
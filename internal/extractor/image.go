@@ -0,0 +1,42 @@
+package extractor
+
+import (
+	"errors"
+	"io"
+
+	"github.com/richardwooding/txtr/internal/evidence"
+	"github.com/richardwooding/txtr/internal/vdisk"
+)
+
+// Image is the media held by a file that is scanned in place of the file:
+// the media of an evidence container (Config.Evidence) or the disk of a
+// virtual disk (Config.VirtualDisk)
+type Image interface {
+	io.ReaderAt
+	io.Closer
+	Size() int64 // Bytes of media
+}
+
+// OpenImage opens the media held by path if it is a container enabled by
+// config. It returns a nil Image for other files.
+func OpenImage(path string, config Config) (Image, error) {
+	if config.Evidence {
+		img, err := evidence.Open(path)
+		if err == nil {
+			return img, nil
+		}
+		if !errors.Is(err, evidence.ErrNotEvidence) {
+			return nil, err
+		}
+	}
+	if config.VirtualDisk {
+		disk, err := vdisk.Open(path)
+		if err == nil {
+			return disk, nil
+		}
+		if !errors.Is(err, vdisk.ErrNotVirtualDisk) {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
@@ -0,0 +1,110 @@
+package extractor
+
+import (
+	"bytes"
+	"unicode/utf8"
+
+	"github.com/richardwooding/txtr/internal/bufpool"
+)
+
+// textSniffLen is the length of the start of an input IsText looks at
+const textSniffLen = 8192
+
+// IsText reports whether data, the start of an input, looks like plain text:
+// valid UTF-8 (the last character may be cut off) without NUL bytes and with
+// few control characters other than whitespace, backspace and escape
+func IsText(data []byte) bool {
+	if len(data) == 0 {
+		return false
+	}
+	controls := 0
+	for i := 0; i < len(data); {
+		b := data[i]
+		if b >= utf8.RuneSelf {
+			r, size := utf8.DecodeRune(data[i:])
+			if r == utf8.RuneError && size == 1 {
+				// A character cut off at the end of data is still text
+				if !utf8.FullRune(data[i:]) {
+					break
+				}
+				return false
+			}
+			i += size
+			continue
+		}
+		switch {
+		case b == 0:
+			return false
+		case b < ' ' && b != '\t' && b != '\n' && b != '\r' && b != '\v' && b != '\f' && b != '\b' && b != 0x1B, b == 0x7F:
+			controls++
+		}
+		i++
+	}
+	return controls*100 <= len(data)
+}
+
+// applyLines returns config with Lines kept only if data, the start of an
+// input scanned in a single-byte encoding, looks like plain text (see
+// IsText): other inputs are scanned for printable runs as usual
+func applyLines(data []byte, config Config) Config {
+	if config.Lines && (config.Encoding != "" && config.Encoding != "s" && config.Encoding != "S" || !IsText(data)) {
+		config.Lines = false
+	}
+	return config
+}
+
+// lineScanner reports the lines of plain text, with their line numbers in
+// Config.Line. Lines shorter than the minimum length are skipped, and line
+// breaks (\n or \r\n) are not part of them.
+type lineScanner struct {
+	stringRun
+	line    int64 // Number of the current line
+	started bool  // Whether the current line has started
+}
+
+// newLineScanner returns a scanner of the lines of a text input
+func newLineScanner(filename string, config Config, printFunc func([]byte, string, int64, Config)) scanner {
+	s := &lineScanner{line: 1}
+	s.init(filename, config, printFunc)
+	return s
+}
+
+func (s *lineScanner) write(data []byte, offset int64) {
+	for len(data) > 0 {
+		if !s.started {
+			s.start, s.started = offset, true
+		}
+		i := bytes.IndexByte(data, '\n')
+		if i < 0 {
+			s.current = append(s.current, data...)
+			return
+		}
+		s.current = append(s.current, data[:i]...)
+		s.flushLine()
+		data = data[i+1:]
+		offset += int64(i + 1)
+	}
+}
+
+// flushLine reports the current line if it is long enough and starts the next
+func (s *lineScanner) flushLine() {
+	line := bytes.TrimSuffix(s.current, []byte("\r"))
+	if utf8.RuneCount(line) >= s.config.MinLength {
+		config := s.config
+		config.Line = s.line
+		report(line, s.filename, s.start, config, s.printFunc)
+	}
+	s.current = s.current[:0]
+	s.started = false
+	s.line++
+}
+
+// end reports the last line, if not terminated, and returns the buffer to
+// the pool
+func (s *lineScanner) end() {
+	if s.started {
+		s.flushLine()
+	}
+	*s.buf = s.current
+	bufpool.Put(s.buf)
+}
@@ -0,0 +1,107 @@
+package extractor
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestIsText(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want bool
+	}{
+		{"log", "2026-01-02 INFO started\r\n2026-01-02 WARN\tslow\n", true},
+		{"UTF-8", "grüße, 世界\n", true},
+		{"cut off character", "grüße \xE4\xB8", true},
+		{"escape sequences", "\x1b[1mbold\x1b[0m\n", true},
+		{"NUL", "text\x00more text", false},
+		{"invalid UTF-8", "caf\xE9 au lait\n", false},
+		{"control characters", "\x01\x02\x03 header\n", false},
+		{"empty", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsText([]byte(tt.data)); got != tt.want {
+				t.Errorf("IsText(%q) = %v, want %v", tt.data, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtractLines tests that text inputs are reported line by line with
+// their line numbers and offsets, however the input is read
+func TestExtractLines(t *testing.T) {
+	data := "first line\r\nab\n\nsecond line here\nunterminated"
+
+	type found struct {
+		str    string
+		offset int64
+		line   int64
+	}
+	want := []found{{"first line", 0, 1}, {"second line here", 16, 4}, {"unterminated", 33, 5}}
+
+	check := func(t *testing.T, got []found) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("lines = %v, want %v", got, want)
+		}
+		for i := range got {
+			if got[i] != want[i] {
+				t.Errorf("line %d = %v, want %v", i, got[i], want[i])
+			}
+		}
+	}
+	config := Config{MinLength: 4, Encoding: "s", Lines: true}
+
+	t.Run("reader", func(t *testing.T) {
+		var got []found
+		err := ExtractStringsContext(context.Background(), iotest.OneByteReader(strings.NewReader(data)), "", config, func(str []byte, _ string, offset int64, cfg Config) {
+			got = append(got, found{string(str), offset, cfg.Line})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		check(t, got)
+	})
+
+	t.Run("file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "app.log")
+		if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		var got []found
+		if err := ExtractStringsFromFile(path, config, func(str []byte, _ string, offset int64, cfg Config) {
+			got = append(got, found{string(str), offset, cfg.Line})
+		}); err != nil {
+			t.Fatal(err)
+		}
+		check(t, got)
+	})
+}
+
+// TestExtractLinesBinary tests that inputs that are not text are scanned for
+// printable runs as without Lines
+func TestExtractLinesBinary(t *testing.T) {
+	data := "\x7fELF\x02\x01\x01\x00\x00hello world\x00"
+	config := Config{MinLength: 4, Encoding: "s", Lines: true}
+
+	var got []string
+	err := ExtractStringsContext(context.Background(), strings.NewReader(data), "", config, func(str []byte, _ string, _ int64, cfg Config) {
+		if cfg.Line != 0 {
+			t.Errorf("Line = %d for a binary input, want 0", cfg.Line)
+		}
+		got = append(got, string(str))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "hello world" {
+		t.Errorf("strings = %q, want [\"hello world\"]", got)
+	}
+}
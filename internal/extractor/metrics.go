@@ -0,0 +1,65 @@
+package extractor
+
+import (
+	"io"
+	"time"
+)
+
+// Metrics accumulates I/O and per-stage costs during extraction. Set
+// Config.Metrics to collect them; a Metrics value must not be shared between
+// concurrent extractions.
+type Metrics struct {
+	BytesRead  int64         // Bytes read from files, sections and readers
+	ReadTime   time.Duration // Time spent reading input
+	FilterTime time.Duration // Time spent applying match/exclude patterns
+	OutputTime time.Duration // Time spent in the print callback
+}
+
+// meteredReader records bytes read and time spent reading into Metrics
+type meteredReader struct {
+	reader  io.Reader
+	metrics *Metrics
+}
+
+// Read implements io.Reader
+func (r *meteredReader) Read(p []byte) (int, error) {
+	start := time.Now()
+	n, err := r.reader.Read(p)
+	r.metrics.ReadTime += time.Since(start)
+	r.metrics.BytesRead += int64(n)
+	return n, err
+}
+
+// meterReader wraps reader to record read metrics if enabled
+func meterReader(reader io.Reader, config Config) io.Reader {
+	if config.Metrics == nil {
+		return reader
+	}
+	return &meteredReader{reader: reader, metrics: config.Metrics}
+}
+
+// meterPrintFunc wraps printFunc to record output time if metrics are enabled
+func meterPrintFunc(printFunc func([]byte, string, int64, Config), config Config) func([]byte, string, int64, Config) {
+	metrics := config.Metrics
+	if metrics == nil {
+		return printFunc
+	}
+	return func(str []byte, filename string, offset int64, cfg Config) {
+		start := time.Now()
+		printFunc(str, filename, offset, cfg)
+		metrics.OutputTime += time.Since(start)
+	}
+}
+
+// shouldPrint applies ShouldPrintString, recording filter time if metrics are
+// enabled and patterns are configured
+func shouldPrint(str []byte, config Config) bool {
+	if config.Metrics == nil || (len(config.MatchPatterns) == 0 && len(config.ExcludePatterns) == 0) {
+		return ShouldPrintString(str, config)
+	}
+
+	start := time.Now()
+	ok := ShouldPrintString(str, config)
+	config.Metrics.FilterTime += time.Since(start)
+	return ok
+}
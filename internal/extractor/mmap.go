@@ -1,89 +1,142 @@
 package extractor
 
 import (
-	"encoding/binary"
+	"context"
 	"fmt"
 	"io"
 	"os"
-	"unicode/utf8"
+	"time"
 
 	"golang.org/x/exp/mmap"
 )
 
-// shouldUseMmap determines if memory-mapped I/O should be used for the given file.
-// It returns false if:
+// ShouldUseMmap reports whether ExtractStringsFromFile uses memory-mapped I/O
+// for the given file (see ChooseStrategy). It returns false if:
 // - mmap is disabled via config
 // - the file is below the threshold size
 // - the file cannot be stat'd
 // - the file is not a regular file (e.g., pipe, device)
-func shouldUseMmap(path string, config Config) bool {
-	// Check if mmap is disabled
-	if config.DisableMmap {
-		return false
-	}
-
-	// Get file info
-	info, err := os.Stat(path)
-	if err != nil {
-		return false
-	}
-
-	// Only use mmap for regular files
-	if !info.Mode().IsRegular() {
-		return false
-	}
-
-	// Check if file size meets threshold
-	return info.Size() >= config.MmapThreshold
+// - the file is larger than the memory budget (the mapped file is copied into memory)
+// - another strategy is forced by config
+func ShouldUseMmap(path string, config Config) bool {
+	strategy := ChooseStrategy(path, config)
+	return strategy == StrategyMmap || strategy == StrategyChunked
 }
 
 // ExtractStringsFromFile extracts strings from a file, automatically choosing
-// between memory-mapped I/O (for large files) or buffered I/O (for small files).
+// how to read it by its size (see ChooseStrategy): tiny files are read whole,
+// medium ones through a buffer, large ones memory-mapped and huge ones
+// memory-mapped and scanned in chunks by parallel workers.
 //
 // This function provides transparent optimization - it will use mmap when
 // beneficial and fall back to buffered I/O when appropriate.
+//
+// With config.Evidence or config.VirtualDisk, evidence containers and virtual
+// disks are scanned by the media they hold (see OpenImage), at offsets in the
+// media. With config.MaxStrings, strings after the first MaxStrings are not
+// reported (see LimitStrings); with config.Background, strings are scored by
+// rarity, and with config.SortRarity reported rarest first (see ScoreRarity).
 func ExtractStringsFromFile(path string, config Config, printFunc func([]byte, string, int64, Config)) error {
-	// Decide whether to use mmap
-	if shouldUseMmap(path, config) {
+	printFunc, flush := ScoreRarity(LimitStrings(printFunc, path, config), config)
+	defer flush()
+	img, err := OpenImage(path, config)
+	if err != nil {
+		return fmt.Errorf("error reading container: %w", err)
+	}
+	if img != nil {
+		defer func() {
+			if closeErr := img.Close(); closeErr != nil {
+				Warn(config, Warning{Severity: SeverityWarning, Code: WarnCloseFailed, File: path, Message: "error closing container", Err: closeErr})
+			}
+		}()
+		warnRead(config, ExtractStringsContext(context.Background(), io.NewSectionReader(img, 0, img.Size()), path, config, printFunc))
+		return nil
+	}
+
+	switch strategy := ChooseStrategy(path, config); strategy {
+	case StrategyRead:
+		return extractStringsRead(path, config, printFunc)
+	case StrategyMmap, StrategyChunked:
 		// Try mmap first
-		err := extractStringsWithMmap(path, config, printFunc)
+		err := extractStringsWithMmap(path, config, printFunc, strategy == StrategyChunked)
 		if err == nil {
 			return nil
 		}
 		// If mmap fails, fall back to buffered I/O
 		// This can happen due to permissions, OS limits, etc.
-		fmt.Fprintf(os.Stderr, "warning: mmap failed for %s: %v, falling back to buffered I/O\n", path, err)
+		Warn(config, Warning{Severity: SeverityWarning, Code: WarnMmapFallback, File: path,
+			Message: "mmap failed, falling back to buffered I/O", Err: err})
 	}
 
 	// Fall back to traditional buffered I/O
-	file, err := os.Open(path)
+	var file *os.File
+	err = config.Retry.Do(path, config, func() (err error) {
+		file, err = os.Open(path)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("error opening file: %w", err)
 	}
 	defer func() {
 		if closeErr := file.Close(); closeErr != nil {
 			// Log error but don't override successful extraction
-			fmt.Fprintf(os.Stderr, "warning: error closing file %s: %v\n", path, closeErr)
+			Warn(config, Warning{Severity: SeverityWarning, Code: WarnCloseFailed, File: path, Message: "error closing file", Err: closeErr})
 		}
 	}()
 
-	ExtractStrings(file, path, config, printFunc)
+	warnRead(config, ExtractStringsContext(context.Background(), file, path, config, printFunc))
+	return nil
+}
+
+// extractStringsRead extracts strings from a file read whole in one call
+func extractStringsRead(path string, config Config, printFunc func([]byte, string, int64, Config)) error {
+	readStart := time.Now()
+	var data []byte
+	err := config.Retry.Do(path, config, func() (err error) {
+		data, err = os.ReadFile(path)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("error reading file: %w", err)
+	}
+	if config.Throttle != nil {
+		config.Throttle.Wait(len(data))
+	}
+	if config.Metrics != nil {
+		config.Metrics.ReadTime += time.Since(readStart)
+		config.Metrics.BytesRead += int64(len(data))
+	}
+
+	printFunc = meterPrintFunc(printFunc, config)
+	config, skip := applyBOM(data, path, config)
+	config = applyLines(data[skip:min(len(data), skip+textSniffLen)], config)
+	printFunc, config = mergeScripts(printFunc, config)
+
+	if !knownEncoding(config.Encoding) {
+		return fmt.Errorf("unsupported encoding: %s", config.Encoding)
+	}
+	scanBytes(newScanner(path, config, printFunc), data[skip:], int64(skip))
 	return nil
 }
 
 // extractStringsWithMmap extracts strings using memory-mapped I/O.
 // It uses the golang.org/x/exp/mmap package to map the file into memory
-// and then delegates to the appropriate *FromBytes() function.
-func extractStringsWithMmap(path string, config Config, printFunc func([]byte, string, int64, Config)) error {
+// and then scans the mapped data in memory, in chunks by parallel workers if
+// chunked is set (see scanChunked).
+func extractStringsWithMmap(path string, config Config, printFunc func([]byte, string, int64, Config), chunked bool) error {
 	// Open the file with mmap
-	reader, err := mmap.Open(path)
+	var reader *mmap.ReaderAt
+	err := config.Retry.Do(path, config, func() (err error) {
+		reader, err = mmap.Open(path)
+		return err
+	})
 	if err != nil {
 		return fmt.Errorf("error memory-mapping file: %w", err)
 	}
 	defer func() {
 		if closeErr := reader.Close(); closeErr != nil {
 			// Log error but don't override successful extraction
-			fmt.Fprintf(os.Stderr, "warning: error closing mmap reader for %s: %v\n", path, closeErr)
+			Warn(config, Warning{Severity: SeverityWarning, Code: WarnCloseFailed, File: path, Message: "error closing memory map", Err: closeErr})
 		}
 	}()
 
@@ -92,106 +145,32 @@ func extractStringsWithMmap(path string, config Config, printFunc func([]byte, s
 
 	// Read the entire file into memory
 	// Note: mmap.ReaderAt implements ReadAt, we need to read into a slice
+	readStart := time.Now()
 	data := make([]byte, fileSize)
-	n, err := reader.ReadAt(data, 0)
+	n, err := readAtThrottled(reader, data, config)
 	if err != nil && err != io.EOF {
 		return fmt.Errorf("error reading memory-mapped file: %w", err)
 	}
 	data = data[:n]
-
-	// Delegate to the appropriate extraction function based on encoding
-	// These functions are already optimized for in-memory byte slices
-	switch config.Encoding {
-	case "s":
-		// 7-bit ASCII
-		if config.Unicode != "" && config.Unicode != "default" && config.Unicode != "invalid" {
-			// UTF-8 aware mode
-			extractUTF8AwareFromBytes(data, path, config, printFunc)
-		} else {
-			extractASCIIFromBytes(data, 0, path, config, printFunc, false)
-		}
-	case "S":
-		// 8-bit ASCII
-		extractASCIIFromBytes(data, 0, path, config, printFunc, true)
-	case "b":
-		// UTF-16 big-endian
-		extractUTF16FromBytes(data, 0, path, config, printFunc, binary.BigEndian)
-	case "l":
-		// UTF-16 little-endian
-		extractUTF16FromBytes(data, 0, path, config, printFunc, binary.LittleEndian)
-	case "B":
-		// UTF-32 big-endian
-		extractUTF32FromBytes(data, 0, path, config, printFunc, binary.BigEndian)
-	case "L":
-		// UTF-32 little-endian
-		extractUTF32FromBytes(data, 0, path, config, printFunc, binary.LittleEndian)
-	default:
-		return fmt.Errorf("unsupported encoding: %s", config.Encoding)
+	if config.Metrics != nil {
+		config.Metrics.ReadTime += time.Since(readStart)
+		config.Metrics.BytesRead += int64(n)
 	}
 
-	return nil
-}
+	printFunc = meterPrintFunc(printFunc, config)
+	config, skip := applyBOM(data, path, config)
+	config = applyLines(data[skip:min(len(data), skip+textSniffLen)], config)
+	printFunc, config = mergeScripts(printFunc, config)
 
-// extractUTF8AwareFromBytes is a helper that wraps the byte-slice extraction
-// for UTF-8 aware mode. This function didn't exist before, so we create it here.
-func extractUTF8AwareFromBytes(data []byte, filename string, config Config, printFunc func([]byte, string, int64, Config)) {
-	// For UTF-8 aware mode, we need to process byte-by-byte like the streaming version
-	// We can't use the simple ASCII extractor because we need UTF-8 validation
-	var currentString []byte
-	var startOffset int64
-
-	for i := 0; i < len(data); {
-		b := data[i]
-
-		// Check if this is the start of a UTF-8 sequence
-		if b >= 0x80 {
-			// Decode the UTF-8 rune using standard library
-			r, size := utf8.DecodeRune(data[i:])
-
-			// Check if the rune is valid (not replacement character due to invalid UTF-8)
-			if r != utf8.RuneError || size == 1 {
-				// Valid UTF-8 sequence - add to current string
-				if len(currentString) == 0 {
-					startOffset = int64(i)
-				}
-				currentString = append(currentString, data[i:i+size]...)
-				i += size
-				continue
-			}
-
-			// Invalid UTF-8 - treat as non-printable
-			if len(currentString) >= config.MinLength {
-				if ShouldPrintString(currentString, config) {
-					printFunc(currentString, filename, startOffset, config)
-				}
-			}
-			currentString = currentString[:0]
-			i++
-			continue
-		}
-
-		// Single-byte character
-		if isPrintableASCII(b, config.Encoding == "S", config.IncludeAllWhitespace) {
-			if len(currentString) == 0 {
-				startOffset = int64(i)
-			}
-			currentString = append(currentString, b)
-		} else {
-			// Non-printable character
-			if len(currentString) >= config.MinLength {
-				if ShouldPrintString(currentString, config) {
-					printFunc(currentString, filename, startOffset, config)
-				}
-			}
-			currentString = currentString[:0]
-		}
-		i++
+	if !knownEncoding(config.Encoding) {
+		return fmt.Errorf("unsupported encoding: %s", config.Encoding)
 	}
-
-	// Handle any remaining string at EOF
-	if len(currentString) >= config.MinLength {
-		if ShouldPrintString(currentString, config) {
-			printFunc(currentString, filename, startOffset, config)
-		}
+	// Lines are numbered from the start of the file, so text is not chunked
+	if chunked && !config.Lines {
+		scanChunked(data[skip:], int64(skip), chunkSize, path, config, printFunc)
+		return nil
 	}
+	scanBytes(newScanner(path, config, printFunc), data[skip:], int64(skip))
+
+	return nil
 }
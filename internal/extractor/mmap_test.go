@@ -2,6 +2,7 @@ package extractor
 
 import (
 	"bytes"
+	"encoding/binary"
 	"os"
 	"path/filepath"
 	"testing"
@@ -76,13 +77,33 @@ func TestShouldUseMmap(t *testing.T) {
 			},
 			wantMmap: true,
 		},
+		{
+			name: "Large file exceeding memory budget",
+			path: largeFile,
+			config: Config{
+				DisableMmap:   false,
+				MmapThreshold: 1 * 1024 * 1024, // 1MB
+				MaxMemory:     4 * 1024 * 1024, // 4MB
+			},
+			wantMmap: false,
+		},
+		{
+			name: "Large file within memory budget",
+			path: largeFile,
+			config: Config{
+				DisableMmap:   false,
+				MmapThreshold: 1 * 1024 * 1024,  // 1MB
+				MaxMemory:     64 * 1024 * 1024, // 64MB
+			},
+			wantMmap: true,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := shouldUseMmap(tt.path, tt.config)
+			got := ShouldUseMmap(tt.path, tt.config)
 			if got != tt.wantMmap {
-				t.Errorf("shouldUseMmap() = %v, want %v", got, tt.wantMmap)
+				t.Errorf("ShouldUseMmap() = %v, want %v", got, tt.wantMmap)
 			}
 		})
 	}
@@ -355,3 +376,69 @@ func TestMmapNonexistentFile(t *testing.T) {
 		t.Error("Expected error for nonexistent file, got nil")
 	}
 }
+
+// TestExtractStringsFromFileEvidence tests scanning the media of an EnCase
+// image with Evidence, at offsets in the media, and the raw file without it
+func TestExtractStringsFromFileEvidence(t *testing.T) {
+	le := binary.LittleEndian
+	media := make([]byte, 64)
+	copy(media[8:], "secret-in-media")
+
+	// One segment: the volume, sectors and table sections, then done
+	image := []byte("EVF\x09\x0d\x0a\xff\x00\x01\x01\x00\x00\x00")
+	section := func(kind string, data []byte, last bool) {
+		descriptor := make([]byte, 76)
+		copy(descriptor, kind)
+		next := len(image) + 76 + len(data)
+		if last {
+			next = len(image)
+		}
+		le.PutUint64(descriptor[16:], uint64(next))
+		le.PutUint64(descriptor[24:], uint64(76+len(data)))
+		image = append(append(image, descriptor...), data...)
+	}
+	volume := make([]byte, 1052)
+	le.PutUint32(volume[8:], 1)   // Sectors per chunk
+	le.PutUint32(volume[12:], 64) // Bytes per sector
+	le.PutUint64(volume[16:], 1)  // Sectors
+	section("volume", volume, false)
+	chunkOffset := len(image) + 76
+	section("sectors", append(append([]byte{}, media...), 0, 0, 0, 0), false)
+	table := make([]byte, 28)
+	le.PutUint32(table, 1)
+	le.PutUint64(table[8:], uint64(chunkOffset))
+	section("table", table, false)
+	section("done", nil, true)
+
+	path := filepath.Join(t.TempDir(), "disk.E01")
+	if err := os.WriteFile(path, image, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, enabled := range []bool{true, false} {
+		var got []string
+		var offsets []int64
+		config := Config{MinLength: 6, Encoding: "s", DisableMmap: true, Evidence: enabled}
+		err := ExtractStringsFromFile(path, config, func(str []byte, _ string, offset int64, _ Config) {
+			got = append(got, string(str))
+			offsets = append(offsets, offset)
+		})
+		if err != nil {
+			t.Fatalf("ExtractStringsFromFile(Evidence: %v) error = %v", enabled, err)
+		}
+		found := false
+		for i, str := range got {
+			if str == "secret-in-media" {
+				found = true
+				if want := int64(8); enabled && offsets[i] != want {
+					t.Errorf("offset = %d, want %d", offsets[i], want)
+				}
+			}
+			if enabled && str == "volume" {
+				t.Errorf("Evidence scan found the section name %q", str)
+			}
+		}
+		if !found {
+			t.Errorf("ExtractStringsFromFile(Evidence: %v) = %q, want secret-in-media", enabled, got)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package extractor
+
+import (
+	"bytes"
+	"cmp"
+	"slices"
+)
+
+// scoredString is a string held back by ScoreRarity until the end of its file
+type scoredString struct {
+	str      []byte
+	filename string
+	offset   int64
+	config   Config
+}
+
+// ScoreRarity wraps printFunc for --background: it sets the rarity of each
+// string in Config.Rarity (see bloom.Index.Rarity; strings too short to score
+// are 0, as common as noise). With config.SortRarity it holds the strings
+// back and passes them on rarest first, in the order found among equals, when
+// the returned flush is called at the end of the file. printFunc is returned
+// unchanged, with a flush doing nothing, if config.Background is not set.
+func ScoreRarity(printFunc func([]byte, string, int64, Config), config Config) (func([]byte, string, int64, Config), func()) {
+	if config.Background == nil {
+		return printFunc, func() {}
+	}
+	if !config.SortRarity {
+		return func(str []byte, filename string, offset int64, cfg Config) {
+			cfg.Rarity, _ = config.Background.Rarity(str)
+			printFunc(str, filename, offset, cfg)
+		}, func() {}
+	}
+
+	var held []scoredString
+	score := func(str []byte, filename string, offset int64, cfg Config) {
+		cfg.Rarity, _ = config.Background.Rarity(str)
+		// Strings and their raw bytes may be in buffers reused by the scanners
+		cfg.Raw = bytes.Clone(cfg.Raw)
+		held = append(held, scoredString{str: bytes.Clone(str), filename: filename, offset: offset, config: cfg})
+	}
+	flush := func() {
+		slices.SortStableFunc(held, func(a, b scoredString) int {
+			return cmp.Compare(b.config.Rarity, a.config.Rarity)
+		})
+		for _, s := range held {
+			printFunc(s.str, s.filename, s.offset, s.config)
+		}
+		held = nil
+	}
+	return score, flush
+}
@@ -0,0 +1,59 @@
+package extractor
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/bloom"
+)
+
+// TestScoreRarity tests that strings are scored against the corpus and, with
+// SortRarity, reported rarest first, within the --max-strings limit
+func TestScoreRarity(t *testing.T) {
+	corpus := &bloom.Index{}
+	for _, strs := range [][]string{{"kernel32.dll", "common string"}, {"kernel32.dll", "other string"}} {
+		set := bloom.GramSet{}
+		for _, str := range strs {
+			set.Add([]byte(str))
+		}
+		corpus.Samples = append(corpus.Samples, bloom.Sample{Name: strs[1], Filter: set.Filter(bloom.DefaultFalsePositiveRate)})
+	}
+
+	path := filepath.Join(t.TempDir(), "sample.bin")
+	if err := os.WriteFile(path, []byte("kernel32.dll\x00common string\x00beacon.example.com\x00"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+		want   []string
+	}{
+		{"in order", Config{MinLength: 4, Encoding: "s", Background: corpus}, []string{"kernel32.dll", "common string", "beacon.example.com"}},
+		{"rarest first", Config{MinLength: 4, Encoding: "s", Background: corpus, SortRarity: true}, []string{"beacon.example.com", "common string", "kernel32.dll"}},
+		{"rarest first, limited", Config{MinLength: 4, Encoding: "s", Background: corpus, SortRarity: true, MaxStrings: 1, Warnings: func(Warning) {}}, []string{"beacon.example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			rarities := map[string]float64{}
+			if err := ExtractStringsFromFile(path, tt.config, func(str []byte, _ string, _ int64, cfg Config) {
+				got = append(got, string(str))
+				rarities[string(str)] = cfg.Rarity
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("strings = %q, want %q", got, tt.want)
+			}
+			if r, ok := rarities["beacon.example.com"]; ok && r != 1 {
+				t.Errorf("rarity of a string in no sample = %v, want 1", r)
+			}
+			if r, ok := rarities["kernel32.dll"]; ok && r != 0 {
+				t.Errorf("rarity of a string in every sample = %v, want 0", r)
+			}
+		})
+	}
+}
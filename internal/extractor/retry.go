@@ -0,0 +1,98 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// Retry retries opens and reads that fail with transient I/O errors, as
+// network filesystems report them when a server is briefly unavailable. Set
+// Config.Retry to use it; one Retry is shared by all workers of a run, so it
+// counts the retries of the run as a whole.
+type Retry struct {
+	attempts int           // Retries after the first attempt
+	backoff  time.Duration // Wait before the first retry, doubled for each next one
+	count    atomic.Int64  // Retries made
+	sleep    func(time.Duration)
+}
+
+// NewRetry returns a policy retrying a failed operation up to attempts times,
+// waiting backoff before the first retry and twice as long before each next
+// one
+func NewRetry(attempts int, backoff time.Duration) *Retry {
+	return &Retry{attempts: attempts, backoff: backoff, sleep: time.Sleep}
+}
+
+// Count returns the number of retries made. A nil Retry made none.
+func (r *Retry) Count() int64 {
+	if r == nil {
+		return 0
+	}
+	return r.count.Load()
+}
+
+// Do runs op, running it again while it fails with a transient error (see
+// IsTransient) and attempts are left. Each retry is reported as an info
+// warning about filename. A nil Retry runs op once.
+func (r *Retry) Do(filename string, config Config, op func() error) error {
+	err := op()
+	if r == nil {
+		return err
+	}
+	wait := r.backoff
+	for attempt := 1; attempt <= r.attempts && IsTransient(err); attempt++ {
+		Warn(config, Warning{Severity: SeverityInfo, Code: WarnRetried, File: filename,
+			Message: fmt.Sprintf("transient I/O error, retry %d of %d in %v", attempt, r.attempts, wait), Err: err})
+		r.count.Add(1)
+		r.sleep(wait)
+		wait *= 2
+		err = op()
+	}
+	return err
+}
+
+// IsTransient reports whether err is an I/O error that may not recur, such as
+// EIO or ESTALE from a network filesystem
+func IsTransient(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.EIO, syscall.ESTALE, syscall.EAGAIN, syscall.EINTR, syscall.ETIMEDOUT} {
+		if errors.Is(err, errno) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryingReader retries reads that fail with transient errors
+type retryingReader struct {
+	reader   io.Reader
+	filename string
+	config   Config
+}
+
+// Read implements io.Reader. A failed read leaves the file offset unchanged,
+// so it is repeated as is.
+func (r *retryingReader) Read(p []byte) (int, error) {
+	var n int
+	err := r.config.Retry.Do(r.filename, r.config, func() error {
+		var err error
+		n, err = r.reader.Read(p)
+		if n > 0 && IsTransient(err) {
+			return nil // Report the bytes read; the error recurs on the next read
+		}
+		return err
+	})
+	return n, err
+}
+
+// retryReader wraps reader to retry transient read errors if a retry policy
+// is set
+func retryReader(reader io.Reader, filename string, config Config) io.Reader {
+	if config.Retry == nil {
+		return reader
+	}
+	return &retryingReader{reader: reader, filename: filename, config: config}
+}
@@ -0,0 +1,103 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// flakyReader fails its first reads with err, then reads from reader
+type flakyReader struct {
+	reader   io.Reader
+	failures int
+	err      error
+}
+
+// Read implements io.Reader
+func (r *flakyReader) Read(p []byte) (int, error) {
+	if r.failures > 0 {
+		r.failures--
+		return 0, r.err
+	}
+	return r.reader.Read(p)
+}
+
+// TestRetryDo tests the backoff and the errors retried
+func TestRetryDo(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  int
+		err       error
+		wantErr   bool
+		wantCalls int
+		wantSlept []time.Duration
+	}{
+		{"success", 0, nil, false, 1, nil},
+		{"recovers", 2, &os.PathError{Op: "read", Path: "f", Err: syscall.EIO}, false, 3, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond}},
+		{"stale handle", 1, syscall.ESTALE, false, 2, []time.Duration{10 * time.Millisecond}},
+		{"gives up", 5, syscall.EIO, true, 4, []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 40 * time.Millisecond}},
+		{"not transient", 1, os.ErrNotExist, true, 1, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			retry := NewRetry(3, 10*time.Millisecond)
+			var slept []time.Duration
+			retry.sleep = func(d time.Duration) { slept = append(slept, d) }
+			var warnings []Warning
+			config := Config{Warnings: func(w Warning) { warnings = append(warnings, w) }}
+
+			calls, failures := 0, tt.failures
+			err := retry.Do("f", config, func() error {
+				calls++
+				if failures > 0 {
+					failures--
+					return tt.err
+				}
+				return nil
+			})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls || !slices.Equal(slept, tt.wantSlept) {
+				t.Errorf("Do() called op %d times sleeping %v, want %d times sleeping %v", calls, slept, tt.wantCalls, tt.wantSlept)
+			}
+			if retry.Count() != int64(len(tt.wantSlept)) || len(warnings) != len(tt.wantSlept) {
+				t.Errorf("Count() = %d with %d warnings, want %d", retry.Count(), len(warnings), len(tt.wantSlept))
+			}
+			for _, w := range warnings {
+				if w.Code != WarnRetried || w.Severity != SeverityInfo || w.File != "f" {
+					t.Errorf("warning = %+v, want an info %s warning about f", w, WarnRetried)
+				}
+			}
+		})
+	}
+
+	var none *Retry
+	if err := none.Do("f", Config{}, func() error { return syscall.EIO }); !errors.Is(err, syscall.EIO) || none.Count() != 0 {
+		t.Errorf("nil Retry: Do() = %v, Count() = %d", err, none.Count())
+	}
+}
+
+// TestRetriedExtraction tests that strings are complete after transient read
+// errors were retried
+func TestRetriedExtraction(t *testing.T) {
+	retry := NewRetry(2, 0)
+	retry.sleep = func(time.Duration) {}
+	config := Config{MinLength: 4, Encoding: "s", Retry: retry, Warnings: func(Warning) {}}
+
+	reader := &flakyReader{reader: strings.NewReader("first\x00second\x00"), failures: 2, err: fmt.Errorf("read: %w", syscall.EIO)}
+	var got []string
+	ExtractStrings(reader, "f", config, func(str []byte, _ string, _ int64, _ Config) { got = append(got, string(str)) })
+	if want := []string{"first", "second"}; !slices.Equal(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	if retry.Count() != 2 {
+		t.Errorf("Count() = %d, want 2", retry.Count())
+	}
+}
@@ -0,0 +1,146 @@
+package extractor
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Script types reported by DetectScriptType
+const (
+	ScriptShell      = "shell"
+	ScriptPowerShell = "powershell"
+	ScriptJavaScript = "javascript"
+	ScriptPython     = "python"
+	ScriptPerl       = "perl"
+	ScriptRuby       = "ruby"
+	ScriptPHP        = "php"
+)
+
+// shebangPattern matches an interpreter line with an absolute interpreter
+// path, capturing the interpreter and its first argument (for
+// "#!/usr/bin/env python")
+var shebangPattern = regexp.MustCompile(`^#![ \t]*(/[\w./+-]+)(?:[ \t]+([\w.+-]+))?(?:[ \t]|$)`)
+
+// shebangInterpreters maps interpreter names to script types
+var shebangInterpreters = map[string]string{
+	"sh": ScriptShell, "bash": ScriptShell, "dash": ScriptShell, "zsh": ScriptShell,
+	"ksh": ScriptShell, "ash": ScriptShell, "busybox": ScriptShell,
+	"pwsh": ScriptPowerShell, "powershell": ScriptPowerShell,
+	"node": ScriptJavaScript, "nodejs": ScriptJavaScript, "deno": ScriptJavaScript,
+	"python": ScriptPython, "perl": ScriptPerl, "ruby": ScriptRuby, "php": ScriptPHP,
+}
+
+// scriptLoader is a pattern for a line that starts or launches a script
+type scriptLoader struct {
+	scriptType string
+	pattern    *regexp.Regexp
+}
+
+// scriptLoaders lists the loader patterns, checked in order. Most require a
+// long base64 payload so ordinary text mentioning a tool does not match.
+var scriptLoaders = []scriptLoader{
+	// powershell.exe -nop -w hidden -enc <base64>
+	{ScriptPowerShell, regexp.MustCompile(`(?i)\b(?:powershell|pwsh)(?:\.exe)?\b.*\s[-/](?:e|ec|enc|encodedcommand)\s+[A-Za-z0-9+/=]{32,}`)},
+	// [Convert]::FromBase64String('<base64>')
+	{ScriptPowerShell, regexp.MustCompile(`(?i)frombase64string\(\s*['"][A-Za-z0-9+/=]{32,}`)},
+	// IEX (New-Object Net.WebClient).DownloadString(...)
+	{ScriptPowerShell, regexp.MustCompile(`(?i)\b(?:iex|invoke-expression)\b.*\b(?:downloadstring|net\.webclient)\b`)},
+	// eval(atob('<base64>')) and the classic p,a,c,k,e,d packer
+	{ScriptJavaScript, regexp.MustCompile(`(?i)\beval\s*\(\s*(?:atob|unescape)\s*\(\s*['"][A-Za-z0-9+/=%]{32,}`)},
+	{ScriptJavaScript, regexp.MustCompile(`\beval\s*\(\s*function\s*\(\s*p\s*,\s*a\s*,\s*c\s*,\s*k\s*,\s*e\s*,\s*[dr]\s*\)`)},
+	{ScriptJavaScript, regexp.MustCompile(`(?i)<script\b`)},
+	// echo <base64> | base64 -d | sh, curl ... | sh
+	{ScriptShell, regexp.MustCompile(`(?i)\b(?:echo|printf)\s+['"]?[A-Za-z0-9+/=]{32,}['"]?\s*\|\s*base64\s+(?:-d|--decode)\b`)},
+	{ScriptShell, regexp.MustCompile(`\b(?:curl|wget)\b[^|\n]*\|\s*(?:sudo\s+)?(?:ba|da|z)?sh\b`)},
+	// exec(base64.b64decode('<base64>'))
+	{ScriptPython, regexp.MustCompile(`\bexec\s*\(\s*(?:base64\.b64decode|__import__\(\s*['"]base64)`)},
+}
+
+// DetectScriptType returns the type of script that str starts with (a shebang
+// line or a known loader in its first line), or "" if it does not look like a
+// script.
+func DetectScriptType(str []byte) string {
+	line := str
+	if i := bytes.IndexAny(line, "\n\r\v\f"); i >= 0 {
+		line = line[:i]
+	}
+	line = bytes.TrimLeft(line, " \t")
+
+	if m := shebangPattern.FindSubmatch(line); m != nil {
+		interpreter := path.Base(string(m[1]))
+		if interpreter == "env" && len(m[2]) > 0 {
+			interpreter = string(m[2])
+		}
+		// python3, python3.11, perl5 ...
+		name := strings.TrimRight(interpreter, "0123456789.")
+		if scriptType, ok := shebangInterpreters[name]; ok {
+			return scriptType
+		}
+		return name
+	}
+
+	for _, loader := range scriptLoaders {
+		if loader.pattern.Match(line) {
+			return loader.scriptType
+		}
+	}
+	return ""
+}
+
+// scriptsSupported reports whether script merging applies to the configured
+// encoding: single-byte encodings without a Unicode display mode, so that
+// line offsets can be derived from byte positions
+func scriptsSupported(config Config) bool {
+	if config.Encoding != "" && config.Encoding != "s" && config.Encoding != "S" {
+		return false
+	}
+	return config.Unicode == "" || config.Unicode == "default" || config.Unicode == "invalid"
+}
+
+// mergeScripts wraps printFunc for --scripts. It returns a print function and
+// an extraction config that keeps whitespace inside strings; each run is then
+// split back at line breaks, except that everything from a line that
+// starts a script (see DetectScriptType) to the end of the run is printed as a
+// single multi-line string. Lines are printed exactly as without --scripts:
+// minimum length and filters apply per line (per script for scripts).
+func mergeScripts(printFunc func([]byte, string, int64, Config), config Config) (func([]byte, string, int64, Config), Config) {
+	// With -w line breaks are already part of strings, and lines of text
+	// inputs are reported whole
+	if !config.Scripts || config.IncludeAllWhitespace || config.Lines || !scriptsSupported(config) {
+		return printFunc, config
+	}
+
+	original := config
+	config.IncludeAllWhitespace = true
+	config = withoutFilters(config)
+
+	emit := func(str []byte, filename string, offset int64) {
+		if len(str) >= original.MinLength {
+			report(str, filename, offset, original, printFunc)
+		}
+	}
+
+	return func(str []byte, filename string, offset int64, _ Config) {
+		start := 0
+		for start < len(str) {
+			end := bytes.IndexAny(str[start:], "\n\r\v\f")
+			if end < 0 {
+				end = len(str)
+			} else {
+				end += start
+			}
+
+			line := str[start:end]
+			if len(bytes.TrimSpace(line)) > 0 && DetectScriptType(line) != "" {
+				// The script extends to the end of the run
+				emit(bytes.TrimRight(str[start:], " \t\n\r\v\f"), filename, offset+int64(start))
+				return
+			}
+
+			emit(line, filename, offset+int64(start))
+			start = end + 1
+		}
+	}, config
+}
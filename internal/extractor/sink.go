@@ -0,0 +1,142 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// Result is a string found by extraction
+type Result struct {
+	Str    []byte // The string, valid only until Emit returns
+	File   string // File the string was found in ("" for standard input)
+	Offset int64  // Offset of the string in the file
+	Config Config // Configuration of the scan, as passed to a printFunc
+}
+
+// StringSink receives the strings found by extraction. Unlike a printFunc
+// it can fail: an Emit error stops the extraction that feeds the sink (see
+// ExtractToSink). Emit blocks extraction until it returns, so a slow sink
+// slows extraction down rather than buffering strings. Close is called once
+// no more strings will be emitted.
+type StringSink interface {
+	Emit(Result) error
+	Close() error
+}
+
+// PrintFunc adapts a printFunc callback to a StringSink that never fails.
+// Its Close does nothing.
+type PrintFunc func([]byte, string, int64, Config)
+
+// Emit calls f with the result
+func (f PrintFunc) Emit(r Result) error {
+	f(r.Str, r.File, r.Offset, r.Config)
+	return nil
+}
+
+// Close implements StringSink
+func (f PrintFunc) Close() error {
+	return nil
+}
+
+// ExtractToSink is like ExtractStringsContext, emitting the strings to sink.
+// It stops at the first Emit error and returns it. The sink is not closed.
+func ExtractToSink(ctx context.Context, reader io.Reader, filename string, config Config, sink StringSink) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var emitErr error
+	err := ExtractStringsContext(ctx, reader, filename, config, func(str []byte, fname string, offset int64, cfg Config) {
+		// Strings of the chunk being scanned when Emit failed are dropped
+		if emitErr != nil {
+			return
+		}
+		if emitErr = sink.Emit(Result{Str: str, File: fname, Offset: offset, Config: cfg}); emitErr != nil {
+			cancel(emitErr)
+		}
+	})
+	if emitErr != nil {
+		return emitErr
+	}
+	return err
+}
+
+// teeSink emits each string to every sink
+type teeSink []StringSink
+
+// Tee returns a sink emitting each string to all of sinks, e.g. the text
+// output and a JSON report. Emit and Close reach every sink even if one
+// fails, and return the errors joined.
+func Tee(sinks ...StringSink) StringSink {
+	return teeSink(sinks)
+}
+
+func (ts teeSink) Emit(r Result) error {
+	var errs []error
+	for _, s := range ts {
+		errs = append(errs, s.Emit(r))
+	}
+	return errors.Join(errs...)
+}
+
+func (ts teeSink) Close() error {
+	var errs []error
+	for _, s := range ts {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// filterSink emits the strings keep accepts to next
+type filterSink struct {
+	next StringSink
+	keep func(Result) bool
+}
+
+// Filter returns a sink emitting to next the strings keep returns true for.
+// Closing it closes next.
+func Filter(next StringSink, keep func(Result) bool) StringSink {
+	return &filterSink{next: next, keep: keep}
+}
+
+func (fs *filterSink) Emit(r Result) error {
+	if !fs.keep(r) {
+		return nil
+	}
+	return fs.next.Emit(r)
+}
+
+func (fs *filterSink) Close() error {
+	return fs.next.Close()
+}
+
+// dedupeSink emits the first occurrence of each string of a file to next
+type dedupeSink struct {
+	next StringSink
+	file string
+	seen map[string]struct{}
+}
+
+// Dedupe returns a sink emitting to next the first occurrence of each
+// distinct string of a file, like --unique. Strings are remembered until the
+// file changes, so the strings of a file must be emitted together. Closing it
+// closes next.
+func Dedupe(next StringSink) StringSink {
+	return &dedupeSink{next: next, seen: make(map[string]struct{})}
+}
+
+func (ds *dedupeSink) Emit(r Result) error {
+	if r.File != ds.file {
+		ds.file = r.File
+		clear(ds.seen)
+	}
+	if _, ok := ds.seen[string(r.Str)]; ok {
+		return nil
+	}
+	ds.seen[string(r.Str)] = struct{}{}
+	return ds.next.Emit(r)
+}
+
+func (ds *dedupeSink) Close() error {
+	return ds.next.Close()
+}
@@ -0,0 +1,117 @@
+package extractor
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// collectSink records the strings emitted to it, failing from the failAt-th
+// (0 = never)
+type collectSink struct {
+	strings []string
+	failAt  int
+	closed  bool
+}
+
+func (cs *collectSink) Emit(r Result) error {
+	if cs.failAt > 0 && len(cs.strings)+1 >= cs.failAt {
+		return errors.New("sink full")
+	}
+	cs.strings = append(cs.strings, string(r.Str))
+	return nil
+}
+
+func (cs *collectSink) Close() error {
+	cs.closed = true
+	return nil
+}
+
+// TestExtractToSinkError tests that extraction stops at the first Emit error
+// and returns it
+func TestExtractToSinkError(t *testing.T) {
+	reader := iotest.OneByteReader(strings.NewReader("first\x00second\x00third\x00"))
+	sink := &collectSink{failAt: 2}
+
+	err := ExtractToSink(context.Background(), reader, "", Config{MinLength: 4, Encoding: "s"}, sink)
+	if err == nil || err.Error() != "sink full" {
+		t.Errorf("ExtractToSink() error = %v, want sink full", err)
+	}
+	if !slices.Equal(sink.strings, []string{"first"}) {
+		t.Errorf("strings = %q, want [first]", sink.strings)
+	}
+	if sink.closed {
+		t.Error("ExtractToSink() closed the sink")
+	}
+}
+
+// TestSinkComposition tests a filter, dedupe and tee chain feeding two sinks
+func TestSinkComposition(t *testing.T) {
+	data := "alpha\x00beta\x00alpha\x00gamma\x00beta\x00"
+	a, b := &collectSink{}, &collectSink{}
+	chain := Filter(Dedupe(Tee(a, b)), func(r Result) bool {
+		return !strings.HasPrefix(string(r.Str), "g")
+	})
+
+	if err := ExtractToSink(context.Background(), strings.NewReader(data), "", Config{MinLength: 4, Encoding: "s"}, chain); err != nil {
+		t.Fatalf("ExtractToSink() error = %v", err)
+	}
+	if err := chain.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	want := []string{"alpha", "beta"}
+	if !slices.Equal(a.strings, want) || !slices.Equal(b.strings, want) {
+		t.Errorf("strings = %q, %q, want %q in both", a.strings, b.strings, want)
+	}
+	if !a.closed || !b.closed {
+		t.Error("Close() did not reach every sink")
+	}
+}
+
+// TestTeeErrors tests that a failing sink does not starve the others
+func TestTeeErrors(t *testing.T) {
+	failing, ok := &collectSink{failAt: 1}, &collectSink{}
+	err := Tee(failing, ok).Emit(Result{Str: []byte("hello")})
+	if err == nil {
+		t.Error("Emit() error = nil, want sink full")
+	}
+	if !slices.Equal(ok.strings, []string{"hello"}) {
+		t.Errorf("strings = %q, want [hello]", ok.strings)
+	}
+}
+
+// TestDedupeFiles tests that strings are deduplicated per file
+func TestDedupeFiles(t *testing.T) {
+	sink := &collectSink{}
+	dedupe := Dedupe(sink)
+	for _, r := range []Result{
+		{Str: []byte("hello"), File: "a"},
+		{Str: []byte("hello"), File: "a"},
+		{Str: []byte("hello"), File: "b"},
+	} {
+		if err := dedupe.Emit(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(sink.strings) != 2 {
+		t.Errorf("strings = %q, want hello once per file", sink.strings)
+	}
+}
+
+// TestPrintFunc tests the adapter of printFunc callbacks
+func TestPrintFunc(t *testing.T) {
+	var got []string
+	sink := PrintFunc(func(str []byte, filename string, _ int64, _ Config) {
+		got = append(got, filename+": "+string(str))
+	})
+	if err := ExtractToSink(context.Background(), strings.NewReader("hello\x00"), "a.bin", Config{MinLength: 4}, sink); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []string{"a.bin: hello"}) {
+		t.Errorf("strings = %q", got)
+	}
+}
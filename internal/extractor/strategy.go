@@ -0,0 +1,171 @@
+package extractor
+
+import (
+	"encoding/binary"
+	"os"
+	"slices"
+	"sync"
+)
+
+// Strategy is how ExtractStringsFromFile reads a file (see ChooseStrategy)
+type Strategy string
+
+// Read strategies, from the smallest files to the largest
+const (
+	StrategyAuto     Strategy = ""         // Chosen by file size
+	StrategyRead     Strategy = "read"     // Read whole in one call (tiny files)
+	StrategyBuffered Strategy = "buffered" // Streamed through a buffer (medium files, or any file under a memory budget)
+	StrategyMmap     Strategy = "mmap"     // Memory-mapped (large files)
+	StrategyChunked  Strategy = "chunked"  // Memory-mapped and scanned in chunks by parallel workers (huge files)
+)
+
+// ReadThreshold is the largest file read whole in one call rather than
+// through a buffer
+const ReadThreshold = 64 << 10
+
+// DefaultChunkThreshold is the default Config.ChunkThreshold
+const DefaultChunkThreshold = 256 << 20
+
+// chunkSize is the size chunked scans aim for, large enough that workers
+// spend their time scanning rather than starting
+const chunkSize = 16 << 20
+
+// ChooseStrategy returns the strategy ExtractStringsFromFile uses for the
+// file at path. Config.Strategy forces one, otherwise it follows the size of
+// the file: tiny files (up to ReadThreshold, below Config.MmapThreshold) are
+// read whole, files below Config.MmapThreshold are buffered, larger ones
+// memory-mapped, and those of Config.ChunkThreshold or more scanned in chunks
+// if Config.ChunkWorkers allows. Files that are not regular, exceed the memory
+// budget or cannot be memory-mapped (--no-mmap) are buffered whatever the
+// choice.
+func ChooseStrategy(path string, config Config) Strategy {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() {
+		return StrategyBuffered
+	}
+	size := info.Size()
+	if config.MaxMemory > 0 && size > config.MaxMemory {
+		return StrategyBuffered
+	}
+
+	strategy := config.Strategy
+	if strategy == StrategyAuto {
+		threshold := config.ChunkThreshold
+		if threshold <= 0 {
+			threshold = DefaultChunkThreshold
+		}
+		switch {
+		case size < config.MmapThreshold && size <= ReadThreshold:
+			strategy = StrategyRead
+		case size < config.MmapThreshold:
+			strategy = StrategyBuffered
+		case size >= threshold:
+			strategy = StrategyChunked
+		default:
+			strategy = StrategyMmap
+		}
+	}
+
+	if (strategy == StrategyMmap || strategy == StrategyChunked) && config.DisableMmap {
+		return StrategyBuffered
+	}
+	if strategy == StrategyChunked && config.ChunkWorkers < 2 {
+		return StrategyMmap
+	}
+	return strategy
+}
+
+// splitChunks returns the ends of the chunks of data for a chunked scan.
+// Chunks end after four zero bytes aligned to four bytes, which end the
+// strings of every encoding and leave no scanner state behind, so each chunk
+// can be scanned on its own. A chunk extends past size until such bytes are
+// found, and data without any is a single chunk.
+func splitChunks(data []byte, size int) []int {
+	var ends []int
+	start := 0
+	for start < len(data) {
+		end := len(data)
+		for i := (start + size + 3) &^ 3; i+4 <= len(data); i += 4 {
+			if binary.LittleEndian.Uint32(data[i:]) == 0 {
+				end = i + 4
+				break
+			}
+		}
+		ends = append(ends, end)
+		start = end
+	}
+	return ends
+}
+
+// chunkEvent is a string found in a chunk, reported once the chunks before it
+// were
+type chunkEvent struct {
+	str      []byte
+	offset   int64
+	config   Config
+	rejected bool // Dropped by the filters (reported to Config.Rejected)
+}
+
+// scanChunked scans data, the input from offset base, in chunks of about size
+// bytes with up to config.ChunkWorkers workers, reporting the strings to
+// printFunc in order, as scanBytes would
+func scanChunked(data []byte, base int64, size int, filename string, config Config, printFunc func([]byte, string, int64, Config)) {
+	ends := splitChunks(data, size)
+	events := make([][]chunkEvent, len(ends))
+	metrics := make([]Metrics, len(ends))
+	done := make([]chan struct{}, len(ends))
+	for i := range done {
+		done[i] = make(chan struct{})
+	}
+
+	jobs := make(chan int, len(ends))
+	for i := range ends {
+		jobs <- i
+	}
+	close(jobs)
+
+	var wg sync.WaitGroup
+	for range min(config.ChunkWorkers, len(ends)) {
+		wg.Go(func() {
+			for i := range jobs {
+				start := 0
+				if i > 0 {
+					start = ends[i-1]
+				}
+				record := func(rejected bool) func([]byte, string, int64, Config) {
+					return func(str []byte, _ string, offset int64, cfg Config) {
+						cfg.Raw = slices.Clone(cfg.Raw)
+						events[i] = append(events[i], chunkEvent{str: slices.Clone(str), offset: offset, config: cfg, rejected: rejected})
+					}
+				}
+				chunkConfig := config
+				if config.Metrics != nil {
+					chunkConfig.Metrics = &metrics[i]
+				}
+				if config.Rejected != nil {
+					chunkConfig.Rejected = record(true)
+				}
+				scanBytes(newScanner(filename, chunkConfig, record(false)), data[start:ends[i]], base+int64(start))
+				close(done[i])
+			}
+		})
+	}
+
+	// Report each chunk's strings as soon as it and all earlier chunks are done
+	for i := range ends {
+		<-done[i]
+		for _, event := range events[i] {
+			event.config.Metrics, event.config.Rejected = config.Metrics, config.Rejected
+			if event.rejected {
+				config.Rejected(event.str, filename, event.offset, event.config)
+			} else {
+				printFunc(event.str, filename, event.offset, event.config)
+			}
+		}
+		events[i] = nil
+		if config.Metrics != nil {
+			config.Metrics.FilterTime += metrics[i].FilterTime
+		}
+	}
+	wg.Wait()
+}
@@ -0,0 +1,111 @@
+package extractor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"testing"
+)
+
+// TestChooseStrategy tests the strategy chosen by file size and settings
+func TestChooseStrategy(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name string, size int) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, make([]byte, size), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	tiny := write("tiny", 100)
+	medium := write("medium", ReadThreshold+1)
+	large := write("large", 2<<20)
+
+	base := Config{MmapThreshold: 1 << 20, ChunkThreshold: 2 << 20, ChunkWorkers: 4}
+	tests := []struct {
+		name   string
+		path   string
+		modify func(*Config)
+		want   Strategy
+	}{
+		{"tiny file", tiny, nil, StrategyRead},
+		{"medium file", medium, nil, StrategyBuffered},
+		{"large file", large, func(c *Config) { c.ChunkThreshold = 4 << 20 }, StrategyMmap},
+		{"huge file", large, nil, StrategyChunked},
+		{"huge file with one worker", large, func(c *Config) { c.ChunkWorkers = 1 }, StrategyMmap},
+		{"default chunk threshold", large, func(c *Config) { c.ChunkThreshold = 0 }, StrategyMmap},
+		{"no mmap", large, func(c *Config) { c.DisableMmap = true }, StrategyBuffered},
+		{"over memory budget", large, func(c *Config) { c.MaxMemory = 1 << 20 }, StrategyBuffered},
+		{"forced read", large, func(c *Config) { c.Strategy = StrategyRead }, StrategyRead},
+		{"forced chunked", tiny, func(c *Config) { c.Strategy = StrategyChunked }, StrategyChunked},
+		{"directory", dir, nil, StrategyBuffered},
+		{"missing file", filepath.Join(dir, "missing"), nil, StrategyBuffered},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := base
+			if tt.modify != nil {
+				tt.modify(&config)
+			}
+			if got := ChooseStrategy(tt.path, config); got != tt.want {
+				t.Errorf("ChooseStrategy() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestScanChunked tests that chunked scans report the strings of a plain scan,
+// in order, across chunk boundaries
+func TestScanChunked(t *testing.T) {
+	var data []byte
+	for i := range 2000 {
+		data = append(data, bytes.Repeat([]byte{'a' + byte(i%26)}, 3+i%17)...)
+		data = append(data, make([]byte, i%6)...)
+		data = append(data, []byte{'w', 0, 'i', 0, 'd', 0, 'e', 0, 0xff}...)
+	}
+
+	tests := []struct {
+		name   string
+		config Config
+	}{
+		{"ascii", Config{MinLength: 4, Encoding: "s"}},
+		{"utf-16le", Config{MinLength: 4, Encoding: "l"}},
+		{"all encodings", Config{MinLength: 4, Encoding: "B"}},
+		{"filtered", Config{MinLength: 4, Encoding: "s", ExcludePatterns: []*regexp.Regexp{regexp.MustCompile("^[aeiou]")}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			type found struct {
+				value  string
+				offset int64
+			}
+			collect := func(strings *[]found) func([]byte, string, int64, Config) {
+				return func(str []byte, _ string, offset int64, _ Config) {
+					*strings = append(*strings, found{string(str), offset})
+				}
+			}
+
+			var want, wantRejected []found
+			config := tt.config
+			config.Rejected = collect(&wantRejected)
+			scanBytes(newScanner("f", config, collect(&want)), data, 0)
+
+			for _, size := range []int{1, 64, 1000} {
+				var got, gotRejected []found
+				config := tt.config
+				config.Rejected = collect(&gotRejected)
+				config.ChunkWorkers = 3
+				ends := splitChunks(data, size)
+				if len(ends) < 2 || ends[len(ends)-1] != len(data) {
+					t.Fatalf("splitChunks(%d) = %d chunks ending at %d, want several ending at %d", size, len(ends), ends[len(ends)-1], len(data))
+				}
+				scanChunked(data, 0, size, "f", config, collect(&got))
+				if !slices.Equal(got, want) || !slices.Equal(gotRejected, wantRejected) {
+					t.Errorf("chunks of %d: got %d strings and %d rejected, want %d and %d", size, len(got), len(gotRejected), len(want), len(wantRejected))
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package extractor
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// throttleChunk is the size of the reads a throttled memory-mapped file is
+// copied in, so that a large file is spread over time instead of taken in
+// one burst
+const throttleChunk = 1 << 20
+
+// Throttle limits the combined read bandwidth of extractions with a token
+// bucket. Set Config.Throttle to use it; one Throttle is shared by all workers
+// of a run, so the limit applies to the run as a whole.
+type Throttle struct {
+	mu     sync.Mutex
+	rate   float64   // Bytes per second
+	tokens float64   // Available bytes; negative if reads are ahead of the rate
+	last   time.Time // Time tokens were last refilled
+	sleep  func(time.Duration)
+}
+
+// NewThrottle returns a throttle allowing bytesPerSecond bytes per second,
+// with bursts of up to one second's worth
+func NewThrottle(bytesPerSecond int64) *Throttle {
+	return &Throttle{
+		rate:   float64(bytesPerSecond),
+		tokens: float64(bytesPerSecond),
+		last:   time.Now(),
+		sleep:  time.Sleep,
+	}
+}
+
+// Wait takes n bytes from the bucket, sleeping until the rate allows them.
+// Reads larger than the burst are admitted and paid for by waiting longer.
+func (t *Throttle) Wait(n int) {
+	t.mu.Lock()
+	now := time.Now()
+	t.tokens = min(t.tokens+now.Sub(t.last).Seconds()*t.rate, t.rate)
+	t.last = now
+	t.tokens -= float64(n)
+	deficit := -t.tokens
+	t.mu.Unlock()
+
+	if deficit > 0 {
+		t.sleep(time.Duration(deficit / t.rate * float64(time.Second)))
+	}
+}
+
+// throttledReader waits for the throttle after each read
+type throttledReader struct {
+	reader   io.Reader
+	throttle *Throttle
+}
+
+// Read implements io.Reader
+func (r *throttledReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.throttle.Wait(n)
+	}
+	return n, err
+}
+
+// throttleReader wraps reader to limit its bandwidth if a throttle is set
+func throttleReader(reader io.Reader, config Config) io.Reader {
+	if config.Throttle == nil {
+		return reader
+	}
+	return &throttledReader{reader: reader, throttle: config.Throttle}
+}
+
+// readAtThrottled fills data from reader at offset 0, in chunks paced by the
+// throttle if one is set
+func readAtThrottled(reader io.ReaderAt, data []byte, config Config) (int, error) {
+	if config.Throttle == nil {
+		return reader.ReadAt(data, 0)
+	}
+	total := 0
+	for total < len(data) {
+		end := min(total+throttleChunk, len(data))
+		config.Throttle.Wait(end - total)
+		n, err := reader.ReadAt(data[total:end], int64(total))
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,57 @@
+package extractor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestThrottleWait tests that reads beyond the burst wait for the rate
+func TestThrottleWait(t *testing.T) {
+	throttle := NewThrottle(1000)
+	var slept time.Duration
+	throttle.sleep = func(d time.Duration) { slept += d }
+
+	// The first second's worth is a burst
+	throttle.Wait(1000)
+	if slept != 0 {
+		t.Errorf("burst slept %v, want 0", slept)
+	}
+
+	// 500 more bytes take about half a second
+	throttle.Wait(500)
+	if slept < 400*time.Millisecond || slept > 500*time.Millisecond {
+		t.Errorf("slept %v, want about 500ms", slept)
+	}
+}
+
+// TestThrottledExtraction tests that throttled buffered and mmap reads
+// extract the same strings and are paced by the throttle
+func TestThrottledExtraction(t *testing.T) {
+	data := bytes.Repeat([]byte("throttled string\x00"), 200000) // 3.4 MB
+	path := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, disableMmap := range []bool{false, true} {
+		throttle := NewThrottle(1 << 20)
+		var slept time.Duration
+		throttle.sleep = func(d time.Duration) { slept += d }
+
+		config := Config{MinLength: 4, Encoding: "s", MmapThreshold: 1024, DisableMmap: disableMmap, Throttle: throttle}
+		count := 0
+		if err := ExtractStringsFromFile(path, config, func([]byte, string, int64, Config) { count++ }); err != nil {
+			t.Fatalf("ExtractStringsFromFile() error = %v", err)
+		}
+		if count != 200000 {
+			t.Errorf("mmap disabled=%v: got %d strings, want 200000", disableMmap, count)
+		}
+		// 3.4 MB at 1 MB/s after a 1 MB burst
+		if slept < 2*time.Second {
+			t.Errorf("mmap disabled=%v: slept %v, want at least 2s", disableMmap, slept)
+		}
+	}
+}
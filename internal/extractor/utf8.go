@@ -0,0 +1,190 @@
+package extractor
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/richardwooding/txtr/internal/bufpool"
+)
+
+// utf8Scanner extracts UTF-8 strings for the -U display modes. It validates
+// multi-byte sequences as they arrive with the state machine of the UTF-8
+// grammar (RFC 3629), so each byte is examined once and sequences may span
+// writes.
+type utf8Scanner struct {
+	filename  string
+	config    Config
+	printFunc func([]byte, string, int64, Config)
+
+	buf, outBuf *[]byte // Pooled buffers of current and output
+	current     []byte  // Raw bytes of the current string
+	output      []byte  // Displayed form of the current string (see Config.Unicode)
+	start       int64   // Offset of the current string
+
+	seq      [utf8.UTFMax]byte // Bytes of the sequence being decoded
+	seqLen   int               // Bytes in seq
+	need     int               // Continuation bytes still expected
+	lo, hi   byte              // Valid range of the next continuation byte
+	seqStart int64             // Offset of the sequence being decoded
+}
+
+// newUTF8Scanner returns a scanner reporting strings to printFunc. Call end
+// when the input is exhausted.
+func newUTF8Scanner(filename string, config Config, printFunc func([]byte, string, int64, Config)) *utf8Scanner {
+	s := &utf8Scanner{
+		filename:  filename,
+		config:    config,
+		printFunc: printFunc,
+		buf:       bufpool.Get(bufpool.StringSize),
+		outBuf:    bufpool.Get(bufpool.StringSize),
+	}
+	s.current, s.output = *s.buf, *s.outBuf
+	return s
+}
+
+// utf8Lead returns the number of continuation bytes following a lead byte and
+// the valid range of the first one, or 0 if b cannot start a sequence.
+// The narrowed ranges exclude overlong forms (E0, F0), surrogates (ED) and
+// code points above U+10FFFF (F4).
+func utf8Lead(b byte) (need int, lo, hi byte) {
+	switch {
+	case b >= 0xC2 && b <= 0xDF:
+		return 1, 0x80, 0xBF
+	case b == 0xE0:
+		return 2, 0xA0, 0xBF
+	case b == 0xED:
+		return 2, 0x80, 0x9F
+	case b >= 0xE1 && b <= 0xEF:
+		return 2, 0x80, 0xBF
+	case b == 0xF0:
+		return 3, 0x90, 0xBF
+	case b >= 0xF1 && b <= 0xF3:
+		return 3, 0x80, 0xBF
+	case b == 0xF4:
+		return 3, 0x80, 0x8F
+	default:
+		return 0, 0, 0
+	}
+}
+
+// write processes data, the input at offset. Printable ASCII outside a
+// sequence, the common case, is appended directly.
+func (s *utf8Scanner) write(data []byte, offset int64) {
+	for i, b := range data {
+		if b >= ' ' && b <= '~' && s.need == 0 && len(s.current) > 0 {
+			s.current = append(s.current, b)
+			s.output = append(s.output, b)
+			continue
+		}
+		s.feed(b, offset+int64(i))
+	}
+}
+
+// feed processes the byte at offset
+func (s *utf8Scanner) feed(b byte, offset int64) {
+	if s.need > 0 {
+		if b >= s.lo && b <= s.hi {
+			s.seq[s.seqLen] = b
+			s.seqLen++
+			s.need--
+			s.lo, s.hi = 0x80, 0xBF
+			if s.need == 0 {
+				r, _ := utf8.DecodeRune(s.seq[:s.seqLen])
+				s.addRune(r, s.seq[:s.seqLen], s.seqStart)
+			}
+			return
+		}
+		// Invalid sequence: it ends the string, and b is examined on its own
+		s.need = 0
+		s.flush()
+	}
+
+	if b < utf8.RuneSelf {
+		if isPrintableASCII(b, false, s.config.IncludeAllWhitespace) {
+			if len(s.current) == 0 {
+				s.start = offset
+			}
+			s.current = append(s.current, b)
+			s.output = append(s.output, b)
+		} else {
+			s.flush()
+		}
+		return
+	}
+
+	need, lo, hi := utf8Lead(b)
+	if need == 0 {
+		s.flush()
+		return
+	}
+	s.seq[0], s.seqLen = b, 1
+	s.need, s.lo, s.hi = need, lo, hi
+	s.seqStart = offset
+}
+
+// addRune appends a decoded multi-byte rune, formatted for the display mode,
+// or ends the string if the rune is not printable
+func (s *utf8Scanner) addRune(r rune, raw []byte, offset int64) {
+	if !isPrintableRune(r, s.config.IncludeAllWhitespace) {
+		s.flush()
+		return
+	}
+	if len(s.current) == 0 {
+		s.start = offset
+	}
+	s.current = append(s.current, raw...)
+
+	switch s.config.Unicode {
+	case "escape":
+		s.output = appendHexRune(s.output, `\u`, r, 4, "")
+	case "hex":
+		s.output = appendHexRune(s.output, "<", r, 2, ">")
+	case "highlight":
+		s.output = appendHexRune(s.output, "\033[1m\\u", r, 4, "\033[0m")
+	default: // locale
+		s.output = append(s.output, raw...)
+	}
+}
+
+// appendHexRune appends r in lowercase hex, zero-padded to width digits,
+// between prefix and suffix (like fmt's %0*x, without allocating)
+func appendHexRune(dst []byte, prefix string, r rune, width int, suffix string) []byte {
+	var digits [8]byte
+	hex := strconv.AppendUint(digits[:0], uint64(r), 16)
+	dst = append(dst, prefix...)
+	for range width - len(hex) {
+		dst = append(dst, '0')
+	}
+	dst = append(dst, hex...)
+	return append(dst, suffix...)
+}
+
+// flush reports the current string if it is long enough and starts a new one
+func (s *utf8Scanner) flush() {
+	if len(s.current) >= s.config.MinLength {
+		config := s.config
+		if config.EmitRaw {
+			config.Raw = s.current
+		}
+		report(s.output, s.filename, s.start, config, s.printFunc)
+	}
+	s.current = s.current[:0]
+	s.output = s.output[:0]
+}
+
+// end reports the last string, dropping an incomplete trailing sequence, and
+// returns the scanner's buffers to the pool
+func (s *utf8Scanner) end() {
+	s.need = 0
+	s.flush()
+	*s.buf, *s.outBuf = s.current, s.output
+	bufpool.Put(s.buf)
+	bufpool.Put(s.outBuf)
+}
+
+// extractUTF8Aware extracts strings with UTF-8 awareness and special display modes
+func extractUTF8Aware(reader io.Reader, filename string, config Config, printFunc func([]byte, string, int64, Config)) {
+	warnRead(config, scanReader(context.Background(), newUTF8Scanner(filename, config, printFunc), reader, filename, 0))
+}
@@ -0,0 +1,58 @@
+package extractor
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"testing"
+)
+
+// TestUTF8ScannerPaths tests that the streaming and byte-slice UTF-8 paths
+// agree, including on malformed input
+func TestUTF8ScannerPaths(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		unicode string
+		want    []string
+	}{
+		{"valid", "\x00héllo wörld\x00", "locale", []string{"1:héllo wörld"}},
+		{"escape", "\x00naïve\x00", "escape", []string{"1:na\\u00efve"}},
+		{"hex", "\x00日本語text\x00", "hex", []string{"1:<65e5><672c><8a9e>text"}},
+		{"truncated sequence", "abcd\xe6\x97efgh", "locale", []string{"0:abcd", "6:efgh"}},
+		{"truncated at end", "abcd\xe6\x97", "locale", []string{"0:abcd"}},
+		{"overlong", "abcd\xc0\xafefgh", "locale", []string{"0:abcd", "6:efgh"}},
+		{"overlong three bytes", "abcd\xe0\x80\xafefgh", "locale", []string{"0:abcd", "7:efgh"}},
+		{"surrogate", "abcd\xed\xa0\x80efgh", "locale", []string{"0:abcd", "7:efgh"}},
+		{"above U+10FFFF", "abcd\xf4\x90\x80\x80efgh", "locale", []string{"0:abcd", "8:efgh"}},
+		{"stray continuation", "abcd\x80efgh", "locale", []string{"0:abcd", "5:efgh"}},
+		{"non-printable rune", "abcd\xc2\x85efgh", "locale", []string{"0:abcd", "6:efgh"}},
+		{"lead restarts", "abcd\xe6\xc3\xa9fg", "locale", []string{"0:abcd", "5:éfg"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := Config{MinLength: 4, Encoding: "s", Unicode: tt.unicode}
+			collect := func(got *[]string) func([]byte, string, int64, Config) {
+				return func(str []byte, _ string, offset int64, _ Config) {
+					*got = append(*got, fmt.Sprintf("%d:%s", offset, str))
+				}
+			}
+
+			var streamed, sliced, section []string
+			extractUTF8Aware(bytes.NewReader([]byte(tt.input)), "", config, collect(&streamed))
+			scanBytes(newUTF8Scanner("", config, collect(&sliced)), []byte(tt.input), 0)
+			ExtractFromSection([]byte(tt.input), "", 0, "", config, collect(&section))
+
+			if !slices.Equal(streamed, tt.want) {
+				t.Errorf("streaming = %q, want %q", streamed, tt.want)
+			}
+			if !slices.Equal(sliced, tt.want) {
+				t.Errorf("byte slice = %q, want %q", sliced, tt.want)
+			}
+			if !slices.Equal(section, tt.want) {
+				t.Errorf("section = %q, want %q", section, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,170 @@
+package extractor
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Severity classifies a Warning
+type Severity int
+
+const (
+	// SeverityInfo reports a noteworthy event, e.g. a packed binary was unpacked.
+	SeverityInfo Severity = iota
+	// SeverityWarning reports a problem extraction worked around, e.g. a
+	// fallback to a full scan. Results are complete.
+	SeverityWarning
+	// SeverityError reports a problem that cut extraction short, e.g. a read
+	// error. Results up to the problem were reported.
+	SeverityError
+)
+
+// String returns the lowercase name of the severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityInfo:
+		return "info"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "error"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler, so severities appear by name
+// in JSON
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (s *Severity) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "info":
+		*s = SeverityInfo
+	case "warning":
+		*s = SeverityWarning
+	case "error":
+		*s = SeverityError
+	default:
+		return fmt.Errorf("unknown severity %q", text)
+	}
+	return nil
+}
+
+// Warning codes identify the kind of a Warning independently of its message
+const (
+	WarnMmapFallback  = "mmap-fallback"  // Memory mapping failed, the file was read with buffered I/O
+	WarnCloseFailed   = "close-failed"   // Closing an input failed after extraction
+	WarnReadFailed    = "read-failed"    // Reading an input failed, later strings are missing
+	WarnParseFallback = "parse-fallback" // The binary format could not be parsed, the whole file was scanned
+	WarnPacked        = "packed"         // The binary appears packed or encrypted
+	WarnUnpacked      = "unpacked"       // Strings come from the unpacked image (--unpack)
+	WarnUnpackFailed  = "unpack-failed"  // Unpacking failed, the packed image was scanned
+	WarnLiteralPools  = "literal-pools"  // Literal pools could not be resolved (--literal-pools)
+	WarnXrefs         = "xrefs"          // Cross-references could not be scanned (--xrefs)
+	WarnRelocs        = "relocs"         // Relocations could not be read (--relocs)
+	WarnSignatures    = "signatures"     // The signature scan failed (--signatures)
+	WarnExtractFS     = "extract-fs"     // An embedded filesystem could not be read (--extract-fs)
+	WarnArtifact      = "artifact"       // A registry hive, event log, prefetch file or NTFS image could not be read in full
+	WarnRetried       = "retried"        // An open or read failed with a transient error and was retried (--retries)
+	WarnDuplicate     = "duplicate"      // The input has the content of another input and was not scanned (--dedupe-inputs)
+	WarnStreams       = "streams"        // The alternate data streams or resource fork of a file could not be listed (--ads)
+	WarnXattrs        = "xattrs"         // The extended attributes of a file could not be read (--xattrs, --scan-xattrs)
+	WarnArchive       = "archive"        // An archive or one of its members could not be read (--archives)
+	WarnEncrypted     = "encrypted"      // An encrypted archive member could not be decrypted (--password)
+	WarnArchiveLimit  = "archive-limit"  // An archive member was skipped or truncated, or a nested archive not expanded (--max-depth, --max-expansion, --max-extracted)
+	WarnSampled       = "sampled"        // Only windows of the file were scanned (--coverage)
+	WarnNotify        = "notify"         // The summary of the scan could not be posted (--notify-webhook)
+	WarnExec          = "exec"           // A command run for a string failed (--exec-per-match)
+	WarnJournal       = "journal"        // The scan journal could not be written (--journal)
+	WarnBOM           = "bom"            // The input starts with a byte order mark and was scanned in its encoding (see DetectBOM)
+	WarnMaxStrings    = "max-strings"    // Strings after the first --max-strings of the file were not reported (see LimitStrings)
+)
+
+// Warning is a problem that did not stop a scan, reported through
+// Config.Warnings (or on standard error if it is nil)
+type Warning struct {
+	Severity Severity
+	Code     string // One of the Warn* codes
+	File     string // File the warning is about ("" for standard input)
+	Message  string // Human-readable description, without Err
+	Err      error  // Underlying error, if any
+}
+
+// Text returns the message followed by the underlying error, if any
+func (w Warning) Text() string {
+	if w.Err == nil {
+		return w.Message
+	}
+	return w.Message + ": " + w.Err.Error()
+}
+
+// String formats the warning as a line of diagnostic output, e.g.
+// "strings: app.exe: warning: cannot parse as ELF, falling back to full scan: EOF"
+func (w Warning) String() string {
+	var b strings.Builder
+	b.WriteString("strings: ")
+	if w.File != "" {
+		b.WriteString(w.File)
+		b.WriteString(": ")
+	}
+	if w.Severity != SeverityInfo {
+		b.WriteString(w.Severity.String())
+		b.WriteString(": ")
+	}
+	b.WriteString(w.Text())
+	return b.String()
+}
+
+// ReadError is a read error that cut extraction short, returned by
+// ExtractStringsContext. The strings before Offset were reported.
+type ReadError struct {
+	File   string // File being read ("" for standard input)
+	Offset int64  // Bytes read before the error
+	Err    error
+}
+
+// Error implements error, e.g. "error reading app.exe at offset 4096: EIO"
+func (e *ReadError) Error() string {
+	file := e.File
+	if file == "" {
+		file = "standard input"
+	}
+	return fmt.Sprintf("error reading %s at offset %d: %v", file, e.Offset, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
+// Warning returns the error as the warning ExtractStrings reports
+func (e *ReadError) Warning() Warning {
+	return Warning{Severity: SeverityError, Code: WarnReadFailed, File: e.File, Message: "error reading", Err: e.Err}
+}
+
+// warnRead reports err, an error of ExtractStringsContext, as a warning. It
+// does nothing if err is nil.
+func warnRead(config Config, err error) {
+	var readErr *ReadError
+	switch {
+	case err == nil:
+	case errors.As(err, &readErr):
+		Warn(config, readErr.Warning())
+	default:
+		Warn(config, Warning{Severity: SeverityError, Code: WarnReadFailed, Message: "extraction stopped", Err: err})
+	}
+}
+
+// Warn reports w to config.Warnings, or writes it to standard error if no
+// callback is set
+func Warn(config Config, w Warning) {
+	if config.Warnings != nil {
+		config.Warnings(w)
+		return
+	}
+	fmt.Fprintln(os.Stderr, w)
+}
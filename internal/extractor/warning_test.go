@@ -0,0 +1,82 @@
+package extractor
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+// TestWarningString tests the diagnostic line of each severity
+func TestWarningString(t *testing.T) {
+	tests := []struct {
+		name    string
+		warning Warning
+		want    string
+	}{
+		{"warning", Warning{Severity: SeverityWarning, File: "app.exe", Message: "cannot parse as PE", Err: io.ErrUnexpectedEOF}, "strings: app.exe: warning: cannot parse as PE: unexpected EOF"},
+		{"error", Warning{Severity: SeverityError, File: "disk.img", Message: "error reading", Err: errors.New("I/O error")}, "strings: disk.img: error: error reading: I/O error"},
+		{"info", Warning{Severity: SeverityInfo, File: "packed", Message: "unpacked UPX"}, "strings: packed: unpacked UPX"},
+		{"stdin", Warning{Severity: SeverityError, Message: "error reading"}, "strings: error: error reading"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.warning.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestSeverityJSON tests that severities are encoded by name and decoded back
+func TestSeverityJSON(t *testing.T) {
+	severities := []Severity{SeverityInfo, SeverityWarning, SeverityError}
+	data, err := json.Marshal(severities)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `["info","warning","error"]`; string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+
+	var decoded []Severity
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(decoded, severities) {
+		t.Errorf("json.Unmarshal() = %v, want %v", decoded, severities)
+	}
+	var s Severity
+	if err := json.Unmarshal([]byte(`"fatal"`), &s); err == nil {
+		t.Error("json.Unmarshal() of an unknown severity succeeded")
+	}
+}
+
+// TestReadErrorWarning tests that a read error is reported through
+// Config.Warnings after the strings read before it
+func TestReadErrorWarning(t *testing.T) {
+	readErr := errors.New("device not ready")
+	reader := io.MultiReader(strings.NewReader("hello world\x00"), iotest.ErrReader(readErr))
+
+	var got []string
+	var warnings []Warning
+	config := Config{MinLength: 4, Encoding: "s", Warnings: func(w Warning) { warnings = append(warnings, w) }}
+	ExtractStrings(reader, "disk.img", config, func(str []byte, _ string, _ int64, _ Config) {
+		got = append(got, string(str))
+	})
+
+	if len(got) != 1 || got[0] != "hello world" {
+		t.Errorf("strings = %q, want [\"hello world\"]", got)
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("got %d warnings, want 1", len(warnings))
+	}
+	w := warnings[0]
+	if w.Severity != SeverityError || w.Code != WarnReadFailed || w.File != "disk.img" || !errors.Is(w.Err, readErr) {
+		t.Errorf("warning = %+v", w)
+	}
+}
@@ -0,0 +1,102 @@
+// Package fingerprint identifies third-party libraries, tools and compilers
+// embedded in a binary from the version banners and idents they leave in its
+// strings.
+package fingerprint
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// Kinds of component identified by a signature
+const (
+	KindLibrary     = "library"
+	KindApplication = "application"
+	KindCompiler    = "compiler"
+	KindOS          = "os"
+	KindFirmware    = "firmware"
+)
+
+// Library is a component identified from a string
+type Library struct {
+	Name    string
+	Kind    string
+	Version string
+	CPE     string // CPE 2.3 name for vulnerability matching (see CPE)
+}
+
+// signature recognizes the version banner of one component
+type signature struct {
+	name    string
+	kind    string
+	cpe     string         // CPE part, vendor and product, e.g. "a:openssl:openssl"
+	keyword string         // Literal every match contains, checked before the pattern
+	pattern *regexp.Regexp // The first capture group is the version
+}
+
+// signatures is the signature database, checked in order
+var signatures = []signature{
+	// TLS and crypto
+	{"OpenSSL", KindLibrary, "a:openssl:openssl", "OpenSSL ", regexp.MustCompile(`OpenSSL (\d+\.\d+\.\d+[a-z]?)(?:-[\w.]+)?\s+\d{1,2} [A-Z][a-z]{2} \d{4}`)},
+	{"LibreSSL", KindLibrary, "a:openbsd:libressl", "LibreSSL ", regexp.MustCompile(`LibreSSL (\d+\.\d+\.\d+)\b`)},
+	{"Mbed TLS", KindLibrary, "a:arm:mbed_tls", "bed TLS ", regexp.MustCompile(`\b[Mm]bed TLS (\d+\.\d+\.\d+)\b`)},
+
+	// Compression and parsing
+	{"zlib", KindLibrary, "a:zlib:zlib", "flate ", regexp.MustCompile(`\b(?:de|in)flate (\d+\.\d+(?:\.\d+)*) Copyright`)},
+	{"libpng", KindLibrary, "a:libpng:libpng", "libpng version ", regexp.MustCompile(`libpng version (\d+\.\d+\.\d+)\b`)},
+	{"expat", KindLibrary, "a:libexpat_project:libexpat", "expat_", regexp.MustCompile(`^expat_(\d+\.\d+\.\d+)$`)},
+
+	// Network
+	{"libcurl", KindLibrary, "a:haxx:libcurl", "libcurl/", regexp.MustCompile(`\blibcurl/(\d+\.\d+\.\d+)\b`)},
+	{"curl", KindApplication, "a:haxx:curl", "curl ", regexp.MustCompile(`^curl (\d+\.\d+\.\d+) \(`)},
+	{"OpenSSH", KindApplication, "a:openbsd:openssh", "OpenSSH_", regexp.MustCompile(`^(?:SSH-2\.0-)?OpenSSH_(\d+\.\d+(?:p\d+)?)(?:\s|$)`)},
+	{"Dropbear", KindApplication, "a:dropbear_ssh_project:dropbear_ssh", "dropbear_", regexp.MustCompile(`\bdropbear_(\d{4}\.\d+)\b`)},
+	{"BusyBox", KindApplication, "a:busybox:busybox", "BusyBox v", regexp.MustCompile(`\bBusyBox v(\d+\.\d+(?:\.\d+)?)`)},
+	{"nginx", KindApplication, "a:f5:nginx", "nginx/", regexp.MustCompile(`^nginx/(\d+\.\d+\.\d+)$`)},
+	{"lighttpd", KindApplication, "a:lighttpd:lighttpd", "lighttpd/", regexp.MustCompile(`^lighttpd/(\d+\.\d+\.\d+)$`)},
+
+	// Boot loaders and kernels
+	{"U-Boot", KindFirmware, "a:denx:u-boot", "U-Boot ", regexp.MustCompile(`\bU-Boot (\d{4}\.\d{2}(?:\.\d+)?)\S* \(`)},
+	{"Linux", KindOS, "o:linux:linux_kernel", "Linux version ", regexp.MustCompile(`\bLinux version (\d+\.\d+(?:\.\d+)?)\S* \(`)},
+
+	// Compiler idents
+	{"GCC", KindCompiler, "a:gnu:gcc", "GCC: ", regexp.MustCompile(`\bGCC: \([^)]*\) (\d+\.\d+(?:\.\d+)?)`)},
+	{"clang", KindCompiler, "a:llvm:clang", "clang version ", regexp.MustCompile(`\bclang version (\d+\.\d+(?:\.\d+)?)`)},
+	{"rustc", KindCompiler, "a:rust-lang:rust", "rustc version ", regexp.MustCompile(`\brustc version (\d+\.\d+\.\d+)`)},
+	{"Go", KindCompiler, "a:golang:go", "go1.", regexp.MustCompile(`^go(1\.\d+(?:\.\d+)?)$`)},
+}
+
+// Identify returns the library whose version banner str contains, if any
+func Identify(str []byte) (Library, bool) {
+	for _, sig := range signatures {
+		if !bytes.Contains(str, []byte(sig.keyword)) {
+			continue
+		}
+		if m := sig.pattern.FindSubmatch(str); m != nil {
+			version := string(m[1])
+			return Library{Name: sig.name, Kind: sig.kind, Version: version, CPE: CPE(sig.cpe, version)}, true
+		}
+	}
+	return Library{}, false
+}
+
+// patchLevel matches versions with a portable patch level such as "9.2p1",
+// which CPE names split into version "9.2" and update "p1"
+var patchLevel = regexp.MustCompile(`^(\d+(?:\.\d+)*)(p\d+)$`)
+
+// CPE returns the CPE 2.3 formatted string for a product ("part:vendor:product")
+// at a version, or "" if product is empty
+func CPE(product, version string) string {
+	if product == "" {
+		return ""
+	}
+	update := "*"
+	if m := patchLevel.FindStringSubmatch(version); m != nil {
+		version, update = m[1], m[2]
+	}
+	if version == "" {
+		version = "*"
+	}
+	return strings.Join([]string{"cpe:2.3", product, version, update, "*", "*", "*", "*", "*", "*"}, ":")
+}
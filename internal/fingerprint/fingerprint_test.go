@@ -0,0 +1,79 @@
+package fingerprint
+
+import "testing"
+
+// TestIdentify tests library identification from version banners
+func TestIdentify(t *testing.T) {
+	tests := []struct {
+		input   string
+		name    string
+		version string
+	}{
+		{"OpenSSL 3.0.17 1 Jul 2025", "OpenSSL", "3.0.17"},
+		{"OpenSSL 1.1.1w  11 Sep 2023", "OpenSSL", "1.1.1w"},
+		{"OpenSSL 1.0.2u-fips  20 Dec 2019", "OpenSSL", "1.0.2u"},
+		{" deflate 1.2.13 Copyright 1995-2022 Jean-loup Gailly and Mark Adler ", "zlib", "1.2.13"},
+		{" inflate 1.3.1 Copyright 1995-2024 Mark Adler ", "zlib", "1.3.1"},
+		{"libcurl/7.88.1", "libcurl", "7.88.1"},
+		{"curl 7.88.1 (x86_64-pc-linux-gnu) %s", "curl", "7.88.1"},
+		{" libpng version 1.6.39 - November 20, 2022", "libpng", "1.6.39"},
+		{"expat_2.5.0", "expat", "2.5.0"},
+		{"OpenSSH_9.2p1 Debian-2+deb12u7", "OpenSSH", "9.2p1"},
+		{"SSH-2.0-dropbear_2022.83", "Dropbear", "2022.83"},
+		{"BusyBox v1.36.1 (2023-06-01 12:00:00 UTC) multi-call binary.", "BusyBox", "1.36.1"},
+		{"U-Boot 2020.04-rc1 (Jan 01 2020 - 00:00:00 +0000)", "U-Boot", "2020.04"},
+		{"Linux version 5.10.110-rt63 (builder@host) (gcc version 9.3.0) #1 SMP", "Linux", "5.10.110"},
+		{"GCC: (Ubuntu 11.4.0-1ubuntu1~22.04) 11.4.0", "GCC", "11.4.0"},
+		{"GCC: (GNU) 13.2.0", "GCC", "13.2.0"},
+		{"Apple clang version 15.0.0 (clang-1500.1.0.2.5)", "clang", "15.0.0"},
+		{"rustc version 1.75.0 (82e1608df 2023-12-21)", "rustc", "1.75.0"},
+		{"go1.22.1", "Go", "1.22.1"},
+		{"mbed TLS 2.28.3", "Mbed TLS", "2.28.3"},
+
+		// Mentions without a version banner
+		{"OpenSSL_version_num", "", ""},
+		{"requires OpenSSL 1.1.1 or later", "", ""},
+		{"libcurl/%s", "", ""},
+		{"OpenSSH_7.4*", "", ""},
+		{"fips140only_go1.26.go", "", ""},
+		{"plain string", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			lib, ok := Identify([]byte(tt.input))
+			if ok != (tt.name != "") {
+				t.Fatalf("Identify(%q) ok = %v, want %v (got %+v)", tt.input, ok, tt.name != "", lib)
+			}
+			if lib.Name != tt.name || lib.Version != tt.version {
+				t.Errorf("Identify(%q) = %s %s, want %s %s", tt.input, lib.Name, lib.Version, tt.name, tt.version)
+			}
+		})
+	}
+}
+
+// TestCPE tests CPE 2.3 name construction
+func TestCPE(t *testing.T) {
+	tests := []struct {
+		product string
+		version string
+		want    string
+	}{
+		{"a:openssl:openssl", "1.1.1w", "cpe:2.3:a:openssl:openssl:1.1.1w:*:*:*:*:*:*:*"},
+		{"a:openbsd:openssh", "9.2p1", "cpe:2.3:a:openbsd:openssh:9.2:p1:*:*:*:*:*:*"},
+		{"o:linux:linux_kernel", "5.10.110", "cpe:2.3:o:linux:linux_kernel:5.10.110:*:*:*:*:*:*:*"},
+		{"a:zlib:zlib", "", "cpe:2.3:a:zlib:zlib:*:*:*:*:*:*:*:*"},
+		{"", "1.0", ""},
+	}
+
+	for _, tt := range tests {
+		if got := CPE(tt.product, tt.version); got != tt.want {
+			t.Errorf("CPE(%q, %q) = %q, want %q", tt.product, tt.version, got, tt.want)
+		}
+	}
+
+	lib, _ := Identify([]byte("libcurl/7.88.1"))
+	if lib.CPE != "cpe:2.3:a:haxx:libcurl:7.88.1:*:*:*:*:*:*:*" {
+		t.Errorf("Identify() CPE = %q", lib.CPE)
+	}
+}
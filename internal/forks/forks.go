@@ -0,0 +1,30 @@
+// Package forks lists the extra data streams of files: the alternate data
+// streams of NTFS files on Windows (report.txt:hidden) and the resource
+// forks of macOS files (app/..namedfork/rsrc). Malware hides payloads in
+// them because tools reading a file only see its main stream.
+package forks
+
+import (
+	"errors"
+	"strings"
+)
+
+// ErrUnsupported is returned by List on platforms without alternate streams
+var ErrUnsupported = errors.New("alternate data streams and resource forks are not supported on this platform")
+
+// Stream is an extra data stream of a file
+type Stream struct {
+	Path string // Path that opens the stream, which also names it in output
+	Size int64
+}
+
+// streamName returns the name of a stream from its NTFS description
+// (":name:$DATA"), or "" for the main stream ("::$DATA") and streams that
+// hold no data
+func streamName(description string) string {
+	name, kind, ok := strings.Cut(strings.TrimPrefix(description, ":"), ":")
+	if !ok || kind != "$DATA" {
+		return ""
+	}
+	return name
+}
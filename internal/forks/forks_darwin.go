@@ -0,0 +1,25 @@
+//go:build darwin
+
+package forks
+
+import "os"
+
+// Supported reports whether List is implemented on this platform
+const Supported = true
+
+// List returns the resource fork of the file at path, named
+// path/..namedfork/rsrc, if it is not empty
+func List(path string) ([]Stream, error) {
+	fork := path + "/..namedfork/rsrc"
+	info, err := os.Stat(fork)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil // Not a regular file, or a file system without forks
+		}
+		return nil, err
+	}
+	if info.Size() == 0 {
+		return nil, nil
+	}
+	return []Stream{{Path: fork, Size: info.Size()}}, nil
+}
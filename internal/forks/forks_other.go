@@ -0,0 +1,11 @@
+//go:build !windows && !darwin
+
+package forks
+
+// Supported reports whether List is implemented on this platform
+const Supported = false
+
+// List reports ErrUnsupported: streams are only listed on Windows and macOS
+func List(string) ([]Stream, error) {
+	return nil, ErrUnsupported
+}
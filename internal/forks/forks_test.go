@@ -0,0 +1,22 @@
+package forks
+
+import "testing"
+
+// TestStreamName tests parsing NTFS stream descriptions
+func TestStreamName(t *testing.T) {
+	tests := []struct {
+		description string
+		want        string
+	}{
+		{"::$DATA", ""},
+		{":Zone.Identifier:$DATA", "Zone.Identifier"},
+		{":payload.exe:$DATA", "payload.exe"},
+		{":meta:$INDEX_ALLOCATION", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := streamName(tt.description); got != tt.want {
+			t.Errorf("streamName(%q) = %q, want %q", tt.description, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+//go:build windows
+
+package forks
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Supported reports whether List is implemented on this platform
+const Supported = true
+
+var (
+	kernel32        = syscall.NewLazyDLL("kernel32.dll")
+	findFirstStream = kernel32.NewProc("FindFirstStreamW")
+	findNextStream  = kernel32.NewProc("FindNextStreamW")
+)
+
+// errorHandleEOF is ERROR_HANDLE_EOF, returned when there are no more streams
+const errorHandleEOF syscall.Errno = 38
+
+// findStreamData is WIN32_FIND_STREAM_DATA
+type findStreamData struct {
+	size int64
+	name [syscall.MAX_PATH + 36]uint16
+}
+
+// List returns the alternate data streams of the file at path, named
+// path:stream, in the order NTFS lists them. Files on file systems without
+// streams have none.
+func List(path string) ([]Stream, error) {
+	name, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var data findStreamData
+	handle, _, err := findFirstStream.Call(uintptr(unsafe.Pointer(name)), 0, uintptr(unsafe.Pointer(&data)), 0)
+	if syscall.Handle(handle) == syscall.InvalidHandle {
+		if err == errorHandleEOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer func() { _ = syscall.FindClose(syscall.Handle(handle)) }()
+
+	var streams []Stream
+	for {
+		if stream := streamName(syscall.UTF16ToString(data.name[:])); stream != "" {
+			streams = append(streams, Stream{Path: path + ":" + stream, Size: data.size})
+		}
+		ok, _, err := findNextStream.Call(handle, uintptr(unsafe.Pointer(&data)))
+		if ok == 0 {
+			if err == errorHandleEOF {
+				return streams, nil
+			}
+			return streams, err
+		}
+	}
+}
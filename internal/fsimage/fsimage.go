@@ -0,0 +1,163 @@
+// Package fsimage reads the files of the embedded filesystems found by the
+// signature package (SquashFS, JFFS2, UBIFS and the UBIFS or SquashFS volumes
+// of UBI images) in memory, so the strings of a firmware image can be scanned
+// file by file and attributed to their paths without extracting the image.
+package fsimage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/richardwooding/txtr/internal/signature"
+)
+
+// File is a regular file of a filesystem image
+type File struct {
+	Path   string // Absolute path in the filesystem, e.g. /etc/passwd
+	Volume string // Name of the UBI volume holding the filesystem, "" outside UBI images
+	Data   []byte
+	Err    error // Set if parts of Data could not be read, which are left zero
+}
+
+// ErrUnsupported is returned (wrapped) for filesystems and file data using a
+// compression this package cannot decompress, such as xz or LZO
+var ErrUnsupported = errors.New("not supported")
+
+// maxImageSize is the largest filesystem read into memory
+const maxImageSize = 1 << 30
+
+// maxExpansion is the most a file's data can grow over the image holding it
+// (about the best ratio of deflate), which keeps the corrupt sizes of small
+// images from allocating gigabytes
+const maxExpansion = 1032
+
+// fileSizeLimit returns the largest file read from an image of imageSize bytes
+func fileSizeLimit(imageSize int) uint64 {
+	return min(maxImageSize, uint64(imageSize)*maxExpansion)
+}
+
+// maxDepth is the deepest directory nesting followed, which stops directory
+// loops in corrupted images
+const maxDepth = 64
+
+// Supported reports whether Walk can read filesystems of the signature region
+// type regionType
+func Supported(regionType string) bool {
+	switch regionType {
+	case signature.TypeSquashFS, signature.TypeJFFS2, signature.TypeUBIFS, signature.TypeUBI:
+		return true
+	}
+	return false
+}
+
+// Walk calls fn with each regular file of the filesystem in region (found by
+// signature.Scan in r), depth first in path order. It stops at the first error
+// fn returns. Problems with single files are reported in File.Err; Walk fails
+// if the filesystem as a whole cannot be read.
+func Walk(r io.ReaderAt, region signature.Region, fn func(File) error) error {
+	if !Supported(region.Type) {
+		return fmt.Errorf("%s: %w", region.Type, ErrUnsupported)
+	}
+	if region.Size <= 0 || region.Size > maxImageSize {
+		return fmt.Errorf("%s filesystem of unknown or excessive size", region.Type)
+	}
+
+	data := make([]byte, region.Size)
+	if _, err := r.ReadAt(data, region.Offset); err != nil && err != io.EOF {
+		return err
+	}
+
+	return walkRegion(region.Type, data, fn)
+}
+
+// walkRegion calls fn with the regular files of the filesystem of type
+// regionType in data
+func walkRegion(regionType string, data []byte, fn func(File) error) error {
+	switch regionType {
+	case signature.TypeSquashFS:
+		return walkSquashFS(data, fn)
+	case signature.TypeJFFS2:
+		return walkJFFS2(data, fn)
+	case signature.TypeUBIFS:
+		return walkUBIFS(data, fn)
+	default:
+		return walkUBI(data, fn)
+	}
+}
+
+// dirent is an entry of a directory, as listed by a log-structured filesystem
+type dirent struct {
+	name string
+	ino  uint64
+	dir  bool
+}
+
+// walkTree calls file with the path and inode of each regular file below the
+// directory root, depth first in name order. children lists the entries of a
+// directory.
+func walkTree(children map[uint64][]dirent, root uint64, file func(path string, ino uint64) error) error {
+	visited := map[uint64]bool{root: true}
+
+	var walk func(dir uint64, dirPath string, depth int) error
+	walk = func(dir uint64, dirPath string, depth int) error {
+		entries := slices.Clone(children[dir])
+		slices.SortFunc(entries, func(a, b dirent) int {
+			return strings.Compare(a.name, b.name)
+		})
+		for _, entry := range entries {
+			entryPath := path.Join(dirPath, entry.name)
+			switch {
+			case !entry.dir:
+				if err := file(entryPath, entry.ino); err != nil {
+					return err
+				}
+			case !visited[entry.ino] && depth < maxDepth:
+				visited[entry.ino] = true
+				if err := walk(entry.ino, entryPath, depth+1); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	return walk(root, "/", 0)
+}
+
+// validName reports whether a directory entry name can be part of a path
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, "/\x00")
+}
+
+// joinErrors returns the first of the problems with the parts of a file, and
+// how many more there are
+func joinErrors(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return fmt.Errorf("%w (and %d more)", errs[0], len(errs)-1)
+}
+
+// inflateZlib decompresses zlib data into dst, returning the bytes written
+func inflateZlib(dst, src []byte) (int, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(src))
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		_ = zr.Close()
+	}()
+	n, err := io.ReadFull(zr, dst)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		err = nil
+	}
+	return n, err
+}
@@ -0,0 +1,66 @@
+package fsimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/signature"
+)
+
+// regionTypes are the filesystems FuzzWalk reads, by the fuzzed type index
+var regionTypes = []string{signature.TypeSquashFS, signature.TypeJFFS2, signature.TypeUBIFS, signature.TypeUBI}
+
+// FuzzWalk tests reading filesystem images with random inputs
+func FuzzWalk(f *testing.F) {
+	// Seed corpus: the images of the Walk tests, each as its own type, and
+	// malformed data
+	hosts := []byte(strings.Repeat("127.0.0.1 localhost\n", 300))
+	config := []byte("wifi_ssid=factory\n")
+	f.Add(uint8(0), squashfsImage([]byte(strings.Repeat("root:x:0:0::/root:/bin/sh\n", 200)), []byte("banner"), []byte("readme")))
+	f.Add(uint8(1), jffs2Image(
+		jffs2TestDirent(binary.LittleEndian, jffs2Root, 1, 2, jffs2TypeReg, "config"),
+		jffs2TestInode(binary.LittleEndian, 2, 1, uint32(len(config)), 0, jffs2ComprZlib, len(config), zlibBytes(config)),
+	))
+	f.Add(uint8(1), jffs2Image(
+		jffs2TestDirent(binary.BigEndian, jffs2Root, 1, 2, jffs2TypeDir, "etc"),
+		jffs2TestDirent(binary.BigEndian, 2, 2, 3, jffs2TypeReg, "pattern"),
+		jffs2TestInode(binary.BigEndian, 3, 1, 12, 0, jffs2ComprRtime, 9, []byte{'a', 0, 'b', 0, 'c', 0, 'a', 5}),
+	))
+	f.Add(uint8(2), ubifsImage(hosts, 16384))
+	f.Add(uint8(3), ubiImage(hosts, 16384))
+	f.Add(uint8(0), []byte("hsqs"))
+	f.Add(uint8(1), []byte{0x85, 0x19, 0x01, 0xe0})
+	f.Add(uint8(2), []byte{0x31, 0x18, 0x10, 0x06})
+	f.Add(uint8(3), []byte("UBI#"))
+	f.Add(uint8(0), []byte(""))
+
+	f.Fuzz(func(t *testing.T, kind uint8, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		region := signature.Region{Type: regionTypes[int(kind)%len(regionTypes)], Size: int64(len(data))}
+		if region.Size == 0 {
+			region.Size = 1
+		}
+
+		// Errors are expected for invalid input
+		_ = Walk(bytes.NewReader(data), region, func(file File) error {
+			// Invariant: paths are absolute and clean
+			if !strings.HasPrefix(file.Path, "/") || strings.Contains(file.Path, "//") || strings.Contains(file.Path, "/../") {
+				t.Errorf("file path %q is not absolute and clean", file.Path)
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,206 @@
+package fsimage
+
+import (
+	"cmp"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"slices"
+)
+
+// JFFS2 node types and compression types
+const (
+	jffs2Dirent = 0xe001
+	jffs2Inode  = 0xe002
+
+	jffs2ComprNone  = 0
+	jffs2ComprZero  = 1
+	jffs2ComprRtime = 2
+	jffs2ComprZlib  = 6
+
+	jffs2Root    = 1 // Inode number of the root directory
+	jffs2TypeDir = 4 // Directory entry type of directories (DT_DIR)
+	jffs2TypeReg = 8 // Directory entry type of regular files (DT_REG)
+)
+
+// jffs2ComprNames names the compression types that cannot be read
+var jffs2ComprNames = map[byte]string{3: "rubinmips", 4: "copy", 5: "dynrubin", 7: "lzo", 8: "lzma"}
+
+// jffs2Data is a data node: dsize bytes of a file at offset, compressed
+type jffs2Data struct {
+	version uint32
+	offset  uint32
+	isize   uint32 // File size when the node was written
+	dsize   uint32
+	compr   byte
+	data    []byte
+}
+
+// jffs2Name is the latest directory entry for a name in a directory
+type jffs2Name struct {
+	version uint32
+	ino     uint32
+	kind    byte
+}
+
+// jffs2CRC is the checksum of JFFS2 nodes: CRC-32 without the final inversion
+func jffs2CRC(b []byte) uint32 {
+	return ^crc32.Update(0xffffffff, crc32.IEEETable, b)
+}
+
+// walkJFFS2 calls fn with the regular files of a JFFS2 image. JFFS2 is a log
+// of nodes: the node with the highest version of each name and of each file
+// range wins, and directory entries for inode 0 delete the name.
+func walkJFFS2(data []byte, fn func(File) error) error {
+	var order binary.ByteOrder = binary.BigEndian
+	if len(data) >= 2 && data[0] == 0x85 && data[1] == 0x19 {
+		order = binary.LittleEndian
+	}
+
+	type nameKey struct {
+		parent uint32
+		name   string
+	}
+	names := make(map[nameKey]jffs2Name)
+	inodes := make(map[uint32][]jffs2Data)
+
+	// Nodes are 4-byte aligned and checksummed, so the image is searched for
+	// them rather than followed, which also skips erased and damaged flash
+	for pos := 0; pos+12 <= len(data); {
+		h := data[pos:]
+		totlen := int(order.Uint32(h[4:]))
+		if order.Uint16(h) != 0x1985 || order.Uint32(h[8:]) != jffs2CRC(h[:8]) || totlen < 12 || pos+totlen > len(data) {
+			pos += 4
+			continue
+		}
+		node := h[:totlen]
+		pos += (totlen + 3) &^ 3
+
+		switch order.Uint16(node[2:]) {
+		case jffs2Dirent:
+			if len(node) < 40 || order.Uint32(node[32:]) != jffs2CRC(node[:32]) {
+				continue
+			}
+			nsize := int(node[28])
+			if 40+nsize > len(node) {
+				continue
+			}
+			key := nameKey{parent: order.Uint32(node[12:]), name: string(node[40 : 40+nsize])}
+			version := order.Uint32(node[16:])
+			if old, ok := names[key]; !ok || version > old.version {
+				names[key] = jffs2Name{version: version, ino: order.Uint32(node[20:]), kind: node[29]}
+			}
+		case jffs2Inode:
+			if len(node) < 68 || order.Uint32(node[64:]) != jffs2CRC(node[:60]) {
+				continue
+			}
+			csize := int(order.Uint32(node[48:]))
+			if 68+csize > len(node) {
+				continue
+			}
+			ino := order.Uint32(node[12:])
+			inodes[ino] = append(inodes[ino], jffs2Data{
+				version: order.Uint32(node[16:]),
+				isize:   order.Uint32(node[28:]),
+				offset:  order.Uint32(node[44:]),
+				dsize:   order.Uint32(node[52:]),
+				compr:   node[56],
+				data:    node[68 : 68+csize],
+			})
+		}
+	}
+
+	children := make(map[uint64][]dirent)
+	for key, entry := range names {
+		if entry.ino == 0 || !validName(key.name) || entry.kind != jffs2TypeDir && entry.kind != jffs2TypeReg {
+			continue
+		}
+		children[uint64(key.parent)] = append(children[uint64(key.parent)], dirent{name: key.name, ino: uint64(entry.ino), dir: entry.kind == jffs2TypeDir})
+	}
+
+	limit := fileSizeLimit(len(data))
+	return walkTree(children, jffs2Root, func(path string, ino uint64) error {
+		data, err := jffs2FileData(inodes[uint32(ino)], limit)
+		return fn(File{Path: path, Data: data, Err: err})
+	})
+}
+
+// jffs2FileData assembles a file from its data nodes, applying them in
+// version order so newer data overwrites older data. Nodes that cannot be
+// decompressed are left zero and reported in the error. Files larger than limit
+// are corrupt.
+func jffs2FileData(nodes []jffs2Data, limit uint64) ([]byte, error) {
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+	slices.SortStableFunc(nodes, func(a, b jffs2Data) int {
+		return cmp.Compare(a.version, b.version)
+	})
+
+	size := nodes[len(nodes)-1].isize
+	if uint64(size) > limit {
+		return nil, errors.New("corrupt jffs2 inode size")
+	}
+	data := make([]byte, size)
+	var errs []error
+	for _, node := range nodes {
+		if uint64(node.offset) >= uint64(size) {
+			continue
+		}
+		dst := data[node.offset:min(uint64(node.offset)+uint64(node.dsize), uint64(size))]
+		if err := jffs2Decompress(dst, node); err != nil {
+			clear(dst)
+			errs = append(errs, fmt.Errorf("offset %d: %w", node.offset, err))
+		}
+	}
+
+	return data, joinErrors(errs)
+}
+
+// jffs2Decompress fills dst with the data of node
+func jffs2Decompress(dst []byte, node jffs2Data) error {
+	switch node.compr {
+	case jffs2ComprNone:
+		copy(dst, node.data)
+	case jffs2ComprZero:
+		clear(dst)
+	case jffs2ComprRtime:
+		return rtimeDecompress(dst, node.data)
+	case jffs2ComprZlib:
+		_, err := inflateZlib(dst, node.data)
+		return err
+	default:
+		name, ok := jffs2ComprNames[node.compr]
+		if !ok {
+			name = fmt.Sprintf("type %d", node.compr)
+		}
+		return fmt.Errorf("%s compression: %w", name, ErrUnsupported)
+	}
+	return nil
+}
+
+// rtimeDecompress decodes JFFS2's rtime compression: pairs of a byte and a
+// count of bytes to copy from after that byte's previous occurrence
+func rtimeDecompress(dst, src []byte) error {
+	var positions [256]int
+	out, in := 0, 0
+	for out < len(dst) {
+		if in+2 > len(src) {
+			return errors.New("truncated rtime data")
+		}
+		value, repeat := src[in], int(src[in+1])
+		in += 2
+
+		dst[out] = value
+		out++
+		backoffs := positions[value]
+		positions[value] = out
+		for ; repeat > 0 && out < len(dst); repeat-- {
+			dst[out] = dst[backoffs]
+			out++
+			backoffs++
+		}
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+package fsimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+)
+
+// jffs2Header fills in the common header and header checksum of node
+func jffs2Header(order binary.ByteOrder, node []byte, nodeType uint16) {
+	order.PutUint16(node, 0x1985)
+	order.PutUint16(node[2:], nodeType)
+	order.PutUint32(node[4:], uint32(len(node)))
+	order.PutUint32(node[8:], jffs2CRC(node[:8]))
+}
+
+// jffs2TestDirent encodes a directory entry node
+func jffs2TestDirent(order binary.ByteOrder, parent, version, ino uint32, kind byte, name string) []byte {
+	node := make([]byte, 40+len(name))
+	jffs2Header(order, node, jffs2Dirent)
+	order.PutUint32(node[12:], parent)
+	order.PutUint32(node[16:], version)
+	order.PutUint32(node[20:], ino)
+	node[28] = byte(len(name))
+	node[29] = kind
+	copy(node[40:], name)
+	order.PutUint32(node[32:], jffs2CRC(node[:32]))
+	return node
+}
+
+// jffs2TestInode encodes an inode node holding data at offset of a file of
+// isize bytes
+func jffs2TestInode(order binary.ByteOrder, ino, version, isize, offset uint32, compr byte, dsize int, data []byte) []byte {
+	node := make([]byte, 68+len(data))
+	jffs2Header(order, node, jffs2Inode)
+	order.PutUint32(node[12:], ino)
+	order.PutUint32(node[16:], version)
+	order.PutUint32(node[20:], 0100644)
+	order.PutUint32(node[28:], isize)
+	order.PutUint32(node[44:], offset)
+	order.PutUint32(node[48:], uint32(len(data)))
+	order.PutUint32(node[52:], uint32(dsize))
+	node[56] = compr
+	copy(node[68:], data)
+	order.PutUint32(node[64:], jffs2CRC(node[:60]))
+	return node
+}
+
+// jffs2Image joins nodes 4-byte aligned, with erased flash in between
+func jffs2Image(nodes ...[]byte) []byte {
+	var image []byte
+	for _, node := range nodes {
+		image = append(image, node...)
+		image = append(image, bytes.Repeat([]byte{0xff}, 8-len(node)%4)...)
+	}
+	return image
+}
+
+// TestWalkJFFS2 tests assembling files from versioned data nodes in both byte
+// orders, with renames, deletions and each supported compression
+func TestWalkJFFS2(t *testing.T) {
+	config := []byte("wifi_ssid=factory\nwifi_key=secret\n")
+	// rtime: "abcabcabc" is a, b, c, then a followed by the 5 bytes after the
+	// previous a
+	rtime := []byte{'a', 0, 'b', 0, 'c', 0, 'a', 5}
+
+	for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+		t.Run(order.String(), func(t *testing.T) {
+			image := jffs2Image(
+				jffs2TestDirent(order, jffs2Root, 1, 2, jffs2TypeDir, "etc"),
+				jffs2TestDirent(order, 2, 2, 3, jffs2TypeReg, "config.tmp"),
+				// Version 1 is overwritten in part by version 2
+				jffs2TestInode(order, 3, 1, uint32(len(config)), 0, jffs2ComprNone, len(config), bytes.Repeat([]byte("x"), len(config))),
+				jffs2TestInode(order, 3, 2, uint32(len(config)), 0, jffs2ComprZlib, len(config), zlibBytes(config)),
+				// The file is renamed
+				jffs2TestDirent(order, 2, 3, 0, jffs2TypeReg, "config.tmp"),
+				jffs2TestDirent(order, 2, 4, 3, jffs2TypeReg, "config"),
+				jffs2TestDirent(order, jffs2Root, 5, 4, jffs2TypeReg, "pattern"),
+				jffs2TestInode(order, 4, 1, 12, 0, jffs2ComprRtime, 9, rtime),
+				jffs2TestInode(order, 4, 2, 12, 9, jffs2ComprZero, 3, nil),
+				jffs2TestDirent(order, jffs2Root, 6, 5, jffs2TypeReg, "log"),
+				jffs2TestInode(order, 5, 1, 4, 0, 7, 4, []byte{1, 2, 3, 4}), // lzo
+			)
+
+			files := walkAll(t, image)
+
+			want := []struct {
+				path string
+				data []byte
+				err  bool
+			}{
+				{"/etc/config", config, false},
+				{"/log", make([]byte, 4), true},
+				{"/pattern", []byte("abcabcabc\x00\x00\x00"), false},
+			}
+			if len(files) != len(want) {
+				t.Fatalf("Walk() = %+v, want %d files", files, len(want))
+			}
+			for i, file := range files {
+				if file.Path != want[i].path || !bytes.Equal(file.Data, want[i].data) || (file.Err != nil) != want[i].err {
+					t.Errorf("file %d = %s %q (err %v), want %s %q", i, file.Path, file.Data, file.Err, want[i].path, want[i].data)
+				}
+			}
+			if err := files[1].Err; !errors.Is(err, ErrUnsupported) {
+				t.Errorf("Err = %v, want %v", err, ErrUnsupported)
+			}
+		})
+	}
+}
@@ -0,0 +1,347 @@
+package fsimage
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"path"
+)
+
+// SquashFS inode types
+const (
+	squashfsDir      = 1
+	squashfsFile     = 2
+	squashfsLongDir  = 8
+	squashfsLongFile = 9
+)
+
+const (
+	squashfsGzip          = 1
+	squashfsMetadataSize  = 8192    // Uncompressed bytes of a metadata block
+	squashfsUncompressed  = 1 << 24 // Set in the size of uncompressed data blocks
+	squashfsNoFragment    = 0xffffffff
+	squashfsFragmentsSize = 16 // Bytes of a fragment table entry
+)
+
+// squashfsCompressorNames names the compressors that cannot be read
+var squashfsCompressorNames = map[uint16]string{2: "lzma", 3: "lzo", 4: "xz", 5: "lz4", 6: "zstd"}
+
+var errSquashFSCorrupt = errors.New("corrupt squashfs filesystem")
+
+// squashfs is a SquashFS 4 image (little endian, gzip compressed or not
+// compressed at all) held in memory
+type squashfs struct {
+	data          []byte
+	blockSize     uint32
+	inodeTable    uint64
+	dirTable      uint64
+	fragmentTable uint64
+	fragments     uint32
+	metadata      map[uint64][]byte // Decompressed metadata blocks by position
+	fragmentData  map[uint64][]byte // Decompressed fragment blocks by position
+}
+
+// walkSquashFS calls fn with the regular files of a SquashFS image
+func walkSquashFS(data []byte, fn func(File) error) error {
+	if len(data) < 96 || string(data[:4]) != "hsqs" {
+		return fmt.Errorf("big endian squashfs: %w", ErrUnsupported)
+	}
+	le := binary.LittleEndian
+	if compressor := le.Uint16(data[20:]); compressor != squashfsGzip {
+		name, ok := squashfsCompressorNames[compressor]
+		if !ok {
+			return errSquashFSCorrupt
+		}
+		return fmt.Errorf("%s compressed squashfs: %w", name, ErrUnsupported)
+	}
+
+	fs := &squashfs{
+		data:          data,
+		blockSize:     le.Uint32(data[12:]),
+		fragments:     le.Uint32(data[16:]),
+		inodeTable:    le.Uint64(data[64:]),
+		dirTable:      le.Uint64(data[72:]),
+		fragmentTable: le.Uint64(data[80:]),
+		metadata:      make(map[uint64][]byte),
+		fragmentData:  make(map[uint64][]byte),
+	}
+	// The block size is a power of two from 4KiB to 1MiB
+	if fs.blockSize < 4096 || fs.blockSize > 1<<20 || fs.blockSize&(fs.blockSize-1) != 0 {
+		return errSquashFSCorrupt
+	}
+
+	root := le.Uint64(data[32:])
+	inode, err := fs.inode(root)
+	if err != nil {
+		return err
+	}
+	return fs.walkDir(inode, "/", 0, map[uint64]bool{root: true}, fn)
+}
+
+// metadataBlock returns the decompressed metadata block at pos and the
+// position of the next block
+func (fs *squashfs) metadataBlock(pos uint64) ([]byte, uint64, error) {
+	if pos+2 > uint64(len(fs.data)) {
+		return nil, 0, errSquashFSCorrupt
+	}
+	header := binary.LittleEndian.Uint16(fs.data[pos:])
+	size := uint64(header & 0x7fff)
+	next := pos + 2 + size
+	if next > uint64(len(fs.data)) {
+		return nil, 0, errSquashFSCorrupt
+	}
+	if block, ok := fs.metadata[pos]; ok {
+		return block, next, nil
+	}
+
+	block := fs.data[pos+2 : next]
+	if header&0x8000 == 0 {
+		buf := make([]byte, squashfsMetadataSize)
+		n, err := inflateZlib(buf, block)
+		if err != nil {
+			return nil, 0, fmt.Errorf("%w: %v", errSquashFSCorrupt, err)
+		}
+		block = buf[:n]
+	}
+	fs.metadata[pos] = block
+	return block, next, nil
+}
+
+// readMetadata reads n bytes of metadata, starting offset bytes into the block
+// at pos and continuing into the blocks after it
+func (fs *squashfs) readMetadata(pos uint64, offset, n int) ([]byte, error) {
+	out := make([]byte, 0, min(n, squashfsMetadataSize))
+	for len(out) < n {
+		block, next, err := fs.metadataBlock(pos)
+		if err != nil {
+			return nil, err
+		}
+		if offset > len(block) || offset == len(block) && len(block) == 0 {
+			return nil, errSquashFSCorrupt
+		}
+		take := min(n-len(out), len(block)-offset)
+		out = append(out, block[offset:offset+take]...)
+		pos, offset = next, 0
+	}
+	return out, nil
+}
+
+// squashfsInode is the part of an inode needed to walk the filesystem
+type squashfsInode struct {
+	kind uint16
+	// Directories
+	dirBlock  uint64
+	dirOffset int
+	dirSize   int
+	// Files
+	startBlock uint64
+	fileSize   uint64
+	fragment   uint32
+	fragOffset uint32
+	blockSizes []uint32
+}
+
+// inode reads the inode of reference ref (metadata block << 16 | offset)
+func (fs *squashfs) inode(ref uint64) (squashfsInode, error) {
+	pos, offset := fs.inodeTable+ref>>16, int(ref&0xffff)
+	le := binary.LittleEndian
+
+	header, err := fs.readMetadata(pos, offset, 16)
+	if err != nil {
+		return squashfsInode{}, err
+	}
+	inode := squashfsInode{kind: le.Uint16(header)}
+
+	var fixed []byte
+	switch inode.kind {
+	case squashfsDir:
+		if fixed, err = fs.readMetadata(pos, offset, 32); err != nil {
+			return inode, err
+		}
+		inode.dirBlock = uint64(le.Uint32(fixed[16:]))
+		inode.dirSize = int(le.Uint16(fixed[24:]))
+		inode.dirOffset = int(le.Uint16(fixed[26:]))
+	case squashfsLongDir:
+		if fixed, err = fs.readMetadata(pos, offset, 40); err != nil {
+			return inode, err
+		}
+		inode.dirSize = int(le.Uint32(fixed[20:]))
+		inode.dirBlock = uint64(le.Uint32(fixed[24:]))
+		inode.dirOffset = int(le.Uint16(fixed[34:]))
+	case squashfsFile:
+		if fixed, err = fs.readMetadata(pos, offset, 32); err != nil {
+			return inode, err
+		}
+		inode.startBlock = uint64(le.Uint32(fixed[16:]))
+		inode.fragment = le.Uint32(fixed[20:])
+		inode.fragOffset = le.Uint32(fixed[24:])
+		inode.fileSize = uint64(le.Uint32(fixed[28:]))
+	case squashfsLongFile:
+		if fixed, err = fs.readMetadata(pos, offset, 56); err != nil {
+			return inode, err
+		}
+		inode.startBlock = le.Uint64(fixed[16:])
+		inode.fileSize = le.Uint64(fixed[24:])
+		inode.fragment = le.Uint32(fixed[44:])
+		inode.fragOffset = le.Uint32(fixed[48:])
+	default:
+		return inode, nil
+	}
+
+	if inode.kind == squashfsFile || inode.kind == squashfsLongFile {
+		if inode.fileSize > fileSizeLimit(len(fs.data)) {
+			return inode, errSquashFSCorrupt
+		}
+		// One size per full block; the tail is in a fragment if it has one
+		blocks := (inode.fileSize + uint64(fs.blockSize) - 1) / uint64(fs.blockSize)
+		if inode.fragment != squashfsNoFragment {
+			blocks = inode.fileSize / uint64(fs.blockSize)
+		}
+		sizes, err := fs.readMetadata(pos, offset+len(fixed), int(blocks)*4)
+		if err != nil {
+			return inode, err
+		}
+		inode.blockSizes = make([]uint32, blocks)
+		for i := range inode.blockSizes {
+			inode.blockSizes[i] = le.Uint32(sizes[i*4:])
+		}
+	}
+	return inode, nil
+}
+
+// walkDir calls fn with the regular files below the directory inode dir
+func (fs *squashfs) walkDir(dir squashfsInode, dirPath string, depth int, visited map[uint64]bool, fn func(File) error) error {
+	// The listing size counts 3 bytes for the implicit . and .. entries
+	if dir.dirSize <= 3 {
+		return nil
+	}
+	listing, err := fs.readMetadata(fs.dirTable+dir.dirBlock, dir.dirOffset, dir.dirSize-3)
+	if err != nil {
+		return err
+	}
+
+	le := binary.LittleEndian
+	for pos := 0; pos+12 <= len(listing); {
+		// A header for up to 256 entries whose inodes share a metadata block
+		count := int(le.Uint32(listing[pos:])) + 1
+		start := uint64(le.Uint32(listing[pos+4:]))
+		pos += 12
+		for ; count > 0 && pos+8 <= len(listing); count-- {
+			offset := uint64(le.Uint16(listing[pos:]))
+			nameLen := int(le.Uint16(listing[pos+6:])) + 1
+			if pos+8+nameLen > len(listing) {
+				return errSquashFSCorrupt
+			}
+			name := string(listing[pos+8 : pos+8+nameLen])
+			pos += 8 + nameLen
+			if !validName(name) {
+				continue
+			}
+
+			ref := start<<16 | offset
+			inode, err := fs.inode(ref)
+			if err != nil {
+				return err
+			}
+			entryPath := path.Join(dirPath, name)
+			switch inode.kind {
+			case squashfsDir, squashfsLongDir:
+				if visited[ref] || depth >= maxDepth {
+					continue
+				}
+				visited[ref] = true
+				if err := fs.walkDir(inode, entryPath, depth+1, visited, fn); err != nil {
+					return err
+				}
+			case squashfsFile, squashfsLongFile:
+				data, err := fs.fileData(inode)
+				if err := fn(File{Path: entryPath, Data: data, Err: err}); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// fileData returns the contents of the file inode. Unreadable blocks are left
+// zero and reported in the error.
+func (fs *squashfs) fileData(inode squashfsInode) ([]byte, error) {
+	data := make([]byte, inode.fileSize)
+	var errs []error
+
+	pos := inode.startBlock
+	for i, size := range inode.blockSizes {
+		start := uint64(i) * uint64(fs.blockSize)
+		if start >= inode.fileSize {
+			break
+		}
+		stored := uint64(size &^ squashfsUncompressed)
+		// Size 0 is a sparse block of zeros
+		if stored == 0 {
+			continue
+		}
+		if err := fs.readBlock(data[start:min(start+uint64(fs.blockSize), inode.fileSize)], pos, stored, size&squashfsUncompressed != 0); err != nil {
+			errs = append(errs, fmt.Errorf("block %d: %w", i, err))
+		}
+		pos += stored
+	}
+
+	if inode.fragment != squashfsNoFragment {
+		tail := data[uint64(len(inode.blockSizes))*uint64(fs.blockSize):]
+		if err := fs.readFragment(tail, inode.fragment, inode.fragOffset); err != nil {
+			errs = append(errs, fmt.Errorf("fragment %d: %w", inode.fragment, err))
+		}
+	}
+
+	return data, joinErrors(errs)
+}
+
+// readBlock fills dst from the data block of stored bytes at pos
+func (fs *squashfs) readBlock(dst []byte, pos, stored uint64, uncompressed bool) error {
+	if pos+stored > uint64(len(fs.data)) {
+		return errSquashFSCorrupt
+	}
+	block := fs.data[pos : pos+stored]
+	if uncompressed {
+		copy(dst, block)
+		return nil
+	}
+	_, err := inflateZlib(dst, block)
+	return err
+}
+
+// readFragment fills dst from offset bytes into fragment block index, which
+// holds the tails of several files
+func (fs *squashfs) readFragment(dst []byte, index, offset uint32) error {
+	if index >= fs.fragments {
+		return errSquashFSCorrupt
+	}
+	// The fragment table is indexed by the positions of its metadata blocks
+	perBlock := uint32(squashfsMetadataSize / squashfsFragmentsSize)
+	indexPos := fs.fragmentTable + uint64(index/perBlock)*8
+	if indexPos+8 > uint64(len(fs.data)) {
+		return errSquashFSCorrupt
+	}
+	entryPos := binary.LittleEndian.Uint64(fs.data[indexPos:])
+	entry, err := fs.readMetadata(entryPos, int(index%perBlock)*squashfsFragmentsSize, squashfsFragmentsSize)
+	if err != nil {
+		return err
+	}
+	start := binary.LittleEndian.Uint64(entry)
+	size := binary.LittleEndian.Uint32(entry[8:])
+
+	block, ok := fs.fragmentData[start]
+	if !ok {
+		block = make([]byte, fs.blockSize)
+		if err := fs.readBlock(block, start, uint64(size&^squashfsUncompressed), size&squashfsUncompressed != 0); err != nil {
+			return err
+		}
+		fs.fragmentData[start] = block
+	}
+	if uint64(offset)+uint64(len(dst)) > uint64(len(block)) {
+		return errSquashFSCorrupt
+	}
+	copy(dst, block[offset:])
+	return nil
+}
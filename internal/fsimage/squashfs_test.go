@@ -0,0 +1,194 @@
+package fsimage
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/signature"
+)
+
+// zlibBytes compresses data with zlib
+func zlibBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, _ = zw.Write(data)
+	_ = zw.Close()
+	return buf.Bytes()
+}
+
+// squashfsEntry is a directory entry of a test image
+type squashfsEntry struct {
+	name  string
+	inode uint16 // Offset of the inode in the inode table
+	kind  uint16
+}
+
+// squashfsListing encodes a directory listing whose inodes are all in the
+// first metadata block
+func squashfsListing(entries []squashfsEntry) []byte {
+	le := binary.LittleEndian
+	listing := le.AppendUint32(nil, uint32(len(entries)-1))
+	listing = le.AppendUint32(listing, 0)   // Inode metadata block
+	listing = le.AppendUint32(listing, 100) // Base inode number
+	for _, entry := range entries {
+		listing = le.AppendUint16(listing, entry.inode)
+		listing = le.AppendUint16(listing, 0)
+		listing = le.AppendUint16(listing, entry.kind)
+		listing = le.AppendUint16(listing, uint16(len(entry.name)-1))
+		listing = append(listing, entry.name...)
+	}
+	return listing
+}
+
+// squashfsDirInode encodes a basic directory inode
+func squashfsDirInode(listingOffset, listingSize int) []byte {
+	le := binary.LittleEndian
+	inode := make([]byte, 32)
+	le.PutUint16(inode, squashfsDir)
+	le.PutUint32(inode[16:], 0) // Directory metadata block
+	le.PutUint16(inode[24:], uint16(listingSize+3))
+	le.PutUint16(inode[26:], uint16(listingOffset))
+	return inode
+}
+
+// squashfsFileInode encodes a basic file inode
+func squashfsFileInode(start, size uint32, fragment, fragOffset uint32, blockSizes ...uint32) []byte {
+	le := binary.LittleEndian
+	inode := make([]byte, 32)
+	le.PutUint16(inode, squashfsFile)
+	le.PutUint32(inode[16:], start)
+	le.PutUint32(inode[20:], fragment)
+	le.PutUint32(inode[24:], fragOffset)
+	le.PutUint32(inode[28:], size)
+	for _, blockSize := range blockSizes {
+		inode = le.AppendUint32(inode, blockSize)
+	}
+	return inode
+}
+
+// squashfsImage builds a gzip compressed SquashFS image (block size 4096) of
+// /bin/banner (in a fragment), /etc/passwd (a compressed block and a
+// fragment) and /readme (an uncompressed block)
+func squashfsImage(passwd, banner, readme []byte) []byte {
+	const blockSize = 4096
+	le := binary.LittleEndian
+
+	image := make([]byte, 96)
+	passwdBlock := zlibBytes(passwd[:blockSize])
+	passwdStart := len(image)
+	image = append(image, passwdBlock...)
+	readmeStart := len(image)
+	image = append(image, readme...)
+
+	// The fragment holds the tail of passwd and all of banner
+	tail := passwd[blockSize:]
+	fragment := zlibBytes(append(append([]byte{}, tail...), banner...))
+	fragmentStart := len(image)
+	image = append(image, fragment...)
+
+	// Inodes: root, bin, etc, banner, passwd, readme
+	const rootIno, binIno, etcIno, bannerIno, passwdIno, readmeIno = 0, 32, 64, 96, 128, 164
+	rootListing := squashfsListing([]squashfsEntry{{"bin", binIno, squashfsDir}, {"etc", etcIno, squashfsDir}, {"readme", readmeIno, squashfsFile}})
+	binListing := squashfsListing([]squashfsEntry{{"banner", bannerIno, squashfsFile}})
+	etcListing := squashfsListing([]squashfsEntry{{"passwd", passwdIno, squashfsFile}})
+	dirBlock := bytes.Join([][]byte{rootListing, binListing, etcListing}, nil)
+
+	inodes := bytes.Join([][]byte{
+		squashfsDirInode(0, len(rootListing)),
+		squashfsDirInode(len(rootListing), len(binListing)),
+		squashfsDirInode(len(rootListing)+len(binListing), len(etcListing)),
+		squashfsFileInode(0, uint32(len(banner)), 0, uint32(len(tail))),
+		squashfsFileInode(uint32(passwdStart), uint32(len(passwd)), 0, 0, uint32(len(passwdBlock))),
+		squashfsFileInode(uint32(readmeStart), uint32(len(readme)), squashfsNoFragment, 0, uint32(len(readme))|squashfsUncompressed),
+	}, nil)
+
+	// Metadata: the inode table is stored uncompressed, the directory table
+	// compressed
+	inodeTable := len(image)
+	image = le.AppendUint16(image, uint16(len(inodes))|0x8000)
+	image = append(image, inodes...)
+	dirTable := len(image)
+	compressedDirs := zlibBytes(dirBlock)
+	image = le.AppendUint16(image, uint16(len(compressedDirs)))
+	image = append(image, compressedDirs...)
+
+	fragmentEntries := len(image)
+	entry := le.AppendUint64(nil, uint64(fragmentStart))
+	entry = le.AppendUint32(entry, uint32(len(fragment)))
+	entry = le.AppendUint32(entry, 0)
+	image = le.AppendUint16(image, uint16(len(entry))|0x8000)
+	image = append(image, entry...)
+	fragmentTable := len(image)
+	image = le.AppendUint64(image, uint64(fragmentEntries))
+
+	copy(image, "hsqs")
+	le.PutUint32(image[4:], 6)
+	le.PutUint32(image[12:], blockSize)
+	le.PutUint32(image[16:], 1) // Fragments
+	le.PutUint16(image[20:], squashfsGzip)
+	le.PutUint16(image[22:], 12)
+	le.PutUint16(image[28:], 4)
+	le.PutUint64(image[32:], rootIno)
+	le.PutUint64(image[40:], uint64(len(image)))
+	le.PutUint64(image[64:], uint64(inodeTable))
+	le.PutUint64(image[72:], uint64(dirTable))
+	le.PutUint64(image[80:], uint64(fragmentTable))
+	return image
+}
+
+// walkAll returns the files Walk finds in the first region of image
+func walkAll(t *testing.T, image []byte) []File {
+	t.Helper()
+	regions, err := signature.Scan(bytes.NewReader(image), int64(len(image)))
+	if err != nil || len(regions) == 0 {
+		t.Fatalf("signature.Scan() = %v, %v, want a region", regions, err)
+	}
+	var files []File
+	err = Walk(bytes.NewReader(image), regions[0], func(file File) error {
+		files = append(files, file)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	return files
+}
+
+// TestWalkSquashFS tests reading files from the compressed and uncompressed
+// blocks and the fragments of a SquashFS image, with compressed and
+// uncompressed metadata
+func TestWalkSquashFS(t *testing.T) {
+	passwd := []byte(strings.Repeat("root:x:0:0:root:/root:/bin/sh\n", 150))
+	banner := []byte("Welcome to the firmware")
+	readme := []byte("read me first")
+
+	files := walkAll(t, squashfsImage(passwd, banner, readme))
+
+	want := []File{{Path: "/bin/banner", Data: banner}, {Path: "/etc/passwd", Data: passwd}, {Path: "/readme", Data: readme}}
+	if len(files) != len(want) {
+		t.Fatalf("Walk() found %d files, want %d", len(files), len(want))
+	}
+	for i, file := range files {
+		if file.Path != want[i].Path || !bytes.Equal(file.Data, want[i].Data) || file.Err != nil {
+			t.Errorf("file %d = %s (%d bytes, err %v), want %s (%d bytes)", i, file.Path, len(file.Data), file.Err, want[i].Path, len(want[i].Data))
+		}
+	}
+}
+
+// TestWalkSquashFSUnsupportedCompressor tests that filesystems using another
+// compressor than gzip fail with ErrUnsupported
+func TestWalkSquashFSUnsupportedCompressor(t *testing.T) {
+	image := squashfsImage(make([]byte, 5000), []byte("banner"), []byte("readme"))
+	binary.LittleEndian.PutUint16(image[20:], 4) // xz
+
+	err := Walk(bytes.NewReader(image), signature.Region{Type: signature.TypeSquashFS, Size: int64(len(image))}, func(File) error {
+		return nil
+	})
+	if !errors.Is(err, ErrUnsupported) || !strings.Contains(err.Error(), "xz") {
+		t.Errorf("Walk() error = %v, want xz %v", err, ErrUnsupported)
+	}
+}
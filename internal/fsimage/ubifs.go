@@ -0,0 +1,295 @@
+package fsimage
+
+import (
+	"bytes"
+	"cmp"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+
+	"github.com/richardwooding/txtr/internal/signature"
+)
+
+// UBIFS node types, inode types and compression types
+const (
+	ubifsInoNode  = 0
+	ubifsDataNode = 1
+	ubifsDentNode = 2
+
+	ubifsTypeReg = 0
+	ubifsTypeDir = 1
+
+	ubifsComprNone = 0
+	ubifsComprZlib = 2
+
+	ubifsRoot      = 1    // Inode number of the root directory
+	ubifsBlockSize = 4096 // Bytes of file data per data node
+)
+
+// ubifsComprNames names the compression types that cannot be read
+var ubifsComprNames = map[uint16]string{1: "lzo", 3: "zstd"}
+
+// ubifsNode is the latest node written for a key
+type ubifsNode struct {
+	sqnum uint64
+	node  []byte
+}
+
+// ubifsCRC is the checksum of UBI and UBIFS headers: CRC-32 without the final
+// inversion
+func ubifsCRC(b []byte) uint32 {
+	return ^crc32.ChecksumIEEE(b)
+}
+
+// walkUBIFS calls fn with the regular files of a UBIFS image. Rather than
+// following the index, every node is read and the one with the highest
+// sequence number for each inode, directory entry and data block wins, which
+// also recovers files from images whose index is damaged.
+func walkUBIFS(data []byte, fn func(File) error) error {
+	le := binary.LittleEndian
+
+	type dentKey struct {
+		parent uint32
+		name   string
+	}
+	type blockKey struct {
+		ino   uint32
+		block uint32
+	}
+	inodes := make(map[uint32]ubifsNode)
+	dents := make(map[dentKey]ubifsNode)
+	blocks := make(map[blockKey]ubifsNode)
+
+	// Nodes are 8-byte aligned
+	for pos := 0; pos+24 <= len(data); {
+		h := data[pos:]
+		length := int(le.Uint32(h[16:]))
+		if le.Uint32(h) != 0x06101831 || length < 24 || pos+length > len(data) || le.Uint32(h[4:]) != ubifsCRC(h[8:length]) {
+			pos += 8
+			continue
+		}
+		node := h[:length]
+		pos += (length + 7) &^ 7
+
+		sqnum := le.Uint64(node[8:])
+		latest := func(old ubifsNode, ok bool) bool {
+			return !ok || sqnum > old.sqnum
+		}
+		switch node[20] {
+		case ubifsInoNode:
+			if length < 160 {
+				continue
+			}
+			ino := le.Uint32(node[24:])
+			if old, ok := inodes[ino]; latest(old, ok) {
+				inodes[ino] = ubifsNode{sqnum, node}
+			}
+		case ubifsDentNode:
+			if length < 56 || 56+int(le.Uint16(node[50:])) > length {
+				continue
+			}
+			key := dentKey{le.Uint32(node[24:]), string(node[56 : 56+int(le.Uint16(node[50:]))])}
+			if old, ok := dents[key]; latest(old, ok) {
+				dents[key] = ubifsNode{sqnum, node}
+			}
+		case ubifsDataNode:
+			if length < 48 {
+				continue
+			}
+			key := blockKey{le.Uint32(node[24:]), le.Uint32(node[28:]) & 0x1fffffff}
+			if old, ok := blocks[key]; latest(old, ok) {
+				blocks[key] = ubifsNode{sqnum, node}
+			}
+		}
+	}
+
+	// Data blocks by inode, to assemble files
+	fileBlocks := make(map[uint32][]ubifsNode)
+	for _, key := range slices.SortedFunc(maps.Keys(blocks), func(a, b blockKey) int {
+		return cmp.Compare(a.block, b.block)
+	}) {
+		fileBlocks[key.ino] = append(fileBlocks[key.ino], blocks[key])
+	}
+
+	children := make(map[uint64][]dirent)
+	for key, dent := range dents {
+		ino := le.Uint64(dent.node[40:])
+		kind := dent.node[49]
+		// Deleted names have inode 0, deleted files a link count of 0
+		inode, ok := inodes[uint32(ino)]
+		if ino == 0 || !ok || le.Uint32(inode.node[92:]) == 0 || !validName(key.name) || kind != ubifsTypeReg && kind != ubifsTypeDir {
+			continue
+		}
+		children[uint64(key.parent)] = append(children[uint64(key.parent)], dirent{name: key.name, ino: ino, dir: kind == ubifsTypeDir})
+	}
+
+	limit := fileSizeLimit(len(data))
+	return walkTree(children, ubifsRoot, func(path string, ino uint64) error {
+		size := le.Uint64(inodes[uint32(ino)].node[48:])
+		data, err := ubifsFileData(size, limit, fileBlocks[uint32(ino)])
+		return fn(File{Path: path, Data: data, Err: err})
+	})
+}
+
+// ubifsFileData assembles a file of size bytes from its data nodes. Holes are
+// zero; blocks that cannot be decompressed are left zero and reported in the
+// error. Files larger than limit are corrupt.
+func ubifsFileData(size, limit uint64, nodes []ubifsNode) ([]byte, error) {
+	if size > limit {
+		return nil, errors.New("corrupt ubifs inode size")
+	}
+	le := binary.LittleEndian
+	data := make([]byte, size)
+	var errs []error
+	for _, n := range nodes {
+		start := uint64(le.Uint32(n.node[28:])&0x1fffffff) * ubifsBlockSize
+		if start >= size {
+			continue
+		}
+		dst := data[start:min(start+uint64(min(le.Uint32(n.node[40:]), ubifsBlockSize)), size)]
+		compr := le.Uint16(n.node[44:])
+		var err error
+		switch compr {
+		case ubifsComprNone:
+			copy(dst, n.node[48:])
+		case ubifsComprZlib:
+			// UBIFS stores raw deflate data without a zlib header
+			_, err = io.ReadFull(flate.NewReader(bytes.NewReader(n.node[48:])), dst)
+		default:
+			name, ok := ubifsComprNames[compr]
+			if !ok {
+				name = fmt.Sprintf("type %d", compr)
+			}
+			err = fmt.Errorf("%s compression: %w", name, ErrUnsupported)
+		}
+		if err != nil {
+			clear(dst)
+			errs = append(errs, fmt.Errorf("block %d: %w", start/ubifsBlockSize, err))
+		}
+	}
+
+	return data, joinErrors(errs)
+}
+
+// ubiLayoutVolume is the volume ID of the UBI volume table
+const ubiLayoutVolume = 0x7fffefff
+
+// ubiLEB is the latest copy of a logical erase block of a UBI volume
+type ubiLEB struct {
+	sqnum uint64
+	data  []byte
+}
+
+// walkUBI calls fn with the regular files of the UBIFS and SquashFS volumes of
+// a UBI image, naming each file's volume. Logical erase blocks are mapped to
+// their volumes from the volume ID headers of the physical erase blocks.
+func walkUBI(data []byte, fn func(File) error) error {
+	be := binary.BigEndian
+	pebSize := int(signature.UBIBlockSize(bytes.NewReader(data), 0, int64(len(data))))
+	if pebSize == 0 {
+		pebSize = len(data)
+	}
+
+	volumes := make(map[uint32]map[uint32]ubiLEB)
+	lebSizes := make(map[uint32]int)
+	for pos := 0; pos+pebSize <= len(data); pos += pebSize {
+		peb := data[pos : pos+pebSize]
+		if !signature.UBIEraseCounterHeaderAt(peb) {
+			continue
+		}
+		vidOffset, dataOffset := int(be.Uint32(peb[16:])), int(be.Uint32(peb[20:]))
+		if vidOffset < 64 || vidOffset+64 > pebSize || dataOffset >= pebSize {
+			continue
+		}
+		// Erased blocks have no volume ID header
+		vid := peb[vidOffset : vidOffset+64]
+		if string(vid[:4]) != "UBI!" || be.Uint32(vid[60:]) != ubifsCRC(vid[:60]) {
+			continue
+		}
+		volume, lnum, sqnum := be.Uint32(vid[8:]), be.Uint32(vid[12:]), be.Uint64(vid[40:])
+		dataPad := int(be.Uint32(vid[28:]))
+		if dataOffset+dataPad > pebSize {
+			continue
+		}
+
+		if volumes[volume] == nil {
+			volumes[volume] = make(map[uint32]ubiLEB)
+		}
+		if old, ok := volumes[volume][lnum]; !ok || sqnum > old.sqnum {
+			volumes[volume][lnum] = ubiLEB{sqnum, peb[dataOffset : pebSize-dataPad]}
+			lebSizes[volume] = pebSize - dataOffset - dataPad
+		}
+	}
+
+	names := ubiVolumeNames(volumes[ubiLayoutVolume][0].data)
+	for _, volume := range slices.Sorted(maps.Keys(volumes)) {
+		if volume == ubiLayoutVolume {
+			continue
+		}
+		name, ok := names[volume]
+		if !ok {
+			name = strconv.FormatUint(uint64(volume), 10)
+		}
+		err := walkVolume(assembleVolume(volumes[volume], lebSizes[volume], len(data)), func(file File) error {
+			file.Volume = name
+			return fn(file)
+		})
+		if err != nil && !errors.Is(err, errNoFilesystem) {
+			return fmt.Errorf("volume %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// assembleVolume returns the contents of a volume from its logical erase
+// blocks; unmapped blocks read as erased flash (0xFF). A volume has fewer
+// blocks than the image of imageSize bytes holding it has erase blocks.
+func assembleVolume(lebs map[uint32]ubiLEB, lebSize, imageSize int) []byte {
+	last := slices.Max(slices.Collect(maps.Keys(lebs)))
+	if int64(last+1)*int64(lebSize) > int64(imageSize) {
+		return nil
+	}
+	volume := bytes.Repeat([]byte{0xff}, int(last+1)*lebSize)
+	for lnum, leb := range lebs {
+		copy(volume[int(lnum)*lebSize:], leb.data)
+	}
+	return volume
+}
+
+// ubiVolumeNames reads the volume names from the UBI volume table
+func ubiVolumeNames(table []byte) map[uint32]string {
+	const recordSize = 172
+	names := make(map[uint32]string)
+	for i := 0; (i+1)*recordSize <= len(table) && i < 128; i++ {
+		record := table[i*recordSize : (i+1)*recordSize]
+		length := int(binary.BigEndian.Uint16(record[14:]))
+		if binary.BigEndian.Uint32(record) == 0 || length == 0 || length > 127 {
+			continue
+		}
+		names[uint32(i)] = string(record[16 : 16+length])
+	}
+	return names
+}
+
+// errNoFilesystem is returned by walkVolume for volumes without a filesystem
+// Walk can read (e.g. a kernel)
+var errNoFilesystem = errors.New("no supported filesystem")
+
+// walkVolume calls fn with the regular files of the filesystem at the start of
+// a UBI volume
+func walkVolume(volume []byte, fn func(File) error) error {
+	regions, err := signature.Scan(bytes.NewReader(volume), int64(len(volume)))
+	if err != nil {
+		return err
+	}
+	if len(regions) == 0 || regions[0].Offset != 0 || regions[0].Type == signature.TypeUBI || !Supported(regions[0].Type) {
+		return errNoFilesystem
+	}
+	return walkRegion(regions[0].Type, volume[:regions[0].Size], fn)
+}
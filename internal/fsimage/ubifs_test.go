@@ -0,0 +1,187 @@
+package fsimage
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// ubifsTestNode encodes a UBIFS node of nodeType whose fields after the
+// common header are body
+func ubifsTestNode(nodeType byte, sqnum uint64, body []byte) []byte {
+	le := binary.LittleEndian
+	node := make([]byte, 24, 24+len(body))
+	le.PutUint32(node, 0x06101831)
+	le.PutUint64(node[8:], sqnum)
+	le.PutUint32(node[16:], uint32(24+len(body)))
+	node[20] = nodeType
+	node = append(node, body...)
+	le.PutUint32(node[4:], ubifsCRC(node[8:]))
+	return node
+}
+
+// ubifsKey encodes the key of an inode's node
+func ubifsKey(ino uint32, keyType, value uint32) []byte {
+	key := make([]byte, 16)
+	binary.LittleEndian.PutUint32(key, ino)
+	binary.LittleEndian.PutUint32(key[4:], keyType<<29|value)
+	return key
+}
+
+// ubifsTestIno encodes an inode node
+func ubifsTestIno(sqnum uint64, ino uint32, size uint64, nlink uint32) []byte {
+	body := make([]byte, 160-24)
+	copy(body, ubifsKey(ino, ubifsInoNode, 0))
+	binary.LittleEndian.PutUint64(body[48-24:], size)
+	binary.LittleEndian.PutUint32(body[92-24:], nlink)
+	return ubifsTestNode(ubifsInoNode, sqnum, body)
+}
+
+// ubifsTestDent encodes a directory entry node
+func ubifsTestDent(sqnum uint64, parent uint32, name string, ino uint64, kind byte) []byte {
+	body := make([]byte, 56-24, 56-24+len(name)+1)
+	copy(body, ubifsKey(parent, ubifsDentNode, uint32(len(name))))
+	binary.LittleEndian.PutUint64(body[40-24:], ino)
+	body[49-24] = kind
+	binary.LittleEndian.PutUint16(body[50-24:], uint16(len(name)))
+	body = append(body, name...)
+	return ubifsTestNode(ubifsDentNode, sqnum, append(body, 0))
+}
+
+// ubifsTestData encodes a data node of block of an inode
+func ubifsTestData(sqnum uint64, ino, block uint32, compr uint16, size int, data []byte) []byte {
+	body := make([]byte, 48-24, 48-24+len(data))
+	copy(body, ubifsKey(ino, ubifsDataNode, block))
+	binary.LittleEndian.PutUint32(body[40-24:], uint32(size))
+	binary.LittleEndian.PutUint16(body[44-24:], compr)
+	return ubifsTestNode(ubifsDataNode, sqnum, append(body, data...))
+}
+
+// deflateBytes compresses data with raw deflate
+func deflateBytes(data []byte) []byte {
+	var buf bytes.Buffer
+	fw, _ := flate.NewWriter(&buf, flate.BestCompression)
+	_, _ = fw.Write(data)
+	_ = fw.Close()
+	return buf.Bytes()
+}
+
+// ubifsImage builds a UBIFS image of logical erase blocks of lebSize bytes
+// holding /etc/hosts (two blocks, the first rewritten), /firmware.bin (LZO
+// compressed) and a deleted /tmp.txt
+func ubifsImage(hosts []byte, lebSize int) []byte {
+	sb := make([]byte, 4096-24)
+	binary.LittleEndian.PutUint32(sb[36-24:], uint32(lebSize))
+	binary.LittleEndian.PutUint32(sb[40-24:], 3)
+	binary.LittleEndian.PutUint16(sb[84-24:], ubifsComprZlib)
+
+	nodes := [][]byte{
+		ubifsTestNode(6, 1, sb),
+		ubifsTestIno(2, ubifsRoot, 160, 3),
+		ubifsTestDent(3, ubifsRoot, "etc", 64, ubifsTypeDir),
+		ubifsTestIno(4, 64, 160, 2),
+		ubifsTestDent(5, 64, "hosts", 65, ubifsTypeReg),
+		ubifsTestIno(6, 65, uint64(len(hosts)), 1),
+		ubifsTestData(7, 65, 0, ubifsComprNone, ubifsBlockSize, bytes.Repeat([]byte{'o'}, ubifsBlockSize)),
+		ubifsTestData(8, 65, 1, ubifsComprNone, len(hosts)-ubifsBlockSize, hosts[ubifsBlockSize:]),
+		ubifsTestData(9, 65, 0, ubifsComprZlib, ubifsBlockSize, deflateBytes(hosts[:ubifsBlockSize])),
+		ubifsTestDent(10, ubifsRoot, "tmp.txt", 66, ubifsTypeReg),
+		ubifsTestIno(11, 66, 4, 1),
+		ubifsTestData(12, 66, 0, ubifsComprNone, 4, []byte("temp")),
+		ubifsTestDent(13, ubifsRoot, "tmp.txt", 0, ubifsTypeReg),
+		ubifsTestIno(14, 66, 4, 0),
+		ubifsTestDent(15, ubifsRoot, "firmware.bin", 67, ubifsTypeReg),
+		ubifsTestIno(16, 67, 6, 1),
+		ubifsTestData(17, 67, 0, 1, 6, []byte{1, 2, 3, 4}),
+	}
+
+	var image []byte
+	for _, node := range nodes {
+		image = append(image, node...)
+		image = append(image, make([]byte, -len(image)&7)...)
+	}
+	return append(image, bytes.Repeat([]byte{0xff}, 3*lebSize-len(image))...)
+}
+
+// checkUBIFSFiles checks the files Walk found in the image of ubifsImage
+func checkUBIFSFiles(t *testing.T, files []File, hosts []byte, volume string) {
+	t.Helper()
+	if len(files) != 2 {
+		t.Fatalf("Walk() = %+v, want 2 files", files)
+	}
+	if files[0].Path != "/etc/hosts" || !bytes.Equal(files[0].Data, hosts) || files[0].Err != nil || files[0].Volume != volume {
+		t.Errorf("files[0] = %s in %q (%d bytes, err %v), want /etc/hosts in %q", files[0].Path, files[0].Volume, len(files[0].Data), files[0].Err, volume)
+	}
+	if files[1].Path != "/firmware.bin" || !errors.Is(files[1].Err, ErrUnsupported) || !bytes.Equal(files[1].Data, make([]byte, 6)) {
+		t.Errorf("files[1] = %s %v (err %v), want /firmware.bin with an LZO error", files[1].Path, files[1].Data, files[1].Err)
+	}
+}
+
+// TestWalkUBIFS tests that the latest node of each directory entry, inode and
+// data block wins, so rewritten blocks and deleted files are handled
+func TestWalkUBIFS(t *testing.T) {
+	hosts := []byte(strings.Repeat("127.0.0.1 localhost\n", 300))
+	checkUBIFSFiles(t, walkAll(t, ubifsImage(hosts, 16384)), hosts, "")
+}
+
+// ubiTestPEB encodes a physical erase block of pebSize bytes holding data as
+// logical erase block lnum of volume; volume -1 is an erased block
+func ubiTestPEB(pebSize int, volume int64, lnum uint32, sqnum uint64, data []byte) []byte {
+	const vidOffset, dataOffset = 64, 128
+	be := binary.BigEndian
+	peb := bytes.Repeat([]byte{0xff}, pebSize)
+
+	ec := peb[:64]
+	clear(ec)
+	copy(ec, "UBI#")
+	ec[4] = 1
+	be.PutUint32(ec[16:], vidOffset)
+	be.PutUint32(ec[20:], dataOffset)
+	be.PutUint32(ec[60:], ubifsCRC(ec[:60]))
+	if volume < 0 {
+		return peb
+	}
+
+	vid := peb[vidOffset : vidOffset+64]
+	clear(vid)
+	copy(vid, "UBI!")
+	vid[4] = 1
+	be.PutUint32(vid[8:], uint32(volume))
+	be.PutUint32(vid[12:], lnum)
+	be.PutUint64(vid[40:], sqnum)
+	be.PutUint32(vid[60:], ubifsCRC(vid[:60]))
+	copy(peb[dataOffset:], data)
+	return peb
+}
+
+// ubiImage builds a UBI image of erase blocks of pebSize bytes holding the
+// UBIFS image of ubifsImage as volume "rootfs", with its erase blocks out of
+// order, an erased block and a stale copy of a block
+func ubiImage(hosts []byte, pebSize int) []byte {
+	lebSize := pebSize - 128
+	volume := ubifsImage(hosts, lebSize)
+
+	table := make([]byte, 172)
+	binary.BigEndian.PutUint32(table, 3) // Reserved erase blocks
+	binary.BigEndian.PutUint16(table[14:], 6)
+	copy(table[16:], "rootfs")
+
+	return bytes.Join([][]byte{
+		ubiTestPEB(pebSize, ubiLayoutVolume, 0, 1, table),
+		ubiTestPEB(pebSize, 0, 1, 2, bytes.Repeat([]byte{0xaa}, lebSize)), // Stale
+		ubiTestPEB(pebSize, 0, 2, 3, volume[2*lebSize:]),
+		ubiTestPEB(pebSize, -1, 0, 0, nil),
+		ubiTestPEB(pebSize, 0, 0, 4, volume[:lebSize]),
+		ubiTestPEB(pebSize, 0, 1, 5, volume[lebSize:2*lebSize]),
+	}, nil)
+}
+
+// TestWalkUBI tests reading a UBIFS volume of a UBI image with its name, from
+// erase blocks out of order, with an erased block and a stale copy of a block
+func TestWalkUBI(t *testing.T) {
+	hosts := []byte(strings.Repeat("10.0.0.1 router\n", 400))
+	checkUBIFSFiles(t, walkAll(t, ubiImage(hosts, 16384)), hosts, "rootfs")
+}
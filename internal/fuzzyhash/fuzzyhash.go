@@ -0,0 +1,153 @@
+// Package fuzzyhash computes similarity hashes of string sets. Like TLSH, a
+// hash is a locality-sensitive digest of a histogram: files that share most
+// of their strings get hashes with a small Distance, so related samples can
+// be clustered without exchanging the strings themselves.
+package fuzzyhash
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"slices"
+)
+
+// prefix identifies the hash version
+const prefix = "S1"
+
+// buckets is the number of histogram buckets (2 bits each in the hash)
+const buckets = 128
+
+// MinStrings is the smallest number of distinct strings a hash is computed
+// for; smaller sets give histograms too sparse to compare
+const MinStrings = 32
+
+// hashLength is the length of a hash string: prefix, one count byte and the
+// bucket codes, hex encoded
+const hashLength = len(prefix) + 2 + buckets/4*2
+
+// Hasher accumulates the distinct strings of a file. The zero value is ready
+// to use.
+type Hasher struct {
+	set map[uint32]struct{}
+}
+
+// Add adds a string to the set
+func (h *Hasher) Add(str []byte) {
+	if h.set == nil {
+		h.set = make(map[uint32]struct{})
+	}
+	fnvHash := fnv.New32a()
+	_, _ = fnvHash.Write(str)
+	h.set[fnvHash.Sum32()] = struct{}{}
+}
+
+// Merge adds the strings of other to the set
+func (h *Hasher) Merge(other *Hasher) {
+	if h.set == nil {
+		h.set = make(map[uint32]struct{}, len(other.set))
+	}
+	for key := range other.set {
+		h.set[key] = struct{}{}
+	}
+}
+
+// Count returns the number of distinct strings added
+func (h *Hasher) Count() int {
+	return len(h.set)
+}
+
+// Sum returns the similarity hash of the set, or "" if it holds fewer than
+// MinStrings strings. The order strings were added in does not matter.
+func (h *Hasher) Sum() string {
+	if len(h.set) < MinStrings {
+		return ""
+	}
+
+	var counts [buckets]int
+	for key := range h.set {
+		// Mix the FNV hash so the bucket depends on all of its bits
+		counts[(key*2654435761)>>25]++
+	}
+
+	// Quartiles of the bucket counts
+	sorted := counts
+	slices.Sort(sorted[:])
+	q1, q2, q3 := sorted[buckets/4-1], sorted[buckets/2-1], sorted[buckets*3/4-1]
+
+	// Each bucket is coded by the quartile its count falls in
+	body := make([]byte, buckets/4)
+	for i, count := range counts {
+		var code byte
+		switch {
+		case count <= q1:
+			code = 0
+		case count <= q2:
+			code = 1
+		case count <= q3:
+			code = 2
+		default:
+			code = 3
+		}
+		body[i/4] |= code << (uint(i%4) * 2)
+	}
+
+	return fmt.Sprintf("%s%02x%s", prefix, countByte(len(h.set)), hex.EncodeToString(body))
+}
+
+// countByte encodes a set size on a logarithmic scale
+func countByte(n int) byte {
+	return byte(min(math.Floor(math.Log(float64(n))/math.Log(1.2)), 255))
+}
+
+// Distance returns the distance between two hashes: 0 for identical string
+// sets, growing as they share fewer strings. Sets of very different sizes are
+// penalized.
+func Distance(a, b string) (int, error) {
+	countA, bodyA, err := parse(a)
+	if err != nil {
+		return 0, err
+	}
+	countB, bodyB, err := parse(b)
+	if err != nil {
+		return 0, err
+	}
+
+	distance := 0
+	if diff := absDiff(int(countA), int(countB)); diff > 1 {
+		distance += diff * 12
+	} else {
+		distance += diff
+	}
+
+	for i := range buckets {
+		shift := uint(i%4) * 2
+		diff := absDiff(int(bodyA[i/4]>>shift&3), int(bodyB[i/4]>>shift&3))
+		// Opposite quartiles weigh double
+		if diff == 3 {
+			diff = 6
+		}
+		distance += diff
+	}
+	return distance, nil
+}
+
+// parse splits a hash into its count byte and bucket codes
+func parse(hash string) (byte, []byte, error) {
+	if len(hash) != hashLength || hash[:len(prefix)] != prefix {
+		return 0, nil, fmt.Errorf("invalid string hash %q", hash)
+	}
+	decoded, err := hex.DecodeString(hash[len(prefix):])
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid string hash %q: %w", hash, err)
+	}
+	return decoded[0], decoded[1:], nil
+}
+
+// absDiff returns |a-b|
+func absDiff(a, b int) int {
+	if a > b {
+		return a - b
+	}
+	return b - a
+}
@@ -0,0 +1,75 @@
+package fuzzyhash
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// stringSet returns a hasher holding n strings of a family starting at index start
+func stringSet(family string, start, n int) *Hasher {
+	h := &Hasher{}
+	for i := start; i < start+n; i++ {
+		h.Add(fmt.Appendf(nil, "%s string %d", family, i))
+	}
+	return h
+}
+
+// TestSum tests hash format and order independence
+func TestSum(t *testing.T) {
+	if hash := stringSet("a", 0, MinStrings-1).Sum(); hash != "" {
+		t.Errorf("Sum() of %d strings = %q, want empty", MinStrings-1, hash)
+	}
+
+	hash := stringSet("a", 0, 500).Sum()
+	if len(hash) != hashLength || !strings.HasPrefix(hash, prefix) {
+		t.Fatalf("Sum() = %q, want %d characters starting with %s", hash, hashLength, prefix)
+	}
+
+	// Order and duplicates do not matter
+	reversed := &Hasher{}
+	for i := 499; i >= 0; i-- {
+		reversed.Add(fmt.Appendf(nil, "a string %d", i))
+		reversed.Add(fmt.Appendf(nil, "a string %d", i))
+	}
+	if reversed.Count() != 500 {
+		t.Errorf("Count() = %d, want 500", reversed.Count())
+	}
+	if got := reversed.Sum(); got != hash {
+		t.Errorf("Sum() of reordered set = %q, want %q", got, hash)
+	}
+
+	// Merging the halves gives the hash of the whole
+	merged := stringSet("a", 0, 250)
+	merged.Merge(stringSet("a", 250, 250))
+	if got := merged.Sum(); got != hash {
+		t.Errorf("Sum() of merged set = %q, want %q", got, hash)
+	}
+}
+
+// TestDistance tests that distance grows as string sets share fewer strings
+func TestDistance(t *testing.T) {
+	base := stringSet("a", 0, 2000).Sum()
+
+	same, err := Distance(base, base)
+	if err != nil || same != 0 {
+		t.Errorf("Distance(h, h) = %d, %v, want 0", same, err)
+	}
+
+	near, _ := Distance(base, stringSet("a", 100, 2000).Sum())    // 95% shared
+	half, _ := Distance(base, stringSet("a", 1000, 2000).Sum())   // 50% shared
+	unrelated, _ := Distance(base, stringSet("b", 0, 2000).Sum()) // nothing shared
+	smaller, _ := Distance(base, stringSet("a", 0, 200).Sum())    // subset of very different size
+	if !(near < half && half < unrelated) {
+		t.Errorf("distances not ordered by overlap: 95%% = %d, 50%% = %d, 0%% = %d", near, half, unrelated)
+	}
+	if smaller <= near {
+		t.Errorf("distance to a much smaller set = %d, want more than %d", smaller, near)
+	}
+
+	for _, invalid := range []string{"", "S1zz", "X1" + base[2:], base[:len(base)-2] + "zz"} {
+		if _, err := Distance(base, invalid); err == nil {
+			t.Errorf("Distance(h, %q) succeeded, want error", invalid)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package ignore
+
+import "fmt"
+
+// Finding is a problem with an ignore rule reported by "txtr check-ignores"
+type Finding struct {
+	Rule    Rule
+	Problem string // "duplicate", "overlap", "dead" or "redundant"
+	Detail  string
+}
+
+// Overlaps returns rules that can never change the result of the list: exact
+// strings and patterns repeating an earlier rule, and exact strings that a
+// glob or regex rule also matches
+func (l *List) Overlaps() []Finding {
+	var findings []Finding
+	seen := make(map[string]int)
+	for i, rule := range l.Rules {
+		key := rule.String()
+		if first, ok := seen[key]; ok {
+			findings = append(findings, Finding{
+				Rule:    rule,
+				Problem: "duplicate",
+				Detail:  fmt.Sprintf("same rule as %s", l.Rules[first].Position()),
+			})
+			continue
+		}
+		seen[key] = i
+
+		if rule.Kind != KindExact {
+			continue
+		}
+		for _, index := range l.patterns {
+			if l.Rules[index].re.MatchString(rule.Pattern) {
+				findings = append(findings, Finding{
+					Rule:    rule,
+					Problem: "overlap",
+					Detail:  fmt.Sprintf("also matched by %s (%s)", l.Rules[index], l.Rules[index].Position()),
+				})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+// Coverage counts how often each rule of a list matches a corpus of strings
+type Coverage struct {
+	list      *List
+	Hits      []int64 // Matches per rule
+	Exclusive []int64 // Matches per rule that no other rule matched
+}
+
+// NewCoverage returns an empty coverage count for the list
+func (l *List) NewCoverage() *Coverage {
+	return &Coverage{
+		list:      l,
+		Hits:      make([]int64, len(l.Rules)),
+		Exclusive: make([]int64, len(l.Rules)),
+	}
+}
+
+// Add counts the rules matching str
+func (c *Coverage) Add(str []byte) {
+	indexes := c.list.Matching(str)
+	for _, index := range indexes {
+		c.Hits[index]++
+	}
+	if len(indexes) == 1 {
+		c.Exclusive[indexes[0]]++
+	}
+}
+
+// Findings returns the rules that never matched (dead) and the rules whose
+// every match was also matched by another rule (redundant)
+func (c *Coverage) Findings() []Finding {
+	var findings []Finding
+	for i, rule := range c.list.Rules {
+		switch {
+		case c.Hits[i] == 0:
+			findings = append(findings, Finding{Rule: rule, Problem: "dead", Detail: "matched no strings"})
+		case c.Exclusive[i] == 0:
+			findings = append(findings, Finding{
+				Rule:    rule,
+				Problem: "redundant",
+				Detail:  fmt.Sprintf("all %d matches also matched by other rules", c.Hits[i]),
+			})
+		}
+	}
+	return findings
+}
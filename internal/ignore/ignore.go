@@ -0,0 +1,211 @@
+// Package ignore implements analyst-maintained ignore lists: files of exact
+// strings, globs and regular expressions whose matches are dropped from the
+// output (--ignore-file) and checked for dead and overlapping rules
+// ("txtr check-ignores").
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Kind is the type of an ignore rule
+type Kind int
+
+const (
+	// KindExact matches one string exactly
+	KindExact Kind = iota
+	// KindGlob matches strings against a shell-style pattern (* ? [...])
+	KindGlob
+	// KindRegex matches strings containing a regular expression match
+	KindRegex
+)
+
+// String returns the name of the rule kind
+func (k Kind) String() string {
+	switch k {
+	case KindGlob:
+		return "glob"
+	case KindRegex:
+		return "regex"
+	default:
+		return "exact"
+	}
+}
+
+// Rule is one rule of an ignore list
+type Rule struct {
+	File    string // File the rule was read from
+	Line    int    // Line number in File (1-based)
+	Kind    Kind
+	Pattern string // Exact string, glob or regular expression
+	re      *regexp.Regexp
+}
+
+// String returns the rule as written in the ignore file
+func (r Rule) String() string {
+	switch r.Kind {
+	case KindGlob:
+		return "glob:" + r.Pattern
+	case KindRegex:
+		return "re:" + r.Pattern
+	default:
+		return r.Pattern
+	}
+}
+
+// Position returns the rule's location as "file:line"
+func (r Rule) Position() string {
+	return fmt.Sprintf("%s:%d", r.File, r.Line)
+}
+
+// Match reports whether the rule matches str
+func (r Rule) Match(str []byte) bool {
+	if r.Kind == KindExact {
+		return string(str) == r.Pattern
+	}
+	return r.re.Match(str)
+}
+
+// List is a set of ignore rules
+type List struct {
+	Rules    []Rule
+	exact    map[string]int // Exact pattern to index of its first rule
+	patterns []int          // Indexes of glob and regex rules
+}
+
+// Parse parses an ignore file. Each line is an exact string, a glob prefixed
+// with "glob:" or a regular expression prefixed with "re:". Blank lines and
+// lines starting with "#" are skipped; a leading "\" escapes a "#", "glob:" or
+// "re:" at the start of an exact string. Leading and trailing whitespace is
+// not significant. name is used in rule positions and error messages.
+func Parse(name string, data []byte) (*List, error) {
+	list := &List{}
+	if err := list.parse(name, data); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// Load reads and parses ignore files, combining their rules into one list
+func Load(paths ...string) (*List, error) {
+	list := &List{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := list.parse(path, data); err != nil {
+			return nil, err
+		}
+	}
+	return list, nil
+}
+
+// parse appends the rules of an ignore file to the list
+func (l *List) parse(name string, data []byte) error {
+	if l.exact == nil {
+		l.exact = make(map[string]int)
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, 1<<20)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		rule := Rule{File: name, Line: lineNum, Kind: KindExact, Pattern: line}
+		var err error
+		if pattern, ok := strings.CutPrefix(line, "glob:"); ok {
+			rule.Kind, rule.Pattern = KindGlob, pattern
+			rule.re, err = compileGlob(pattern)
+		} else if pattern, ok := strings.CutPrefix(line, "re:"); ok {
+			rule.Kind, rule.Pattern = KindRegex, pattern
+			rule.re, err = regexp.Compile(pattern)
+		} else if escaped, ok := strings.CutPrefix(line, `\`); ok {
+			rule.Pattern = escaped
+		}
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid %s rule %q: %w", name, lineNum, rule.Kind, rule.Pattern, err)
+		}
+
+		index := len(l.Rules)
+		l.Rules = append(l.Rules, rule)
+		if rule.Kind == KindExact {
+			if _, ok := l.exact[rule.Pattern]; !ok {
+				l.exact[rule.Pattern] = index
+			}
+		} else {
+			l.patterns = append(l.patterns, index)
+		}
+	}
+	return scanner.Err()
+}
+
+// compileGlob converts a glob to an anchored regular expression: "*" matches
+// any run of characters, "?" any one character and "[...]" a character class
+// ("[!...]" negated). A "\" escapes the next character.
+func compileGlob(glob string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString(`\A(?s:`)
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			b.WriteString(".*")
+		case '?':
+			b.WriteString(".")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated character class")
+			}
+			class := glob[i+1 : i+1+end]
+			if negated, ok := strings.CutPrefix(class, "!"); ok {
+				class = "^" + negated
+			}
+			b.WriteString("[" + class + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(glob) {
+				i++
+			}
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`)\z`)
+	return regexp.Compile(b.String())
+}
+
+// Contains reports whether any rule matches str
+func (l *List) Contains(str []byte) bool {
+	if _, ok := l.exact[string(str)]; ok {
+		return true
+	}
+	for _, index := range l.patterns {
+		if l.Rules[index].re.Match(str) {
+			return true
+		}
+	}
+	return false
+}
+
+// Matching returns the indexes of all rules that match str, in rule order
+func (l *List) Matching(str []byte) []int {
+	var indexes []int
+	for i, rule := range l.Rules {
+		if rule.Match(str) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
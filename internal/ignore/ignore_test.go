@@ -0,0 +1,191 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testList = `# Build noise
+GCC: (GNU) 13.2.0
+glob:*.so.[0-9]
+glob:GLIBC_2.?
+re:^_Z[A-Za-z0-9_]+$
+
+\# not a comment
+\re:literal
+`
+
+// TestParse tests rule kinds, comments and escapes
+func TestParse(t *testing.T) {
+	list, err := Parse("test.txt", []byte(testList))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	wantRules := []struct {
+		line    int
+		kind    Kind
+		pattern string
+	}{
+		{2, KindExact, "GCC: (GNU) 13.2.0"},
+		{3, KindGlob, "*.so.[0-9]"},
+		{4, KindGlob, "GLIBC_2.?"},
+		{5, KindRegex, "^_Z[A-Za-z0-9_]+$"},
+		{7, KindExact, "# not a comment"},
+		{8, KindExact, "re:literal"},
+	}
+	if len(list.Rules) != len(wantRules) {
+		t.Fatalf("got %d rules, want %d", len(list.Rules), len(wantRules))
+	}
+	for i, want := range wantRules {
+		rule := list.Rules[i]
+		if rule.Line != want.line || rule.Kind != want.kind || rule.Pattern != want.pattern {
+			t.Errorf("rule %d = %d %v %q, want %d %v %q", i, rule.Line, rule.Kind, rule.Pattern, want.line, want.kind, want.pattern)
+		}
+	}
+
+	tests := []struct {
+		str  string
+		want bool
+	}{
+		{"GCC: (GNU) 13.2.0", true},
+		{"libc.so.6", true},
+		{"libc.so.6x", false},
+		{"GLIBC_2.5", true},
+		{"GLIBC_2.34", false},
+		{"_ZN3foo3barEv", true},
+		{"x_ZN3foo", false},
+		{"# not a comment", true},
+		{"re:literal", true},
+		{"literal", false},
+	}
+	for _, tt := range tests {
+		if got := list.Contains([]byte(tt.str)); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.str, got, tt.want)
+		}
+	}
+}
+
+// TestParseInvalid tests that invalid patterns are reported with their position
+func TestParseInvalid(t *testing.T) {
+	for _, data := range []string{"ok\nre:([a-z\n", "glob:[abc\n"} {
+		_, err := Parse("bad.txt", []byte(data))
+		if err == nil || !strings.HasPrefix(err.Error(), "bad.txt:") {
+			t.Errorf("Parse(%q) error = %v, want positioned error", data, err)
+		}
+	}
+}
+
+// TestCompileGlob tests glob to regular expression conversion
+func TestCompileGlob(t *testing.T) {
+	tests := []struct {
+		glob string
+		str  string
+		want bool
+	}{
+		{"abc", "abc", true},
+		{"abc", "abcd", false},
+		{"a*c", "a/b/c", true},
+		{"a?c", "abc", true},
+		{"a?c", "ac", false},
+		{"[!a]bc", "xbc", true},
+		{"[!a]bc", "abc", false},
+		{`a\*c`, "a*c", true},
+		{`a\*c`, "abc", false},
+		{"a.c", "abc", false},
+		{"*", "line\nbreak", true},
+	}
+	for _, tt := range tests {
+		re, err := compileGlob(tt.glob)
+		if err != nil {
+			t.Fatalf("compileGlob(%q) error = %v", tt.glob, err)
+		}
+		if got := re.MatchString(tt.str); got != tt.want {
+			t.Errorf("glob %q matching %q = %v, want %v", tt.glob, tt.str, got, tt.want)
+		}
+	}
+}
+
+// TestLoad tests combining several ignore files
+func TestLoad(t *testing.T) {
+	dir := t.TempDir()
+	first := filepath.Join(dir, "first.txt")
+	second := filepath.Join(dir, "second.txt")
+	if err := os.WriteFile(first, []byte("alpha\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(second, []byte("# comment\nglob:beta*\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := Load(first, second)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(list.Rules) != 2 || list.Rules[1].Position() != second+":2" {
+		t.Errorf("Load() rules = %+v", list.Rules)
+	}
+	if !list.Contains([]byte("alpha")) || !list.Contains([]byte("betamax")) {
+		t.Error("combined list does not match rules of both files")
+	}
+
+	if _, err := Load(filepath.Join(dir, "missing.txt")); err == nil {
+		t.Error("Load() accepted a missing file")
+	}
+}
+
+// TestOverlaps tests static detection of duplicate and overlapping rules
+func TestOverlaps(t *testing.T) {
+	list, err := Parse("test.txt", []byte("libc.so.6\nglob:lib*.so.*\nlibc.so.6\nre:^x+$\nre:^x+$\nunique\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	findings := list.Overlaps()
+	want := []struct {
+		line    int
+		problem string
+	}{
+		{1, "overlap"},
+		{3, "duplicate"},
+		{5, "duplicate"},
+	}
+	if len(findings) != len(want) {
+		t.Fatalf("Overlaps() = %+v, want %d findings", findings, len(want))
+	}
+	for i, w := range want {
+		if findings[i].Rule.Line != w.line || findings[i].Problem != w.problem {
+			t.Errorf("finding %d = line %d %s, want line %d %s", i, findings[i].Rule.Line, findings[i].Problem, w.line, w.problem)
+		}
+	}
+}
+
+// TestCoverage tests detection of dead and redundant rules against a corpus
+func TestCoverage(t *testing.T) {
+	list, err := Parse("test.txt", []byte("glob:debug:*\nre:^debug: verbose\nnever seen\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	coverage := list.NewCoverage()
+	for _, str := range []string{"debug: start", "debug: verbose on", "unrelated"} {
+		coverage.Add([]byte(str))
+	}
+
+	if coverage.Hits[0] != 2 || coverage.Exclusive[0] != 1 {
+		t.Errorf("rule 1 hits = %d (%d exclusive), want 2 (1 exclusive)", coverage.Hits[0], coverage.Exclusive[0])
+	}
+
+	findings := coverage.Findings()
+	if len(findings) != 2 {
+		t.Fatalf("Findings() = %+v, want 2 findings", findings)
+	}
+	if findings[0].Rule.Line != 2 || findings[0].Problem != "redundant" {
+		t.Errorf("finding 0 = line %d %s, want line 2 redundant", findings[0].Rule.Line, findings[0].Problem)
+	}
+	if findings[1].Rule.Line != 3 || findings[1].Problem != "dead" {
+		t.Errorf("finding 1 = line %d %s, want line 3 dead", findings[1].Rule.Line, findings[1].Problem)
+	}
+}
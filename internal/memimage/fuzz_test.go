@@ -0,0 +1,96 @@
+package memimage
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// FuzzParseMap tests reading memory maps with random inputs
+func FuzzParseMap(f *testing.F) {
+	// Seed corpus: a map of each format of the ParseMap tests and malformed
+	// lines
+	f.Add("# regions\n0x1000 +0x1000 kernel\n\n0x3000 0x5000 pid 4 System\n")
+	f.Add("00001000-0009ffff : System RAM\n00100000-3fffffff : System RAM\n  01000000-01e0ffff : Kernel code\n")
+	f.Add("PID\tProcess\tVirtual\tPhysical\tSize\tOffset in File\tFile output\n" +
+		"4\tSystem\t0xf80000000000\t0x2000\t0x1000\t0x0\tDisabled\n" +
+		"1234\texplorer.exe\t0x7ff000000000\t0x2000\t0x1000\t0x0\tDisabled\n")
+	f.Add("0x1000 0x2000 kernel\n0x2000 0x3000\n")
+	f.Add("0 +ffffffffffffffff all\n")
+	f.Add("")
+
+	f.Fuzz(func(t *testing.T, input string) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(input) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, input)
+			}
+		}()
+
+		regions, err := ParseMap(strings.NewReader(input))
+		if err != nil {
+			// Errors are expected for invalid input
+			return
+		}
+
+		// Invariant: regions are not empty, in offset order and do not
+		// overlap
+		end := int64(0)
+		for i, region := range regions {
+			if region.Offset < 0 || region.Size <= 0 || region.Offset < end || region.Offset+region.Size < region.Offset {
+				t.Errorf("region %d %+v is empty or overlaps the one before (ending at 0x%x)", i, region, end)
+			}
+			end = region.Offset + region.Size
+		}
+	})
+}
+
+// FuzzAnalyze tests walking the page tables of memory images with random
+// inputs
+func FuzzAnalyze(f *testing.F) {
+	// Seed corpus: the image of the Analyze tests with each profile
+	image := testMemory()
+	f.Add(false, image)
+	f.Add(true, image)
+	f.Add(false, image[:4*pageSize])
+	f.Add(true, []byte(""))
+
+	f.Fuzz(func(t *testing.T, linux bool, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		profile := ProfileWindows
+		if linux {
+			profile = ProfileLinux
+		}
+		regions, err := Analyze(bytes.NewReader(data), int64(len(data)), profile)
+		if err != nil {
+			// Errors are expected for invalid input
+			return
+		}
+
+		// Invariant: regions are whole pages of the image, in offset order
+		// without overlaps
+		end := int64(0)
+		for i, region := range regions {
+			if region.Offset < end || region.Size <= 0 || region.Offset%pageSize != 0 || region.Size%pageSize != 0 || region.Offset+region.Size > int64(len(data)) {
+				t.Errorf("region %d %+v is not pages of the %d byte image after 0x%x", i, region, len(data), end)
+			}
+			end = region.Offset + region.Size
+		}
+	})
+}
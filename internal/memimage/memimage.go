@@ -0,0 +1,284 @@
+// Package memimage divides raw physical memory images into regions (the
+// kernel, each process, page tables) so strings found in them can be
+// attributed, from a map of the image made by another tool (ParseMap) or by
+// walking the page tables found in the image (Analyze).
+package memimage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Region is a range of a memory image, with what it holds
+type Region struct {
+	Offset     int64
+	Size       int64
+	Label      string // e.g. "kernel", "pid 1234 explorer.exe", "System RAM"
+	Address    uint64 // Virtual address at Offset, if HasAddress
+	HasAddress bool
+}
+
+// maxSharedLabels is how many labels of a range shared by several regions
+// (a page mapped by several processes) are listed
+const maxSharedLabels = 3
+
+var errSyntax = errors.New("memory map syntax")
+
+// ParseMap reads a map of a memory image, in one of these formats, and
+// returns its regions sorted by offset, without overlaps: where regions
+// nest (/proc/iomem), the innermost wins; where they coincide (a page mapped
+// by several processes), their labels are joined. Numbers are hexadecimal,
+// with or without 0x.
+//
+//   - Volatility 3 memmap output, with a header naming the PID, Process,
+//     Virtual, Physical and Size columns: each row's physical range is
+//     labelled "pid PID PROCESS", with its virtual address
+//   - START-END LABEL, END inclusive if it ends in fff (/proc/iomem, whose
+//     "START-END : LABEL" lines are also read) and exclusive otherwise
+//   - START +SIZE LABEL
+//
+// Blank lines and lines starting with # are skipped.
+func ParseMap(r io.Reader) ([]Region, error) {
+	var regions []Region
+	var columns map[string]int // Volatility memmap columns
+	separator := ""
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(text)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if columns == nil && len(regions) == 0 && strings.Contains(text, "PID") && strings.Contains(text, "Physical") {
+			if strings.Contains(text, "\t") {
+				separator = "\t"
+			}
+			columns = map[string]int{}
+			for i, name := range splitColumns(text, separator) {
+				columns[name] = i
+			}
+			continue
+		}
+
+		var region Region
+		var err error
+		if columns != nil {
+			region, err = parseMemmapRow(splitColumns(text, separator), columns)
+		} else {
+			region, err = parseRange(trimmed)
+		}
+		if err != nil {
+			// Volatility prints a banner and progress lines before its table
+			if columns == nil && len(regions) == 0 && line <= 3 {
+				continue
+			}
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		if region.Size > 0 {
+			regions = append(regions, region)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return Flatten(regions), nil
+}
+
+// splitColumns splits a row of a table into its trimmed columns, by tabs if
+// separator is a tab, else by runs of spaces
+func splitColumns(text, separator string) []string {
+	if separator == "" {
+		return strings.Fields(text)
+	}
+	columns := strings.Split(text, separator)
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+	return columns
+}
+
+// parseMemmapRow parses a row of Volatility memmap output
+func parseMemmapRow(row []string, columns map[string]int) (Region, error) {
+	get := func(name string) (string, error) {
+		i, ok := columns[name]
+		if !ok || i >= len(row) {
+			return "", fmt.Errorf("%w: no %s column", errSyntax, name)
+		}
+		return row[i], nil
+	}
+	pid, err := get("PID")
+	if err != nil {
+		return Region{}, err
+	}
+	physical, err := get("Physical")
+	if err != nil {
+		return Region{}, err
+	}
+	size, err := get("Size")
+	if err != nil {
+		return Region{}, err
+	}
+	offset, err := parseHex(physical)
+	if err != nil {
+		return Region{}, err
+	}
+	length, err := parseHex(size)
+	if err != nil {
+		return Region{}, err
+	}
+	region := Region{Offset: int64(offset), Size: int64(length), Label: "pid " + pid}
+	if process, err := get("Process"); err == nil && process != "" {
+		region.Label += " " + process
+	}
+	if virtual, err := get("Virtual"); err == nil {
+		if address, err := parseHex(virtual); err == nil {
+			region.Address, region.HasAddress = address, true
+		}
+	}
+	return region, nil
+}
+
+// parseRange parses a START-END LABEL or START +SIZE LABEL line
+func parseRange(text string) (Region, error) {
+	first, rest, _ := strings.Cut(text, " ")
+	var start, end uint64
+	var err error
+	if from, to, ok := strings.Cut(first, "-"); ok {
+		if start, err = parseHex(from); err != nil {
+			return Region{}, err
+		}
+		if end, err = parseHex(to); err != nil {
+			return Region{}, err
+		}
+		if end&0xfff == 0xfff {
+			end++
+		}
+	} else {
+		if start, err = parseHex(first); err != nil {
+			return Region{}, err
+		}
+		var second string
+		second, rest, _ = strings.Cut(strings.TrimSpace(rest), " ")
+		if size, ok := strings.CutPrefix(second, "+"); ok {
+			length, err := parseHex(size)
+			if err != nil {
+				return Region{}, err
+			}
+			end = start + length
+		} else if end, err = parseHex(second); err != nil {
+			return Region{}, err
+		}
+	}
+	if end < start || start > 1<<62 || end > 1<<62 {
+		return Region{}, fmt.Errorf("%w: range 0x%x-0x%x", errSyntax, start, end)
+	}
+	label := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(rest), ":"))
+	if label == "" {
+		return Region{}, fmt.Errorf("%w: no label", errSyntax)
+	}
+	return Region{Offset: int64(start), Size: int64(end - start), Label: label}, nil
+}
+
+// parseHex parses a hexadecimal number, with or without 0x
+func parseHex(s string) (uint64, error) {
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "0x"), "0X")
+	n, err := strconv.ParseUint(s, 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q is not a hexadecimal number", errSyntax, s)
+	}
+	return n, nil
+}
+
+// Flatten resolves the overlaps of regions and returns them sorted by
+// offset: each range goes to the smallest region holding it, with the labels
+// of regions of the same offset and size joined (and no address). Adjacent
+// ranges of the same label (and consecutive addresses) are merged.
+func Flatten(regions []Region) []Region {
+	if len(regions) == 0 {
+		return nil
+	}
+	sorted := append([]Region(nil), regions...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Offset < sorted[j].Offset })
+	bounds := make([]int64, 0, 2*len(sorted))
+	for _, r := range sorted {
+		bounds = append(bounds, r.Offset, r.Offset+r.Size)
+	}
+	sort.Slice(bounds, func(i, j int) bool { return bounds[i] < bounds[j] })
+
+	var out []Region
+	var active []Region
+	next := 0
+	for i := 0; i+1 < len(bounds); i++ {
+		from, to := bounds[i], bounds[i+1]
+		if from == to {
+			continue
+		}
+		for next < len(sorted) && sorted[next].Offset <= from {
+			active = append(active, sorted[next])
+			next++
+		}
+		kept := active[:0]
+		for _, r := range active {
+			if r.Offset+r.Size > from {
+				kept = append(kept, r)
+			}
+		}
+		active = kept
+		if len(active) == 0 {
+			continue
+		}
+		out = appendRange(out, from, to, innermost(active))
+	}
+	return out
+}
+
+// innermost returns the smallest of the regions holding a range, with the
+// labels of those of the same offset and size joined
+func innermost(active []Region) Region {
+	best := active[0]
+	for _, r := range active[1:] {
+		if r.Size < best.Size {
+			best = r
+		}
+	}
+	var labels []string
+	seen := map[string]bool{}
+	for _, r := range active {
+		if r.Offset == best.Offset && r.Size == best.Size && !seen[r.Label] {
+			seen[r.Label] = true
+			labels = append(labels, r.Label)
+		}
+	}
+	if len(labels) > 1 {
+		best.HasAddress, best.Address = false, 0
+		if len(labels) > maxSharedLabels {
+			labels = append(labels[:maxSharedLabels], fmt.Sprintf("+%d more", len(labels)-maxSharedLabels))
+		}
+		best.Label = strings.Join(labels, ", ")
+	}
+	return best
+}
+
+// appendRange appends the range from-to of region r to out, merging it with
+// the last range if they continue each other
+func appendRange(out []Region, from, to int64, r Region) []Region {
+	part := Region{Offset: from, Size: to - from, Label: r.Label, HasAddress: r.HasAddress}
+	if r.HasAddress {
+		part.Address = r.Address + uint64(from-r.Offset)
+	}
+	if n := len(out); n > 0 {
+		last := &out[n-1]
+		if last.Offset+last.Size == from && last.Label == part.Label && last.HasAddress == part.HasAddress &&
+			(!part.HasAddress || last.Address+uint64(last.Size) == part.Address) {
+			last.Size += part.Size
+			return out
+		}
+	}
+	return append(out, part)
+}
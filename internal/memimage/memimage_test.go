@@ -0,0 +1,124 @@
+package memimage
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestParseMap tests reading each format of memory map
+func TestParseMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []Region
+		wantErr bool
+	}{
+		{
+			name:  "ranges",
+			input: "# regions\n0x1000 +0x1000 kernel\n\n0x3000 0x5000 pid 4 System\n",
+			want: []Region{
+				{Offset: 0x1000, Size: 0x1000, Label: "kernel"},
+				{Offset: 0x3000, Size: 0x2000, Label: "pid 4 System"},
+			},
+		},
+		{
+			name:  "iomem",
+			input: "00001000-0009ffff : System RAM\n00100000-3fffffff : System RAM\n  01000000-01e0ffff : Kernel code\n",
+			want: []Region{
+				{Offset: 0x1000, Size: 0x9f000, Label: "System RAM"},
+				{Offset: 0x100000, Size: 0xf00000, Label: "System RAM"},
+				{Offset: 0x1000000, Size: 0xe10000, Label: "Kernel code"},
+				{Offset: 0x1e10000, Size: 0x3e1f0000, Label: "System RAM"},
+			},
+		},
+		{
+			name: "volatility memmap",
+			input: "Volatility 3 Framework 2.5.0\nProgress:  100.00\t\tPDB scanning finished\n" +
+				"PID\tProcess\tVirtual\tPhysical\tSize\tOffset in File\tFile output\n\n" +
+				"4\tSystem\t0xf80000000000\t0x2000\t0x1000\t0x0\tDisabled\n" +
+				"4\tSystem\t0xf80000001000\t0x3000\t0x1000\t0x1000\tDisabled\n" +
+				"1234\texplorer.exe\t0x7ff000000000\t0x3000\t0x1000\t0x0\tDisabled\n" +
+				"1234\texplorer.exe\t0x7ff000001000\t0x8000\t0x1000\t0x1000\tDisabled\n",
+			want: []Region{
+				{Offset: 0x2000, Size: 0x1000, Label: "pid 4 System", Address: 0xf80000000000, HasAddress: true},
+				{Offset: 0x3000, Size: 0x1000, Label: "pid 4 System, pid 1234 explorer.exe"},
+				{Offset: 0x8000, Size: 0x1000, Label: "pid 1234 explorer.exe", Address: 0x7ff000001000, HasAddress: true},
+			},
+		},
+		{
+			name:    "no label",
+			input:   "0x1000 0x2000 kernel\n0x2000 0x3000\n",
+			wantErr: true,
+		},
+		{
+			name:    "not a map",
+			input:   "0x1000 0x2000 kernel\nhello world\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMap(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseMap() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseMap() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// testMemory builds a 16-page physical memory image of two Windows address
+// spaces (top-level tables in pages 1 and 11, mapping themselves), sharing
+// the kernel page 10 and the user page 9, with pages 8 and 15 their own
+func testMemory() []byte {
+	image := make([]byte, 16*pageSize)
+	set := func(page, entry int, value uint64) {
+		binary.LittleEndian.PutUint64(image[page*pageSize+entry*8:], value)
+	}
+	const user, kernel = entryPresent | 2 | entryUser, entryPresent | 2
+	for _, space := range []struct{ pml4, pdpt, pd, pt, own int }{{1, 2, 3, 4, 8}, {11, 12, 13, 14, 15}} {
+		set(space.pml4, 0, uint64(space.pdpt*pageSize)|user)
+		set(space.pml4, 256, 5*pageSize|kernel)
+		set(space.pml4, 493, uint64(space.pml4*pageSize)|kernel)
+		set(space.pdpt, 0, uint64(space.pd*pageSize)|user)
+		set(space.pd, 0, uint64(space.pt*pageSize)|user)
+		set(space.pt, space.pml4/11, uint64(space.own*pageSize)|user)
+		set(space.pt, 2, 9*pageSize|user)
+	}
+	set(5, 0, 6*pageSize|kernel)
+	set(6, 0, 7*pageSize|kernel)
+	set(7, 0, 10*pageSize|kernel)
+	set(7, 1, 10*pageSize|kernel)
+	copy(image[8*pageSize:], "first process")
+	return image
+}
+
+// TestAnalyze tests attributing the pages of a memory image by its page
+// tables
+func TestAnalyze(t *testing.T) {
+	image := testMemory()
+	got, err := Analyze(bytes.NewReader(image), int64(len(image)), ProfileWindows)
+	if err != nil {
+		t.Fatalf("Analyze() error = %v", err)
+	}
+	want := []Region{
+		{Offset: 1 * pageSize, Size: 7 * pageSize, Label: LabelPageTable},
+		{Offset: 8 * pageSize, Size: pageSize, Label: "process dtb=0x1000", Address: 0, HasAddress: true},
+		{Offset: 9 * pageSize, Size: pageSize, Label: LabelShared},
+		{Offset: 10 * pageSize, Size: pageSize, Label: LabelKernel, Address: 0xffff800000000000, HasAddress: true},
+		{Offset: 11 * pageSize, Size: 4 * pageSize, Label: LabelPageTable},
+		{Offset: 15 * pageSize, Size: pageSize, Label: "process dtb=0xb000", Address: 0x1000, HasAddress: true},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Analyze() =\n%+v\nwant\n%+v", got, want)
+	}
+
+	if _, err := Analyze(bytes.NewReader(image), int64(len(image)), "macos"); err == nil {
+		t.Error("Analyze() with an unknown profile did not fail")
+	}
+}
@@ -0,0 +1,263 @@
+package memimage
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Profiles accepted by Analyze
+const (
+	ProfileWindows = "windows-x64"
+	ProfileLinux   = "linux-x64"
+)
+
+// Labels of the regions found by Analyze
+const (
+	LabelKernel    = "kernel"
+	LabelShared    = "shared user"
+	LabelPageTable = "page tables"
+)
+
+// x86-64 4-level paging: tables of 512 8-byte entries in 4 KiB pages
+const (
+	pageSize     = 4096
+	tableEntries = 512
+	entryPresent = 1 << 0
+	entryUser    = 1 << 2
+	entryLarge   = 1 << 7 // 1 GiB (PDPT) or 2 MiB (PD) page
+	entryAddress = 0x000ffffffffff000
+
+	minPresentEntries = 2
+	maxDTBs           = 256     // Address spaces walked
+	maxMappedPages    = 1 << 26 // Pages marked by all walks
+	scanBuffer        = 1 << 20
+)
+
+// Page owners, in increasing precedence: none, the kernel, page tables, user
+// pages of several address spaces, then the address spaces (ownerSpace + their
+// index)
+const (
+	ownerNone = iota
+	ownerKernel
+	ownerPageTable
+	ownerShared
+	ownerSpace
+)
+
+// Analyze finds the address spaces of the processes in the raw x86-64
+// physical memory image in r of size bytes, by the shape of their page
+// tables, walks them and returns the regions of the image they map: pages
+// mapped by a single address space as "process dtb=0x…" (the physical address
+// of its top-level table) with their virtual address, user pages mapped by
+// several as LabelShared, supervisor pages as LabelKernel, and the page
+// tables as LabelPageTable. Which top-level tables count as address spaces
+// depends on the profile: on Windows, those that map themselves; on Linux,
+// the largest set sharing the same kernel half.
+//
+// The image must be laid out by physical address (a raw or padded dump).
+// This is a coarse heuristic: pages of processes that are not found, or
+// paged out, are not attributed.
+func Analyze(r io.ReaderAt, size int64, profile string) ([]Region, error) {
+	if profile != ProfileWindows && profile != ProfileLinux {
+		return nil, fmt.Errorf("unknown memory profile %q (want %s or %s)", profile, ProfileWindows, ProfileLinux)
+	}
+	pages := size / pageSize
+	w := &walker{r: r, pages: pages, owner: make([]uint32, pages), virt: make([]uint64, pages)}
+	dtbs, err := w.findDTBs(profile)
+	if err != nil {
+		return nil, err
+	}
+	for i, dtb := range dtbs {
+		w.space = ownerSpace + uint32(i)
+		// The kernel half is the same in every address space
+		w.walk(4, dtb, 0, true, dtb, i == 0)
+	}
+
+	var regions []Region
+	for pfn, owner := range w.owner {
+		if owner == ownerNone {
+			continue
+		}
+		region := Region{Offset: int64(pfn) * pageSize, Size: pageSize}
+		switch owner {
+		case ownerPageTable:
+			region.Label = LabelPageTable
+		case ownerKernel:
+			region.Label, region.Address, region.HasAddress = LabelKernel, w.virt[pfn], true
+		case ownerShared:
+			region.Label = LabelShared
+		default:
+			region.Label = fmt.Sprintf("process dtb=0x%x", dtbs[owner-ownerSpace])
+			region.Address, region.HasAddress = w.virt[pfn], true
+		}
+		regions = appendRange(regions, region.Offset, region.Offset+region.Size, region)
+	}
+	return regions, nil
+}
+
+// walker walks page tables, marking the pages they map
+type walker struct {
+	r      io.ReaderAt
+	pages  int64
+	owner  []uint32 // By page frame number
+	virt   []uint64 // First virtual address each page was seen at
+	space  uint32   // Owner of the user pages of the address space walked
+	marked int
+	table  [pageSize]byte
+}
+
+// readTable returns the entries of the page table at physical address phys
+func (w *walker) readTable(phys uint64) ([]uint64, bool) {
+	if phys/pageSize >= uint64(w.pages) {
+		return nil, false
+	}
+	if _, err := w.r.ReadAt(w.table[:], int64(phys)); err != nil {
+		return nil, false
+	}
+	entries := make([]uint64, tableEntries)
+	for i := range entries {
+		entries[i] = binary.LittleEndian.Uint64(w.table[i*8:])
+	}
+	return entries, true
+}
+
+// looksLikeTable reports whether page holds a page table: at least
+// minPresentEntries present entries, nearly all pointing into the image
+func looksLikeTable(page []byte, pages int64) bool {
+	present, outside := 0, 0
+	for i := 0; i < pageSize; i += 8 {
+		entry := binary.LittleEndian.Uint64(page[i:])
+		if entry&entryPresent == 0 {
+			continue
+		}
+		present++
+		if int64((entry&entryAddress)/pageSize) >= pages {
+			outside++
+		}
+	}
+	return present >= minPresentEntries && outside*16 <= present
+}
+
+// findDTBs returns the physical addresses of the top-level tables of the
+// address spaces in the image
+func (w *walker) findDTBs(profile string) ([]uint64, error) {
+	var dtbs []uint64
+	groups := map[[tableEntries / 2]uint64][]uint64{} // Linux: by kernel half
+	buf := make([]byte, scanBuffer)
+	for offset := int64(0); offset < w.pages*pageSize; offset += scanBuffer {
+		n, err := w.r.ReadAt(buf, offset)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		for at := 0; at+pageSize <= n; at += pageSize {
+			page := buf[at : at+pageSize]
+			if !looksLikeTable(page, w.pages) {
+				continue
+			}
+			phys := uint64(offset) + uint64(at)
+			if profile == ProfileWindows {
+				// Windows maps the top-level table into itself, from the kernel half
+				for i := tableEntries / 2; i < tableEntries; i++ {
+					entry := binary.LittleEndian.Uint64(page[i*8:])
+					if entry&entryPresent != 0 && entry&entryUser == 0 && entry&entryAddress == phys {
+						dtbs = append(dtbs, phys)
+						break
+					}
+				}
+				continue
+			}
+			// Linux always maps its kernel image from the last entry
+			last := binary.LittleEndian.Uint64(page[pageSize-8:])
+			if last&entryPresent == 0 || last&entryLarge != 0 || last&entryUser != 0 {
+				continue
+			}
+			var kernel [tableEntries / 2]uint64
+			for i := range kernel {
+				kernel[i] = binary.LittleEndian.Uint64(page[(tableEntries/2+i)*8:])
+			}
+			groups[kernel] = append(groups[kernel], phys)
+		}
+	}
+	for _, group := range groups {
+		if len(group) >= 2 && len(group) > len(dtbs) {
+			dtbs = group
+		}
+	}
+	sort.Slice(dtbs, func(i, j int) bool { return dtbs[i] < dtbs[j] })
+	if len(dtbs) > maxDTBs {
+		dtbs = dtbs[:maxDTBs]
+	}
+	return dtbs, nil
+}
+
+// levelShift is how much of the virtual address an entry of a table of each
+// level (4 the top) maps
+var levelShift = [5]uint{0, 12, 21, 30, 39}
+
+// walk walks the page table of level at physical address phys, mapping
+// virtual addresses from base. user tells whether the entries above allowed
+// user access; the kernel half of the top-level table is walked only if
+// kernel is set.
+func (w *walker) walk(level int, phys, base uint64, user bool, dtb uint64, kernel bool) {
+	entries, ok := w.readTable(phys)
+	if !ok {
+		return
+	}
+	w.mark(phys/pageSize, 0, ownerPageTable)
+	for i, entry := range entries {
+		if entry&entryPresent == 0 || w.marked >= maxMappedPages {
+			continue
+		}
+		addr := entry & entryAddress
+		virt := base | uint64(i)<<levelShift[level]
+		if level == 4 {
+			if i >= tableEntries/2 {
+				if !kernel {
+					break
+				}
+				virt |= 0xffff000000000000
+			}
+			if addr == dtb {
+				continue // The Windows self-map
+			}
+		}
+		isUser := user && entry&entryUser != 0
+		if level > 1 && (level == 4 || entry&entryLarge == 0) {
+			w.walk(level-1, addr, virt, isUser, dtb, kernel)
+			continue
+		}
+		if level == 3 || level == 2 {
+			addr &^= 1<<levelShift[level] - 1 // The PAT bit of large pages
+		}
+		owner := uint32(ownerKernel)
+		if isUser {
+			owner = w.space
+		}
+		for n := uint64(0); n < 1<<(levelShift[level]-12) && w.marked < maxMappedPages; n++ {
+			w.mark(addr/pageSize+n, virt+n*pageSize, owner)
+		}
+	}
+}
+
+// mark records that page frame pfn, at virtual address virt, belongs to
+// owner, unless it belongs to an owner of higher precedence: user pages win
+// over kernel ones (Linux maps all memory into the kernel), and those of
+// several address spaces become shared.
+func (w *walker) mark(pfn, virt uint64, owner uint32) {
+	if pfn >= uint64(w.pages) {
+		return
+	}
+	w.marked++
+	current := w.owner[pfn]
+	switch {
+	case current == owner:
+		return
+	case owner >= ownerSpace && current >= ownerShared:
+		w.owner[pfn] = ownerShared
+	case owner > current:
+		w.owner[pfn] = owner
+		w.virt[pfn] = virt
+	}
+}
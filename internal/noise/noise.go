@@ -0,0 +1,188 @@
+// Package noise holds the known-string noise database: strings so common in
+// binaries of a platform (loader paths, section names, C runtime imports and
+// messages, Go runtime symbols) that they tell an analyst nothing. A copy is
+// embedded in txtr; "txtr db update" caches a newer one.
+package noise
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultURL is where "txtr db update" fetches the latest database from
+const DefaultURL = "https://raw.githubusercontent.com/richardwooding/txtr/main/internal/noise/common.txt"
+
+// versionPrefix introduces the version comment of a database file
+const versionPrefix = "# version:"
+
+// maxDatabaseSize limits the size of a downloaded database
+const maxDatabaseSize = 16 << 20
+
+//go:embed common.txt
+var embedded []byte
+
+// Database is a set of common strings. Entries ending in "*" match every
+// string with that prefix.
+type Database struct {
+	Version  string // Date of the database (YYYY-MM-DD), newer versions sort later
+	exact    map[string]struct{}
+	prefixes []string
+}
+
+// Parse parses a database file: one string per line, "#" comments and a
+// "# version: YYYY-MM-DD" comment identifying the release
+func Parse(data []byte) (*Database, error) {
+	db := &Database{exact: make(map[string]struct{})}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if version, ok := strings.CutPrefix(line, versionPrefix); ok && db.Version == "" {
+			db.Version = strings.TrimSpace(version)
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if prefix, ok := strings.CutSuffix(line, "*"); ok && prefix != "" {
+			db.prefixes = append(db.prefixes, prefix)
+			continue
+		}
+		db.exact[line] = struct{}{}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if db.Version == "" {
+		return nil, errors.New("not a common strings database (missing version)")
+	}
+	return db, nil
+}
+
+// Embedded returns the database built into txtr
+func Embedded() *Database {
+	db, err := Parse(embedded)
+	if err != nil {
+		panic("noise: invalid embedded database: " + err.Error())
+	}
+	return db
+}
+
+// Contains reports whether str is a common string
+func (db *Database) Contains(str []byte) bool {
+	if _, ok := db.exact[string(str)]; ok {
+		return true
+	}
+	for _, prefix := range db.prefixes {
+		if bytes.HasPrefix(str, []byte(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the number of entries (exact strings and prefixes)
+func (db *Database) Len() int {
+	return len(db.exact) + len(db.prefixes)
+}
+
+// CachePath returns where "txtr db update" stores the downloaded database
+func CachePath() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "txtr", "common.txt"), nil
+}
+
+// Load returns the newest available database: the cached copy if it is newer
+// than the embedded one, else the embedded one. The source is the cache path
+// or "embedded". An unreadable or invalid cache is an error.
+func Load() (*Database, string, error) {
+	db := Embedded()
+	path, err := CachePath()
+	if err != nil {
+		return db, "embedded", nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return db, "embedded", nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	cached, err := Parse(data)
+	if err != nil {
+		return nil, "", fmt.Errorf("%s: %w", path, err)
+	}
+	if cached.Version > db.Version {
+		return cached, path, nil
+	}
+	return db, "embedded", nil
+}
+
+// Fetch downloads a database and returns its contents and parsed form
+func Fetch(ctx context.Context, client *http.Client, url string) ([]byte, *Database, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("unexpected HTTP status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDatabaseSize+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(data) > maxDatabaseSize {
+		return nil, nil, fmt.Errorf("database exceeds %d bytes", maxDatabaseSize)
+	}
+	db, err := Parse(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, db, nil
+}
+
+// Save atomically writes database contents to path, creating its directory
+func Save(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".common-*.txt")
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,156 @@
+package noise
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const testDatabase = `# version: 2099-01-01
+# comment
+exact string
+
+GLIBC_*
+*
+`
+
+// TestParse tests parsing of exact entries, prefixes, comments and the version
+func TestParse(t *testing.T) {
+	db, err := Parse([]byte(testDatabase))
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if db.Version != "2099-01-01" {
+		t.Errorf("Version = %q, want 2099-01-01", db.Version)
+	}
+	if db.Len() != 3 {
+		t.Errorf("Len() = %d, want 3", db.Len())
+	}
+
+	tests := []struct {
+		str  string
+		want bool
+	}{
+		{"exact string", true},
+		{"exact string!", false},
+		{"GLIBC_2.34", true},
+		{"GLIBC", false},
+		{"# comment", false},
+		{"*", true}, // A lone "*" is an exact entry, not a match-all prefix
+		{"anything", false},
+	}
+	for _, tt := range tests {
+		if got := db.Contains([]byte(tt.str)); got != tt.want {
+			t.Errorf("Contains(%q) = %v, want %v", tt.str, got, tt.want)
+		}
+	}
+}
+
+// TestParseMissingVersion tests that files without a version are rejected
+func TestParseMissingVersion(t *testing.T) {
+	if _, err := Parse([]byte("just a string\n")); err == nil {
+		t.Error("Parse() accepted a database without a version")
+	}
+}
+
+// TestEmbedded tests that the embedded database parses and covers common noise
+func TestEmbedded(t *testing.T) {
+	db := Embedded()
+	if db.Version == "" || db.Len() < 100 {
+		t.Fatalf("embedded database version %q with %d entries", db.Version, db.Len())
+	}
+	for _, str := range []string{"/lib64/ld-linux-x86-64.so.2", "GLIBC_2.2.5", "GLIBCXX_3.4.21", ".rodata"} {
+		if !db.Contains([]byte(str)) {
+			t.Errorf("embedded database does not contain %q", str)
+		}
+	}
+}
+
+// TestLoad tests that a newer cached database takes precedence
+func TestLoad(t *testing.T) {
+	cache := t.TempDir()
+	t.Setenv("XDG_CACHE_HOME", cache)
+	t.Setenv("HOME", cache)
+	t.Setenv("LocalAppData", cache)
+
+	db, source, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if source != "embedded" || db.Version != Embedded().Version {
+		t.Errorf("Load() without cache = %s from %s, want embedded", db.Version, source)
+	}
+
+	path, err := CachePath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(path, []byte(testDatabase)); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+
+	db, source, err = Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if source != path || db.Version != "2099-01-01" {
+		t.Errorf("Load() with newer cache = %s from %s, want 2099-01-01 from %s", db.Version, source, path)
+	}
+
+	// An older cache is ignored
+	if err := Save(path, []byte("# version: 2000-01-01\n")); err != nil {
+		t.Fatal(err)
+	}
+	if _, source, err = Load(); err != nil || source != "embedded" {
+		t.Errorf("Load() with older cache = %s, %v; want embedded", source, err)
+	}
+
+	// An invalid cache is an error
+	if err := os.WriteFile(path, []byte("no version\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = Load(); err == nil {
+		t.Error("Load() accepted an invalid cache")
+	}
+}
+
+// TestFetch tests downloading a database
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = w.Write([]byte(testDatabase))
+	}))
+	defer server.Close()
+
+	data, db, err := Fetch(context.Background(), server.Client(), server.URL+"/common.txt")
+	if err != nil {
+		t.Fatalf("Fetch() error = %v", err)
+	}
+	if string(data) != testDatabase || db.Version != "2099-01-01" {
+		t.Errorf("Fetch() = version %q, %d bytes", db.Version, len(data))
+	}
+
+	if _, _, err := Fetch(context.Background(), server.Client(), server.URL+"/missing"); err == nil {
+		t.Error("Fetch() accepted HTTP 404")
+	}
+}
+
+// TestSave tests that Save creates the directory and replaces existing files
+func TestSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "common.txt")
+	for _, contents := range []string{"first", "second"} {
+		if err := Save(path, []byte(contents)); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+		got, err := os.ReadFile(path)
+		if err != nil || string(got) != contents {
+			t.Errorf("file contents = %q, %v; want %q", got, err, contents)
+		}
+	}
+}
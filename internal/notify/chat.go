@@ -0,0 +1,253 @@
+package notify
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Formats of the payload of a summary (see Payload)
+const (
+	FormatJSON  = "json"  // The Summary itself
+	FormatSlack = "slack" // A Slack message of mrkdwn blocks (see SlackMessage)
+	FormatTeams = "teams" // A Microsoft Teams message with an Adaptive Card (see TeamsMessage)
+)
+
+// Limits on the lines of a chat message, which is read rather than processed
+const (
+	chatErrors   = 5
+	chatFindings = 10
+	chatValueLen = 80 // Characters of an example or error shown
+)
+
+// Payload returns the summary in a format: FormatJSON, FormatSlack or
+// FormatTeams
+func Payload(s *Summary, format string) (any, error) {
+	switch format {
+	case FormatJSON:
+		return s, nil
+	case FormatSlack:
+		return SlackMessage(s), nil
+	case FormatTeams:
+		return TeamsMessage(s), nil
+	default:
+		return nil, fmt.Errorf("unknown summary format %q", format)
+	}
+}
+
+// digest is the content of a chat message: a title, facts, then sections of
+// lines and a footer, rendered for Slack or Teams
+type digest struct {
+	title    string
+	facts    [][2]string
+	sections []digestSection
+	footer   string
+}
+
+// digestSection is a titled list of lines of a digest
+type digestSection struct {
+	title string
+	lines []string
+}
+
+// newDigest returns the digest of a summary, formatting strings taken from
+// the scan (file names, values) with code and escaping other text with escape
+func newDigest(s *Summary, code, escape func(string) string) digest {
+	d := digest{
+		title: "txtr scan completed",
+		facts: [][2]string{
+			{"Files scanned", fmt.Sprint(s.Files)},
+			{"Strings", fmt.Sprint(s.Strings)},
+			{"Failed", fmt.Sprint(s.Failed)},
+			{"Warnings", fmt.Sprint(s.Warnings)},
+			{"Duration", time.Duration(s.Duration * float64(time.Second)).String()},
+		},
+		footer: "txtr " + s.Version,
+	}
+	if s.Host != "" {
+		d.footer += " on " + s.Host
+	}
+	d.footer = escape(d.footer + ", started " + s.Started.Format(time.RFC3339))
+
+	if len(s.Artifacts) > 0 {
+		section := digestSection{title: "Notable artifacts"}
+		for _, artifact := range s.Artifacts {
+			line := fmt.Sprintf("%s: %d", artifact.Kind, artifact.Count)
+			if len(artifact.Examples) > 0 {
+				examples := make([]string, len(artifact.Examples))
+				for i, example := range artifact.Examples {
+					examples[i] = code(shorten(example))
+				}
+				line += " (" + strings.Join(examples, ", ") + ")"
+			}
+			section.lines = append(section.lines, line)
+		}
+		d.sections = append(d.sections, section)
+	}
+	if s.FindingCount > 0 {
+		section := digestSection{title: fmt.Sprintf("Findings (%d)", s.FindingCount)}
+		for _, finding := range s.Findings[:min(len(s.Findings), chatFindings)] {
+			section.lines = append(section.lines, fmt.Sprintf("%s %s in %s at 0x%x", finding.Kind, code(finding.Value), code(finding.File), finding.Offset))
+		}
+		if more := s.FindingCount - min(len(s.Findings), chatFindings); more > 0 {
+			section.lines = append(section.lines, fmt.Sprintf("and %d more", more))
+		}
+		d.sections = append(d.sections, section)
+	}
+	if s.Failed > 0 {
+		section := digestSection{title: fmt.Sprintf("Failures (%d)", s.Failed)}
+		for _, e := range s.Errors[:min(len(s.Errors), chatErrors)] {
+			section.lines = append(section.lines, code(e.File)+": "+escape(shorten(e.Error)))
+		}
+		if more := s.Failed - min(len(s.Errors), chatErrors); more > 0 {
+			section.lines = append(section.lines, fmt.Sprintf("and %d more", more))
+		}
+		d.sections = append(d.sections, section)
+	}
+	return d
+}
+
+// shorten cuts a value to chatValueLen characters, on one line
+func shorten(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	if runes := []rune(value); len(runes) > chatValueLen {
+		return string(runes[:chatValueLen-1]) + "…"
+	}
+	return value
+}
+
+// Slack is a Slack message for an incoming webhook or chat.postMessage: a
+// header, the counts as fields, sections of mrkdwn and a context footer
+type Slack struct {
+	Text   string       `json:"text"` // Fallback shown in notifications
+	Blocks []slackBlock `json:"blocks"`
+}
+
+// slackBlock is a block of Slack's Block Kit
+type slackBlock struct {
+	Type     string      `json:"type"`
+	Text     *slackText  `json:"text,omitempty"`
+	Fields   []slackText `json:"fields,omitempty"`
+	Elements []slackText `json:"elements,omitempty"`
+}
+
+// slackText is a text object of Slack's Block Kit
+type slackText struct {
+	Type string `json:"type"` // "plain_text" or "mrkdwn"
+	Text string `json:"text"`
+}
+
+// SlackMessage renders a summary as a compact Slack message
+func SlackMessage(s *Summary) Slack {
+	d := newDigest(s, func(value string) string {
+		return "`" + slackEscape(strings.ReplaceAll(value, "`", "'")) + "`"
+	}, slackEscape)
+
+	message := Slack{
+		Text: fmt.Sprintf("%s: %d files, %d strings, %d failed", d.title, s.Files, s.Strings, s.Failed),
+		Blocks: []slackBlock{
+			{Type: "header", Text: &slackText{Type: "plain_text", Text: d.title}},
+		},
+	}
+	fields := slackBlock{Type: "section"}
+	for _, fact := range d.facts {
+		fields.Fields = append(fields.Fields, slackText{Type: "mrkdwn", Text: "*" + fact[0] + "*\n" + fact[1]})
+	}
+	message.Blocks = append(message.Blocks, fields)
+	for _, section := range d.sections {
+		text := "*" + section.title + "*"
+		for _, line := range section.lines {
+			text += "\n• " + line
+		}
+		message.Blocks = append(message.Blocks, slackBlock{Type: "section", Text: &slackText{Type: "mrkdwn", Text: text}})
+	}
+	message.Blocks = append(message.Blocks, slackBlock{Type: "context", Elements: []slackText{{Type: "mrkdwn", Text: d.footer}}})
+	return message
+}
+
+// slackEscape escapes the characters mrkdwn reserves for links and mentions
+func slackEscape(text string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(text)
+}
+
+// teamsEscape escapes the characters Adaptive Card markdown reserves for
+// links, emphasis and code, so a string such as [click me](https://...) found
+// in a sample is shown as it is instead of as a link
+func teamsEscape(text string) string {
+	return strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`, "(", `\(`, ")", `\)`,
+		"*", `\*`, "_", `\_`, "`", "\\`").Replace(text)
+}
+
+// Teams is a Microsoft Teams message for an incoming webhook (or a Workflows
+// "post to a channel when a webhook request is received" flow), holding an
+// Adaptive Card
+type Teams struct {
+	Type        string            `json:"type"` // Always "message"
+	Attachments []teamsAttachment `json:"attachments"`
+}
+
+// teamsAttachment is the card attachment of a Teams message
+type teamsAttachment struct {
+	ContentType string       `json:"contentType"`
+	Content     adaptiveCard `json:"content"`
+}
+
+// adaptiveCard is an Adaptive Card
+type adaptiveCard struct {
+	Schema  string        `json:"$schema"`
+	Type    string        `json:"type"` // Always "AdaptiveCard"
+	Version string        `json:"version"`
+	Body    []cardElement `json:"body"`
+}
+
+// cardElement is a TextBlock or FactSet of an Adaptive Card
+type cardElement struct {
+	Type     string     `json:"type"`
+	Text     string     `json:"text,omitempty"`
+	Size     string     `json:"size,omitempty"`
+	Weight   string     `json:"weight,omitempty"`
+	Wrap     bool       `json:"wrap,omitempty"`
+	IsSubtle bool       `json:"isSubtle,omitempty"`
+	Facts    []cardFact `json:"facts,omitempty"`
+}
+
+// cardFact is a fact of a FactSet
+type cardFact struct {
+	Title string `json:"title"`
+	Value string `json:"value"`
+}
+
+// TeamsMessage renders a summary as a compact Teams message
+func TeamsMessage(s *Summary) Teams {
+	d := newDigest(s, func(value string) string {
+		return `"` + teamsEscape(value) + `"`
+	}, teamsEscape)
+
+	body := []cardElement{{Type: "TextBlock", Text: d.title, Size: "Medium", Weight: "Bolder", Wrap: true}}
+	facts := cardElement{Type: "FactSet"}
+	for _, fact := range d.facts {
+		facts.Facts = append(facts.Facts, cardFact{Title: fact[0], Value: fact[1]})
+	}
+	body = append(body, facts)
+	for _, section := range d.sections {
+		text := "**" + section.title + "**\n"
+		for _, line := range section.lines {
+			text += "\n- " + line
+		}
+		body = append(body, cardElement{Type: "TextBlock", Text: text, Wrap: true})
+	}
+	body = append(body, cardElement{Type: "TextBlock", Text: d.footer, Wrap: true, IsSubtle: true, Size: "Small"})
+
+	return Teams{
+		Type: "message",
+		Attachments: []teamsAttachment{{
+			ContentType: "application/vnd.microsoft.card.adaptive",
+			Content: adaptiveCard{
+				Schema:  "http://adaptivecards.io/schemas/adaptive-card.json",
+				Type:    "AdaptiveCard",
+				Version: "1.4",
+				Body:    body,
+			},
+		}},
+	}
+}
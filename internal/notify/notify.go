@@ -0,0 +1,164 @@
+// Package notify posts a summary of a completed scan to a webhook
+// (--notify-webhook), so txtr can slot into alerting workflows: the counts of
+// files, strings, errors and warnings, the notable artifacts found, and
+// optionally the high-severity findings of the scan, such as secrets. The
+// summary is posted as JSON or as a Slack or Microsoft Teams message (see
+// Payload).
+package notify
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Limits on the lists of a summary, so a huge scan posts a bounded payload.
+// The counts always cover the whole scan.
+const (
+	MaxErrors   = 100
+	MaxFindings = 100
+	MaxExamples = 3 // Examples kept per kind of artifact
+)
+
+// maxResponseSize limits how much of a response body is kept for an error
+const maxResponseSize = 512
+
+// Summary is the payload posted when a scan completes
+type Summary struct {
+	Event    string      `json:"event"` // Always "scan_completed"
+	Version  string      `json:"version"`
+	Host     string      `json:"host,omitempty"`
+	Started  time.Time   `json:"started"`
+	Duration float64     `json:"duration_seconds"`
+	Files    int         `json:"files"`  // Files scanned, including archive members and embedded files
+	Failed   int         `json:"failed"` // Files that could not be scanned
+	Strings  int64       `json:"strings"`
+	Warnings int         `json:"warnings"`
+	Errors   []FileError `json:"errors,omitempty"` // The first MaxErrors failures
+	// Artifacts counts the strings of each kind of artifact, most frequent first
+	Artifacts []Artifact `json:"artifacts,omitempty"`
+	// Findings is the first MaxFindings findings, of FindingCount
+	Findings     []Finding `json:"findings,omitempty"`
+	FindingCount int       `json:"finding_count,omitempty"`
+}
+
+// FileError is a file that could not be scanned
+type FileError struct {
+	File  string `json:"file"`
+	Error string `json:"error"`
+}
+
+// Artifact counts the strings holding a kind of artifact (see rank.Categories)
+type Artifact struct {
+	Kind     string   `json:"kind"` // e.g. "url" or "ip address"
+	Count    int      `json:"count"`
+	Examples []string `json:"examples,omitempty"` // The first MaxExamples distinct values
+}
+
+// Finding is a high-severity string found by the scan. Its value is masked
+// (see Mask), so the payload does not spread the secret it reports.
+type Finding struct {
+	File   string `json:"file"`
+	Offset int64  `json:"offset"`
+	Kind   string `json:"kind"` // What the string holds, e.g. "secret"
+	Value  string `json:"value"`
+}
+
+// NewSummary returns an empty summary of a scan started at started
+func NewSummary(version string, started time.Time) *Summary {
+	host, _ := os.Hostname()
+	return &Summary{Event: "scan_completed", Version: version, Host: host, Started: started.UTC()}
+}
+
+// AddError records a file that could not be scanned
+func (s *Summary) AddError(file string, err error) {
+	s.Failed++
+	if len(s.Errors) < MaxErrors {
+		s.Errors = append(s.Errors, FileError{File: file, Error: err.Error()})
+	}
+}
+
+// AddArtifact counts a string holding a kind of artifact. An empty example
+// (e.g. of a secret) is counted but not kept.
+func (s *Summary) AddArtifact(kind, example string) {
+	i := slices.IndexFunc(s.Artifacts, func(a Artifact) bool { return a.Kind == kind })
+	if i < 0 {
+		i = len(s.Artifacts)
+		s.Artifacts = append(s.Artifacts, Artifact{Kind: kind})
+	}
+	artifact := &s.Artifacts[i]
+	artifact.Count++
+	if example != "" && len(artifact.Examples) < MaxExamples && !slices.Contains(artifact.Examples, example) {
+		artifact.Examples = append(artifact.Examples, example)
+	}
+}
+
+// AddFinding records a finding, masking its value
+func (s *Summary) AddFinding(file string, offset int64, kind, value string) {
+	s.FindingCount++
+	if len(s.Findings) < MaxFindings {
+		s.Findings = append(s.Findings, Finding{File: file, Offset: offset, Kind: kind, Value: Mask(value)})
+	}
+}
+
+// Finish sets the duration of the scan, which ended at end, and sorts the
+// artifacts
+func (s *Summary) Finish(end time.Time) {
+	s.Duration = end.Sub(s.Started).Round(time.Millisecond).Seconds()
+	slices.SortStableFunc(s.Artifacts, func(a, b Artifact) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+}
+
+// Mask hides all but the first 4 characters of value, keeping enough to tell
+// findings apart without disclosing them. Values of 8 characters or less are
+// hidden entirely.
+func Mask(value string) string {
+	runes := []rune(value)
+	if len(runes) <= 8 {
+		return strings.Repeat("*", len(runes))
+	}
+	return string(runes[:4]) + strings.Repeat("*", min(len(runes)-4, 16))
+}
+
+// Post sends payload as JSON to url and fails unless the response status is
+// 2xx
+func Post(ctx context.Context, client *http.Client, url string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+		if text := strings.TrimSpace(string(body)); text != "" {
+			return fmt.Errorf("unexpected HTTP status %s: %s", resp.Status, text)
+		}
+		return fmt.Errorf("unexpected HTTP status %s", resp.Status)
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return nil
+}
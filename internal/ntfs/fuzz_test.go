@@ -0,0 +1,53 @@
+package ntfs
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// FuzzExtents tests reading the MFT of NTFS images with random inputs
+func FuzzExtents(f *testing.F) {
+	// Seed corpus: the image of the Extents tests, a truncated copy and bare
+	// boot sectors
+	image := testImage([]byte(strings.Repeat("cluster data ", 40)))
+	f.Add(image)
+	f.Add(image[:testMFT*testCluster+4*testRecord])
+	f.Add(image[:512])
+	// A record size of 2^128 bytes
+	boot := append([]byte(nil), image[:512]...)
+	boot[64] = 0x80
+	f.Add(boot)
+	f.Add([]byte("\xeb\x52\x90NTFS    "))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		extents, err := Extents(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			// Errors are expected for invalid input
+			return
+		}
+
+		// Invariant: extents are in offset order and inside the image
+		for i, extent := range extents {
+			if i > 0 && extent.Offset < extents[i-1].Offset {
+				t.Errorf("extent %d at 0x%x is before the previous one", i, extent.Offset)
+			}
+			if extent.Offset < 0 || extent.Size < 0 || extent.Offset+extent.Size > int64(len(data)) {
+				t.Errorf("extent %d (0x%x, %d bytes) is outside the %d byte image", i, extent.Offset, extent.Size, len(data))
+			}
+		}
+	})
+}
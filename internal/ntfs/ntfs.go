@@ -0,0 +1,346 @@
+// Package ntfs reads the master file table (MFT) of NTFS partition images to
+// find where the names and data of files are in the image, so strings found
+// by scanning the raw image can be attributed to files.
+package ntfs
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"unicode/utf16"
+)
+
+// Attribute types and record numbers used
+const (
+	attrFileName = 0x30
+	attrData     = 0x80
+	attrEnd      = 0xffffffff
+	recordMFT    = 0
+	recordRoot   = 5
+	maxRecords   = 1 << 24 // Records read from the MFT
+	maxPathDepth = 256
+	fixupStride  = 512
+)
+
+// Attribute names reported in Extent.Attribute
+const (
+	AttributeFileName = "$FILE_NAME"
+	AttributeData     = "$DATA" // Followed by ":name" for alternate data streams
+)
+
+// Extent is where the name or data of a file is in an NTFS image
+type Extent struct {
+	Offset    int64
+	Size      int64
+	Record    uint64 // MFT record number of the file
+	Path      string // e.g. \Users\alice\notes.txt; names whose parent is unknown start with ?\
+	Attribute string // AttributeFileName, AttributeData or AttributeData + ":" + stream name
+	Deleted   bool   // The MFT record is not in use
+}
+
+// ErrNotNTFS is returned for images that do not start with an NTFS boot sector
+var ErrNotNTFS = errors.New("not an NTFS partition")
+
+var errCorrupt = errors.New("corrupt NTFS partition")
+
+// IsNTFS reports whether boot, the start of an image, is an NTFS boot sector
+func IsNTFS(boot []byte) bool {
+	return len(boot) >= 11 && string(boot[3:11]) == "NTFS    "
+}
+
+// volume is an NTFS partition image being read
+type volume struct {
+	r           io.ReaderAt
+	size        int64
+	clusterSize int64
+	recordSize  int64
+}
+
+// file is what the MFT records of a file say about it
+type file struct {
+	parent  uint64
+	name    string
+	deleted bool
+	extents []Extent // Path not yet set
+}
+
+// Extents reads the MFT of the NTFS image in r and returns where the names,
+// resident data and non-resident data of its files are, sorted by offset.
+// Deleted files are included with their names and resident data, which are
+// in their MFT records, but not their clusters, which may have been reused.
+// Data in the last cluster of a file beyond its size (slack) is not included.
+func Extents(r io.ReaderAt, size int64) ([]Extent, error) {
+	boot := make([]byte, 512)
+	if _, err := r.ReadAt(boot, 0); err != nil {
+		return nil, ErrNotNTFS
+	}
+	if !IsNTFS(boot) {
+		return nil, ErrNotNTFS
+	}
+
+	le := binary.LittleEndian
+	v := &volume{r: r, size: size}
+	sectorSize := int64(le.Uint16(boot[11:]))
+	v.clusterSize = sectorSize * int64(boot[13])
+	if perRecord := int8(boot[64]); perRecord < 0 {
+		v.recordSize = 1 << -int(perRecord)
+	} else {
+		v.recordSize = int64(perRecord) * v.clusterSize
+	}
+	if v.clusterSize == 0 || v.recordSize < 256 || v.recordSize > 64<<10 || v.recordSize%fixupStride != 0 {
+		return nil, fmt.Errorf("%w: cluster size %d, record size %d", errCorrupt, v.clusterSize, v.recordSize)
+	}
+
+	// Record 0 is the MFT itself, whose data says where the rest of it is
+	mftStart := int64(le.Uint64(boot[48:])) * v.clusterSize
+	first := make([]byte, v.recordSize)
+	if _, err := r.ReadAt(first, mftStart); err != nil || !fixup(first) {
+		return nil, fmt.Errorf("%w: cannot read the MFT at 0x%x", errCorrupt, mftStart)
+	}
+	var runs []run
+	attributes(first, func(attr []byte, _ int) {
+		if le.Uint32(attr) == attrData && attr[8] != 0 && attr[9] == 0 && len(runs) == 0 {
+			runs = v.runs(attr)
+		}
+	})
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("%w: MFT has no data runs", errCorrupt)
+	}
+
+	fs := make(files)
+	number := uint64(0)
+	for _, run := range runs {
+		for pos := run.offset; pos+v.recordSize <= run.offset+run.size && number < maxRecords; pos += v.recordSize {
+			record := make([]byte, v.recordSize)
+			if _, err := r.ReadAt(record, pos); err != nil {
+				break
+			}
+			v.record(record, pos, number, fs)
+			number++
+		}
+	}
+
+	var extents []Extent
+	for number, f := range fs {
+		path := fs.path(number)
+		for _, extent := range f.extents {
+			extent.Path = path
+			extent.Deleted = f.deleted
+			extents = append(extents, extent)
+		}
+	}
+	sort.Slice(extents, func(i, j int) bool { return extents[i].Offset < extents[j].Offset })
+	return extents, nil
+}
+
+// fixup applies the update sequence array of an MFT record, which replaces the
+// last two bytes of each 512-byte stride with a sequence number to detect torn
+// writes. It reports false for records that are not valid "FILE" records.
+func fixup(record []byte) bool {
+	if string(record[:4]) != "FILE" {
+		return false
+	}
+	le := binary.LittleEndian
+	offset, count := int(le.Uint16(record[4:])), int(le.Uint16(record[6:]))
+	if count < 1 || offset+count*2 > len(record) || (count-1)*fixupStride > len(record) {
+		return false
+	}
+	sequence := record[offset : offset+2]
+	for i := 1; i < count; i++ {
+		end := i * fixupStride
+		if record[end-2] != sequence[0] || record[end-1] != sequence[1] {
+			return false
+		}
+		copy(record[end-2:end], record[offset+i*2:offset+i*2+2])
+	}
+	return true
+}
+
+// attributes calls fn with each attribute of an MFT record and its offset in
+// the record
+func attributes(record []byte, fn func(attr []byte, offset int)) {
+	le := binary.LittleEndian
+	pos := int(le.Uint16(record[20:]))
+	used := min(int(le.Uint32(record[24:])), len(record))
+	for pos+16 <= used {
+		attrType := le.Uint32(record[pos:])
+		length := int(le.Uint32(record[pos+4:]))
+		if attrType == attrEnd || length < 16 || pos+length > used {
+			return
+		}
+		fn(record[pos:pos+length], pos)
+		pos += length
+	}
+}
+
+// record adds the names and data of the MFT record at image offset pos to
+// files. Extension records add to their base record.
+func (v *volume) record(record []byte, pos int64, number uint64, fs files) {
+	if !fixup(record) {
+		return
+	}
+	le := binary.LittleEndian
+	owner := number
+	if base := le.Uint64(record[32:]) & (1<<48 - 1); base != 0 {
+		owner = base
+	}
+	f := fs[owner]
+	if f == nil {
+		f = &file{parent: recordRoot}
+		fs[owner] = f
+	}
+	if owner == number {
+		f.deleted = le.Uint16(record[22:])&0x0001 == 0
+	}
+
+	attributes(record, func(attr []byte, offset int) {
+		nonResident := attr[8] != 0
+		switch le.Uint32(attr) {
+		case attrFileName:
+			value, valueOffset, ok := resident(attr)
+			if nonResident || !ok || len(value) < 66 || 66+int(value[64])*2 > len(value) {
+				return
+			}
+			nameLen := int(value[64]) * 2
+			name := decodeUTF16(value[66 : 66+nameLen])
+			// Prefer the Win32 name over the 8.3 DOS name (namespace 2)
+			if f.name == "" || value[65] != 2 {
+				f.name = name
+				f.parent = le.Uint64(value) & (1<<48 - 1)
+			}
+			f.extents = append(f.extents, Extent{Offset: pos + int64(offset+valueOffset+66), Size: int64(nameLen), Record: owner, Attribute: AttributeFileName})
+
+		case attrData:
+			attribute := AttributeData
+			if nameLen := int(attr[9]); nameLen > 0 {
+				nameOffset := int(le.Uint16(attr[10:]))
+				if nameOffset+nameLen*2 <= len(attr) {
+					attribute += ":" + decodeUTF16(attr[nameOffset:nameOffset+nameLen*2])
+				}
+			}
+			if !nonResident {
+				if value, valueOffset, ok := resident(attr); ok && len(value) > 0 {
+					f.extents = append(f.extents, Extent{Offset: pos + int64(offset+valueOffset), Size: int64(len(value)), Record: owner, Attribute: attribute})
+				}
+				return
+			}
+			// The records of the MFT are attributed by their own names and data
+			if owner == number && f.deleted || owner == recordMFT {
+				return
+			}
+			for _, run := range v.runs(attr) {
+				f.extents = append(f.extents, Extent{Offset: run.offset, Size: run.size, Record: owner, Attribute: attribute})
+			}
+		}
+	})
+}
+
+// resident returns the value of a resident attribute and its offset in the
+// attribute
+func resident(attr []byte) ([]byte, int, bool) {
+	if len(attr) < 24 {
+		return nil, 0, false
+	}
+	le := binary.LittleEndian
+	length, offset := int(le.Uint32(attr[16:])), int(le.Uint16(attr[20:]))
+	if offset+length > len(attr) {
+		return nil, 0, false
+	}
+	return attr[offset : offset+length], offset, true
+}
+
+// run is a range of clusters of a non-resident attribute, in bytes of the
+// image
+type run struct {
+	offset int64
+	size   int64
+}
+
+// runs decodes the runlist of a non-resident attribute into the byte ranges
+// of the image holding its data, up to its size if the attribute starts the
+// data (its first VCN is 0). Sparse runs and runs outside the image are left
+// out.
+func (v *volume) runs(attr []byte) []run {
+	if len(attr) < 64 {
+		return nil
+	}
+	le := binary.LittleEndian
+	remaining := int64(-1)
+	if le.Uint64(attr[16:]) == 0 {
+		remaining = int64(le.Uint64(attr[48:]))
+	}
+
+	var runs []run
+	lcn := int64(0)
+	for pos := int(le.Uint16(attr[32:])); pos < len(attr) && attr[pos] != 0 && remaining != 0; {
+		lengthBytes, offsetBytes := int(attr[pos]&0x0f), int(attr[pos]>>4)
+		if lengthBytes == 0 || lengthBytes > 8 || offsetBytes > 8 || pos+1+lengthBytes+offsetBytes > len(attr) {
+			break
+		}
+		clusters := readUint(attr[pos+1 : pos+1+lengthBytes])
+		size := int64(math.MaxInt64) // Sparse runs can be longer than the image
+		if clusters <= math.MaxInt64/uint64(v.clusterSize) {
+			size = int64(clusters) * v.clusterSize
+		}
+		if remaining >= 0 {
+			size = min(size, remaining)
+			remaining -= size
+		}
+		if offsetBytes > 0 {
+			lcn += readInt(attr[pos+1+lengthBytes : pos+1+lengthBytes+offsetBytes])
+			if lcn >= 0 && lcn <= v.size/v.clusterSize && clusters > 0 && size <= v.size-lcn*v.clusterSize {
+				runs = append(runs, run{offset: lcn * v.clusterSize, size: size})
+			}
+		}
+		pos += 1 + lengthBytes + offsetBytes
+	}
+	return runs
+}
+
+// readUint decodes a little-endian unsigned integer of up to 8 bytes
+func readUint(b []byte) uint64 {
+	var n uint64
+	for i := len(b) - 1; i >= 0; i-- {
+		n = n<<8 | uint64(b[i])
+	}
+	return n
+}
+
+// readInt decodes a little-endian signed integer of up to 8 bytes
+func readInt(b []byte) int64 {
+	shift := 64 - 8*uint(len(b))
+	return int64(readUint(b)<<shift) >> shift
+}
+
+// decodeUTF16 decodes UTF-16LE text
+func decodeUTF16(b []byte) string {
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(b[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// files are the files of an MFT by record number
+type files map[uint64]*file
+
+// path returns the path of a file from the root directory, following the
+// parents recorded in its $FILE_NAME attribute
+func (fs files) path(number uint64) string {
+	path := ""
+	for depth := 0; number != recordRoot; depth++ {
+		f := fs[number]
+		if f == nil || f.name == "" || depth == maxPathDepth {
+			return `?` + path
+		}
+		path = `\` + f.name + path
+		number = f.parent
+	}
+	if path == "" {
+		return `\`
+	}
+	return path
+}
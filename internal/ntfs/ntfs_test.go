@@ -0,0 +1,196 @@
+package ntfs
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+const (
+	testCluster = 4096
+	testRecord  = 1024
+	testMFT     = 4 // Cluster of the MFT
+)
+
+// utf16le encodes s as UTF-16LE
+func utf16le(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// testAttribute encodes an attribute of attrType from its header fields after
+// the common ones and its name and body, padded to 8 bytes
+func testAttribute(attrType uint32, nonResident bool, name string, header, body []byte) []byte {
+	le := binary.LittleEndian
+	attr := make([]byte, 16, 64)
+	le.PutUint32(attr, attrType)
+	if nonResident {
+		attr[8] = 1
+	}
+	attr = append(attr, header...)
+	if name != "" {
+		attr[9] = byte(len([]rune(name)))
+		le.PutUint16(attr[10:], uint16(len(attr)))
+		attr = append(attr, utf16le(name)...)
+	}
+	attr = append(attr, make([]byte, -len(attr)&7)...)
+	return attr
+}
+
+// residentAttribute encodes a resident attribute holding value
+func residentAttribute(attrType uint32, name string, value []byte) []byte {
+	le := binary.LittleEndian
+	header := make([]byte, 8)
+	le.PutUint32(header, uint32(len(value)))
+	attr := testAttribute(attrType, false, name, header, nil)
+	le.PutUint16(attr[20:], uint16(len(attr)))
+	attr = append(attr, value...)
+	attr = append(attr, make([]byte, -len(attr)&7)...)
+	le.PutUint32(attr[4:], uint32(len(attr)))
+	return attr
+}
+
+// fileNameAttribute encodes a $FILE_NAME attribute
+func fileNameAttribute(parent uint64, name string, namespace byte) []byte {
+	value := make([]byte, 66)
+	binary.LittleEndian.PutUint64(value, parent|1<<48) // Sequence number 1
+	value[64] = byte(len([]rune(name)))
+	value[65] = namespace
+	return residentAttribute(attrFileName, "", append(value, utf16le(name)...))
+}
+
+// dataRuns encodes a non-resident $DATA attribute of size bytes in one run
+// of clusters starting at lcn
+func dataRuns(lcn, clusters byte, size uint64) []byte {
+	le := binary.LittleEndian
+	header := make([]byte, 48)
+	le.PutUint64(header[8:], uint64(clusters-1)) // Last VCN
+	le.PutUint16(header[16:], 64)                // Runlist offset
+	le.PutUint64(header[24:], uint64(clusters)*testCluster)
+	le.PutUint64(header[32:], size)
+	le.PutUint64(header[40:], size)
+	attr := testAttribute(attrData, true, "", header, nil)
+	attr = append(attr, 0x11, clusters, lcn, 0, 0, 0, 0, 0)
+	le.PutUint32(attr[4:], uint32(len(attr)))
+	return attr
+}
+
+// mftRecord encodes an MFT record with its update sequence array applied
+func mftRecord(number uint32, inUse bool, base uint64, attrs ...[]byte) []byte {
+	le := binary.LittleEndian
+	record := make([]byte, testRecord)
+	copy(record, "FILE")
+	le.PutUint16(record[4:], 48) // Update sequence array
+	le.PutUint16(record[6:], 3)
+	le.PutUint16(record[20:], 56) // First attribute
+	if inUse {
+		le.PutUint16(record[22:], 1)
+	}
+	le.PutUint32(record[28:], testRecord)
+	le.PutUint64(record[32:], base)
+	le.PutUint32(record[44:], number)
+	pos := 56
+	for _, attr := range attrs {
+		pos += copy(record[pos:], attr)
+	}
+	le.PutUint32(record[pos:], attrEnd)
+	le.PutUint32(record[24:], uint32(pos+8))
+
+	le.PutUint16(record[48:], 7) // Sequence number
+	for i := 1; i <= 2; i++ {
+		end := i * fixupStride
+		copy(record[48+i*2:], record[end-2:end])
+		le.PutUint16(record[end-2:], 7)
+	}
+	return record
+}
+
+// testImage builds an NTFS image of \docs\notes.txt (resident data, an
+// alternate data stream and a DOS name in an extension record), \big.bin
+// (data in clusters) and the deleted \old.txt
+func testImage(big []byte) []byte {
+	image := make([]byte, 24*testCluster)
+	le := binary.LittleEndian
+	copy(image[3:], "NTFS    ")
+	le.PutUint16(image[11:], 512)
+	image[13] = testCluster / 512
+	le.PutUint64(image[48:], testMFT)
+	image[64] = 0xf6 // Records of 2^10 bytes
+
+	records := map[uint32][]byte{
+		0:  mftRecord(0, true, 0, fileNameAttribute(5, "$MFT", 3), dataRuns(testMFT, 8, 32*testRecord)),
+		5:  mftRecord(5, true, 0, fileNameAttribute(5, ".", 3)),
+		16: mftRecord(16, true, 0, fileNameAttribute(5, "docs", 1)),
+		17: mftRecord(17, true, 0, fileNameAttribute(16, "notes.txt", 1), residentAttribute(attrData, "", []byte("password=hunter2")),
+			residentAttribute(attrData, "Zone.Identifier", []byte("[ZoneTransfer]\r\nZoneId=3"))),
+		18: mftRecord(18, true, 0, fileNameAttribute(5, "big.bin", 3), dataRuns(20, 2, uint64(len(big)))),
+		19: mftRecord(19, false, 0, fileNameAttribute(5, "old.txt", 3), residentAttribute(attrData, "", []byte("deleted secret")), dataRuns(22, 1, 100)),
+		20: mftRecord(20, true, 17, fileNameAttribute(16, "NOTES~1.TXT", 2)),
+	}
+	for number, record := range records {
+		copy(image[testMFT*testCluster+int(number)*testRecord:], record)
+	}
+	copy(image[20*testCluster:], big)
+	return image
+}
+
+// TestExtents tests finding the names and data of files, with their paths and
+// record numbers
+func TestExtents(t *testing.T) {
+	big := []byte(strings.Repeat("cluster data ", 400))
+	image := testImage(big)
+	extents, err := Extents(bytes.NewReader(image), int64(len(image)))
+	if err != nil {
+		t.Fatalf("Extents() error = %v", err)
+	}
+
+	type found struct {
+		path, attribute string
+		record          uint64
+		deleted         bool
+		data            string
+	}
+	var got []found
+	for i, extent := range extents {
+		if i > 0 && extent.Offset < extents[i-1].Offset {
+			t.Errorf("extent %d at 0x%x is before the previous one", i, extent.Offset)
+		}
+		data := string(image[extent.Offset : extent.Offset+extent.Size])
+		if extent.Attribute == AttributeFileName {
+			data = decodeUTF16([]byte(data))
+		}
+		got = append(got, found{extent.Path, extent.Attribute, extent.Record, extent.Deleted, data})
+	}
+
+	want := []found{
+		{`\$MFT`, AttributeFileName, 0, false, "$MFT"},
+		{`\`, AttributeFileName, 5, false, "."},
+		{`\docs`, AttributeFileName, 16, false, "docs"},
+		{`\docs\notes.txt`, AttributeFileName, 17, false, "notes.txt"},
+		{`\docs\notes.txt`, AttributeData, 17, false, "password=hunter2"},
+		{`\docs\notes.txt`, AttributeData + ":Zone.Identifier", 17, false, "[ZoneTransfer]\r\nZoneId=3"},
+		{`\big.bin`, AttributeFileName, 18, false, "big.bin"},
+		{`\old.txt`, AttributeFileName, 19, true, "old.txt"},
+		{`\old.txt`, AttributeData, 19, true, "deleted secret"},
+		{`\docs\notes.txt`, AttributeFileName, 17, false, "NOTES~1.TXT"},
+		{`\big.bin`, AttributeData, 18, false, string(big)},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Extents() = %+v, want %d extents", got, len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("extent %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+
+	if _, err := Extents(bytes.NewReader(make([]byte, 1024)), 1024); !errors.Is(err, ErrNotNTFS) {
+		t.Errorf("Extents(not NTFS) error = %v, want %v", err, ErrNotNTFS)
+	}
+}
@@ -0,0 +1,70 @@
+package partition
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// FuzzRead tests reading partition tables with random inputs
+func FuzzRead(f *testing.F) {
+	// Seed corpus: an MBR with an extended partition, a protective MBR with
+	// a GPT and a bare boot signature
+	mbr := make([]byte, 8*sectorSize)
+	mbrEntry(mbr, 0, 0, 0x07, 6, 2)
+	mbrEntry(mbr, 0, 1, 0x0f, 2, 4)
+	mbrEntry(mbr, 2, 0, 0x83, 1, 1)
+	mbrEntry(mbr, 2, 1, 0x05, 2, 2)
+	mbrEntry(mbr, 4, 0, 0x82, 1, 1)
+	f.Add(mbr)
+	f.Add(mbr[:3*sectorSize]) // Truncated before the first logical partition
+
+	gpt := make([]byte, 8*sectorSize)
+	mbrEntry(gpt, 0, 0, mbrTypeGPT, 1, 7)
+	copy(gpt[sectorSize:], "EFI PART")
+	binary.LittleEndian.PutUint64(gpt[sectorSize+72:], 2)
+	binary.LittleEndian.PutUint32(gpt[sectorSize+80:], 4)
+	binary.LittleEndian.PutUint32(gpt[sectorSize+84:], 128)
+	entry := gpt[2*sectorSize:]
+	entry[0], entry[16] = 0xa2, 1
+	binary.LittleEndian.PutUint64(entry[32:], 6)
+	binary.LittleEndian.PutUint64(entry[40:], 7)
+	f.Add(gpt)
+	// A GPT entry whose first LBA is negative as a signed number
+	negative := bytes.Clone(gpt)
+	binary.LittleEndian.PutUint64(negative[2*sectorSize+32:], ^uint64(0))
+	f.Add(negative)
+
+	boot := make([]byte, sectorSize)
+	boot[510], boot[511] = 0x55, 0xaa
+	f.Add(boot)
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		partitions, err := Read(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			// Errors are expected for invalid input
+			return
+		}
+
+		// Invariant: partitions start inside the image and are not empty
+		// (they may end past it, in truncated images)
+		for _, p := range partitions {
+			if p.Offset < 0 || p.Offset >= int64(len(data)) || p.Size <= 0 {
+				t.Errorf("%s at 0x%x of %d bytes is outside the %d byte image", p.Description(), p.Offset, p.Size, len(data))
+			}
+		}
+	})
+}
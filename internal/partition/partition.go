@@ -0,0 +1,210 @@
+// Package partition reads the partition tables of disk images, MBR
+// (including logical partitions in extended partitions) and GPT, to tell
+// which partition an offset of the disk is in.
+package partition
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"unicode/utf16"
+)
+
+// Schemes reported in Partition.Scheme
+const (
+	SchemeMBR = "mbr"
+	SchemeGPT = "gpt"
+)
+
+// Partition is a partition of a disk image
+type Partition struct {
+	Number int    // 1-4 for MBR primary partitions, 5 on for logical ones, 1 on for GPT
+	Scheme string // SchemeMBR or SchemeGPT
+	Offset int64
+	Size   int64
+	Type   string // MBR type byte (0x07) or GPT type GUID
+	Name   string // GPT partition name
+}
+
+// Description returns a readable description of the partition, e.g.
+// GPT partition 2: Microsoft basic data "Basic data partition"
+func (p Partition) Description() string {
+	kind := p.Type
+	if name, ok := typeNames[p.Type]; ok {
+		kind = name + " (" + p.Type + ")"
+	}
+	desc := fmt.Sprintf("%s partition %d: %s", strings.ToUpper(p.Scheme), p.Number, kind)
+	if p.Name != "" {
+		desc += fmt.Sprintf(" %q", p.Name)
+	}
+	return desc
+}
+
+// typeNames are the names of common partition types
+var typeNames = map[string]string{
+	"0x01": "FAT12",
+	"0x04": "FAT16",
+	"0x06": "FAT16",
+	"0x07": "NTFS/exFAT",
+	"0x0b": "FAT32",
+	"0x0c": "FAT32",
+	"0x0e": "FAT16",
+	"0x27": "Windows recovery",
+	"0x82": "Linux swap",
+	"0x83": "Linux",
+	"0x8e": "Linux LVM",
+	"0xa5": "FreeBSD",
+	"0xaf": "HFS+",
+	"0xef": "EFI system",
+	"0xfd": "Linux RAID",
+
+	"C12A7328-F81F-11D2-BA4B-00A0C93EC93B": "EFI system",
+	"E3C9E316-0B5C-4DB8-817D-F92DF00215AE": "Microsoft reserved",
+	"EBD0A0A2-B9E5-4433-87C0-68B6B72699C7": "Microsoft basic data",
+	"DE94BBA4-06D1-4D40-A16A-BFD50179D6AC": "Windows recovery",
+	"0FC63DAF-8483-4772-8E79-3D69D8477DE4": "Linux filesystem",
+	"0657FD6D-A4AB-43C4-84E5-0933C84B4F4F": "Linux swap",
+	"E6D6D379-F507-44C2-A23C-238F2A3DF928": "Linux LVM",
+	"4F68BCE3-E8CD-4DB1-96E7-FBCAF984B709": "Linux root (x86-64)",
+	"48465300-0000-11AA-AA11-00306543ECAC": "Apple HFS+",
+	"7C3457EF-0000-11AA-AA11-00306543ECAC": "Apple APFS",
+	"516E7CB6-6ECF-11D6-8FF8-00022D09712B": "FreeBSD data",
+}
+
+// Limits on what is read
+const (
+	sectorSize       = 512
+	maxLogical       = 128
+	maxGPTEntries    = 1024
+	mbrTypeGPT       = 0xee
+	gptEntryMinSize  = 128
+	gptEntryMaxSize  = 4096
+	gptNameOffset    = 56
+	gptNameMaxLength = 72
+)
+
+// ErrNoTable is returned for images without an MBR or GPT
+var ErrNoTable = errors.New("no partition table")
+
+// Read returns the partitions of the disk image in r of size bytes, in the
+// order of their table. A protective MBR is followed to the GPT, which is
+// read with 512- or 4096-byte sectors.
+func Read(r io.ReaderAt, size int64) ([]Partition, error) {
+	mbr := make([]byte, sectorSize)
+	if _, err := r.ReadAt(mbr, 0); err != nil || mbr[510] != 0x55 || mbr[511] != 0xaa {
+		return nil, ErrNoTable
+	}
+	// Boot sectors of filesystems end like an MBR
+	if string(mbr[3:11]) == "NTFS    " || string(mbr[3:11]) == "EXFAT   " || string(mbr[82:87]) == "FAT32" || string(mbr[54:59]) == "FAT16" || string(mbr[54:59]) == "FAT12" {
+		return nil, ErrNoTable
+	}
+	le := binary.LittleEndian
+	var partitions []Partition
+	for i := range 4 {
+		entry := mbr[446+i*16 : 462+i*16]
+		kind, start, sectors := entry[4], int64(le.Uint32(entry[8:])), int64(le.Uint32(entry[12:]))
+		if entry[0]&0x7f != 0 || kind == 0 || sectors == 0 || start*sectorSize >= size {
+			continue
+		}
+		if kind == mbrTypeGPT {
+			for _, gptSector := range []int64{512, 4096} {
+				if gpt, err := readGPT(r, size, gptSector); err == nil {
+					return gpt, nil
+				}
+			}
+			continue
+		}
+		partitions = append(partitions, Partition{Number: i + 1, Scheme: SchemeMBR, Offset: start * sectorSize, Size: sectors * sectorSize, Type: fmt.Sprintf("0x%02x", kind)})
+		if kind == 0x05 || kind == 0x0f || kind == 0x85 {
+			partitions = append(partitions, readLogical(r, size, start)...)
+		}
+	}
+	if len(partitions) == 0 {
+		return nil, ErrNoTable
+	}
+	return partitions, nil
+}
+
+// readLogical follows the chain of extended boot records of the extended
+// partition starting at sector extended, returning its logical partitions
+// that start in the image of size bytes
+func readLogical(r io.ReaderAt, size, extended int64) []Partition {
+	le := binary.LittleEndian
+	var partitions []Partition
+	ebr := make([]byte, sectorSize)
+	for next := extended; len(partitions) < maxLogical; {
+		if _, err := r.ReadAt(ebr, next*sectorSize); err != nil || ebr[510] != 0x55 || ebr[511] != 0xaa {
+			break
+		}
+		// The first entry is the logical partition, relative to its EBR;
+		// the second links to the next EBR, relative to the extended partition
+		if kind, start, sectors := ebr[446+4], int64(le.Uint32(ebr[446+8:])), int64(le.Uint32(ebr[446+12:])); kind != 0 && sectors != 0 && (next+start)*sectorSize < size {
+			partitions = append(partitions, Partition{Number: 5 + len(partitions), Scheme: SchemeMBR, Offset: (next + start) * sectorSize, Size: sectors * sectorSize, Type: fmt.Sprintf("0x%02x", kind)})
+		}
+		link := int64(le.Uint32(ebr[462+8:]))
+		if link == 0 || extended+link <= next {
+			break
+		}
+		next = extended + link
+	}
+	return partitions
+}
+
+// readGPT reads the GPT whose header is in the second sector of sector bytes
+func readGPT(r io.ReaderAt, size, sector int64) ([]Partition, error) {
+	le := binary.LittleEndian
+	header := make([]byte, 92)
+	if _, err := r.ReadAt(header, sector); err != nil || string(header[:8]) != "EFI PART" {
+		return nil, ErrNoTable
+	}
+	entriesLBA := int64(le.Uint64(header[72:]))
+	count := int(le.Uint32(header[80:]))
+	entrySize := int(le.Uint32(header[84:]))
+	if count > maxGPTEntries || entrySize < gptEntryMinSize || entrySize > gptEntryMaxSize {
+		return nil, fmt.Errorf("%w: GPT of %d entries of %d bytes", ErrNoTable, count, entrySize)
+	}
+	entries := make([]byte, count*entrySize)
+	if _, err := r.ReadAt(entries, entriesLBA*sector); err != nil {
+		return nil, ErrNoTable
+	}
+
+	var partitions []Partition
+	for i := range count {
+		entry := entries[i*entrySize : (i+1)*entrySize]
+		first, last := int64(le.Uint64(entry[32:])), int64(le.Uint64(entry[40:]))
+		// LBAs are unsigned; those past the image would overflow
+		if isZero(entry[:16]) || first < 0 || last < first || first >= size/sector || last >= math.MaxInt64/sector {
+			continue
+		}
+		units := make([]uint16, 0, gptNameMaxLength/2)
+		for pos := gptNameOffset; pos+2 <= gptNameOffset+gptNameMaxLength; pos += 2 {
+			unit := le.Uint16(entry[pos:])
+			if unit == 0 {
+				break
+			}
+			units = append(units, unit)
+		}
+		partitions = append(partitions, Partition{Number: i + 1, Scheme: SchemeGPT, Offset: first * sector, Size: (last - first + 1) * sector,
+			Type: formatGUID(entry[:16]), Name: string(utf16.Decode(units))})
+	}
+	return partitions, nil
+}
+
+// formatGUID formats a GUID stored in mixed-endian byte order
+func formatGUID(b []byte) string {
+	le := binary.LittleEndian
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X", le.Uint32(b), le.Uint16(b[4:]), le.Uint16(b[6:]), b[8:10], b[10:16])
+}
+
+// isZero reports whether b is all zeros
+func isZero(b []byte) bool {
+	for _, c := range b {
+		if c != 0 {
+			return false
+		}
+	}
+	return true
+}
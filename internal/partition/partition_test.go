@@ -0,0 +1,95 @@
+package partition
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+// mbrEntry writes a partition entry of kind at sector start of sectors
+// sectors into entry i of the boot record at sector
+func mbrEntry(disk []byte, sector int64, i int, kind byte, start, sectors uint32) {
+	entry := disk[sector*sectorSize+446+int64(i)*16:]
+	entry[4] = kind
+	binary.LittleEndian.PutUint32(entry[8:], start)
+	binary.LittleEndian.PutUint32(entry[12:], sectors)
+	disk[sector*sectorSize+510], disk[sector*sectorSize+511] = 0x55, 0xaa
+}
+
+// TestReadMBR tests primary partitions and the logical partitions of an
+// extended partition
+func TestReadMBR(t *testing.T) {
+	disk := make([]byte, 4096*sectorSize)
+	mbrEntry(disk, 0, 0, 0x07, 2048, 1000)
+	mbrEntry(disk, 0, 1, 0x0f, 3100, 900)
+	// Two logical partitions: their EBRs at sectors 3100 and 3500
+	mbrEntry(disk, 3100, 0, 0x83, 63, 300)
+	mbrEntry(disk, 3100, 1, 0x05, 400, 400)
+	mbrEntry(disk, 3500, 0, 0x82, 63, 200)
+
+	got, err := Read(bytes.NewReader(disk), int64(len(disk)))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want := []Partition{
+		{Number: 1, Scheme: SchemeMBR, Offset: 2048 * sectorSize, Size: 1000 * sectorSize, Type: "0x07"},
+		{Number: 2, Scheme: SchemeMBR, Offset: 3100 * sectorSize, Size: 900 * sectorSize, Type: "0x0f"},
+		{Number: 5, Scheme: SchemeMBR, Offset: 3163 * sectorSize, Size: 300 * sectorSize, Type: "0x83"},
+		{Number: 6, Scheme: SchemeMBR, Offset: 3563 * sectorSize, Size: 200 * sectorSize, Type: "0x82"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Read() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("partition %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if desc := got[0].Description(); desc != "MBR partition 1: NTFS/exFAT (0x07)" {
+		t.Errorf("Description() = %q", desc)
+	}
+
+	// A filesystem boot sector is not a partition table
+	boot := make([]byte, 1024*sectorSize)
+	copy(boot[3:], "NTFS    ")
+	mbrEntry(boot, 0, 0, 0x07, 1, 10)
+	if _, err := Read(bytes.NewReader(boot), int64(len(boot))); !errors.Is(err, ErrNoTable) {
+		t.Errorf("Read(NTFS boot sector) error = %v, want %v", err, ErrNoTable)
+	}
+}
+
+// TestReadGPT tests following a protective MBR to the GPT
+func TestReadGPT(t *testing.T) {
+	le := binary.LittleEndian
+	disk := make([]byte, 8192*sectorSize)
+	mbrEntry(disk, 0, 0, mbrTypeGPT, 1, 8191)
+	header := disk[sectorSize:]
+	copy(header, "EFI PART")
+	le.PutUint64(header[72:], 2)
+	le.PutUint32(header[80:], 128)
+	le.PutUint32(header[84:], 128)
+
+	entry := disk[2*sectorSize+128:] // The second entry; the first is unused
+	copy(entry, []byte{0xa2, 0xa0, 0xd0, 0xeb, 0xe5, 0xb9, 0x33, 0x44, 0x87, 0xc0, 0x68, 0xb6, 0xb7, 0x26, 0x99, 0xc7})
+	entry[16] = 1 // Unique GUID
+	le.PutUint64(entry[32:], 2048)
+	le.PutUint64(entry[40:], 4095)
+	for i, u := range utf16.Encode([]rune("Basic data partition")) {
+		le.PutUint16(entry[56+i*2:], u)
+	}
+
+	got, err := Read(bytes.NewReader(disk), int64(len(disk)))
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	want := Partition{Number: 2, Scheme: SchemeGPT, Offset: 2048 * sectorSize, Size: 2048 * sectorSize,
+		Type: "EBD0A0A2-B9E5-4433-87C0-68B6B72699C7", Name: "Basic data partition"}
+	if len(got) != 1 || got[0] != want {
+		t.Fatalf("Read() = %+v, want %+v", got, want)
+	}
+	if desc, wantDesc := got[0].Description(), `GPT partition 2: Microsoft basic data (EBD0A0A2-B9E5-4433-87C0-68B6B72699C7) "Basic data partition"`; desc != wantDesc {
+		t.Errorf("Description() = %q, want %q", desc, wantDesc)
+	}
+}
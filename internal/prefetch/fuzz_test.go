@@ -0,0 +1,46 @@
+package prefetch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+)
+
+// FuzzWalk tests reading prefetch files with random inputs
+func FuzzWalk(f *testing.F) {
+	// Seed corpus: the prefetch file of the Walk tests, uncompressed and
+	// compressed, and malformed data
+	plain := testPrefetch()
+	f.Add(plain)
+	f.Add(append(binary.LittleEndian.AppendUint32([]byte("MAM\x04"), uint32(len(plain))), xpressCompress(plain)...))
+	f.Add(plain[:120])
+	f.Add([]byte("\x1e\x00\x00\x00SCCA"))
+	f.Add([]byte("MAM\x84"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		// Errors are expected for invalid input
+		compressed := len(data) >= 3 && string(data[:3]) == "MAM"
+		_ = Walk(data, func(text Text) error {
+			// Invariant: the strings of uncompressed files are the data at
+			// their offsets
+			if !compressed && (text.Offset < 0 || text.Offset+int64(len(text.Data)) > int64(len(data)) ||
+				!bytes.Equal(data[text.Offset:text.Offset+int64(len(text.Data))], text.Data)) {
+				t.Errorf("%s: offset 0x%x does not hold %q", text.Field, text.Offset, text.Data)
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,155 @@
+// Package prefetch reads Windows prefetch files (.pf), which list the
+// executable a file was made for, the files it loaded and the volumes they
+// were on, so those paths can be reported as such rather than scanned as
+// UTF-16 text from between the file's tables.
+package prefetch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Fields of a prefetch file (Text.Field)
+const (
+	FieldExecutable = "executable" // Name of the executable
+	FieldFilename   = "filename"   // Path of a file it loaded
+	FieldVolume     = "volume"     // Device path of a volume
+	FieldDirectory  = "directory"  // Path of a directory on a volume
+)
+
+// Text is a UTF-16LE string of a prefetch file
+type Text struct {
+	Field  string
+	Offset int64 // Offset of Data in the file, or in the decompressed file if it is compressed
+	Data   []byte
+}
+
+// ErrNotPrefetch is returned for files that are not prefetch files
+var ErrNotPrefetch = errors.New("not a prefetch file")
+
+var errCorrupt = errors.New("corrupt prefetch file")
+
+// Size of a volume information entry by format version (Windows XP, Vista
+// and 7, 8, 10 and 11)
+var volumeEntrySize = map[uint32]int{17: 40, 23: 104, 26: 104, 30: 96, 31: 96}
+
+// IsPrefetch reports whether header is the start of a prefetch file, either
+// uncompressed ("SCCA" after the version) or XPRESS Huffman compressed
+// ("MAM\x04", as written by Windows 10 and later)
+func IsPrefetch(header []byte) bool {
+	return len(header) >= 8 && (string(header[4:8]) == "SCCA" || string(header[:3]) == "MAM" && header[3]&0x7f == 4)
+}
+
+// Walk decompresses a prefetch file if it is compressed and calls fn with its
+// strings: the executable name, the file name strings and the device path
+// and directory strings of each volume, in that order. It stops at the first
+// error fn returns. Tables that do not fit in the file end the walk with an
+// error wrapping errCorrupt, after the strings found before them.
+func Walk(data []byte, fn func(Text) error) error {
+	if !IsPrefetch(data) {
+		return ErrNotPrefetch
+	}
+	le := binary.LittleEndian
+	if string(data[:3]) == "MAM" {
+		start := 8
+		if data[3]&0x80 != 0 {
+			start += 4 // CRC32
+		}
+		if start > len(data) {
+			return errCorrupt
+		}
+		var err error
+		data, err = decompressXpressHuffman(data[start:], int(le.Uint32(data[4:])))
+		if err != nil {
+			return err
+		}
+		if len(data) < 8 || string(data[4:8]) != "SCCA" {
+			return ErrNotPrefetch
+		}
+	}
+
+	version := le.Uint32(data)
+	entrySize, ok := volumeEntrySize[version]
+	if !ok {
+		return fmt.Errorf("%w: version %d", ErrNotPrefetch, version)
+	}
+	if len(data) < 120 {
+		return fmt.Errorf("%w: truncated header", errCorrupt)
+	}
+
+	// The executable name is NUL-terminated in a 60-byte field
+	name := 16
+	for name < 76 && le.Uint16(data[name:]) != 0 {
+		name += 2
+	}
+	if name > 16 {
+		if err := fn(Text{Field: FieldExecutable, Offset: 16, Data: data[16:name]}); err != nil {
+			return err
+		}
+	}
+
+	filenames, filenamesSize := int(le.Uint32(data[100:])), int(le.Uint32(data[104:]))
+	if !inside(data, filenames, filenamesSize) {
+		return fmt.Errorf("%w: file name strings", errCorrupt)
+	}
+	if err := emitStrings(data, filenames, filenames+filenamesSize, FieldFilename, fn); err != nil {
+		return err
+	}
+
+	volumes, volumeCount := int(le.Uint32(data[108:])), int(le.Uint32(data[112:]))
+	if !inside(data, volumes, volumeCount*entrySize) {
+		return fmt.Errorf("%w: volumes", errCorrupt)
+	}
+	for i := range volumeCount {
+		entry := data[volumes+i*entrySize:]
+		path, pathChars := volumes+int(le.Uint32(entry)), int(le.Uint32(entry[4:]))
+		if !inside(data, path, pathChars*2) {
+			return fmt.Errorf("%w: volume %d", errCorrupt, i)
+		}
+		if err := fn(Text{Field: FieldVolume, Offset: int64(path), Data: data[path : path+pathChars*2]}); err != nil {
+			return err
+		}
+
+		// Directory strings are each a character count, the characters and a NUL
+		dirs, dirCount := volumes+int(le.Uint32(entry[28:])), int(le.Uint32(entry[32:]))
+		for range dirCount {
+			if !inside(data, dirs, 2) {
+				return fmt.Errorf("%w: directories of volume %d", errCorrupt, i)
+			}
+			chars := int(le.Uint16(data[dirs:]))
+			if !inside(data, dirs+2, chars*2) {
+				return fmt.Errorf("%w: directories of volume %d", errCorrupt, i)
+			}
+			if err := fn(Text{Field: FieldDirectory, Offset: int64(dirs + 2), Data: data[dirs+2 : dirs+2+chars*2]}); err != nil {
+				return err
+			}
+			dirs += 2 + chars*2 + 2
+		}
+	}
+	return nil
+}
+
+// inside reports whether size bytes at offset are within data
+func inside(data []byte, offset, size int) bool {
+	return offset >= 0 && size >= 0 && offset <= len(data) && size <= len(data)-offset
+}
+
+// emitStrings reports the NUL-terminated UTF-16 strings of data[start:end] as
+// field
+func emitStrings(data []byte, start, end int, field string, fn func(Text) error) error {
+	le := binary.LittleEndian
+	for pos := start; pos+2 <= end; {
+		next := pos
+		for next+2 <= end && le.Uint16(data[next:]) != 0 {
+			next += 2
+		}
+		if next > pos {
+			if err := fn(Text{Field: field, Offset: int64(pos), Data: data[pos:next]}); err != nil {
+				return err
+			}
+		}
+		pos = next + 2
+	}
+	return nil
+}
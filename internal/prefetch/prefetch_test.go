@@ -0,0 +1,156 @@
+package prefetch
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16le encodes s as UTF-16LE
+func utf16le(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// xpressCompress compresses data (under 64 KiB) with XPRESS Huffman, using a
+// code where all 512 symbols are 9 bits (the code of each symbol is its
+// value) and matches of up to 17 bytes, which need no extra length bytes
+func xpressCompress(data []byte) []byte {
+	out := bytes.Repeat([]byte{0x99}, xpressTableBytes)
+	var word uint16
+	used := 0
+	write := func(value, bits int) {
+		for i := bits - 1; i >= 0; i-- {
+			word = word<<1 | uint16(value>>i&1)
+			used++
+			if used == 16 {
+				out = binary.LittleEndian.AppendUint16(out, word)
+				word, used = 0, 0
+			}
+		}
+	}
+
+	for pos := 0; pos < len(data); {
+		bestLength, bestOffset := 0, 0
+		for offset := 1; offset <= min(pos, 4096); offset++ {
+			length := 0
+			for length < 17 && pos+length < len(data) && data[pos+length] == data[pos+length-offset] {
+				length++
+			}
+			if length > bestLength {
+				bestLength, bestOffset = length, offset
+			}
+		}
+		if bestLength < 3 {
+			write(int(data[pos]), 9)
+			pos++
+			continue
+		}
+		offsetBits := 0
+		for 1<<(offsetBits+1) <= bestOffset {
+			offsetBits++
+		}
+		write(256+offsetBits<<4+bestLength-3, 9)
+		write(bestOffset-1<<offsetBits, offsetBits)
+		pos += bestLength
+	}
+	write(0, 16-used)
+	return append(out, 0, 0, 0, 0)
+}
+
+// TestDecompressXpressHuffman tests decoding literals and matches, including
+// matches overlapping their own output
+func TestDecompressXpressHuffman(t *testing.T) {
+	data := []byte(strings.Repeat("C:\\WINDOWS\\SYSTEM32\\NTDLL.DLL ", 40) + strings.Repeat("z", 100))
+	got, err := decompressXpressHuffman(xpressCompress(data), len(data))
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("decompressXpressHuffman() = %q, %v, want %q", got, err, data)
+	}
+
+	// Code lengths that do not make a complete code are rejected
+	if _, err := decompressXpressHuffman(make([]byte, 300), 10); !errors.Is(err, errCorrupt) {
+		t.Errorf("decompressXpressHuffman(zero lengths) error = %v, want %v", err, errCorrupt)
+	}
+}
+
+// testPrefetch builds an uncompressed Windows 10 (version 30) prefetch file
+func testPrefetch() []byte {
+	le := binary.LittleEndian
+	data := make([]byte, 304)
+	le.PutUint32(data, 30)
+	copy(data[4:], "SCCA")
+	copy(data[16:], utf16le("MIMIKATZ.EXE"))
+
+	filenames := utf16le("\\VOLUME{01d2}\\WINDOWS\\SYSTEM32\\NTDLL.DLL\x00\\VOLUME{01d2}\\TEMP\\MIMIKATZ.EXE\x00")
+	le.PutUint32(data[100:], uint32(len(data)))
+	le.PutUint32(data[104:], uint32(len(filenames)))
+	data = append(data, filenames...)
+
+	// One volume: its entry, device path and directory strings
+	volumes := len(data)
+	entry := make([]byte, 96)
+	device := utf16le("\\VOLUME{01d2}\x00")
+	le.PutUint32(entry, 96)
+	le.PutUint32(entry[4:], uint32(len(device)/2-1))
+	var dirs []byte
+	for _, dir := range []string{"\\VOLUME{01d2}\\TEMP", "\\VOLUME{01d2}\\WINDOWS"} {
+		dirs = le.AppendUint16(dirs, uint16(len(dir)))
+		dirs = append(dirs, utf16le(dir+"\x00")...)
+	}
+	le.PutUint32(entry[28:], uint32(96+len(device)))
+	le.PutUint32(entry[32:], 2)
+	le.PutUint32(data[108:], uint32(volumes))
+	le.PutUint32(data[112:], 1)
+	data = append(append(append(data, entry...), device...), dirs...)
+	le.PutUint32(data[12:], uint32(len(data)))
+	return data
+}
+
+// TestWalk tests reading the strings of uncompressed and compressed prefetch
+// files
+func TestWalk(t *testing.T) {
+	plain := testPrefetch()
+	compressed := binary.LittleEndian.AppendUint32([]byte("MAM\x04"), uint32(len(plain)))
+	compressed = append(compressed, xpressCompress(plain)...)
+
+	want := []struct{ field, text string }{
+		{FieldExecutable, "MIMIKATZ.EXE"},
+		{FieldFilename, "\\VOLUME{01d2}\\WINDOWS\\SYSTEM32\\NTDLL.DLL"},
+		{FieldFilename, "\\VOLUME{01d2}\\TEMP\\MIMIKATZ.EXE"},
+		{FieldVolume, "\\VOLUME{01d2}"},
+		{FieldDirectory, "\\VOLUME{01d2}\\TEMP"},
+		{FieldDirectory, "\\VOLUME{01d2}\\WINDOWS"},
+	}
+	for name, data := range map[string][]byte{"plain": plain, "compressed": compressed} {
+		t.Run(name, func(t *testing.T) {
+			var texts []Text
+			if err := Walk(data, func(text Text) error {
+				texts = append(texts, text)
+				return nil
+			}); err != nil {
+				t.Fatalf("Walk() error = %v", err)
+			}
+			if len(texts) != len(want) {
+				t.Fatalf("Walk() = %d texts, want %d", len(texts), len(want))
+			}
+			for i, text := range texts {
+				if text.Field != want[i].field || !bytes.Equal(text.Data, utf16le(want[i].text)) {
+					t.Errorf("text %d = %s %q, want %s %q", i, text.Field, text.Data, want[i].field, want[i].text)
+				}
+				if !bytes.Equal(plain[text.Offset:text.Offset+int64(len(text.Data))], text.Data) {
+					t.Errorf("text %d: offset 0x%x does not hold it", i, text.Offset)
+				}
+			}
+		})
+	}
+
+	if err := Walk([]byte("regf\x00\x00\x00\x00"), func(Text) error { return nil }); !errors.Is(err, ErrNotPrefetch) {
+		t.Errorf("Walk(not prefetch) error = %v, want %v", err, ErrNotPrefetch)
+	}
+}
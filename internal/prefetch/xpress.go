@@ -0,0 +1,137 @@
+package prefetch
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+const (
+	xpressBlockSize  = 65536 // Output bytes decoded with each Huffman table
+	xpressTableBytes = 256   // 512 code lengths of 4 bits
+	xpressMaxBits    = 15
+	maxDecompressed  = 64 << 20 // Far more than any prefetch file holds
+)
+
+var errXpress = fmt.Errorf("%w: XPRESS Huffman data", errCorrupt)
+
+// decompressXpressHuffman decodes size bytes of XPRESS Huffman compressed
+// data (MS-XCA 2.2.4), the compression of Windows 10 prefetch files. Each
+// 64 KiB of output has its own Huffman table of 256 literal and 256 match
+// symbols.
+func decompressXpressHuffman(in []byte, size int) ([]byte, error) {
+	if size > maxDecompressed {
+		return nil, fmt.Errorf("%w: %d bytes", errXpress, size)
+	}
+	out := make([]byte, 0, size)
+	pos := 0
+	read16 := func() uint32 {
+		if pos+2 > len(in) {
+			pos += 2
+			return 0
+		}
+		v := uint32(binary.LittleEndian.Uint16(in[pos:]))
+		pos += 2
+		return v
+	}
+
+	var lengths [512]uint8
+	table := make([]uint16, 1<<xpressMaxBits)
+	for len(out) < size {
+		if pos+xpressTableBytes > len(in) {
+			return out, errXpress
+		}
+		for i, b := range in[pos : pos+xpressTableBytes] {
+			lengths[2*i] = b & 0x0f
+			lengths[2*i+1] = b >> 4
+		}
+		pos += xpressTableBytes
+		if !xpressTable(&lengths, table) {
+			return out, errXpress
+		}
+
+		bits := read16()<<16 | read16()
+		extra := 16 // Bits available beyond the 16 at the top of bits
+		consume := func(n int) {
+			bits <<= n
+			extra -= n
+			if extra < 0 {
+				bits |= read16() << -extra
+				extra += 16
+			}
+		}
+
+		blockEnd := min(len(out)+xpressBlockSize, size)
+		for len(out) < blockEnd {
+			if pos > len(in)+4 {
+				return out, errXpress
+			}
+			symbol := int(table[bits>>(32-xpressMaxBits)])
+			consume(int(lengths[symbol]))
+			if symbol < 256 {
+				out = append(out, byte(symbol))
+				continue
+			}
+
+			symbol -= 256
+			length := symbol & 0x0f
+			offsetBits := symbol >> 4
+			if length == 15 {
+				if pos >= len(in) {
+					return out, errXpress
+				}
+				length = int(in[pos])
+				pos++
+				if length == 255 {
+					if pos+2 > len(in) {
+						return out, errXpress
+					}
+					length = int(binary.LittleEndian.Uint16(in[pos:]))
+					pos += 2
+					if length < 15 {
+						return out, errXpress
+					}
+					length -= 15
+				}
+				length += 15
+			}
+			length += 3
+
+			offset := 1 << offsetBits
+			if offsetBits > 0 {
+				offset |= int(bits >> (32 - offsetBits))
+				consume(offsetBits)
+			}
+			if offset > len(out) {
+				return out, errXpress
+			}
+			// Matches may overlap the bytes they produce
+			for range min(length, size-len(out)) {
+				out = append(out, out[len(out)-offset])
+			}
+		}
+	}
+	return out, nil
+}
+
+// xpressTable fills table, indexed by the next 15 bits of input, with the
+// symbols of the canonical Huffman code of lengths. It reports false for
+// lengths that do not make a complete code.
+func xpressTable(lengths *[512]uint8, table []uint16) bool {
+	entry := 0
+	for bits := 1; bits <= xpressMaxBits; bits++ {
+		for symbol, length := range lengths {
+			if int(length) != bits {
+				continue
+			}
+			count := 1 << (xpressMaxBits - bits)
+			if entry+count > len(table) {
+				return false
+			}
+			for i := range count {
+				table[entry+i] = uint16(symbol)
+			}
+			entry += count
+		}
+	}
+	return entry == len(table)
+}
@@ -0,0 +1,83 @@
+package printer
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ArtifactCell is where text of a parsed forensic artifact (a registry hive,
+// event log, prefetch file or NTFS image) is in the file, with what the
+// artifact says about it. Only the fields of the artifact's kind are set.
+type ArtifactCell struct {
+	Offset int64
+	Size   int64
+	// Registry hives (see registry.Walk)
+	RegistryKey   string // Path of the key
+	RegistryValue string // Name of the value; empty for key names
+	// Event logs (see evtx.Walk)
+	EventRecordID uint64
+	EventTime     string // RFC 3339
+	// Prefetch files (see prefetch.Walk)
+	PrefetchField string
+	// NTFS images (see ntfs.Extents); NTFSPath is set for all of them
+	MFTRecord     uint64
+	NTFSPath      string
+	NTFSAttribute string
+	NTFSDeleted   bool
+	// Memory images (see memimage.ParseMap and memimage.Analyze);
+	// MemoryRegion is set for all of them
+	MemoryRegion     string
+	MemoryAddress    uint64 // Virtual address at Offset, if MemoryHasAddress
+	MemoryHasAddress bool
+}
+
+// ArtifactCells are the cells of a file, sorted by offset
+type ArtifactCells []ArtifactCell
+
+// Lookup returns the cell holding offset
+func (cells ArtifactCells) Lookup(offset int64) (ArtifactCell, bool) {
+	i := sort.Search(len(cells), func(i int) bool { return cells[i].Offset > offset }) - 1
+	if i < 0 || offset >= cells[i].Offset+cells[i].Size {
+		return ArtifactCell{}, false
+	}
+	return cells[i], true
+}
+
+// attribute copies what the cell holding its offset says about result, if any
+func (cells ArtifactCells) attribute(result *StringResult) {
+	if cell, ok := cells.Lookup(result.Offset); ok {
+		result.RegistryKey = cell.RegistryKey
+		result.RegistryValue = cell.RegistryValue
+		result.EventRecordID = cell.EventRecordID
+		result.EventTime = cell.EventTime
+		result.PrefetchField = cell.PrefetchField
+		if cell.NTFSPath != "" {
+			record := cell.MFTRecord
+			result.MFTRecord = &record
+			result.NTFSPath = cell.NTFSPath
+			result.NTFSAttribute = cell.NTFSAttribute
+			result.NTFSDeleted = cell.NTFSDeleted
+		}
+		if cell.MemoryRegion != "" {
+			result.MemoryRegion = cell.MemoryRegion
+			if cell.MemoryHasAddress {
+				result.MemoryAddress = fmt.Sprintf("0x%x", cell.MemoryAddress+uint64(result.Offset-cell.Offset))
+			}
+		}
+	}
+}
+
+// SetArtifactCells records the cells of the current file when it is a parsed
+// artifact (--registry, --evtx, --prefetch, --ntfs, --memory-map,
+// --memory-profile), used to report the key, event record, field, file or
+// memory region each string belongs to. It applies to the
+// current file only and is cleared by SetFileInfo.
+func (jp *JSONPrinter) SetArtifactCells(cells ArtifactCells) {
+	jp.currentArtifact = cells
+}
+
+// SetArtifactCells records the cells of the current file when it is a parsed
+// artifact, like JSONPrinter.SetArtifactCells. It applies until the next call.
+func (np *NDJSONPrinter) SetArtifactCells(cells ArtifactCells) {
+	np.artifact = cells
+}
@@ -0,0 +1,112 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestArtifactCells tests attributing strings to the registry key and value
+// whose cell holds them, in JSON and NDJSON
+func TestArtifactCells(t *testing.T) {
+	cells := ArtifactCells{
+		{Offset: 0x1000, Size: 8, RegistryKey: `\Software`},
+		{Offset: 0x1010, Size: 0x20, RegistryKey: `\Software\Vendor`, RegistryValue: "Path"},
+	}
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+
+	var jsonBuf, ndjsonBuf bytes.Buffer
+	jp := NewJSONPrinter(config, &jsonBuf)
+	np := NewNDJSONPrinter(&ndjsonBuf)
+	jp.SetFileInfo("SOFTWARE", "registry", nil)
+	jp.SetArtifactCells(cells)
+	np.SetArtifactCells(cells)
+	for _, offset := range []int64{0x1000, 0x1018, 0x1030} {
+		jp.PrintString([]byte("text"), "SOFTWARE", offset, config)
+		np.PrintString([]byte("text"), "SOFTWARE", offset, config)
+	}
+	// Cells must not carry over to the next file
+	jp.SetFileInfo("other.bin", "", nil)
+	jp.PrintString([]byte("text"), "other.bin", 0x1000, config)
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if err := np.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var output JSONOutput
+	if err := json.Unmarshal(jsonBuf.Bytes(), &output); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	got := append(output.Files[0].Strings, output.Files[1].Strings...)
+	for line := range bytes.Lines(ndjsonBuf.Bytes()) {
+		var result StringResult
+		if err := json.Unmarshal(line, &result); err != nil {
+			t.Fatalf("Invalid NDJSON line %q: %v", line, err)
+		}
+		got = append(got, result)
+	}
+
+	want := [][2]string{{`\Software`, ""}, {`\Software\Vendor`, "Path"}, {"", ""}, {"", ""}, {`\Software`, ""}, {`\Software\Vendor`, "Path"}, {"", ""}}
+	if len(got) != len(want) {
+		t.Fatalf("got %d strings, want %d", len(got), len(want))
+	}
+	for i, result := range got {
+		if result.RegistryKey != want[i][0] || result.RegistryValue != want[i][1] {
+			t.Errorf("string %d at 0x%x = %q %q, want %q %q", i, result.Offset, result.RegistryKey, result.RegistryValue, want[i][0], want[i][1])
+		}
+	}
+}
+
+// TestArtifactCellsNTFS tests that the MFT record of NTFS files is reported
+// even when it is 0, and not for strings outside any file
+func TestArtifactCellsNTFS(t *testing.T) {
+	cells := ArtifactCells{
+		{Offset: 0x4000, Size: 8, MFTRecord: 0, NTFSPath: `\$MFT`, NTFSAttribute: "$FILE_NAME"},
+		{Offset: 0x9000, Size: 0x100, MFTRecord: 42, NTFSPath: `\old.txt`, NTFSAttribute: "$DATA", NTFSDeleted: true},
+	}
+	for _, tt := range []struct {
+		offset  int64
+		record  *uint64
+		path    string
+		deleted bool
+	}{
+		{0x4000, new(uint64(0)), `\$MFT`, false},
+		{0x9010, new(uint64(42)), `\old.txt`, true},
+		{0x9100, nil, "", false},
+	} {
+		result := StringResult{Offset: tt.offset}
+		cells.attribute(&result)
+		if (result.MFTRecord == nil) != (tt.record == nil) || result.MFTRecord != nil && *result.MFTRecord != *tt.record ||
+			result.NTFSPath != tt.path || result.NTFSDeleted != tt.deleted {
+			t.Errorf("attribute(0x%x) = %v %q deleted %v, want %v %q deleted %v", tt.offset, result.MFTRecord, result.NTFSPath, result.NTFSDeleted, tt.record, tt.path, tt.deleted)
+		}
+	}
+}
+
+// TestArtifactCellsMemory tests that strings of memory images are reported
+// with their region and, where known, their virtual address
+func TestArtifactCellsMemory(t *testing.T) {
+	cells := ArtifactCells{
+		{Offset: 0x1000, Size: 0x1000, MemoryRegion: "kernel", MemoryAddress: 0xfffff80000000000, MemoryHasAddress: true},
+		{Offset: 0x2000, Size: 0x1000, MemoryRegion: "shared user"},
+	}
+	for _, tt := range []struct {
+		offset  int64
+		region  string
+		address string
+	}{
+		{0x1010, "kernel", "0xfffff80000000010"},
+		{0x2fff, "shared user", ""},
+		{0x3000, "", ""},
+	} {
+		result := StringResult{Offset: tt.offset}
+		cells.attribute(&result)
+		if result.MemoryRegion != tt.region || result.MemoryAddress != tt.address {
+			t.Errorf("attribute(0x%x) = %q %q, want %q %q", tt.offset, result.MemoryRegion, result.MemoryAddress, tt.region, tt.address)
+		}
+	}
+}
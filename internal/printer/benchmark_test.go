@@ -196,6 +196,29 @@ func BenchmarkPrintString_CustomSeparator(b *testing.B) {
 	}
 }
 
+// Benchmark: style resolved once (no environment lookups or stat per string)
+
+func BenchmarkTextPrinter_AutoColor(b *testing.B) {
+	str := []byte("Hello, World!")
+	config := extractor.Config{MinLength: 4, PrintFileName: true, ColorMode: extractor.ColorAuto}
+	printer := NewTextPrinter(io.Discard, config)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		printer.PrintString(str, "test.bin", 1024, config)
+	}
+}
+
+func BenchmarkPrintString_AutoColor(b *testing.B) {
+	str := []byte("Hello, World!")
+	config := extractor.Config{MinLength: 4, PrintFileName: true, ColorMode: extractor.ColorAuto}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		PrintStringToWriter(io.Discard, str, "test.bin", 1024, config)
+	}
+}
+
 // Benchmark: JSON output
 
 func BenchmarkJSONPrinter_Collect(b *testing.B) {
@@ -4,6 +4,7 @@ package printer
 import (
 	"os"
 
+	"github.com/richardwooding/txtr/internal/console"
 	"github.com/richardwooding/txtr/internal/extractor"
 )
 
@@ -26,7 +27,8 @@ const (
 )
 
 // ShouldUseColor determines if colored output should be used based on the mode,
-// NO_COLOR environment variable, and whether stdout is a TTY.
+// NO_COLOR environment variable, and whether stdout is a TTY interpreting ANSI
+// escape sequences.
 func ShouldUseColor(mode extractor.ColorMode) bool {
 	// Respect NO_COLOR environment variable (https://no-color.org/)
 	if os.Getenv("NO_COLOR") != "" {
@@ -37,10 +39,13 @@ func ShouldUseColor(mode extractor.ColorMode) bool {
 	case extractor.ColorNever:
 		return false
 	case extractor.ColorAlways:
+		// Forced colors still need a Windows console to interpret them
+		console.VirtualTerminal()
 		return true
 	case extractor.ColorAuto:
-		// Auto-detect if stdout is a terminal
-		return isTerminal(os.Stdout)
+		// Auto-detect if stdout is a terminal (a Windows console without
+		// escape sequences would show them as text)
+		return isTerminal(os.Stdout) && console.VirtualTerminal()
 	default:
 		return false
 	}
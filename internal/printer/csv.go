@@ -0,0 +1,55 @@
+package printer
+
+import (
+	"encoding/csv"
+	"strconv"
+)
+
+// csvHeader lists the columns written by FlushCSV
+var csvHeader = []string{"file", "offset", "offset_hex", "length", "encoding", "value"}
+
+// FlushCSV outputs all collected strings as CSV, one row per string in file
+// order, preceded by a header row. Unique mode adds count and offsets columns,
+// and the secrets preset a secret_rule column. Files that failed are omitted (their errors
+// are reported when they occur); relocation strings are not included.
+func (jp *JSONPrinter) FlushCSV() error {
+	// Finalize any remaining current file
+	if jp.currentFile != "" || len(jp.currentStrings) > 0 {
+		jp.FinalizeCurrentFile()
+	}
+
+	w := csv.NewWriter(jp.writer)
+	header := append(csvHeader, csvUniqueHeader(jp.config)...)
+	if jp.config.Secrets {
+		header = append(header, "secret_rule")
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	err := jp.forEachString(func(fileResult FileResult, result StringResult) error {
+		file := result.File
+		if file == "" {
+			file = fileResult.File
+		}
+		record := []string{
+			file,
+			strconv.FormatInt(result.Offset, 10),
+			result.OffsetHex,
+			strconv.Itoa(result.Length),
+			result.Encoding,
+			result.Value,
+		}
+		record = append(record, csvUniqueFields(result, jp.config)...)
+		if jp.config.Secrets {
+			record = append(record, result.SecretRule)
+		}
+		return w.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	w.Flush()
+	return w.Error()
+}
@@ -0,0 +1,74 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestJSONPrinterFlushCSV tests CSV output of collected strings
+func TestJSONPrinterFlushCSV(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+	jp := NewJSONPrinter(config, &buf)
+
+	jp.SetFileInfo("a.bin", "ELF", []string{".rodata"})
+	jp.PrintString([]byte("hello"), "a.bin", 16, config)
+	jp.PrintString([]byte("with, comma \"quoted\""), "a.bin", 32, config)
+	jp.SetFileInfo("b.bin", "", nil)
+	jp.PrintString([]byte("world"), "b.bin", 0, config)
+
+	if err := jp.FlushCSV(); err != nil {
+		t.Fatalf("FlushCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+
+	want := [][]string{
+		csvHeader,
+		{"a.bin", "16", "0x10", "5", "ascii-7bit", "hello"},
+		{"a.bin", "32", "0x20", "20", "ascii-7bit", "with, comma \"quoted\""},
+		{"b.bin", "0", "0x0", "5", "ascii-7bit", "world"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d: %v", len(records), len(want), records)
+	}
+	for i := range want {
+		for j := range want[i] {
+			if records[i][j] != want[i][j] {
+				t.Errorf("record %d field %d = %q, want %q", i, j, records[i][j], want[i][j])
+			}
+		}
+	}
+}
+
+// TestJSONPrinterFlushCSVSpilled tests that CSV output is unchanged when
+// strings are spilled to disk
+func TestJSONPrinterFlushCSVSpilled(t *testing.T) {
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+
+	run := func(limit int64) string {
+		var buf bytes.Buffer
+		jp := NewJSONPrinter(config, &buf)
+		jp.SetMemoryLimit(limit)
+		for _, file := range []string{"a.bin", "b.bin"} {
+			jp.SetFileInfo(file, "", nil)
+			for i := range 50 {
+				jp.PrintString([]byte("string number"), file, int64(i*16), config)
+			}
+		}
+		if err := jp.FlushCSV(); err != nil {
+			t.Fatalf("FlushCSV() error = %v", err)
+		}
+		return buf.String()
+	}
+
+	if unlimited, spilled := run(0), run(500); unlimited != spilled {
+		t.Error("CSV output differs when strings are spilled")
+	}
+}
@@ -0,0 +1,133 @@
+package printer
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/fingerprint"
+)
+
+// cycloneDXSpecVersion is the CycloneDX specification written by FlushCycloneDX
+const cycloneDXSpecVersion = "1.6"
+
+// cycloneDXBOM is a CycloneDX JSON bill of materials
+type cycloneDXBOM struct {
+	BOMFormat    string               `json:"bomFormat"`
+	SpecVersion  string               `json:"specVersion"`
+	SerialNumber string               `json:"serialNumber"`
+	Version      int                  `json:"version"`
+	Metadata     cycloneDXMetadata    `json:"metadata"`
+	Components   []cycloneDXComponent `json:"components"`
+}
+
+// cycloneDXMetadata records when and by which tool the BOM was produced
+type cycloneDXMetadata struct {
+	Timestamp string `json:"timestamp"`
+	Tools     struct {
+		Components []cycloneDXComponent `json:"components"`
+	} `json:"tools"`
+}
+
+// cycloneDXComponent is a component of the BOM
+type cycloneDXComponent struct {
+	Type     string             `json:"type"`
+	BOMRef   string             `json:"bom-ref,omitempty"`
+	Name     string             `json:"name"`
+	Version  string             `json:"version,omitempty"`
+	CPE      string             `json:"cpe,omitempty"`
+	Evidence *cycloneDXEvidence `json:"evidence,omitempty"`
+}
+
+// cycloneDXEvidence lists where a component was found
+type cycloneDXEvidence struct {
+	Occurrences []cycloneDXOccurrence `json:"occurrences"`
+}
+
+// cycloneDXOccurrence is a file and offset a component's banner was found at
+type cycloneDXOccurrence struct {
+	Location          string `json:"location"`
+	Offset            int64  `json:"offset"`
+	AdditionalContext string `json:"additionalContext,omitempty"`
+}
+
+// cycloneDXType maps a component kind to a CycloneDX component type (compilers
+// that leave idents in a binary are reported as applications)
+func cycloneDXType(kind string) string {
+	switch kind {
+	case fingerprint.KindOS:
+		return "operating-system"
+	case fingerprint.KindFirmware:
+		return "firmware"
+	case fingerprint.KindLibrary:
+		return "library"
+	default:
+		return "application"
+	}
+}
+
+// FlushCycloneDX outputs the components identified in all files (see
+// FileResult.Components) as a CycloneDX JSON BOM. Each name and version is
+// listed once, with an evidence occurrence for every file it was found in;
+// the strings themselves are not included.
+func (jp *JSONPrinter) FlushCycloneDX(toolVersion string) error {
+	// Finalize any remaining current file
+	if jp.currentFile != "" || len(jp.currentStrings) > 0 {
+		jp.FinalizeCurrentFile()
+	}
+	defer jp.closeSpill()
+
+	bom := cycloneDXBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  cycloneDXSpecVersion,
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Components:   make([]cycloneDXComponent, 0),
+	}
+	bom.Metadata.Timestamp = time.Now().UTC().Format(time.RFC3339)
+	bom.Metadata.Tools.Components = []cycloneDXComponent{{Type: "application", Name: "txtr", Version: toolVersion}}
+
+	index := make(map[string]int)
+	for _, fileResult := range jp.FileResults {
+		location := fileResult.File
+		if location == "" {
+			location = "-"
+		}
+		for _, component := range fileResult.Components {
+			ref := component.Name + "@" + component.Version
+			i, ok := index[ref]
+			if !ok {
+				i = len(bom.Components)
+				index[ref] = i
+				bom.Components = append(bom.Components, cycloneDXComponent{
+					Type:     cycloneDXType(component.Kind),
+					BOMRef:   ref,
+					Name:     component.Name,
+					Version:  component.Version,
+					CPE:      component.CPE,
+					Evidence: &cycloneDXEvidence{},
+				})
+			}
+			evidence := bom.Components[i].Evidence
+			evidence.Occurrences = append(evidence.Occurrences, cycloneDXOccurrence{
+				Location:          location,
+				Offset:            component.Offset,
+				AdditionalContext: component.Evidence,
+			})
+		}
+	}
+
+	encoder := json.NewEncoder(jp.writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(bom)
+}
+
+// newUUID returns a random (version 4) UUID for the BOM serial number
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
@@ -0,0 +1,112 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestJSONPrinterComponents tests per-file component identification
+func TestJSONPrinterComponents(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+	jp := NewJSONPrinter(config, &buf)
+
+	jp.SetFileInfo("a.bin", "ELF", nil)
+	jp.PrintString([]byte("OpenSSL 3.0.17 1 Jul 2025"), "a.bin", 0x10, config)
+	jp.PrintString([]byte("OpenSSL 3.0.17 1 Jul 2025"), "a.bin", 0x40, config)
+	jp.PrintString([]byte("hello"), "a.bin", 0x80, config)
+	jp.SetFileInfo("b.bin", "", nil)
+	jp.PrintString([]byte("hello"), "b.bin", 0, config)
+	jp.FinalizeCurrentFile()
+
+	components := jp.FileResults[0].Components
+	if len(components) != 1 {
+		t.Fatalf("a.bin components = %+v, want one", components)
+	}
+	want := Component{
+		Name:      "OpenSSL",
+		Version:   "3.0.17",
+		Kind:      "library",
+		CPE:       "cpe:2.3:a:openssl:openssl:3.0.17:*:*:*:*:*:*:*",
+		Evidence:  "OpenSSL 3.0.17 1 Jul 2025",
+		Offset:    0x10,
+		OffsetHex: "0x10",
+	}
+	if components[0] != want {
+		t.Errorf("a.bin component = %+v, want %+v", components[0], want)
+	}
+	if jp.FileResults[1].Components != nil {
+		t.Errorf("b.bin components = %+v, want none", jp.FileResults[1].Components)
+	}
+}
+
+// TestJSONPrinterFlushCycloneDX tests CycloneDX BOM output of detected components
+func TestJSONPrinterFlushCycloneDX(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+	jp := NewJSONPrinter(config, &buf)
+
+	jp.SetFileInfo("a.bin", "ELF", nil)
+	jp.PrintString([]byte(" deflate 1.2.13 Copyright 1995-2022 Jean-loup Gailly and Mark Adler "), "a.bin", 0x10, config)
+	jp.PrintString([]byte("Linux version 5.10.110 (builder@host) #1 SMP"), "a.bin", 0x20, config)
+	jp.SetFileInfo("b.bin", "", nil)
+	jp.PrintString([]byte(" inflate 1.2.13 Copyright 1995-2022 Mark Adler "), "b.bin", 0x30, config)
+
+	if err := jp.FlushCycloneDX("1.2.3"); err != nil {
+		t.Fatalf("FlushCycloneDX() error = %v", err)
+	}
+
+	var bom cycloneDXBOM
+	if err := json.Unmarshal(buf.Bytes(), &bom); err != nil {
+		t.Fatalf("output is not valid JSON: %v\n%s", err, buf.String())
+	}
+
+	if bom.BOMFormat != "CycloneDX" || bom.SpecVersion != cycloneDXSpecVersion || bom.Version != 1 {
+		t.Errorf("BOM header = %q %q %d", bom.BOMFormat, bom.SpecVersion, bom.Version)
+	}
+	if !regexp.MustCompile(`^urn:uuid:[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`).MatchString(bom.SerialNumber) {
+		t.Errorf("serialNumber = %q, want a version 4 UUID URN", bom.SerialNumber)
+	}
+	if tools := bom.Metadata.Tools.Components; len(tools) != 1 || tools[0].Name != "txtr" || tools[0].Version != "1.2.3" {
+		t.Errorf("metadata tools = %+v", tools)
+	}
+
+	if len(bom.Components) != 2 {
+		t.Fatalf("got %d components, want 2: %+v", len(bom.Components), bom.Components)
+	}
+
+	zlib := bom.Components[0]
+	if zlib.Type != "library" || zlib.Name != "zlib" || zlib.Version != "1.2.13" || zlib.BOMRef != "zlib@1.2.13" {
+		t.Errorf("components[0] = %+v", zlib)
+	}
+	if zlib.Evidence == nil || len(zlib.Evidence.Occurrences) != 2 {
+		t.Fatalf("zlib evidence = %+v, want an occurrence per file", zlib.Evidence)
+	}
+	if occurrence := zlib.Evidence.Occurrences[1]; occurrence.Location != "b.bin" || occurrence.Offset != 0x30 {
+		t.Errorf("zlib occurrences[1] = %+v", occurrence)
+	}
+
+	linux := bom.Components[1]
+	if linux.Type != "operating-system" || linux.CPE != "cpe:2.3:o:linux:linux_kernel:5.10.110:*:*:*:*:*:*:*" {
+		t.Errorf("components[1] = %+v", linux)
+	}
+}
+
+// TestJSONPrinterFlushCycloneDXEmpty tests that a BOM without components has an empty array
+func TestJSONPrinterFlushCycloneDXEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	jp := NewJSONPrinter(extractor.Config{MinLength: 4}, &buf)
+	jp.SetFileInfo("a.bin", "", nil)
+	jp.PrintString([]byte("hello"), "a.bin", 0, extractor.Config{})
+
+	if err := jp.FlushCycloneDX("dev"); err != nil {
+		t.Fatalf("FlushCycloneDX() error = %v", err)
+	}
+	if !bytes.Contains(buf.Bytes(), []byte(`"components": []`)) {
+		t.Errorf("output missing empty components array:\n%s", buf.String())
+	}
+}
@@ -0,0 +1,120 @@
+package printer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+)
+
+// ghidraScript is the body of the script written by FlushGhidra. It runs with
+// the Jython and PyGhidra interpreters, so it sticks to Python 2/3 syntax.
+const ghidraScript = `
+
+def main():
+    program = currentProgram.getName()
+    memory = currentProgram.getMemory()
+    files = set(entry[0] for entry in STRINGS)
+    marked = 0
+    for name, offset, value in STRINGS:
+        # Strings of several files: only those of the open program apply
+        if len(files) > 1 and name != program:
+            continue
+        for address in memory.locateAddressesForFileOffset(offset):
+            createBookmark(address, "txtr", value)
+            if getEOLComment(address) is None:
+                setEOLComment(address, "txtr: " + value)
+            marked += 1
+    print("txtr: bookmarked %d of %d strings" % (marked, len(STRINGS)))
+
+
+main()
+`
+
+// idaPythonScript is the body of the script written by FlushIDAPython
+const idaPythonScript = `
+
+def main():
+    program = ida_nalt.get_root_filename()
+    files = set(entry[0] for entry in STRINGS)
+    marked = 0
+    for name, offset, value in STRINGS:
+        # Strings of several files: only those of the open database apply
+        if len(files) > 1 and name != program:
+            continue
+        ea = ida_loader.get_fileregion_ea(offset)
+        if ea == idaapi.BADADDR:
+            continue
+        if not idc.get_cmt(ea, 0):
+            idc.set_cmt(ea, "txtr: " + value, 0)
+        marked += 1
+    print("txtr: commented %d of %d strings" % (marked, len(STRINGS)))
+
+
+main()
+`
+
+// FlushGhidra outputs all collected strings as a Ghidra Python script that
+// bookmarks each string (category "txtr") and adds an end-of-line comment
+// with its value, unless the address already has one. The script maps file
+// offsets to addresses when it runs, so strings outside the loaded image are
+// skipped. With several files, only the strings of the file with the same
+// name as the open program are applied.
+func (jp *JSONPrinter) FlushGhidra(toolVersion string) error {
+	header := "# Bookmarks the strings found by txtr " + toolVersion + " in the current program.\n" +
+		"# Run it from the Script Manager with the scanned binary open.\n" +
+		"# @category: txtr\n"
+	return jp.flushScript(header, ghidraScript)
+}
+
+// FlushIDAPython outputs all collected strings as an IDAPython script that
+// adds a comment with each string's value at its address, unless the address
+// already has one. Like FlushGhidra, file offsets are mapped to addresses when
+// the script runs.
+func (jp *JSONPrinter) FlushIDAPython(toolVersion string) error {
+	header := "# Comments the strings found by txtr " + toolVersion + " in the current database.\n" +
+		"# Run it with File > Script file... with the scanned binary open.\n" +
+		"\nimport ida_loader\nimport ida_nalt\nimport idaapi\nimport idc\n"
+	return jp.flushScript(header, idaPythonScript)
+}
+
+// flushScript writes a Python script: header, the STRINGS table of (file name,
+// file offset, value) entries in file order, then body. Files that failed are
+// omitted; relocation strings are not included.
+func (jp *JSONPrinter) flushScript(header, body string) error {
+	// Finalize any remaining current file
+	if jp.currentFile != "" || len(jp.currentStrings) > 0 {
+		jp.FinalizeCurrentFile()
+	}
+
+	w := bufio.NewWriter(jp.writer)
+	_, _ = io.WriteString(w, header)
+	_, _ = io.WriteString(w, "\nSTRINGS = [\n")
+
+	err := jp.forEachString(func(fileResult FileResult, result StringResult) error {
+		file := result.File
+		if file == "" {
+			file = fileResult.File
+		}
+		// Disassemblers name programs after the file they were imported from
+		if file != "" {
+			file = filepath.Base(file)
+		}
+		_, err := fmt.Fprintf(w, "    (%s, 0x%x, %s),\n", pythonString(file), result.Offset, pythonString(result.Value))
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, _ = io.WriteString(w, "]\n")
+	_, _ = io.WriteString(w, body)
+	return w.Flush()
+}
+
+// pythonString returns s as a Python unicode literal. Go's ASCII quoting only
+// produces escapes Python understands (\n, \xNN, \uNNNN, \UNNNNNNNN...).
+func pythonString(s string) string {
+	return "u" + strconv.QuoteToASCII(s)
+}
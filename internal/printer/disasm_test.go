@@ -0,0 +1,62 @@
+package printer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestJSONPrinterFlushScripts tests the string tables of the disassembler scripts
+func TestJSONPrinterFlushScripts(t *testing.T) {
+	tests := []struct {
+		name  string
+		flush func(*JSONPrinter) error
+		want  []string
+	}{
+		{"ghidra", func(jp *JSONPrinter) error { return jp.FlushGhidra("1.2.3") }, []string{
+			"txtr 1.2.3",
+			"# @category: txtr",
+			"createBookmark(address, \"txtr\", value)",
+		}},
+		{"idapython", func(jp *JSONPrinter) error { return jp.FlushIDAPython("1.2.3") }, []string{
+			"txtr 1.2.3",
+			"ida_loader.get_fileregion_ea(offset)",
+			"idc.set_cmt(ea, \"txtr: \" + value, 0)",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			config := extractor.Config{MinLength: 4, Encoding: "s"}
+			jp := NewJSONPrinter(config, &buf)
+
+			jp.SetFileInfo("/samples/a.bin", "ELF", []string{".rodata"})
+			jp.PrintString([]byte("hello"), "/samples/a.bin", 0x10, config)
+			jp.PrintString([]byte("say \"hi\"\n\\ caf\xc3\xa9"), "/samples/a.bin", 0x20, config)
+			jp.SetFileInfo("b.bin", "", nil)
+			jp.PrintString([]byte("world"), "b.bin", 0, config)
+
+			if err := tt.flush(jp); err != nil {
+				t.Fatalf("flush error = %v", err)
+			}
+
+			out := buf.String()
+			table := "STRINGS = [\n" +
+				"    (u\"a.bin\", 0x10, u\"hello\"),\n" +
+				"    (u\"a.bin\", 0x20, u\"say \\\"hi\\\"\\n\\\\ caf\\u00e9\"),\n" +
+				"    (u\"b.bin\", 0x0, u\"world\"),\n" +
+				"]\n"
+			if !strings.Contains(out, table) {
+				t.Errorf("output does not contain the string table\n%s\ngot:\n%s", table, out)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out, want) {
+					t.Errorf("output does not contain %q", want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+package printer
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/richardwooding/txtr/internal/notify"
+	"github.com/richardwooding/txtr/internal/rank"
+)
+
+// Limits on relationship graphs, which are drawn rather than processed
+const (
+	graphMaxArtifacts = 200 // Shared artifacts drawn, those shared by the most files first
+	graphLabelLen     = 60  // Characters of an artifact shown in its node
+)
+
+// graphColors are the node colors of the kinds of artifacts
+var graphColors = map[string]string{
+	"secret":       "#f4b6b6",
+	"url":          "#b6d4f4",
+	"domain":       "#b6e3f4",
+	"command":      "#f4d9b6",
+	"email":        "#d9f4b6",
+	"ip address":   "#c9b6f4",
+	"registry key": "#f4f0b6",
+	"user agent":   "#e0e0e0",
+	"path":         "#e6d2c3",
+}
+
+// relationGraph links files to the notable artifacts they share (URLs,
+// domains, keys, ...; see rank.Extract)
+type relationGraph struct {
+	files     []string
+	artifacts []sharedArtifact // Artifacts found in at least two files
+	omitted   int              // Shared artifacts past graphMaxArtifacts
+}
+
+// sharedArtifact is an artifact with the files it was found in
+type sharedArtifact struct {
+	rank.Artifact
+	files []int // Indices of the files, ascending
+}
+
+// label returns the text shown for the artifact: its value, masked for
+// secrets and shortened
+func (a sharedArtifact) label() string {
+	value := a.Value
+	if a.Kind == "secret" {
+		value = notify.Mask(value)
+	}
+	if runes := []rune(value); len(runes) > graphLabelLen {
+		value = string(runes[:graphLabelLen-1]) + "…"
+	}
+	return value
+}
+
+// collectGraph collects the artifacts of all files and keeps those found in
+// more than one
+func (jp *JSONPrinter) collectGraph() (relationGraph, error) {
+	// Finalize any remaining current file
+	if jp.currentFile != "" || len(jp.currentStrings) > 0 {
+		jp.FinalizeCurrentFile()
+	}
+
+	var graph relationGraph
+	found := map[rank.Artifact][]int{}
+	current := -1
+	err := jp.forEachFileString(func(i int, fileResult FileResult) error {
+		current = i
+		graph.files = append(graph.files, cmp.Or(fileResult.File, "{standard input}"))
+		return nil
+	}, func(_ FileResult, result StringResult) error {
+		for _, artifact := range rank.Extract([]byte(result.Value)) {
+			files := found[artifact]
+			if len(files) == 0 || files[len(files)-1] != current {
+				found[artifact] = append(files, current)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return relationGraph{}, err
+	}
+
+	for artifact, files := range found {
+		if len(files) > 1 {
+			graph.artifacts = append(graph.artifacts, sharedArtifact{Artifact: artifact, files: files})
+		}
+	}
+	slices.SortFunc(graph.artifacts, func(a, b sharedArtifact) int {
+		return cmp.Or(cmp.Compare(len(b.files), len(a.files)), cmp.Compare(a.Kind, b.Kind), cmp.Compare(a.Value, b.Value))
+	})
+	if len(graph.artifacts) > graphMaxArtifacts {
+		graph.omitted = len(graph.artifacts) - graphMaxArtifacts
+		graph.artifacts = graph.artifacts[:graphMaxArtifacts]
+	}
+	return graph, nil
+}
+
+// kinds returns the kinds of the artifacts of the graph, sorted
+func (g relationGraph) kinds() []string {
+	var kinds []string
+	for _, artifact := range g.artifacts {
+		if !slices.Contains(kinds, artifact.Kind) {
+			kinds = append(kinds, artifact.Kind)
+		}
+	}
+	slices.Sort(kinds)
+	return kinds
+}
+
+// writeComment writes what the graph shows, as a comment starting with
+// prefix
+func (g relationGraph) writeComment(w io.Writer, prefix, toolVersion string) {
+	_, _ = fmt.Fprintf(w, "%s txtr %s: %d files, %d shared artifacts", prefix, toolVersion, len(g.files), len(g.artifacts))
+	if g.omitted > 0 {
+		_, _ = fmt.Fprintf(w, " (%d more not drawn)", g.omitted)
+	}
+	_, _ = fmt.Fprintln(w)
+}
+
+// FlushDOT outputs a Graphviz graph of the collected files and the notable
+// artifacts (URLs, domains, keys, ...) found in more than one of them, to
+// render with e.g. dot -Tsvg. Secrets are masked.
+func (jp *JSONPrinter) FlushDOT(toolVersion string) error {
+	graph, err := jp.collectGraph()
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(jp.writer)
+	graph.writeComment(w, "//", toolVersion)
+	_, _ = fmt.Fprintf(w, "graph txtr {\n  rankdir=LR;\n  node [fontname=\"Helvetica\", fontsize=10];\n")
+	for i, file := range graph.files {
+		_, _ = fmt.Fprintf(w, "  f%d [label=%s, shape=box];\n", i, dotQuote(file))
+	}
+	for i, artifact := range graph.artifacts {
+		_, _ = fmt.Fprintf(w, "  a%d [label=%s, tooltip=%s, shape=ellipse, style=filled, fillcolor=%s];\n",
+			i, dotQuote(artifact.label()), dotQuote(artifact.Kind), dotQuote(cmp.Or(graphColors[artifact.Kind], "#ffffff")))
+	}
+	for i, artifact := range graph.artifacts {
+		for _, file := range artifact.files {
+			_, _ = fmt.Fprintf(w, "  f%d -- a%d;\n", file, i)
+		}
+	}
+	_, _ = fmt.Fprintf(w, "}\n")
+	return w.Flush()
+}
+
+// dotQuote returns s as a DOT string
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`).Replace(s) + `"`
+}
+
+// FlushMermaid outputs a Mermaid flowchart of the collected files and the
+// notable artifacts (URLs, domains, keys, ...) found in more than one of
+// them, for reports rendering Mermaid (GitHub, GitLab, ...). Secrets are
+// masked.
+func (jp *JSONPrinter) FlushMermaid(toolVersion string) error {
+	graph, err := jp.collectGraph()
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(jp.writer)
+	graph.writeComment(w, "%%", toolVersion)
+	_, _ = fmt.Fprintf(w, "flowchart LR\n")
+	for i, file := range graph.files {
+		_, _ = fmt.Fprintf(w, "  f%d[\"%s\"]\n", i, mermaidEscape(file))
+	}
+	for i, artifact := range graph.artifacts {
+		_, _ = fmt.Fprintf(w, "  a%d([\"%s\"]):::%s\n", i, mermaidEscape(artifact.label()), mermaidClass(artifact.Kind))
+	}
+	for i, artifact := range graph.artifacts {
+		for _, file := range artifact.files {
+			_, _ = fmt.Fprintf(w, "  f%d --- a%d\n", file, i)
+		}
+	}
+	for _, kind := range graph.kinds() {
+		_, _ = fmt.Fprintf(w, "  classDef %s fill:%s\n", mermaidClass(kind), cmp.Or(graphColors[kind], "#ffffff"))
+	}
+	return w.Flush()
+}
+
+// mermaidEscape escapes the text of a quoted Mermaid label with entity codes
+func mermaidEscape(s string) string {
+	return strings.NewReplacer("#", "#35;", `"`, "#quot;", "<", "#lt;", ">", "#gt;", "`", "#96;", "\n", " ", "\r", " ").Replace(s)
+}
+
+// mermaidClass returns the class name of a kind of artifact
+func mermaidClass(kind string) string {
+	return strings.ReplaceAll(kind, " ", "_")
+}
@@ -0,0 +1,278 @@
+package printer
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"html"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/rank"
+)
+
+// htmlStyle is the stylesheet of the report written by FlushHTML. The
+// summary is written last, as charts are only complete once every string has
+// been seen, and shown first by its flex order.
+const htmlStyle = `
+body { margin: 0; font: 14px/1.4 system-ui, sans-serif; color: #1d232a; background: #f5f6f8; }
+main { display: flex; flex-direction: column; gap: 16px; max-width: 1200px; margin: 0 auto; padding: 16px; }
+.summary { order: -1; }
+section, .summary { background: #fff; border: 1px solid #d8dce1; border-radius: 6px; padding: 12px 16px; }
+h1 { font-size: 20px; margin: 0 0 4px; }
+h2 { font-size: 16px; margin: 0 0 8px; word-break: break-all; }
+h3 { font-size: 13px; margin: 0 0 6px; text-transform: uppercase; color: #5b6570; }
+.meta { color: #5b6570; margin: 0 0 12px; }
+.cards { display: flex; flex-wrap: wrap; gap: 12px; margin-bottom: 12px; }
+.card { border: 1px solid #d8dce1; border-radius: 6px; padding: 8px 12px; min-width: 110px; }
+.card b { display: block; font-size: 20px; }
+.charts { display: grid; grid-template-columns: repeat(auto-fit, minmax(320px, 1fr)); gap: 16px; }
+.bar { display: grid; grid-template-columns: 140px 1fr 70px; align-items: center; gap: 6px; margin: 2px 0; }
+.bar span:first-child { overflow: hidden; text-overflow: ellipsis; white-space: nowrap; }
+.bar i { display: block; height: 12px; background: #3d7dca; border-radius: 2px; min-width: 1px; }
+.bar span:last-child { text-align: right; font-variant-numeric: tabular-nums; }
+.toolbar { position: sticky; top: 0; z-index: 1; display: flex; gap: 8px; align-items: center; padding: 8px 16px; background: #1d232a; color: #fff; }
+.toolbar input { flex: 1; max-width: 480px; padding: 4px 8px; }
+dl { display: grid; grid-template-columns: max-content 1fr; gap: 2px 12px; margin: 0 0 8px; }
+dt { color: #5b6570; }
+dd { margin: 0; word-break: break-all; }
+.error { color: #b3261e; }
+.warning { color: #8a5a00; }
+table { width: 100%; border-collapse: collapse; font-size: 13px; }
+th { position: sticky; top: 40px; background: #eef0f3; text-align: left; cursor: pointer; user-select: none; padding: 4px 6px; }
+th[data-dir=asc]::after { content: " \25B2"; }
+th[data-dir=desc]::after { content: " \25BC"; }
+td { padding: 2px 6px; border-top: 1px solid #eef0f3; vertical-align: top; }
+td:nth-child(-n+2) { font-variant-numeric: tabular-nums; white-space: nowrap; }
+td:last-child { font-family: ui-monospace, monospace; white-space: pre-wrap; word-break: break-all; }
+tr:target { background: #fff3c4; }
+.count { color: #5b6570; font-size: 13px; }
+`
+
+// htmlScript sorts a table by the column clicked and filters the rows of all
+// tables by the text of the filter box
+const htmlScript = `
+(function () {
+  function key(row, column, numeric) {
+    var cell = row.cells[column];
+    return numeric ? Number(cell.dataset.value) : cell.textContent.toLowerCase();
+  }
+  document.querySelectorAll("table.strings th").forEach(function (th) {
+    th.addEventListener("click", function () {
+      var table = th.closest("table"), body = table.tBodies[0];
+      var numeric = th.dataset.type === "number", column = th.cellIndex;
+      var dir = th.dataset.dir === "asc" ? "desc" : "asc";
+      table.querySelectorAll("th").forEach(function (h) { delete h.dataset.dir; });
+      th.dataset.dir = dir;
+      var rows = Array.prototype.slice.call(body.rows);
+      rows.sort(function (a, b) {
+        var x = key(a, column, numeric), y = key(b, column, numeric);
+        return (x < y ? -1 : x > y ? 1 : 0) * (dir === "asc" ? 1 : -1);
+      });
+      var fragment = document.createDocumentFragment();
+      rows.forEach(function (row) { fragment.appendChild(row); });
+      body.appendChild(fragment);
+    });
+  });
+  var filter = document.getElementById("filter"), timer;
+  filter.addEventListener("input", function () {
+    clearTimeout(timer);
+    timer = setTimeout(function () {
+      var query = filter.value.toLowerCase();
+      document.querySelectorAll("table.strings").forEach(function (table) {
+        var shown = 0, rows = table.tBodies[0].rows;
+        for (var i = 0; i < rows.length; i++) {
+          var match = query === "" || rows[i].textContent.toLowerCase().indexOf(query) >= 0;
+          rows[i].hidden = !match;
+          if (match) shown++;
+        }
+        var count = table.closest("section").querySelector(".count");
+        count.textContent = query === "" ? "" : shown + " of " + rows.length + " strings match";
+      });
+    }, 150);
+  });
+})();
+`
+
+// htmlLengthBuckets are the upper bounds of the string length chart's bars
+var htmlLengthBuckets = []int{7, 15, 31, 63, 127, 255}
+
+// htmlChartBars limits the bars of a chart (files with the most strings)
+const htmlChartBars = 20
+
+// htmlBar is a bar of a chart of the HTML report
+type htmlBar struct {
+	label string
+	count int
+}
+
+// FlushHTML outputs all collected strings as a standalone HTML report: a
+// summary with charts of the encodings, lengths and artifacts of the strings
+// and of the strings per file, then a section per file with its metadata,
+// errors and warnings and a table of its strings. Tables sort by the column
+// clicked and a filter box hides the rows not containing its text; all
+// styles and scripts are embedded. Each string has an anchor (#f<file
+// index>-<hex offset>) for links to it.
+func (jp *JSONPrinter) FlushHTML(toolVersion string) error {
+	// Finalize any remaining current file
+	if jp.currentFile != "" || len(jp.currentStrings) > 0 {
+		jp.FinalizeCurrentFile()
+	}
+
+	w := bufio.NewWriter(jp.writer)
+	write := func(format string, args ...any) {
+		_, _ = fmt.Fprintf(w, format, args...)
+	}
+	esc := html.EscapeString
+
+	write("<!DOCTYPE html>\n<html lang=\"en\">\n<head>\n<meta charset=\"utf-8\">\n")
+	write("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	write("<meta name=\"generator\" content=\"txtr %s\">\n<title>txtr report</title>\n", esc(toolVersion))
+	write("<style>%s</style>\n</head>\n<body>\n", htmlStyle)
+	write("<div class=\"toolbar\"><b>txtr</b><input id=\"filter\" type=\"search\" placeholder=\"Filter strings\" aria-label=\"Filter strings\"></div>\n<main>\n")
+
+	encodings := map[string]int{}
+	artifacts := map[string]int{}
+	lengths := make([]int, len(htmlLengthBuckets)+1)
+	perFile := make([]htmlBar, len(jp.FileResults))
+	var totalStrings, failed, warnings int
+	var totalBytes int64
+	current := -1
+
+	endFile := func() {
+		if current >= 0 && len(jp.FileResults[current].Strings)+jp.FileResults[current].spilled > 0 {
+			write("</tbody>\n</table>\n")
+		}
+		if current >= 0 {
+			write("</section>\n")
+		}
+	}
+
+	err := jp.forEachFileString(func(i int, fileResult FileResult) error {
+		endFile()
+		current = i
+		name := fileResult.File
+		if name == "" {
+			name = "{standard input}"
+		}
+		perFile[i].label = name
+		warnings += len(fileResult.Warnings)
+
+		write("<section id=\"f%d\">\n<h2>%s</h2>\n<dl>\n", i, esc(name))
+		if link := fileURL(fileResult.File); fileResult.File != "" && link != "" {
+			write("<dt>Location</dt><dd><a href=\"%s\">%s</a></dd>\n", esc(link), esc(link))
+		}
+		if fileResult.Format != "" {
+			write("<dt>Format</dt><dd>%s</dd>\n", esc(fileResult.Format))
+		}
+		if len(fileResult.Sections) > 0 {
+			write("<dt>Sections</dt><dd>%s</dd>\n", esc(strings.Join(fileResult.Sections, ", ")))
+		}
+		if fileResult.Packed && fileResult.Packing != nil {
+			write("<dt>Packed</dt><dd>%s</dd>\n", esc(cmp.Or(fileResult.Packing.Packer, fileResult.Packing.Reason, "yes")))
+		}
+		for _, component := range fileResult.Components {
+			write("<dt>Component</dt><dd>%s %s (<a href=\"#f%d-%s\">%s</a>)</dd>\n", esc(component.Name), esc(component.Version), i, component.OffsetHex, component.OffsetHex)
+		}
+		for _, duplicate := range jp.config.Duplicates[fileResult.File] {
+			write("<dt>Duplicate</dt><dd>%s</dd>\n", esc(duplicate))
+		}
+		write("<dt>Strings</dt><dd>%d</dd>\n</dl>\n", len(fileResult.Strings)+fileResult.spilled)
+		if fileResult.Error != "" {
+			failed++
+			write("<p class=\"error\">Error: %s</p>\n", esc(fileResult.Error))
+		}
+		for _, warning := range fileResult.Warnings {
+			write("<p class=\"warning\">%s (%s): %s</p>\n", esc(warning.Severity.String()), esc(warning.Code), esc(warning.Message))
+		}
+		if len(fileResult.Strings)+fileResult.spilled > 0 {
+			write("<p class=\"count\"></p>\n<table class=\"strings\">\n<thead><tr><th data-type=\"number\">Offset</th><th data-type=\"number\">Length</th><th>Encoding</th><th>Section</th><th>Value</th></tr></thead>\n<tbody>\n")
+		} else {
+			write("<p class=\"count\"></p>\n")
+		}
+		return nil
+	}, func(_ FileResult, result StringResult) error {
+		totalStrings++
+		totalBytes += int64(result.Length)
+		perFile[current].count++
+		encodings[result.Encoding]++
+		bucket, _ := slices.BinarySearch(htmlLengthBuckets, result.Length)
+		lengths[bucket]++
+		for _, kind := range rank.Categories([]byte(result.Value)) {
+			artifacts[kind]++
+		}
+
+		id := fmt.Sprintf("f%d-%s", current, result.OffsetHex)
+		write("<tr id=\"%s\"><td data-value=\"%d\"><a href=\"#%s\">%s</a></td><td data-value=\"%d\">%d</td><td>%s</td><td>%s</td><td>%s</td></tr>\n",
+			id, result.Offset, id, result.OffsetHex, result.Length, result.Length, esc(result.Encoding), esc(result.Section), esc(result.Value))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	endFile()
+
+	// The summary, shown first (see htmlStyle)
+	write("<div class=\"summary\">\n<h1>txtr report</h1>\n<p class=\"meta\">Generated %s by txtr %s, minimum length %d</p>\n",
+		esc(time.Now().Format(time.RFC1123)), esc(toolVersion), jp.config.MinLength)
+	write("<div class=\"cards\">")
+	for _, card := range []htmlBar{{"Files", len(jp.FileResults)}, {"Strings", totalStrings}, {"Failed", failed}, {"Warnings", warnings}} {
+		write("<div class=\"card\"><b>%d</b>%s</div>", card.count, card.label)
+	}
+	write("<div class=\"card\"><b>%s</b>Bytes of strings</div></div>\n", strconv.FormatInt(totalBytes, 10))
+
+	write("<div class=\"charts\">\n")
+	writeHTMLChart(w, "Encodings", sortedBars(encodings))
+	lengthBars := make([]htmlBar, len(lengths))
+	low := 0
+	for i, count := range lengths {
+		switch {
+		case i == len(htmlLengthBuckets):
+			lengthBars[i] = htmlBar{fmt.Sprintf("%d+", low), count}
+		default:
+			lengthBars[i] = htmlBar{fmt.Sprintf("%d-%d", low, htmlLengthBuckets[i]), count}
+			low = htmlLengthBuckets[i] + 1
+		}
+	}
+	writeHTMLChart(w, "Lengths", lengthBars)
+	writeHTMLChart(w, "Artifacts", sortedBars(artifacts))
+	if len(perFile) > 1 {
+		files := slices.Clone(perFile)
+		slices.SortStableFunc(files, func(a, b htmlBar) int { return cmp.Compare(b.count, a.count) })
+		writeHTMLChart(w, "Strings per file", files[:min(len(files), htmlChartBars)])
+	}
+	write("</div>\n</div>\n</main>\n<script>%s</script>\n</body>\n</html>\n", htmlScript)
+	return w.Flush()
+}
+
+// sortedBars returns the bars of counts by label, largest first
+func sortedBars(counts map[string]int) []htmlBar {
+	bars := make([]htmlBar, 0, len(counts))
+	for label, count := range counts {
+		bars = append(bars, htmlBar{label, count})
+	}
+	slices.SortFunc(bars, func(a, b htmlBar) int {
+		return cmp.Or(cmp.Compare(b.count, a.count), cmp.Compare(a.label, b.label))
+	})
+	return bars
+}
+
+// writeHTMLChart writes a horizontal bar chart, scaled to its largest bar
+func writeHTMLChart(w *bufio.Writer, title string, bars []htmlBar) {
+	_, _ = fmt.Fprintf(w, "<div>\n<h3>%s</h3>\n", html.EscapeString(title))
+	largest := 0
+	for _, bar := range bars {
+		largest = max(largest, bar.count)
+	}
+	if largest == 0 {
+		_, _ = fmt.Fprintf(w, "<p class=\"meta\">None</p>\n</div>\n")
+		return
+	}
+	for _, bar := range bars {
+		_, _ = fmt.Fprintf(w, "<div class=\"bar\"><span title=\"%[1]s\">%[1]s</span><span><i style=\"width:%.1[2]f%%\"></i></span><span>%[3]d</span></div>\n",
+			html.EscapeString(bar.label), float64(bar.count)*100/float64(largest), bar.count)
+	}
+	_, _ = fmt.Fprintf(w, "</div>\n")
+}
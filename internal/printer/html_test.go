@@ -0,0 +1,93 @@
+package printer
+
+import (
+	"bytes"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestJSONPrinterFlushHTML tests the HTML report of collected strings
+func TestJSONPrinterFlushHTML(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+	jp := NewJSONPrinter(config, &buf)
+
+	jp.SetFileInfo("a.bin", "ELF", []string{".rodata"})
+	jp.PrintString([]byte("<script>alert(1)</script>"), "a.bin", 16, config)
+	jp.PrintString([]byte("https://example.com/"), "a.bin", 64, config)
+	jp.SetFileInfo("b.bin", "", nil)
+	jp.PrintString([]byte("world"), "b.bin", 0, config)
+	jp.FinalizeCurrentFile()
+	jp.AddFileResult("missing.bin", "", nil, nil, errors.New("no such file"))
+
+	if err := jp.FlushHTML("1.2.3"); err != nil {
+		t.Fatalf("FlushHTML() error = %v", err)
+	}
+	report := buf.String()
+
+	for _, want := range []string{
+		"<!DOCTYPE html>",
+		`<section id="f0">`,
+		`<section id="f1">`,
+		`<section id="f2">`,
+		`<tr id="f0-0x10">`,
+		`<a href="#f0-0x40">0x40</a>`,
+		`<tr id="f1-0x0">`,
+		"&lt;script&gt;alert(1)&lt;/script&gt;",
+		"<dd>ELF</dd>",
+		"Error: no such file",
+		`id="filter"`,
+		"<h3>Encodings</h3>",
+		"<h3>Lengths</h3>",
+		"<h3>Artifacts</h3>",
+		"<h3>Strings per file</h3>",
+		"by txtr 1.2.3",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report does not contain %q", want)
+		}
+	}
+	if strings.Contains(report, "<script>alert") {
+		t.Error("string values are not escaped")
+	}
+	if strings.Contains(report, "%!") {
+		t.Error("report contains a formatting error")
+	}
+	if strings.Contains(report, "<link") || strings.Contains(report, " src=") {
+		t.Error("report refers to external assets")
+	}
+	if got := strings.Count(report, `<table class="strings">`); got != 2 {
+		t.Errorf("got %d string tables, want 2 (none for the failed file)", got)
+	}
+}
+
+// TestJSONPrinterFlushHTMLSpilled tests that the HTML report is unchanged
+// when strings are spilled to disk
+func TestJSONPrinterFlushHTMLSpilled(t *testing.T) {
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+	generated := regexp.MustCompile(`Generated [^<]* by`)
+
+	run := func(limit int64) string {
+		var buf bytes.Buffer
+		jp := NewJSONPrinter(config, &buf)
+		jp.SetMemoryLimit(limit)
+		for _, file := range []string{"a.bin", "b.bin"} {
+			jp.SetFileInfo(file, "", nil)
+			for i := range 50 {
+				jp.PrintString([]byte("string number"), file, int64(i*16), config)
+			}
+		}
+		if err := jp.FlushHTML("dev"); err != nil {
+			t.Fatalf("FlushHTML() error = %v", err)
+		}
+		return generated.ReplaceAllString(buf.String(), "")
+	}
+
+	if unlimited, spilled := run(0), run(500); unlimited != spilled {
+		t.Error("HTML report differs when strings are spilled")
+	}
+}
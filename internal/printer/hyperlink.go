@@ -0,0 +1,62 @@
+package printer
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/richardwooding/txtr/internal/console"
+	"github.com/richardwooding/txtr/internal/winpath"
+)
+
+// fileURLs caches the file:// URL of each file name (see fileURL)
+var fileURLs sync.Map
+
+// ShouldUseHyperlinks reports whether OSC 8 hyperlinks can be written: stdout
+// is a terminal interpreting escape sequences, and not one declaring itself as
+// dumb. Terminals without OSC 8 support ignore the sequences.
+func ShouldUseHyperlinks() bool {
+	return isTerminal(os.Stdout) && console.VirtualTerminal() && os.Getenv("TERM") != "dumb"
+}
+
+// Hyperlink wraps text in an OSC 8 hyperlink to the file at path. Text is
+// returned unchanged if the path cannot be made absolute.
+func Hyperlink(text, path string) string {
+	link := fileURL(path)
+	if link == "" {
+		return text
+	}
+	return "\x1b]8;;" + link + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// fileURL returns the file:// URL of a path, including the host name so that
+// terminals can tell local files from files on remote hosts
+func fileURL(path string) string {
+	if link, ok := fileURLs.Load(path); ok {
+		return link.(string)
+	}
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return ""
+	}
+	host, _ := os.Hostname()
+	link := absFileURL(filepath.ToSlash(winpath.Short(abs)), host)
+
+	fileURLs.Store(path, link)
+	return link
+}
+
+// absFileURL returns the file:// URL of an absolute path on host. Windows UNC
+// paths (\\server\share\dir) link to the share's server instead.
+func absFileURL(abs, host string) string {
+	if server, rest, ok := winpath.SplitUNC(abs); ok {
+		host, abs = server, strings.ReplaceAll(rest, `\`, "/")
+	}
+	if !strings.HasPrefix(abs, "/") {
+		abs = "/" + abs // Windows drive letter
+	}
+	return (&url.URL{Scheme: "file", Host: host, Path: abs}).String()
+}
@@ -0,0 +1,51 @@
+package printer
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestHyperlink tests OSC 8 hyperlinks to files
+func TestHyperlink(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "my file#1.bin")
+
+	got := Hyperlink("name", path)
+	host, _ := os.Hostname()
+	wantPrefix := "\x1b]8;;file://" + host + "/"
+	if !strings.HasPrefix(got, wantPrefix) || !strings.HasSuffix(got, "\x1b\\name\x1b]8;;\x1b\\") {
+		t.Fatalf("Hyperlink() = %q, want an OSC 8 link around name", got)
+	}
+	if !strings.Contains(got, "my%20file%231.bin") {
+		t.Errorf("Hyperlink() = %q, want the path percent-encoded", got)
+	}
+
+	// Relative paths are resolved against the working directory
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if link := fileURL("rel.bin"); !strings.HasSuffix(link, filepath.ToSlash(filepath.Join(wd, "rel.bin"))) {
+		t.Errorf("fileURL(rel.bin) = %q, want it under %s", link, wd)
+	}
+}
+
+// TestAbsFileURL tests file:// URLs of Windows drive and UNC paths
+func TestAbsFileURL(t *testing.T) {
+	tests := []struct {
+		abs  string
+		want string
+	}{
+		{"/home/user/a.bin", "file://host/home/user/a.bin"},
+		{"C:/fw/a.bin", "file://host/C:/fw/a.bin"},
+		{`\\nas\share\fw\a.bin`, "file://nas/share/fw/a.bin"},
+		{"//nas/share/a.bin", "file://nas/share/a.bin"},
+	}
+	for _, tt := range tests {
+		if got := absFileURL(tt.abs, "host"); got != tt.want {
+			t.Errorf("absFileURL(%q) = %q, want %q", tt.abs, got, tt.want)
+		}
+	}
+}
@@ -3,12 +3,25 @@
 package printer
 
 import (
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/fingerprint"
+)
+
+// Encodings of string values other than text (see extractor.Config.ValueEncoding)
+const (
+	ValueBase64 = "base64"
+	ValueHex    = "hex"
 )
 
 // StringResult represents a single extracted string in JSON format
@@ -19,7 +32,56 @@ type StringResult struct {
 	OffsetHex string `json:"offset_hex"`
 	Length    int    `json:"length"`
 	Encoding  string `json:"encoding"`
-	Section   string `json:"section,omitempty"`
+	// How Value holds the bytes of the string if not as text: "base64" or "hex" (--value-encoding)
+	ValueEncoding string `json:"value_encoding,omitempty"`
+	// Bytes of the string in the input, base64 encoded, whatever its display (--emit-raw)
+	Raw     string `json:"raw,omitempty"`
+	Section string `json:"section,omitempty"`
+	// Byte order, alignment and byte order mark of a UTF-16 or UTF-32 string (--wide-info)
+	Wide *WideInfo `json:"wide,omitempty"`
+	// Line number of the string in a plain text input (--lines)
+	Line int64 `json:"line,omitempty"`
+	// Rarity of the string in the corpus, 0 (in every sample) to 1 (in none) (--background)
+	Rarity *float64 `json:"rarity,omitempty"`
+	// Type of embedded script the string holds (--scripts)
+	ScriptType string `json:"script_type,omitempty"`
+	// printf-style directives of a format string (--format-strings)
+	FormatDirectives []string `json:"format_directives,omitempty"`
+	// Rule of the secrets preset the string matched, e.g. "aws-access-key-id" (--secrets)
+	SecretRule string `json:"secret_rule,omitempty"`
+	// Code addresses that reference this string (see SetReferenceResolver)
+	ReferencedFrom []string `json:"referenced_from,omitempty"`
+	// Number of pointers to this string from other sections (see SetXrefCounter)
+	XrefCount *int `json:"xref_count,omitempty"`
+	// Function containing the code that references this string (see SetFunctionResolver)
+	Function string `json:"function,omitempty"`
+	// Key and value of a registry hive the string was stored in (--registry, see SetArtifactCells)
+	RegistryKey   string `json:"registry_key,omitempty"`
+	RegistryValue string `json:"registry_value,omitempty"`
+	// Event log record the string was stored in (--evtx, see SetArtifactCells)
+	EventRecordID uint64 `json:"event_record_id,omitempty"`
+	EventTime     string `json:"event_time,omitempty"`
+	// Part of a prefetch file the string was stored in (--prefetch, see SetArtifactCells)
+	PrefetchField string `json:"prefetch,omitempty"`
+	// File of an NTFS image whose name or data holds the string (--ntfs, see SetArtifactCells)
+	MFTRecord     *uint64 `json:"mft_record,omitempty"`
+	NTFSPath      string  `json:"ntfs_path,omitempty"`
+	NTFSAttribute string  `json:"ntfs_attribute,omitempty"`
+	NTFSDeleted   bool    `json:"ntfs_deleted,omitempty"`
+	// Region of a memory image holding the string, and its virtual address (--memory-map, --memory-profile, see SetArtifactCells)
+	MemoryRegion  string `json:"memory_region,omitempty"`
+	MemoryAddress string `json:"memory_address,omitempty"`
+	// Occurrences of the value in the file (--count)
+	Count int `json:"count,omitempty"`
+	// Offsets of every occurrence of the value (--all-offsets)
+	Offsets []int64 `json:"offsets,omitempty"`
+	// Spellings of the value collapsed by case folding (--dedupe-fold-case)
+	Variants []string `json:"variants,omitempty"`
+	// Triage score and what it is based on (--top, see rank.Score)
+	Score   float64  `json:"score,omitempty"`
+	Reasons []string `json:"reasons,omitempty"`
+	// Neighborhood of the string in its file (--cluster-by-offset, see FileResult.Clusters)
+	Cluster int `json:"cluster,omitempty"`
 }
 
 // JSONOutput represents the complete JSON output structure
@@ -28,21 +90,158 @@ type JSONOutput struct {
 	Summary Summary      `json:"summary"`
 }
 
+// TopOutput is the JSON output of --top: the highest-ranked strings of all
+// files, best first
+type TopOutput struct {
+	Top []StringResult `json:"top"`
+}
+
 // FileResult represents results for a single file
 type FileResult struct {
-	File     string         `json:"file,omitempty"`
-	Format   string         `json:"format,omitempty"`
-	Sections []string       `json:"sections,omitempty"`
-	Strings  []StringResult `json:"strings"`
-	Error    string         `json:"error,omitempty"`
+	File         string       `json:"file,omitempty"`
+	Format       string       `json:"format,omitempty"`
+	FormatSource string       `json:"format_source,omitempty"` // "detected", "hint" (-T used for an undetected file) or "recorded" (--rescan)
+	Packed       bool         `json:"packed,omitempty"`
+	Packing      *PackingInfo `json:"packing,omitempty"`
+	Sections     []string     `json:"sections,omitempty"`
+	Strategy     string       `json:"strategy,omitempty"` // How the file was read: "read", "buffered", "mmap" or "chunked" (see extractor.ChooseStrategy)
+	// Decryption status of an encrypted archive member: "decrypted",
+	// "no-password", "wrong-password" or "unsupported" (see archive.Member)
+	Decryption string `json:"decryption,omitempty"`
+	// Other inputs with the same content, which were not scanned (--dedupe-inputs)
+	Duplicates []string `json:"duplicates,omitempty"`
+	// Third-party components identified from version banners (see fingerprint.Identify)
+	Components []Component    `json:"components,omitempty"`
+	Strings    []StringResult `json:"strings"`
+	// Strings pointed to by relocation entries (see PrintRelocString)
+	RelocStrings []StringResult `json:"reloc_strings,omitempty"`
+	Error        string         `json:"error,omitempty"`
+	// Problems that did not stop the scan (see extractor.Warning)
+	Warnings []WarningResult `json:"warnings,omitempty"`
+	// Neighborhoods of strings close to each other (--cluster-by-offset)
+	Clusters []OffsetCluster `json:"clusters,omitempty"`
+	// Embedded filesystems and compressed data (--signatures)
+	Regions []Region `json:"regions,omitempty"`
+	// Extended attributes of the file (--xattrs)
+	Xattrs []Xattr `json:"xattrs,omitempty"`
+	// Where the sections are loaded (see SetSectionLayout)
+	Layout []SectionRange `json:"-"`
+
+	spilled int // Number of leading strings moved to the spill file
+}
+
+// WideInfo describes how a UTF-16 or UTF-32 string is laid out in the input,
+// which tells genuine wide strings (aligned, often after a byte order mark)
+// from byte patterns that happen to decode
+type WideInfo struct {
+	ByteOrder string `json:"byte_order"` // "little-endian" or "big-endian"
+	Aligned   bool   `json:"aligned"`    // The offset is a multiple of the code unit size (2 or 4 bytes)
+	Start     string `json:"start"`      // "even" or "odd" offset
+	BOM       bool   `json:"bom"`        // A byte order mark directly precedes the string
+}
+
+// NewWideInfo returns the layout of a string at offset in encoding, or nil if
+// the encoding is not UTF-16 or UTF-32
+func NewWideInfo(offset int64, config extractor.Config) *WideInfo {
+	var unit int64
+	info := &WideInfo{ByteOrder: "little-endian", Start: "even", BOM: config.AfterBOM}
+	switch config.Encoding {
+	case "b", "l":
+		unit = 2
+	case "B", "L":
+		unit = 4
+	default:
+		return nil
+	}
+	if config.Encoding == "b" || config.Encoding == "B" {
+		info.ByteOrder = "big-endian"
+	}
+	info.Aligned = offset%unit == 0
+	if offset%2 != 0 {
+		info.Start = "odd"
+	}
+	return info
+}
+
+// WarningResult represents a warning about a file in JSON format
+type WarningResult struct {
+	Severity extractor.Severity `json:"severity"`
+	Code     string             `json:"code"`
+	Message  string             `json:"message"`
+}
+
+// NewWarningResult converts a warning to its JSON form
+func NewWarningResult(w extractor.Warning) WarningResult {
+	return WarningResult{Severity: w.Severity, Code: w.Code, Message: w.Text()}
+}
+
+// Region is an embedded filesystem or compressed blob found in a file (see
+// signature.Scan)
+type Region struct {
+	Type        string `json:"type"`
+	Offset      int64  `json:"offset"`
+	OffsetHex   string `json:"offset_hex"`
+	Size        int64  `json:"size,omitempty"` // Omitted if the format does not record it
+	Description string `json:"description"`
+}
+
+// Xattr is an extended attribute of a file (see xattr.List)
+type Xattr struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// How Value holds the bytes of the attribute if not as text: "base64"
+	ValueEncoding string `json:"value_encoding,omitempty"`
+}
+
+// NewXattr returns an attribute as reported in JSON: its value as text if it is
+// printable UTF-8 (without a trailing NUL), base64 encoded otherwise
+func NewXattr(name string, value []byte) Xattr {
+	text := strings.TrimSuffix(string(value), "\x00")
+	if utf8.ValidString(text) && !strings.ContainsFunc(text, unicode.IsControl) {
+		return Xattr{Name: name, Value: text}
+	}
+	return Xattr{Name: name, Value: base64.StdEncoding.EncodeToString(value), ValueEncoding: ValueBase64}
+}
+
+// PackingInfo describes the entropy analysis of a binary (see binary.AnalyzePacking)
+type PackingInfo struct {
+	Packed   bool             `json:"-"` // Reported as FileResult.Packed
+	Packer   string           `json:"packer,omitempty"`
+	Reason   string           `json:"reason,omitempty"`
+	Unpacked bool             `json:"unpacked,omitempty"` // Strings come from the unpacked image (--unpack)
+	Entropy  float64          `json:"entropy"`
+	Sections []SectionEntropy `json:"sections,omitempty"`
+}
+
+// SectionEntropy is the entropy of one loaded section of a binary
+type SectionEntropy struct {
+	Name       string  `json:"name"`
+	Size       int64   `json:"size"`
+	Entropy    float64 `json:"entropy"`
+	Executable bool    `json:"executable,omitempty"`
+}
+
+// Component is a library, application or compiler identified in a file from
+// a version banner, with the string that identified it
+type Component struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Kind      string `json:"kind"`
+	CPE       string `json:"cpe,omitempty"`
+	Evidence  string `json:"evidence"`
+	Offset    int64  `json:"offset"`
+	OffsetHex string `json:"offset_hex"`
 }
 
 // Summary contains metadata about the extraction
 type Summary struct {
-	TotalStrings int   `json:"total_strings"`
-	TotalBytes   int64 `json:"total_bytes"`
-	MinLength    int   `json:"min_length"`
+	TotalStrings int    `json:"total_strings"`
+	TotalBytes   int64  `json:"total_bytes"`
+	MinLength    int    `json:"min_length"`
 	Encoding     string `json:"encoding"`
+	Retries      int64  `json:"retries,omitempty"` // Opens and reads retried after transient errors (--retries)
+	// Whether strings of some file were not reported (--max-strings)
+	TruncatedResults bool `json:"truncated_results,omitempty"`
 }
 
 // JSONPrinter collects and outputs strings in JSON format
@@ -51,10 +250,39 @@ type JSONPrinter struct {
 	config      extractor.Config
 	writer      io.Writer
 	// Current file being processed
-	currentFile    string
-	currentFormat  string
+	currentFile     string
+	currentFormat   string
+	currentSource   string
+	currentStrategy string
+	currentDecrypt  string
+	currentPacking  *PackingInfo
 	currentSections []string
+	currentLayout   []SectionRange
+	currentRegions  []Region
+	currentXattrs   []Xattr
+	currentArtifact ArtifactCells
 	currentStrings  []StringResult
+	// Components identified in the current file, once per name and version
+	currentComponents []Component
+	// Strings found through relocated pointers for the current file
+	currentRelocStrings []StringResult
+	// Index of each value in currentStrings (unique mode, see addUnique)
+	uniqueIndex map[string]int
+	// Resolves code references for the current file (optional)
+	resolveRefs func(offset int64) []uint64
+	// Counts cross-references for the current file (optional)
+	countXrefs func(offset int64) int
+	// Names the function referencing a string for the current file (optional)
+	resolveFunction func(offset int64) string
+	// Memory limit for buffered strings and spill state (see SetMemoryLimit)
+	memoryLimit    int64
+	bufferedBytes  int64
+	currentSpilled int
+	spill          spillState
+	// Warnings not yet attached to a file result, by file name (see AddWarning)
+	warnings map[string][]WarningResult
+	// Neighborhoods of the current file (nil unless config.ClusterGap is set)
+	clusters *OffsetClusterer
 }
 
 // NewJSONPrinter creates a new JSON printer
@@ -62,12 +290,16 @@ func NewJSONPrinter(config extractor.Config, writer io.Writer) *JSONPrinter {
 	if writer == nil {
 		writer = os.Stdout
 	}
-	return &JSONPrinter{
+	jp := &JSONPrinter{
 		FileResults:    make([]FileResult, 0),
 		currentStrings: make([]StringResult, 0),
 		config:         config,
 		writer:         writer,
 	}
+	if config.ClusterGap > 0 {
+		jp.clusters = &OffsetClusterer{Gap: config.ClusterGap}
+	}
+	return jp
 }
 
 // SetFileInfo sets the current file and format information
@@ -81,18 +313,197 @@ func (jp *JSONPrinter) SetFileInfo(filename, format string, sections []string) {
 	// Start new file
 	jp.currentFile = filename
 	jp.currentFormat = format
+	jp.currentSource = ""
+	jp.currentStrategy = ""
+	jp.currentDecrypt = ""
+	jp.currentPacking = nil
 	jp.currentSections = sections
+	jp.currentLayout = nil
+	jp.currentRegions = nil
+	jp.currentXattrs = nil
+	jp.currentArtifact = nil
 	jp.currentStrings = make([]StringResult, 0)
+	jp.currentComponents = nil
+	jp.currentRelocStrings = nil
+	jp.uniqueIndex = nil
+	jp.resolveRefs = nil
+	jp.countXrefs = nil
+	jp.resolveFunction = nil
+	if jp.clusters != nil {
+		jp.clusters.Take()
+	}
+}
+
+// SetFormatSource records how the current file's format was decided. It applies
+// to the current file only and is cleared by SetFileInfo.
+func (jp *JSONPrinter) SetFormatSource(source string) {
+	jp.currentSource = source
+}
+
+// SetStrategy records how the current file was read (see
+// extractor.ChooseStrategy). It applies to the current file only and is
+// cleared by SetFileInfo.
+func (jp *JSONPrinter) SetStrategy(strategy string) {
+	jp.currentStrategy = strategy
+}
+
+// SetDecryption records the decryption status of the current file, an
+// encrypted archive member. It applies to the current file only and is cleared
+// by SetFileInfo.
+func (jp *JSONPrinter) SetDecryption(status string) {
+	jp.currentDecrypt = status
+}
+
+// SetPacking attaches the packing analysis of the current file. It applies to
+// the current file only and is cleared by SetFileInfo.
+func (jp *JSONPrinter) SetPacking(packing *PackingInfo) {
+	jp.currentPacking = packing
+}
+
+// SetRegions attaches the embedded filesystems and compressed data found in
+// the current file. It applies to the current file only and is cleared by
+// SetFileInfo.
+func (jp *JSONPrinter) SetRegions(regions []Region) {
+	jp.currentRegions = regions
+}
+
+// SetXattrs attaches the extended attributes of the current file. It applies
+// to the current file only and is cleared by SetFileInfo.
+func (jp *JSONPrinter) SetXattrs(xattrs []Xattr) {
+	jp.currentXattrs = xattrs
+}
+
+// SetReferenceResolver sets a function that returns the code addresses referencing
+// the string at a given file offset. It applies to the current file only and is
+// cleared by SetFileInfo.
+func (jp *JSONPrinter) SetReferenceResolver(resolve func(offset int64) []uint64) {
+	jp.resolveRefs = resolve
+}
+
+// SetXrefCounter sets a function that returns the number of cross-references to
+// the string at a given file offset. It applies to the current file only and is
+// cleared by SetFileInfo.
+func (jp *JSONPrinter) SetXrefCounter(count func(offset int64) int) {
+	jp.countXrefs = count
+}
+
+// SetFunctionResolver sets a function that returns the name of the function
+// referencing the string at a given file offset, or "" if unknown. It applies
+// to the current file only and is cleared by SetFileInfo.
+func (jp *JSONPrinter) SetFunctionResolver(resolve func(offset int64) string) {
+	jp.resolveFunction = resolve
 }
 
 // PrintString collects a string result (implements the printFunc signature)
 func (jp *JSONPrinter) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	result := jp.newStringResult(str, filename, offset, config)
+	if config.Unique && jp.addUnique(&result, config) {
+		return
+	}
+	if jp.clusters != nil {
+		result.Cluster = jp.clusters.Add(filename, offset, extractor.EncodedLen(str, config.Encoding))
+	}
+	jp.currentStrings = append(jp.currentStrings, result)
+	jp.trackMemory(result)
+	jp.identifyComponent(str, offset)
+}
+
+// Emit collects a string result (implements extractor.StringSink), returning
+// the error of spilling strings to disk under a memory budget, if any
+func (jp *JSONPrinter) Emit(r extractor.Result) error {
+	jp.PrintString(r.Str, r.File, r.Offset, r.Config)
+	return jp.spill.err
+}
+
+// Close outputs the collected results as JSON (implements
+// extractor.StringSink). Use FlushCSV and the like for other formats.
+func (jp *JSONPrinter) Close() error {
+	return jp.Flush()
+}
+
+// identifyComponent records the component whose version banner str holds, if
+// any, unless the same name and version was already found in the current file
+func (jp *JSONPrinter) identifyComponent(str []byte, offset int64) {
+	lib, ok := fingerprint.Identify(str)
+	if !ok {
+		return
+	}
+	for _, component := range jp.currentComponents {
+		if component.Name == lib.Name && component.Version == lib.Version {
+			return
+		}
+	}
+	jp.currentComponents = append(jp.currentComponents, Component{
+		Name:      lib.Name,
+		Version:   lib.Version,
+		Kind:      lib.Kind,
+		CPE:       lib.CPE,
+		Evidence:  string(str),
+		Offset:    offset,
+		OffsetHex: fmt.Sprintf("0x%x", offset),
+	})
+}
+
+// PrintRelocString collects a string found through a relocated pointer
+// (implements the printFunc signature). These are reported separately in reloc_strings.
+func (jp *JSONPrinter) PrintRelocString(str []byte, filename string, offset int64, config extractor.Config) {
+	jp.currentRelocStrings = append(jp.currentRelocStrings, jp.newStringResult(str, filename, offset, config))
+}
+
+// newStringResult builds a string result, attaching reference data for the current file
+func (jp *JSONPrinter) newStringResult(str []byte, filename string, offset int64, config extractor.Config) StringResult {
+	result := NewStringResult(str, filename, offset, config)
+
+	// Attach referencing code addresses if a resolver is set
+	if jp.resolveRefs != nil {
+		for _, addr := range jp.resolveRefs(offset) {
+			result.ReferencedFrom = append(result.ReferencedFrom, fmt.Sprintf("0x%x", addr))
+		}
+	}
+
+	// Attach cross-reference count if a counter is set (zero is reported)
+	if jp.countXrefs != nil {
+		count := jp.countXrefs(offset)
+		result.XrefCount = &count
+	}
+
+	if jp.resolveFunction != nil {
+		result.Function = jp.resolveFunction(offset)
+	}
+
+	jp.currentArtifact.attribute(&result)
+
+	return result
+}
+
+// NewStringResult builds the JSON representation of an extracted string
+func NewStringResult(str []byte, filename string, offset int64, config extractor.Config) StringResult {
 	result := StringResult{
 		Value:     string(str),
 		Offset:    offset,
 		OffsetHex: fmt.Sprintf("0x%x", offset),
 		Length:    len(str),
-		Encoding:  getEncodingName(config.Encoding),
+		Encoding:  EncodingName(config.Encoding),
+		Line:      config.Line,
+	}
+	if config.Background != nil {
+		rarity := math.Round(config.Rarity*100) / 100 // As precise as the text output
+		result.Rarity = &rarity
+	}
+
+	// Invalid UTF-8 (8-bit strings) would be replaced when written as JSON
+	switch config.ValueEncoding {
+	case ValueBase64:
+		result.Value, result.ValueEncoding = base64.StdEncoding.EncodeToString(str), ValueBase64
+	case ValueHex:
+		result.Value, result.ValueEncoding = hex.EncodeToString(str), ValueHex
+	}
+	if config.EmitRaw {
+		raw := config.Raw
+		if raw == nil {
+			raw = extractor.EncodeString(str, config.Encoding)
+		}
+		result.Raw = base64.StdEncoding.EncodeToString(raw)
 	}
 
 	// Only include filename if PrintFileName is enabled or it's different from stdin
@@ -100,16 +511,45 @@ func (jp *JSONPrinter) PrintString(str []byte, filename string, offset int64, co
 		result.File = filename
 	}
 
-	jp.currentStrings = append(jp.currentStrings, result)
+	if config.WideInfo {
+		result.Wide = NewWideInfo(offset, config)
+	}
+
+	if config.Scripts {
+		result.ScriptType = extractor.DetectScriptType(str)
+	}
+	if config.FormatStrings {
+		result.FormatDirectives = extractor.FormatDirectives(str)
+	}
+	if config.Secrets {
+		result.SecretRule = extractor.DetectSecret(str)
+	}
+
+	return result
 }
 
 // FinalizeCurrentFile adds the current file's results to the fileResults list
 func (jp *JSONPrinter) FinalizeCurrentFile() {
 	fileResult := FileResult{
-		File:     jp.currentFile,
-		Format:   jp.currentFormat,
-		Sections: jp.currentSections,
-		Strings:  jp.currentStrings,
+		File:         jp.currentFile,
+		Format:       jp.currentFormat,
+		FormatSource: jp.currentSource,
+		Packed:       jp.currentPacking != nil && jp.currentPacking.Packed,
+		Packing:      jp.currentPacking,
+		Sections:     jp.currentSections,
+		Strategy:     jp.currentStrategy,
+		Decryption:   jp.currentDecrypt,
+		Layout:       jp.currentLayout,
+		Regions:      jp.currentRegions,
+		Xattrs:       jp.currentXattrs,
+		Components:   jp.currentComponents,
+		Strings:      jp.currentStrings,
+		RelocStrings: jp.currentRelocStrings,
+		Warnings:     jp.takeWarnings(jp.currentFile),
+		spilled:      jp.currentSpilled,
+	}
+	if jp.clusters != nil {
+		fileResult.Clusters = jp.clusters.Take()
 	}
 
 	jp.FileResults = append(jp.FileResults, fileResult)
@@ -117,8 +557,30 @@ func (jp *JSONPrinter) FinalizeCurrentFile() {
 	// Reset current file state
 	jp.currentFile = ""
 	jp.currentFormat = ""
+	jp.currentSource = ""
+	jp.currentStrategy = ""
+	jp.currentDecrypt = ""
+	jp.currentPacking = nil
 	jp.currentSections = nil
 	jp.currentStrings = make([]StringResult, 0)
+	jp.currentComponents = nil
+	jp.currentRelocStrings = nil
+	jp.uniqueIndex = nil
+	jp.currentSpilled = 0
+}
+
+// AppendFileResult adds a complete file result (e.g. one collected by a
+// temporary printer during parallel processing)
+func (jp *JSONPrinter) AppendFileResult(fileResult FileResult) {
+	// Ensure strings is never nil (use empty array instead)
+	if fileResult.Strings == nil {
+		fileResult.Strings = make([]StringResult, 0)
+	}
+
+	jp.FileResults = append(jp.FileResults, fileResult)
+	for _, result := range fileResult.Strings {
+		jp.trackMemory(result)
+	}
 }
 
 // AddFileResult adds a file result (useful for adding error results from parallel processing)
@@ -138,8 +600,38 @@ func (jp *JSONPrinter) AddFileResult(filename, format string, sections []string,
 	if err != nil {
 		fileResult.Error = err.Error()
 	}
+	fileResult.Warnings = jp.takeWarnings(filename)
 
 	jp.FileResults = append(jp.FileResults, fileResult)
+	for _, result := range strings {
+		jp.trackMemory(result)
+	}
+}
+
+// AddWarning records a warning for the file result of w.File, which may be
+// the current file, one whose results are added later (warnings can precede
+// SetFileInfo, e.g. a format parse failure) or one already added (e.g. a
+// filesystem in the file that could not be read)
+func (jp *JSONPrinter) AddWarning(w extractor.Warning) {
+	if w.File != jp.currentFile {
+		for i := len(jp.FileResults) - 1; i >= 0; i-- {
+			if jp.FileResults[i].File == w.File {
+				jp.FileResults[i].Warnings = append(jp.FileResults[i].Warnings, NewWarningResult(w))
+				return
+			}
+		}
+	}
+	if jp.warnings == nil {
+		jp.warnings = make(map[string][]WarningResult)
+	}
+	jp.warnings[w.File] = append(jp.warnings[w.File], NewWarningResult(w))
+}
+
+// takeWarnings removes and returns the warnings recorded for filename
+func (jp *JSONPrinter) takeWarnings(filename string) []WarningResult {
+	warnings := jp.warnings[filename]
+	delete(jp.warnings, filename)
+	return warnings
 }
 
 // Flush outputs all collected results as JSON
@@ -149,21 +641,34 @@ func (jp *JSONPrinter) Flush() error {
 		jp.FinalizeCurrentFile()
 	}
 
-	// Calculate summary across all files
-	totalStrings := 0
-	totalBytes := int64(0)
-	for _, fileResult := range jp.FileResults {
+	// Calculate summary across all files (including spilled strings)
+	totalStrings := jp.spill.strings
+	totalBytes := jp.spill.bytes
+	truncated := false
+	for i, fileResult := range jp.FileResults {
+		jp.FileResults[i].Duplicates = jp.config.Duplicates[fileResult.File]
 		for _, result := range fileResult.Strings {
 			totalStrings++
 			totalBytes += int64(result.Length)
 		}
+		for _, warning := range fileResult.Warnings {
+			truncated = truncated || warning.Code == extractor.WarnMaxStrings
+		}
 	}
 
 	summary := Summary{
 		TotalStrings: totalStrings,
 		TotalBytes:   totalBytes,
 		MinLength:    jp.config.MinLength,
-		Encoding:     getEncodingName(jp.config.Encoding),
+		Encoding:     EncodingName(jp.config.Encoding),
+		Retries:      jp.config.Retry.Count(),
+
+		TruncatedResults: truncated,
+	}
+
+	// Stream the output if strings were spilled to disk
+	if jp.spill.file != nil || jp.spill.err != nil {
+		return jp.writeSpilled(summary)
 	}
 
 	// Build output structure
@@ -178,8 +683,33 @@ func (jp *JSONPrinter) Flush() error {
 	return encoder.Encode(output)
 }
 
-// getEncodingName returns a human-readable encoding name
-func getEncodingName(encoding string) string {
+// EncodingCode returns the extractor encoding (see extractor.Config.Encoding)
+// of an encoding name of JSON output, "s" for names it does not know
+func EncodingCode(name string) string {
+	for _, code := range []string{"S", "b", "l", "B", "L"} {
+		if EncodingName(code) == name {
+			return code
+		}
+	}
+	return "s"
+}
+
+// Bytes returns the bytes of the string, decoding its ValueEncoding
+func (r StringResult) Bytes() ([]byte, error) {
+	switch r.ValueEncoding {
+	case ValueBase64:
+		return base64.StdEncoding.DecodeString(r.Value)
+	case ValueHex:
+		return hex.DecodeString(r.Value)
+	case "":
+		return []byte(r.Value), nil
+	}
+	return nil, fmt.Errorf("unknown value encoding %q", r.ValueEncoding)
+}
+
+// EncodingName returns the name of an extractor encoding (see
+// extractor.Config.Encoding) in JSON output, e.g. utf-16le for l
+func EncodingName(encoding string) string {
 	switch encoding {
 	case "s":
 		return "ascii-7bit"
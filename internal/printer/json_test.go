@@ -3,7 +3,10 @@ package printer
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
+	"maps"
 	"os"
+	"slices"
 	"strings"
 	"testing"
 
@@ -173,7 +176,7 @@ func TestJSONPrinterWithFileInfo(t *testing.T) {
 	}
 }
 
-func TestGetEncodingName(t *testing.T) {
+func TestEncodingName(t *testing.T) {
 	tests := []struct {
 		encoding string
 		want     string
@@ -190,9 +193,12 @@ func TestGetEncodingName(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.encoding, func(t *testing.T) {
-			got := getEncodingName(tt.encoding)
+			got := EncodingName(tt.encoding)
 			if got != tt.want {
-				t.Errorf("getEncodingName(%q) = %q, want %q", tt.encoding, got, tt.want)
+				t.Errorf("EncodingName(%q) = %q, want %q", tt.encoding, got, tt.want)
+			}
+			if code := EncodingCode(got); EncodingName(code) != got {
+				t.Errorf("EncodingCode(%q) = %q, which is named %q", got, code, EncodingName(code))
 			}
 		})
 	}
@@ -239,3 +245,450 @@ func TestJSONOutputValid(t *testing.T) {
 		t.Fatalf("Invalid JSON output: %v", err)
 	}
 }
+
+func TestJSONPrinterReferenceResolver(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{
+		MinLength: 4,
+		Encoding:  "s",
+	}
+
+	jp := NewJSONPrinter(config, &buf)
+	jp.SetFileInfo("firmware.elf", "ELF", []string{".rodata"})
+	jp.SetReferenceResolver(func(offset int64) []uint64 {
+		if offset == 0x100 {
+			return []uint64{0x8004, 0x8120}
+		}
+		return nil
+	})
+	jp.PrintString([]byte("referenced"), "firmware.elf", 0x100, config)
+	jp.PrintString([]byte("unreferenced"), "firmware.elf", 0x200, config)
+
+	// Resolver must not carry over to the next file
+	jp.SetFileInfo("other.elf", "ELF", nil)
+	jp.PrintString([]byte("referenced"), "other.elf", 0x100, config)
+
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var output JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+
+	refs := output.Files[0].Strings[0].ReferencedFrom
+	if len(refs) != 2 || refs[0] != "0x8004" || refs[1] != "0x8120" {
+		t.Errorf("referenced_from = %v, want [0x8004 0x8120]", refs)
+	}
+	if refs := output.Files[0].Strings[1].ReferencedFrom; refs != nil {
+		t.Errorf("unreferenced string has referenced_from = %v, want none", refs)
+	}
+	if refs := output.Files[1].Strings[0].ReferencedFrom; refs != nil {
+		t.Errorf("resolver leaked into next file: referenced_from = %v", refs)
+	}
+	if strings.Count(buf.String(), "referenced_from") != 1 {
+		t.Error("referenced_from should be omitted for unreferenced strings")
+	}
+}
+
+func TestJSONPrinterFunctionResolver(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{
+		MinLength: 4,
+		Encoding:  "s",
+	}
+
+	jp := NewJSONPrinter(config, &buf)
+	jp.SetFileInfo("app.elf", "ELF", []string{".rodata"})
+	jp.SetFunctionResolver(func(offset int64) string {
+		if offset == 0x100 {
+			return "load_config"
+		}
+		return ""
+	})
+	jp.PrintString([]byte("config.ini"), "app.elf", 0x100, config)
+	jp.PrintString([]byte("unreferenced"), "app.elf", 0x200, config)
+
+	// Resolver must not carry over to the next file
+	jp.SetFileInfo("other.elf", "ELF", nil)
+	jp.PrintString([]byte("config.ini"), "other.elf", 0x100, config)
+
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var output JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+
+	if fn := output.Files[0].Strings[0].Function; fn != "load_config" {
+		t.Errorf("function = %q, want load_config", fn)
+	}
+	if fn := output.Files[1].Strings[0].Function; fn != "" {
+		t.Errorf("resolver leaked into next file: function = %q", fn)
+	}
+	if strings.Count(buf.String(), `"function"`) != 1 {
+		t.Error("function should be omitted for strings without a known function")
+	}
+}
+
+// TestJSONPrinterRegions tests that regions are attached to their file only
+func TestJSONPrinterRegions(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+
+	jp := NewJSONPrinter(config, &buf)
+	jp.SetFileInfo("firmware.bin", "", nil)
+	jp.SetRegions([]Region{{Type: "squashfs", Offset: 0x40000, OffsetHex: "0x40000", Size: 4096, Description: "SquashFS 4.0 filesystem"}})
+	jp.PrintString([]byte("U-Boot 2020.04"), "firmware.bin", 0x100, config)
+	jp.SetFileInfo("other.bin", "", nil)
+	jp.PrintString([]byte("hello"), "other.bin", 0, config)
+
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var output JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+	if regions := output.Files[0].Regions; len(regions) != 1 || regions[0].Type != "squashfs" || regions[0].Size != 4096 {
+		t.Errorf("regions = %+v, want the squashfs region", regions)
+	}
+	if regions := output.Files[1].Regions; regions != nil {
+		t.Errorf("regions leaked into next file: %+v", regions)
+	}
+}
+
+func TestJSONPrinterXrefCounter(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{
+		MinLength: 4,
+		Encoding:  "s",
+	}
+
+	jp := NewJSONPrinter(config, &buf)
+	jp.SetFileInfo("app.exe", "PE", []string{".rdata"})
+	jp.SetXrefCounter(func(offset int64) int {
+		if offset == 0x400 {
+			return 3
+		}
+		return 0
+	})
+	jp.PrintString([]byte("popular"), "app.exe", 0x400, config)
+	jp.PrintString([]byte("orphan"), "app.exe", 0x500, config)
+
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var output JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+
+	strs := output.Files[0].Strings
+	if strs[0].XrefCount == nil || *strs[0].XrefCount != 3 {
+		t.Errorf("xref_count = %v, want 3", strs[0].XrefCount)
+	}
+	// Zero counts must be reported, not omitted
+	if strs[1].XrefCount == nil || *strs[1].XrefCount != 0 {
+		t.Errorf("xref_count = %v, want 0", strs[1].XrefCount)
+	}
+}
+
+func TestJSONPrinterRelocStrings(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{
+		MinLength: 4,
+		Encoding:  "s",
+	}
+
+	jp := NewJSONPrinter(config, &buf)
+	jp.SetFileInfo("app.so", "ELF", []string{".rodata"})
+	jp.PrintString([]byte("scanned"), "app.so", 0x10, config)
+	jp.PrintRelocString([]byte("relocated"), "app.so", 0x20, config)
+
+	// Relocation strings are per-file
+	jp.SetFileInfo("other.so", "ELF", nil)
+	jp.PrintString([]byte("plain"), "other.so", 0x10, config)
+	jp.FinalizeCurrentFile()
+
+	// Pre-built results keep their relocation strings
+	jp.AppendFileResult(FileResult{
+		File:         "worker.so",
+		RelocStrings: []StringResult{{Value: "from worker", Length: 11}},
+	})
+
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var output JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Invalid JSON output: %v", err)
+	}
+
+	if len(output.Files) != 3 {
+		t.Fatalf("got %d files, want 3", len(output.Files))
+	}
+	if rs := output.Files[0].RelocStrings; len(rs) != 1 || rs[0].Value != "relocated" {
+		t.Errorf("reloc_strings = %+v, want [relocated]", rs)
+	}
+	if len(output.Files[0].Strings) != 1 {
+		t.Errorf("relocation strings must not be mixed into strings: %+v", output.Files[0].Strings)
+	}
+	if rs := output.Files[1].RelocStrings; rs != nil {
+		t.Errorf("reloc_strings leaked into next file: %+v", rs)
+	}
+	if output.Files[2].Strings == nil {
+		t.Error("AppendFileResult() should never produce null strings")
+	}
+	if rs := output.Files[2].RelocStrings; len(rs) != 1 || rs[0].Value != "from worker" {
+		t.Errorf("appended reloc_strings = %+v, want [from worker]", rs)
+	}
+}
+
+// TestNewStringResultScriptType tests script tagging with --scripts
+func TestNewStringResultScriptType(t *testing.T) {
+	script := []byte("#!/bin/bash\necho hi")
+
+	config := extractor.Config{Encoding: "s"}
+	if got := NewStringResult(script, "f", 0, config).ScriptType; got != "" {
+		t.Errorf("ScriptType without --scripts = %q, want empty", got)
+	}
+
+	config.Scripts = true
+	if got := NewStringResult(script, "f", 0, config).ScriptType; got != extractor.ScriptShell {
+		t.Errorf("ScriptType = %q, want %q", got, extractor.ScriptShell)
+	}
+	if got := NewStringResult([]byte("plain string"), "f", 0, config).ScriptType; got != "" {
+		t.Errorf("ScriptType of plain string = %q, want empty", got)
+	}
+}
+
+// TestNewStringResultFormatDirectives tests directive listing with --format-strings
+func TestNewStringResultFormatDirectives(t *testing.T) {
+	str := []byte("open %s failed: %d")
+
+	config := extractor.Config{Encoding: "s"}
+	if got := NewStringResult(str, "f", 0, config).FormatDirectives; got != nil {
+		t.Errorf("FormatDirectives without --format-strings = %q, want nil", got)
+	}
+
+	config.FormatStrings = true
+	got := NewStringResult(str, "f", 0, config).FormatDirectives
+	if len(got) != 2 || got[0] != "%s" || got[1] != "%d" {
+		t.Errorf("FormatDirectives = %q, want [%%s %%d]", got)
+	}
+}
+
+// TestNewStringResultValueEncoding tests that --value-encoding keeps the
+// exact bytes of strings that are not valid UTF-8
+func TestNewStringResultValueEncoding(t *testing.T) {
+	str := []byte("caf\xe9 menu")
+	tests := []struct {
+		encoding     string
+		wantValue    string
+		wantEncoding string
+	}{
+		{"", "caf\xe9 menu", ""},
+		{"utf8", "caf\xe9 menu", ""},
+		{ValueBase64, "Y2Fm6SBtZW51", ValueBase64},
+		{ValueHex, "636166e9206d656e75", ValueHex},
+	}
+	for _, tt := range tests {
+		result := NewStringResult(str, "f", 0, extractor.Config{Encoding: "S", ValueEncoding: tt.encoding})
+		if result.Value != tt.wantValue || result.ValueEncoding != tt.wantEncoding || result.Length != len(str) {
+			t.Errorf("NewStringResult() with %q = %q %q (length %d), want %q %q", tt.encoding, result.Value, result.ValueEncoding, result.Length, tt.wantValue, tt.wantEncoding)
+		}
+		if got, err := result.Bytes(); err != nil || !bytes.Equal(got, str) {
+			t.Errorf("Bytes() with %q = %q, %v, want %q", tt.encoding, got, err, str)
+		}
+	}
+}
+
+// TestNewStringResultRaw tests that --emit-raw reports the bytes scanned,
+// re-encoding strings of wide encodings
+func TestNewStringResultRaw(t *testing.T) {
+	tests := []struct {
+		name   string
+		str    string
+		config extractor.Config
+		want   string
+	}{
+		{"off", "hello", extractor.Config{Encoding: "s"}, ""},
+		{"ascii", "hello", extractor.Config{Encoding: "s", EmitRaw: true}, "aGVsbG8="},
+		{"utf-16le", "hi", extractor.Config{Encoding: "l", EmitRaw: true}, "aABpAA=="},
+		{"escaped", "na\\u00efve", extractor.Config{Encoding: "s", Unicode: "escape", EmitRaw: true, Raw: []byte("na\xc3\xafve")}, "bmHDr3Zl"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := NewStringResult([]byte(tt.str), "f", 0, tt.config)
+			if result.Raw != tt.want {
+				t.Errorf("NewStringResult().Raw = %q, want %q", result.Raw, tt.want)
+			}
+		})
+	}
+}
+
+// TestNewWideInfo tests the layout reported for wide strings (--wide-info)
+func TestNewWideInfo(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int64
+		config extractor.Config
+		want   *WideInfo
+	}{
+		{"ascii", 0, extractor.Config{Encoding: "s"}, nil},
+		{"utf-16le aligned", 4, extractor.Config{Encoding: "l"}, &WideInfo{ByteOrder: "little-endian", Aligned: true, Start: "even"}},
+		{"utf-16be odd", 5, extractor.Config{Encoding: "b"}, &WideInfo{ByteOrder: "big-endian", Start: "odd"}},
+		{"utf-32le after bom", 4, extractor.Config{Encoding: "L", AfterBOM: true}, &WideInfo{ByteOrder: "little-endian", Aligned: true, Start: "even", BOM: true}},
+		{"utf-32be even unaligned", 6, extractor.Config{Encoding: "B"}, &WideInfo{ByteOrder: "big-endian", Start: "even"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewWideInfo(tt.offset, tt.config)
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("NewWideInfo(%d) = %+v, want %+v", tt.offset, got, tt.want)
+			}
+		})
+	}
+
+	if result := NewStringResult([]byte("hi"), "f", 2, extractor.Config{Encoding: "l"}); result.Wide != nil {
+		t.Errorf("NewStringResult().Wide = %+v without WideInfo, want nil", result.Wide)
+	}
+}
+
+// TestJSONPrinterWarnings tests that warnings are attached to the result of
+// their file, including warnings reported before the file's info is set and
+// after its results were added
+func TestJSONPrinterWarnings(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+
+	jp := NewJSONPrinter(config, &buf)
+	jp.AddWarning(extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnParseFallback, File: "a.bin", Message: "cannot parse as ELF", Err: errors.New("bad magic")})
+	jp.SetFileInfo("a.bin", "", nil)
+	jp.PrintString([]byte("test"), "a.bin", 0, config)
+	jp.SetFileInfo("b.bin", "", nil)
+	jp.PrintString([]byte("more"), "b.bin", 0, config)
+	jp.AddWarning(extractor.Warning{Severity: extractor.SeverityWarning, Code: extractor.WarnExtractFS, File: "a.bin", Message: "cannot read the squashfs filesystem at 0x0"})
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var output struct {
+		Files []struct {
+			File     string            `json:"file"`
+			Warnings []map[string]string `json:"warnings"`
+		} `json:"files"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("JSON unmarshal error = %v", err)
+	}
+	if len(output.Files) != 2 {
+		t.Fatalf("got %d files, want 2", len(output.Files))
+	}
+	want := map[string]string{"severity": "warning", "code": "parse-fallback", "message": "cannot parse as ELF: bad magic"}
+	if len(output.Files[0].Warnings) != 2 || !maps.Equal(output.Files[0].Warnings[0], want) || output.Files[0].Warnings[1]["code"] != "extract-fs" {
+		t.Errorf("a.bin warnings = %v, want [%v] and an extract-fs warning", output.Files[0].Warnings, want)
+	}
+	if output.Files[1].Warnings != nil {
+		t.Errorf("b.bin warnings = %v, want none", output.Files[1].Warnings)
+	}
+}
+
+// TestJSONPrinterTruncatedResults tests that the summary flags files whose
+// strings were cut off by --max-strings
+func TestJSONPrinterTruncatedResults(t *testing.T) {
+	for _, truncated := range []bool{false, true} {
+		var buf bytes.Buffer
+		config := extractor.Config{MinLength: 4, Encoding: "s", MaxStrings: 1}
+
+		jp := NewJSONPrinter(config, &buf)
+		jp.SetFileInfo("a.bin", "", nil)
+		printFunc := extractor.LimitStrings(jp.PrintString, "a.bin", extractor.Config{MaxStrings: 1, Warnings: jp.AddWarning})
+		printFunc([]byte("first"), "a.bin", 0, config)
+		if truncated {
+			printFunc([]byte("second"), "a.bin", 6, config)
+		}
+		if err := jp.Flush(); err != nil {
+			t.Fatalf("Flush() error = %v", err)
+		}
+
+		var output JSONOutput
+		if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+			t.Fatalf("JSON unmarshal error = %v", err)
+		}
+		if output.Summary.TruncatedResults != truncated || output.Summary.TotalStrings != 1 {
+			t.Errorf("summary = %+v, want truncated_results %v and 1 string", output.Summary, truncated)
+		}
+	}
+}
+
+// TestNDJSONPrinterWarning tests the warning line of the NDJSON output
+func TestNDJSONPrinterWarning(t *testing.T) {
+	var buf bytes.Buffer
+	np := NewNDJSONPrinter(&buf)
+	np.PrintWarning(extractor.Warning{Severity: extractor.SeverityError, Code: extractor.WarnReadFailed, File: "disk.img", Message: "error reading", Err: errors.New("I/O error")})
+	if err := np.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	want := `{"type":"warning","file":"disk.img","severity":"error","code":"read-failed","message":"error reading: I/O error"}` + "\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+// TestJSONDuplicates tests that inputs not scanned for --dedupe-inputs are
+// listed with the input they duplicate
+func TestJSONDuplicates(t *testing.T) {
+	config := extractor.Config{MinLength: 4, Encoding: "s", Duplicates: map[string][]string{"a.bin": {"copy.bin"}}}
+	var buf bytes.Buffer
+	jp := NewJSONPrinter(config, &buf)
+	jp.SetFileInfo("a.bin", "", nil)
+	jp.PrintString([]byte("hello"), "a.bin", 0, config)
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	var output JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("JSON unmarshal error = %v", err)
+	}
+	if got := output.Files[0].Duplicates; !slices.Equal(got, []string{"copy.bin"}) {
+		t.Errorf("duplicates = %q, want [copy.bin]", got)
+	}
+
+	buf.Reset()
+	np := NewNDJSONPrinter(&buf)
+	np.PrintDuplicate("copy.bin", "a.bin")
+	if err := np.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+	if want := `{"type":"duplicate","file":"copy.bin","duplicate_of":"a.bin"}` + "\n"; buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+}
+
+// TestNewXattr tests reporting attribute values as text or base64
+func TestNewXattr(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  Xattr
+	}{
+		{"text", "https://example.com/a.zip", Xattr{Name: "text", Value: "https://example.com/a.zip"}},
+		{"nul-terminated", "0083;65f1;Safari;\x00", Xattr{Name: "nul-terminated", Value: "0083;65f1;Safari;"}},
+		{"binary", "bplist00\x01\xff", Xattr{Name: "binary", Value: "YnBsaXN0MDAB/w==", ValueEncoding: ValueBase64}},
+		{"empty", "", Xattr{Name: "empty"}},
+	}
+	for _, tt := range tests {
+		if got := NewXattr(tt.name, []byte(tt.value)); got != tt.want {
+			t.Errorf("NewXattr(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
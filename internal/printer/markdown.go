@@ -0,0 +1,311 @@
+package printer
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/notify"
+	"github.com/richardwooding/txtr/internal/rank"
+)
+
+// Limits on the rows of a report, which is pasted into issues and tickets
+// rather than processed (trackers cap their size, e.g. 65536 characters for
+// a GitHub issue)
+const (
+	reportMaxStrings  = 100 // Strings listed per file
+	reportMaxFindings = 50  // Findings listed per file
+)
+
+// Report is the data of a Markdown report (FlushMarkdown), as given to its
+// template
+type Report struct {
+	Version   string    // txtr version
+	Generated time.Time // When the report was written
+	MinLength int       // Minimum string length of the scan
+	Files     []ReportFile
+	Strings   int   // Strings found in all files
+	Bytes     int64 // Total length of the strings
+	Failed    int   // Files that could not be scanned
+	Warnings  int
+	Findings  int           // Notable artifacts found in all files
+	Encodings []ReportCount // Strings per encoding, most first
+	Artifacts []ReportCount // Notable artifacts per kind (see rank.Categories), most first
+}
+
+// ReportFile is a file of a report
+type ReportFile struct {
+	Name         string // "{standard input}" for stdin
+	Format       string
+	Sections     []string
+	Packer       string // Packer of a packed file ("" = not packed)
+	Components   []Component
+	Duplicates   []string
+	Error        string
+	Warnings     []WarningResult
+	Count        int            // Strings found
+	Strings      []StringResult // The first strings found, up to a limit
+	Findings     []ReportFinding
+	FindingCount int // Findings in the file, some not listed past a limit
+}
+
+// Omitted returns the number of strings found but not listed
+func (rf ReportFile) Omitted() int {
+	return rf.Count - len(rf.Strings)
+}
+
+// OmittedFindings returns the number of findings not listed
+func (rf ReportFile) OmittedFindings() int {
+	return rf.FindingCount - len(rf.Findings)
+}
+
+// ReportFinding is a notable artifact of a file: a string recognized as a
+// URL, address, secret, etc. Secrets are masked (see notify.Mask), here and
+// in the strings listed.
+type ReportFinding struct {
+	Kind      string
+	Value     string
+	OffsetHex string
+}
+
+// ReportCount is a count of strings in a report
+type ReportCount struct {
+	Name  string
+	Count int
+}
+
+// DefaultReportTemplate is the template of Markdown reports: a summary
+// table, a table of the files, then a section per file with its findings and
+// strings. Long lists are collapsed in <details> blocks, which GitHub and
+// GitLab render and other trackers show as plain text.
+const DefaultReportTemplate = `# txtr report
+
+| Files | Strings | Findings | Failed | Warnings |
+|------:|--------:|---------:|-------:|---------:|
+| {{len .Files}} | {{.Strings}} | {{.Findings}} | {{.Failed}} | {{.Warnings}} |
+
+Generated {{.Generated.Format "2006-01-02 15:04:05 MST"}} by txtr {{.Version}}, minimum length {{.MinLength}}.
+{{- if .Artifacts}}
+
+| Finding | Count |
+|---------|------:|
+{{- range .Artifacts}}
+| {{.Name}} | {{.Count}} |
+{{- end}}
+{{- end}}
+
+| File | Format | Strings | Findings | Status |
+|------|--------|--------:|---------:|--------|
+{{- range .Files}}
+| {{code .Name | cell}} | {{cell .Format}} | {{.Count}} | {{.FindingCount}} | {{if .Error}}failed{{else if .Warnings}}{{len .Warnings}} warnings{{else}}ok{{end}} |
+{{- end}}
+{{- range .Files}}
+
+## {{code .Name}}
+{{if .Format}}
+- Format: {{text .Format}}
+{{- end}}
+{{- if .Sections}}
+- Sections: {{text (join .Sections ", ")}}
+{{- end}}
+{{- if .Packer}}
+- Packed: {{text .Packer}}
+{{- end}}
+{{- range .Components}}
+- Component: {{text .Name}} {{text .Version}} at {{.OffsetHex}}
+{{- end}}
+{{- range .Duplicates}}
+- Duplicate: {{code .}}
+{{- end}}
+- Strings: {{.Count}}
+{{- if .Error}}
+
+> **Error:** {{text .Error}}
+{{- end}}
+{{- range .Warnings}}
+
+> **Warning** ({{.Code}}): {{text .Message}}
+{{- end}}
+{{- if .Findings}}
+
+{{if gt (len .Findings) 10}}<details>
+<summary>{{.FindingCount}} findings</summary>
+
+{{end}}| Kind | Value | Offset |
+|------|-------|-------:|
+{{- range .Findings}}
+| {{.Kind}} | {{code .Value | cell}} | {{.OffsetHex}} |
+{{- end}}
+{{- if .OmittedFindings}}
+
+{{.OmittedFindings}} more findings not listed.
+{{- end}}
+{{- if gt (len .Findings) 10}}
+
+</details>
+{{- end}}
+{{- end}}
+{{- if .Strings}}
+
+<details>
+<summary>{{.Count}} strings</summary>
+
+| Offset | Length | Encoding | Value |
+|-------:|-------:|----------|-------|
+{{- range .Strings}}
+| {{.OffsetHex}} | {{.Length}} | {{.Encoding}} | {{code .Value | cell}} |
+{{- end}}
+{{- if .Omitted}}
+
+{{.Omitted}} more strings not listed.
+{{- end}}
+
+</details>
+{{- end}}
+{{- end}}
+`
+
+// reportFuncs are the functions available to report templates
+var reportFuncs = template.FuncMap{
+	"code": markdownCode,
+	"cell": markdownCell,
+	"text": markdownText,
+	"join": strings.Join,
+}
+
+// ParseReportTemplate parses a report template (see Report for its data and
+// DefaultReportTemplate for an example). Besides the built-in functions,
+// templates can use code (an inline code span), cell (escaping a table
+// cell), text (escaping Markdown) and join (strings.Join).
+func ParseReportTemplate(name, text string) (*template.Template, error) {
+	return template.New(name).Funcs(reportFuncs).Parse(text)
+}
+
+// markdownCode returns value as an inline code span, fenced by more
+// backticks than it contains, with control characters escaped
+func markdownCode(value string) string {
+	value = strings.NewReplacer("\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(value)
+	longest, run := 0, 0
+	for _, r := range value {
+		if r == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if value == "" || strings.HasPrefix(value, "`") || strings.HasSuffix(value, "`") {
+		value = " " + value + " "
+	}
+	return fence + value + fence
+}
+
+// markdownCell escapes the pipes ending a table cell, which are not literal
+// even in code spans
+func markdownCell(value string) string {
+	return strings.ReplaceAll(value, "|", `\|`)
+}
+
+// markdownText escapes the characters Markdown would take as formatting
+func markdownText(value string) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch r {
+		case '\\', '`', '*', '_', '[', ']', '<', '>', '|', '#':
+			b.WriteByte('\\')
+		case '\n', '\r':
+			r = ' '
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// FlushMarkdown outputs all collected strings as a Markdown report for
+// issues and tickets, executing the configured template (ReportTemplate,
+// DefaultReportTemplate if nil) with the Report. Strings and findings are
+// listed up to a limit per file; secrets are masked.
+func (jp *JSONPrinter) FlushMarkdown(toolVersion string) error {
+	// Finalize any remaining current file
+	if jp.currentFile != "" || len(jp.currentStrings) > 0 {
+		jp.FinalizeCurrentFile()
+	}
+	tmpl := jp.config.ReportTemplate
+	if tmpl == nil {
+		tmpl = template.Must(ParseReportTemplate("report", DefaultReportTemplate))
+	}
+
+	report := Report{Version: toolVersion, Generated: time.Now(), MinLength: jp.config.MinLength}
+	encodings := map[string]int{}
+	artifacts := map[string]int{}
+	var file *ReportFile
+	err := jp.forEachFileString(func(_ int, fileResult FileResult) error {
+		report.Files = append(report.Files, ReportFile{
+			Name:       cmp.Or(fileResult.File, "{standard input}"),
+			Format:     fileResult.Format,
+			Sections:   fileResult.Sections,
+			Components: fileResult.Components,
+			Duplicates: jp.config.Duplicates[fileResult.File],
+			Error:      fileResult.Error,
+			Warnings:   fileResult.Warnings,
+		})
+		file = &report.Files[len(report.Files)-1]
+		if fileResult.Packed && fileResult.Packing != nil {
+			file.Packer = cmp.Or(fileResult.Packing.Packer, fileResult.Packing.Reason, "yes")
+		}
+		if fileResult.Error != "" {
+			report.Failed++
+		}
+		report.Warnings += len(fileResult.Warnings)
+		return nil
+	}, func(_ FileResult, result StringResult) error {
+		report.Strings++
+		report.Bytes += int64(result.Length)
+		encodings[result.Encoding]++
+		file.Count++
+		kinds := rank.Categories([]byte(result.Value))
+		if slices.Contains(kinds, "secret") {
+			result.Value = notify.Mask(result.Value)
+		}
+		if len(file.Strings) < reportMaxStrings {
+			file.Strings = append(file.Strings, result)
+		}
+		for _, kind := range kinds {
+			artifacts[kind]++
+			report.Findings++
+			file.FindingCount++
+			if len(file.Findings) < reportMaxFindings {
+				file.Findings = append(file.Findings, ReportFinding{Kind: kind, Value: result.Value, OffsetHex: result.OffsetHex})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	report.Encodings = sortedCounts(encodings)
+	report.Artifacts = sortedCounts(artifacts)
+
+	w := bufio.NewWriter(jp.writer)
+	if err := tmpl.Execute(w, report); err != nil {
+		return fmt.Errorf("error executing report template: %w", err)
+	}
+	return w.Flush()
+}
+
+// sortedCounts returns counts by name, most first
+func sortedCounts(counts map[string]int) []ReportCount {
+	result := make([]ReportCount, 0, len(counts))
+	for name, count := range counts {
+		result = append(result, ReportCount{Name: name, Count: count})
+	}
+	slices.SortFunc(result, func(a, b ReportCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+	return result
+}
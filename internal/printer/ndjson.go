@@ -0,0 +1,127 @@
+package printer
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// NDJSONPrinter streams strings as newline-delimited JSON, one StringResult
+// per line. Unlike JSONPrinter it buffers nothing, so it suits very large scans.
+type NDJSONPrinter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	err     error // First write error (reported by Flush)
+	// Neighborhoods of the current file (--cluster-by-offset, created on demand)
+	clusters *OffsetClusterer
+	// Cells of the current file if it is a parsed artifact (see SetArtifactCells)
+	artifact ArtifactCells
+}
+
+// NewNDJSONPrinter creates a new NDJSON printer
+func NewNDJSONPrinter(writer io.Writer) *NDJSONPrinter {
+	w := bufio.NewWriter(writer)
+	return &NDJSONPrinter{writer: w, encoder: json.NewEncoder(w)}
+}
+
+// PrintString writes a string result (implements the printFunc signature).
+// The file name is always included so lines from different files can be told apart.
+func (np *NDJSONPrinter) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	if np.err != nil {
+		return
+	}
+	result := NewStringResult(str, filename, offset, config)
+	result.File = filename
+	np.artifact.attribute(&result)
+	if config.ClusterGap > 0 {
+		if np.clusters == nil {
+			np.clusters = &OffsetClusterer{Gap: config.ClusterGap}
+		}
+		result.Cluster = np.clusters.Add(filename, offset, extractor.EncodedLen(str, config.Encoding))
+	}
+	np.err = np.encoder.Encode(result)
+}
+
+// Emit writes a string result (implements extractor.StringSink), returning
+// the first write error
+func (np *NDJSONPrinter) Emit(r extractor.Result) error {
+	np.PrintString(r.Str, r.File, r.Offset, r.Config)
+	return np.err
+}
+
+// Close flushes the output (implements extractor.StringSink)
+func (np *NDJSONPrinter) Close() error {
+	return np.Flush()
+}
+
+// ndjsonWarning is the NDJSON line of a warning, told apart from string lines
+// by its type field
+type ndjsonWarning struct {
+	Type string `json:"type"` // Always "warning"
+	File string `json:"file,omitempty"`
+	WarningResult
+}
+
+// PrintWarning writes a warning line
+func (np *NDJSONPrinter) PrintWarning(w extractor.Warning) {
+	if np.err != nil {
+		return
+	}
+	np.err = np.encoder.Encode(ndjsonWarning{Type: "warning", File: w.File, WarningResult: NewWarningResult(w)})
+}
+
+// ndjsonDuplicate is the NDJSON line of an input that was not scanned for
+// having the content of another (--dedupe-inputs)
+type ndjsonDuplicate struct {
+	Type        string `json:"type"` // Always "duplicate"
+	File        string `json:"file"`
+	DuplicateOf string `json:"duplicate_of"` // The input whose strings apply to File
+}
+
+// PrintDuplicate writes a line telling that the strings of original also
+// apply to duplicate
+func (np *NDJSONPrinter) PrintDuplicate(duplicate, original string) {
+	if np.err != nil {
+		return
+	}
+	np.err = np.encoder.Encode(ndjsonDuplicate{Type: "duplicate", File: duplicate, DuplicateOf: original})
+}
+
+// ndjsonRegion is the NDJSON line of a region (see FileResult.Regions). The
+// region's own type is reported as region_type, since type tells the kind of
+// line apart.
+type ndjsonRegion struct {
+	Type        string `json:"type"` // Always "region"
+	File        string `json:"file,omitempty"`
+	RegionType  string `json:"region_type"`
+	Offset      int64  `json:"offset"`
+	OffsetHex   string `json:"offset_hex"`
+	Size        int64  `json:"size,omitempty"`
+	Description string `json:"description"`
+}
+
+// PrintRegion writes a region line
+func (np *NDJSONPrinter) PrintRegion(filename string, region Region) {
+	if np.err != nil {
+		return
+	}
+	np.err = np.encoder.Encode(ndjsonRegion{
+		Type:        "region",
+		File:        filename,
+		RegionType:  region.Type,
+		Offset:      region.Offset,
+		OffsetHex:   region.OffsetHex,
+		Size:        region.Size,
+		Description: region.Description,
+	})
+}
+
+// Flush writes any buffered output and returns the first write error
+func (np *NDJSONPrinter) Flush() error {
+	if np.err != nil {
+		return np.err
+	}
+	return np.writer.Flush()
+}
@@ -0,0 +1,62 @@
+package printer
+
+import "fmt"
+
+// OffsetCluster is a neighborhood of strings of one file: strings at most a
+// gap apart, which usually belong to the same table or resource
+// (--cluster-by-offset)
+type OffsetCluster struct {
+	ID       int    `json:"id"`    // Position in the file, from 1
+	Start    int64  `json:"start"` // Offset of the first string
+	End      int64  `json:"end"`   // Offset of the last byte of the last string
+	StartHex string `json:"start_hex"`
+	EndHex   string `json:"end_hex"`
+	Strings  int    `json:"strings"`
+}
+
+// OffsetClusterer groups the strings of a file into OffsetClusters as they are
+// reported, in offset order
+type OffsetClusterer struct {
+	Gap      int64           // Largest distance between strings of a cluster
+	Clusters []OffsetCluster // Clusters of the current file so far
+	file     string
+}
+
+// Add adds the string at offset, length bytes long in the input, and returns
+// the ID of its cluster. A string starts a new cluster if it is more than Gap
+// bytes after the end of the previous string, before it (sections of a binary
+// need not be in offset order) or in another file; the clusters of an earlier
+// file are discarded.
+func (c *OffsetClusterer) Add(filename string, offset, length int64) int {
+	if filename != c.file {
+		c.file = filename
+		c.Clusters = nil
+	}
+	end := offset + max(length, 1) - 1
+
+	if n := len(c.Clusters); n > 0 {
+		last := &c.Clusters[n-1]
+		if offset > last.End && offset-last.End-1 <= c.Gap {
+			last.End, last.EndHex = end, fmt.Sprintf("0x%x", end)
+			last.Strings++
+			return last.ID
+		}
+	}
+
+	c.Clusters = append(c.Clusters, OffsetCluster{
+		ID:       len(c.Clusters) + 1,
+		Start:    offset,
+		End:      end,
+		StartHex: fmt.Sprintf("0x%x", offset),
+		EndHex:   fmt.Sprintf("0x%x", end),
+		Strings:  1,
+	})
+	return len(c.Clusters)
+}
+
+// Take returns the clusters of the current file and starts over
+func (c *OffsetClusterer) Take() []OffsetCluster {
+	clusters := c.Clusters
+	c.Clusters, c.file = nil, ""
+	return clusters
+}
@@ -0,0 +1,71 @@
+package printer
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestOffsetClusterer tests how strings are grouped by distance, order and file
+func TestOffsetClusterer(t *testing.T) {
+	type add struct {
+		file   string
+		offset int64
+		length int64
+	}
+	tests := []struct {
+		name     string
+		adds     []add
+		wantIDs  []int
+		wantLast []OffsetCluster // Clusters of the last file
+	}{
+		{
+			"within gap",
+			[]add{{"a", 0, 10}, {"a", 26, 4}, {"a", 40, 8}},
+			[]int{1, 1, 1},
+			[]OffsetCluster{{ID: 1, Start: 0, End: 47, StartHex: "0x0", EndHex: "0x2f", Strings: 3}},
+		},
+		{
+			"beyond gap",
+			[]add{{"a", 0, 10}, {"a", 27, 4}},
+			[]int{1, 2},
+			[]OffsetCluster{
+				{ID: 1, Start: 0, End: 9, StartHex: "0x0", EndHex: "0x9", Strings: 1},
+				{ID: 2, Start: 27, End: 30, StartHex: "0x1b", EndHex: "0x1e", Strings: 1},
+			},
+		},
+		{
+			"backwards",
+			[]add{{"a", 100, 10}, {"a", 50, 10}},
+			[]int{1, 2},
+			[]OffsetCluster{
+				{ID: 1, Start: 100, End: 109, StartHex: "0x64", EndHex: "0x6d", Strings: 1},
+				{ID: 2, Start: 50, End: 59, StartHex: "0x32", EndHex: "0x3b", Strings: 1},
+			},
+		},
+		{
+			"new file",
+			[]add{{"a", 0, 10}, {"b", 12, 10}},
+			[]int{1, 1},
+			[]OffsetCluster{{ID: 1, Start: 12, End: 21, StartHex: "0xc", EndHex: "0x15", Strings: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := OffsetClusterer{Gap: 16}
+			var ids []int
+			for _, a := range tt.adds {
+				ids = append(ids, c.Add(a.file, a.offset, a.length))
+			}
+			if !slices.Equal(ids, tt.wantIDs) {
+				t.Errorf("Add() IDs = %v, want %v", ids, tt.wantIDs)
+			}
+			if got := c.Take(); !slices.Equal(got, tt.wantLast) {
+				t.Errorf("Take() = %+v, want %+v", got, tt.wantLast)
+			}
+			if c.Clusters != nil {
+				t.Error("Take() did not reset the clusters")
+			}
+		})
+	}
+}
@@ -3,56 +3,173 @@
 package printer
 
 import (
+	"bufio"
 	"fmt"
 	"io"
 	"os"
 
+	"github.com/richardwooding/txtr/internal/bufpool"
 	"github.com/richardwooding/txtr/internal/extractor"
 )
 
-// PrintString formats and prints a string with optional filename and offset prefix
+// PrintString formats and prints a string with optional filename and offset
+// prefix. It resolves the Style of config for every string: a TextPrinter
+// resolves it once.
 func PrintString(str []byte, filename string, offset int64, config extractor.Config) {
 	PrintStringToWriter(os.Stdout, str, filename, offset, config)
 }
 
-// PrintStringToWriter is like PrintString but writes to a specific io.Writer
+// PrintStringToWriter is like PrintString but writes to a specific io.Writer.
+// Write errors are ignored; a TextSink reports them.
 func PrintStringToWriter(w io.Writer, str []byte, filename string, offset int64, config extractor.Config) {
-	// Determine if colors should be used
-	useColor := ShouldUseColor(config.ColorMode)
+	_ = writeString(w, str, filename, offset, config, NewStyle(config))
+}
+
+// Style is how text output is styled, as resolved from the ColorMode,
+// Sanitize and Hyperlinks of a configuration. Resolving looks up environment
+// variables and whether stdout is a terminal, so it is done once per run or
+// sink rather than per string.
+type Style struct {
+	Color      bool // ANSI colors
+	Sanitize   bool // Replace terminal control characters
+	Hyperlinks bool // OSC 8 hyperlinks for file names
+}
+
+// NewStyle resolves the style of text output with config
+func NewStyle(config extractor.Config) Style {
+	return Style{
+		Color:      ShouldUseColor(config.ColorMode),
+		Sanitize:   ShouldSanitize(config.Sanitize),
+		Hyperlinks: config.Hyperlinks && ShouldUseHyperlinks(),
+	}
+}
+
+// TextPrinter prints strings like PrintString with a style resolved once.
+// The ColorMode, Sanitize and Hyperlinks of the configurations passed to
+// PrintString are ignored.
+type TextPrinter struct {
+	Writer io.Writer
+	Style  Style
+}
+
+// NewTextPrinter returns a printer writing to w, styled as config asks
+func NewTextPrinter(w io.Writer, config extractor.Config) *TextPrinter {
+	return &TextPrinter{Writer: w, Style: NewStyle(config)}
+}
+
+// PrintString prints a string (implements the printFunc signature). Write
+// errors are ignored.
+func (tp *TextPrinter) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	_ = writeString(tp.Writer, str, filename, offset, config, tp.Style)
+}
+
+// TextSink is a StringSink printing strings like PrintString, with the style
+// of the configuration it was created with. It buffers its output: Close
+// flushes it.
+type TextSink struct {
+	writer *bufio.Writer
+	style  Style
+	err    error // First write error
+}
+
+// NewTextSink returns a text sink writing to w, styled as config asks
+func NewTextSink(w io.Writer, config extractor.Config) *TextSink {
+	return &TextSink{writer: bufio.NewWriter(w), style: NewStyle(config)}
+}
+
+// Emit prints a string, returning the first write error
+func (ts *TextSink) Emit(r extractor.Result) error {
+	if ts.err == nil {
+		ts.err = writeString(ts.writer, r.Str, r.File, r.Offset, r.Config, ts.style)
+	}
+	return ts.err
+}
 
-	prefix := ""
+// Close flushes the output, returning the first write error
+func (ts *TextSink) Close() error {
+	if ts.err != nil {
+		return ts.err
+	}
+	return ts.writer.Flush()
+}
+
+// writeString formats a string as PrintString does and writes it to w
+func writeString(w io.Writer, str []byte, filename string, offset int64, config extractor.Config, style Style) error {
+	useColor := style.Color
+
+	// Format the line into a pooled buffer and write it at once
+	buf := bufpool.Get(bufpool.StringSize)
+	line := *buf
+	defer func() {
+		*buf = line
+		bufpool.Put(buf)
+	}()
 
 	// Add filename prefix with color
 	if config.PrintFileName && filename != "" {
-		filenameStr := filename + ": "
+		name := filename
 		if useColor {
-			filenameStr = ColorString(filename, AnsiBold+AnsiCyan, true) + ": "
+			name = ColorString(filename, AnsiBold+AnsiCyan, true)
+		}
+		if style.Hyperlinks {
+			name = Hyperlink(name, filename)
 		}
-		prefix = filenameStr
+		line = append(append(line, name...), ": "...)
 	}
 
 	// Add offset prefix with color
 	if config.PrintOffset {
-		var offsetStr string
+		var verb string
 		switch config.Radix {
 		case "o":
-			offsetStr = fmt.Sprintf("%7o ", offset)
+			verb = "%7o"
 		case "d":
-			offsetStr = fmt.Sprintf("%7d ", offset)
+			verb = "%7d"
 		case "x":
-			offsetStr = fmt.Sprintf("%7x ", offset)
-		default:
-			offsetStr = ""
+			verb = "%7x"
 		}
-		if useColor && offsetStr != "" {
-			// Color the offset yellow
-			offsetStr = ColorString(offsetStr[:len(offsetStr)-1], AnsiYellow, true) + " "
+		if verb != "" {
+			if useColor {
+				// Color the offset yellow
+				line = append(line, ColorString(fmt.Sprintf(verb, offset), AnsiYellow, true)...)
+			} else {
+				line = fmt.Appendf(line, verb, offset)
+			}
+			line = append(line, ' ')
+		}
+	}
+
+	// Add line number prefix of the lines of text inputs with color
+	if config.Line > 0 {
+		if useColor {
+			line = append(line, ColorString(fmt.Sprintf("%7d:", config.Line), AnsiGreen, true)...)
+		} else {
+			line = fmt.Appendf(line, "%7d:", config.Line)
 		}
-		prefix += offsetStr
+		line = append(line, ' ')
+	}
+
+	// Add the rarity of the string in the corpus of --background with color
+	if config.Background != nil {
+		if useColor {
+			line = append(line, ColorString(fmt.Sprintf("%.2f", config.Rarity), AnsiMagenta, true)...)
+		} else {
+			line = fmt.Appendf(line, "%.2f", config.Rarity)
+		}
+		line = append(line, ' ')
+	}
+
+	// Add the rule of --secrets the string matched with color
+	if config.Secrets {
+		rule := "[" + extractor.DetectSecret(str) + "]"
+		if useColor {
+			rule = ColorString(rule, AnsiBold, true)
+		}
+		line = append(append(line, rule...), ' ')
 	}
 
 	// Determine string color based on encoding
-	stringOutput := string(str)
+	stringOutput := renderValue(str, config, style.Sanitize)
 	if useColor {
 		switch config.Encoding {
 		case "S": // 8-bit ASCII (high-byte)
@@ -68,6 +185,7 @@ func PrintStringToWriter(w io.Writer, str []byte, filename string, offset int64,
 			// Default: no color (white/default terminal color)
 		}
 	}
+	line = append(line, stringOutput...)
 
 	// Use custom output separator if specified, otherwise use newline
 	separator := config.OutputSeparator
@@ -78,10 +196,8 @@ func PrintStringToWriter(w io.Writer, str []byte, filename string, offset int64,
 		// Dim the separator if it's custom
 		separator = ColorString(separator, AnsiDim, true)
 	}
+	line = append(line, separator...)
 
-	if _, err := fmt.Fprintf(w, "%s%s%s", prefix, stringOutput, separator); err != nil {
-		// Error writing to writer, but we can't do much about it in this context
-		// The caller should handle writer errors appropriately
-		return
-	}
+	_, err := w.Write(line)
+	return err
 }
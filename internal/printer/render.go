@@ -0,0 +1,117 @@
+package printer
+
+import (
+	"fmt"
+	"os"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// ShouldSanitize determines if terminal control characters should be replaced
+// in text output, based on the mode and whether stdout is a TTY
+func ShouldSanitize(mode extractor.ColorMode) bool {
+	switch mode {
+	case extractor.ColorAlways:
+		return true
+	case extractor.ColorNever:
+		return false
+	default:
+		return isTerminal(os.Stdout)
+	}
+}
+
+// RenderValue prepares an extracted string for text output: non-printable
+// characters are escaped (EscapeNonPrint) or terminal control characters
+// replaced (Sanitize), then the result is truncated to MaxWidth characters.
+func RenderValue(str []byte, config extractor.Config) string {
+	return renderValue(str, config, ShouldSanitize(config.Sanitize))
+}
+
+// renderValue is RenderValue with the Sanitize mode resolved
+func renderValue(str []byte, config extractor.Config, sanitizeControls bool) string {
+	var value string
+	switch {
+	case config.EscapeNonPrint:
+		value = escapeNonPrint(str)
+	case config.Unicode != "highlight" && sanitizeControls:
+		// -U highlight emits its own escape sequences
+		value = sanitize(str)
+	default:
+		value = string(str)
+	}
+
+	if config.MaxWidth > 0 && utf8.RuneCountInString(value) > config.MaxWidth {
+		n := 0
+		for i := range value {
+			if n == config.MaxWidth-1 {
+				return value[:i] + "…"
+			}
+			n++
+		}
+	}
+	return value
+}
+
+// cEscapes are the C escape sequences of control characters
+var cEscapes = map[rune]string{
+	'\a': `\a`, '\b': `\b`, '\t': `\t`, '\n': `\n`, '\v': `\v`, '\f': `\f`, '\r': `\r`, '\\': `\\`,
+}
+
+// escapeNonPrint writes control characters, invalid UTF-8 and other
+// non-printable characters as C-style escapes (\n, \x1b), keeping printable
+// UTF-8. Backslashes are escaped so the output is unambiguous.
+func escapeNonPrint(str []byte) string {
+	out := make([]byte, 0, len(str))
+	for i := 0; i < len(str); {
+		r, size := utf8.DecodeRune(str[i:])
+		switch {
+		case cEscapes[r] != "" && size == 1:
+			out = append(out, cEscapes[r]...)
+		case r == utf8.RuneError && size == 1, r != ' ' && !unicode.IsPrint(r):
+			for _, b := range str[i : i+size] {
+				out = fmt.Appendf(out, `\x%02x`, b)
+			}
+		default:
+			out = append(out, str[i:i+size]...)
+		}
+		i += size
+	}
+	return string(out)
+}
+
+// sanitize replaces characters that control the terminal or reorder the text
+// around them with '?': C0 controls other than tab and newline, DEL, C1
+// controls (as UTF-8 or as raw bytes, which some terminals interpret) and
+// bidirectional overrides. Other characters, including raw 8-bit bytes, are
+// kept as extracted.
+func sanitize(str []byte) string {
+	out := make([]byte, 0, len(str))
+	for i := 0; i < len(str); {
+		r, size := utf8.DecodeRune(str[i:])
+		if r == utf8.RuneError && size == 1 {
+			r = rune(str[i]) // Raw byte
+		}
+		if isTerminalControl(r) {
+			out = append(out, '?')
+		} else {
+			out = append(out, str[i:i+size]...)
+		}
+		i += size
+	}
+	return string(out)
+}
+
+// isTerminalControl reports whether r is replaced by sanitize
+func isTerminalControl(r rune) bool {
+	switch {
+	case r == '\t' || r == '\n':
+		return false
+	case r < 0x20, r >= 0x7f && r <= 0x9f:
+		return true
+	case r >= 0x202a && r <= 0x202e, r >= 0x2066 && r <= 0x2069:
+		return true
+	}
+	return false
+}
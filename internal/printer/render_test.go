@@ -0,0 +1,39 @@
+package printer
+
+import (
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestRenderValue tests escaping, sanitizing and truncating strings for text output
+func TestRenderValue(t *testing.T) {
+	tests := []struct {
+		name   string
+		str    string
+		config extractor.Config
+		want   string
+	}{
+		{"plain", "hello world", extractor.Config{Sanitize: extractor.ColorAlways}, "hello world"},
+		{"sanitize controls", "a\rb\x1b[2Jc\td\ne", extractor.Config{Sanitize: extractor.ColorAlways}, "a?b?[2Jc\td\ne"},
+		{"sanitize C1", "x\u009b31my \x9bz", extractor.Config{Sanitize: extractor.ColorAlways}, "x?31my ?z"},
+		{"sanitize bidi", "abc\u202edef", extractor.Config{Sanitize: extractor.ColorAlways}, "abc?def"},
+		{"sanitize keeps 8-bit", "caf\xe9 über", extractor.Config{Sanitize: extractor.ColorAlways}, "caf\xe9 über"},
+		{"sanitize never", "a\rb", extractor.Config{Sanitize: extractor.ColorNever}, "a\rb"},
+		{"highlight mode", "\x1b[1m\\u00e9\x1b[0m", extractor.Config{Sanitize: extractor.ColorAlways, Unicode: "highlight"}, "\x1b[1m\\u00e9\x1b[0m"},
+		{"escape", "tab\there\r\n\\ \x1b\x00 über \xff", extractor.Config{EscapeNonPrint: true}, `tab\there\r\n\\ \x1b\x00 über \xff`},
+		{"escape bidi", "a\u202eb", extractor.Config{EscapeNonPrint: true}, `a\xe2\x80\xaeb`},
+		{"max width", "abcdefghij", extractor.Config{MaxWidth: 5}, "abcd…"},
+		{"max width exact", "abcde", extractor.Config{MaxWidth: 5}, "abcde"},
+		{"max width runes", "ääääää", extractor.Config{MaxWidth: 3}, "ää…"},
+		{"max width after escape", "a\nbcdef", extractor.Config{EscapeNonPrint: true, MaxWidth: 4}, `a\n…`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderValue([]byte(tt.str), tt.config); got != tt.want {
+				t.Errorf("RenderValue(%q) = %q, want %q", tt.str, got, tt.want)
+			}
+		})
+	}
+}
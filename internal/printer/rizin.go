@@ -0,0 +1,127 @@
+package printer
+
+import (
+	"bufio"
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// SectionRange is where a section of a binary is in the file and in memory
+// (see binary.Section)
+type SectionRange struct {
+	Name   string
+	Offset int64
+	Size   int64
+	Addr   uint64 // Virtual address the section is loaded at
+}
+
+// rizinString is a string in the shape of rizin's izj command
+type rizinString struct {
+	File    string `json:"file,omitempty"` // Only with several files (not part of izj)
+	VAddr   uint64 `json:"vaddr"`
+	PAddr   int64  `json:"paddr"`
+	Ordinal int    `json:"ordinal"`
+	Size    int64  `json:"size"`   // Bytes in the file
+	Length  int    `json:"length"` // Characters
+	Section string `json:"section"`
+	Type    string `json:"type"`
+	String  string `json:"string"`
+}
+
+// SetSectionLayout records where the sections of the current file are loaded,
+// used to report virtual addresses and section names (see FlushRizin). It
+// applies to the current file only and is cleared by SetFileInfo.
+func (jp *JSONPrinter) SetSectionLayout(layout []SectionRange) {
+	jp.currentLayout = layout
+}
+
+// FlushRizin outputs all collected strings as a JSON array in the shape of
+// rizin's (and radare2's) izj command, so tools written for it can read
+// txtr's results. Virtual addresses and section names come from the section
+// layout (see SetSectionLayout); strings outside any section, or of files
+// without a layout, report their file offset as vaddr and an empty section.
+// Ordinals count the strings of each file. With several files, each string
+// also has a file field. Files that failed are omitted; relocation strings are
+// not included.
+func (jp *JSONPrinter) FlushRizin() error {
+	// Finalize any remaining current file
+	if jp.currentFile != "" || len(jp.currentStrings) > 0 {
+		jp.FinalizeCurrentFile()
+	}
+
+	w := bufio.NewWriter(jp.writer)
+	_ = w.WriteByte('[')
+
+	multiFile := len(jp.FileResults) > 1
+	count, ordinal := 0, 0
+	lastFile := ""
+	err := jp.forEachString(func(fileResult FileResult, result StringResult) error {
+		if count == 0 || fileResult.File != lastFile {
+			lastFile = fileResult.File
+			ordinal = 0
+		}
+
+		entry := rizinString{
+			VAddr:   uint64(result.Offset),
+			PAddr:   result.Offset,
+			Ordinal: ordinal,
+			Length:  utf8.RuneCountInString(result.Value),
+			String:  result.Value,
+		}
+		if multiFile {
+			entry.File = fileResult.File
+		}
+		var encoding string
+		entry.Type, encoding = rizinType(result)
+		entry.Size = extractor.EncodedLen([]byte(result.Value), encoding)
+		for _, section := range fileResult.Layout {
+			if result.Offset >= section.Offset && result.Offset < section.Offset+section.Size {
+				entry.VAddr = section.Addr + uint64(result.Offset-section.Offset)
+				entry.Section = section.Name
+				break
+			}
+		}
+
+		data, err := json.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		if count > 0 {
+			_ = w.WriteByte(',')
+		}
+		_, err = w.Write(data)
+		count++
+		ordinal++
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	_, _ = w.WriteString("]\n")
+	return w.Flush()
+}
+
+// rizinType returns the rizin string type of result and the extractor
+// encoding it was found with
+func rizinType(result StringResult) (string, string) {
+	switch result.Encoding {
+	case "utf-16le":
+		return "utf16le", "l"
+	case "utf-16be":
+		return "utf16be", "b"
+	case "utf-32le":
+		return "utf32le", "L"
+	case "utf-32be":
+		return "utf32be", "B"
+	}
+	// 8-bit strings holding multi-byte characters are UTF-8 (-U)
+	for i := 0; i < len(result.Value); i++ {
+		if result.Value[i] >= utf8.RuneSelf {
+			return "utf8", "S"
+		}
+	}
+	return "ascii", "s"
+}
@@ -0,0 +1,63 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestJSONPrinterFlushRizin tests the izj-shaped output of collected strings
+func TestJSONPrinterFlushRizin(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+	jp := NewJSONPrinter(config, &buf)
+
+	jp.SetFileInfo("app.elf", "ELF", []string{".rodata"})
+	jp.SetSectionLayout([]SectionRange{{Name: ".rodata", Offset: 0x2000, Size: 0x100, Addr: 0x402000}})
+	jp.PrintString([]byte("hello"), "app.elf", 0x2010, config)
+	jp.PrintString([]byte("outside"), "app.elf", 0x3000, config)
+
+	// Layout must not carry over to the next file
+	jp.SetFileInfo("other.bin", "", nil)
+	wide := config
+	wide.Encoding = "l"
+	jp.PrintString([]byte("caf\xc3\xa9"), "other.bin", 0x2010, wide)
+
+	if err := jp.FlushRizin(); err != nil {
+		t.Fatalf("FlushRizin() error = %v", err)
+	}
+
+	var got []rizinString
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, buf.String())
+	}
+	want := []rizinString{
+		{File: "app.elf", VAddr: 0x402010, PAddr: 0x2010, Ordinal: 0, Size: 5, Length: 5, Section: ".rodata", Type: "ascii", String: "hello"},
+		{File: "app.elf", VAddr: 0x3000, PAddr: 0x3000, Ordinal: 1, Size: 7, Length: 7, Type: "ascii", String: "outside"},
+		{File: "other.bin", VAddr: 0x2010, PAddr: 0x2010, Ordinal: 0, Size: 8, Length: 4, Type: "utf16le", String: "café"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FlushRizin() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+// TestJSONPrinterFlushRizinSingleFile tests that a single file's strings have no file field
+func TestJSONPrinterFlushRizinSingleFile(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+	jp := NewJSONPrinter(config, &buf)
+
+	jp.SetFileInfo("app.elf", "", nil)
+	jp.PrintString([]byte("hello"), "app.elf", 0, config)
+	if err := jp.FlushRizin(); err != nil {
+		t.Fatalf("FlushRizin() error = %v", err)
+	}
+
+	want := `[{"vaddr":0,"paddr":0,"ordinal":0,"size":5,"length":5,"section":"","type":"ascii","string":"hello"}]` + "\n"
+	if buf.String() != want {
+		t.Errorf("FlushRizin() = %s, want %s", buf.String(), want)
+	}
+}
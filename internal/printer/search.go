@@ -0,0 +1,185 @@
+package printer
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// SearchDocument is a string as a document for a full-text search engine:
+// flat, with a unique id, so it can be fed as is to tantivy (tantivy index,
+// see SearchSchema) or bleve (Index.Index(id, document)) and searched across
+// the strings of thousands of files
+type SearchDocument struct {
+	// Unique and stable across runs: the file, offset and encoding of the string
+	ID       string `json:"id"`
+	File     string `json:"file"`
+	FileName string `json:"file_name"` // Base name of File, for searches by sample name
+	Format   string `json:"format,omitempty"`
+	Value    string `json:"value"`
+	Offset   int64  `json:"offset"`
+	Length   int    `json:"length"`
+	Encoding string `json:"encoding"`
+}
+
+// SearchField describes a field of SearchDocument for the schema of a search
+// engine index
+type SearchField struct {
+	Name string
+	Type string // "text" (tokenized for full-text search), "keyword" (matched whole) or "integer"
+}
+
+// SearchSchema lists the fields of SearchDocument in order
+var SearchSchema = []SearchField{
+	{"id", "keyword"},
+	{"file", "keyword"},
+	{"file_name", "keyword"},
+	{"format", "keyword"},
+	{"value", "text"},
+	{"offset", "integer"},
+	{"length", "integer"},
+	{"encoding", "keyword"},
+}
+
+// SearchPrinter streams strings as SearchDocuments, one JSON object per line
+type SearchPrinter struct {
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	err     error  // First write error (reported by Flush)
+	format  string // Binary format of the current file (see SetFormat)
+}
+
+// NewSearchPrinter creates a new search document printer
+func NewSearchPrinter(writer io.Writer) *SearchPrinter {
+	w := bufio.NewWriter(writer)
+	return &SearchPrinter{writer: w, encoder: json.NewEncoder(w)}
+}
+
+// SetFormat sets the binary format of the current file (-d only)
+func (sp *SearchPrinter) SetFormat(format string) {
+	sp.format = format
+}
+
+// PrintString writes a string document (implements the printFunc signature)
+func (sp *SearchPrinter) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	if sp.err != nil {
+		return
+	}
+	result := NewStringResult(str, filename, offset, config)
+	name := filename
+	if name == "" {
+		name = "{standard input}"
+	}
+	sp.err = sp.encoder.Encode(SearchDocument{
+		ID:       fmt.Sprintf("%s@%s/%s", name, result.OffsetHex, result.Encoding),
+		File:     name,
+		FileName: filepath.Base(name),
+		Format:   sp.format,
+		Value:    result.Value,
+		Offset:   result.Offset,
+		Length:   result.Length,
+		Encoding: result.Encoding,
+	})
+}
+
+// Flush writes any buffered output and returns the first write error
+func (sp *SearchPrinter) Flush() error {
+	if sp.err != nil {
+		return sp.err
+	}
+	return sp.writer.Flush()
+}
+
+// tantivyField is a field of a tantivy schema (as read by tantivy-cli)
+type tantivyField struct {
+	Name    string         `json:"name"`
+	Type    string         `json:"type"`
+	Options map[string]any `json:"options"`
+}
+
+// WriteTantivySchema writes the tantivy schema of SearchDocument: value is
+// tokenized with positions for phrase queries, the other text fields are
+// indexed whole and the numbers are fast fields
+func WriteTantivySchema(w io.Writer) error {
+	fields := make([]tantivyField, 0, len(SearchSchema))
+	for _, field := range SearchSchema {
+		switch field.Type {
+		case "text":
+			fields = append(fields, tantivyField{field.Name, "text", map[string]any{
+				"indexing": map[string]any{"record": "position", "fieldnorms": true, "tokenizer": "default"},
+				"stored":   true,
+				"fast":     false,
+			}})
+		case "keyword":
+			fields = append(fields, tantivyField{field.Name, "text", map[string]any{
+				"indexing": map[string]any{"record": "basic", "fieldnorms": false, "tokenizer": "raw"},
+				"stored":   true,
+				"fast":     false,
+			}})
+		default:
+			fields = append(fields, tantivyField{field.Name, "i64", map[string]any{
+				"indexed":    true,
+				"fieldnorms": false,
+				"fast":       true,
+				"stored":     true,
+			}})
+		}
+	}
+	return writeIndented(w, fields)
+}
+
+// bleveField is a field mapping of a bleve index mapping
+type bleveField struct {
+	Name               string `json:"name"`
+	Type               string `json:"type"`
+	Analyzer           string `json:"analyzer,omitempty"`
+	Store              bool   `json:"store"`
+	Index              bool   `json:"index"`
+	IncludeTermVectors bool   `json:"include_term_vectors"`
+	IncludeInAll       bool   `json:"include_in_all"`
+}
+
+// bleveProperty is the document mapping of a property of a bleve index mapping
+type bleveProperty struct {
+	Enabled bool         `json:"enabled"`
+	Dynamic bool         `json:"dynamic"`
+	Fields  []bleveField `json:"fields"`
+}
+
+// WriteBleveMapping writes the bleve index mapping of SearchDocument (for
+// bleve create -m): value is analyzed by the standard analyzer with term
+// vectors for highlighting, the other text fields by the keyword analyzer
+func WriteBleveMapping(w io.Writer) error {
+	properties := make(map[string]bleveProperty, len(SearchSchema))
+	for _, field := range SearchSchema {
+		mapping := bleveField{Name: field.Name, Type: "text", Store: true, Index: true}
+		switch field.Type {
+		case "text":
+			mapping.Analyzer = "standard"
+			mapping.IncludeTermVectors = true
+			mapping.IncludeInAll = true
+		case "keyword":
+			mapping.Analyzer = "keyword"
+		default:
+			mapping.Type = "number"
+		}
+		properties[field.Name] = bleveProperty{Enabled: true, Fields: []bleveField{mapping}}
+	}
+	return writeIndented(w, map[string]any{
+		"default_mapping":  map[string]any{"enabled": true, "dynamic": false, "properties": properties},
+		"type_field":       "_type",
+		"default_type":     "_default",
+		"default_analyzer": "standard",
+	})
+}
+
+// writeIndented writes v as indented JSON
+func writeIndented(w io.Writer, v any) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
@@ -0,0 +1,120 @@
+package printer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestSearchPrinter tests that strings are written as search documents with
+// unique ids and the fields of SearchSchema
+func TestSearchPrinter(t *testing.T) {
+	var buf bytes.Buffer
+	sp := NewSearchPrinter(&buf)
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+
+	sp.SetFormat("ELF")
+	sp.PrintString([]byte("hello"), "dir/a.bin", 16, config)
+	sp.PrintString([]byte("hello"), "dir/a.bin", 32, config)
+	sp.SetFormat("")
+	config.Encoding = "l"
+	sp.PrintString([]byte("hello"), "dir/a.bin", 16, config)
+	sp.PrintString([]byte("stdin"), "", 0, config)
+	if err := sp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var documents []SearchDocument
+	ids := make(map[string]bool)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var document SearchDocument
+		if err := json.Unmarshal(scanner.Bytes(), &document); err != nil {
+			t.Fatalf("invalid document %q: %v", scanner.Text(), err)
+		}
+		if ids[document.ID] {
+			t.Errorf("duplicate id %q", document.ID)
+		}
+		ids[document.ID] = true
+		documents = append(documents, document)
+
+		var fields map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &fields); err != nil {
+			t.Fatal(err)
+		}
+		for name := range fields {
+			if !slices.ContainsFunc(SearchSchema, func(f SearchField) bool { return f.Name == name }) {
+				t.Errorf("field %q is not in SearchSchema", name)
+			}
+		}
+	}
+	if len(documents) != 4 {
+		t.Fatalf("got %d documents, want 4", len(documents))
+	}
+
+	want := SearchDocument{ID: "dir/a.bin@0x10/ascii-7bit", File: "dir/a.bin", FileName: "a.bin", Format: "ELF", Value: "hello", Offset: 16, Length: 5, Encoding: "ascii-7bit"}
+	if documents[0] != want {
+		t.Errorf("document = %+v, want %+v", documents[0], want)
+	}
+	if documents[2].Format != "" || documents[2].Encoding != "utf-16le" {
+		t.Errorf("document = %+v, want no format and utf-16le", documents[2])
+	}
+	if documents[3].File != "{standard input}" {
+		t.Errorf("stdin document file = %q", documents[3].File)
+	}
+}
+
+// TestSearchSchemas tests that the tantivy schema and bleve mapping declare
+// every field of SearchSchema
+func TestSearchSchemas(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteTantivySchema(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var fields []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatalf("invalid tantivy schema: %v", err)
+	}
+	if len(fields) != len(SearchSchema) {
+		t.Fatalf("tantivy schema has %d fields, want %d", len(fields), len(SearchSchema))
+	}
+	for i, field := range fields {
+		if field.Name != SearchSchema[i].Name {
+			t.Errorf("tantivy field %d = %q, want %q", i, field.Name, SearchSchema[i].Name)
+		}
+	}
+
+	buf.Reset()
+	if err := WriteBleveMapping(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var mapping struct {
+		DefaultMapping struct {
+			Properties map[string]struct {
+				Fields []struct {
+					Type string `json:"type"`
+				} `json:"fields"`
+			} `json:"properties"`
+		} `json:"default_mapping"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &mapping); err != nil {
+		t.Fatalf("invalid bleve mapping: %v", err)
+	}
+	for _, field := range SearchSchema {
+		property, ok := mapping.DefaultMapping.Properties[field.Name]
+		if !ok || len(property.Fields) != 1 {
+			t.Errorf("bleve mapping lacks field %q", field.Name)
+			continue
+		}
+		if (property.Fields[0].Type == "number") != (field.Type == "integer") {
+			t.Errorf("bleve field %q has type %q", field.Name, property.Fields[0].Type)
+		}
+	}
+}
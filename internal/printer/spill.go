@@ -0,0 +1,257 @@
+package printer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+)
+
+// stringResultOverhead approximates the memory used by a StringResult beyond
+// its value (struct fields, offset string, slice growth)
+const stringResultOverhead = 96
+
+// spillState holds strings that were moved to a temporary file once the
+// printer's memory limit was exceeded.
+//
+// Every spill moves all buffered strings to disk in file order, so the spill
+// file always holds a prefix of each file's strings, grouped by file. Flush
+// replays them before the strings still in memory.
+type spillState struct {
+	file    *os.File
+	writer  *bufio.Writer
+	encoder *json.Encoder
+	err     error // First error encountered while spilling (reported by Flush)
+
+	strings int   // Number of spilled strings
+	bytes   int64 // Total length of spilled strings
+}
+
+// SetMemoryLimit sets the approximate number of bytes of string results the
+// printer may buffer before spilling them to a temporary file (0 = unlimited)
+func (jp *JSONPrinter) SetMemoryLimit(limit int64) {
+	jp.memoryLimit = limit
+}
+
+// trackMemory accounts for a newly buffered string result and spills all
+// buffered results if the memory limit is exceeded
+func (jp *JSONPrinter) trackMemory(result StringResult) {
+	if jp.memoryLimit <= 0 {
+		return
+	}
+
+	jp.bufferedBytes += int64(len(result.Value)) + stringResultOverhead
+	if jp.bufferedBytes > jp.memoryLimit {
+		jp.spillBuffered()
+	}
+}
+
+// spillBuffered moves all buffered strings (finalized files first, then the
+// current file) to the spill file
+func (jp *JSONPrinter) spillBuffered() {
+	if jp.spill.err != nil {
+		return
+	}
+
+	if jp.spill.file == nil {
+		file, err := os.CreateTemp("", "txtr-spill-*.jsonl")
+		if err != nil {
+			jp.spill.err = fmt.Errorf("error creating spill file: %w", err)
+			return
+		}
+		jp.spill.file = file
+		jp.spill.writer = bufio.NewWriter(file)
+		jp.spill.encoder = json.NewEncoder(jp.spill.writer)
+	}
+
+	for i := range jp.FileResults {
+		jp.FileResults[i].spilled += jp.spillStrings(jp.FileResults[i].Strings)
+		jp.FileResults[i].Strings = make([]StringResult, 0)
+	}
+
+	jp.currentSpilled += jp.spillStrings(jp.currentStrings)
+	jp.currentStrings = make([]StringResult, 0)
+
+	jp.bufferedBytes = 0
+}
+
+// spillStrings writes results to the spill file and returns how many were written
+func (jp *JSONPrinter) spillStrings(results []StringResult) int {
+	for i, result := range results {
+		if err := jp.spill.encoder.Encode(result); err != nil {
+			jp.spill.err = fmt.Errorf("error writing spill file: %w", err)
+			return i
+		}
+		jp.spill.strings++
+		jp.spill.bytes += int64(result.Length)
+	}
+	return len(results)
+}
+
+// closeSpill closes and removes the spill file
+func (jp *JSONPrinter) closeSpill() {
+	if jp.spill.file == nil {
+		return
+	}
+	name := jp.spill.file.Name()
+	_ = jp.spill.file.Close()
+	_ = os.Remove(name)
+	jp.spill.file = nil
+}
+
+// writeSpilled streams the JSON output, replaying spilled strings from disk.
+// The output is identical to encoding the complete JSONOutput with the
+// printer's indentation.
+func (jp *JSONPrinter) writeSpilled(summary Summary) error {
+	defer jp.closeSpill()
+
+	if jp.spill.err != nil {
+		return jp.spill.err
+	}
+	if err := jp.spill.writer.Flush(); err != nil {
+		return fmt.Errorf("error writing spill file: %w", err)
+	}
+	if _, err := jp.spill.file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("error reading spill file: %w", err)
+	}
+	decoder := json.NewDecoder(bufio.NewReader(jp.spill.file))
+
+	w := bufio.NewWriter(jp.writer)
+
+	if _, err := io.WriteString(w, "{\n  \"files\": ["); err != nil {
+		return err
+	}
+
+	for i, fileResult := range jp.FileResults {
+		if i > 0 {
+			if _, err := io.WriteString(w, ","); err != nil {
+				return err
+			}
+		}
+
+		// Encode the file result without its strings, then stream the
+		// strings array into the placeholder
+		inMemory := fileResult.Strings
+		fileResult.Strings = nil
+		header, err := json.MarshalIndent(fileResult, "    ", "  ")
+		if err != nil {
+			return err
+		}
+		placeholder := []byte("\n      \"strings\": null")
+		before, after, found := bytes.Cut(header, placeholder)
+		if !found {
+			return fmt.Errorf("internal error: strings placeholder not found")
+		}
+
+		if _, err := fmt.Fprintf(w, "\n    %s\n      \"strings\": ", before); err != nil {
+			return err
+		}
+
+		count := fileResult.spilled + len(inMemory)
+		if count == 0 {
+			if _, err := io.WriteString(w, "[]"); err != nil {
+				return err
+			}
+		} else {
+			if _, err := io.WriteString(w, "["); err != nil {
+				return err
+			}
+			for j := range count {
+				var result StringResult
+				if j < fileResult.spilled {
+					if err := decoder.Decode(&result); err != nil {
+						return fmt.Errorf("error reading spill file: %w", err)
+					}
+				} else {
+					result = inMemory[j-fileResult.spilled]
+				}
+
+				encoded, err := json.MarshalIndent(result, "        ", "  ")
+				if err != nil {
+					return err
+				}
+				sep := ","
+				if j == 0 {
+					sep = ""
+				}
+				if _, err := fmt.Fprintf(w, "%s\n        %s", sep, encoded); err != nil {
+					return err
+				}
+			}
+			if _, err := io.WriteString(w, "\n      ]"); err != nil {
+				return err
+			}
+		}
+
+		if _, err := w.Write(after); err != nil {
+			return err
+		}
+	}
+
+	if len(jp.FileResults) > 0 {
+		if _, err := io.WriteString(w, "\n  "); err != nil {
+			return err
+		}
+	}
+
+	encodedSummary, err := json.MarshalIndent(summary, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "],\n  \"summary\": %s\n}\n", encodedSummary); err != nil {
+		return err
+	}
+
+	return w.Flush()
+}
+
+// forEachString calls fn for every collected string in output order, replaying
+// spilled strings from disk. The spill file is removed afterwards.
+func (jp *JSONPrinter) forEachString(fn func(fileResult FileResult, result StringResult) error) error {
+	return jp.forEachFileString(nil, fn)
+}
+
+// forEachFileString is forEachString also calling begin (if not nil) at the
+// start of every file, including files without strings
+func (jp *JSONPrinter) forEachFileString(begin func(i int, fileResult FileResult) error, fn func(fileResult FileResult, result StringResult) error) error {
+	var decoder *json.Decoder
+	if jp.spill.file != nil || jp.spill.err != nil {
+		defer jp.closeSpill()
+
+		if jp.spill.err != nil {
+			return jp.spill.err
+		}
+		if err := jp.spill.writer.Flush(); err != nil {
+			return fmt.Errorf("error writing spill file: %w", err)
+		}
+		if _, err := jp.spill.file.Seek(0, io.SeekStart); err != nil {
+			return fmt.Errorf("error reading spill file: %w", err)
+		}
+		decoder = json.NewDecoder(bufio.NewReader(jp.spill.file))
+	}
+
+	for i, fileResult := range jp.FileResults {
+		if begin != nil {
+			if err := begin(i, fileResult); err != nil {
+				return err
+			}
+		}
+		for range fileResult.spilled {
+			var result StringResult
+			if err := decoder.Decode(&result); err != nil {
+				return fmt.Errorf("error reading spill file: %w", err)
+			}
+			if err := fn(fileResult, result); err != nil {
+				return err
+			}
+		}
+		for _, result := range fileResult.Strings {
+			if err := fn(fileResult, result); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// fillJSONPrinter writes the same mix of files and strings to a printer
+func fillJSONPrinter(jp *JSONPrinter, config extractor.Config) {
+	jp.SetFileInfo("first.bin", "ELF", []string{".rodata", ".data"})
+	for i := range 50 {
+		jp.PrintString(fmt.Appendf(nil, "first string <%d> & more", i), "first.bin", int64(i*32), config)
+	}
+	jp.PrintRelocString([]byte("relocated"), "first.bin", 0x40, config)
+
+	jp.SetFileInfo("empty.bin", "", nil)
+
+	jp.SetFileInfo("second.bin", "", nil)
+	for i := range 30 {
+		jp.PrintString(fmt.Appendf(nil, "second \"quoted\" %d", i), "second.bin", int64(i*16), config)
+	}
+	jp.FinalizeCurrentFile()
+
+	jp.AddFileResult("missing.bin", "", nil, nil, errors.New("no such file"))
+	jp.AppendFileResult(FileResult{
+		File:    "worker.bin",
+		Strings: []StringResult{{Value: "from worker", Length: 11, OffsetHex: "0x0", Encoding: "ascii-7bit"}},
+	})
+}
+
+// TestJSONPrinterSpillMatchesInMemory tests that spilled output is byte-identical
+func TestJSONPrinterSpillMatchesInMemory(t *testing.T) {
+	config := extractor.Config{MinLength: 4, Encoding: "s", PrintFileName: true}
+
+	var want bytes.Buffer
+	jp := NewJSONPrinter(config, &want)
+	fillJSONPrinter(jp, config)
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	for _, limit := range []int64{1, 500, 4096} {
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			var got bytes.Buffer
+			spilling := NewJSONPrinter(config, &got)
+			spilling.SetMemoryLimit(limit)
+			fillJSONPrinter(spilling, config)
+
+			if spilling.spill.file == nil {
+				t.Fatal("expected printer to spill to disk")
+			}
+			if err := spilling.Flush(); err != nil {
+				t.Fatalf("Flush() error = %v", err)
+			}
+			if spilling.spill.file != nil {
+				t.Error("spill file not cleaned up after Flush()")
+			}
+
+			if got.String() != want.String() {
+				t.Errorf("spilled output differs from in-memory output\ngot:\n%s\nwant:\n%s", got.String(), want.String())
+			}
+		})
+	}
+}
+
+// TestJSONPrinterSpillEmpty tests streaming output with no files
+func TestJSONPrinterSpillEmpty(t *testing.T) {
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+
+	var buf bytes.Buffer
+	jp := NewJSONPrinter(config, &buf)
+	jp.SetMemoryLimit(1)
+	jp.spillBuffered()
+
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var output JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("Invalid JSON output: %v\n%s", err, buf.String())
+	}
+	if len(output.Files) != 0 || output.Summary.TotalStrings != 0 {
+		t.Errorf("unexpected output: %+v", output)
+	}
+}
+
+// TestJSONPrinterNoLimitDoesNotSpill tests that spilling is disabled by default
+func TestJSONPrinterNoLimitDoesNotSpill(t *testing.T) {
+	config := extractor.Config{MinLength: 4, Encoding: "s"}
+
+	var buf bytes.Buffer
+	jp := NewJSONPrinter(config, &buf)
+	fillJSONPrinter(jp, config)
+
+	if jp.spill.file != nil {
+		t.Error("printer without memory limit should not spill")
+	}
+}
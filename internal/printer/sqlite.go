@@ -0,0 +1,274 @@
+//go:build sqlite
+
+package printer
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+
+	_ "modernc.org/sqlite" // Registers the "sqlite" database/sql driver
+)
+
+// sqliteBatch is the number of strings inserted per transaction
+const sqliteBatch = 10000
+
+// sqliteSchema creates the tables of a SQLitePrinter database. The indexes
+// (sqliteIndexes) are created once all strings are inserted, which is much
+// faster than updating them on every insert.
+const sqliteSchema = `
+CREATE TABLE metadata (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE files (
+	id           INTEGER PRIMARY KEY,
+	path         TEXT NOT NULL,
+	format       TEXT,
+	string_count INTEGER NOT NULL DEFAULT 0,
+	error        TEXT
+);
+CREATE TABLE sections (
+	id       INTEGER PRIMARY KEY,
+	file_id  INTEGER NOT NULL REFERENCES files(id),
+	name     TEXT NOT NULL,
+	"offset" INTEGER NOT NULL,
+	size     INTEGER NOT NULL,
+	address  INTEGER NOT NULL
+);
+CREATE TABLE strings (
+	id         INTEGER PRIMARY KEY,
+	file_id    INTEGER NOT NULL REFERENCES files(id),
+	section_id INTEGER REFERENCES sections(id),
+	value      TEXT NOT NULL,
+	"offset"   INTEGER NOT NULL,
+	length     INTEGER NOT NULL,
+	encoding   TEXT NOT NULL
+);
+`
+
+// sqliteIndexes indexes the strings by value and by file and offset
+const sqliteIndexes = `
+CREATE INDEX strings_value ON strings(value);
+CREATE INDEX strings_file_offset ON strings(file_id, "offset");
+CREATE INDEX sections_file ON sections(file_id);
+`
+
+// SQLitePrinter writes the strings of a scan into a SQLite database, for SQL
+// queries over the strings of large corpora: files has a row per file,
+// sections the sections of binaries (-d) and strings a row per string, in
+// the section it was found in if known. metadata records the extraction
+// settings. Rows are inserted in transactions of sqliteBatch strings.
+type SQLitePrinter struct {
+	db     *sql.DB
+	tx     *sql.Tx
+	insert *sql.Stmt // Inserts a string in tx
+	batch  int       // Strings inserted in tx
+	err    error     // First error (reported by Close)
+
+	file     int64          // Row of the current file (0 = none)
+	count    int            // Strings of the current file
+	layout   []SectionRange // Sections of the current file
+	sections []int64        // Rows of layout
+}
+
+// NewSQLitePrinter creates the SQLite database at path, replacing any file
+// there, and records the settings of config in its metadata table
+func NewSQLitePrinter(path string, config extractor.Config) (*SQLitePrinter, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+	// One connection, so the pragmas apply to every statement. The database
+	// is written once: a crash loses it whatever the journal.
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("PRAGMA journal_mode = OFF; PRAGMA synchronous = OFF;" + sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("cannot create database %s: %w", path, err)
+	}
+
+	sp := &SQLitePrinter{db: db}
+	for key, value := range sqliteMetadata(config) {
+		sp.SetMetadata(key, value)
+	}
+	if sp.err != nil {
+		_ = db.Close()
+		return nil, sp.err
+	}
+	return sp, nil
+}
+
+// sqliteMetadata returns the extraction settings of config recorded in the
+// metadata table
+func sqliteMetadata(config extractor.Config) map[string]string {
+	scan := "all"
+	if config.ScanDataOnly {
+		scan = "data"
+	}
+	metadata := map[string]string{
+		"created":    time.Now().UTC().Format(time.RFC3339),
+		"min_length": strconv.Itoa(config.MinLength),
+		"encoding":   EncodingName(config.Encoding),
+		"scan":       scan,
+	}
+	if config.ValueEncoding == ValueBase64 || config.ValueEncoding == ValueHex {
+		metadata["value_encoding"] = config.ValueEncoding
+	}
+	if config.MaxStrings > 0 {
+		metadata["max_strings"] = strconv.Itoa(config.MaxStrings)
+	}
+	if len(config.MatchPatterns) > 0 {
+		metadata["match"] = joinPatterns(config.MatchPatterns)
+	}
+	if len(config.ExcludePatterns) > 0 {
+		metadata["exclude"] = joinPatterns(config.ExcludePatterns)
+	}
+	return metadata
+}
+
+// joinPatterns lists patterns one per line
+func joinPatterns(patterns []*regexp.Regexp) string {
+	sources := make([]string, len(patterns))
+	for i, pattern := range patterns {
+		sources[i] = pattern.String()
+	}
+	return strings.Join(sources, "\n")
+}
+
+// SetMetadata records key in the metadata table, replacing an earlier value
+func (sp *SQLitePrinter) SetMetadata(key, value string) {
+	sp.exec("INSERT OR REPLACE INTO metadata (key, value) VALUES (?, ?)", key, value)
+}
+
+// BeginFile adds a file and the sections of layout, where its strings are
+// attributed to. format is its binary format (-d only).
+func (sp *SQLitePrinter) BeginFile(filename, format string, layout []SectionRange) {
+	if filename == "" {
+		filename = "{standard input}"
+	}
+	sp.file = sp.exec("INSERT INTO files (path, format) VALUES (?, ?)", filename, nullString(format))
+	sp.count = 0
+	sp.layout = layout
+	sp.sections = make([]int64, len(layout))
+	for i, section := range layout {
+		sp.sections[i] = sp.exec(`INSERT INTO sections (file_id, name, "offset", size, address) VALUES (?, ?, ?, ?, ?)`,
+			sp.file, section.Name, section.Offset, section.Size, int64(section.Addr))
+	}
+}
+
+// PrintString inserts a string of the current file (implements the printFunc
+// signature)
+func (sp *SQLitePrinter) PrintString(str []byte, filename string, offset int64, config extractor.Config) {
+	if sp.err != nil {
+		return
+	}
+	if sp.file == 0 {
+		// Strings without BeginFile, e.g. of the library
+		sp.BeginFile(filename, "", nil)
+	}
+	if sp.begin(); sp.err != nil {
+		return
+	}
+
+	var section any
+	for i, s := range sp.layout {
+		if offset >= s.Offset && offset < s.Offset+s.Size {
+			section = sp.sections[i]
+			break
+		}
+	}
+	result := NewStringResult(str, filename, offset, config)
+	if _, sp.err = sp.insert.Exec(sp.file, section, result.Value, offset, result.Length, result.Encoding); sp.err != nil {
+		return
+	}
+	sp.count++
+	if sp.batch++; sp.batch == sqliteBatch {
+		sp.commit()
+	}
+}
+
+// EndFile records the number of strings of the current file and err, the
+// error that cut its scan short if not nil
+func (sp *SQLitePrinter) EndFile(err error) {
+	if sp.file == 0 {
+		return
+	}
+	var message any
+	if err != nil {
+		message = err.Error()
+	}
+	sp.exec("UPDATE files SET string_count = ?, error = ? WHERE id = ?", sp.count, message, sp.file)
+	sp.file = 0
+}
+
+// Close commits the strings, creates the indexes and closes the database. It
+// returns the first error writing it.
+func (sp *SQLitePrinter) Close() error {
+	sp.EndFile(nil)
+	sp.commit()
+	if sp.err == nil {
+		_, sp.err = sp.db.Exec(sqliteIndexes)
+	}
+	if err := sp.db.Close(); err != nil && sp.err == nil {
+		sp.err = err
+	}
+	return sp.err
+}
+
+// begin opens a transaction if none is open
+func (sp *SQLitePrinter) begin() {
+	if sp.tx != nil || sp.err != nil {
+		return
+	}
+	if sp.tx, sp.err = sp.db.Begin(); sp.err != nil {
+		return
+	}
+	sp.insert, sp.err = sp.tx.Prepare(`INSERT INTO strings (file_id, section_id, value, "offset", length, encoding) VALUES (?, ?, ?, ?, ?, ?)`)
+}
+
+// commit commits the open transaction, if any
+func (sp *SQLitePrinter) commit() {
+	if sp.tx == nil {
+		return
+	}
+	if sp.err == nil {
+		sp.err = sp.tx.Commit()
+	} else {
+		_ = sp.tx.Rollback()
+	}
+	sp.tx, sp.insert, sp.batch = nil, nil, 0
+}
+
+// exec runs a statement in the open transaction, returning the row it
+// inserted
+func (sp *SQLitePrinter) exec(query string, args ...any) int64 {
+	if sp.begin(); sp.err != nil {
+		return 0
+	}
+	result, err := sp.tx.Exec(query, args...)
+	if err != nil {
+		sp.err = err
+		return 0
+	}
+	id, _ := result.LastInsertId()
+	return id
+}
+
+// nullString returns s, or nil (NULL) if it is empty
+func nullString(s string) any {
+	if s == "" {
+		return nil
+	}
+	return s
+}
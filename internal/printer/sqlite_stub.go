@@ -0,0 +1,41 @@
+//go:build !sqlite
+
+package printer
+
+import (
+	"errors"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// ErrNoSQLite is returned by NewSQLitePrinter in builds without the sqlite
+// tag: the pure Go SQLite library would grow the binary several times over
+// for one output format
+var ErrNoSQLite = errors.New("SQLite output is not built in (build txtr with -tags sqlite)")
+
+// SQLitePrinter stands in for the SQLite database writer of builds with the
+// sqlite tag. NewSQLitePrinter always fails, so there is none to call the
+// methods of.
+type SQLitePrinter struct{}
+
+// NewSQLitePrinter returns ErrNoSQLite, leaving any file at path alone
+func NewSQLitePrinter(string, extractor.Config) (*SQLitePrinter, error) {
+	return nil, ErrNoSQLite
+}
+
+// SetMetadata does nothing
+func (*SQLitePrinter) SetMetadata(string, string) {}
+
+// BeginFile does nothing
+func (*SQLitePrinter) BeginFile(string, string, []SectionRange) {}
+
+// PrintString does nothing
+func (*SQLitePrinter) PrintString([]byte, string, int64, extractor.Config) {}
+
+// EndFile does nothing
+func (*SQLitePrinter) EndFile(error) {}
+
+// Close returns ErrNoSQLite
+func (*SQLitePrinter) Close() error {
+	return ErrNoSQLite
+}
@@ -0,0 +1,27 @@
+//go:build !sqlite
+
+package printer
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestSQLitePrinterNotBuilt tests that builds without the sqlite tag refuse
+// SQLite output, leaving the file in its path alone
+func TestSQLitePrinterNotBuilt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	if err := os.WriteFile(path, []byte("kept"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSQLitePrinter(path, extractor.Config{MinLength: 4, Encoding: "s"}); !errors.Is(err, ErrNoSQLite) {
+		t.Errorf("NewSQLitePrinter() error = %v, want ErrNoSQLite", err)
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != "kept" {
+		t.Errorf("file after NewSQLitePrinter() = %q, %v, want it kept", data, err)
+	}
+}
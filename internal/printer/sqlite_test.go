@@ -0,0 +1,91 @@
+//go:build sqlite
+
+package printer
+
+import (
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestSQLitePrinter tests that files, sections, strings and the extraction
+// settings are written to the database, and strings attributed to their
+// sections
+func TestSQLitePrinter(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	config := extractor.Config{MinLength: 4, Encoding: "s", ScanDataOnly: true}
+	sp, err := NewSQLitePrinter(path, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sp.SetMetadata("version", "1.2.3")
+
+	sp.BeginFile("a.bin", "ELF", []SectionRange{{Name: ".rodata", Offset: 0x100, Size: 0x100, Addr: 0x400100}})
+	sp.PrintString([]byte("in rodata"), "a.bin", 0x180, config)
+	sp.PrintString([]byte("outside"), "a.bin", 0x10, config)
+	sp.EndFile(nil)
+	sp.BeginFile("b.bin", "", nil)
+	// More than a batch, so several transactions are committed
+	for i := range sqliteBatch + 1 {
+		sp.PrintString([]byte("many"), "b.bin", int64(i*8), config)
+	}
+	sp.EndFile(errors.New("read failed"))
+	if err := sp.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var scan, version, minLength string
+	if err := db.QueryRow("SELECT (SELECT value FROM metadata WHERE key = 'scan'), (SELECT value FROM metadata WHERE key = 'version'), (SELECT value FROM metadata WHERE key = 'min_length')").Scan(&scan, &version, &minLength); err != nil {
+		t.Fatal(err)
+	}
+	if scan != "data" || version != "1.2.3" || minLength != "4" {
+		t.Errorf("metadata scan=%q version=%q min_length=%q, want data, 1.2.3 and 4", scan, version, minLength)
+	}
+
+	var count int
+	var fileErr sql.NullString
+	if err := db.QueryRow("SELECT string_count, error FROM files WHERE path = 'b.bin'").Scan(&count, &fileErr); err != nil {
+		t.Fatal(err)
+	}
+	if count != sqliteBatch+1 || fileErr.String != "read failed" {
+		t.Errorf("b.bin has string_count %d and error %q, want %d and %q", count, fileErr.String, sqliteBatch+1, "read failed")
+	}
+
+	rows, err := db.Query(`SELECT s.value, s."offset", s.encoding, sec.name FROM strings s JOIN files f ON f.id = s.file_id LEFT JOIN sections sec ON sec.id = s.section_id WHERE f.path = 'a.bin' ORDER BY s."offset"`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var got []string
+	for rows.Next() {
+		var value, encoding string
+		var offset int64
+		var section sql.NullString
+		if err := rows.Scan(&value, &offset, &encoding, &section); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, value+"@"+section.String+"/"+encoding)
+	}
+	want := []string{"outside@/ascii-7bit", "in rodata@.rodata/ascii-7bit"}
+	if !slices.Equal(got, want) {
+		t.Errorf("strings of a.bin = %q, want %q", got, want)
+	}
+
+	var indexes int
+	if err := db.QueryRow("SELECT count(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = 'strings'").Scan(&indexes); err != nil {
+		t.Fatal(err)
+	}
+	if indexes != 2 {
+		t.Errorf("strings has %d indexes, want 2 (value, file and offset)", indexes)
+	}
+}
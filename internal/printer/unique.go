@@ -0,0 +1,88 @@
+package printer
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// addUnique collects a string result in unique mode (--unique/--count): the
+// first occurrence of a value in the current file is kept, later ones only
+// increment its count and, with AllOffsets, extend its offset list. With
+// FoldCase, values differing only in case are collapsed too, and the distinct
+// spellings are listed in Variants. It reports false if the value was not seen
+// before and the caller should append result.
+func (jp *JSONPrinter) addUnique(result *StringResult, config extractor.Config) bool {
+	if jp.uniqueIndex == nil {
+		jp.uniqueIndex = make(map[string]int)
+	}
+
+	key := result.Value
+	if config.FoldCase {
+		key = strings.ToLower(key)
+	}
+	i, seen := jp.uniqueIndex[key]
+	if !seen {
+		jp.uniqueIndex[key] = len(jp.currentStrings)
+		if config.Count {
+			result.Count = 1
+		}
+		if config.AllOffsets {
+			result.Offsets = []int64{result.Offset}
+		}
+		return false
+	}
+
+	first := &jp.currentStrings[i]
+	if config.Count {
+		first.Count++
+	}
+	if config.AllOffsets && (config.MaxOffsets <= 0 || len(first.Offsets) < config.MaxOffsets) {
+		first.Offsets = append(first.Offsets, result.Offset)
+	}
+	if config.FoldCase && result.Value != first.Value && !slices.Contains(first.Variants, result.Value) {
+		if first.Variants == nil {
+			first.Variants = []string{first.Value}
+		}
+		first.Variants = append(first.Variants, result.Value)
+	}
+	return true
+}
+
+// csvUniqueHeader returns the extra CSV columns of unique mode
+func csvUniqueHeader(config extractor.Config) []string {
+	var columns []string
+	if config.Count {
+		columns = append(columns, "count")
+	}
+	if config.AllOffsets {
+		columns = append(columns, "offsets")
+	}
+	if config.FoldCase {
+		columns = append(columns, "variants")
+	}
+	return columns
+}
+
+// csvUniqueFields returns the values of the extra CSV columns of unique mode.
+// Offsets are written in decimal, separated by semicolons; case variants are
+// separated by newlines, as values may contain semicolons.
+func csvUniqueFields(result StringResult, config extractor.Config) []string {
+	var fields []string
+	if config.Count {
+		fields = append(fields, strconv.Itoa(result.Count))
+	}
+	if config.AllOffsets {
+		offsets := make([]string, len(result.Offsets))
+		for i, offset := range result.Offsets {
+			offsets[i] = strconv.FormatInt(offset, 10)
+		}
+		fields = append(fields, strings.Join(offsets, ";"))
+	}
+	if config.FoldCase {
+		fields = append(fields, strings.Join(result.Variants, "\n"))
+	}
+	return fields
+}
@@ -0,0 +1,119 @@
+package printer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// TestJSONPrinterUnique tests collapsing duplicates with counts and offsets
+func TestJSONPrinterUnique(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s", Unique: true, Count: true, AllOffsets: true, MaxOffsets: 2}
+	jp := NewJSONPrinter(config, &buf)
+
+	jp.SetFileInfo("a.bin", "", nil)
+	for i, value := range []string{"dup!", "once", "dup!", "dup!"} {
+		jp.PrintString([]byte(value), "a.bin", int64(i*8), config)
+	}
+	jp.SetFileInfo("b.bin", "", nil)
+	jp.PrintString([]byte("dup!"), "b.bin", 4, config)
+
+	if err := jp.Flush(); err != nil {
+		t.Fatalf("Flush() error = %v", err)
+	}
+
+	var output JSONOutput
+	if err := json.Unmarshal(buf.Bytes(), &output); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	a := output.Files[0].Strings
+	if len(a) != 2 {
+		t.Fatalf("a.bin has %d strings, want 2: %+v", len(a), a)
+	}
+	if a[0].Value != "dup!" || a[0].Offset != 0 || a[0].Count != 3 || !slices.Equal(a[0].Offsets, []int64{0, 16}) {
+		t.Errorf("collapsed string = %+v, want count 3 at offsets [0 16] (capped)", a[0])
+	}
+	if a[1].Count != 1 || !slices.Equal(a[1].Offsets, []int64{8}) {
+		t.Errorf("single string = %+v, want count 1 at offsets [8]", a[1])
+	}
+
+	// Duplicates are collapsed per file
+	b := output.Files[1].Strings
+	if len(b) != 1 || b[0].Count != 1 || b[0].Offset != 4 {
+		t.Errorf("b.bin strings = %+v, want dup! once at offset 4", b)
+	}
+	if output.Summary.TotalStrings != 3 {
+		t.Errorf("TotalStrings = %d, want 3", output.Summary.TotalStrings)
+	}
+}
+
+// TestJSONPrinterUniqueCSV tests the count and offsets columns of CSV output
+func TestJSONPrinterUniqueCSV(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s", Unique: true, Count: true, AllOffsets: true}
+	jp := NewJSONPrinter(config, &buf)
+
+	jp.SetFileInfo("a.bin", "", nil)
+	jp.PrintString([]byte("dup!"), "a.bin", 0, config)
+	jp.PrintString([]byte("dup!"), "a.bin", 10, config)
+
+	if err := jp.FlushCSV(); err != nil {
+		t.Fatalf("FlushCSV() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+
+	want := [][]string{
+		append(slices.Clone(csvHeader), "count", "offsets"),
+		{"a.bin", "0", "0x0", "4", "ascii-7bit", "dup!", "2", "0;10"},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d: %v", len(records), len(want), records)
+	}
+	for i := range want {
+		if !slices.Equal(records[i], want[i]) {
+			t.Errorf("record %d = %v, want %v", i, records[i], want[i])
+		}
+	}
+}
+
+// TestJSONPrinterFoldCase tests collapsing strings that differ only in case
+func TestJSONPrinterFoldCase(t *testing.T) {
+	var buf bytes.Buffer
+	config := extractor.Config{MinLength: 4, Encoding: "s", Unique: true, Count: true, FoldCase: true}
+	jp := NewJSONPrinter(config, &buf)
+
+	jp.SetFileInfo("fw.bin", "", nil)
+	for i, value := range []string{"ERROR", "error", "Error", "error", "warning"} {
+		jp.PrintString([]byte(value), "fw.bin", int64(i*8), config)
+	}
+
+	if err := jp.FlushCSV(); err != nil {
+		t.Fatalf("FlushCSV() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("output is not valid CSV: %v", err)
+	}
+
+	want := [][]string{
+		append(slices.Clone(csvHeader), "count", "variants"),
+		{"fw.bin", "0", "0x0", "5", "ascii-7bit", "ERROR", "4", "ERROR\nerror\nError"},
+		{"fw.bin", "32", "0x20", "7", "ascii-7bit", "warning", "1", ""},
+	}
+	if len(records) != len(want) {
+		t.Fatalf("got %d records, want %d: %v", len(records), len(want), records)
+	}
+	for i := range want {
+		if !slices.Equal(records[i], want[i]) {
+			t.Errorf("record %d = %q, want %q", i, records[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,277 @@
+// Package rank scores extracted strings by how much they are likely to tell an
+// analyst about an unfamiliar binary, for a quick triage summary (--top). A
+// score combines the kind of artifact a string holds (URLs, commands, key
+// material...), how much it reads like text rather than random bytes, and how
+// rare it is (strings in the common strings database or repeated many times
+// score lower).
+package rank
+
+import (
+	"cmp"
+	"fmt"
+	"math"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/fingerprint"
+	"github.com/richardwooding/txtr/internal/noise"
+)
+
+// secretPattern matches key material and credentials
+var secretPattern = regexp.MustCompile(`-----BEGIN [A-Z ]*(?:PRIVATE KEY|CERTIFICATE)-----|\bAKIA[0-9A-Z]{16}\b|\bgh[pousr]_[A-Za-z0-9]{36}\b|\bxox[abprs]-[A-Za-z0-9-]{10,}`)
+
+// category is a kind of artifact recognized in strings
+type category struct {
+	name    string
+	weight  float64
+	pattern *regexp.Regexp
+}
+
+// categories lists the artifact categories, reported in this order
+var categories = []category{
+	{"secret", 10, secretPattern},
+	{"url", 8, regexp.MustCompile(`(?i)\b(?:https?|ftp|wss?)://[^\s"'<>]+`)},
+	{"command", 7, regexp.MustCompile(`(?i)\bcmd(?:\.exe)?\s+/[ck]\b|\bpowershell(?:\.exe)?\s+-|/bin/(?:ba)?sh\s+-c\b|\bwget\s+\S|\bcurl\s+-|\bchmod\s+[+0-7]|\b(?:rundll32|schtasks|certutil|vssadmin|bcdedit)(?:\.exe)?\b`)},
+	{"email", 6, regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`)},
+	{"ip address", 6, regexp.MustCompile(`\b(?:25[0-5]|2[0-4]\d|1?\d?\d)(?:\.(?:25[0-5]|2[0-4]\d|1?\d?\d)){3}\b`)},
+	{"registry key", 5, regexp.MustCompile(`(?i)\b(?:HKEY_[A-Z_]+|HK(?:LM|CU|CR|U))\\|\bSOFTWARE\\(?:Microsoft|Classes|Policies|Wow6432Node)\\`)},
+	{"user agent", 5, regexp.MustCompile(`\bMozilla/\d\.\d \(`)},
+	{"path", 4, regexp.MustCompile(`(?:^|[\s"'=(])(?:/(?:usr|etc|bin|sbin|var|tmp|home|opt|dev|proc|root|Library|Users|System|Applications)/|[A-Za-z]:\\|\\\\[A-Za-z0-9.$-]+\\|%[A-Za-z]+%\\)`)},
+}
+
+// IsSecret reports whether str holds key material or a credential (the
+// "secret" category), e.g. a private key header or a cloud access key
+func IsSecret(str []byte) bool {
+	return secretPattern.Match(str)
+}
+
+// Categories returns the names of the artifact categories str belongs to,
+// e.g. "url" or "secret", in the order of their weight
+func Categories(str []byte) []string {
+	var names []string
+	for _, c := range categories {
+		if c.pattern.Match(str) {
+			names = append(names, c.name)
+		}
+	}
+	return names
+}
+
+// Artifact is a notable artifact found in a string
+type Artifact struct {
+	Kind  string // A category name (see Categories) or "domain"
+	Value string
+}
+
+// matched lists the categories whose artifacts are the text matched, which
+// is part of a longer string; the artifacts of the other categories are the
+// whole string
+var matched = map[string]bool{"secret": true, "url": true, "email": true, "ip address": true}
+
+// Extract returns the distinct artifacts of str, in the order of their
+// weight: the secrets, URLs and email and IP addresses it contains, the host
+// of each URL as a "domain", and str itself for the other categories (e.g. a
+// command line)
+func Extract(str []byte) []Artifact {
+	var artifacts []Artifact
+	add := func(artifact Artifact) {
+		if !slices.Contains(artifacts, artifact) {
+			artifacts = append(artifacts, artifact)
+		}
+	}
+	for _, c := range categories {
+		if !matched[c.name] {
+			if c.pattern.Match(str) {
+				add(Artifact{Kind: c.name, Value: strings.TrimSpace(string(str))})
+			}
+			continue
+		}
+		for _, match := range c.pattern.FindAll(str, -1) {
+			add(Artifact{Kind: c.name, Value: string(match)})
+			if c.name != "url" {
+				continue
+			}
+			if u, err := url.Parse(string(match)); err == nil && u.Hostname() != "" {
+				add(Artifact{Kind: "domain", Value: strings.ToLower(u.Hostname())})
+			}
+		}
+	}
+	return artifacts
+}
+
+// Score weights of the other signals
+const (
+	componentWeight = 6   // Version banner of an identified component
+	formatWeight    = 1   // printf-style format string
+	textWeight      = 4   // Multiplies the confidence (0-1)
+	commonPenalty   = 10  // String in the common strings database
+	maxLengthScore  = 6.0 // Cap of the log2 length bonus (64 characters)
+	readable        = 0.5 // Confidence from which a string is "readable"
+)
+
+// Ranked is a distinct string with its score
+type Ranked struct {
+	Value   []byte
+	File    string // File of the first occurrence
+	Offset  int64  // Offset of the first occurrence
+	Count   int    // Occurrences in all files
+	Score   float64
+	Reasons []string // What the score is based on, most significant first
+}
+
+// Ranker collects strings and ranks them. Strings are deduplicated by value
+// across files.
+type Ranker struct {
+	common  *noise.Database // nil = no common strings database
+	index   map[string]int  // Value → position in entries
+	entries []Ranked
+}
+
+// New returns a ranker that penalizes strings in the common strings database
+// common (if non-nil)
+func New(common *noise.Database) *Ranker {
+	return &Ranker{common: common, index: make(map[string]int)}
+}
+
+// Add records an occurrence of str. The extractor reuses str, so it is copied.
+func (r *Ranker) Add(str []byte, filename string, offset int64) {
+	if i, ok := r.index[string(str)]; ok {
+		r.entries[i].Count++
+		return
+	}
+	r.index[string(str)] = len(r.entries)
+	r.entries = append(r.entries, Ranked{Value: slices.Clone(str), File: filename, Offset: offset, Count: 1})
+}
+
+// Len returns the number of distinct strings added
+func (r *Ranker) Len() int {
+	return len(r.entries)
+}
+
+// Top returns the k highest-scoring strings, best first. Equal scores keep
+// the order strings were first added in.
+func (r *Ranker) Top(k int) []Ranked {
+	ranked := make([]Ranked, len(r.entries))
+	for i, entry := range r.entries {
+		entry.Score, entry.Reasons = Score(entry.Value, entry.Count, r.common)
+		ranked[i] = entry
+	}
+	slices.SortStableFunc(ranked, func(a, b Ranked) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+	return ranked[:min(k, len(ranked))]
+}
+
+// Score rates str, found count times, and lists the reasons for the score.
+// Higher is more interesting; the scale is open-ended and only meaningful for
+// comparing strings.
+func Score(str []byte, count int, common *noise.Database) (float64, []string) {
+	var reasons []string
+	score := min(math.Log2(float64(max(utf8.RuneCount(str), 1))), maxLengthScore)
+
+	for _, c := range categories {
+		if c.pattern.Match(str) {
+			score += c.weight
+			reasons = append(reasons, c.name)
+		}
+	}
+	if lib, ok := fingerprint.Identify(str); ok {
+		score += componentWeight
+		reasons = append(reasons, "component "+lib.Name+" "+lib.Version)
+	}
+	if extractor.IsFormatString(str) {
+		score += formatWeight
+		reasons = append(reasons, "format string")
+	}
+
+	conf := Confidence(str)
+	score += textWeight * conf
+	if conf >= readable {
+		reasons = append(reasons, "readable")
+	}
+
+	if common != nil && common.Contains(str) {
+		score -= commonPenalty
+		reasons = append(reasons, "common")
+	}
+	if count > 1 {
+		score -= math.Log2(float64(count))
+		reasons = append(reasons, fmt.Sprintf("seen %d times", count))
+	}
+	return score, reasons
+}
+
+// Confidence estimates how likely str is real text rather than bytes that
+// happen to be printable: the fraction of it made of word-like runs of
+// letters. A run is word-like if it has three or more letters, a plausible
+// share of vowels and consistent case (lower, upper, Capitalized or
+// camelCase). Non-ASCII letters always count as word-like.
+func Confidence(str []byte) float64 {
+	if len(str) == 0 {
+		return 0
+	}
+	words := 0
+	for i := 0; i < len(str); {
+		r, size := utf8.DecodeRune(str[i:])
+		if !unicode.IsLetter(r) {
+			i += size
+			continue
+		}
+		if r >= utf8.RuneSelf {
+			words += size
+			i += size
+			continue
+		}
+		j := i
+		for j < len(str) && isASCIILetter(str[j]) {
+			j++
+		}
+		if isWordLike(str[i:j]) {
+			words += j - i
+		}
+		i = j
+	}
+	return float64(words) / float64(len(str))
+}
+
+// isASCIILetter reports whether b is an ASCII letter
+func isASCIILetter(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z'
+}
+
+// isWordLike reports whether a run of ASCII letters looks like a word or a
+// sequence of camelCase words
+func isWordLike(run []byte) bool {
+	if len(run) < 3 {
+		return false
+	}
+	vowels, upper, caseChanges := 0, 0, 0
+	for i, b := range run {
+		switch b | 0x20 {
+		case 'a', 'e', 'i', 'o', 'u', 'y':
+			vowels++
+		}
+		isUpper := b <= 'Z'
+		if isUpper {
+			upper++
+		}
+		// Lower to upper starts a camelCase word
+		if i > 0 && isUpper && run[i-1] > 'Z' {
+			caseChanges++
+		}
+	}
+	ratio := float64(vowels) / float64(len(run))
+	if ratio < 0.2 || ratio > 0.75 {
+		return false
+	}
+	// Every camelCase word should be at least three letters long
+	if upper != len(run) && caseChanges*3 > len(run) {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,42 @@
+package registry
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzWalk tests walking hives with random inputs
+func FuzzWalk(f *testing.F) {
+	// Seed corpus: the hive of the Walk tests, a truncated copy and bare
+	// signatures
+	hive := sampleHive()
+	f.Add(hive)
+	f.Add(hive[:hbinStart+64])
+	f.Add(append([]byte("regf"), make([]byte, hbinStart)...))
+	f.Add([]byte("regf"))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		// Errors are expected for invalid input
+		_ = Walk(data, func(text Text) error {
+			// Invariant: each text is the data at its offset
+			if text.Offset < 0 || text.Offset+int64(len(text.Data)) > int64(len(data)) ||
+				!bytes.Equal(data[text.Offset:text.Offset+int64(len(text.Data))], text.Data) {
+				t.Errorf("Offset 0x%x does not hold %q", text.Offset, text.Data)
+			}
+			return nil
+		})
+	})
+}
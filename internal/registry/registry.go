@@ -0,0 +1,285 @@
+// Package registry reads Windows registry hive files (SYSTEM, SOFTWARE,
+// NTUSER.DAT...) by walking their key and value cells, so the text they hold
+// can be scanned without the cell headers and hashes around it and attributed
+// to its key.
+package registry
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+)
+
+// Value types holding text
+const (
+	TypeSZ         = 1 // REG_SZ
+	TypeExpandSZ   = 2 // REG_EXPAND_SZ
+	TypeLink       = 6 // REG_LINK
+	TypeMultiSZ    = 7 // REG_MULTI_SZ
+	DefaultValue   = "(default)"
+	hbinStart      = 4096  // File offset of the first hive bin; cell offsets are relative to it
+	bigDataSegment = 16344 // Bytes of data per segment of a big data record
+	maxDepth       = 512   // Deepest key nesting Windows allows
+	noCell         = 0xffffffff
+)
+
+// Text is text stored in a hive: the name of a key, the name of a value or the
+// data of a string value
+type Text struct {
+	Key    string // Path of the key, e.g. \Microsoft\Windows\CurrentVersion\Run (the root key is \)
+	Value  string // Name of the value (DefaultValue for the unnamed value); "" for key names
+	Offset int64  // File offset of Data
+	Data   []byte
+	UTF16  bool // Data is UTF-16LE; otherwise names are ASCII/Latin-1
+}
+
+// ErrNotHive is returned for files that do not start with a hive header
+var ErrNotHive = errors.New("not a registry hive")
+
+var errCorrupt = errors.New("corrupt registry hive")
+
+// IsHive reports whether data starts with the "regf" signature of a hive
+func IsHive(data []byte) bool {
+	return len(data) >= 4 && string(data[:4]) == "regf"
+}
+
+// hive is a hive file held in memory
+type hive struct {
+	data    []byte
+	visited map[uint32]bool // Key cells already walked, which stops loops
+	fn      func(Text) error
+}
+
+// Walk calls fn with the text of a hive: depth first from the root key, each
+// key's name, then the name and string data of each of its values, then its
+// subkeys. Strings of REG_MULTI_SZ values are reported together. It stops at
+// the first error fn returns; cells that cannot be read end the walk of their
+// key with an error wrapping errCorrupt, after the text found so far.
+func Walk(data []byte, fn func(Text) error) error {
+	if !IsHive(data) || len(data) < hbinStart {
+		return ErrNotHive
+	}
+	h := &hive{data: data, visited: make(map[uint32]bool), fn: fn}
+	root := binary.LittleEndian.Uint32(data[36:])
+	return h.walkKey(root, "", 0)
+}
+
+// cell returns the data of the cell at offset (relative to the first hive
+// bin) and its file offset
+func (h *hive) cell(offset uint32) ([]byte, int64, error) {
+	pos := int64(hbinStart) + int64(offset)
+	if offset == noCell || pos+4 > int64(len(h.data)) {
+		return nil, 0, fmt.Errorf("%w: cell offset 0x%x", errCorrupt, offset)
+	}
+	// Allocated cells have a negative size, which includes the size field
+	size := -int64(int32(binary.LittleEndian.Uint32(h.data[pos:])))
+	if size < 4 || pos+size > int64(len(h.data)) {
+		return nil, 0, fmt.Errorf("%w: cell at 0x%x", errCorrupt, pos)
+	}
+	return h.data[pos+4 : pos+size], pos + 4, nil
+}
+
+// walkKey walks the key whose "nk" cell is at offset; parent is the path of
+// its parent ("" for the root key)
+func (h *hive) walkKey(offset uint32, parent string, depth int) error {
+	if h.visited[offset] || depth > maxDepth {
+		return nil
+	}
+	h.visited[offset] = true
+
+	nk, pos, err := h.cell(offset)
+	if err != nil {
+		return err
+	}
+	if len(nk) < 76 || string(nk[:2]) != "nk" {
+		return fmt.Errorf("%w: key at 0x%x", errCorrupt, pos)
+	}
+	le := binary.LittleEndian
+	flags := le.Uint16(nk[2:])
+	nameLen := int(le.Uint16(nk[72:]))
+	if 76+nameLen > len(nk) {
+		return fmt.Errorf("%w: key name at 0x%x", errCorrupt, pos)
+	}
+	nameData := nk[76 : 76+nameLen]
+	compressed := flags&0x0020 != 0 // KEY_COMP_NAME: Latin-1 rather than UTF-16
+
+	path := `\`
+	if depth > 0 {
+		path = strings.TrimSuffix(parent, `\`) + `\` + decodeName(nameData, compressed)
+		if err := h.fn(Text{Key: path, Offset: pos + 76, Data: nameData, UTF16: !compressed}); err != nil {
+			return err
+		}
+	}
+
+	if err := h.walkValues(le.Uint32(nk[40:]), int(le.Uint32(nk[36:])), path); err != nil {
+		return err
+	}
+
+	subkeys, err := h.subkeys(le.Uint32(nk[28:]), int(le.Uint32(nk[20:])), 0)
+	if err != nil {
+		return err
+	}
+	for _, subkey := range subkeys {
+		if err := h.walkKey(subkey, path, depth+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// subkeys returns the key cell offsets of a subkey list (lf, lh, li, or ri
+// listing further lists)
+func (h *hive) subkeys(offset uint32, count, depth int) ([]uint32, error) {
+	if count == 0 || offset == noCell {
+		return nil, nil
+	}
+	list, pos, err := h.cell(offset)
+	if err != nil {
+		return nil, err
+	}
+	if len(list) < 4 || depth > 1 {
+		return nil, fmt.Errorf("%w: subkey list at 0x%x", errCorrupt, pos)
+	}
+	le := binary.LittleEndian
+	n := int(le.Uint16(list[2:]))
+
+	var stride int
+	switch string(list[:2]) {
+	case "lf", "lh":
+		stride = 8 // Offset and name hash
+	case "li", "ri":
+		stride = 4
+	default:
+		return nil, fmt.Errorf("%w: subkey list at 0x%x", errCorrupt, pos)
+	}
+	if 4+n*stride > len(list) {
+		return nil, fmt.Errorf("%w: subkey list at 0x%x", errCorrupt, pos)
+	}
+
+	var keys []uint32
+	for i := range n {
+		entry := le.Uint32(list[4+i*stride:])
+		if string(list[:2]) != "ri" {
+			keys = append(keys, entry)
+			continue
+		}
+		// An index root lists subkey lists
+		more, err := h.subkeys(entry, -1, depth+1)
+		if err != nil {
+			return keys, err
+		}
+		keys = append(keys, more...)
+	}
+	return keys, nil
+}
+
+// walkValues reports the names and string data of the count values listed in
+// the cell at offset
+func (h *hive) walkValues(offset uint32, count int, key string) error {
+	if count == 0 || offset == noCell {
+		return nil
+	}
+	list, pos, err := h.cell(offset)
+	if err != nil {
+		return err
+	}
+	if count*4 > len(list) {
+		return fmt.Errorf("%w: value list at 0x%x", errCorrupt, pos)
+	}
+
+	le := binary.LittleEndian
+	for i := range count {
+		vk, pos, err := h.cell(le.Uint32(list[i*4:]))
+		if err != nil {
+			return err
+		}
+		if len(vk) < 20 || string(vk[:2]) != "vk" {
+			return fmt.Errorf("%w: value at 0x%x", errCorrupt, pos)
+		}
+		nameLen := int(le.Uint16(vk[2:]))
+		if 20+nameLen > len(vk) {
+			return fmt.Errorf("%w: value name at 0x%x", errCorrupt, pos)
+		}
+		nameData := vk[20 : 20+nameLen]
+		compressed := le.Uint16(vk[16:])&0x0001 != 0 // VALUE_COMP_NAME
+
+		name := DefaultValue
+		if nameLen > 0 {
+			name = decodeName(nameData, compressed)
+			if err := h.fn(Text{Key: key, Value: name, Offset: pos + 20, Data: nameData, UTF16: !compressed}); err != nil {
+				return err
+			}
+		}
+
+		switch le.Uint32(vk[12:]) {
+		case TypeSZ, TypeExpandSZ, TypeLink, TypeMultiSZ:
+			if err := h.valueData(le.Uint32(vk[4:]), le.Uint32(vk[8:]), key, name); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// valueData reports the UTF-16 data of a string value: size bytes in the cell
+// at offset, split into segments by a big data record ("db") for values of
+// more than 16344 bytes
+func (h *hive) valueData(size, offset uint32, key, name string) error {
+	// Data of up to 4 bytes is stored in the offset field, too short for text
+	if size&0x80000000 != 0 || size < 4 {
+		return nil
+	}
+	data, pos, err := h.cell(offset)
+	if err != nil {
+		return err
+	}
+
+	if size <= bigDataSegment || len(data) < 8 || string(data[:2]) != "db" {
+		if int(size) > len(data) {
+			return fmt.Errorf("%w: value data at 0x%x", errCorrupt, pos)
+		}
+		return h.fn(Text{Key: key, Value: name, Offset: pos, Data: data[:size], UTF16: true})
+	}
+
+	le := binary.LittleEndian
+	segments, listPos, err := h.cell(le.Uint32(data[4:]))
+	if err != nil {
+		return err
+	}
+	count := int(le.Uint16(data[2:]))
+	if count*4 > len(segments) {
+		return fmt.Errorf("%w: big data list at 0x%x", errCorrupt, listPos)
+	}
+	remaining := int(size)
+	for i := 0; i < count && remaining > 0; i++ {
+		segment, pos, err := h.cell(le.Uint32(segments[i*4:]))
+		if err != nil {
+			return err
+		}
+		n := min(remaining, bigDataSegment, len(segment))
+		if err := h.fn(Text{Key: key, Value: name, Offset: pos, Data: segment[:n], UTF16: true}); err != nil {
+			return err
+		}
+		remaining -= n
+	}
+	return nil
+}
+
+// decodeName decodes a key or value name stored as Latin-1 (compressed) or
+// UTF-16LE
+func decodeName(data []byte, compressed bool) string {
+	if compressed {
+		runes := make([]rune, len(data))
+		for i, b := range data {
+			runes[i] = rune(b)
+		}
+		return string(runes)
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = binary.LittleEndian.Uint16(data[i*2:])
+	}
+	return string(utf16.Decode(units))
+}
@@ -0,0 +1,236 @@
+package registry
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"unicode/utf16"
+)
+
+// testHive builds a hive cell by cell
+type testHive struct {
+	bin []byte // The hive bins, from the "hbin" header on
+}
+
+// utf16le encodes s as UTF-16LE
+func utf16le(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = binary.LittleEndian.AppendUint16(b, u)
+	}
+	return b
+}
+
+// add appends an allocated cell holding data and returns its offset
+func (th *testHive) add(data []byte) uint32 {
+	if th.bin == nil {
+		th.bin = make([]byte, 32)
+		copy(th.bin, "hbin")
+	}
+	offset := uint32(len(th.bin))
+	size := (4 + len(data) + 7) &^ 7
+	cell := make([]byte, size)
+	binary.LittleEndian.PutUint32(cell, uint32(-int32(size)))
+	copy(cell[4:], data)
+	th.bin = append(th.bin, cell...)
+	return offset
+}
+
+// key appends a key node; an ASCII name is stored compressed
+func (th *testHive) key(name string, ascii bool, subkeys uint32, subkeyCount int, values uint32, valueCount int) uint32 {
+	le := binary.LittleEndian
+	nameData := []byte(name)
+	nk := make([]byte, 76)
+	copy(nk, "nk")
+	if ascii {
+		le.PutUint16(nk[2:], 0x0020)
+	} else {
+		nameData = utf16le(name)
+	}
+	le.PutUint32(nk[20:], uint32(subkeyCount))
+	le.PutUint32(nk[28:], subkeys)
+	le.PutUint32(nk[36:], uint32(valueCount))
+	le.PutUint32(nk[40:], values)
+	le.PutUint16(nk[72:], uint16(len(nameData)))
+	return th.add(append(nk, nameData...))
+}
+
+// value appends a value node whose data is in the cell at data
+func (th *testHive) value(name string, valueType, size, data uint32) uint32 {
+	le := binary.LittleEndian
+	vk := make([]byte, 20)
+	copy(vk, "vk")
+	le.PutUint16(vk[2:], uint16(len(name)))
+	le.PutUint32(vk[4:], size)
+	le.PutUint32(vk[8:], data)
+	le.PutUint32(vk[12:], valueType)
+	le.PutUint16(vk[16:], 1)
+	return th.add(append(vk, name...))
+}
+
+// list appends a list of cell offsets, after a "li"/"ri"/"lf" header if kind
+// is set (lf entries get a zero hash)
+func (th *testHive) list(kind string, offsets ...uint32) uint32 {
+	le := binary.LittleEndian
+	var data []byte
+	if kind != "" {
+		data = append([]byte(kind), 0, 0)
+		le.PutUint16(data[2:], uint16(len(offsets)))
+	}
+	for _, offset := range offsets {
+		data = le.AppendUint32(data, offset)
+		if kind == "lf" {
+			data = le.AppendUint32(data, 0)
+		}
+	}
+	return th.add(data)
+}
+
+// file returns the hive file with its root key at root
+func (th *testHive) file(root uint32) []byte {
+	header := make([]byte, hbinStart)
+	copy(header, "regf")
+	binary.LittleEndian.PutUint32(header[36:], root)
+	return append(header, th.bin...)
+}
+
+// sampleHive builds a hive of \Software\Vendor (values Path, (default) and a
+// DWORD) and \System\Run (a MULTI_SZ value and, through an index root,
+// the UTF-16 named key Ünïcode)
+func sampleHive() []byte {
+	th := &testHive{}
+
+	pathData := th.add(utf16le("C:\\Program Files\\Vendor\x00"))
+	defaultData := th.add(utf16le("vendor default\x00"))
+	path := th.value("Path", TypeSZ, 48, pathData)
+	def := th.value("", TypeSZ, 30, defaultData)
+	dword := th.value("Flags", 4, 0x80000004, 1)
+	vendor := th.key("Vendor", true, noCell, 0, th.list("", path, def, dword), 3)
+	software := th.key("Software", true, th.list("lf", vendor), 1, noCell, 0)
+
+	multiData := th.add(utf16le("first\x00second\x00\x00"))
+	multi := th.value("Commands", TypeMultiSZ, 28, multiData)
+	unicode := th.key("Ünïcode", false, noCell, 0, noCell, 0)
+	run := th.key("Run", true, th.list("ri", th.list("li", unicode)), 1, th.list("", multi), 1)
+	system := th.key("System", true, th.list("lh", run), 1, noCell, 0)
+
+	root := th.key("ROOT", true, th.list("lf", software, system), 2, noCell, 0)
+	return th.file(root)
+}
+
+// TestWalk tests walking the keys and values of a hive depth first, with key
+// paths from the root and values attributed to their key
+func TestWalk(t *testing.T) {
+	var texts []Text
+	err := Walk(sampleHive(), func(text Text) error {
+		texts = append(texts, text)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+
+	want := []struct {
+		key, value, data string
+		utf16            bool
+	}{
+		{`\Software`, "", "Software", false},
+		{`\Software\Vendor`, "", "Vendor", false},
+		{`\Software\Vendor`, "Path", "Path", false},
+		{`\Software\Vendor`, "Path", "C:\\Program Files\\Vendor\x00", true},
+		{`\Software\Vendor`, DefaultValue, "vendor default\x00", true},
+		{`\Software\Vendor`, "Flags", "Flags", false},
+		{`\System`, "", "System", false},
+		{`\System\Run`, "", "Run", false},
+		{`\System\Run`, "Commands", "Commands", false},
+		{`\System\Run`, "Commands", "first\x00second\x00\x00", true},
+		{`\System\Run\Ünïcode`, "", "Ünïcode", true},
+	}
+	if len(texts) != len(want) {
+		t.Fatalf("Walk() = %+v, want %d texts", texts, len(want))
+	}
+	for i, text := range texts {
+		data := string(text.Data)
+		if text.UTF16 {
+			data = decodeName(text.Data, false)
+		}
+		if text.Key != want[i].key || text.Value != want[i].value || data != want[i].data || text.UTF16 != want[i].utf16 {
+			t.Errorf("text %d = %s %q %q (utf16 %v), want %s %q %q", i, text.Key, text.Value, data, text.UTF16, want[i].key, want[i].value, want[i].data)
+		}
+	}
+}
+
+// TestWalkOffsets tests that the offset of each text is where its data is in
+// the file
+func TestWalkOffsets(t *testing.T) {
+	hive := sampleHive()
+	err := Walk(hive, func(text Text) error {
+		if !bytes.Equal(hive[text.Offset:text.Offset+int64(len(text.Data))], text.Data) {
+			t.Errorf("Offset 0x%x does not hold %q", text.Offset, text.Data)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+}
+
+// TestWalkBigData tests that the data of values over 16344 bytes is read from
+// the segments of a big data record
+func TestWalkBigData(t *testing.T) {
+	th := &testHive{}
+	data := utf16le(string(bytes.Repeat([]byte("0123456789abcdef"), 600)))
+	first := th.add(data[:bigDataSegment])
+	second := th.add(data[bigDataSegment:])
+	db := th.add(append([]byte("db\x02\x00"), binary.LittleEndian.AppendUint32(nil, th.list("", first, second))...))
+	value := th.value("Blob", TypeSZ, uint32(len(data)), db)
+	root := th.key("ROOT", true, noCell, 0, th.list("", value), 1)
+
+	var got []byte
+	err := Walk(th.file(root), func(text Text) error {
+		if text.UTF16 {
+			got = append(got, text.Data...)
+		}
+		return nil
+	})
+	if err != nil || !bytes.Equal(got, data) {
+		t.Errorf("Walk() = %d bytes of data, %v, want %d bytes", len(got), err, len(data))
+	}
+}
+
+// TestWalkCorrupt tests that invalid hives are rejected, and that a hive with
+// a bad cell reports the text found before it
+func TestWalkCorrupt(t *testing.T) {
+	if err := Walk([]byte("MZ\x90\x00"), func(Text) error { return nil }); !errors.Is(err, ErrNotHive) {
+		t.Errorf("Walk(not a hive) error = %v, want %v", err, ErrNotHive)
+	}
+
+	th := &testHive{}
+	bad := th.add([]byte("xx"))
+	good := th.key("Good", true, noCell, 0, noCell, 0)
+	root := th.key("ROOT", true, th.list("li", good, bad), 2, noCell, 0)
+
+	var keys []string
+	err := Walk(th.file(root), func(text Text) error {
+		keys = append(keys, text.Key)
+		return nil
+	})
+	if !errors.Is(err, errCorrupt) || len(keys) != 1 || keys[0] != `\Good` {
+		t.Errorf("Walk() = %v, %v, want [\\Good] and %v", keys, err, errCorrupt)
+	}
+
+	// A subkey list pointing back to the root key is not followed again
+	th = &testHive{}
+	loop := th.list("li", 0)
+	child := th.key("Child", true, loop, 1, noCell, 0)
+	root = th.key("ROOT", true, th.list("li", child), 1, noCell, 0)
+	binary.LittleEndian.PutUint32(th.bin[loop+8:], root)
+	keys = nil
+	if err := Walk(th.file(root), func(text Text) error {
+		keys = append(keys, text.Key)
+		return nil
+	}); err != nil || len(keys) != 1 {
+		t.Errorf("Walk(loop) = %v, %v, want [\\Child]", keys, err)
+	}
+}
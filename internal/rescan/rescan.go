@@ -0,0 +1,176 @@
+// Package rescan reads the results of a previous scan (--json or NDJSON
+// output) so the same files can be scanned again with different options,
+// only where that scan found strings and without detecting their format
+// again.
+package rescan
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Range is a byte range of a file
+type Range struct {
+	Offset int64
+	Size   int64
+}
+
+// File is what a previous scan recorded about a file
+type File struct {
+	Name   string
+	Format string  // As reported, e.g. "ELF"; empty if not recorded
+	Ranges []Range // Around the strings found, sorted and merged
+}
+
+// Plan is the files of a previous scan, in the order it reported them
+type Plan struct {
+	files  []*File
+	byName map[string]*File
+}
+
+// stringRecord is the part of a string of the results read (see
+// printer.StringResult)
+type stringRecord struct {
+	File     string  `json:"file"`
+	Offset   int64   `json:"offset"`
+	Length   int     `json:"length"`
+	Encoding string  `json:"encoding"`
+	Offsets  []int64 `json:"offsets"`
+}
+
+// fileRecord is the part of a file of JSON output read (see
+// printer.FileResult)
+type fileRecord struct {
+	File    string         `json:"file"`
+	Format  string         `json:"format"`
+	Strings []stringRecord `json:"strings"`
+}
+
+// record is a value of JSON output ({"files": [...]}) or a line of NDJSON
+// output (a string, or a warning told apart by its type)
+type record struct {
+	Files []fileRecord `json:"files"`
+	Type  string       `json:"type"`
+	stringRecord
+}
+
+// Load reads the results of a previous scan from path and plans to scan the
+// bytes within context of each string they list (of all its offsets, with
+// --all-offsets), over-estimating the size of strings that were decoded.
+func Load(path string, context int64) (*Plan, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = file.Close() }()
+	return Read(file, context)
+}
+
+// Read is like Load, reading the results from r
+func Read(r io.Reader, context int64) (*Plan, error) {
+	plan := &Plan{byName: map[string]*File{}}
+	decoder := json.NewDecoder(r)
+	for {
+		var rec record
+		if err := decoder.Decode(&rec); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("not txtr JSON or NDJSON results: %w", err)
+		}
+		for _, result := range rec.Files {
+			file := plan.file(result.File)
+			file.Format = result.Format
+			for _, s := range result.Strings {
+				file.add(s, context)
+			}
+		}
+		if rec.Files == nil && rec.Type == "" && rec.File != "" {
+			plan.file(rec.File).add(rec.stringRecord, context)
+		}
+	}
+	if len(plan.files) == 0 {
+		return nil, fmt.Errorf("no files in the results")
+	}
+	for _, file := range plan.files {
+		file.Ranges = merge(file.Ranges)
+	}
+	return plan, nil
+}
+
+// file returns the file named name, adding it if new
+func (p *Plan) file(name string) *File {
+	if file, ok := p.byName[name]; ok {
+		return file
+	}
+	file := &File{Name: name}
+	p.files = append(p.files, file)
+	p.byName[name] = file
+	return file
+}
+
+// add adds the ranges of string s, widened by context
+func (f *File) add(s stringRecord, context int64) {
+	size := int64(s.Length) * unitSize(s.Encoding)
+	offsets := s.Offsets
+	if len(offsets) == 0 {
+		offsets = []int64{s.Offset}
+	}
+	for _, offset := range offsets {
+		start := max(offset-context, 0)
+		f.Ranges = append(f.Ranges, Range{Offset: start, Size: offset + size + context - start})
+	}
+}
+
+// unitSize returns the most bytes an encoding takes per byte of the decoded
+// string
+func unitSize(encoding string) int64 {
+	switch {
+	case strings.Contains(encoding, "32"):
+		return 4
+	case strings.Contains(encoding, "16"):
+		return 2
+	default:
+		return 1
+	}
+}
+
+// merge sorts ranges and merges those that overlap or touch
+func merge(ranges []Range) []Range {
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Offset < ranges[j].Offset })
+	var merged []Range
+	for _, r := range ranges {
+		if n := len(merged); n > 0 && r.Offset <= merged[n-1].Offset+merged[n-1].Size {
+			merged[n-1].Size = max(merged[n-1].Size, r.Offset+r.Size-merged[n-1].Offset)
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged
+}
+
+// Names returns the names of the files, in the order they were reported
+func (p *Plan) Names() []string {
+	names := make([]string, len(p.files))
+	for i, file := range p.files {
+		names[i] = file.Name
+	}
+	return names
+}
+
+// Lookup returns what was recorded about the file named name. A nil plan has
+// no files.
+func (p *Plan) Lookup(name string) (File, bool) {
+	if p == nil {
+		return File{}, false
+	}
+	file, ok := p.byName[name]
+	if !ok {
+		return File{}, false
+	}
+	return *file, true
+}
@@ -0,0 +1,70 @@
+package rescan
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestRead tests planning from JSON and NDJSON results
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []File
+		wantErr bool
+	}{
+		{
+			name: "json",
+			input: `{"files": [{"file": "a.exe", "format": "PE", "sections": [".rdata"], "strings": [
+				{"value": "hello", "offset": 100, "length": 5, "encoding": "ascii-7bit"},
+				{"value": "world", "offset": 110, "length": 5, "encoding": "utf-16le"},
+				{"value": "again", "offset": 4000, "length": 5, "encoding": "ascii-7bit", "offsets": [4000, 9000]}
+			]}, {"file": "empty.bin", "format": "Raw", "strings": []}], "summary": {"total_strings": 3}}`,
+			want: []File{
+				{Name: "a.exe", Format: "PE", Ranges: []Range{{Offset: 84, Size: 52}, {Offset: 3984, Size: 37}, {Offset: 8984, Size: 37}}},
+				{Name: "empty.bin", Format: "Raw"},
+			},
+		},
+		{
+			name: "ndjson",
+			input: `{"file": "b.bin", "value": "first", "offset": 10, "length": 5, "encoding": "ascii-7bit"}
+{"type": "warning", "file": "b.bin", "severity": "warning", "code": "close_failed", "message": "x"}
+{"file": "c.bin", "value": "second", "offset": 0, "length": 6, "encoding": "utf-32le"}
+`,
+			want: []File{
+				{Name: "b.bin", Ranges: []Range{{Offset: 0, Size: 31}}},
+				{Name: "c.bin", Ranges: []Range{{Offset: 0, Size: 40}}},
+			},
+		},
+		{name: "no files", input: `{"files": [], "summary": {}}`, wantErr: true},
+		{name: "not json", input: "hello\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plan, err := Read(strings.NewReader(tt.input), 16)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var got []File
+			for _, name := range plan.Names() {
+				file, ok := plan.Lookup(name)
+				if !ok {
+					t.Fatalf("Lookup(%q) found nothing", name)
+				}
+				got = append(got, file)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Read() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	var none *Plan
+	if _, ok := none.Lookup("a.exe"); ok {
+		t.Error("Lookup() on a nil plan found a file")
+	}
+}
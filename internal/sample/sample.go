@@ -0,0 +1,107 @@
+// Package sample picks the windows of a file scanned by --coverage, so that a
+// quick look at a huge file (say a disk image of hundreds of gigabytes) scans
+// a fraction of it spread over the whole file. Full scans can follow on the
+// regions found interesting.
+package sample
+
+import (
+	"io"
+	"math"
+)
+
+// Mode is how windows are placed
+type Mode string
+
+// Modes of placing windows
+const (
+	Even    Mode = "even"    // At regular intervals, the first at the start of the file
+	Content Mode = "content" // At content-defined boundaries near the regular positions
+)
+
+// DefaultWindow is the size of windows if Options.Window is not set
+const DefaultWindow = 1 << 20
+
+// Options control sampling
+type Options struct {
+	Percent float64 // Share of the file scanned, in percent (0 = no sampling)
+	Window  int64   // Size of each window (0 = DefaultWindow)
+	Mode    Mode    // Where windows are placed ("" = Even)
+}
+
+// Window is a byte range of a file to scan
+type Window struct {
+	Offset int64
+	Size   int64
+}
+
+// Content-defined boundaries are found with a gear hash (as in FastCDC): a
+// boundary follows each byte whose rolling hash has boundaryMask bits clear,
+// about every 8KB, searched within the first searchLimit bytes of an interval
+const (
+	boundaryMask = 1<<13 - 1
+	searchLimit  = 64 << 10
+)
+
+// gear maps bytes to the random values of the gear hash, generated from a
+// fixed seed so windows are placed alike in every run
+var gear = func() (table [256]uint64) {
+	state := uint64(0x74787472) // splitmix64
+	for i := range table {
+		state += 0x9e3779b97f4a7c15
+		z := state
+		z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+		z = (z ^ z>>27) * 0x94d049bb133111eb
+		table[i] = z ^ z>>31
+	}
+	return table
+}()
+
+// Windows returns the windows covering about options.Percent of a file of
+// size bytes read from r, in order and spread evenly over the file: the file
+// is cut into as many intervals as windows, and each window starts at the
+// start of its interval (Even) or at the first content-defined boundary in it
+// (Content), so that the same data is sampled alike wherever it sits in the
+// file, e.g. in two versions of a disk image. Windows covering the whole file
+// are merged into one.
+func Windows(r io.ReaderAt, size int64, options Options) []Window {
+	if size <= 0 || options.Percent <= 0 {
+		return nil
+	}
+	window := options.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	total := int64(math.Ceil(float64(size) * min(options.Percent, 100) / 100))
+	count := (total + window - 1) / window
+	if count*window >= size {
+		return []Window{{Offset: 0, Size: size}}
+	}
+
+	interval := size / count
+	windows := make([]Window, count)
+	for i := range windows {
+		start := int64(i) * interval
+		if options.Mode == Content {
+			start += boundary(r, start, min(interval-window, searchLimit))
+		}
+		windows[i] = Window{Offset: start, Size: window}
+	}
+	return windows
+}
+
+// boundary returns the position of the first content-defined boundary in the
+// limit bytes read from r at offset, or 0 if there is none (or they cannot be
+// read)
+func boundary(r io.ReaderAt, offset, limit int64) int64 {
+	buf := make([]byte, limit)
+	n, _ := r.ReadAt(buf, offset)
+	var hash uint64
+	for i, b := range buf[:n] {
+		// Once 64 bytes were hashed, the hash only depends on the last 64
+		hash = hash<<1 + gear[b]
+		if i >= 63 && hash&boundaryMask == 0 {
+			return int64(i) + 1
+		}
+	}
+	return 0
+}
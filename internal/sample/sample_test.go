@@ -0,0 +1,61 @@
+package sample
+
+import (
+	"bytes"
+	"math/rand/v2"
+	"reflect"
+	"testing"
+)
+
+// TestWindows tests the number, size and spread of windows
+func TestWindows(t *testing.T) {
+	data := make([]byte, 1<<20)
+	rng := rand.New(rand.NewPCG(1, 2))
+	for i := range data {
+		data[i] = byte(rng.Uint32())
+	}
+	r := bytes.NewReader(data)
+
+	tests := []struct {
+		name    string
+		size    int64
+		options Options
+		want    []Window
+	}{
+		{"no sampling", 1 << 20, Options{}, nil},
+		{"empty file", 0, Options{Percent: 10}, nil},
+		{"even", 1 << 20, Options{Percent: 10, Window: 32 << 10}, []Window{
+			{0, 32 << 10}, {262144, 32 << 10}, {524288, 32 << 10}, {786432, 32 << 10},
+		}},
+		{"rounded up to a window", 1000, Options{Percent: 1, Window: 100}, []Window{{0, 100}}},
+		{"whole file", 1 << 20, Options{Percent: 80, Window: 600 << 10}, []Window{{0, 1 << 20}}},
+		{"default window", 100 << 20, Options{Percent: 1}, []Window{{0, 1 << 20}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Windows(r, tt.size, tt.options); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Windows() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Content-defined windows start in their interval, at a boundary
+	options := Options{Percent: 10, Window: 32 << 10, Mode: Content}
+	even := Windows(r, int64(len(data)), Options{Percent: options.Percent, Window: options.Window})
+	content := Windows(r, int64(len(data)), options)
+	if len(content) != len(even) {
+		t.Fatalf("content windows = %v, want %d", content, len(even))
+	}
+	for i, w := range content {
+		if w.Size != options.Window || w.Offset <= even[i].Offset || w.Offset-even[i].Offset > searchLimit {
+			t.Errorf("content window %d = %v, want one after %d within %d bytes", i, w, even[i].Offset, searchLimit)
+		}
+	}
+
+	// Boundaries follow the content, not where the search starts
+	at := 1000 + boundary(r, 1000, searchLimit)
+	shifted := bytes.NewReader(append(make([]byte, 4321), data...))
+	if got := 600 + boundary(shifted, 4321+600, searchLimit); at == 1000 || got != at {
+		t.Errorf("boundary in shifted data at %d, want %d", got, at)
+	}
+}
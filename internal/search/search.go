@@ -0,0 +1,230 @@
+// Package search finds literal strings in binary data in several encodings at
+// once, without extracting every string first ("txtr find").
+package search
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"slices"
+	"unicode/utf16"
+)
+
+// Encoding names accepted by Needles
+const (
+	EncodingASCII   = "ascii" // Also matches UTF-8
+	EncodingUTF16LE = "utf-16le"
+	EncodingUTF16BE = "utf-16be"
+	EncodingUTF32LE = "utf-32le"
+	EncodingUTF32BE = "utf-32be"
+)
+
+// Encodings lists the supported encodings in search order
+var Encodings = []string{EncodingASCII, EncodingUTF16LE, EncodingUTF16BE, EncodingUTF32LE, EncodingUTF32BE}
+
+// Needle is the byte representation of a literal in one encoding
+type Needle struct {
+	Encoding string
+	Bytes    []byte
+	unit     int       // Bytes per code unit
+	order    byteOrder // Byte order of multi-byte code units
+}
+
+// byteOrder reads and appends multi-byte code units
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// Needles encodes literal in each of the given encodings. Encodings that
+// cannot represent the literal distinctly (e.g. an empty literal) are skipped.
+func Needles(literal string, encodings []string) []Needle {
+	var needles []Needle
+	for _, encoding := range encodings {
+		needle := Needle{Encoding: encoding, unit: 1}
+		switch encoding {
+		case EncodingASCII:
+			needle.Bytes = []byte(literal)
+		case EncodingUTF16LE:
+			needle.unit, needle.order = 2, binary.LittleEndian
+		case EncodingUTF16BE:
+			needle.unit, needle.order = 2, binary.BigEndian
+		case EncodingUTF32LE:
+			needle.unit, needle.order = 4, binary.LittleEndian
+		case EncodingUTF32BE:
+			needle.unit, needle.order = 4, binary.BigEndian
+		default:
+			continue
+		}
+		switch needle.unit {
+		case 2:
+			for _, unit := range utf16.Encode([]rune(literal)) {
+				needle.Bytes = needle.order.AppendUint16(needle.Bytes, unit)
+			}
+		case 4:
+			for _, r := range literal {
+				needle.Bytes = needle.order.AppendUint32(needle.Bytes, uint32(r))
+			}
+		}
+		if len(needle.Bytes) > 0 {
+			needles = append(needles, needle)
+		}
+	}
+	return needles
+}
+
+// Match is an occurrence of a needle
+type Match struct {
+	Offset        int64
+	Encoding      string
+	Context       string // Printable text around the match, decoded
+	ContextOffset int64  // Offset of the start of Context in the data
+	Start         int    // Start of the match in Context (bytes)
+	End           int    // End of the match in Context (bytes)
+
+	length int // Length of the match in the data (bytes)
+	unit   int // Bytes per code unit of the encoding
+	run    int // Printable characters of the enclosing string (see resolveOverlaps)
+}
+
+// maxRun caps the printable characters counted on each side of a match when
+// resolving overlapping matches
+const maxRun = 4096
+
+// Find returns every occurrence of the needles in data, ordered by offset.
+// context limits the surrounding printable characters included on each side
+// of a match.
+func Find(data []byte, needles []Needle, context int) []Match {
+	var matches []Match
+	for _, needle := range needles {
+		// bytes.Index uses SIMD/memchr-style scanning for the first byte
+		for pos := 0; pos <= len(data)-len(needle.Bytes); {
+			i := bytes.Index(data[pos:], needle.Bytes)
+			if i < 0 {
+				break
+			}
+			offset := pos + i
+			matches = append(matches, needle.match(data, offset, context))
+			pos = offset + needle.unit
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		return cmp.Compare(a.Offset, b.Offset)
+	})
+	return resolveOverlaps(matches)
+}
+
+// resolveOverlaps drops matches that are a misaligned reading of another
+// match: ASCII text in UTF-16LE ("w\0o\0") also reads as UTF-16BE one byte
+// later. Of two overlapping multi-byte matches in different encodings, the one
+// in the longer printable string is kept, as the misaligned reading usually
+// loses a character at its start; on a tie, the one aligned to its code unit.
+func resolveOverlaps(matches []Match) []Match {
+	kept := matches[:0]
+	for _, match := range matches {
+		if len(kept) > 0 {
+			last := &kept[len(kept)-1]
+			if last.Encoding != match.Encoding && last.length > 1 && match.length > 1 &&
+				match.Offset < last.Offset+int64(last.length) && match.run > 0 && last.run > 0 {
+				aligned := match.Offset%int64(match.unit) == 0 && last.Offset%int64(last.unit) != 0
+				if match.run > last.run || match.run == last.run && aligned {
+					*last = match
+				}
+				continue
+			}
+		}
+		kept = append(kept, match)
+	}
+	return kept
+}
+
+// match builds the match at offset, decoding up to context printable
+// characters before and after it
+func (n Needle) match(data []byte, offset, context int) Match {
+	before := n.decodeBackward(data, offset, context)
+	literal := n.decode(data[offset : offset+len(n.Bytes)])
+	after := n.decodeForward(data, offset+len(n.Bytes), context)
+	match := Match{
+		Offset:        int64(offset),
+		Encoding:      n.Encoding,
+		Context:       before + literal + after,
+		ContextOffset: int64(offset - len(before)*n.unit),
+		Start:         len(before),
+		End:           len(before) + len(literal),
+		length:        len(n.Bytes),
+		unit:          n.unit,
+	}
+	if n.unit > 1 {
+		match.run = len(n.decodeBackward(data, offset, maxRun)) + len(n.Bytes)/n.unit +
+			len(n.decodeForward(data, offset+len(n.Bytes), maxRun))
+	}
+	return match
+}
+
+// decode decodes the bytes of the literal itself
+func (n Needle) decode(b []byte) string {
+	switch n.unit {
+	case 1:
+		return string(b)
+	case 4:
+		runes := make([]rune, len(b)/4)
+		for i := range runes {
+			runes[i] = rune(n.order.Uint32(b[4*i:]))
+		}
+		return string(runes)
+	}
+	units := make([]uint16, len(b)/2)
+	for i := range units {
+		units[i] = n.order.Uint16(b[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
+
+// char returns the printable ASCII character of the code unit at i, if any
+func (n Needle) char(data []byte, i int) (byte, bool) {
+	if i < 0 || i+n.unit > len(data) {
+		return 0, false
+	}
+	var c uint32
+	switch n.unit {
+	case 1:
+		c = uint32(data[i])
+	case 2:
+		c = uint32(n.order.Uint16(data[i:]))
+	default:
+		c = n.order.Uint32(data[i:])
+	}
+	if c >= 0x20 && c < 0x7f || c == '\t' {
+		return byte(c), true
+	}
+	return 0, false
+}
+
+// decodeBackward returns up to limit printable characters ending at end
+func (n Needle) decodeBackward(data []byte, end, limit int) string {
+	var chars []byte
+	for i := end - n.unit; len(chars) < limit; i -= n.unit {
+		c, ok := n.char(data, i)
+		if !ok {
+			break
+		}
+		chars = append(chars, c)
+	}
+	for i, j := 0, len(chars)-1; i < j; i, j = i+1, j-1 {
+		chars[i], chars[j] = chars[j], chars[i]
+	}
+	return string(chars)
+}
+
+// decodeForward returns up to limit printable characters starting at start
+func (n Needle) decodeForward(data []byte, start, limit int) string {
+	var chars []byte
+	for i := start; len(chars) < limit; i += n.unit {
+		c, ok := n.char(data, i)
+		if !ok {
+			break
+		}
+		chars = append(chars, c)
+	}
+	return string(chars)
+}
@@ -0,0 +1,140 @@
+package search
+
+import (
+	"bytes"
+	"testing"
+)
+
+// utf16le encodes ASCII text as UTF-16LE
+func utf16le(s string) []byte {
+	var b []byte
+	for _, c := range []byte(s) {
+		b = append(b, c, 0)
+	}
+	return b
+}
+
+// utf16be encodes ASCII text as UTF-16BE
+func utf16be(s string) []byte {
+	var b []byte
+	for _, c := range []byte(s) {
+		b = append(b, 0, c)
+	}
+	return b
+}
+
+// utf32le encodes ASCII text as UTF-32LE
+func utf32le(s string) []byte {
+	var b []byte
+	for _, c := range []byte(s) {
+		b = append(b, c, 0, 0, 0)
+	}
+	return b
+}
+
+// utf32be encodes ASCII text as UTF-32BE
+func utf32be(s string) []byte {
+	var b []byte
+	for _, c := range []byte(s) {
+		b = append(b, 0, 0, 0, c)
+	}
+	return b
+}
+
+// TestNeedles tests encoding of the literal
+func TestNeedles(t *testing.T) {
+	needles := Needles("ab", Encodings)
+	want := map[string][]byte{
+		EncodingASCII:   []byte("ab"),
+		EncodingUTF16LE: {'a', 0, 'b', 0},
+		EncodingUTF16BE: {0, 'a', 0, 'b'},
+		EncodingUTF32LE: {'a', 0, 0, 0, 'b', 0, 0, 0},
+		EncodingUTF32BE: {0, 0, 0, 'a', 0, 0, 0, 'b'},
+	}
+	if len(needles) != len(want) {
+		t.Fatalf("Needles() returned %d needles, want %d", len(needles), len(want))
+	}
+	for _, needle := range needles {
+		if !bytes.Equal(needle.Bytes, want[needle.Encoding]) {
+			t.Errorf("%s needle = %v, want %v", needle.Encoding, needle.Bytes, want[needle.Encoding])
+		}
+	}
+
+	if needles := Needles("", Encodings); len(needles) != 0 {
+		t.Errorf("Needles(\"\") = %v, want none", needles)
+	}
+	if needles := Needles("ab", []string{"ebcdic"}); len(needles) != 0 {
+		t.Errorf("Needles() with unknown encoding = %v, want none", needles)
+	}
+}
+
+// TestFind tests matches, context and encodings
+func TestFind(t *testing.T) {
+	var data []byte
+	data = append(data, "\x00\x01connect to c2.example.com now\x00\x00\x00"...)
+	leOffset := len(data)
+	data = append(data, utf16le("http://c2.example.com/")...)
+	data = append(data, 0, 0, 0xff, 0xff)
+	beOffset := len(data)
+	data = append(data, utf16be("c2.example.com")...)
+	data = append(data, 0, 0, 0xff, 0xff)
+	utf32Offset := len(data)
+	data = append(data, utf32le("ftp c2.example.com")...)
+	data = append(data, 0, 0, 0, 0)
+
+	matches := Find(data, Needles("c2.example.com", Encodings), 8)
+	if len(matches) != 4 {
+		t.Fatalf("Find() = %+v, want 4 matches", matches)
+	}
+
+	tests := []struct {
+		offset        int
+		encoding      string
+		context       string
+		contextOffset int
+	}{
+		{13, EncodingASCII, "nect to c2.example.com now", 5},
+		{leOffset + 14, EncodingUTF16LE, "http://c2.example.com/", leOffset},
+		{beOffset, EncodingUTF16BE, "c2.example.com", beOffset},
+		{utf32Offset + 16, EncodingUTF32LE, "ftp c2.example.com", utf32Offset},
+	}
+	for i, tt := range tests {
+		m := matches[i]
+		if m.Offset != int64(tt.offset) || m.Encoding != tt.encoding || m.Context != tt.context {
+			t.Errorf("match %d = %d %s %q, want %d %s %q", i, m.Offset, m.Encoding, m.Context, tt.offset, tt.encoding, tt.context)
+		}
+		if m.ContextOffset != int64(tt.contextOffset) {
+			t.Errorf("match %d context offset = %d, want %d", i, m.ContextOffset, tt.contextOffset)
+		}
+		if m.Context[m.Start:m.End] != "c2.example.com" {
+			t.Errorf("match %d span = %q, want the literal", i, m.Context[m.Start:m.End])
+		}
+	}
+}
+
+// TestFindMisaligned tests that UTF-16 and UTF-32 text is not also reported
+// in the opposite byte order a few bytes off
+func TestFindMisaligned(t *testing.T) {
+	for _, tt := range []struct {
+		data     []byte
+		encoding string
+	}{
+		{append(utf16le("hello world"), 0, 0), EncodingUTF16LE},
+		{append([]byte{0xff, 0xff}, append(utf16be("hello world"), 0, 0)...), EncodingUTF16BE},
+		{append(utf32le("hello world"), 0, 0, 0, 0), EncodingUTF32LE},
+		{append([]byte{0xff, 0xff, 0xff, 0xff}, append(utf32be("hello world"), 0, 0, 0, 0)...), EncodingUTF32BE},
+	} {
+		matches := Find(tt.data, Needles("world", Encodings), 16)
+		if len(matches) != 1 || matches[0].Encoding != tt.encoding || matches[0].Context != "hello world" {
+			t.Errorf("Find() in %s text = %+v, want one %s match", tt.encoding, matches, tt.encoding)
+		}
+	}
+}
+
+// TestFindOverlapping tests that repeated occurrences are all reported
+func TestFindOverlapping(t *testing.T) {
+	matches := Find([]byte("aaaa"), Needles("aa", []string{EncodingASCII}), 0)
+	if len(matches) != 3 {
+		t.Errorf("Find() = %+v, want 3 overlapping matches", matches)
+	}
+}
@@ -0,0 +1,78 @@
+// Package service integrates "txtr serve" with service managers: it takes
+// over the sockets systemd opened for it (socket activation) and reports
+// when the service is ready, reloading or stopping (sd_notify), so it can run
+// as a Type=notify unit. Both are no-ops outside systemd.
+package service
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFdsStart is the first file descriptor passed by socket activation
+const listenFdsStart = 3
+
+// Listeners returns the sockets passed by systemd socket activation, in the
+// order of the unit's Listen= lines (their names are not needed, as they
+// are all served alike), or none if the process was not socket activated.
+// The activation variables are unset, so child processes do not take the
+// sockets too.
+func Listeners() ([]net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_PID")
+	_ = os.Unsetenv("LISTEN_FDS")
+	_ = os.Unsetenv("LISTEN_FDNAMES")
+
+	if pid == "" || fds == "" {
+		return nil, nil
+	}
+	// The variables are meant for the process systemd started
+	if pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+	count, err := strconv.Atoi(fds)
+	if err != nil || count < 0 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+	listeners := make([]net.Listener, 0, count)
+	for fd := listenFdsStart; fd < listenFdsStart+count; fd++ {
+		file := os.NewFile(uintptr(fd), "LISTEN_FD_"+strconv.Itoa(fd))
+		listener, err := net.FileListener(file)
+		// FileListener duplicates the descriptor
+		_ = file.Close()
+		if err != nil {
+			for _, l := range listeners {
+				_ = l.Close()
+			}
+			return nil, fmt.Errorf("socket %d passed by systemd: %w", fd, err)
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// States reported with Notify
+const (
+	Ready     = "READY=1"     // Startup or a reload finished
+	Reloading = "RELOADING=1" // The configuration is being reloaded
+	Stopping  = "STOPPING=1"  // Shutdown began
+)
+
+// Notify reports the state of the service to systemd (see sd_notify). It
+// does nothing if the service manager did not ask for notifications
+// (NOTIFY_SOCKET is unset).
+func Notify(state string) error {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	if socket == "" {
+		return nil
+	}
+	conn, err := net.Dial("unixgram", socket)
+	if err != nil {
+		return err
+	}
+	_, err = conn.Write([]byte(state))
+	return errors.Join(err, conn.Close())
+}
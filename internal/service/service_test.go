@@ -0,0 +1,70 @@
+package service
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// TestListenersNotActivated tests that processes systemd did not start with
+// sockets get none
+func TestListenersNotActivated(t *testing.T) {
+	tests := []struct {
+		name     string
+		pid, fds string
+	}{
+		{"not activated", "", ""},
+		{"another process", strconv.Itoa(os.Getpid() + 1), "1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("LISTEN_PID", tt.pid)
+			t.Setenv("LISTEN_FDS", tt.fds)
+			listeners, err := Listeners()
+			if err != nil || len(listeners) != 0 {
+				t.Errorf("Listeners() = %v, %v, want none", listeners, err)
+			}
+			if os.Getenv("LISTEN_FDS") != "" {
+				t.Error("LISTEN_FDS is still set")
+			}
+		})
+	}
+
+	t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+	t.Setenv("LISTEN_FDS", "two")
+	if _, err := Listeners(); err == nil {
+		t.Error("Listeners() accepted an invalid LISTEN_FDS")
+	}
+}
+
+// TestNotify tests that states are sent to the notification socket
+func TestNotify(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", "")
+	if err := Notify(Ready); err != nil {
+		t.Errorf("Notify() without a socket error = %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unix datagram sockets unavailable: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+	t.Setenv("NOTIFY_SOCKET", path)
+
+	for _, state := range []string{Ready, Reloading, Stopping} {
+		if err := Notify(state); err != nil {
+			t.Fatalf("Notify(%q) error = %v", state, err)
+		}
+		buf := make([]byte, 64)
+		n, err := conn.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(buf[:n]); got != state {
+			t.Errorf("received %q, want %q", got, state)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package signature
+
+import (
+	"bytes"
+	"testing"
+)
+
+// FuzzScan tests finding regions with random inputs
+func FuzzScan(f *testing.F) {
+	// Seed corpus: valid headers, truncated headers and bare magic numbers
+	f.Add(append(squashfsHeader(4096), make([]byte, 4000)...))
+	f.Add(squashfsHeader(1 << 20))
+	f.Add(append(append(jffs2Node(0x2003, 12), 0xff, 0xff, 0xff, 0xff), jffs2Node(0xe002, 70)...))
+	f.Add(append([]byte{1, 2, 3}, jffs2Node(0x2004, 13)...))
+	f.Add([]byte("hsqs"))
+	f.Add([]byte("sqsh"))
+	f.Add([]byte("UBI#\x01"))
+	f.Add([]byte{0x31, 0x18, 0x10, 0x06})
+	f.Add([]byte{0x1f, 0x8b, 0x08})
+	f.Add([]byte{0xfd, '7', 'z', 'X', 'Z', 0})
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		regions, err := Scan(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			t.Fatalf("Scan() error = %v", err)
+		}
+
+		// Invariant: regions are in offset order, inside the input and do not
+		// overlap
+		end := int64(0)
+		for _, region := range regions {
+			if region.Offset < end || region.Size < 0 || region.End() > int64(len(data)) {
+				t.Errorf("region %+v overlaps the one before (ending at %d) or the end of the %d byte input", region, end, len(data))
+			}
+			end = region.End()
+		}
+	})
+}
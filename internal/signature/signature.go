@@ -0,0 +1,465 @@
+// Package signature finds embedded filesystems and compressed data in a file
+// from their magic numbers and headers, like binwalk, so firmware images can
+// be mapped alongside their strings. Every candidate header is validated
+// (field ranges, checksums where the format has one) to keep false positives
+// from random data low.
+package signature
+
+import (
+	"bytes"
+	"cmp"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/bits"
+	"os"
+	"slices"
+)
+
+// Region types reported by Scan
+const (
+	TypeSquashFS = "squashfs"
+	TypeCramFS   = "cramfs"
+	TypeJFFS2    = "jffs2"
+	TypeUBI      = "ubi"
+	TypeUBIFS    = "ubifs"
+	TypeLZMA     = "lzma"
+	TypeGzip     = "gzip"
+	TypeXZ       = "xz"
+)
+
+// Region is an embedded filesystem or compressed blob found in a file
+type Region struct {
+	Type        string // One of the Type constants
+	Offset      int64
+	Size        int64 // Bytes, 0 if the format does not record it
+	Description string
+}
+
+// End returns the offset after the region (Offset if its size is unknown)
+func (r Region) End() int64 {
+	return r.Offset + r.Size
+}
+
+const (
+	chunkSize  = 1 << 20 // Bytes searched for magic numbers at a time
+	headerSize = 64      // Longest header the signatures inspect
+)
+
+// signature recognizes one format from its magic number
+type signature struct {
+	magic []byte
+	// parse validates the header at offset (up to headerSize bytes, fewer at
+	// the end of the file) and describes the region
+	parse func(r io.ReaderAt, offset, fileSize int64, header []byte) (Region, bool)
+}
+
+// signatures lists the recognized formats
+var signatures = []signature{
+	{[]byte("hsqs"), func(_ io.ReaderAt, offset, fileSize int64, h []byte) (Region, bool) {
+		return parseSquashFS(offset, fileSize, h, binary.LittleEndian)
+	}},
+	{[]byte("sqsh"), func(_ io.ReaderAt, offset, fileSize int64, h []byte) (Region, bool) {
+		return parseSquashFS(offset, fileSize, h, binary.BigEndian)
+	}},
+	{[]byte{0x45, 0x3d, 0xcd, 0x28}, func(_ io.ReaderAt, offset, fileSize int64, h []byte) (Region, bool) {
+		return parseCramFS(offset, fileSize, h, binary.LittleEndian)
+	}},
+	{[]byte{0x28, 0xcd, 0x3d, 0x45}, func(_ io.ReaderAt, offset, fileSize int64, h []byte) (Region, bool) {
+		return parseCramFS(offset, fileSize, h, binary.BigEndian)
+	}},
+	{[]byte{0x85, 0x19}, func(r io.ReaderAt, offset, fileSize int64, _ []byte) (Region, bool) {
+		return parseJFFS2(r, offset, fileSize, binary.LittleEndian)
+	}},
+	{[]byte{0x19, 0x85}, func(r io.ReaderAt, offset, fileSize int64, _ []byte) (Region, bool) {
+		return parseJFFS2(r, offset, fileSize, binary.BigEndian)
+	}},
+	{[]byte("UBI#"), func(r io.ReaderAt, offset, fileSize int64, h []byte) (Region, bool) {
+		return parseUBI(r, offset, fileSize, h)
+	}},
+	{[]byte{0x31, 0x18, 0x10, 0x06}, func(r io.ReaderAt, offset, fileSize int64, _ []byte) (Region, bool) {
+		return parseUBIFS(r, offset, fileSize)
+	}},
+	{[]byte{0x5d, 0x00, 0x00}, func(_ io.ReaderAt, offset, _ int64, h []byte) (Region, bool) {
+		return parseLZMA(offset, h)
+	}},
+	{[]byte{0x1f, 0x8b, 0x08}, func(_ io.ReaderAt, offset, _ int64, h []byte) (Region, bool) {
+		return parseGzip(offset, h)
+	}},
+	{[]byte{0xfd, '7', 'z', 'X', 'Z', 0x00}, func(_ io.ReaderAt, offset, _ int64, h []byte) (Region, bool) {
+		return parseXZ(offset, h)
+	}},
+}
+
+// ScanFile finds the regions of the file at path
+func ScanFile(path string) ([]Region, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return Scan(file, info.Size())
+}
+
+// Scan finds the regions of the size bytes of r, in offset order. Magic
+// numbers inside a region of known size are not reported, so the compressed
+// blocks of a filesystem do not show up as separate regions.
+func Scan(r io.ReaderAt, size int64) ([]Region, error) {
+	var regions []Region
+	buf := make([]byte, chunkSize+headerSize)
+	header := make([]byte, headerSize)
+	skipTo := int64(0) // End of the last region of known size
+
+	type candidate struct {
+		offset int64
+		sig    *signature
+	}
+	var candidates []candidate
+
+	for base := int64(0); base < size; base += chunkSize {
+		n, err := r.ReadAt(buf[:min(int64(len(buf)), size-base)], base)
+		if err != nil && err != io.EOF {
+			return regions, err
+		}
+		data := buf[:n]
+
+		// Magic numbers starting in this chunk, in offset order
+		candidates = candidates[:0]
+		for i := range signatures {
+			sig := &signatures[i]
+			end := min(len(data), chunkSize+len(sig.magic)-1)
+			for pos := 0; pos < end; {
+				j := bytes.Index(data[pos:end], sig.magic)
+				if j < 0 {
+					break
+				}
+				candidates = append(candidates, candidate{base + int64(pos+j), sig})
+				pos += j + 1
+			}
+		}
+		slices.SortFunc(candidates, func(a, b candidate) int {
+			return cmp.Compare(a.offset, b.offset)
+		})
+
+		for _, c := range candidates {
+			if c.offset < skipTo {
+				continue
+			}
+			hn, err := r.ReadAt(header, c.offset)
+			if err != nil && err != io.EOF {
+				return regions, err
+			}
+			region, ok := c.sig.parse(r, c.offset, size, header[:hn])
+			if !ok {
+				continue
+			}
+			regions = append(regions, region)
+			skipTo = max(skipTo, region.End())
+		}
+	}
+
+	return regions, nil
+}
+
+// clampSize limits a size read from a header to the bytes left in the file
+// (truncated images)
+func clampSize(offset, size, fileSize int64) int64 {
+	return max(0, min(size, fileSize-offset))
+}
+
+// squashfsCompressors names the compression IDs of a SquashFS superblock
+var squashfsCompressors = map[uint16]string{1: "gzip", 2: "lzma", 3: "lzo", 4: "xz", 5: "lz4", 6: "zstd"}
+
+// parseSquashFS validates a SquashFS 4 superblock
+func parseSquashFS(offset, fileSize int64, h []byte, order binary.ByteOrder) (Region, bool) {
+	if len(h) < 48 {
+		return Region{}, false
+	}
+	inodes := order.Uint32(h[4:])
+	blockSize := order.Uint32(h[12:])
+	compressor := order.Uint16(h[20:])
+	blockLog := order.Uint16(h[22:])
+	major, minor := order.Uint16(h[28:]), order.Uint16(h[30:])
+	bytesUsed := order.Uint64(h[40:])
+
+	if major != 4 || blockSize < 4096 || blockSize > 1<<20 || bits.OnesCount32(blockSize) != 1 ||
+		int(blockLog) != bits.TrailingZeros32(blockSize) || bytesUsed < 96 || bytesUsed > 1<<40 {
+		return Region{}, false
+	}
+	name, ok := squashfsCompressors[compressor]
+	if !ok {
+		return Region{}, false
+	}
+
+	endian := "little"
+	if order == binary.BigEndian {
+		endian = "big"
+	}
+	return Region{
+		Type:   TypeSquashFS,
+		Offset: offset,
+		Size:   clampSize(offset, int64(bytesUsed), fileSize),
+		Description: fmt.Sprintf("SquashFS %d.%d filesystem, %s endian, %s compressed, %d inodes, block size %d",
+			major, minor, endian, name, inodes, blockSize),
+	}, true
+}
+
+// parseCramFS validates a cramfs superblock
+func parseCramFS(offset, fileSize int64, h []byte, order binary.ByteOrder) (Region, bool) {
+	if len(h) < 64 || string(h[16:32]) != "Compressed ROMFS" {
+		return Region{}, false
+	}
+	size := order.Uint32(h[4:])
+	if size < 64 {
+		return Region{}, false
+	}
+	name := string(bytes.TrimRight(h[48:64], "\x00"))
+
+	endian := "little"
+	if order == binary.BigEndian {
+		endian = "big"
+	}
+	return Region{
+		Type:        TypeCramFS,
+		Offset:      offset,
+		Size:        clampSize(offset, int64(size), fileSize),
+		Description: fmt.Sprintf("cramfs filesystem, %s endian, name %q", endian, name),
+	}, true
+}
+
+// JFFS2 node types
+var jffs2NodeTypes = map[uint16]bool{
+	0xe001: true, // Directory entry
+	0xe002: true, // Inode
+	0x2003: true, // Clean marker
+	0x2004: true, // Padding
+	0x6006: true, // Summary
+	0xe008: true, // Extended attribute
+	0xe009: true, // Extended attribute reference
+}
+
+// jffs2NodeAt reports whether a valid JFFS2 node header is at offset and
+// returns the node's length
+func jffs2NodeAt(r io.ReaderAt, offset, fileSize int64, order binary.ByteOrder) (int64, bool) {
+	var h [12]byte
+	if offset+int64(len(h)) > fileSize {
+		return 0, false
+	}
+	if _, err := r.ReadAt(h[:], offset); err != nil {
+		return 0, false
+	}
+	if order.Uint16(h[0:]) != 0x1985 || !jffs2NodeTypes[order.Uint16(h[2:])] {
+		return 0, false
+	}
+	// JFFS2 checksums are CRC-32 without the final inversion
+	if order.Uint32(h[8:]) != ^crc32.Update(0xffffffff, crc32.IEEETable, h[:8]) {
+		return 0, false
+	}
+	totlen := int64(order.Uint32(h[4:]))
+	if totlen < int64(len(h)) {
+		return 0, false
+	}
+	return totlen, true
+}
+
+// parseJFFS2 validates the JFFS2 node at offset and follows the nodes after
+// it, across erased (0xFF) flash, to the end of the filesystem
+func parseJFFS2(r io.ReaderAt, offset, fileSize int64, order binary.ByteOrder) (Region, bool) {
+	totlen, ok := jffs2NodeAt(r, offset, fileSize, order)
+	if !ok {
+		return Region{}, false
+	}
+
+	nodes := 0
+	end := offset
+	var word [4]byte
+	for pos := offset; ok; totlen, ok = jffs2NodeAt(r, pos, fileSize, order) {
+		nodes++
+		end = min(pos+totlen, fileSize)
+		// Nodes are 4-byte aligned (from the start of the filesystem); erased
+		// flash between them reads as 0xFF
+		pos = end + (-(end - offset) & 3)
+		for pos+4 <= fileSize {
+			if _, err := r.ReadAt(word[:], pos); err != nil || word != [4]byte{0xff, 0xff, 0xff, 0xff} {
+				break
+			}
+			pos += 4
+		}
+	}
+
+	endian := "little"
+	if order == binary.BigEndian {
+		endian = "big"
+	}
+	return Region{
+		Type:        TypeJFFS2,
+		Offset:      offset,
+		Size:        end - offset,
+		Description: fmt.Sprintf("JFFS2 filesystem, %s endian, %d nodes", endian, nodes),
+	}, true
+}
+
+// UBIEraseCounterHeaderAt reports whether a valid UBI erase counter header is
+// in h (at least 64 bytes), the header at the start of every physical erase
+// block of a UBI image. UBI headers are big endian.
+func UBIEraseCounterHeaderAt(h []byte) bool {
+	if len(h) < 64 || string(h[:4]) != "UBI#" || h[4] != 1 {
+		return false
+	}
+	// UBI checksums are CRC-32 without the final inversion
+	return binary.BigEndian.Uint32(h[60:]) == ^crc32.ChecksumIEEE(h[:60])
+}
+
+// UBIBlockSize returns the size of the physical erase blocks of the UBI image
+// starting at offset: the distance to the next erase counter header. Erase
+// blocks are a power of two between 16 KiB and 2 MiB; 0 means the image has a
+// single block or the size could not be found.
+func UBIBlockSize(r io.ReaderAt, offset, fileSize int64) int64 {
+	h := make([]byte, 64)
+	for size := int64(16 << 10); size <= 2<<20 && offset+size+64 <= fileSize; size <<= 1 {
+		if _, err := r.ReadAt(h, offset+size); err == nil && UBIEraseCounterHeaderAt(h) {
+			return size
+		}
+	}
+	return 0
+}
+
+// parseUBI validates a UBI erase counter header and follows the erase blocks
+// after it to the end of the image
+func parseUBI(r io.ReaderAt, offset, fileSize int64, h []byte) (Region, bool) {
+	if !UBIEraseCounterHeaderAt(h) {
+		return Region{}, false
+	}
+
+	blockSize := UBIBlockSize(r, offset, fileSize)
+	blocks := int64(1)
+	if blockSize > 0 {
+		next := make([]byte, 64)
+		for pos := offset + blockSize; pos+64 <= fileSize; pos += blockSize {
+			if _, err := r.ReadAt(next, pos); err != nil || !UBIEraseCounterHeaderAt(next) {
+				break
+			}
+			blocks++
+		}
+	}
+
+	region := Region{Type: TypeUBI, Offset: offset, Description: "UBI image, version 1"}
+	if blockSize > 0 {
+		region.Size = clampSize(offset, blocks*blockSize, fileSize)
+		region.Description += fmt.Sprintf(", %d erase blocks of %d bytes", blocks, blockSize)
+	}
+	return region, true
+}
+
+// UBIFS node types
+const (
+	ubifsSuperblockNode = 6
+	ubifsSuperblockSize = 4096 // Bytes of a superblock node, including padding
+)
+
+// ubifsCompressors names the compression types of UBIFS nodes
+var ubifsCompressors = map[uint16]string{0: "no", 1: "lzo", 2: "zlib", 3: "zstd"}
+
+// parseUBIFS validates a UBIFS superblock node (the start of a UBIFS image or
+// UBI volume)
+func parseUBIFS(r io.ReaderAt, offset, fileSize int64) (Region, bool) {
+	if offset+ubifsSuperblockSize > fileSize {
+		return Region{}, false
+	}
+	node := make([]byte, ubifsSuperblockSize)
+	if _, err := r.ReadAt(node, offset); err != nil {
+		return Region{}, false
+	}
+
+	// The checksum covers the node after the magic number and itself; like
+	// UBI, UBIFS checksums are CRC-32 without the final inversion
+	length := binary.LittleEndian.Uint32(node[16:])
+	if node[20] != ubifsSuperblockNode || length < 96 || length > ubifsSuperblockSize ||
+		binary.LittleEndian.Uint32(node[4:]) != ^crc32.ChecksumIEEE(node[8:length]) {
+		return Region{}, false
+	}
+	lebSize := binary.LittleEndian.Uint32(node[36:])
+	lebCount := binary.LittleEndian.Uint32(node[40:])
+	compressor, ok := ubifsCompressors[binary.LittleEndian.Uint16(node[84:])]
+	if !ok || lebSize < 4096 {
+		return Region{}, false
+	}
+
+	return Region{
+		Type:   TypeUBIFS,
+		Offset: offset,
+		Size:   clampSize(offset, int64(lebSize)*int64(lebCount), fileSize),
+		Description: fmt.Sprintf("UBIFS filesystem, %s compression, %d logical erase blocks of %d bytes",
+			compressor, lebCount, lebSize),
+	}, true
+}
+
+// parseLZMA validates a legacy .lzma header (default properties lc=3 lp=0 pb=2)
+func parseLZMA(offset int64, h []byte) (Region, bool) {
+	if len(h) < 13 {
+		return Region{}, false
+	}
+	dict := binary.LittleEndian.Uint32(h[1:])
+	usize := binary.LittleEndian.Uint64(h[5:])
+	if dict < 1<<16 || dict > 1<<30 || bits.OnesCount32(dict) != 1 {
+		return Region{}, false
+	}
+	if usize == 0 || usize != ^uint64(0) && usize > 1<<32 {
+		return Region{}, false
+	}
+
+	description := fmt.Sprintf("LZMA compressed data, dictionary size %d bytes", dict)
+	if usize != ^uint64(0) {
+		description += fmt.Sprintf(", uncompressed size %d bytes", usize)
+	}
+	return Region{Type: TypeLZMA, Offset: offset, Description: description}, true
+}
+
+// gzip header flags
+const (
+	gzipFlagExtra    = 0x04
+	gzipFlagName     = 0x08
+	gzipFlagReserved = 0xe0
+)
+
+// parseGzip validates a gzip member header
+func parseGzip(offset int64, h []byte) (Region, bool) {
+	if len(h) < 10 || h[3]&gzipFlagReserved != 0 {
+		return Region{}, false
+	}
+	// Extra flags: 0, 2 (best) or 4 (fastest); OS: 0-13 or 255 (unknown)
+	if h[8] != 0 && h[8] != 2 && h[8] != 4 || h[9] > 13 && h[9] != 255 {
+		return Region{}, false
+	}
+
+	description := "gzip compressed data"
+	if h[3]&gzipFlagName != 0 && h[3]&gzipFlagExtra == 0 {
+		if name, _, ok := bytes.Cut(h[10:], []byte{0}); ok && len(name) > 0 {
+			description += fmt.Sprintf(", original name %q", name)
+		}
+	}
+	return Region{Type: TypeGzip, Offset: offset, Description: description}, true
+}
+
+// xzChecks names the integrity checks of an xz stream
+var xzChecks = map[byte]string{0: "none", 1: "CRC32", 4: "CRC64", 10: "SHA-256"}
+
+// parseXZ validates the stream header of xz data
+func parseXZ(offset int64, h []byte) (Region, bool) {
+	if len(h) < 12 || h[6] != 0 {
+		return Region{}, false
+	}
+	check, ok := xzChecks[h[7]]
+	if !ok || binary.LittleEndian.Uint32(h[8:]) != crc32.ChecksumIEEE(h[6:8]) {
+		return Region{}, false
+	}
+	return Region{Type: TypeXZ, Offset: offset, Description: "xz compressed data, " + check + " check"}, true
+}
@@ -0,0 +1,201 @@
+package signature
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"hash/crc32"
+	"strings"
+	"testing"
+)
+
+// squashfsHeader returns a little-endian SquashFS 4 superblock of bytesUsed bytes
+func squashfsHeader(bytesUsed uint64) []byte {
+	h := make([]byte, 96)
+	copy(h, "hsqs")
+	binary.LittleEndian.PutUint32(h[4:], 12)      // Inodes
+	binary.LittleEndian.PutUint32(h[12:], 131072) // Block size
+	binary.LittleEndian.PutUint16(h[20:], 4)      // xz
+	binary.LittleEndian.PutUint16(h[22:], 17)     // log2(block size)
+	binary.LittleEndian.PutUint16(h[28:], 4)
+	binary.LittleEndian.PutUint64(h[40:], bytesUsed)
+	return h
+}
+
+// jffs2Node returns a big-endian JFFS2 node of totlen bytes
+func jffs2Node(nodeType uint16, totlen int) []byte {
+	node := make([]byte, totlen)
+	binary.BigEndian.PutUint16(node[0:], 0x1985)
+	binary.BigEndian.PutUint16(node[2:], nodeType)
+	binary.BigEndian.PutUint32(node[4:], uint32(totlen))
+	binary.BigEndian.PutUint32(node[8:], ^crc32.Update(0xffffffff, crc32.IEEETable, node[:8]))
+	return node
+}
+
+// TestScan tests detection of each format at its offset
+func TestScan(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	zw.Name = "rootfs.cpio"
+	_, _ = zw.Write([]byte("payload"))
+	_ = zw.Close()
+
+	cramfs := make([]byte, 64)
+	binary.LittleEndian.PutUint32(cramfs[0:], 0x28cd3d45)
+	binary.LittleEndian.PutUint32(cramfs[4:], 4096)
+	copy(cramfs[16:], "Compressed ROMFS")
+	copy(cramfs[48:], "Compressed")
+
+	lzma := []byte{0x5d, 0, 0, 0x80, 0, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	xz := []byte{0xfd, '7', 'z', 'X', 'Z', 0, 0, 4, 0, 0, 0, 0}
+	binary.LittleEndian.PutUint32(xz[8:], crc32.ChecksumIEEE(xz[6:8]))
+
+	jffs2 := append(jffs2Node(0x2003, 12), 0xff, 0xff, 0xff, 0xff)
+	jffs2 = append(jffs2, jffs2Node(0xe002, 70)...)
+
+	ubi := make([]byte, 64)
+	copy(ubi, "UBI#")
+	ubi[4] = 1
+	binary.BigEndian.PutUint32(ubi[60:], ^crc32.ChecksumIEEE(ubi[:60]))
+
+	ubifs := make([]byte, 4096)
+	binary.LittleEndian.PutUint32(ubifs[0:], 0x06101831)
+	binary.LittleEndian.PutUint32(ubifs[16:], 4096)
+	ubifs[20] = 6                                   // Superblock node
+	binary.LittleEndian.PutUint32(ubifs[36:], 8192) // LEB size
+	binary.LittleEndian.PutUint32(ubifs[40:], 16)   // LEB count
+	binary.LittleEndian.PutUint16(ubifs[84:], 2)    // zlib
+	binary.LittleEndian.PutUint32(ubifs[4:], ^crc32.ChecksumIEEE(ubifs[8:]))
+
+	tests := []struct {
+		name string
+		blob []byte
+		want Region
+	}{
+		{"squashfs", append(squashfsHeader(4096), make([]byte, 4000)...), Region{Type: TypeSquashFS, Size: 4096,
+			Description: "SquashFS 4.0 filesystem, little endian, xz compressed, 12 inodes, block size 131072"}},
+		{"cramfs", cramfs, Region{Type: TypeCramFS, Size: 64 + 200,
+			Description: `cramfs filesystem, little endian, name "Compressed"`}},
+		{"jffs2", jffs2, Region{Type: TypeJFFS2, Size: 12 + 4 + 70,
+			Description: "JFFS2 filesystem, big endian, 2 nodes"}},
+		{"ubi", ubi, Region{Type: TypeUBI,
+			Description: "UBI image, version 1"}},
+		{"ubifs", ubifs, Region{Type: TypeUBIFS, Size: 4096 + 200,
+			Description: "UBIFS filesystem, zlib compression, 16 logical erase blocks of 8192 bytes"}},
+		{"lzma", lzma, Region{Type: TypeLZMA,
+			Description: "LZMA compressed data, dictionary size 8388608 bytes"}},
+		{"gzip", gz.Bytes(), Region{Type: TypeGzip,
+			Description: `gzip compressed data, original name "rootfs.cpio"`}},
+		{"xz", xz, Region{Type: TypeXZ,
+			Description: "xz compressed data, CRC64 check"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The blob follows some text and is followed by padding
+			data := append([]byte(strings.Repeat("header text ", 10)), tt.blob...)
+			data = append(data, make([]byte, 200)...)
+
+			regions, err := Scan(bytes.NewReader(data), int64(len(data)))
+			if err != nil {
+				t.Fatalf("Scan() error = %v", err)
+			}
+			want := tt.want
+			want.Offset = 120
+			if len(regions) != 1 || regions[0] != want {
+				t.Errorf("Scan() = %+v, want [%+v]", regions, want)
+			}
+		})
+	}
+}
+
+// TestScanRejectsInvalidHeaders tests that bare magic numbers are not reported
+func TestScanRejectsInvalidHeaders(t *testing.T) {
+	badSquashfs := squashfsHeader(4096)
+	binary.LittleEndian.PutUint16(badSquashfs[28:], 9) // Unknown version
+
+	badJFFS2 := jffs2Node(0xe002, 32)
+	badJFFS2[9] ^= 0xff // Header checksum
+
+	data := bytes.Join([][]byte{
+		badSquashfs,
+		badJFFS2,
+		[]byte("hsqs"),
+		append([]byte("UBI#\x01"), make([]byte, 59)...),  // Header checksum
+		{0x31, 0x18, 0x10, 0x06},                         // Truncated UBIFS node
+		{0x5d, 0, 0, 0x12, 0x34, 0, 0, 0, 0, 0, 0, 0, 0}, // Dictionary size not a power of two
+		{0x1f, 0x8b, 0x08, 0xff, 0, 0, 0, 0, 0, 0},       // Reserved gzip flags
+		{0xfd, '7', 'z', 'X', 'Z', 0, 0, 4, 1, 2, 3, 4},  // xz flags checksum
+		[]byte("Compressed ROMFS"),
+		make([]byte, 64),
+	}, []byte("...."))
+
+	regions, err := Scan(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(regions) != 0 {
+		t.Errorf("Scan() = %+v, want no regions", regions)
+	}
+}
+
+// TestScanSkipsNestedMagic tests that magic numbers inside a region of known
+// size are not reported separately and that regions spanning chunks are found
+func TestScanSkipsNestedMagic(t *testing.T) {
+	var gz bytes.Buffer
+	zw := gzip.NewWriter(&gz)
+	_, _ = zw.Write([]byte("block"))
+	_ = zw.Close()
+
+	fs := squashfsHeader(8192)
+	fs = append(fs, gz.Bytes()...)
+	fs = append(fs, make([]byte, 8192-len(fs))...)
+
+	// The filesystem starts just before a chunk boundary
+	data := make([]byte, chunkSize-10)
+	data = append(data, fs...)
+	data = append(data, gz.Bytes()...)
+
+	regions, err := Scan(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(regions) != 2 {
+		t.Fatalf("Scan() = %+v, want a filesystem and a gzip member after it", regions)
+	}
+	if regions[0].Type != TypeSquashFS || regions[0].Offset != chunkSize-10 {
+		t.Errorf("regions[0] = %+v, want squashfs at %d", regions[0], chunkSize-10)
+	}
+	if regions[1].Type != TypeGzip || regions[1].Offset != chunkSize-10+8192 {
+		t.Errorf("regions[1] = %+v, want gzip at %d", regions[1], chunkSize-10+8192)
+	}
+}
+
+// TestScanTruncated tests that sizes are clamped to the end of the file
+func TestScanTruncated(t *testing.T) {
+	data := squashfsHeader(1 << 20)
+	regions, err := Scan(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	if len(regions) != 1 || regions[0].Size != int64(len(data)) {
+		t.Errorf("Scan() = %+v, want one region of %d bytes", regions, len(data))
+	}
+}
+
+// TestScanJFFS2Unaligned tests that JFFS2 nodes are aligned from the start of
+// a filesystem that is not 4-byte aligned in the file
+func TestScanJFFS2Unaligned(t *testing.T) {
+	data := append([]byte{1, 2, 3}, jffs2Node(0x2004, 13)...)
+	data = append(data, 0xff, 0xff, 0xff)
+	data = append(data, jffs2Node(0x2004, 16)...)
+
+	regions, err := Scan(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	want := Region{Type: TypeJFFS2, Offset: 3, Size: 32, Description: "JFFS2 filesystem, big endian, 2 nodes"}
+	if len(regions) != 1 || regions[0] != want {
+		t.Errorf("Scan() = %+v, want [%+v]", regions, want)
+	}
+}
@@ -0,0 +1,171 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/richardwooding/txtr/internal/printer"
+	"golang.org/x/text/message"
+)
+
+// String kinds counted in Statistics.Kinds (see Classify)
+const (
+	KindNumeric    = "numeric"    // Digits with separators only: counters, dates, versions
+	KindHex        = "hex"        // Hex digits: hashes, keys, addresses
+	KindIdentifier = "identifier" // Symbol, function or dotted names without spaces
+	KindSentence   = "sentence"   // Words separated by spaces: messages, prose
+	KindOther      = "other"      // Anything else: paths, format strings, noise
+)
+
+// stringKinds lists the string kinds in display order
+var stringKinds = []string{KindNumeric, KindHex, KindIdentifier, KindSentence, KindOther}
+
+// Character classes counted in Statistics.CharClasses
+const (
+	ClassLetter      = "letters"
+	ClassDigit       = "digits"
+	ClassPunctuation = "punctuation" // Punctuation and symbols
+	ClassWhitespace  = "whitespace"
+	ClassOther       = "other" // Control and unprintable characters, invalid UTF-8
+)
+
+// charClasses lists the character classes in display order
+var charClasses = []string{ClassLetter, ClassDigit, ClassPunctuation, ClassWhitespace, ClassOther}
+
+// Classify returns the kind of content a string holds, the first of:
+// numeric-only (digits and . , : - + / separators), hex-looking (hex digits,
+// optionally with a 0x prefix, with both digits and letters or the prefix),
+// identifier-like (a letter or _ followed by letters, digits and _ . : $),
+// sentence-like (two or more words, mostly letters) or other
+func Classify(str []byte) string {
+	var letters, digits, spaces, other int
+	identifier := len(str) > 0 && (isASCIILetter(str[0]) || str[0] == '_')
+	for _, b := range str {
+		switch {
+		case b >= '0' && b <= '9':
+			digits++
+		case isASCIILetter(b):
+			letters++
+		case b == ' ':
+			spaces++
+		case b == '.' || b == ',' || b == ':' || b == '-' || b == '+' || b == '/':
+			// Separators of numbers
+		default:
+			other++
+		}
+		if !isASCIILetter(b) && !(b >= '0' && b <= '9') && b != '_' && b != '.' && b != ':' && b != '$' {
+			identifier = false
+		}
+	}
+
+	switch {
+	case digits > 0 && letters == 0 && spaces == 0 && other == 0:
+		return KindNumeric
+	case isHex(str):
+		return KindHex
+	case identifier:
+		return KindIdentifier
+	case spaces > 0 && isASCIILetter(str[0]) && letters*10 >= (len(str)-spaces)*6 && hasWords(str):
+		return KindSentence
+	}
+	return KindOther
+}
+
+// isHex reports whether str looks like hex: hex digits only, with both
+// digits and letters (so words like "face" are not hex) or a 0x prefix
+func isHex(str []byte) bool {
+	prefixed := len(str) > 2 && str[0] == '0' && str[1]|0x20 == 'x'
+	if prefixed {
+		str = str[2:]
+	}
+	var digits, letters int
+	for _, b := range str {
+		switch {
+		case b >= '0' && b <= '9':
+			digits++
+		case b|0x20 >= 'a' && b|0x20 <= 'f':
+			letters++
+		default:
+			return false
+		}
+	}
+	return prefixed || digits > 0 && letters > 0
+}
+
+// hasWords reports whether str has two or more words of two or more letters
+func hasWords(str []byte) bool {
+	words, run := 0, 0
+	for i := 0; i <= len(str); i++ {
+		if i < len(str) && isASCIILetter(str[i]) {
+			run++
+			continue
+		}
+		if run >= 2 {
+			words++
+		}
+		run = 0
+	}
+	return words >= 2
+}
+
+// isASCIILetter reports whether b is an ASCII letter
+func isASCIILetter(b byte) bool {
+	return b|0x20 >= 'a' && b|0x20 <= 'z'
+}
+
+// countCharClasses adds the characters of str to s.CharClasses
+func (s *Statistics) countCharClasses(str []byte) {
+	for len(str) > 0 {
+		r, size := utf8.DecodeRune(str)
+		str = str[size:]
+		switch {
+		case r == utf8.RuneError && size == 1:
+			s.CharClasses[ClassOther]++
+		case unicode.IsLetter(r):
+			s.CharClasses[ClassLetter]++
+		case unicode.IsDigit(r):
+			s.CharClasses[ClassDigit]++
+		case unicode.IsSpace(r):
+			s.CharClasses[ClassWhitespace]++
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			s.CharClasses[ClassPunctuation]++
+		default:
+			s.CharClasses[ClassOther]++
+		}
+	}
+}
+
+// formatComposition writes the string kinds and character classes
+//
+//nolint:errcheck // Writing to stdout/buffer, errors are not critical
+func (s *Statistics) formatComposition(w io.Writer, p *message.Printer, useColor bool) {
+	header := printer.ColorString(p.Sprintf("String composition:"), printer.AnsiBold+printer.AnsiCyan, useColor)
+	fmt.Fprintf(w, "  %s\n", header)
+	s.formatCounts(w, p, useColor, stringKinds, s.Kinds)
+
+	header = printer.ColorString(p.Sprintf("Character classes:"), printer.AnsiBold+printer.AnsiCyan, useColor)
+	fmt.Fprintf(w, "  %s\n", header)
+	s.formatCounts(w, p, useColor, charClasses, s.CharClasses)
+}
+
+// formatCounts writes the counts of names with their share of the total,
+// aligned
+//
+//nolint:errcheck // Writing to stdout/buffer, errors are not critical
+func (s *Statistics) formatCounts(w io.Writer, p *message.Printer, useColor bool, names []string, counts map[string]int) {
+	total, width := 0, 0
+	for _, name := range names {
+		total += counts[name]
+		width = max(width, len(formatNumber(p, counts[name])))
+	}
+	for _, name := range names {
+		count := counts[name]
+		label := printer.ColorString(fmt.Sprintf("%-12s", name+":"), printer.AnsiMagenta, useColor)
+		countNum := printer.ColorString(fmt.Sprintf("%*s", width, formatNumber(p, count)), printer.AnsiYellow, useColor)
+		pct := printer.ColorString(p.Sprintf("%5.1f%%", percentage(count, total)), printer.AnsiGreen, useColor)
+		fmt.Fprintf(w, "    %s %s (%s)\n", label, countNum, pct)
+	}
+	fmt.Fprintln(w)
+}
@@ -0,0 +1,25 @@
+//go:build linux
+
+package stats
+
+import (
+	"syscall"
+	"time"
+)
+
+// rusageThread is RUSAGE_THREAD (not exported by package syscall)
+const rusageThread = 1
+
+// threadCPUSupported reports whether ThreadCPUTime returns per-thread CPU time
+const threadCPUSupported = true
+
+// ThreadCPUTime returns the user+system CPU time consumed by the calling OS
+// thread. Callers should lock the goroutine to its thread
+// (runtime.LockOSThread) while measuring.
+func ThreadCPUTime() time.Duration {
+	var usage syscall.Rusage
+	if err := syscall.Getrusage(rusageThread, &usage); err != nil {
+		return 0
+	}
+	return time.Duration(usage.Utime.Nano() + usage.Stime.Nano())
+}
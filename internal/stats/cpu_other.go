@@ -0,0 +1,14 @@
+//go:build !linux
+
+package stats
+
+import "time"
+
+// threadCPUSupported reports whether ThreadCPUTime returns per-thread CPU time
+const threadCPUSupported = false
+
+// ThreadCPUTime returns the CPU time consumed by the calling OS thread.
+// Per-thread accounting is only available on Linux; elsewhere it returns 0.
+func ThreadCPUTime() time.Duration {
+	return 0
+}
@@ -0,0 +1,132 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"math/bits"
+	"slices"
+	"strings"
+
+	"github.com/richardwooding/txtr/internal/printer"
+	"golang.org/x/text/message"
+)
+
+// lengthPercentiles are the percentiles of string lengths reported
+var lengthPercentiles = []int{50, 90, 99}
+
+// histogramWidth is the width of the longest bar of the length histogram
+const histogramWidth = 30
+
+// LengthCount is the number of strings of one length
+type LengthCount struct {
+	Length int
+	Count  int
+}
+
+// Lengths returns the number of strings of each length, by length
+func (s *Statistics) Lengths() []LengthCount {
+	lengths := make([]LengthCount, 0, len(s.LengthCounts))
+	for length, count := range s.LengthCounts {
+		lengths = append(lengths, LengthCount{Length: length, Count: count})
+	}
+	slices.SortFunc(lengths, func(a, b LengthCount) int { return a.Length - b.Length })
+	return lengths
+}
+
+// LengthPercentile returns the length that percent of the strings are at most
+// as long as (nearest rank), or 0 if there are no strings
+func (s *Statistics) LengthPercentile(percent int) int {
+	total := 0
+	for _, count := range s.LengthCounts {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(float64(percent) / 100 * float64(total)))
+	seen := 0
+	for _, lc := range s.Lengths() {
+		seen += lc.Count
+		if seen >= rank {
+			return lc.Length
+		}
+	}
+	return s.MaxLength
+}
+
+// lengthBin is a bar of the length histogram: the strings of lengths from
+// low to high
+type lengthBin struct {
+	low, high int
+	count     int
+}
+
+// lengthHistogram groups the string lengths into bins doubling in width
+// (4-7, 8-15, 16-31, ...), from the shortest length found to the longest
+func (s *Statistics) lengthHistogram() []lengthBin {
+	var histogram []lengthBin
+	for _, lc := range s.Lengths() {
+		low := 1 << (bits.Len(uint(lc.Length)) - 1)
+		if lc.Length == 0 {
+			low = 0
+		}
+		// Add empty bins between the last one and this one
+		for len(histogram) > 0 && histogram[len(histogram)-1].high < low-1 {
+			next := histogram[len(histogram)-1].high + 1
+			histogram = append(histogram, lengthBin{low: next, high: 2*next - 1})
+		}
+		if len(histogram) == 0 || histogram[len(histogram)-1].low != low {
+			histogram = append(histogram, lengthBin{low: low, high: max(2*low-1, 0)})
+		}
+		histogram[len(histogram)-1].count += lc.Count
+	}
+	return histogram
+}
+
+// formatLengths writes the length percentiles and histogram
+//
+//nolint:errcheck // Writing to stdout/buffer, errors are not critical
+func (s *Statistics) formatLengths(w io.Writer, p *message.Printer, useColor bool) {
+	header := printer.ColorString(p.Sprintf("Length percentiles:"), printer.AnsiBold+printer.AnsiCyan, useColor)
+	fmt.Fprintf(w, "  %s\n", header)
+	fields := make([]string, len(lengthPercentiles))
+	for i, percent := range lengthPercentiles {
+		value := printer.ColorString(formatNumber(p, s.LengthPercentile(percent)), printer.AnsiYellow, useColor)
+		fields[i] = fmt.Sprintf("p%d: %s", percent, value)
+	}
+	fmt.Fprintf(w, "    %s\n\n", strings.Join(fields, "   "))
+
+	histogram := s.lengthHistogram()
+	header = printer.ColorString(p.Sprintf("Length histogram:"), printer.AnsiBold+printer.AnsiCyan, useColor)
+	fmt.Fprintf(w, "  %s\n", header)
+	most, labelWidth := 0, 0
+	for _, bin := range histogram {
+		most = max(most, bin.count)
+		labelWidth = max(labelWidth, len(fmt.Sprintf("%d-%d", bin.low, bin.high)))
+	}
+	for _, bin := range histogram {
+		label := fmt.Sprintf("%d-%d", bin.low, bin.high)
+		// Non-empty bins get at least one mark
+		bar := (bin.count*histogramWidth + most - 1) / most
+		bars := printer.ColorString(strings.Repeat("#", bar), printer.AnsiGreen, useColor)
+		countNum := printer.ColorString(formatNumber(p, bin.count), printer.AnsiYellow, useColor)
+		fmt.Fprintf(w, "    %-*s %s%s %s\n", labelWidth, label, bars, strings.Repeat(" ", histogramWidth-bar), countNum)
+	}
+	fmt.Fprintln(w)
+}
+
+// lengthsJSON returns the length percentiles and the number of strings of
+// each length for JSON output
+func (s *Statistics) lengthsJSON() (map[string]int, []map[string]int) {
+	percentiles := make(map[string]int, len(lengthPercentiles))
+	for _, percent := range lengthPercentiles {
+		percentiles[fmt.Sprintf("p%d", percent)] = s.LengthPercentile(percent)
+	}
+	lengths := s.Lengths()
+	counts := make([]map[string]int, len(lengths))
+	for i, lc := range lengths {
+		counts[i] = map[string]int{"length": lc.Length, "count": lc.Count}
+	}
+	return percentiles, counts
+}
@@ -0,0 +1,199 @@
+package stats
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+	"golang.org/x/text/message/catalog"
+)
+
+// statsCatalog holds the translations of the text statistics report. Messages
+// are keyed by their English format strings; translations bring their own
+// label padding so that values stay aligned.
+var statsCatalog = newStatsCatalog()
+
+// newStatsCatalog builds the message catalog from statsMessages
+func newStatsCatalog() catalog.Catalog {
+	builder := catalog.NewBuilder(catalog.Fallback(language.English))
+	for tag, messages := range statsMessages {
+		for _, m := range messages {
+			if err := builder.SetString(tag, m[0], m[1]); err != nil {
+				panic(fmt.Sprintf("stats: invalid translation %q: %v", m[1], err))
+			}
+		}
+	}
+	return builder
+}
+
+// ParseLocale converts a locale name in BCP 47 (de-CH) or POSIX form
+// (de_CH.UTF-8@euro) to a language tag. "C" and "POSIX" select English.
+func ParseLocale(name string) (language.Tag, error) {
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, "@")
+	if name == "" || name == "C" || name == "POSIX" {
+		return language.English, nil
+	}
+	return language.Parse(strings.ReplaceAll(name, "_", "-"))
+}
+
+// LocaleFromEnv returns the locale of the environment from LC_ALL, LC_MESSAGES
+// or LANG (the first one set), falling back to English
+func LocaleFromEnv() language.Tag {
+	for _, name := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		if value := os.Getenv(name); value != "" {
+			if tag, err := ParseLocale(value); err == nil {
+				return tag
+			}
+			break
+		}
+	}
+	return language.English
+}
+
+// messagePrinter returns the printer for the text report in s.Locale
+func (s *Statistics) messagePrinter() *message.Printer {
+	tag := s.Locale
+	if tag == language.Und {
+		tag = language.English
+	}
+	return message.NewPrinter(tag, message.Catalog(statsCatalog))
+}
+
+// statsMessages are the translations of the text report
+var statsMessages = map[language.Tag][][2]string{
+	language.German: {
+		{"Statistics for %s:", "Statistik für %s:"},
+		{"Statistics:", "Statistik:"},
+		{"  Binary format:     %s\n", "  Binärformat:           %s\n"},
+		{"  Sections scanned:  %s\n", "  Gescannte Sektionen:   %s\n"},
+		{"  Total strings:     %s\n", "  Zeichenketten gesamt:  %s\n"},
+		{"  Total bytes:       %s\n", "  Bytes gesamt:          %s\n"},
+		{"  Min length:        %s (configured)\n", "  Mindestlänge:          %s (konfiguriert)\n"},
+		{"  Max length:        %s\n", "  Maximale Länge:        %s\n"},
+		{"  Avg length:        %s\n", "  Mittlere Länge:        %s\n"},
+		{"  Read retries:      %s\n", "  Lese-Wiederholungen:   %s\n"},
+		{"  String hash:       %s\n", "  String-Hash:           %s\n"},
+		{"  Total strings extracted:  %s\n", "  Extrahierte Zeichenketten:  %s\n"},
+		{"  Matched filters:          %s (%s)\n", "  Passend zu Filtern:         %s (%s)\n"},
+		{"    Files processed:  %s\n", "    Verarbeitete Dateien:  %s\n"},
+		{"    Elapsed:          %s\n", "    Verstrichen:           %s\n"},
+		{"    Wall time:        %s\n", "    Echtzeit:              %s\n"},
+		{"    CPU time:         %s\n", "    CPU-Zeit:              %s\n"},
+		{"    Bytes read:       %s (%s)\n", "    Gelesene Bytes:        %s (%s)\n"},
+		{"String hashes:", "String-Hashes:"},
+		{"Encoding distribution:", "Verteilung der Kodierungen:"},
+		{"Length distribution:", "Längenverteilung:"},
+		{"Length percentiles:", "Längenperzentile:"},
+		{"Length histogram:", "Längenhistogramm:"},
+		{"String composition:", "Zusammensetzung der Zeichenketten:"},
+		{"Character classes:", "Zeichenklassen:"},
+		{"Longest strings:", "Längste Zeichenketten:"},
+		{"Detected libraries:", "Erkannte Bibliotheken:"},
+		{"at %s", "bei %s"},
+		{"in %s at %s", "in %s bei %s"},
+		{"Pattern matches:", "Treffer je Muster:"},
+		{"no matches", "keine Treffer"},
+		{"Performance:", "Leistung:"},
+		{"Read", "Lesen"},
+		{"Extract", "Extrahieren"},
+		{"Filter", "Filtern"},
+		{"Output", "Ausgabe"},
+		{"    %s chars:    %6s (%s)\n", "    %s Zeichen:  %6s (%s)\n"},
+		{"    %s chars at %s: %s\n", "    %s Zeichen bei %s: %s\n"},
+		{"    Stages:\n", "    Phasen:\n"},
+		{"    Workers:\n", "    Worker:\n"},
+		{"      #%-3d %s files, wall %s, cpu %s, %s bytes\n", "      #%-3d %s Dateien, Echtzeit %s, CPU %s, %s Bytes\n"},
+		{"      %-8s %s (%s)\n", "      %-12s %s (%s)\n"},
+	},
+	language.French: {
+		{"Statistics for %s:", "Statistiques pour %s :"},
+		{"Statistics:", "Statistiques :"},
+		{"  Binary format:     %s\n", "  Format binaire :         %s\n"},
+		{"  Sections scanned:  %s\n", "  Sections analysées :     %s\n"},
+		{"  Total strings:     %s\n", "  Chaînes au total :       %s\n"},
+		{"  Total bytes:       %s\n", "  Octets au total :        %s\n"},
+		{"  Min length:        %s (configured)\n", "  Longueur min. :          %s (configurée)\n"},
+		{"  Max length:        %s\n", "  Longueur max. :          %s\n"},
+		{"  Avg length:        %s\n", "  Longueur moy. :          %s\n"},
+		{"  Read retries:      %s\n", "  Lectures réessayées :    %s\n"},
+		{"  String hash:       %s\n", "  Empreinte des chaînes :  %s\n"},
+		{"  Total strings extracted:  %s\n", "  Chaînes extraites :          %s\n"},
+		{"  Matched filters:          %s (%s)\n", "  Correspondant aux filtres :  %s (%s)\n"},
+		{"    Files processed:  %s\n", "    Fichiers traités :  %s\n"},
+		{"    Elapsed:          %s\n", "    Écoulé :            %s\n"},
+		{"    Wall time:        %s\n", "    Temps réel :        %s\n"},
+		{"    CPU time:         %s\n", "    Temps CPU :         %s\n"},
+		{"    Bytes read:       %s (%s)\n", "    Octets lus :        %s (%s)\n"},
+		{"String hashes:", "Empreintes des chaînes :"},
+		{"Encoding distribution:", "Répartition des encodages :"},
+		{"Length distribution:", "Répartition des longueurs :"},
+		{"Length percentiles:", "Centiles des longueurs :"},
+		{"Length histogram:", "Histogramme des longueurs :"},
+		{"String composition:", "Composition des chaînes :"},
+		{"Character classes:", "Classes de caractères :"},
+		{"Longest strings:", "Chaînes les plus longues :"},
+		{"Detected libraries:", "Bibliothèques détectées :"},
+		{"at %s", "à %s"},
+		{"in %s at %s", "dans %s à %s"},
+		{"Pattern matches:", "Correspondances par motif :"},
+		{"no matches", "aucune correspondance"},
+		{"Performance:", "Performances :"},
+		{"Read", "Lecture"},
+		{"Extract", "Extraction"},
+		{"Filter", "Filtrage"},
+		{"Output", "Sortie"},
+		{"    %s chars:    %6s (%s)\n", "    %s caractères : %6s (%s)\n"},
+		{"    %s chars at %s: %s\n", "    %s caractères à %s : %s\n"},
+		{"    Stages:\n", "    Étapes :\n"},
+		{"    Workers:\n", "    Exécuteurs :\n"},
+		{"      #%-3d %s files, wall %s, cpu %s, %s bytes\n", "      #%-3d %s fichiers, réel %s, CPU %s, %s octets\n"},
+		{"      %-8s %s (%s)\n", "      %-12s %s (%s)\n"},
+	},
+	language.Spanish: {
+		{"Statistics for %s:", "Estadísticas de %s:"},
+		{"Statistics:", "Estadísticas:"},
+		{"  Binary format:     %s\n", "  Formato binario:       %s\n"},
+		{"  Sections scanned:  %s\n", "  Secciones analizadas:  %s\n"},
+		{"  Total strings:     %s\n", "  Cadenas totales:       %s\n"},
+		{"  Total bytes:       %s\n", "  Bytes totales:         %s\n"},
+		{"  Min length:        %s (configured)\n", "  Longitud mínima:       %s (configurada)\n"},
+		{"  Max length:        %s\n", "  Longitud máxima:       %s\n"},
+		{"  Avg length:        %s\n", "  Longitud media:        %s\n"},
+		{"  Read retries:      %s\n", "  Lecturas reintentadas: %s\n"},
+		{"  String hash:       %s\n", "  Hash de cadenas:       %s\n"},
+		{"  Total strings extracted:  %s\n", "  Cadenas extraídas:      %s\n"},
+		{"  Matched filters:          %s (%s)\n", "  Coinciden con filtros:  %s (%s)\n"},
+		{"    Files processed:  %s\n", "    Archivos procesados:  %s\n"},
+		{"    Elapsed:          %s\n", "    Transcurrido:         %s\n"},
+		{"    Wall time:        %s\n", "    Tiempo real:          %s\n"},
+		{"    CPU time:         %s\n", "    Tiempo de CPU:        %s\n"},
+		{"    Bytes read:       %s (%s)\n", "    Bytes leídos:         %s (%s)\n"},
+		{"String hashes:", "Hashes de cadenas:"},
+		{"Encoding distribution:", "Distribución de codificaciones:"},
+		{"Length distribution:", "Distribución de longitudes:"},
+		{"Length percentiles:", "Percentiles de longitud:"},
+		{"Length histogram:", "Histograma de longitudes:"},
+		{"String composition:", "Composición de las cadenas:"},
+		{"Character classes:", "Clases de caracteres:"},
+		{"Longest strings:", "Cadenas más largas:"},
+		{"Detected libraries:", "Bibliotecas detectadas:"},
+		{"at %s", "en %s"},
+		{"in %s at %s", "en %s, %s"},
+		{"Pattern matches:", "Coincidencias por patrón:"},
+		{"no matches", "sin coincidencias"},
+		{"Performance:", "Rendimiento:"},
+		{"Read", "Lectura"},
+		{"Extract", "Extracción"},
+		{"Filter", "Filtrado"},
+		{"Output", "Salida"},
+		{"    %s chars:    %6s (%s)\n", "    %s caracteres: %6s (%s)\n"},
+		{"    %s chars at %s: %s\n", "    %s caracteres en %s: %s\n"},
+		{"    Stages:\n", "    Etapas:\n"},
+		{"    Workers:\n", "    Trabajadores:\n"},
+		{"      #%-3d %s files, wall %s, cpu %s, %s bytes\n", "      #%-3d %s archivos, real %s, CPU %s, %s bytes\n"},
+		{"      %-8s %s (%s)\n", "      %-12s %s (%s)\n"},
+	},
+}
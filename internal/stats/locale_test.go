@@ -0,0 +1,93 @@
+package stats
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"golang.org/x/text/language"
+)
+
+// TestParseLocale tests BCP 47 and POSIX locale names
+func TestParseLocale(t *testing.T) {
+	tests := []struct {
+		name string
+		want language.Tag
+	}{
+		{"", language.English},
+		{"C", language.English},
+		{"POSIX.UTF-8", language.English},
+		{"de", language.German},
+		{"fr_FR.UTF-8", language.MustParse("fr-FR")},
+		{"de_DE@euro", language.MustParse("de-DE")},
+		{"pt-BR", language.MustParse("pt-BR")},
+	}
+	for _, tt := range tests {
+		got, err := ParseLocale(tt.name)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseLocale(%q) = %v, %v, want %v", tt.name, got, err, tt.want)
+		}
+	}
+
+	if _, err := ParseLocale("not a locale!"); err == nil {
+		t.Error("ParseLocale() accepted an invalid name")
+	}
+}
+
+// TestLocaleFromEnv tests the precedence of the locale environment variables
+func TestLocaleFromEnv(t *testing.T) {
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "fr_FR.UTF-8")
+	t.Setenv("LANG", "de_DE.UTF-8")
+	if got := LocaleFromEnv(); got != language.MustParse("fr-FR") {
+		t.Errorf("LocaleFromEnv() = %v, want fr-FR", got)
+	}
+
+	t.Setenv("LC_ALL", "es_ES.UTF-8")
+	if got := LocaleFromEnv(); got != language.MustParse("es-ES") {
+		t.Errorf("LocaleFromEnv() with LC_ALL = %v, want es-ES", got)
+	}
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("LC_MESSAGES", "")
+	t.Setenv("LANG", "")
+	if got := LocaleFromEnv(); got != language.English {
+		t.Errorf("LocaleFromEnv() without locale = %v, want English", got)
+	}
+}
+
+// TestFormatLocale tests translated labels and localized numbers in the text report
+func TestFormatLocale(t *testing.T) {
+	s := New(4)
+	config := extractor.Config{Encoding: "s"}
+	for range 1200 {
+		s.Add([]byte("hello world"), "", 0, config)
+	}
+	s.Locale = language.MustParse("de-DE")
+
+	var buf bytes.Buffer
+	s.Format(&buf, extractor.ColorNever)
+	output := buf.String()
+
+	for _, want := range []string{
+		"Statistik:",
+		"  Zeichenketten gesamt:  1.200\n",
+		"  Bytes gesamt:          13.200\n",
+		"  Mittlere Länge:        11,0\n",
+		"  Mindestlänge:          4 (konfiguriert)\n",
+		"Längenverteilung:",
+		"11-50 Zeichen:   1.200 (100,0%)",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Format() in German missing %q:\n%s", want, output)
+		}
+	}
+
+	// Every catalog message must have a translation in each language
+	for tag, messages := range statsMessages {
+		if len(messages) != len(statsMessages[language.German]) {
+			t.Errorf("%v has %d messages, want %d", tag, len(messages), len(statsMessages[language.German]))
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/richardwooding/txtr/internal/printer"
+	"golang.org/x/text/message"
+)
+
+// PatternHit is the number of reported strings matching one match pattern
+type PatternHit struct {
+	Pattern string
+	Hits    int
+}
+
+// TrackPatterns registers match patterns so that patterns without hits are
+// reported too. Patterns are reported in the order they were first seen.
+func (s *Statistics) TrackPatterns(patterns []*regexp.Regexp) {
+	for _, pattern := range patterns {
+		s.patternIndex(pattern.String())
+	}
+}
+
+// patternIndex returns the index of a pattern in PatternHits, adding it if needed
+func (s *Statistics) patternIndex(pattern string) int {
+	for i, hit := range s.PatternHits {
+		if hit.Pattern == pattern {
+			return i
+		}
+	}
+	s.PatternHits = append(s.PatternHits, PatternHit{Pattern: pattern})
+	return len(s.PatternHits) - 1
+}
+
+// countPatterns counts the match patterns matching a reported string. A string
+// matching several patterns counts for each of them.
+func (s *Statistics) countPatterns(str []byte, patterns []*regexp.Regexp) {
+	if len(s.PatternHits) < len(patterns) {
+		s.TrackPatterns(patterns)
+	}
+	for _, pattern := range patterns {
+		if pattern.Match(str) {
+			s.PatternHits[s.patternIndex(pattern.String())].Hits++
+		}
+	}
+}
+
+// mergePatterns adds the pattern hits of other
+func (s *Statistics) mergePatterns(other *Statistics) {
+	for _, hit := range other.PatternHits {
+		s.PatternHits[s.patternIndex(hit.Pattern)].Hits += hit.Hits
+	}
+}
+
+// formatPatterns writes the pattern hit counts, listing patterns without hits last
+//
+//nolint:errcheck // Writing to stdout/buffer, errors are not critical
+func (s *Statistics) formatPatterns(w io.Writer, p *message.Printer, useColor bool) {
+	header := printer.ColorString(p.Sprintf("Pattern matches:"), printer.AnsiBold+printer.AnsiCyan, useColor)
+	fmt.Fprintf(w, "  %s\n", header)
+
+	width := 0
+	for _, hit := range s.PatternHits {
+		width = max(width, len(hit.Pattern))
+	}
+
+	var unmatched []string
+	for _, hit := range s.PatternHits {
+		if hit.Hits == 0 {
+			unmatched = append(unmatched, hit.Pattern)
+			continue
+		}
+		countNum := printer.ColorString(formatNumber(p, hit.Hits), printer.AnsiYellow, useColor)
+		pct := printer.ColorString(p.Sprintf("%5.1f%%", percentage(hit.Hits, s.TotalStrings)), printer.AnsiGreen, useColor)
+		fmt.Fprintf(w, "    %-*s  %6s (%s)\n", width, hit.Pattern, countNum, pct)
+	}
+	for _, pattern := range unmatched {
+		none := printer.ColorString(p.Sprintf("no matches"), printer.AnsiDim, useColor)
+		fmt.Fprintf(w, "    %-*s  %6s (%s)\n", width, pattern, "0", none)
+	}
+	fmt.Fprintln(w)
+}
+
+// patternsJSON returns the pattern hit counts for JSON output
+func (s *Statistics) patternsJSON() []map[string]any {
+	patterns := make([]map[string]any, len(s.PatternHits))
+	for i, hit := range s.PatternHits {
+		patterns[i] = map[string]any{
+			"pattern": hit.Pattern,
+			"matches": hit.Hits,
+		}
+	}
+	return patterns
+}
@@ -5,12 +5,18 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math"
 	"sort"
 	"strings"
+	"time"
 	"unicode/utf8"
 
 	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/fingerprint"
+	"github.com/richardwooding/txtr/internal/fuzzyhash"
 	"github.com/richardwooding/txtr/internal/printer"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
 )
 
 // Statistics holds aggregated statistics about extracted strings
@@ -31,9 +37,31 @@ type Statistics struct {
 	// Distribution maps
 	EncodingCounts map[string]int
 	LengthBuckets  map[string]int
+	LengthCounts   map[int]int    // Strings of each length (see LengthPercentile)
+	Kinds          map[string]int // Strings of each kind of content (see Classify)
+	CharClasses    map[string]int // Characters of each class, e.g. letters or digits
 
 	// Longest strings
 	LongestStrings []LongestString
+
+	// Hits per match pattern (-m), in pattern order
+	PatternHits []PatternHit
+
+	// Third-party libraries identified from version banners
+	Libraries     []DetectedLibrary
+	seenLibraries map[string]bool
+
+	// Distinct strings of each file for similarity hashing (see StringHashes)
+	stringSets map[string]*fuzzyhash.Hasher
+
+	// Performance accounting (nil unless timing is recorded)
+	Timing        *Timing
+	WorkerTimings map[int]*Timing // Per-worker totals
+	Elapsed       time.Duration   // Real time of the whole run
+	Retries       int64           // Opens and reads retried after transient errors (--retries)
+
+	// Language and number format of the text report (see Format); English if unset
+	Locale language.Tag
 }
 
 // LongestString represents one of the longest strings found
@@ -43,12 +71,26 @@ type LongestString struct {
 	Offset int64
 }
 
+// DetectedLibrary is a library found in a file, with the string that identified it
+type DetectedLibrary struct {
+	fingerprint.Library
+	File     string
+	Offset   int64
+	Evidence string
+}
+
+// maxEvidenceLength caps the evidence string kept for a detected library
+const maxEvidenceLength = 120
+
 // New creates a new Statistics instance with initialized maps
 func New(minLength int) *Statistics {
 	return &Statistics{
 		MinLength:      minLength,
 		EncodingCounts: make(map[string]int),
 		LengthBuckets:  make(map[string]int),
+		LengthCounts:   make(map[int]int),
+		Kinds:          make(map[string]int),
+		CharClasses:    make(map[string]int),
 		LongestStrings: make([]LongestString, 0, 5),
 	}
 }
@@ -67,7 +109,7 @@ func (s *Statistics) AddUnfiltered() {
 
 // Add adds a string to the statistics (for strings that passed filters)
 // This method signature matches the printFunc signature for easy integration
-func (s *Statistics) Add(str []byte, _ string, offset int64, config extractor.Config) {
+func (s *Statistics) Add(str []byte, filename string, offset int64, config extractor.Config) {
 	s.TotalStrings++
 	s.FilteredCount++
 	s.TotalBytes += int64(len(str))
@@ -88,6 +130,88 @@ func (s *Statistics) Add(str []byte, _ string, offset int64, config extractor.Co
 	// Update length bucket
 	bucket := s.getBucket(length)
 	s.LengthBuckets[bucket]++
+	s.LengthCounts[length]++
+
+	// Classify the content of the string
+	s.Kinds[Classify(str)]++
+	s.countCharClasses(str)
+
+	// Count hits per match pattern
+	if len(config.MatchPatterns) > 0 {
+		s.countPatterns(str, config.MatchPatterns)
+	}
+
+	// Collect the string set of the file for its similarity hash
+	s.stringSet(filename).Add(str)
+
+	// Identify libraries from version banners
+	if lib, ok := fingerprint.Identify(str); ok {
+		evidence := string(str)
+		if len(evidence) > maxEvidenceLength {
+			evidence = evidence[:maxEvidenceLength-3] + "..."
+		}
+		s.addLibrary(DetectedLibrary{Library: lib, File: filename, Offset: offset, Evidence: evidence})
+	}
+}
+
+// Emit adds a string to the statistics (implements extractor.StringSink)
+func (s *Statistics) Emit(r extractor.Result) error {
+	s.Add(r.Str, r.File, r.Offset, r.Config)
+	return nil
+}
+
+// Close implements extractor.StringSink. The statistics stay available to
+// Format and ToJSON.
+func (s *Statistics) Close() error {
+	return nil
+}
+
+// stringSet returns the string set of a file, creating it if needed
+func (s *Statistics) stringSet(filename string) *fuzzyhash.Hasher {
+	set, ok := s.stringSets[filename]
+	if !ok {
+		if s.stringSets == nil {
+			s.stringSets = make(map[string]*fuzzyhash.Hasher)
+		}
+		set = &fuzzyhash.Hasher{}
+		s.stringSets[filename] = set
+	}
+	return set
+}
+
+// FileHash is the similarity hash of the strings of one file
+type FileHash struct {
+	File string
+	Hash string
+}
+
+// StringHashes returns the similarity hash (see fuzzyhash) of the strings of
+// each file, sorted by file name. Files with fewer than fuzzyhash.MinStrings
+// distinct strings have no hash and are left out.
+func (s *Statistics) StringHashes() []FileHash {
+	var hashes []FileHash
+	for file, set := range s.stringSets {
+		if hash := set.Sum(); hash != "" {
+			hashes = append(hashes, FileHash{File: file, Hash: hash})
+		}
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return hashes[i].File < hashes[j].File
+	})
+	return hashes
+}
+
+// addLibrary records a detected library once per file, name and version
+func (s *Statistics) addLibrary(lib DetectedLibrary) {
+	key := lib.File + "\x00" + lib.Name + "\x00" + lib.Version
+	if s.seenLibraries[key] {
+		return
+	}
+	if s.seenLibraries == nil {
+		s.seenLibraries = make(map[string]bool)
+	}
+	s.seenLibraries[key] = true
+	s.Libraries = append(s.Libraries, lib)
 }
 
 // detectEncoding classifies the encoding type of a string
@@ -152,12 +276,24 @@ func (s *Statistics) updateLongest(str []byte, offset int64, length int) {
 	// Add to list
 	s.LongestStrings = append(s.LongestStrings, entry)
 
-	// Sort by length (descending)
+	// Keep only top 5
+	s.trimLongest()
+}
+
+// trimLongest sorts the longest strings by length (descending), breaking
+// ties by offset and value so the same strings are kept whatever order
+// workers merged them in, and keeps the top 5
+func (s *Statistics) trimLongest() {
 	sort.Slice(s.LongestStrings, func(i, j int) bool {
-		return s.LongestStrings[i].Length > s.LongestStrings[j].Length
+		a, b := s.LongestStrings[i], s.LongestStrings[j]
+		if a.Length != b.Length {
+			return a.Length > b.Length
+		}
+		if a.Offset != b.Offset {
+			return a.Offset < b.Offset
+		}
+		return a.Value < b.Value
 	})
-
-	// Keep only top 5
 	if len(s.LongestStrings) > 5 {
 		s.LongestStrings = s.LongestStrings[:5]
 	}
@@ -177,25 +313,26 @@ func (s *Statistics) AvgLength() float64 {
 func (s *Statistics) Format(w io.Writer, colorMode extractor.ColorMode) {
 	// Determine if colors should be used
 	useColor := printer.ShouldUseColor(colorMode)
+	p := s.messagePrinter()
 
 	// Header
 	if s.Filename != "" {
-		header := "Statistics for " + s.Filename + ":"
+		header := p.Sprintf("Statistics for %s:", s.Filename)
 		header = printer.ColorString(header, printer.AnsiBold+printer.AnsiCyan, useColor)
 		fmt.Fprintf(w, "%s\n", header)
 	} else {
-		header := printer.ColorString("Statistics:", printer.AnsiBold+printer.AnsiCyan, useColor)
+		header := printer.ColorString(p.Sprintf("Statistics:"), printer.AnsiBold+printer.AnsiCyan, useColor)
 		fmt.Fprintf(w, "%s\n", header)
 	}
 
 	// Binary format info
 	if s.BinaryFormat != "" {
 		format := printer.ColorString(s.BinaryFormat, printer.AnsiCyan, useColor)
-		fmt.Fprintf(w, "  Binary format:     %s\n", format)
+		p.Fprintf(w, "  Binary format:     %s\n", format)
 	}
 	if len(s.Sections) > 0 {
 		sections := printer.ColorString(strings.Join(s.Sections, ", "), printer.AnsiCyan, useColor)
-		fmt.Fprintf(w, "  Sections scanned:  %s\n", sections)
+		p.Fprintf(w, "  Sections scanned:  %s\n", sections)
 	}
 	if s.BinaryFormat != "" || len(s.Sections) > 0 {
 		fmt.Fprintln(w)
@@ -204,34 +341,56 @@ func (s *Statistics) Format(w io.Writer, colorMode extractor.ColorMode) {
 	// Count statistics
 	if s.UnfilteredCount > 0 {
 		// Show filter statistics
-		unfilteredNum := printer.ColorString(formatNumber(s.UnfilteredCount), printer.AnsiYellow, useColor)
-		fmt.Fprintf(w, "  Total strings extracted:  %s\n", unfilteredNum)
+		unfilteredNum := printer.ColorString(formatNumber(p, s.UnfilteredCount), printer.AnsiYellow, useColor)
+		p.Fprintf(w, "  Total strings extracted:  %s\n", unfilteredNum)
 
-		filteredNum := printer.ColorString(formatNumber(s.FilteredCount), printer.AnsiYellow, useColor)
-		pct := printer.ColorString(fmt.Sprintf("%.1f%%", percentage(s.FilteredCount, s.UnfilteredCount)), printer.AnsiGreen, useColor)
-		fmt.Fprintf(w, "  Matched filters:          %s (%s)\n", filteredNum, pct)
+		filteredNum := printer.ColorString(formatNumber(p, s.FilteredCount), printer.AnsiYellow, useColor)
+		pct := printer.ColorString(p.Sprintf("%.1f%%", percentage(s.FilteredCount, s.UnfilteredCount)), printer.AnsiGreen, useColor)
+		p.Fprintf(w, "  Matched filters:          %s (%s)\n", filteredNum, pct)
 	} else {
 		// No filtering
-		totalNum := printer.ColorString(formatNumber(s.TotalStrings), printer.AnsiYellow, useColor)
-		fmt.Fprintf(w, "  Total strings:     %s\n", totalNum)
+		totalNum := printer.ColorString(formatNumber(p, s.TotalStrings), printer.AnsiYellow, useColor)
+		p.Fprintf(w, "  Total strings:     %s\n", totalNum)
 	}
 
-	bytesNum := printer.ColorString(formatNumber(int(s.TotalBytes)), printer.AnsiYellow, useColor)
-	fmt.Fprintf(w, "  Total bytes:       %s\n", bytesNum)
+	bytesNum := printer.ColorString(formatNumber(p, int(s.TotalBytes)), printer.AnsiYellow, useColor)
+	p.Fprintf(w, "  Total bytes:       %s\n", bytesNum)
+
+	minNum := printer.ColorString(formatNumber(p, s.MinLength), printer.AnsiYellow, useColor)
+	p.Fprintf(w, "  Min length:        %s (configured)\n", minNum)
+
+	maxNum := printer.ColorString(formatNumber(p, s.MaxLength), printer.AnsiYellow, useColor)
+	p.Fprintf(w, "  Max length:        %s\n", maxNum)
 
-	minNum := printer.ColorString(fmt.Sprintf("%d", s.MinLength), printer.AnsiYellow, useColor)
-	fmt.Fprintf(w, "  Min length:        %s (configured)\n", minNum)
+	avgNum := printer.ColorString(p.Sprintf("%.1f", s.AvgLength()), printer.AnsiYellow, useColor)
+	p.Fprintf(w, "  Avg length:        %s\n", avgNum)
 
-	maxNum := printer.ColorString(fmt.Sprintf("%d", s.MaxLength), printer.AnsiYellow, useColor)
-	fmt.Fprintf(w, "  Max length:        %s\n", maxNum)
+	if s.Retries > 0 {
+		retries := printer.ColorString(formatNumber(p, int(s.Retries)), printer.AnsiYellow, useColor)
+		p.Fprintf(w, "  Read retries:      %s\n", retries)
+	}
 
-	avgNum := printer.ColorString(fmt.Sprintf("%.1f", s.AvgLength()), printer.AnsiYellow, useColor)
-	fmt.Fprintf(w, "  Avg length:        %s\n", avgNum)
+	// Similarity hash of the strings (listed per file for several files)
+	hashes := s.StringHashes()
+	if len(hashes) == 1 && len(s.stringSets) == 1 {
+		hash := printer.ColorString(hashes[0].Hash, printer.AnsiDim, useColor)
+		p.Fprintf(w, "  String hash:       %s\n", hash)
+	}
 	fmt.Fprintln(w)
 
+	if len(s.stringSets) > 1 && len(hashes) > 0 {
+		header := printer.ColorString(p.Sprintf("String hashes:"), printer.AnsiBold+printer.AnsiCyan, useColor)
+		fmt.Fprintf(w, "  %s\n", header)
+		for _, fh := range hashes {
+			hash := printer.ColorString(fh.Hash, printer.AnsiDim, useColor)
+			fmt.Fprintf(w, "    %s: %s\n", fh.File, hash)
+		}
+		fmt.Fprintln(w)
+	}
+
 	// Encoding distribution
 	if len(s.EncodingCounts) > 0 {
-		header := printer.ColorString("Encoding distribution:", printer.AnsiBold+printer.AnsiCyan, useColor)
+		header := printer.ColorString(p.Sprintf("Encoding distribution:"), printer.AnsiBold+printer.AnsiCyan, useColor)
 		fmt.Fprintf(w, "  %s\n", header)
 
 		// Sort encoding types for consistent output
@@ -244,8 +403,8 @@ func (s *Statistics) Format(w io.Writer, colorMode extractor.ColorMode) {
 		for _, enc := range encodings {
 			count := s.EncodingCounts[enc]
 			encName := printer.ColorString(formatEncodingName(enc)+":", printer.AnsiMagenta, useColor)
-			countNum := printer.ColorString(formatNumber(count), printer.AnsiYellow, useColor)
-			pct := printer.ColorString(fmt.Sprintf("%5.1f%%", percentage(count, s.TotalStrings)), printer.AnsiGreen, useColor)
+			countNum := printer.ColorString(formatNumber(p, count), printer.AnsiYellow, useColor)
+			pct := printer.ColorString(p.Sprintf("%5.1f%%", percentage(count, s.TotalStrings)), printer.AnsiGreen, useColor)
 			fmt.Fprintf(w, "    %-15s %6s (%s)\n", encName, countNum, pct)
 		}
 		fmt.Fprintln(w)
@@ -253,24 +412,39 @@ func (s *Statistics) Format(w io.Writer, colorMode extractor.ColorMode) {
 
 	// Length distribution
 	if len(s.LengthBuckets) > 0 {
-		header := printer.ColorString("Length distribution:", printer.AnsiBold+printer.AnsiCyan, useColor)
+		header := printer.ColorString(p.Sprintf("Length distribution:"), printer.AnsiBold+printer.AnsiCyan, useColor)
 		fmt.Fprintf(w, "  %s\n", header)
 
 		// Fixed bucket order
 		buckets := []string{"4-10", "11-50", "51-100", "100+"}
 		for _, bucket := range buckets {
 			if count, ok := s.LengthBuckets[bucket]; ok {
-				countNum := printer.ColorString(formatNumber(count), printer.AnsiYellow, useColor)
-				pct := printer.ColorString(fmt.Sprintf("%5.1f%%", percentage(count, s.TotalStrings)), printer.AnsiGreen, useColor)
-				fmt.Fprintf(w, "    %s chars:    %6s (%s)\n", bucket, countNum, pct)
+				countNum := printer.ColorString(formatNumber(p, count), printer.AnsiYellow, useColor)
+				pct := printer.ColorString(p.Sprintf("%5.1f%%", percentage(count, s.TotalStrings)), printer.AnsiGreen, useColor)
+				p.Fprintf(w, "    %s chars:    %6s (%s)\n", bucket, countNum, pct)
 			}
 		}
 		fmt.Fprintln(w)
 	}
 
+	// Length percentiles and histogram
+	if len(s.LengthCounts) > 0 {
+		s.formatLengths(w, p, useColor)
+	}
+
+	// Kinds of strings and character classes
+	if len(s.Kinds) > 0 {
+		s.formatComposition(w, p, useColor)
+	}
+
+	// Match pattern hits
+	if len(s.PatternHits) > 0 {
+		s.formatPatterns(w, p, useColor)
+	}
+
 	// Longest strings
 	if len(s.LongestStrings) > 0 {
-		header := printer.ColorString("Longest strings:", printer.AnsiBold+printer.AnsiCyan, useColor)
+		header := printer.ColorString(p.Sprintf("Longest strings:"), printer.AnsiBold+printer.AnsiCyan, useColor)
 		fmt.Fprintf(w, "  %s\n", header)
 
 		for _, ls := range s.LongestStrings {
@@ -278,30 +452,50 @@ func (s *Statistics) Format(w io.Writer, colorMode extractor.ColorMode) {
 			if len(preview) > 50 {
 				preview = preview[:47] + "..."
 			}
-			lengthNum := printer.ColorString(fmt.Sprintf("%d", ls.Length), printer.AnsiYellow, useColor)
+			lengthNum := printer.ColorString(formatNumber(p, ls.Length), printer.AnsiYellow, useColor)
 			offsetNum := printer.ColorString(fmt.Sprintf("0x%x", ls.Offset), printer.AnsiYellow, useColor)
 			previewStr := printer.ColorString(fmt.Sprintf("%q", preview), printer.AnsiDim, useColor)
-			fmt.Fprintf(w, "    %s chars at %s: %s\n", lengthNum, offsetNum, previewStr)
+			p.Fprintf(w, "    %s chars at %s: %s\n", lengthNum, offsetNum, previewStr)
 		}
 	}
-}
 
-// formatNumber adds thousand separators to numbers
-func formatNumber(n int) string {
-	if n < 1000 {
-		return fmt.Sprintf("%d", n)
+	// Detected libraries
+	if len(s.Libraries) > 0 {
+		if len(s.LongestStrings) > 0 {
+			fmt.Fprintln(w)
+		}
+		header := printer.ColorString(p.Sprintf("Detected libraries:"), printer.AnsiBold+printer.AnsiCyan, useColor)
+		fmt.Fprintf(w, "  %s\n", header)
+
+		for _, lib := range s.Libraries {
+			name := printer.ColorString(strings.TrimSpace(lib.Name+" "+lib.Version), printer.AnsiMagenta, useColor)
+			offset := printer.ColorString(fmt.Sprintf("0x%x", lib.Offset), printer.AnsiYellow, useColor)
+			location := p.Sprintf("at %s", offset)
+			// Aggregated statistics cover several files
+			if s.Filename == "" && lib.File != "" {
+				location = p.Sprintf("in %s at %s", lib.File, offset)
+			}
+			preview := lib.Evidence
+			if len(preview) > 50 {
+				preview = preview[:47] + "..."
+			}
+			previewStr := printer.ColorString(fmt.Sprintf("%q", preview), printer.AnsiDim, useColor)
+			fmt.Fprintf(w, "    %s (%s) %s: %s\n", name, lib.Kind, location, previewStr)
+		}
 	}
 
-	// Convert to string and add commas
-	str := fmt.Sprintf("%d", n)
-	var result []byte
-	for i, digit := range str {
-		if i > 0 && (len(str)-i)%3 == 0 {
-			result = append(result, ',')
+	// Performance accounting
+	if s.Timing != nil {
+		if len(s.LongestStrings) > 0 || len(s.Libraries) > 0 {
+			fmt.Fprintln(w)
 		}
-		result = append(result, byte(digit))
+		s.formatTiming(w, p, useColor)
 	}
-	return string(result)
+}
+
+// formatNumber adds the thousand separators of the printer's locale to numbers
+func formatNumber(p *message.Printer, n int) string {
+	return p.Sprintf("%d", n)
 }
 
 // percentage calculates percentage with 1 decimal place
@@ -312,6 +506,12 @@ func percentage(part, total int) float64 {
 	return float64(part) * 100.0 / float64(total)
 }
 
+// roundFixed rounds averages and percentages to two decimals for JSON, so
+// they do not vary in the last digits with the order values were summed in
+func roundFixed(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
 // formatEncodingName converts internal encoding names to display names
 func formatEncodingName(enc string) string {
 	switch enc {
@@ -330,14 +530,15 @@ func formatEncodingName(enc string) string {
 	}
 }
 
-// ToJSON converts statistics to JSON format
+// ToJSON converts statistics to JSON format. Keys are sorted and numbers
+// rounded, so the same scan gives the same bytes.
 func (s *Statistics) ToJSON() ([]byte, error) {
 	output := map[string]any{
 		"total_strings": s.TotalStrings,
 		"total_bytes":   s.TotalBytes,
 		"min_length":    s.MinLength,
 		"max_length":    s.MaxLength,
-		"avg_length":    s.AvgLength(),
+		"avg_length":    roundFixed(s.AvgLength()),
 	}
 
 	// Add file info if available
@@ -355,7 +556,11 @@ func (s *Statistics) ToJSON() ([]byte, error) {
 	if s.UnfilteredCount > 0 {
 		output["unfiltered_count"] = s.UnfilteredCount
 		output["filtered_count"] = s.FilteredCount
-		output["filter_percentage"] = percentage(s.FilteredCount, s.UnfilteredCount)
+		output["filter_percentage"] = roundFixed(percentage(s.FilteredCount, s.UnfilteredCount))
+	}
+
+	if s.Retries > 0 {
+		output["retries"] = s.Retries
 	}
 
 	// Add distributions
@@ -365,6 +570,18 @@ func (s *Statistics) ToJSON() ([]byte, error) {
 	if len(s.LengthBuckets) > 0 {
 		output["length_distribution"] = s.LengthBuckets
 	}
+	if len(s.LengthCounts) > 0 {
+		output["length_percentiles"], output["length_counts"] = s.lengthsJSON()
+	}
+	if len(s.Kinds) > 0 {
+		output["string_composition"] = s.Kinds
+		output["character_classes"] = s.CharClasses
+	}
+
+	// Add match pattern hits
+	if len(s.PatternHits) > 0 {
+		output["pattern_matches"] = s.patternsJSON()
+	}
 
 	// Add longest strings
 	if len(s.LongestStrings) > 0 {
@@ -384,6 +601,43 @@ func (s *Statistics) ToJSON() ([]byte, error) {
 		output["longest_strings"] = longest
 	}
 
+	// Add similarity hashes (per file when several files were scanned)
+	if hashes := s.StringHashes(); len(hashes) == 1 && len(s.stringSets) == 1 {
+		output["string_hash"] = hashes[0].Hash
+	} else if len(hashes) > 0 {
+		byFile := make(map[string]string, len(hashes))
+		for _, fh := range hashes {
+			byFile[fh.File] = fh.Hash
+		}
+		output["string_hashes"] = byFile
+	}
+
+	// Add detected libraries
+	if len(s.Libraries) > 0 {
+		libraries := make([]map[string]any, len(s.Libraries))
+		for i, lib := range s.Libraries {
+			entry := map[string]any{
+				"name":       lib.Name,
+				"kind":       lib.Kind,
+				"version":    lib.Version,
+				"cpe":        lib.CPE,
+				"offset":     lib.Offset,
+				"offset_hex": fmt.Sprintf("0x%x", lib.Offset),
+				"evidence":   lib.Evidence,
+			}
+			if lib.File != "" {
+				entry["file"] = lib.File
+			}
+			libraries[i] = entry
+		}
+		output["libraries"] = libraries
+	}
+
+	// Add performance accounting
+	if s.Timing != nil {
+		output["timing"] = s.timingJSON()
+	}
+
 	return json.MarshalIndent(output, "", "  ")
 }
 
@@ -408,13 +662,39 @@ func (s *Statistics) Merge(other *Statistics) {
 	for bucket, count := range other.LengthBuckets {
 		s.LengthBuckets[bucket] += count
 	}
+	for length, count := range other.LengthCounts {
+		s.LengthCounts[length] += count
+	}
+	for kind, count := range other.Kinds {
+		s.Kinds[kind] += count
+	}
+	for class, count := range other.CharClasses {
+		s.CharClasses[class] += count
+	}
+
+	// Merge match pattern hits
+	s.mergePatterns(other)
 
 	// Merge longest strings
 	s.LongestStrings = append(s.LongestStrings, other.LongestStrings...)
-	sort.Slice(s.LongestStrings, func(i, j int) bool {
-		return s.LongestStrings[i].Length > s.LongestStrings[j].Length
-	})
-	if len(s.LongestStrings) > 5 {
-		s.LongestStrings = s.LongestStrings[:5]
+	s.trimLongest()
+
+	// Merge string sets per file
+	for file, set := range other.stringSets {
+		s.stringSet(file).Merge(set)
+	}
+
+	// Merge detected libraries, ordered by file and offset
+	for _, lib := range other.Libraries {
+		s.addLibrary(lib)
 	}
+	sort.SliceStable(s.Libraries, func(i, j int) bool {
+		if s.Libraries[i].File != s.Libraries[j].File {
+			return s.Libraries[i].File < s.Libraries[j].File
+		}
+		return s.Libraries[i].Offset < s.Libraries[j].Offset
+	})
+
+	// Merge performance accounting
+	s.mergeTiming(other)
 }
@@ -3,10 +3,14 @@ package stats
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"strings"
 	"testing"
 
 	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/fuzzyhash"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
 )
 
 func TestNew(t *testing.T) {
@@ -297,22 +301,25 @@ func TestFormatWithFiltering(t *testing.T) {
 
 func TestFormatNumber(t *testing.T) {
 	tests := []struct {
-		input int
-		want  string
+		locale language.Tag
+		input  int
+		want   string
 	}{
-		{0, "0"},
-		{42, "42"},
-		{999, "999"},
-		{1000, "1,000"},
-		{1234, "1,234"},
-		{12345, "12,345"},
-		{123456, "123,456"},
-		{1234567, "1,234,567"},
+		{language.English, 0, "0"},
+		{language.English, 42, "42"},
+		{language.English, 999, "999"},
+		{language.English, 1000, "1,000"},
+		{language.English, 1234, "1,234"},
+		{language.English, 12345, "12,345"},
+		{language.English, 123456, "123,456"},
+		{language.English, 1234567, "1,234,567"},
+		{language.German, 1234567, "1.234.567"},
+		{language.MustParse("de-CH"), 1234567, "1’234’567"},
 	}
 
 	for _, tt := range tests {
-		t.Run(tt.want, func(t *testing.T) {
-			got := formatNumber(tt.input)
+		t.Run(tt.locale.String()+"/"+tt.want, func(t *testing.T) {
+			got := formatNumber(message.NewPrinter(tt.locale), tt.input)
 			if got != tt.want {
 				t.Errorf("formatNumber(%d) = %q, want %q", tt.input, got, tt.want)
 			}
@@ -450,6 +457,177 @@ func TestMerge(t *testing.T) {
 	}
 }
 
+// TestToJSONDeterministic tests that merging the same statistics in any
+// order gives the same JSON, with averages and percentages rounded
+func TestToJSONDeterministic(t *testing.T) {
+	config := extractor.Config{Encoding: "s"}
+	parts := func() []*Statistics {
+		var parts []*Statistics
+		for i, str := range []string{"aaaaaaaa", "bbbbbbbb", "cccccccc", "dd", "eeee", "ffffffff", "gggggggg"} {
+			part := New(2)
+			part.AddUnfiltered()
+			part.AddUnfiltered()
+			part.AddUnfiltered()
+			part.Add([]byte(str), "", int64(i%2)*0x100, config)
+			parts = append(parts, part)
+		}
+		return parts
+	}
+
+	merged := func(order []int) []byte {
+		all := New(2)
+		p := parts()
+		for _, i := range order {
+			all.Merge(p[i])
+		}
+		out, err := all.ToJSON()
+		if err != nil {
+			t.Fatalf("ToJSON() error = %v", err)
+		}
+		return out
+	}
+
+	forward := merged([]int{0, 1, 2, 3, 4, 5, 6})
+	backward := merged([]int{6, 5, 4, 3, 2, 1, 0})
+	if !bytes.Equal(forward, backward) {
+		t.Errorf("ToJSON() depends on merge order:\n%s\nvs\n%s", forward, backward)
+	}
+
+	var output map[string]any
+	if err := json.Unmarshal(forward, &output); err != nil {
+		t.Fatalf("JSON unmarshal error = %v", err)
+	}
+	if got := output["avg_length"]; got != 6.57 {
+		t.Errorf("avg_length = %v, want 6.57", got)
+	}
+	if got := output["filter_percentage"]; got != 33.33 {
+		t.Errorf("filter_percentage = %v, want 33.33", got)
+	}
+}
+
+// TestDetectedLibraries tests library detection, deduplication and merging
+func TestDetectedLibraries(t *testing.T) {
+	config := extractor.Config{Encoding: "s"}
+
+	s1 := New(4)
+	s1.SetFileInfo("libcrypto.so", "", nil)
+	s1.Add([]byte("OpenSSL 3.0.17 1 Jul 2025"), "libcrypto.so", 0x2000, config)
+	s1.Add([]byte("OpenSSL 3.0.17 1 Jul 2025"), "libcrypto.so", 0x3000, config)
+	s1.Add([]byte("not a banner"), "libcrypto.so", 0x4000, config)
+
+	if len(s1.Libraries) != 1 {
+		t.Fatalf("len(Libraries) = %d, want 1", len(s1.Libraries))
+	}
+	lib := s1.Libraries[0]
+	if lib.Name != "OpenSSL" || lib.Version != "3.0.17" || lib.Offset != 0x2000 || lib.File != "libcrypto.so" {
+		t.Errorf("Libraries[0] = %+v", lib)
+	}
+
+	var buf bytes.Buffer
+	s1.Format(&buf, extractor.ColorNever)
+	if !strings.Contains(buf.String(), "Detected libraries:") || !strings.Contains(buf.String(), "OpenSSL 3.0.17 (library) at 0x2000") {
+		t.Errorf("Format() missing detected library:\n%s", buf.String())
+	}
+
+	data, err := s1.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	var output map[string]any
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatalf("ToJSON() invalid JSON: %v", err)
+	}
+	libraries, ok := output["libraries"].([]any)
+	if !ok || len(libraries) != 1 || libraries[0].(map[string]any)["version"] != "3.0.17" {
+		t.Errorf("ToJSON() libraries = %v", output["libraries"])
+	}
+
+	// Aggregated statistics list libraries per file
+	s2 := New(4)
+	s2.Add([]byte(" deflate 1.2.13 Copyright 1995-2022 Jean-loup Gailly and Mark Adler "), "app", 0x100, config)
+	s2.Add([]byte("OpenSSL 3.0.17 1 Jul 2025"), "app", 0x200, config)
+
+	aggregated := New(4)
+	aggregated.Merge(s1)
+	aggregated.Merge(s2)
+	if len(aggregated.Libraries) != 3 {
+		t.Fatalf("after merge, len(Libraries) = %d, want 3", len(aggregated.Libraries))
+	}
+	if aggregated.Libraries[0].File != "app" || aggregated.Libraries[0].Name != "zlib" {
+		t.Errorf("after merge, Libraries[0] = %+v, want zlib in app", aggregated.Libraries[0])
+	}
+
+	buf.Reset()
+	aggregated.Format(&buf, extractor.ColorNever)
+	if !strings.Contains(buf.String(), "zlib 1.2.13 (library) in app at 0x100") {
+		t.Errorf("aggregated Format() missing file name:\n%s", buf.String())
+	}
+}
+
+// TestStringHashes tests per-file similarity hashes of the string sets
+func TestStringHashes(t *testing.T) {
+	config := extractor.Config{Encoding: "s"}
+	addStrings := func(s *Statistics, filename string, n int) {
+		for i := range n {
+			s.Add(fmt.Appendf(nil, "string %d", i), filename, int64(i), config)
+		}
+	}
+
+	// Too few strings for a hash
+	s := New(4)
+	addStrings(s, "small.bin", fuzzyhash.MinStrings-1)
+	if hashes := s.StringHashes(); len(hashes) != 0 {
+		t.Errorf("StringHashes() = %v, want none", hashes)
+	}
+
+	single := New(4)
+	addStrings(single, "a.bin", 100)
+	hashes := single.StringHashes()
+	if len(hashes) != 1 || hashes[0].File != "a.bin" || hashes[0].Hash == "" {
+		t.Fatalf("StringHashes() = %v, want one hash for a.bin", hashes)
+	}
+
+	var buf bytes.Buffer
+	single.Format(&buf, extractor.ColorNever)
+	if !strings.Contains(buf.String(), "String hash:       "+hashes[0].Hash) {
+		t.Errorf("Format() missing string hash:\n%s", buf.String())
+	}
+	data, err := single.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+	var output map[string]any
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatalf("ToJSON() invalid JSON: %v", err)
+	}
+	if output["string_hash"] != hashes[0].Hash {
+		t.Errorf("ToJSON() string_hash = %v, want %s", output["string_hash"], hashes[0].Hash)
+	}
+
+	// Aggregated statistics keep a hash per file
+	other := New(4)
+	addStrings(other, "b.bin", 200)
+	single.Merge(other)
+	hashes = single.StringHashes()
+	if len(hashes) != 2 || hashes[0].File != "a.bin" || hashes[1].File != "b.bin" {
+		t.Fatalf("after merge, StringHashes() = %v, want a.bin and b.bin", hashes)
+	}
+
+	buf.Reset()
+	single.Format(&buf, extractor.ColorNever)
+	if !strings.Contains(buf.String(), "String hashes:") || !strings.Contains(buf.String(), "b.bin: "+hashes[1].Hash) {
+		t.Errorf("aggregated Format() missing per-file hashes:\n%s", buf.String())
+	}
+	data, _ = single.ToJSON()
+	output = nil
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatalf("ToJSON() invalid JSON: %v", err)
+	}
+	if byFile, ok := output["string_hashes"].(map[string]any); !ok || byFile["a.bin"] != hashes[0].Hash {
+		t.Errorf("ToJSON() string_hashes = %v", output["string_hashes"])
+	}
+}
+
 func TestEncodingDistribution(t *testing.T) {
 	s := New(4)
 
@@ -495,6 +673,128 @@ func TestLengthBuckets(t *testing.T) {
 	}
 }
 
+// TestLengthPercentiles tests the length percentiles, histogram and their
+// JSON, before and after merging
+func TestLengthPercentiles(t *testing.T) {
+	config := extractor.Config{Encoding: "s"}
+	s, other := New(4), New(4)
+	for i := range 90 {
+		s.Add([]byte(strings.Repeat("a", 4+i%4)), "a.bin", int64(i), config) // 4-7
+	}
+	for i := range 9 {
+		other.Add([]byte(strings.Repeat("b", 20)), "b.bin", int64(i), config)
+	}
+	other.Add([]byte(strings.Repeat("c", 300)), "b.bin", 100, config)
+	s.Merge(other)
+
+	for percent, want := range map[int]int{50: 6, 90: 7, 99: 20, 100: 300} {
+		if got := s.LengthPercentile(percent); got != want {
+			t.Errorf("LengthPercentile(%d) = %d, want %d", percent, got, want)
+		}
+	}
+	if got := New(4).LengthPercentile(50); got != 0 {
+		t.Errorf("LengthPercentile(50) without strings = %d, want 0", got)
+	}
+
+	var buf bytes.Buffer
+	s.Format(&buf, extractor.ColorNever)
+	output := buf.String()
+	for _, want := range []string{
+		"p50: 6   p90: 7   p99: 20\n",
+		"    4-7     ############################## 90\n",
+		"    16-31   ###                            9\n",
+		"    64-127                                 0\n",
+		"    256-511 #                              1\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Format() missing %q in:\n%s", want, output)
+		}
+	}
+
+	data, err := s.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Percentiles map[string]int `json:"length_percentiles"`
+		Counts      []LengthCount  `json:"length_counts"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Percentiles["p90"] != 7 || len(result.Counts) != 6 || result.Counts[5] != (LengthCount{Length: 300, Count: 1}) {
+		t.Errorf("JSON percentiles = %v, counts = %v", result.Percentiles, result.Counts)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		str  string
+		want string
+	}{
+		{"20260116", KindNumeric},
+		{"1.2.13", KindNumeric},
+		{"2024-01-30", KindNumeric},
+		{"d41d8cd98f00b204e9800998ecf8427e", KindHex},
+		{"0xDEADBEEF", KindHex},
+		{"face", KindIdentifier},
+		{"GetProcAddress", KindIdentifier},
+		{"_ZNSt6vectorIiSaIiEE9push_backEOi", KindIdentifier},
+		{"java.lang.String", KindIdentifier},
+		{"Unable to open the configuration file", KindSentence},
+		{"/usr/lib/libc.so.6", KindOther},
+		{"%s: %d", KindOther},
+		{"x@Y!z#", KindOther},
+	}
+
+	for _, tt := range tests {
+		if got := Classify([]byte(tt.str)); got != tt.want {
+			t.Errorf("Classify(%q) = %q, want %q", tt.str, got, tt.want)
+		}
+	}
+}
+
+// TestComposition tests the string kinds and character classes, before and
+// after merging, in text and JSON
+func TestComposition(t *testing.T) {
+	config := extractor.Config{Encoding: "s"}
+	s, other := New(4), New(4)
+	s.Add([]byte("GetProcAddress"), "a.bin", 0, config)
+	s.Add([]byte("Hello world"), "a.bin", 16, config)
+	other.Add([]byte("1234"), "b.bin", 0, config)
+	other.Add([]byte("é+1"), "b.bin", 8, config)
+	s.Merge(other)
+
+	wantKinds := map[string]int{KindIdentifier: 1, KindSentence: 1, KindNumeric: 1, KindOther: 1}
+	wantClasses := map[string]int{ClassLetter: 25, ClassDigit: 5, ClassWhitespace: 1, ClassPunctuation: 1}
+	if fmt.Sprint(s.Kinds) != fmt.Sprint(wantKinds) || fmt.Sprint(s.CharClasses) != fmt.Sprint(wantClasses) {
+		t.Errorf("Kinds = %v, CharClasses = %v, want %v and %v", s.Kinds, s.CharClasses, wantKinds, wantClasses)
+	}
+
+	var buf bytes.Buffer
+	s.Format(&buf, extractor.ColorNever)
+	for _, want := range []string{"    sentence:    1 ( 25.0%)\n", "    letters:     25 ( 78.1%)\n"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Format() missing %q in:\n%s", want, buf.String())
+		}
+	}
+
+	data, err := s.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		Composition map[string]int `json:"string_composition"`
+		Classes     map[string]int `json:"character_classes"`
+	}
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatal(err)
+	}
+	if result.Composition[KindNumeric] != 1 || result.Classes[ClassDigit] != 5 {
+		t.Errorf("JSON string_composition = %v, character_classes = %v", result.Composition, result.Classes)
+	}
+}
+
 // TestFormatWithColors tests colored output
 func TestFormatWithColors(t *testing.T) {
 	s := New(4)
@@ -617,3 +917,56 @@ func TestFormatColoredElements(t *testing.T) {
 		}
 	}
 }
+
+// TestPatternHits tests per-pattern hit counts, including patterns without hits
+func TestPatternHits(t *testing.T) {
+	patterns, err := extractor.CompilePatterns([]string{`@`, `https?://`, `zzz`}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := extractor.Config{Encoding: "s", MatchPatterns: patterns}
+
+	s := New(4)
+	s.Add([]byte("user@example.com"), "a.bin", 0, config)
+	s.Add([]byte("http://user@example.com"), "a.bin", 20, config)
+
+	other := New(4)
+	other.TrackPatterns(patterns)
+	other.Add([]byte("https://example.org"), "b.bin", 0, config)
+	s.Merge(other)
+
+	want := []PatternHit{{"@", 2}, {"https?://", 2}, {"zzz", 0}}
+	if len(s.PatternHits) != len(want) {
+		t.Fatalf("PatternHits = %+v, want %+v", s.PatternHits, want)
+	}
+	for i := range want {
+		if s.PatternHits[i] != want[i] {
+			t.Errorf("PatternHits[%d] = %+v, want %+v", i, s.PatternHits[i], want[i])
+		}
+	}
+
+	var buf bytes.Buffer
+	s.Format(&buf, extractor.ColorNever)
+	for _, line := range []string{"Pattern matches:", "    @               2 ( 66.7%)", "    zzz             0 (no matches)"} {
+		if !strings.Contains(buf.String(), line) {
+			t.Errorf("Format() missing %q:\n%s", line, buf.String())
+		}
+	}
+
+	data, err := s.ToJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var output struct {
+		PatternMatches []struct {
+			Pattern string `json:"pattern"`
+			Matches int    `json:"matches"`
+		} `json:"pattern_matches"`
+	}
+	if err := json.Unmarshal(data, &output); err != nil {
+		t.Fatal(err)
+	}
+	if len(output.PatternMatches) != 3 || output.PatternMatches[2].Pattern != "zzz" || output.PatternMatches[2].Matches != 0 {
+		t.Errorf("pattern_matches = %+v", output.PatternMatches)
+	}
+}
@@ -0,0 +1,222 @@
+package stats
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/printer"
+	"golang.org/x/text/message"
+)
+
+// Timing holds wall time, CPU time, I/O volume and per-stage timings for one
+// or more processed files
+type Timing struct {
+	Files     int
+	Wall      time.Duration // Time spent processing (summed across workers)
+	CPU       time.Duration // CPU time of the processing threads (Linux only)
+	BytesRead int64
+	Read      time.Duration // Reading files or loading sections
+	Filter    time.Duration // Applying match/exclude patterns
+	Output    time.Duration // Collecting strings into statistics
+}
+
+// NewFileTiming builds the timing for one processed file from its wall and CPU
+// time and the metrics collected during extraction
+func NewFileTiming(wall, cpu time.Duration, metrics *extractor.Metrics) Timing {
+	t := Timing{Files: 1, Wall: wall, CPU: cpu}
+	if metrics != nil {
+		t.BytesRead = metrics.BytesRead
+		t.Read = metrics.ReadTime
+		t.Filter = metrics.FilterTime
+		t.Output = metrics.OutputTime
+	}
+	return t
+}
+
+// Extract returns the time spent scanning for strings, i.e. the wall time not
+// attributed to the read, filter and output stages
+func (t Timing) Extract() time.Duration {
+	return max(t.Wall-t.Read-t.Filter-t.Output, 0)
+}
+
+// add accumulates other into t
+func (t *Timing) add(other Timing) {
+	t.Files += other.Files
+	t.Wall += other.Wall
+	t.CPU += other.CPU
+	t.BytesRead += other.BytesRead
+	t.Read += other.Read
+	t.Filter += other.Filter
+	t.Output += other.Output
+}
+
+// RecordTiming adds the timing of a processed file, attributed to the given
+// worker (0 for sequential processing)
+func (s *Statistics) RecordTiming(worker int, t Timing) {
+	if s.Timing == nil {
+		s.Timing = &Timing{}
+	}
+	s.Timing.add(t)
+
+	if s.WorkerTimings == nil {
+		s.WorkerTimings = make(map[int]*Timing)
+	}
+	if s.WorkerTimings[worker] == nil {
+		s.WorkerTimings[worker] = &Timing{}
+	}
+	s.WorkerTimings[worker].add(t)
+}
+
+// SetElapsed sets the real (wall clock) time of the whole run
+func (s *Statistics) SetElapsed(elapsed time.Duration) {
+	s.Elapsed = elapsed
+}
+
+// mergeTiming merges other's timing into s
+func (s *Statistics) mergeTiming(other *Statistics) {
+	if other.Timing == nil {
+		return
+	}
+	for worker, t := range other.WorkerTimings {
+		s.RecordTiming(worker, *t)
+	}
+}
+
+// formatTiming outputs the performance section
+//
+//nolint:errcheck // Writing to stdout/buffer, errors are not critical
+func (s *Statistics) formatTiming(w io.Writer, p *message.Printer, useColor bool) {
+	t := s.Timing
+
+	header := printer.ColorString(p.Sprintf("Performance:"), printer.AnsiBold+printer.AnsiCyan, useColor)
+	fmt.Fprintf(w, "  %s\n", header)
+
+	value := func(v string) string {
+		return printer.ColorString(v, printer.AnsiYellow, useColor)
+	}
+
+	p.Fprintf(w, "    Files processed:  %s\n", value(formatNumber(p, t.Files)))
+	if s.Elapsed > 0 {
+		p.Fprintf(w, "    Elapsed:          %s\n", value(formatDuration(s.Elapsed)))
+	}
+	p.Fprintf(w, "    Wall time:        %s\n", value(formatDuration(t.Wall)))
+	p.Fprintf(w, "    CPU time:         %s\n", value(formatCPU(t.CPU)))
+	p.Fprintf(w, "    Bytes read:       %s (%s)\n", value(formatNumber(p, int(t.BytesRead))), formatThroughput(t.BytesRead, t.Wall))
+
+	p.Fprintf(w, "    Stages:\n")
+	stages := []struct {
+		name string
+		d    time.Duration
+	}{
+		{"Read", t.Read},
+		{"Extract", t.Extract()},
+		{"Filter", t.Filter},
+		{"Output", t.Output},
+	}
+	for _, stage := range stages {
+		pct := printer.ColorString(p.Sprintf("%5.1f%%", durationPercentage(stage.d, t.Wall)), printer.AnsiGreen, useColor)
+		p.Fprintf(w, "      %-8s %s (%s)\n", p.Sprintf(stage.name)+":", value(fmt.Sprintf("%10s", formatDuration(stage.d))), pct)
+	}
+
+	// Per-worker breakdown for parallel runs
+	if len(s.WorkerTimings) > 1 {
+		p.Fprintf(w, "    Workers:\n")
+
+		workers := make([]int, 0, len(s.WorkerTimings))
+		for worker := range s.WorkerTimings {
+			workers = append(workers, worker)
+		}
+		sort.Ints(workers)
+
+		for _, worker := range workers {
+			wt := s.WorkerTimings[worker]
+			p.Fprintf(w, "      #%-3d %s files, wall %s, cpu %s, %s bytes\n", worker,
+				value(formatNumber(p, wt.Files)), value(formatDuration(wt.Wall)),
+				value(formatCPU(wt.CPU)), value(formatNumber(p, int(wt.BytesRead))))
+		}
+	}
+}
+
+// timingJSON converts timing to a JSON-friendly map (durations in milliseconds)
+func (s *Statistics) timingJSON() map[string]any {
+	t := s.Timing
+	output := map[string]any{
+		"files":      t.Files,
+		"wall_ms":    milliseconds(t.Wall),
+		"bytes_read": t.BytesRead,
+		"stages_ms": map[string]float64{
+			"read":    milliseconds(t.Read),
+			"extract": milliseconds(t.Extract()),
+			"filter":  milliseconds(t.Filter),
+			"output":  milliseconds(t.Output),
+		},
+	}
+	if threadCPUSupported {
+		output["cpu_ms"] = milliseconds(t.CPU)
+	}
+	if s.Elapsed > 0 {
+		output["elapsed_ms"] = milliseconds(s.Elapsed)
+	}
+
+	if len(s.WorkerTimings) > 1 {
+		workers := make(map[string]any, len(s.WorkerTimings))
+		for worker, wt := range s.WorkerTimings {
+			entry := map[string]any{
+				"files":      wt.Files,
+				"wall_ms":    milliseconds(wt.Wall),
+				"bytes_read": wt.BytesRead,
+			}
+			if threadCPUSupported {
+				entry["cpu_ms"] = milliseconds(wt.CPU)
+			}
+			workers[fmt.Sprintf("%d", worker)] = entry
+		}
+		output["workers"] = workers
+	}
+
+	return output
+}
+
+// formatDuration formats a duration rounded for display
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond).String()
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond).String()
+	default:
+		return d.Round(time.Microsecond).String()
+	}
+}
+
+// formatCPU formats CPU time, or "n/a" where per-thread accounting is unavailable
+func formatCPU(d time.Duration) string {
+	if !threadCPUSupported {
+		return "n/a"
+	}
+	return formatDuration(d)
+}
+
+// formatThroughput formats bytes processed per second of wall time
+func formatThroughput(bytes int64, wall time.Duration) string {
+	if wall <= 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.1f MB/s", float64(bytes)/wall.Seconds()/(1024*1024))
+}
+
+// durationPercentage calculates part as a percentage of total
+func durationPercentage(part, total time.Duration) float64 {
+	if total <= 0 {
+		return 0.0
+	}
+	return float64(part) * 100.0 / float64(total)
+}
+
+// milliseconds converts a duration to milliseconds, to the microsecond
+func milliseconds(d time.Duration) float64 {
+	return float64(d.Round(time.Microsecond)) / float64(time.Millisecond)
+}
@@ -0,0 +1,132 @@
+package stats
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+func TestNewFileTiming(t *testing.T) {
+	metrics := &extractor.Metrics{
+		BytesRead:  1024,
+		ReadTime:   2 * time.Millisecond,
+		FilterTime: 3 * time.Millisecond,
+		OutputTime: 4 * time.Millisecond,
+	}
+	timing := NewFileTiming(10*time.Millisecond, 8*time.Millisecond, metrics)
+
+	if timing.Files != 1 {
+		t.Errorf("Files = %d, want 1", timing.Files)
+	}
+	if timing.BytesRead != 1024 {
+		t.Errorf("BytesRead = %d, want 1024", timing.BytesRead)
+	}
+	if got := timing.Extract(); got != time.Millisecond {
+		t.Errorf("Extract() = %v, want 1ms", got)
+	}
+
+	// Stages exceeding wall time (timer granularity) must not go negative
+	timing.Wall = time.Millisecond
+	if got := timing.Extract(); got != 0 {
+		t.Errorf("Extract() = %v, want 0", got)
+	}
+
+	// Nil metrics only records wall and CPU time
+	timing = NewFileTiming(time.Second, 0, nil)
+	if timing.Wall != time.Second || timing.BytesRead != 0 {
+		t.Errorf("NewFileTiming(nil metrics) = %+v", timing)
+	}
+}
+
+func TestRecordTimingAndMerge(t *testing.T) {
+	s1 := New(4)
+	s1.RecordTiming(0, Timing{Files: 1, Wall: time.Millisecond, BytesRead: 100})
+	s1.RecordTiming(0, Timing{Files: 1, Wall: time.Millisecond, BytesRead: 50})
+
+	s2 := New(4)
+	s2.RecordTiming(1, Timing{Files: 1, Wall: 2 * time.Millisecond, BytesRead: 200})
+
+	s3 := New(4) // No timing recorded
+
+	aggregated := New(4)
+	aggregated.Merge(s1)
+	aggregated.Merge(s2)
+	aggregated.Merge(s3)
+
+	if aggregated.Timing == nil {
+		t.Fatal("Timing is nil after merge")
+	}
+	if aggregated.Timing.Files != 3 {
+		t.Errorf("Timing.Files = %d, want 3", aggregated.Timing.Files)
+	}
+	if aggregated.Timing.BytesRead != 350 {
+		t.Errorf("Timing.BytesRead = %d, want 350", aggregated.Timing.BytesRead)
+	}
+	if aggregated.Timing.Wall != 4*time.Millisecond {
+		t.Errorf("Timing.Wall = %v, want 4ms", aggregated.Timing.Wall)
+	}
+	if len(aggregated.WorkerTimings) != 2 {
+		t.Fatalf("len(WorkerTimings) = %d, want 2", len(aggregated.WorkerTimings))
+	}
+	if aggregated.WorkerTimings[0].Files != 2 || aggregated.WorkerTimings[1].Files != 1 {
+		t.Errorf("worker files = %d/%d, want 2/1", aggregated.WorkerTimings[0].Files, aggregated.WorkerTimings[1].Files)
+	}
+}
+
+func TestFormatTiming(t *testing.T) {
+	s := New(4)
+	s.Add([]byte("hello"), "", 0, extractor.Config{Encoding: "s"})
+
+	// No timing section unless timing was recorded
+	var buf bytes.Buffer
+	s.Format(&buf, extractor.ColorNever)
+	if strings.Contains(buf.String(), "Performance") {
+		t.Error("Format() includes Performance section without timing")
+	}
+
+	s.RecordTiming(0, Timing{Files: 1, Wall: time.Millisecond, BytesRead: 100})
+	s.RecordTiming(1, Timing{Files: 1, Wall: time.Millisecond, BytesRead: 100})
+	s.SetElapsed(time.Millisecond)
+
+	buf.Reset()
+	s.Format(&buf, extractor.ColorNever)
+	output := buf.String()
+	for _, want := range []string{"Performance:", "Files processed:", "Elapsed:", "Bytes read:", "Extract:", "Workers:", "#1"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("Format() output missing %q", want)
+		}
+	}
+}
+
+func TestToJSONTiming(t *testing.T) {
+	s := New(4)
+	s.RecordTiming(0, Timing{Files: 1, Wall: 1500 * time.Microsecond, BytesRead: 100})
+
+	data, err := s.ToJSON()
+	if err != nil {
+		t.Fatalf("ToJSON() error = %v", err)
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(data, &result); err != nil {
+		t.Fatalf("Failed to unmarshal JSON: %v", err)
+	}
+
+	timing, ok := result["timing"].(map[string]any)
+	if !ok {
+		t.Fatal("timing missing from JSON output")
+	}
+	if timing["wall_ms"] != 1.5 {
+		t.Errorf("wall_ms = %v, want 1.5", timing["wall_ms"])
+	}
+	if timing["bytes_read"] != float64(100) {
+		t.Errorf("bytes_read = %v, want 100", timing["bytes_read"])
+	}
+	if _, ok := timing["workers"]; ok {
+		t.Error("workers should be omitted for a single worker")
+	}
+}
@@ -0,0 +1,55 @@
+//go:build linux
+
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// networkFileSystems are the statfs magic numbers of network file systems
+var networkFileSystems = map[uint32]bool{
+	0x6969:     true, // NFS
+	0x517b:     true, // SMB
+	0xff534d42: true, // CIFS
+	0xfe534d42: true, // SMB2
+	0x00c36400: true, // Ceph
+	0x5346414f: true, // AFS
+	0x01021997: true, // 9P (also WSL drive mounts)
+}
+
+// detect classifies a file by its file system type and, for local block
+// devices, the rotational flag the kernel reports in sysfs
+func detect(path string) Profile {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err == nil && networkFileSystems[uint32(fs.Type)] {
+		return ProfileNet
+	}
+
+	var st syscall.Stat_t
+	if err := syscall.Stat(path, &st); err != nil {
+		return ProfileSSD
+	}
+	dev := uint64(st.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+
+	// Partitions have no queue of their own; it belongs to the parent disk
+	device, err := filepath.EvalSymlinks(fmt.Sprintf("/sys/dev/block/%d:%d", major, minor))
+	if err != nil {
+		return ProfileSSD
+	}
+	for _, dir := range []string{device, filepath.Dir(device)} {
+		data, err := os.ReadFile(filepath.Join(dir, "queue", "rotational"))
+		if err == nil {
+			if strings.TrimSpace(string(data)) == "1" {
+				return ProfileHDD
+			}
+			return ProfileSSD
+		}
+	}
+	return ProfileSSD
+}
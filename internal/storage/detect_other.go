@@ -0,0 +1,9 @@
+//go:build !linux
+
+package storage
+
+// detect reports ProfileSSD: storage detection is only implemented on Linux,
+// elsewhere --io-profile selects the profile
+func detect(string) Profile {
+	return ProfileSSD
+}
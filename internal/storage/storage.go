@@ -0,0 +1,71 @@
+// Package storage detects the kind of storage input files are read from, so
+// that parallelism and read patterns can be adapted: concurrent sequential
+// readers cause seek storms on spinning disks, and memory mapping files on
+// network file systems turns every page fault into a round trip.
+package storage
+
+import "path/filepath"
+
+// Profile is a storage type
+type Profile string
+
+// Storage profiles selectable with --io-profile
+const (
+	ProfileAuto Profile = "auto" // Detect from the input files
+	ProfileHDD  Profile = "hdd"  // Spinning disk
+	ProfileSSD  Profile = "ssd"  // Solid-state or otherwise seek-free storage
+	ProfileNet  Profile = "net"  // Network file system
+)
+
+// netWorkers caps the workers reading from a network file system, where more
+// concurrent readers only compete for the same link
+const netWorkers = 4
+
+// Workers returns the default number of parallel workers for the profile on a
+// machine with cpus CPUs
+func (p Profile) Workers(cpus int) int {
+	switch p {
+	case ProfileHDD:
+		return 1
+	case ProfileNet:
+		return min(cpus, netWorkers)
+	default:
+		return cpus
+	}
+}
+
+// DisableMmap reports whether files should be read with buffered I/O instead
+// of memory mapping
+func (p Profile) DisableMmap() bool {
+	return p == ProfileNet
+}
+
+// DetectFiles returns the profile of the storage the files are on. With files
+// on several kinds of storage, the most restrictive profile is returned (HDD
+// before network before SSD), as one slow device sets the pace of the run.
+// Each directory is probed once; files that cannot be probed are ignored.
+func DetectFiles(files []string) Profile {
+	result := ProfileSSD
+	probed := make(map[string]bool)
+	for _, file := range files {
+		dir := filepath.Dir(file)
+		if probed[dir] {
+			continue
+		}
+		probed[dir] = true
+
+		switch Detect(file) {
+		case ProfileHDD:
+			return ProfileHDD
+		case ProfileNet:
+			result = ProfileNet
+		}
+	}
+	return result
+}
+
+// Detect returns the profile of the storage a file is on, or ProfileSSD if it
+// cannot be determined
+func Detect(path string) Profile {
+	return detect(path)
+}
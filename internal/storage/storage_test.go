@@ -0,0 +1,45 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+// TestProfileDefaults tests the workers and read strategy of each profile
+func TestProfileDefaults(t *testing.T) {
+	tests := []struct {
+		profile     Profile
+		cpus        int
+		wantWorkers int
+		wantNoMmap  bool
+	}{
+		{ProfileHDD, 16, 1, false},
+		{ProfileSSD, 16, 16, false},
+		{ProfileNet, 16, 4, true},
+		{ProfileNet, 2, 2, true},
+	}
+	for _, tt := range tests {
+		if got := tt.profile.Workers(tt.cpus); got != tt.wantWorkers {
+			t.Errorf("%s.Workers(%d) = %d, want %d", tt.profile, tt.cpus, got, tt.wantWorkers)
+		}
+		if got := tt.profile.DisableMmap(); got != tt.wantNoMmap {
+			t.Errorf("%s.DisableMmap() = %v, want %v", tt.profile, got, tt.wantNoMmap)
+		}
+	}
+}
+
+// TestDetectFiles tests that detection yields a concrete profile
+func TestDetectFiles(t *testing.T) {
+	dir := t.TempDir()
+	if got := DetectFiles(nil); got != ProfileSSD {
+		t.Errorf("DetectFiles(nil) = %s, want ssd", got)
+	}
+	if got := Detect(filepath.Join(dir, "missing.bin")); got != ProfileSSD {
+		t.Errorf("Detect(missing) = %s, want ssd", got)
+	}
+	switch got := DetectFiles([]string{filepath.Join(dir, "a.bin"), dir}); got {
+	case ProfileHDD, ProfileSSD, ProfileNet:
+	default:
+		t.Errorf("DetectFiles() = %q, want hdd, ssd or net", got)
+	}
+}
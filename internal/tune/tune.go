@@ -0,0 +1,265 @@
+// Package tune measures a sample of a corpus for "txtr tune": how dense and
+// how readable its strings are in each encoding, which length separates text
+// from noise and how much noise common filters would drop. From these it
+// recommends the options of a scan of the whole corpus.
+package tune
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"time"
+	"unicode/utf8"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/noise"
+	"github.com/richardwooding/txtr/internal/rank"
+)
+
+// Lengths measured: strings from MinLength characters, those of MaxLength or
+// more counted together
+const (
+	MinLength = 3
+	MaxLength = 12
+)
+
+// Thresholds of the recommendations
+const (
+	readable     = 0.5  // rank.Confidence from which a string is readable
+	wideASCII    = 0.9  // Share of ASCII characters of a readable wide string
+	lengthShare  = 0.5  // Readable share of the strings of the recommended minimum length
+	alsoShare    = 0.05 // Readable strings of a wide encoding, relative to the primary, worth a second scan
+	eightBitGain = 0.1  // Extra readable strings of -e S over -e s worth the switch
+	filterShare  = 0.01 // Share of strings an --exclude pattern must drop
+	filterNoise  = 0.9  // Share of unreadable strings among those it drops
+	commonShare  = 0.05 // Share of strings --filter-common must drop
+)
+
+// Encodings are the encodings measured, as -e letters, narrow ones first
+var Encodings = []string{"s", "S", "l", "b", "L", "B"}
+
+// Filter is a filter whose hit rate is measured: --filter-common, or an
+// --exclude pattern
+type Filter struct {
+	Name    string
+	Pattern string // --exclude pattern ("" = --filter-common)
+	regexp  *regexp.Regexp
+}
+
+// Filters are the filters measured
+var Filters = []Filter{
+	{Name: "common strings"},
+	{Name: "hex digits", Pattern: `^[0-9A-Fa-f]+$`},
+	{Name: "no letters", Pattern: `^[^A-Za-z]+$`},
+	{Name: "punctuation runs", Pattern: `[^A-Za-z0-9\s]{4,}`},
+	{Name: "x86 register saves", Pattern: `(?:A[TUVW]){2}|A\\A\]A\^A_`},
+}
+
+func init() {
+	for i := range Filters {
+		if Filters[i].Pattern != "" {
+			Filters[i].regexp = regexp.MustCompile(Filters[i].Pattern)
+		}
+	}
+}
+
+// Lengths counts strings by length, from MinLength to MaxLength (or more)
+type Lengths [MaxLength - MinLength + 1]int
+
+// add counts a string of length n. 8-bit strings of MinLength bytes can
+// have fewer characters, and are counted as MinLength.
+func (l *Lengths) add(n int) {
+	l[min(max(n, MinLength), MaxLength)-MinLength]++
+}
+
+// From returns the number of strings of n or more characters
+func (l *Lengths) From(n int) int {
+	total := 0
+	for _, count := range l[max(n, MinLength)-MinLength:] {
+		total += count
+	}
+	return total
+}
+
+// Encoding holds the measurements of an encoding
+type Encoding struct {
+	Strings  Lengths   // Strings by length
+	Readable Lengths   // Readable strings by length
+	Hits     []Lengths // Strings dropped by each of Filters, by length
+	Noise    []Lengths // Unreadable strings dropped by each of Filters, by length
+}
+
+// Profile accumulates the measurements of the sampled data
+type Profile struct {
+	Files     int
+	Bytes     int64
+	ScanTime  time.Duration // Time taken to scan the data with -e s and the default options
+	Encodings map[string]*Encoding
+	common    *noise.Database
+}
+
+// NewProfile returns an empty profile. common is the database of
+// --filter-common (nil = the embedded one).
+func NewProfile(common *noise.Database) *Profile {
+	if common == nil {
+		common = noise.Embedded()
+	}
+	p := &Profile{Encodings: make(map[string]*Encoding, len(Encodings)), common: common}
+	for _, encoding := range Encodings {
+		p.Encodings[encoding] = &Encoding{Hits: make([]Lengths, len(Filters)), Noise: make([]Lengths, len(Filters))}
+	}
+	return p
+}
+
+// Add measures the data sampled from a file, in one or more chunks, in every
+// encoding
+func (p *Profile) Add(chunks ...[]byte) {
+	p.Files++
+	for _, data := range chunks {
+		p.Bytes += int64(len(data))
+
+		// Time a plain scan, as a scan of the corpus would run. Reading
+		// from memory cannot fail.
+		start := time.Now()
+		_ = extractor.ExtractStringsContext(context.Background(), bytes.NewReader(data), "", extractor.Config{MinLength: 4, Encoding: "s", NoBOMDetect: true}, func([]byte, string, int64, extractor.Config) {})
+		p.ScanTime += time.Since(start)
+
+		for _, encoding := range Encodings {
+			stats := p.Encodings[encoding]
+			wide := encoding != "s" && encoding != "S"
+			_ = extractor.ExtractStringsContext(context.Background(), bytes.NewReader(data), "", extractor.Config{MinLength: MinLength, Encoding: encoding, NoBOMDetect: true}, func(str []byte, _ string, _ int64, _ extractor.Config) {
+				p.addString(stats, str, wide)
+			})
+		}
+	}
+}
+
+// addString measures a string of an encoding. Wide strings are only readable
+// if mostly ASCII, as those of binaries are: narrow text read as UTF-16 turns
+// into CJK characters, which rank.Confidence takes for words.
+func (p *Profile) addString(stats *Encoding, str []byte, wide bool) {
+	n := utf8.RuneCount(str)
+	isReadable := rank.Confidence(str) >= readable
+	if isReadable && wide {
+		ascii := 0
+		for _, b := range str {
+			if b < utf8.RuneSelf {
+				ascii++
+			}
+		}
+		isReadable = float64(ascii) >= float64(n)*wideASCII
+	}
+	stats.Strings.add(n)
+	if isReadable {
+		stats.Readable.add(n)
+	}
+	for i, filter := range Filters {
+		hit := false
+		if filter.regexp != nil {
+			hit = filter.regexp.Match(str)
+		} else {
+			hit = p.common.Contains(str)
+		}
+		if !hit {
+			continue
+		}
+		stats.Hits[i].add(n)
+		if !isReadable {
+			stats.Noise[i].add(n)
+		}
+	}
+}
+
+// Recommendation is the options recommended for scanning the corpus
+type Recommendation struct {
+	MinLength    int
+	Encoding     string   // -e of the scan
+	Also         []string // Wide encodings worth a scan of their own
+	FilterCommon bool
+	Exclude      []string // --exclude patterns
+	Workers      int
+	Estimate     time.Duration // Estimated time of a scan of the corpus (0 = unknown)
+}
+
+// Recommend returns the options recommended for a corpus of files files and
+// bytes bytes, scanned on a machine with cpus CPUs
+func (p *Profile) Recommend(cpus, files int, bytes int64) Recommendation {
+	r := Recommendation{Encoding: "s", Workers: max(1, min(cpus, files))}
+
+	s, eight := p.Encodings["s"], p.Encodings["S"]
+	r.MinLength = recommendLength(s)
+	if primary := s.Readable.From(r.MinLength); float64(eight.Readable.From(r.MinLength)) > float64(primary)*(1+eightBitGain) {
+		r.Encoding = "S"
+		r.MinLength = recommendLength(eight)
+	}
+
+	primary := p.Encodings[r.Encoding]
+	readableStrings := primary.Readable.From(r.MinLength)
+	for _, encoding := range Encodings[2:] {
+		wide := p.Encodings[encoding].Readable.From(r.MinLength)
+		if wide > 0 && float64(wide) >= float64(readableStrings)*alsoShare {
+			r.Also = append(r.Also, encoding)
+		}
+	}
+
+	total := primary.Strings.From(r.MinLength)
+	for i, filter := range Filters {
+		if total == 0 {
+			break
+		}
+		hits := primary.Hits[i].From(r.MinLength)
+		share := float64(hits) / float64(total)
+		switch {
+		case filter.Pattern == "":
+			r.FilterCommon = share >= commonShare
+		case share >= filterShare && float64(primary.Noise[i].From(r.MinLength)) >= float64(hits)*filterNoise:
+			r.Exclude = append(r.Exclude, filter.Pattern)
+		}
+	}
+
+	if p.ScanTime > 0 && p.Bytes > 0 {
+		perWorker := float64(p.Bytes) / p.ScanTime.Seconds()
+		r.Estimate = time.Duration(float64(bytes) / (perWorker * float64(r.Workers)) * float64(time.Second))
+	}
+	return r
+}
+
+// recommendLength returns the shortest length whose strings are mostly
+// readable, else the length with the most readable share (4 if there are no
+// strings)
+func recommendLength(stats *Encoding) int {
+	best, bestShare := 4, -1.0
+	for n := MinLength; n <= MaxLength; n++ {
+		count := stats.Strings[n-MinLength]
+		if count == 0 {
+			continue
+		}
+		share := float64(stats.Readable[n-MinLength]) / float64(count)
+		if share >= lengthShare {
+			return n
+		}
+		if share > bestShare {
+			best, bestShare = n, share
+		}
+	}
+	return best
+}
+
+// Share returns the readable share of the strings of an encoding from n
+// characters (0 if there are none)
+func (e *Encoding) Share(n int) float64 {
+	if count := e.Strings.From(n); count > 0 {
+		return float64(e.Readable.From(n)) / float64(count)
+	}
+	return 0
+}
+
+// FilterShare returns the share of the strings of an encoding from n
+// characters dropped by Filters[i], and the unreadable share of those
+func (e *Encoding) FilterShare(i, n int) (dropped, unreadable float64) {
+	total, hits := e.Strings.From(n), e.Hits[i].From(n)
+	if total == 0 || hits == 0 {
+		return 0, 0
+	}
+	return float64(hits) / float64(total), float64(e.Noise[i].From(n)) / float64(hits)
+}
@@ -0,0 +1,92 @@
+package tune
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+	"unicode/utf16"
+)
+
+// utf16le encodes s as UTF-16LE
+func utf16le(s string) []byte {
+	var b []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		b = append(b, byte(u), byte(u>>8))
+	}
+	return b
+}
+
+// TestRecommend tests the recommendation for a sample of readable strings,
+// wide strings and hex noise
+func TestRecommend(t *testing.T) {
+	var data bytes.Buffer
+	for range 50 {
+		data.WriteString("\x00\x01Cannot open configuration file\x00\x02")
+		data.WriteString("\x03\x04zkq\x05xq\x06\x00\x00")
+		data.Write(utf16le("Settings were saved"))
+		data.WriteString("\x00\x00\x079f3a07c2e1b4\x08")
+	}
+
+	p := NewProfile(nil)
+	p.Add(data.Bytes()[:data.Len()/2], data.Bytes()[data.Len()/2:])
+	if p.Files != 1 || p.Bytes != int64(data.Len()) {
+		t.Errorf("Files, Bytes = %d, %d, want 1, %d", p.Files, p.Bytes, data.Len())
+	}
+
+	r := p.Recommend(8, 1000, 1<<30)
+	if r.Encoding != "s" {
+		t.Errorf("Encoding = %q, want s", r.Encoding)
+	}
+	if r.MinLength < 4 {
+		t.Errorf("MinLength = %d, want 4 or more (zkq is noise)", r.MinLength)
+	}
+	if !slices.Equal(r.Also, []string{"l"}) {
+		t.Errorf("Also = %q, want [l]", r.Also)
+	}
+	if !slices.Contains(r.Exclude, `^[0-9A-Fa-f]+$`) {
+		t.Errorf("Exclude = %q, want the hex digits pattern", r.Exclude)
+	}
+	if r.Workers != 8 {
+		t.Errorf("Workers = %d, want 8", r.Workers)
+	}
+	if r.Estimate <= 0 {
+		t.Errorf("Estimate = %v, want positive", r.Estimate)
+	}
+}
+
+// TestRecommendEmpty tests the recommendation without any strings
+func TestRecommendEmpty(t *testing.T) {
+	p := NewProfile(nil)
+	p.Add(make([]byte, 1024))
+	r := p.Recommend(4, 2, 2048)
+	if r.MinLength != 4 || r.Encoding != "s" || r.Also != nil || r.Exclude != nil || r.FilterCommon {
+		t.Errorf("Recommend() = %+v, want the defaults", r)
+	}
+	if r.Workers != 2 {
+		t.Errorf("Workers = %d, want 2 (one per file)", r.Workers)
+	}
+}
+
+// TestWideReadability tests that narrow text read as UTF-16 is not readable
+func TestWideReadability(t *testing.T) {
+	p := NewProfile(nil)
+	p.Add(bytes.Repeat([]byte("Narrow text read as wide characters. "), 20))
+	if got := p.Encodings["l"].Readable.From(MinLength); got != 0 {
+		t.Errorf("%d readable UTF-16LE strings in narrow text, want 0", got)
+	}
+	if got := p.Encodings["s"].Share(4); got < 0.9 {
+		t.Errorf("readable share of narrow text = %.2f, want 0.9 or more", got)
+	}
+}
+
+// TestLengths tests counting by length, with short and long strings
+// clamped to the range measured
+func TestLengths(t *testing.T) {
+	var l Lengths
+	for _, n := range []int{1, 3, 5, 12, 40} {
+		l.add(n)
+	}
+	if l.From(0) != 5 || l.From(4) != 3 || l.From(12) != 2 || l.From(13) != 0 {
+		t.Errorf("From() = %d, %d, %d, %d, want 5, 3, 2, 0", l.From(0), l.From(4), l.From(12), l.From(13))
+	}
+}
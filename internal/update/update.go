@@ -0,0 +1,437 @@
+// Package update implements self-updating of the txtr binary from GitHub
+// releases, verifying downloaded archives against the release checksums,
+// whose signature is checked with the release key built into txtr.
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/x509"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DefaultAPIURL is the GitHub API endpoint for the latest txtr release
+const DefaultAPIURL = "https://api.github.com/repos/richardwooding/txtr/releases/latest"
+
+// checksumsName is the name of the release checksum file (see .goreleaser.yaml)
+const checksumsName = "checksums.txt"
+
+// signatureName is the name of the Ed25519 signature of the checksum file
+// (see .goreleaser.yaml)
+const signatureName = checksumsName + ".sig"
+
+// releaseKeyPEM is the public key signing the checksums of releases, whose
+// private key only the release workflow holds
+//
+//go:embed release_key.pem
+var releaseKeyPEM []byte
+
+// tokenHosts are the hosts GITHUB_TOKEN is sent to: a mirror (--api-url) or
+// an asset URL of a tampered release must not receive it
+var tokenHosts = []string{"api.github.com", "github.com"}
+
+// maxDownloadSize limits the size of downloaded release assets
+const maxDownloadSize = 256 << 20
+
+// ErrNoAsset is returned when a release has no archive for the current platform
+var ErrNoAsset = errors.New("no release archive for this platform")
+
+// Release describes a GitHub release
+type Release struct {
+	TagName string  `json:"tag_name"`
+	Assets  []Asset `json:"assets"`
+}
+
+// Asset describes a file attached to a GitHub release
+type Asset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// Version returns the release version without the leading "v"
+func (r *Release) Version() string {
+	return strings.TrimPrefix(r.TagName, "v")
+}
+
+// Asset returns the asset with the given name, or nil if there is none
+func (r *Release) Asset(name string) *Asset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+// Platform identifies the operating system and architecture of a build
+type Platform struct {
+	OS   string // GOOS
+	Arch string // GOARCH
+	Arm  string // GOARM (only for arm)
+}
+
+// ArchiveName returns the release archive name for a version and platform,
+// following the goreleaser name template
+func ArchiveName(version string, platform Platform) string {
+	name := fmt.Sprintf("txtr_%s_%s_%s", version, platform.OS, platform.Arch)
+	if platform.Arch == "arm" && platform.Arm != "" {
+		name += "v" + platform.Arm
+	}
+	if platform.OS == "windows" {
+		return name + ".zip"
+	}
+	return name + ".tar.gz"
+}
+
+// BinaryName returns the name of the txtr executable inside a release archive
+func BinaryName(goos string) string {
+	if goos == "windows" {
+		return "txtr.exe"
+	}
+	return "txtr"
+}
+
+// Updater fetches releases and downloads assets
+type Updater struct {
+	APIURL string            // Latest release endpoint (DefaultAPIURL if empty)
+	Client *http.Client      // HTTP client (http.DefaultClient if nil)
+	Token  string            // Optional GitHub token to avoid API rate limits, sent to GitHub only
+	Key    ed25519.PublicKey // Key signing the release checksums (the release key of txtr if nil)
+}
+
+// LatestRelease fetches the latest release metadata
+func (u *Updater) LatestRelease(ctx context.Context) (*Release, error) {
+	apiURL := u.APIURL
+	if apiURL == "" {
+		apiURL = DefaultAPIURL
+	}
+
+	body, err := u.get(ctx, apiURL, "application/vnd.github+json")
+	if err != nil {
+		return nil, fmt.Errorf("error fetching latest release: %w", err)
+	}
+
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("error decoding release: %w", err)
+	}
+	if release.TagName == "" {
+		return nil, fmt.Errorf("error decoding release: missing tag name")
+	}
+	return &release, nil
+}
+
+// Download fetches the platform's archive from a release, verifies it against
+// the release checksums and their signature and returns the txtr executable
+// it contains. The checksums prove the archive intact, their signature that
+// the release was made by the txtr release workflow.
+func (u *Updater) Download(ctx context.Context, release *Release, platform Platform) ([]byte, error) {
+	archiveName := ArchiveName(release.Version(), platform)
+	archive := release.Asset(archiveName)
+	if archive == nil {
+		return nil, fmt.Errorf("%w (%s)", ErrNoAsset, archiveName)
+	}
+	checksums := release.Asset(checksumsName)
+	if checksums == nil {
+		return nil, fmt.Errorf("release %s has no %s, refusing to install unverified binary", release.TagName, checksumsName)
+	}
+
+	signature := release.Asset(signatureName)
+	if signature == nil {
+		return nil, fmt.Errorf("release %s has no %s, refusing to install unverified binary", release.TagName, signatureName)
+	}
+
+	sums, err := u.get(ctx, checksums.URL, "")
+	if err != nil {
+		return nil, fmt.Errorf("error downloading %s: %w", checksumsName, err)
+	}
+	sig, err := u.get(ctx, signature.URL, "")
+	if err != nil {
+		return nil, fmt.Errorf("error downloading %s: %w", signatureName, err)
+	}
+	key := u.Key
+	if key == nil {
+		if key, err = ReleaseKey(); err != nil {
+			return nil, err
+		}
+	}
+	if err := VerifySignature(sums, sig, key); err != nil {
+		return nil, err
+	}
+	data, err := u.get(ctx, archive.URL, "")
+	if err != nil {
+		return nil, fmt.Errorf("error downloading %s: %w", archiveName, err)
+	}
+
+	if err := VerifyChecksum(data, sums, archiveName); err != nil {
+		return nil, err
+	}
+
+	return ExtractBinary(data, archiveName, BinaryName(platform.OS))
+}
+
+// get performs a GET request and returns the response body
+func (u *Updater) get(ctx context.Context, url, accept string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if u.Token != "" && sendsToken(req.URL) {
+		req.Header.Set("Authorization", "Bearer "+u.Token)
+	}
+
+	client := u.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected HTTP status %s", resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxDownloadSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxDownloadSize {
+		return nil, fmt.Errorf("download exceeds %d bytes", maxDownloadSize)
+	}
+	return data, nil
+}
+
+// sendsToken reports whether the GitHub token may be sent to u: only to
+// GitHub over HTTPS. Redirects to other hosts (such as the storage serving
+// release assets) drop it.
+func sendsToken(u *url.URL) bool {
+	return u.Scheme == "https" && slices.Contains(tokenHosts, strings.ToLower(u.Hostname()))
+}
+
+// ReleaseKey returns the public key signing the checksums of txtr releases
+func ReleaseKey() (ed25519.PublicKey, error) {
+	block, _ := pem.Decode(releaseKeyPEM)
+	if block == nil {
+		return nil, errors.New("invalid release key")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid release key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, errors.New("invalid release key: not an Ed25519 key")
+	}
+	return edKey, nil
+}
+
+// VerifySignature checks the Ed25519 signature of a checksums file (the raw
+// 64 bytes of openssl pkeyutl -sign -rawin) against key
+func VerifySignature(checksums, signature []byte, key ed25519.PublicKey) error {
+	if len(signature) != ed25519.SignatureSize || !ed25519.Verify(key, checksums, signature) {
+		return fmt.Errorf("invalid signature of %s, refusing to install unverified binary", checksumsName)
+	}
+	return nil
+}
+
+// VerifyChecksum checks data against the SHA-256 checksum listed for name in a
+// goreleaser checksums file ("<hex>  <name>" per line)
+func VerifyChecksum(data, checksums []byte, name string) error {
+	scanner := bufio.NewScanner(bytes.NewReader(checksums))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 || strings.TrimPrefix(fields[1], "*") != name {
+			continue
+		}
+
+		want, err := hex.DecodeString(fields[0])
+		if err != nil || len(want) != sha256.Size {
+			return fmt.Errorf("invalid checksum for %s", name)
+		}
+		got := sha256.Sum256(data)
+		if !bytes.Equal(got[:], want) {
+			return fmt.Errorf("checksum mismatch for %s: got %x, want %x", name, got, want)
+		}
+		return nil
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return fmt.Errorf("no checksum for %s", name)
+}
+
+// ExtractBinary returns the contents of the file named binaryName from a
+// .tar.gz or .zip archive
+func ExtractBinary(archive []byte, archiveName, binaryName string) ([]byte, error) {
+	if strings.HasSuffix(archiveName, ".zip") {
+		return extractZip(archive, binaryName)
+	}
+	return extractTarGz(archive, binaryName)
+}
+
+// extractTarGz extracts a file from a gzip-compressed tar archive
+func extractTarGz(archive []byte, binaryName string) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == binaryName {
+			return io.ReadAll(io.LimitReader(tr, maxDownloadSize))
+		}
+	}
+	return nil, fmt.Errorf("%s not found in archive", binaryName)
+}
+
+// extractZip extracts a file from a zip archive
+func extractZip(archive []byte, binaryName string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("error reading archive: %w", err)
+	}
+	for _, file := range zr.File {
+		if file.FileInfo().IsDir() || filepath.Base(file.Name) != binaryName {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, fmt.Errorf("error reading archive: %w", err)
+		}
+		data, err := io.ReadAll(io.LimitReader(rc, maxDownloadSize))
+		_ = rc.Close()
+		return data, err
+	}
+	return nil, fmt.Errorf("%s not found in archive", binaryName)
+}
+
+// ReplaceExecutable atomically replaces the executable at path with data.
+// The new binary is written next to the old one and renamed over it, so an
+// interrupted update never leaves a partial binary in place. On Windows, where
+// a running executable cannot be overwritten, the old binary is first moved
+// aside to path + ".old".
+func ReplaceExecutable(path string, data []byte, goos string) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".txtr-update-*")
+	if err != nil {
+		return fmt.Errorf("error creating temporary file: %w", err)
+	}
+	tmpName := tmp.Name()
+	cleanup := func() {
+		_ = tmp.Close()
+		_ = os.Remove(tmpName)
+	}
+
+	if _, err := tmp.Write(data); err != nil {
+		cleanup()
+		return fmt.Errorf("error writing new binary: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		cleanup()
+		return fmt.Errorf("error writing new binary: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("error writing new binary: %w", err)
+	}
+	if err := os.Chmod(tmpName, info.Mode().Perm()); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("error setting permissions: %w", err)
+	}
+
+	if goos == "windows" {
+		old := path + ".old"
+		_ = os.Remove(old)
+		if err := os.Rename(path, old); err != nil {
+			_ = os.Remove(tmpName)
+			return fmt.Errorf("error moving old binary aside: %w", err)
+		}
+	}
+
+	if err := os.Rename(tmpName, path); err != nil {
+		_ = os.Remove(tmpName)
+		return fmt.Errorf("error replacing binary: %w", err)
+	}
+	return nil
+}
+
+// IsNewer reports whether version latest is newer than current. Versions are
+// compared as dotted numbers with an optional "v" prefix; a pre-release suffix
+// ("-rc1") sorts before the release. Unparseable current versions (e.g. "dev")
+// are never considered outdated.
+func IsNewer(latest, current string) bool {
+	l, lPre, ok := parseVersion(latest)
+	if !ok {
+		return false
+	}
+	c, cPre, ok := parseVersion(current)
+	if !ok {
+		return false
+	}
+
+	for i := range 3 {
+		if l[i] != c[i] {
+			return l[i] > c[i]
+		}
+	}
+	// Same version number: a release is newer than its pre-release
+	return lPre == "" && cPre != ""
+}
+
+// parseVersion parses "v1.2.3-suffix" into its numbers and suffix
+func parseVersion(version string) ([3]int, string, bool) {
+	var parts [3]int
+	version = strings.TrimPrefix(version, "v")
+	version, pre, _ := strings.Cut(version, "-")
+
+	fields := strings.Split(version, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, "", false
+	}
+	for i, field := range fields {
+		n, err := strconv.Atoi(field)
+		if err != nil || n < 0 {
+			return parts, "", false
+		}
+		parts[i] = n
+	}
+	return parts, pre, true
+}
@@ -0,0 +1,328 @@
+package update
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// makeTarGz builds a .tar.gz archive containing the given files
+func makeTarGz(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, data := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(data)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// makeZip builds a .zip archive containing the given files
+func makeZip(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// TestArchiveName tests archive names against the goreleaser name template
+func TestArchiveName(t *testing.T) {
+	tests := []struct {
+		platform Platform
+		want     string
+	}{
+		{Platform{OS: "linux", Arch: "amd64"}, "txtr_1.2.3_linux_amd64.tar.gz"},
+		{Platform{OS: "darwin", Arch: "arm64"}, "txtr_1.2.3_darwin_arm64.tar.gz"},
+		{Platform{OS: "linux", Arch: "arm", Arm: "7"}, "txtr_1.2.3_linux_armv7.tar.gz"},
+		{Platform{OS: "windows", Arch: "amd64"}, "txtr_1.2.3_windows_amd64.zip"},
+	}
+
+	for _, tt := range tests {
+		if got := ArchiveName("1.2.3", tt.platform); got != tt.want {
+			t.Errorf("ArchiveName(%+v) = %q, want %q", tt.platform, got, tt.want)
+		}
+	}
+}
+
+// TestIsNewer tests version comparison
+func TestIsNewer(t *testing.T) {
+	tests := []struct {
+		latest, current string
+		want            bool
+	}{
+		{"1.2.4", "1.2.3", true},
+		{"v2.0.0", "1.9.9", true},
+		{"1.10.0", "1.9.0", true},
+		{"1.2.3", "1.2.3", false},
+		{"1.2.3", "v1.2.3", false},
+		{"1.2.2", "1.2.3", false},
+		{"1.2.3", "1.2.3-rc1", true},
+		{"1.2.3-rc1", "1.2.3", false},
+		{"1.2.3", "dev", false},
+		{"garbage", "1.2.3", false},
+	}
+
+	for _, tt := range tests {
+		if got := IsNewer(tt.latest, tt.current); got != tt.want {
+			t.Errorf("IsNewer(%q, %q) = %v, want %v", tt.latest, tt.current, got, tt.want)
+		}
+	}
+}
+
+// TestVerifyChecksum tests checksum verification against a checksums file
+func TestVerifyChecksum(t *testing.T) {
+	data := []byte("archive contents")
+	sum := sha256.Sum256(data)
+	checksums := fmt.Appendf(nil, "%x  other.tar.gz\n%x  txtr.tar.gz\n", sha256.Sum256([]byte("x")), sum)
+
+	if err := VerifyChecksum(data, checksums, "txtr.tar.gz"); err != nil {
+		t.Errorf("VerifyChecksum() error = %v", err)
+	}
+	if err := VerifyChecksum([]byte("tampered"), checksums, "txtr.tar.gz"); err == nil || !strings.Contains(err.Error(), "mismatch") {
+		t.Errorf("VerifyChecksum(tampered) error = %v, want mismatch", err)
+	}
+	if err := VerifyChecksum(data, checksums, "missing.tar.gz"); err == nil {
+		t.Error("VerifyChecksum(missing entry) succeeded, want error")
+	}
+	if err := VerifyChecksum(data, []byte("zzzz  txtr.tar.gz\n"), "txtr.tar.gz"); err == nil {
+		t.Error("VerifyChecksum(invalid hex) succeeded, want error")
+	}
+}
+
+// TestExtractBinary tests extracting the executable from release archives
+func TestExtractBinary(t *testing.T) {
+	files := map[string][]byte{"README.md": []byte("readme"), "txtr": []byte("binary")}
+
+	got, err := ExtractBinary(makeTarGz(t, files), "txtr.tar.gz", "txtr")
+	if err != nil || string(got) != "binary" {
+		t.Errorf("ExtractBinary(tar.gz) = %q, %v", got, err)
+	}
+
+	got, err = ExtractBinary(makeZip(t, map[string][]byte{"txtr.exe": []byte("exe")}), "txtr.zip", "txtr.exe")
+	if err != nil || string(got) != "exe" {
+		t.Errorf("ExtractBinary(zip) = %q, %v", got, err)
+	}
+
+	if _, err := ExtractBinary(makeTarGz(t, map[string][]byte{"README.md": nil}), "txtr.tar.gz", "txtr"); err == nil {
+		t.Error("ExtractBinary() without binary succeeded, want error")
+	}
+}
+
+// TestReplaceExecutable tests that the binary is replaced and keeps its mode
+func TestReplaceExecutable(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "txtr")
+	if err := os.WriteFile(path, []byte("old"), 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ReplaceExecutable(path, []byte("new"), "linux"); err != nil {
+		t.Fatalf("ReplaceExecutable() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Errorf("binary contents = %q, %v, want %q", data, err, "new")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Mode().Perm() != 0o750 {
+		t.Errorf("binary mode = %v, want 0750", info.Mode().Perm())
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("directory has %d entries, want 1", len(entries))
+	}
+
+	// Windows moves the old binary aside
+	if err := ReplaceExecutable(path, []byte("newer"), "windows"); err != nil {
+		t.Fatalf("ReplaceExecutable(windows) error = %v", err)
+	}
+	if old, err := os.ReadFile(path + ".old"); err != nil || string(old) != "new" {
+		t.Errorf("old binary = %q, %v, want %q", old, err, "new")
+	}
+}
+
+// TestUpdaterDownload tests the release lookup and verified download flow
+// against a fake release server
+func TestUpdaterDownload(t *testing.T) {
+	platform := Platform{OS: "linux", Arch: "amd64"}
+	archiveName := ArchiveName("1.5.0", platform)
+	archive := makeTarGz(t, map[string][]byte{"txtr": []byte("new binary")})
+	checksums := fmt.Appendf(nil, "%x  %s\n", sha256.Sum256(archive), archiveName)
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signature := ed25519.Sign(private, checksums)
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/latest":
+			release := Release{
+				TagName: "v1.5.0",
+				Assets: []Asset{
+					{Name: archiveName, URL: server.URL + "/archive"},
+					{Name: "checksums.txt", URL: server.URL + "/checksums"},
+					{Name: "checksums.txt.sig", URL: server.URL + "/signature"},
+				},
+			}
+			_ = json.NewEncoder(w).Encode(release)
+		case "/archive":
+			_, _ = w.Write(archive)
+		case "/checksums":
+			_, _ = w.Write(checksums)
+		case "/signature":
+			_, _ = w.Write(signature)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	updater := &Updater{APIURL: server.URL + "/latest", Client: server.Client(), Key: public}
+	ctx := context.Background()
+
+	release, err := updater.LatestRelease(ctx)
+	if err != nil {
+		t.Fatalf("LatestRelease() error = %v", err)
+	}
+	if release.Version() != "1.5.0" {
+		t.Errorf("Version() = %q, want %q", release.Version(), "1.5.0")
+	}
+
+	data, err := updater.Download(ctx, release, platform)
+	if err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if string(data) != "new binary" {
+		t.Errorf("Download() = %q, want %q", data, "new binary")
+	}
+
+	// Unsupported platform
+	if _, err := updater.Download(ctx, release, Platform{OS: "plan9", Arch: "386"}); !errors.Is(err, ErrNoAsset) {
+		t.Errorf("Download(plan9) error = %v, want ErrNoAsset", err)
+	}
+
+	// Tampered archive fails verification
+	archive = append(archive, 0)
+	if _, err := updater.Download(ctx, release, platform); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("Download(tampered) error = %v, want checksum mismatch", err)
+	}
+
+	// Checksums matching a tampered archive are not signed by the key
+	checksums = fmt.Appendf(nil, "%x  %s\n", sha256.Sum256(archive), archiveName)
+	if _, err := updater.Download(ctx, release, platform); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("Download(re-checksummed) error = %v, want invalid signature", err)
+	}
+	// Nor are they by the release key of txtr
+	signature = ed25519.Sign(private, checksums)
+	updater.Key = nil
+	if _, err := updater.Download(ctx, release, platform); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Errorf("Download(test key) error = %v, want invalid signature", err)
+	}
+
+	// Missing signatures and checksums are refused
+	release.Assets = release.Assets[:2]
+	if _, err := updater.Download(ctx, release, platform); err == nil {
+		t.Error("Download() without signature succeeded, want error")
+	}
+	release.Assets = release.Assets[:1]
+	if _, err := updater.Download(ctx, release, platform); err == nil {
+		t.Error("Download() without checksums succeeded, want error")
+	}
+}
+
+// TestReleaseKey tests that the release key built into txtr is an Ed25519
+// key
+func TestReleaseKey(t *testing.T) {
+	if key, err := ReleaseKey(); err != nil || len(key) != ed25519.PublicKeySize {
+		t.Errorf("ReleaseKey() = %x, %v, want an Ed25519 key", key, err)
+	}
+}
+
+// TestUpdaterToken tests that the GitHub token is only sent to GitHub
+func TestUpdaterToken(t *testing.T) {
+	var authorization []string
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		_, _ = w.Write([]byte(`{"tag_name": "v1.5.0"}`))
+	}))
+	defer server.Close()
+
+	// A mirror does not receive the token
+	updater := &Updater{APIURL: server.URL + "/latest", Client: server.Client(), Token: "secret"}
+	if _, err := updater.LatestRelease(context.Background()); err != nil {
+		t.Fatalf("LatestRelease() error = %v", err)
+	}
+	if !slices.Equal(authorization, []string{""}) {
+		t.Errorf("Authorization headers = %q, want none", authorization)
+	}
+
+	for _, tt := range []struct {
+		url  string
+		want bool
+	}{
+		{"https://api.github.com/repos/richardwooding/txtr/releases/latest", true},
+		{"https://github.com/richardwooding/txtr/releases/download/v1.5.0/checksums.txt", true},
+		{"https://API.GitHub.com:443/repos", true},
+		{"http://api.github.com/repos", false},
+		{"https://api.github.com.evil.example/repos", false},
+		{"https://mirror.example.com/repos/richardwooding/txtr/releases/latest", false},
+		{"https://objects.githubusercontent.com/release-assets", false},
+	} {
+		u, err := url.Parse(tt.url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := sendsToken(u); got != tt.want {
+			t.Errorf("sendsToken(%s) = %v, want %v", tt.url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package vdisk
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// FuzzOpen tests opening virtual disks with random inputs
+func FuzzOpen(f *testing.F) {
+	// Seed corpus: the disks of the Open tests that fit in one file (the VHDX
+	// disk is 5 MiB, so only its signature is used) and bare signatures
+	f.Add(testSparseExtent(testGuest(4096), false))
+	f.Add(testSparseExtent(testGuest(4096), true))
+	f.Add(testQCOW2(testGuest(4096)))
+	// A sparse extent whose capacity ends inside its first grain
+	short := testSparseExtent(testGuest(4096), false)
+	binary.LittleEndian.PutUint64(short[12:], 1)
+	f.Add(short)
+	f.Add([]byte("# Disk DescriptorFile\nversion=1\n\n# Extent description\nRW 8 ZERO\n"))
+	f.Add([]byte(vmdkMagic))
+	f.Add([]byte(qcowMagic))
+	f.Add([]byte(vhdxMagic))
+	f.Add([]byte(""))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Skip extremely large inputs to prevent resource exhaustion
+		if len(data) > 10*1024*1024 {
+			t.Skip("Input too large")
+		}
+
+		// Should not panic
+		defer func() {
+			if r := recover(); r != nil {
+				t.Fatalf("Panic: %v\nInput: %q", r, data)
+			}
+		}()
+
+		// Open reads the disk from its path
+		path := filepath.Join(t.TempDir(), "disk")
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		disk, err := Open(path)
+		if err != nil {
+			// Errors are expected for invalid input
+			return
+		}
+		defer func() { _ = disk.Close() }()
+
+		// Invariant: the start of the disk can be read up to its size
+		// (errors are expected for corrupt tables)
+		want := min(disk.Size(), 1<<20)
+		got, err := io.ReadAll(io.NewSectionReader(disk, 0, want))
+		if err == nil && int64(len(got)) != want {
+			t.Errorf("read %d bytes of the disk, want %d", len(got), want)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package vdisk
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+)
+
+// QCOW2 images: a big-endian header, an L1 table of the offsets of L2
+// tables, which hold the offsets of clusters
+const (
+	qcowMagic          = "QFI\xfb"
+	qcowOffsetMask     = 0x00fffffffffffe00
+	qcowCompressed     = 1 << 62
+	qcowZero           = 1 // L2 entry flag (version 3): the cluster reads as zeros
+	qcowExtendedL2     = 1 << 4
+	qcowMaxL1Entries   = 1 << 25
+	qcowKnownFeatures  = 1<<0 | 1<<1 | 1<<3 // Dirty, corrupt and compression type bits
+	qcowCompressionMax = 104                // Header length holding the compression type
+)
+
+// openQCOW2 opens a QCOW2 image (versions 2 and 3). Encrypted images, images
+// with extended L2 entries and zstd compressed clusters are not supported;
+// clusters left to a backing file read as zeros.
+func (d *Disk) openQCOW2(file *os.File) error {
+	be := binary.BigEndian
+	header := make([]byte, 112)
+	if n, err := file.ReadAt(header, 0); n < 72 {
+		return fmt.Errorf("%w: short header: %v", errCorrupt, err)
+	}
+	version := be.Uint32(header[4:])
+	if version != 2 && version != 3 {
+		return fmt.Errorf("unsupported QCOW version %d", version)
+	}
+	clusterBits := be.Uint32(header[20:])
+	size := int64(be.Uint64(header[24:]))
+	if method := be.Uint32(header[32:]); method != 0 {
+		return fmt.Errorf("encrypted QCOW2 images are not supported")
+	}
+	l1Size := int64(be.Uint32(header[36:]))
+	l1Offset := int64(be.Uint64(header[40:]))
+	if clusterBits < 9 || clusterBits > 21 || size < 0 || l1Size > qcowMaxL1Entries {
+		return fmt.Errorf("%w: clusters of 2^%d bytes, %d L1 entries", errCorrupt, clusterBits, l1Size)
+	}
+	if version == 3 {
+		features := be.Uint64(header[72:])
+		if features&qcowExtendedL2 != 0 {
+			return fmt.Errorf("QCOW2 images with extended L2 entries are not supported")
+		}
+		if features&^qcowKnownFeatures != 0 {
+			return fmt.Errorf("unsupported QCOW2 features 0x%x", features)
+		}
+		if headerLength := be.Uint32(header[100:]); headerLength > qcowCompressionMax && header[104] != 0 {
+			return fmt.Errorf("QCOW2 compression type %d is not supported", header[104])
+		}
+	}
+
+	clusterSize := int64(1) << clusterBits
+	perTable := clusterSize / 8
+	l1 := make([]byte, l1Size*8)
+	if _, err := file.ReadAt(l1, l1Offset); err != nil {
+		return fmt.Errorf("%w: L1 table: %v", errCorrupt, err)
+	}
+	// Compressed cluster descriptors: the host offset, then the number of
+	// additional 512-byte sectors
+	offsetBits := 62 - (clusterBits - 8)
+
+	// The L2 table of the last cluster read
+	tableNumber := int64(-1)
+	table := make([]byte, clusterSize)
+	d.size = size
+	d.r = newBlockDisk(size, clusterSize, func(index int64) (block, error) {
+		if number := index / perTable; number != tableNumber {
+			if number >= l1Size {
+				return block{}, nil
+			}
+			tableOffset := int64(be.Uint64(l1[number*8:]) & qcowOffsetMask)
+			if tableOffset == 0 {
+				return block{}, nil
+			}
+			if _, err := file.ReadAt(table, tableOffset); err != nil {
+				return block{}, fmt.Errorf("%w: L2 table: %v", errCorrupt, err)
+			}
+			tableNumber = number
+		}
+		entry := be.Uint64(table[index%perTable*8:])
+		if entry&qcowCompressed == 0 {
+			offset := int64(entry & qcowOffsetMask)
+			if offset == 0 || version == 3 && entry&qcowZero != 0 {
+				return block{}, nil
+			}
+			return block{r: file, offset: offset}, nil
+		}
+		descriptor := entry &^ (3 << 62)
+		offset := int64(descriptor & (1<<offsetBits - 1))
+		length := int64(descriptor>>offsetBits+1)*512 - offset%512
+		if length > 2*clusterSize+512 {
+			return block{}, fmt.Errorf("%w: compressed cluster of %d bytes", errCorrupt, length)
+		}
+		var data bytes.Buffer
+		_, err := data.ReadFrom(io.LimitReader(flate.NewReader(io.NewSectionReader(file, offset, length)), clusterSize))
+		// Compressed data may end before the last sector it occupies
+		if err != nil && err != io.ErrUnexpectedEOF {
+			return block{}, err
+		}
+		return block{data: data.Bytes()}, nil
+	})
+	return nil
+}
@@ -0,0 +1,230 @@
+// Package vdisk reads the disk a virtual machine sees from the files of its
+// virtual disk (VMDK, QCOW2, VHDX), following their grain, cluster and block
+// tables, so the disk can be scanned without converting it to a raw image.
+// Unallocated parts of the disk, including those a differencing disk leaves
+// to its parent, read as zeros.
+package vdisk
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Formats reported in Disk.Format
+const (
+	FormatVMDK  = "vmdk"
+	FormatQCOW2 = "qcow2"
+	FormatVHDX  = "vhdx"
+)
+
+// ErrNotVirtualDisk is returned for files that are not virtual disks
+var ErrNotVirtualDisk = errors.New("not a virtual disk")
+
+var errCorrupt = errors.New("corrupt virtual disk")
+
+// maxBlockSize bounds the grains, clusters and blocks read
+const maxBlockSize = 256 << 20
+
+// Detect returns the format of the virtual disk starting with header, at
+// least its first 21 bytes, or "" if it is none
+func Detect(header []byte) string {
+	switch {
+	case len(header) >= 4 && string(header[:4]) == vmdkMagic,
+		len(header) >= 21 && strings.EqualFold(string(header[:21]), "# Disk DescriptorFile"):
+		return FormatVMDK
+	case len(header) >= 4 && string(header[:4]) == qcowMagic:
+		return FormatQCOW2
+	case len(header) >= 8 && string(header[:8]) == vhdxMagic:
+		return FormatVHDX
+	}
+	return ""
+}
+
+// Disk is the guest-visible disk of a virtual disk
+type Disk struct {
+	Format string // FormatVMDK, FormatQCOW2 or FormatVHDX
+	r      io.ReaderAt
+	size   int64
+	files  []*os.File
+}
+
+// Open opens the virtual disk at path. A VMDK descriptor file is opened with
+// the extent files it lists, found next to it. It returns ErrNotVirtualDisk
+// for other files.
+func Open(path string) (*Disk, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 21)
+	n, _ := io.ReadFull(file, header)
+
+	d := &Disk{Format: Detect(header[:n]), files: []*os.File{file}}
+	switch d.Format {
+	case FormatVMDK:
+		err = d.openVMDK(path, file)
+	case FormatQCOW2:
+		err = d.openQCOW2(file)
+	case FormatVHDX:
+		err = d.openVHDX(file)
+	default:
+		err = ErrNotVirtualDisk
+	}
+	if err != nil {
+		_ = d.Close()
+		return nil, err
+	}
+	return d, nil
+}
+
+// Size returns the size of the disk in bytes
+func (d *Disk) Size() int64 {
+	return d.size
+}
+
+// ReadAt reads the disk at offset off
+func (d *Disk) ReadAt(p []byte, off int64) (int, error) {
+	if off >= d.size {
+		return 0, io.EOF
+	}
+	want := len(p)
+	if remaining := d.size - off; int64(want) > remaining {
+		p = p[:remaining]
+	}
+	n, err := d.r.ReadAt(p, off)
+	if err == nil && n < want {
+		err = io.EOF
+	}
+	return n, err
+}
+
+// Close closes the files of the disk
+func (d *Disk) Close() error {
+	var err error
+	for _, file := range d.files {
+		if closeErr := file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// block is where a block of a disk is: at offset in r, or decoded in data
+// (compressed blocks), or nowhere (r and data nil) if it reads as zeros
+type block struct {
+	r      io.ReaderAt
+	offset int64
+	data   []byte
+}
+
+// blockDisk is a disk stored in blocks of blockSize bytes, each found by
+// locate. The last decoded block is cached.
+type blockDisk struct {
+	size      int64
+	blockSize int64
+	locate    func(index int64) (block, error)
+
+	mu     sync.Mutex
+	cached int64 // Index of data, -1 if none
+	data   []byte
+}
+
+func newBlockDisk(size, blockSize int64, locate func(index int64) (block, error)) *blockDisk {
+	return &blockDisk{size: size, blockSize: blockSize, locate: locate, cached: -1}
+}
+
+// ReadAt reads the disk at offset off, block by block
+func (d *blockDisk) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for n < len(p) {
+		pos := off + int64(n)
+		if pos >= d.size {
+			return n, io.EOF
+		}
+		index := pos / d.blockSize
+		within := pos - index*d.blockSize
+		dst := p[n:min(len(p), n+int(min(d.blockSize-within, d.size-pos)))]
+
+		d.mu.Lock()
+		err := d.read(dst, index, within)
+		d.mu.Unlock()
+		if err != nil {
+			return n, fmt.Errorf("block %d: %w", index, err)
+		}
+		n += len(dst)
+	}
+	return n, nil
+}
+
+// read reads dst from offset within of block index
+func (d *blockDisk) read(dst []byte, index, within int64) error {
+	if index == d.cached {
+		copyBlock(dst, d.data, within)
+		return nil
+	}
+	b, err := d.locate(index)
+	switch {
+	case err != nil:
+		return err
+	case b.data != nil:
+		d.cached, d.data = index, b.data
+		copyBlock(dst, b.data, within)
+	case b.r != nil:
+		if _, err := b.r.ReadAt(dst, b.offset+within); err != nil && err != io.EOF {
+			return err
+		}
+	default:
+		clear(dst)
+	}
+	return nil
+}
+
+// copyBlock copies the decoded data of a block from offset within to dst,
+// with zeros past its end
+func copyBlock(dst, data []byte, within int64) {
+	n := 0
+	if within < int64(len(data)) {
+		n = copy(dst, data[within:])
+	}
+	clear(dst[n:])
+}
+
+// spanDisk is a disk made of extents, one after the other
+type spanDisk struct {
+	extents []extent
+}
+
+// extent is a part of a spanDisk; r is nil for extents reading as zeros
+type extent struct {
+	start, size int64
+	r           io.ReaderAt
+}
+
+// ReadAt reads the disk at offset off, extent by extent
+func (d *spanDisk) ReadAt(p []byte, off int64) (int, error) {
+	n := 0
+	for _, e := range d.extents {
+		if n == len(p) {
+			break
+		}
+		pos := off + int64(n)
+		if pos >= e.start+e.size || pos < e.start {
+			continue
+		}
+		dst := p[n:min(len(p), n+int(e.start+e.size-pos))]
+		if e.r == nil {
+			clear(dst)
+		} else if read, err := e.r.ReadAt(dst, pos-e.start); err != nil && !(err == io.EOF && read == len(dst)) {
+			return n + read, err
+		}
+		n += len(dst)
+	}
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
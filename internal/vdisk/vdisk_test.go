@@ -0,0 +1,255 @@
+package vdisk
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// testGuest is a guest disk of three blocks: data, zeros (unallocated) and
+// data
+func testGuest(blockSize int) []byte {
+	guest := make([]byte, 3*blockSize)
+	copy(guest, strings.Repeat("block zero ", blockSize/11))
+	copy(guest[2*blockSize:], strings.Repeat("block two ", blockSize/10))
+	return guest
+}
+
+// zlibCompress compresses data with zlib, or raw deflate if raw is set
+func zlibCompress(data []byte, raw bool) []byte {
+	var buf bytes.Buffer
+	var w io.WriteCloser
+	if raw {
+		w, _ = flate.NewWriter(&buf, flate.BestCompression)
+	} else {
+		w = zlib.NewWriter(&buf)
+	}
+	_, _ = w.Write(data)
+	_ = w.Close()
+	return buf.Bytes()
+}
+
+// padSector pads b to a multiple of 512 bytes
+func padSector(b []byte) []byte {
+	return append(b, make([]byte, -len(b)&511)...)
+}
+
+// testSparseExtent builds a VMDK sparse extent of guest (grains of 8
+// sectors), with compressed grains and the grain directory in a footer if
+// streamOptimized is set
+func testSparseExtent(guest []byte, streamOptimized bool) []byte {
+	le := binary.LittleEndian
+	const grainSectors = 8
+	header := make([]byte, vmdkHeaderSize)
+	copy(header, vmdkMagic)
+	le.PutUint32(header[4:], 1)
+	le.PutUint64(header[12:], uint64(len(guest)/vmdkSector))
+	le.PutUint64(header[20:], grainSectors)
+	le.PutUint32(header[44:], 4) // Grain table entries
+	if streamOptimized {
+		le.PutUint32(header[8:], vmdkCompressed)
+	}
+
+	// Sector 1: the grain directory, sector 2: its only grain table
+	file := append([]byte{}, header...)
+	file = append(file, padSector(le.AppendUint32(nil, 2))...)
+	table := make([]byte, 16)
+	file = append(file, make([]byte, vmdkSector)...)
+	for grain := range len(guest) / (grainSectors * vmdkSector) {
+		data := guest[grain*grainSectors*vmdkSector : (grain+1)*grainSectors*vmdkSector]
+		if grain == 1 {
+			continue
+		}
+		le.PutUint32(table[grain*4:], uint32(len(file)/vmdkSector))
+		if streamOptimized {
+			compressed := zlibCompress(data, false)
+			head := le.AppendUint64(nil, uint64(grain*grainSectors))
+			head = le.AppendUint32(head, uint32(len(compressed)))
+			data = append(head, compressed...)
+		}
+		file = append(file, padSector(append([]byte{}, data...))...)
+	}
+	copy(file[2*vmdkSector:], table)
+
+	le.PutUint64(header[56:], 1) // Grain directory sector
+	if !streamOptimized {
+		copy(file, header)
+		return file
+	}
+	// The header says the directory is in the footer, followed by the
+	// end-of-stream marker
+	le.PutUint64(file[56:], vmdkGDAtEnd)
+	file = append(file, header...)
+	return append(file, make([]byte, vmdkSector)...)
+}
+
+// testQCOW2 builds a version 3 QCOW2 image of guest (4 KiB clusters): the
+// first cluster stored as it is, the second flagged as zeros and the third
+// compressed
+func testQCOW2(guest []byte) []byte {
+	be := binary.BigEndian
+	const cluster = 4096
+	file := make([]byte, 4*cluster)
+	copy(file, qcowMagic)
+	be.PutUint32(file[4:], 3)
+	be.PutUint32(file[20:], 12)
+	be.PutUint64(file[24:], uint64(len(guest)))
+	be.PutUint32(file[36:], 1)
+	be.PutUint64(file[40:], cluster)        // L1 table
+	be.PutUint32(file[100:], 104)           // Header length
+	be.PutUint64(file[cluster:], 2*cluster) // L2 table
+	copy(file[3*cluster:], guest[:cluster])
+	be.PutUint64(file[2*cluster:], 3*cluster)
+	be.PutUint64(file[2*cluster+8:], 3*cluster|qcowZero)
+
+	compressed := zlibCompress(guest[2*cluster:], true)
+	offset := uint64(len(file) + 100)
+	sectors := (100 + uint64(len(compressed)) + 511) / 512
+	be.PutUint64(file[2*cluster+16:], qcowCompressed|(sectors-1)<<58|offset)
+	file = append(file, make([]byte, 100)...)
+	return padSector(append(file, compressed...))
+}
+
+// testVHDX builds a dynamic VHDX of guest (1 MiB blocks), its second block
+// not present
+func testVHDX(guest []byte) []byte {
+	le := binary.LittleEndian
+	file := make([]byte, 5*vhdxMB)
+	copy(file, vhdxMagic)
+	table := file[vhdxRegionTable:]
+	copy(table, "regi")
+	le.PutUint32(table[8:], 2)
+	for i, region := range []struct {
+		guid   [16]byte
+		offset int
+	}{{vhdxBAT, vhdxMB}, {vhdxMetadata, 2 * vhdxMB}} {
+		entry := table[16+i*32:]
+		copy(entry, region.guid[:])
+		le.PutUint64(entry[16:], uint64(region.offset))
+		le.PutUint32(entry[24:], vhdxMB)
+	}
+
+	metadata := file[2*vhdxMB:]
+	copy(metadata, vhdxMetadataMagic)
+	le.PutUint16(metadata[10:], 3)
+	for i, item := range []struct {
+		guid  [16]byte
+		value []byte
+	}{
+		{vhdxFileParameters, le.AppendUint64(nil, vhdxMB)},
+		{vhdxDiskSize, le.AppendUint64(nil, uint64(len(guest)))},
+		{vhdxSectorSize, le.AppendUint32(nil, 512)},
+	} {
+		entry := metadata[32+i*32:]
+		offset := 64<<10 + i*8
+		copy(entry, item.guid[:])
+		le.PutUint32(entry[16:], uint32(offset))
+		le.PutUint32(entry[20:], uint32(len(item.value)))
+		copy(metadata[offset:], item.value)
+	}
+
+	bat := file[vhdxMB:]
+	le.PutUint64(bat, 3<<20|vhdxBlockFullyPresent)
+	le.PutUint64(bat[16:], 4<<20|vhdxBlockFullyPresent)
+	copy(file[3*vhdxMB:], guest[:vhdxMB])
+	copy(file[4*vhdxMB:], guest[2*vhdxMB:])
+	return file
+}
+
+// TestOpen tests reading the guest disk of each format
+func TestOpen(t *testing.T) {
+	sparse := testGuest(4096)
+	flat := []byte(strings.Repeat("flat extent ", 400))[:4096]
+	descriptor := "# Disk DescriptorFile\nversion=1\nCID=fffffffe\n\n# Extent description\n" +
+		"RW 8 FLAT \"disk-flat.vmdk\" 0\nRW 8 ZERO\nRW 24 SPARSE \"disk-s001.vmdk\"\n\n# The Disk Data Base\nddb.virtualHWVersion = \"4\"\n"
+
+	tests := []struct {
+		name   string
+		files  map[string][]byte // disk.* is opened
+		format string
+		want   []byte
+	}{
+		{"vmdk monolithic sparse", map[string][]byte{"disk.vmdk": testSparseExtent(sparse, false)}, FormatVMDK, sparse},
+		{"vmdk stream-optimized", map[string][]byte{"disk.vmdk": testSparseExtent(sparse, true)}, FormatVMDK, sparse},
+		{"vmdk descriptor", map[string][]byte{
+			"disk.vmdk":      []byte(descriptor),
+			"disk-flat.vmdk": flat,
+			"disk-s001.vmdk": testSparseExtent(sparse, false),
+		}, FormatVMDK, append(append(append([]byte{}, flat...), make([]byte, 4096)...), sparse...)},
+		{"qcow2", map[string][]byte{"disk.qcow2": testQCOW2(testGuest(4096))}, FormatQCOW2, testGuest(4096)},
+		{"vhdx", map[string][]byte{"disk.vhdx": testVHDX(testGuest(vhdxMB))}, FormatVHDX, testGuest(vhdxMB)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			var first string
+			for name, data := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), data, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				if strings.HasPrefix(name, "disk.") {
+					first = filepath.Join(dir, name)
+				}
+			}
+			disk, err := Open(first)
+			if err != nil {
+				t.Fatalf("Open() error = %v", err)
+			}
+			defer func() { _ = disk.Close() }()
+			if disk.Format != tt.format || disk.Size() != int64(len(tt.want)) {
+				t.Errorf("Open() = %s of %d bytes, want %s of %d", disk.Format, disk.Size(), tt.format, len(tt.want))
+			}
+			got, err := io.ReadAll(io.NewSectionReader(disk, 0, disk.Size()))
+			if err != nil {
+				t.Fatalf("reading the disk: %v", err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				for i := range got {
+					if i >= len(tt.want) || got[i] != tt.want[i] {
+						t.Fatalf("disk differs at 0x%x", i)
+					}
+				}
+				t.Fatalf("disk is %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+
+	plain := filepath.Join(t.TempDir(), "plain.bin")
+	if err := os.WriteFile(plain, []byte("no virtual disk here"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(plain); !errors.Is(err, ErrNotVirtualDisk) {
+		t.Errorf("Open(plain) error = %v, want %v", err, ErrNotVirtualDisk)
+	}
+}
+
+// TestParseVMDKExtent tests parsing the extent lines of descriptor files
+func TestParseVMDKExtent(t *testing.T) {
+	tests := []struct {
+		line    string
+		kind    string
+		name    string
+		sectors int64
+		offset  int64
+		ok      bool
+	}{
+		{`RW 4192256 SPARSE "disk s001.vmdk"`, "SPARSE", "disk s001.vmdk", 4192256, 0, true},
+		{`RDONLY 2048 FLAT "disk-flat.vmdk" 128`, "FLAT", "disk-flat.vmdk", 2048, 128, true},
+		{`RW 100 ZERO`, "ZERO", "", 100, 0, true},
+		{`ddb.adapterType = "lsilogic"`, "", "", 0, 0, false},
+		{`RW 8 FLAT disk-flat.vmdk`, "", "", 0, 0, false},
+	}
+	for _, tt := range tests {
+		_, sectors, kind, name, offset, ok := parseVMDKExtent(tt.line)
+		if ok != tt.ok || ok && (kind != tt.kind || name != tt.name || sectors != tt.sectors || offset != tt.offset) {
+			t.Errorf("parseVMDKExtent(%q) = %d %s %q %d %v, want %d %s %q %d %v", tt.line, sectors, kind, name, offset, ok, tt.sectors, tt.kind, tt.name, tt.offset, tt.ok)
+		}
+	}
+}
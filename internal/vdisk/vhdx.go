@@ -0,0 +1,119 @@
+package vdisk
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+)
+
+// VHDX files: a file identifier, two headers, two copies of the region table
+// locating the block allocation table (BAT) and the metadata region
+const (
+	vhdxMagic         = "vhdxfile"
+	vhdxRegionTable   = 192 << 10
+	vhdxMaxRegions    = 2047
+	vhdxMetadataMagic = "metadata"
+	vhdxMaxMetadata   = 2047
+	vhdxMB            = 1 << 20
+	vhdxSectorBitmap  = 1 << 23 // Sectors described by a sector bitmap block
+
+	// Payload block states in BAT entries
+	vhdxBlockFullyPresent     = 6
+	vhdxBlockPartiallyPresent = 7
+)
+
+// GUIDs of the regions and metadata items read, in their on-disk byte order
+var (
+	vhdxBAT            = vhdxGUID(0x2DC27766, 0xF623, 0x4200, 0x9D64115E9BFD4A08)
+	vhdxMetadata       = vhdxGUID(0x8B7CA206, 0x4790, 0x4B9A, 0xB8FE575F050F886E)
+	vhdxFileParameters = vhdxGUID(0xCAA16737, 0xFA36, 0x4D43, 0xB3B633F0AA44E76B)
+	vhdxDiskSize       = vhdxGUID(0x2FA54224, 0xCD1B, 0x4876, 0xB2115DBED83BF4B8)
+	vhdxSectorSize     = vhdxGUID(0x8141BF1D, 0xA96F, 0x4709, 0xBA47F233A8FAAB5F)
+)
+
+// vhdxGUID encodes a GUID written as {a-b-c-d} in its on-disk byte order:
+// a, b and c little-endian, d big-endian
+func vhdxGUID(a uint32, b, c uint16, d uint64) [16]byte {
+	var guid [16]byte
+	binary.LittleEndian.PutUint32(guid[0:], a)
+	binary.LittleEndian.PutUint16(guid[4:], b)
+	binary.LittleEndian.PutUint16(guid[6:], c)
+	binary.BigEndian.PutUint64(guid[8:], d)
+	return guid
+}
+
+// openVHDX opens a VHDX file. The blocks of a differencing disk that are in
+// its parent read as zeros, and a log that was not replayed is not applied.
+func (d *Disk) openVHDX(file *os.File) error {
+	le := binary.LittleEndian
+	table := make([]byte, 64<<10)
+	if _, err := file.ReadAt(table, vhdxRegionTable); err != nil || string(table[:4]) != "regi" {
+		return fmt.Errorf("%w: no region table", errCorrupt)
+	}
+	var batOffset, batLength, metaOffset, metaLength int64
+	count := min(int(le.Uint32(table[8:])), vhdxMaxRegions, (len(table)-16)/32)
+	for i := range count {
+		entry := table[16+i*32 : 48+i*32]
+		offset, length := int64(le.Uint64(entry[16:])), int64(le.Uint32(entry[24:]))
+		switch [16]byte(entry[:16]) {
+		case vhdxBAT:
+			batOffset, batLength = offset, length
+		case vhdxMetadata:
+			metaOffset, metaLength = offset, length
+		}
+	}
+	if batLength == 0 || metaLength == 0 || metaLength > 64<<20 || batLength > 1<<30 {
+		return fmt.Errorf("%w: no BAT or metadata region", errCorrupt)
+	}
+
+	metadata := make([]byte, metaLength)
+	if _, err := file.ReadAt(metadata, metaOffset); err != nil || len(metadata) < 32 || !bytes.HasPrefix(metadata, []byte(vhdxMetadataMagic)) {
+		return fmt.Errorf("%w: no metadata table", errCorrupt)
+	}
+	item := func(guid [16]byte, length int) []byte {
+		count := min(int(le.Uint16(metadata[10:])), vhdxMaxMetadata, (len(metadata)-32)/32)
+		for i := range count {
+			entry := metadata[32+i*32 : 64+i*32]
+			if [16]byte(entry[:16]) != guid {
+				continue
+			}
+			offset := int(le.Uint32(entry[16:]))
+			if int(le.Uint32(entry[20:])) < length || offset+length > len(metadata) {
+				return nil
+			}
+			return metadata[offset : offset+length]
+		}
+		return nil
+	}
+	params, diskSize, sectorSize := item(vhdxFileParameters, 8), item(vhdxDiskSize, 8), item(vhdxSectorSize, 4)
+	if params == nil || diskSize == nil || sectorSize == nil {
+		return fmt.Errorf("%w: missing metadata items", errCorrupt)
+	}
+	blockSize := int64(le.Uint32(params))
+	size := int64(le.Uint64(diskSize))
+	logicalSector := int64(le.Uint32(sectorSize))
+	if blockSize < vhdxMB || blockSize > maxBlockSize || logicalSector != 512 && logicalSector != 4096 || size < 0 {
+		return fmt.Errorf("%w: blocks of %d bytes, sectors of %d", errCorrupt, blockSize, logicalSector)
+	}
+
+	bat := make([]byte, batLength)
+	if _, err := file.ReadAt(bat, batOffset); err != nil {
+		return fmt.Errorf("%w: BAT: %v", errCorrupt, err)
+	}
+	// A sector bitmap entry follows each chunk of payload entries
+	chunkRatio := vhdxSectorBitmap * logicalSector / blockSize
+	d.size = size
+	d.r = newBlockDisk(size, blockSize, func(index int64) (block, error) {
+		entryIndex := index + index/chunkRatio
+		if (entryIndex+1)*8 > int64(len(bat)) {
+			return block{}, fmt.Errorf("%w: BAT has no entry for block %d", errCorrupt, index)
+		}
+		entry := le.Uint64(bat[entryIndex*8:])
+		if state := entry & 7; state != vhdxBlockFullyPresent && state != vhdxBlockPartiallyPresent {
+			return block{}, nil
+		}
+		return block{r: file, offset: int64(entry>>20) * vhdxMB}, nil
+	})
+	return nil
+}
@@ -0,0 +1,224 @@
+package vdisk
+
+import (
+	"bufio"
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// VMDK sparse extents: a 512-byte header, a grain directory of grain tables
+// of the sector offsets of grains
+const (
+	vmdkMagic        = "KDMV"
+	vmdkSector       = 512
+	vmdkHeaderSize   = 512
+	vmdkGDAtEnd      = 0xffffffffffffffff // The grain directory is in the footer
+	vmdkCompressed   = 1 << 16            // Header flag: grains are compressed
+	vmdkGrainHeader  = 12                 // LBA and size before a compressed grain
+	vmdkMaxExtents   = 4096
+	vmdkMaxGDEntries = 1 << 24
+)
+
+// openVMDK opens a monolithic sparse VMDK (one file starting with a sparse
+// extent header, also stream-optimized) or a descriptor file listing the
+// extents of the disk: sparse extent files, flat (raw) files and zeros
+func (d *Disk) openVMDK(path string, file *os.File) error {
+	header := make([]byte, 4)
+	if _, err := file.ReadAt(header, 0); err != nil {
+		return ErrNotVirtualDisk
+	}
+	if string(header) == vmdkMagic {
+		r, size, err := openSparseExtent(file)
+		if err != nil {
+			return err
+		}
+		d.r, d.size = r, size
+		return nil
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	descriptor := make([]byte, min(info.Size(), 1<<20))
+	if _, err := file.ReadAt(descriptor, 0); err != nil && err != io.EOF {
+		return err
+	}
+	span := &spanDisk{}
+	dir := filepath.Dir(path)
+	scanner := bufio.NewScanner(bytes.NewReader(descriptor))
+	for scanner.Scan() {
+		access, sectors, kind, name, offset, ok := parseVMDKExtent(scanner.Text())
+		if !ok || access == "NOACCESS" {
+			continue
+		}
+		if len(span.extents) == vmdkMaxExtents {
+			return fmt.Errorf("%w: more than %d extents", errCorrupt, vmdkMaxExtents)
+		}
+		e := extent{start: d.size, size: sectors * vmdkSector}
+		switch kind {
+		case "ZERO":
+		case "FLAT", "VMFS":
+			extentFile, err := d.openExtent(dir, name)
+			if err != nil {
+				return err
+			}
+			e.r = io.NewSectionReader(extentFile, offset*vmdkSector, e.size)
+		case "SPARSE", "VMFSSPARSE":
+			extentFile, err := d.openExtent(dir, name)
+			if err != nil {
+				return err
+			}
+			r, _, err := openSparseExtent(extentFile)
+			if err != nil {
+				return fmt.Errorf("%s: %w", name, err)
+			}
+			e.r = r
+		default:
+			return fmt.Errorf("unsupported VMDK extent type %s", kind)
+		}
+		span.extents = append(span.extents, e)
+		d.size += e.size
+	}
+	if len(span.extents) == 0 {
+		return fmt.Errorf("%w: descriptor lists no extents", errCorrupt)
+	}
+	d.r = span
+	return nil
+}
+
+// openExtent opens the extent file name of a descriptor in dir
+func (d *Disk) openExtent(dir, name string) (*os.File, error) {
+	if !filepath.IsAbs(name) {
+		name = filepath.Join(dir, name)
+	}
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("VMDK extent: %w", err)
+	}
+	d.files = append(d.files, file)
+	return file, nil
+}
+
+// parseVMDKExtent parses an extent line of a descriptor file, e.g.
+// RW 4192256 SPARSE "disk-s001.vmdk" or RW 2048 FLAT "disk-flat.vmdk" 0
+func parseVMDKExtent(line string) (access string, sectors int64, kind, name string, offset int64, ok bool) {
+	fields := strings.Fields(line)
+	if len(fields) < 3 {
+		return
+	}
+	access = strings.ToUpper(fields[0])
+	if access != "RW" && access != "RDONLY" && access != "NOACCESS" {
+		return
+	}
+	sectors, err := strconv.ParseInt(fields[1], 10, 64)
+	if err != nil || sectors < 0 {
+		return
+	}
+	kind = strings.ToUpper(fields[2])
+	if kind == "ZERO" {
+		return access, sectors, kind, "", 0, true
+	}
+	rest := strings.TrimSpace(line[strings.Index(line, fields[2])+len(fields[2]):])
+	if !strings.HasPrefix(rest, `"`) {
+		return
+	}
+	end := strings.IndexByte(rest[1:], '"')
+	if end < 0 {
+		return
+	}
+	name = rest[1 : end+1]
+	if after := strings.Fields(rest[end+2:]); len(after) > 0 {
+		offset, _ = strconv.ParseInt(after[0], 10, 64)
+	}
+	return access, sectors, kind, name, offset, true
+}
+
+// openSparseExtent opens a sparse extent file: grains are located through
+// the grain directory and its grain tables, and may be compressed
+// (stream-optimized extents, whose grain directory is found through the
+// footer at the end of the file)
+func openSparseExtent(file *os.File) (io.ReaderAt, int64, error) {
+	le := binary.LittleEndian
+	header := make([]byte, vmdkHeaderSize)
+	if _, err := file.ReadAt(header, 0); err != nil || string(header[:4]) != vmdkMagic {
+		return nil, 0, fmt.Errorf("%w: no sparse extent header", errCorrupt)
+	}
+	if le.Uint64(header[56:]) == vmdkGDAtEnd {
+		info, err := file.Stat()
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err := file.ReadAt(header, info.Size()-2*vmdkSector); err != nil || string(header[:4]) != vmdkMagic {
+			return nil, 0, fmt.Errorf("%w: no footer", errCorrupt)
+		}
+	}
+	flags := le.Uint32(header[8:])
+	capacity := int64(le.Uint64(header[12:])) * vmdkSector
+	grainSize := int64(le.Uint64(header[20:])) * vmdkSector
+	perTable := int64(le.Uint32(header[44:]))
+	gdOffset := int64(le.Uint64(header[56:])) * vmdkSector
+	if grainSize <= 0 || grainSize > maxBlockSize || perTable <= 0 || perTable > 1<<16 || capacity < 0 {
+		return nil, 0, fmt.Errorf("%w: grains of %d bytes, %d per table", errCorrupt, grainSize, perTable)
+	}
+	// A last grain the capacity ends in is listed too
+	grains := capacity / grainSize
+	if capacity%grainSize != 0 {
+		grains++
+	}
+	tables := (grains + perTable - 1) / perTable
+	if tables > vmdkMaxGDEntries {
+		return nil, 0, fmt.Errorf("%w: %d grain tables", errCorrupt, tables)
+	}
+	directory := make([]byte, tables*4)
+	if _, err := file.ReadAt(directory, gdOffset); err != nil {
+		return nil, 0, fmt.Errorf("%w: grain directory: %v", errCorrupt, err)
+	}
+
+	// The grain table of the last grain read
+	tableNumber := int64(-1)
+	table := make([]byte, perTable*4)
+	return newBlockDisk(capacity, grainSize, func(index int64) (block, error) {
+		if number := index / perTable; number != tableNumber {
+			tableSector := int64(le.Uint32(directory[number*4:]))
+			if tableSector == 0 {
+				return block{}, nil
+			}
+			if _, err := file.ReadAt(table, tableSector*vmdkSector); err != nil {
+				return block{}, fmt.Errorf("%w: grain table: %v", errCorrupt, err)
+			}
+			tableNumber = number
+		}
+		grain := int64(le.Uint32(table[index%perTable*4:]))
+		switch {
+		case grain <= 1: // Unallocated, or zeroed
+			return block{}, nil
+		case flags&vmdkCompressed == 0:
+			return block{r: file, offset: grain * vmdkSector}, nil
+		}
+		head := make([]byte, vmdkGrainHeader)
+		if _, err := file.ReadAt(head, grain*vmdkSector); err != nil {
+			return block{}, err
+		}
+		size := int64(le.Uint32(head[8:]))
+		if size > 2*grainSize+1024 {
+			return block{}, fmt.Errorf("%w: compressed grain of %d bytes", errCorrupt, size)
+		}
+		zr, err := zlib.NewReader(io.NewSectionReader(file, grain*vmdkSector+vmdkGrainHeader, size))
+		if err != nil {
+			return block{}, err
+		}
+		var data bytes.Buffer
+		if _, err := data.ReadFrom(io.LimitReader(zr, grainSize)); err != nil {
+			return block{}, err
+		}
+		return block{data: data.Bytes()}, nil
+	}), capacity, nil
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package walk
+
+import "os"
+
+// fileKey identifies a file by device and inode
+type fileKey struct {
+	dev, ino uint64
+}
+
+// keyOf reports false: the file information of this platform has no inode,
+// so files are compared with os.SameFile instead
+func keyOf(os.FileInfo) (fileKey, bool) {
+	return fileKey{}, false
+}
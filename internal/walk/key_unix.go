@@ -0,0 +1,22 @@
+//go:build unix
+
+package walk
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileKey identifies a file by device and inode
+type fileKey struct {
+	dev, ino uint64
+}
+
+// keyOf returns the device and inode of a file
+func keyOf(info os.FileInfo) (fileKey, bool) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileKey{}, false
+	}
+	return fileKey{dev: uint64(stat.Dev), ino: uint64(stat.Ino)}, true
+}
@@ -0,0 +1,111 @@
+// Package walk visits the regular files below directories for the recursive
+// subcommands, scanning every file once: hard links and directories reached
+// again (through another root, a bind mount or a followed symlink) are
+// skipped, which also stops symlink loops.
+package walk
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/richardwooding/txtr/internal/winpath"
+)
+
+// Walker walks directory trees, remembering the files and directories it has
+// visited across calls to Walk. The zero value does not follow symlinks.
+type Walker struct {
+	FollowSymlinks bool // Follow symlinks to files and directories
+
+	seen   map[fileKey]bool        // Visited files and directories by device and inode
+	bySize map[int64][]os.FileInfo // Visited files without an inode, compared with os.SameFile
+}
+
+// Walk calls fn with every regular file below root in lexical order, or with
+// root itself if it is a file. Files and directories already visited are
+// skipped. Symlinks below root are skipped unless FollowSymlinks is set; root
+// itself is always followed. Paths that cannot be read are passed to fn with
+// the error, and the walk continues.
+func (w *Walker) Walk(root string, fn func(path string, err error)) {
+	info, err := os.Stat(winpath.Input(root))
+	if err != nil {
+		fn(root, err)
+		return
+	}
+	w.visit(root, info, fn)
+}
+
+// visit walks a file or directory reached at path
+func (w *Walker) visit(path string, info os.FileInfo, fn func(path string, err error)) {
+	switch {
+	case info.IsDir():
+		if w.add(info) {
+			w.walkDir(path, fn)
+		}
+	case info.Mode().IsRegular():
+		if w.add(info) {
+			fn(path, nil)
+		}
+	}
+}
+
+// walkDir visits the entries of a directory
+func (w *Walker) walkDir(dir string, fn func(path string, err error)) {
+	entries, err := os.ReadDir(winpath.Input(dir))
+	if err != nil {
+		fn(dir, err)
+	}
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		var info os.FileInfo
+		switch {
+		case entry.Type()&fs.ModeSymlink != 0:
+			if !w.FollowSymlinks {
+				continue
+			}
+			info, err = os.Stat(winpath.Input(path))
+		case entry.IsDir():
+			info, err = os.Stat(winpath.Input(path))
+		case entry.Type().IsRegular():
+			info, err = entry.Info()
+		default:
+			continue
+		}
+		if err != nil {
+			fn(path, err)
+			continue
+		}
+		w.visit(path, info, fn)
+	}
+}
+
+// add records a file or directory as visited, reporting false if it already was
+func (w *Walker) add(info os.FileInfo) bool {
+	if key, ok := keyOf(info); ok {
+		if w.seen[key] {
+			return false
+		}
+		if w.seen == nil {
+			w.seen = make(map[fileKey]bool)
+		}
+		w.seen[key] = true
+		return true
+	}
+
+	// Without inodes, only files of the same size can be the same file.
+	// Directories all have the same size there; as they can only be reached
+	// twice through symlinks, they are not compared unless these are followed.
+	if info.IsDir() && !w.FollowSymlinks {
+		return true
+	}
+	for _, other := range w.bySize[info.Size()] {
+		if os.SameFile(info, other) {
+			return false
+		}
+	}
+	if w.bySize == nil {
+		w.bySize = make(map[int64][]os.FileInfo)
+	}
+	w.bySize[info.Size()] = append(w.bySize[info.Size()], info)
+	return true
+}
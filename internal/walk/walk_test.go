@@ -0,0 +1,115 @@
+package walk
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// walkAll returns the files a walker visits below root, relative to root
+func walkAll(t *testing.T, w *Walker, root string) []string {
+	t.Helper()
+	var files []string
+	w.Walk(root, func(path string, err error) {
+		if err != nil {
+			t.Errorf("Walk() error at %s: %v", path, err)
+			return
+		}
+		rel, _ := filepath.Rel(root, path)
+		files = append(files, filepath.ToSlash(rel))
+	})
+	return files
+}
+
+// writeFile creates a file with parent directories
+func writeFile(t *testing.T, path, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWalkHardlinks tests that hard links to a visited file are skipped
+func TestWalkHardlinks(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a", "firmware.bin"), "firmware")
+	writeFile(t, filepath.Join(root, "a", "other.bin"), "other!!!")
+	if err := os.MkdirAll(filepath.Join(root, "b"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Link(filepath.Join(root, "a", "firmware.bin"), filepath.Join(root, "b", "firmware.bin")); err != nil {
+		t.Skipf("hard links not supported: %v", err)
+	}
+
+	got := walkAll(t, &Walker{}, root)
+	want := []string{"a/firmware.bin", "a/other.bin"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Walk() = %v, want %v", got, want)
+	}
+}
+
+// TestWalkSymlinks tests skipping and following symlinks, including loops
+func TestWalkSymlinks(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "dir", "a.bin"), "a")
+	outside := t.TempDir()
+	writeFile(t, filepath.Join(outside, "b.bin"), "b")
+	links := map[string]string{
+		filepath.Join(root, "dir", "loop"):  filepath.Join(root, "dir"),
+		filepath.Join(root, "outside"):      outside,
+		filepath.Join(root, "alias.bin"):    filepath.Join(root, "dir", "a.bin"),
+		filepath.Join(root, "dangling.bin"): filepath.Join(root, "missing"),
+	}
+	for link, target := range links {
+		if err := os.Symlink(target, link); err != nil {
+			t.Skipf("symlinks not supported: %v", err)
+		}
+	}
+
+	if got := walkAll(t, &Walker{}, root); !slices.Equal(got, []string{"dir/a.bin"}) {
+		t.Errorf("Walk() = %v, want symlinks skipped", got)
+	}
+
+	var got, failed []string
+	(&Walker{FollowSymlinks: true}).Walk(root, func(path string, err error) {
+		rel, _ := filepath.Rel(root, path)
+		if err != nil {
+			failed = append(failed, filepath.ToSlash(rel))
+			return
+		}
+		got = append(got, filepath.ToSlash(rel))
+	})
+	// alias.bin comes first in lexical order, so dir/a.bin is the duplicate
+	if want := []string{"alias.bin", "outside/b.bin"}; !slices.Equal(got, want) {
+		t.Errorf("Walk(FollowSymlinks) = %v, want %v", got, want)
+	}
+	if want := []string{"dangling.bin"}; !slices.Equal(failed, want) {
+		t.Errorf("Walk(FollowSymlinks) errors at %v, want %v", failed, want)
+	}
+}
+
+// TestWalkRoots tests that files are visited once across overlapping roots
+func TestWalkRoots(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "a.bin"), "a")
+	writeFile(t, filepath.Join(root, "sub", "b.bin"), "bb")
+
+	w := &Walker{}
+	var got []string
+	for _, path := range []string{filepath.Join(root, "sub"), root, filepath.Join(root, "a.bin")} {
+		got = append(got, walkAll(t, w, path)...)
+	}
+	if want := []string{"b.bin", "a.bin"}; !slices.Equal(got, want) {
+		t.Errorf("Walk() = %v, want %v", got, want)
+	}
+
+	var errs int
+	w.Walk(filepath.Join(root, "missing"), func(string, error) { errs++ })
+	if errs != 1 {
+		t.Errorf("Walk(missing) reported %d errors, want 1", errs)
+	}
+}
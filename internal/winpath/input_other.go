@@ -0,0 +1,9 @@
+//go:build !windows
+
+package winpath
+
+// Input returns the path to open for an input file. Device names are only
+// reserved on Windows, so paths are returned unchanged.
+func Input(path string) string {
+	return path
+}
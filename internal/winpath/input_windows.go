@@ -0,0 +1,40 @@
+//go:build windows
+
+package winpath
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Input returns the path to open for an input file. Paths with a reserved
+// device name component (e.g. a source file named aux.c) are converted to
+// extended-length form so that the file is read instead of the device; other
+// paths are returned unchanged (long paths are handled by the os package).
+func Input(path string) string {
+	if !HasReserved(path) {
+		return path
+	}
+	abs, err := absolute(path)
+	if err != nil {
+		return path
+	}
+	return Extended(abs)
+}
+
+// absolute makes a path absolute without filepath.Abs, which resolves
+// reserved names to the device (\\.\NUL) instead of the file
+func absolute(path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return filepath.Clean(path), nil
+	}
+	if filepath.VolumeName(path) != "" {
+		// Drive-relative path (C:dir)
+		return filepath.Abs(path)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(wd, path), nil
+}
@@ -0,0 +1,103 @@
+// Package winpath handles the Windows path forms the standard library leaves
+// to callers: reserved device names (CON, NUL, COM1, ...), which open a device
+// instead of a file of that name, extended-length paths (\\?\C:\..., which
+// bypass device names and the MAX_PATH limit) and UNC shares (\\server\share).
+//
+// The functions working on path strings are available on every platform;
+// Input only changes paths on Windows.
+package winpath
+
+import "strings"
+
+// Path prefixes of extended-length paths
+const (
+	extendedPrefix    = `\\?\`
+	extendedUNCPrefix = `\\?\UNC\`
+)
+
+// reservedNames are the device names Windows resolves in any directory
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true,
+}
+
+// IsReserved reports whether a file name is a reserved device name. Like
+// Windows, it ignores case, an extension ("nul.txt"), a stream suffix
+// ("nul:") and trailing spaces ("NUL ").
+func IsReserved(name string) bool {
+	name, _, _ = strings.Cut(name, ".")
+	name, _, _ = strings.Cut(name, ":")
+	name = strings.ToUpper(strings.TrimRight(name, " "))
+	if reservedNames[name] {
+		return true
+	}
+
+	// COM0-COM9 and LPT0-LPT9, including the superscript digits ¹²³
+	for _, prefix := range []string{"COM", "LPT"} {
+		if digit, ok := strings.CutPrefix(name, prefix); ok {
+			switch digit {
+			case "0", "1", "2", "3", "4", "5", "6", "7", "8", "9", "¹", "²", "³":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// HasReserved reports whether any component of a path is a reserved device
+// name. Paths in extended-length form are not interpreted by Windows and never
+// report true.
+func HasReserved(path string) bool {
+	if strings.HasPrefix(path, extendedPrefix) {
+		return false
+	}
+	for component := range strings.FieldsFuncSeq(path, isSeparator) {
+		if IsReserved(component) {
+			return true
+		}
+	}
+	return false
+}
+
+// Extended converts an absolute Windows path to extended-length form:
+// C:\dir becomes \\?\C:\dir and \\server\share\dir becomes
+// \\?\UNC\server\share\dir. Forward slashes are converted, as extended paths
+// are passed to the file system as is. Paths already in extended or device
+// (\\.\) form are returned unchanged.
+func Extended(abs string) string {
+	abs = strings.ReplaceAll(abs, "/", `\`)
+	switch {
+	case strings.HasPrefix(abs, extendedPrefix), strings.HasPrefix(abs, `\\.\`):
+		return abs
+	case strings.HasPrefix(abs, `\\`):
+		return extendedUNCPrefix + abs[2:]
+	default:
+		return extendedPrefix + abs
+	}
+}
+
+// Short reverses Extended, returning the path in its usual form for display
+func Short(path string) string {
+	if rest, ok := strings.CutPrefix(path, extendedUNCPrefix); ok {
+		return `\\` + rest
+	}
+	return strings.TrimPrefix(path, extendedPrefix)
+}
+
+// SplitUNC splits a UNC path (\\server\share\dir, or its extended form) into
+// the server and the path on it (\share\dir). It reports false for other paths.
+func SplitUNC(path string) (host, rest string, ok bool) {
+	path = strings.ReplaceAll(Short(path), "/", `\`)
+	if !strings.HasPrefix(path, `\\`) || strings.HasPrefix(path, `\\.\`) || strings.HasPrefix(path, `\\?\`) {
+		return "", "", false
+	}
+	host, rest, _ = strings.Cut(path[2:], `\`)
+	if host == "" {
+		return "", "", false
+	}
+	return host, `\` + rest, true
+}
+
+// isSeparator reports whether r separates path components on Windows
+func isSeparator(r rune) bool {
+	return r == '\\' || r == '/'
+}
@@ -0,0 +1,106 @@
+package winpath
+
+import "testing"
+
+// TestIsReserved tests recognizing Windows device names
+func TestIsReserved(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"CON", true},
+		{"nul", true},
+		{"aux.c", true},
+		{"NUL.tar.gz", true},
+		{"prn:stream", true},
+		{"NUL ", true},
+		{"COM1", true},
+		{"lpt9.txt", true},
+		{"COM²", true},
+		{"CONIN$", true},
+		{"COM", false},
+		{"COM10", false},
+		{"console.log", false},
+		{"nully", false},
+		{"data.bin", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := IsReserved(tt.name); got != tt.want {
+			t.Errorf("IsReserved(%q) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// TestHasReserved tests finding device names in path components
+func TestHasReserved(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{`src\aux.c`, true},
+		{"src/con/main.go", true},
+		{`C:\firmware\nul`, true},
+		{`\\?\C:\src\aux.c`, false},
+		{`C:\src\auxiliary.c`, false},
+		{"firmware.bin", false},
+	}
+	for _, tt := range tests {
+		if got := HasReserved(tt.path); got != tt.want {
+			t.Errorf("HasReserved(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+// TestExtended tests converting paths to and from extended-length form
+func TestExtended(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{`C:\src\aux.c`, `\\?\C:\src\aux.c`},
+		{"C:/src/aux.c", `\\?\C:\src\aux.c`},
+		{`\\server\share\aux.c`, `\\?\UNC\server\share\aux.c`},
+		{`\\?\C:\src\aux.c`, `\\?\C:\src\aux.c`},
+		{`\\.\PhysicalDrive0`, `\\.\PhysicalDrive0`},
+	}
+	for _, tt := range tests {
+		if got := Extended(tt.path); got != tt.want {
+			t.Errorf("Extended(%q) = %q, want %q", tt.path, got, tt.want)
+		}
+	}
+
+	for extended, want := range map[string]string{
+		`\\?\C:\src\aux.c`:           `C:\src\aux.c`,
+		`\\?\UNC\server\share\aux.c`: `\\server\share\aux.c`,
+		`C:\src\main.c`:              `C:\src\main.c`,
+	} {
+		if got := Short(extended); got != want {
+			t.Errorf("Short(%q) = %q, want %q", extended, got, want)
+		}
+	}
+}
+
+// TestSplitUNC tests splitting UNC paths into server and path
+func TestSplitUNC(t *testing.T) {
+	tests := []struct {
+		path     string
+		wantHost string
+		wantRest string
+		wantOK   bool
+	}{
+		{`\\server\share\dir\a.bin`, "server", `\share\dir\a.bin`, true},
+		{"//server/share/a.bin", "server", `\share\a.bin`, true},
+		{`\\?\UNC\server\share\a.bin`, "server", `\share\a.bin`, true},
+		{`\\?\C:\a.bin`, "", "", false},
+		{`\\.\PhysicalDrive0`, "", "", false},
+		{`C:\a.bin`, "", "", false},
+		{"/home/a.bin", "", "", false},
+	}
+	for _, tt := range tests {
+		host, rest, ok := SplitUNC(tt.path)
+		if host != tt.wantHost || rest != tt.wantRest || ok != tt.wantOK {
+			t.Errorf("SplitUNC(%q) = %q, %q, %v, want %q, %q, %v", tt.path, host, rest, ok, tt.wantHost, tt.wantRest, tt.wantOK)
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Package xattr reads the extended attributes of files, such as the
+// com.apple.quarantine attribute macOS adds to downloads or the user.*
+// attributes browsers and tools leave on Linux (user.xdg.origin.url). They
+// often record where a file came from.
+package xattr
+
+import (
+	"bytes"
+	"errors"
+	"syscall"
+)
+
+// ErrUnsupported is returned by List on platforms without extended attributes
+var ErrUnsupported = errors.New("extended attributes are not supported on this platform")
+
+// Attr is an extended attribute of a file
+type Attr struct {
+	Name  string
+	Value []byte
+}
+
+// List returns the extended attributes of the file at path, in the order the
+// file system lists them. Attributes removed while they are read are skipped.
+func List(path string) ([]Attr, error) {
+	if !Supported {
+		return nil, ErrUnsupported
+	}
+	names, err := read(func(dest []byte) (int, error) { return listxattr(path, dest) })
+	if err != nil {
+		if errors.Is(err, syscall.ENOTSUP) {
+			return nil, nil // File system without extended attributes
+		}
+		return nil, err
+	}
+
+	var attrs []Attr
+	for _, name := range splitNames(names) {
+		value, err := read(func(dest []byte) (int, error) { return getxattr(path, name, dest) })
+		if err != nil {
+			if errors.Is(err, errNoAttr) {
+				continue
+			}
+			return attrs, err
+		}
+		attrs = append(attrs, Attr{Name: name, Value: value})
+	}
+	return attrs, nil
+}
+
+// read calls get with no buffer to learn the size of the result, then with a
+// buffer of that size, growing it while the result grows between the calls
+func read(get func(dest []byte) (int, error)) ([]byte, error) {
+	for {
+		size, err := get(nil)
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		buf := make([]byte, size)
+		n, err := get(buf)
+		if errors.Is(err, syscall.ERANGE) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return buf[:n], nil
+	}
+}
+
+// splitNames splits a list of NUL-terminated attribute names
+func splitNames(list []byte) []string {
+	var names []string
+	for name := range bytes.SplitSeq(list, []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names
+}
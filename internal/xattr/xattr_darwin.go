@@ -0,0 +1,50 @@
+//go:build darwin
+
+package xattr
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// Supported reports whether List is implemented on this platform
+const Supported = true
+
+// errNoAttr is ENOATTR, returned when an attribute does not exist
+const errNoAttr = syscall.Errno(93)
+
+func listxattr(path string, dest []byte) (int, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	n, _, errno := syscall.Syscall6(syscall.SYS_LISTXATTR, uintptr(unsafe.Pointer(p)), bufferPointer(dest), uintptr(len(dest)), 0, 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), nil
+}
+
+func getxattr(path, name string, dest []byte) (int, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	a, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return 0, err
+	}
+	n, _, errno := syscall.Syscall6(syscall.SYS_GETXATTR, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(a)), bufferPointer(dest), uintptr(len(dest)), 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), nil
+}
+
+// bufferPointer returns the address of dest, or 0 to ask for the size
+func bufferPointer(dest []byte) uintptr {
+	if len(dest) == 0 {
+		return 0
+	}
+	return uintptr(unsafe.Pointer(&dest[0]))
+}
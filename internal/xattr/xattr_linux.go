@@ -0,0 +1,19 @@
+//go:build linux
+
+package xattr
+
+import "syscall"
+
+// Supported reports whether List is implemented on this platform
+const Supported = true
+
+// errNoAttr is returned when an attribute does not exist
+const errNoAttr = syscall.ENODATA
+
+func listxattr(path string, dest []byte) (int, error) {
+	return syscall.Listxattr(path, dest)
+}
+
+func getxattr(path, name string, dest []byte) (int, error) {
+	return syscall.Getxattr(path, name, dest)
+}
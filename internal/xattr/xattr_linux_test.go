@@ -0,0 +1,50 @@
+//go:build linux
+
+package xattr
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+// TestList tests reading the attributes of a file
+func TestList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "download.zip")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if attrs, err := List(path); err != nil || len(attrs) != 0 {
+		t.Fatalf("List() without attributes = %v, %v", attrs, err)
+	}
+
+	url := []byte("https://example.com/download.zip")
+	large := bytes.Repeat([]byte("x"), 3000)
+	if err := syscall.Setxattr(path, "user.xdg.origin.url", url, 0); err != nil {
+		t.Skipf("file system without user attributes: %v", err)
+	}
+	if err := syscall.Setxattr(path, "user.large", large, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	attrs, err := List(path)
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	got := make(map[string][]byte)
+	for _, attr := range attrs {
+		if strings.HasPrefix(attr.Name, "user.") {
+			got[attr.Name] = attr.Value
+		}
+	}
+	if len(got) != 2 || !bytes.Equal(got["user.xdg.origin.url"], url) || !bytes.Equal(got["user.large"], large) {
+		t.Errorf("List() = %q, want the URL and the large attribute", got)
+	}
+
+	if _, err := List(filepath.Join(t.TempDir(), "missing")); err == nil {
+		t.Error("List() of a missing file succeeded")
+	}
+}
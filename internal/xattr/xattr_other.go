@@ -0,0 +1,19 @@
+//go:build !linux && !darwin
+
+package xattr
+
+import "syscall"
+
+// Supported reports whether List is implemented on this platform
+const Supported = false
+
+// errNoAttr is never returned: List reports ErrUnsupported
+const errNoAttr = syscall.Errno(0)
+
+func listxattr(string, []byte) (int, error) {
+	return 0, ErrUnsupported
+}
+
+func getxattr(string, string, []byte) (int, error) {
+	return 0, ErrUnsupported
+}
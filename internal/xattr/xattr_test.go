@@ -0,0 +1,24 @@
+package xattr
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestSplitNames tests splitting attribute name lists
+func TestSplitNames(t *testing.T) {
+	tests := []struct {
+		list string
+		want []string
+	}{
+		{"", nil},
+		{"user.a\x00", []string{"user.a"}},
+		{"com.apple.quarantine\x00user.xdg.origin.url\x00", []string{"com.apple.quarantine", "user.xdg.origin.url"}},
+		{"user.a\x00\x00user.b", []string{"user.a", "user.b"}},
+	}
+	for _, tt := range tests {
+		if got := splitNames([]byte(tt.list)); !slices.Equal(got, tt.want) {
+			t.Errorf("splitNames(%q) = %q, want %q", tt.list, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package txtr
+
+import (
+	"regexp"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+	"github.com/richardwooding/txtr/internal/noise"
+)
+
+// ScanOptions control which strings extraction finds
+type ScanOptions struct {
+	MinLength     int    // Minimum length in characters (default 4, like -n)
+	Encoding      string // Character encoding, as -e: s, S, b, l, B or L (default s)
+	Unicode       string // UTF-8 handling, as -U: default, invalid, locale, escape, hex or highlight
+	AllWhitespace bool   // Include all whitespace, as -w
+	Scripts       bool   // Keep embedded scripts together as multi-line strings
+}
+
+// OutputOptions control how strings are printed by sinks formatting them as
+// the txtr command does. Extract does not use them: such sinks read them
+// from the Options (see NewOptions).
+type OutputOptions struct {
+	FileNames bool   // Print the file name before each string, as -f
+	Radix     string // Print offsets in radix o, d or x, as -t ("" = none)
+	Separator string // Separator after each string (default newline)
+	Escape    bool   // C-style escapes for non-printable characters
+	MaxWidth  int    // Truncate strings to this many characters (0 = unlimited)
+}
+
+// FilterOptions control which of the strings found are emitted
+type FilterOptions struct {
+	Match         []*regexp.Regexp // Keep strings matching any of these, as -m
+	Exclude       []*regexp.Regexp // Drop strings matching any of these, as -M
+	FormatStrings bool             // Keep printf-style format strings only
+	Secrets       bool             // Keep likely credentials only, as --secrets
+	Common        bool             // Drop well-known runtime and library strings, as --filter-common
+}
+
+// Options are the options of extraction, set with Option functions
+type Options struct {
+	Scan   ScanOptions
+	Output OutputOptions
+	Filter FilterOptions
+}
+
+// Option sets options of extraction
+type Option func(*Options)
+
+// NewOptions returns the defaults of the txtr command (strings of 4 or more
+// 7-bit ASCII characters) changed by opts
+func NewOptions(opts ...Option) Options {
+	o := Options{Scan: ScanOptions{MinLength: 4, Encoding: "s"}}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// config returns the options as the configuration of the extractor the txtr
+// command uses
+func (o Options) config() extractor.Config {
+	config := extractor.Config{
+		MinLength:            o.Scan.MinLength,
+		Encoding:             o.Scan.Encoding,
+		Unicode:              o.Scan.Unicode,
+		IncludeAllWhitespace: o.Scan.AllWhitespace,
+		Scripts:              o.Scan.Scripts,
+		PrintFileName:        o.Output.FileNames,
+		PrintOffset:          o.Output.Radix != "",
+		Radix:                o.Output.Radix,
+		OutputSeparator:      o.Output.Separator,
+		EscapeNonPrint:       o.Output.Escape,
+		MaxWidth:             o.Output.MaxWidth,
+		MatchPatterns:        o.Filter.Match,
+		ExcludePatterns:      o.Filter.Exclude,
+		FormatStrings:        o.Filter.FormatStrings,
+		Secrets:              o.Filter.Secrets,
+	}
+	if o.Filter.Common {
+		config.CommonStrings = noise.Embedded()
+	}
+	return config
+}
+
+// WithScan replaces the scan options
+func WithScan(scan ScanOptions) Option {
+	return func(o *Options) { o.Scan = scan }
+}
+
+// WithOutput replaces the output options
+func WithOutput(output OutputOptions) Option {
+	return func(o *Options) { o.Output = output }
+}
+
+// WithFilter replaces the filter options
+func WithFilter(filter FilterOptions) Option {
+	return func(o *Options) { o.Filter = filter }
+}
+
+// WithMinLength sets the minimum length of strings in characters
+func WithMinLength(n int) Option {
+	return func(o *Options) { o.Scan.MinLength = n }
+}
+
+// WithEncoding sets the character encoding, as -e: s, S, b, l, B or L
+func WithEncoding(encoding string) Option {
+	return func(o *Options) { o.Scan.Encoding = encoding }
+}
+
+// WithUnicode sets the UTF-8 handling, as -U
+func WithUnicode(mode string) Option {
+	return func(o *Options) { o.Scan.Unicode = mode }
+}
+
+// WithAllWhitespace includes all whitespace in strings, as -w
+func WithAllWhitespace() Option {
+	return func(o *Options) { o.Scan.AllWhitespace = true }
+}
+
+// WithScripts keeps embedded scripts together as multi-line strings
+func WithScripts() Option {
+	return func(o *Options) { o.Scan.Scripts = true }
+}
+
+// WithFileNames prints the file name before each string, as -f
+func WithFileNames() Option {
+	return func(o *Options) { o.Output.FileNames = true }
+}
+
+// WithOffsets prints the offset of each string in radix o, d or x, as -t
+func WithOffsets(radix string) Option {
+	return func(o *Options) { o.Output.Radix = radix }
+}
+
+// WithSeparator sets the separator printed after each string
+func WithSeparator(separator string) Option {
+	return func(o *Options) { o.Output.Separator = separator }
+}
+
+// WithMatch keeps the strings matching any of patterns, as -m. Options
+// accumulate the patterns.
+func WithMatch(patterns ...*regexp.Regexp) Option {
+	return func(o *Options) { o.Filter.Match = append(o.Filter.Match, patterns...) }
+}
+
+// WithExclude drops the strings matching any of patterns, as -M. Options
+// accumulate the patterns.
+func WithExclude(patterns ...*regexp.Regexp) Option {
+	return func(o *Options) { o.Filter.Exclude = append(o.Filter.Exclude, patterns...) }
+}
+
+// WithFormatStrings keeps printf-style format strings only
+func WithFormatStrings() Option {
+	return func(o *Options) { o.Filter.FormatStrings = true }
+}
+
+// WithSecrets keeps the strings that look like credentials only, as --secrets
+// (see extractor.SecretRules)
+func WithSecrets() Option {
+	return func(o *Options) { o.Filter.Secrets = true }
+}
+
+// WithCommonFilter drops well-known runtime and library strings, as
+// --filter-common with the embedded database
+func WithCommonFilter() Option {
+	return func(o *Options) { o.Filter.Common = true }
+}
@@ -0,0 +1,58 @@
+package txtr
+
+import (
+	"context"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// TestNewOptions tests the defaults and the options of each group
+func TestNewOptions(t *testing.T) {
+	if o := NewOptions(); o.Scan.MinLength != 4 || o.Scan.Encoding != "s" {
+		t.Errorf("NewOptions() = %+v, want the defaults of the command", o)
+	}
+
+	digits, hex := regexp.MustCompile(`\d`), regexp.MustCompile(`^[0-9a-f]+$`)
+	config := NewOptions(
+		WithMinLength(8),
+		WithEncoding("l"),
+		WithOffsets("x"),
+		WithFileNames(),
+		WithMatch(digits),
+		WithExclude(hex),
+		WithExclude(digits),
+		WithCommonFilter(),
+	).config()
+
+	if config.MinLength != 8 || config.Encoding != "l" {
+		t.Errorf("MinLength, Encoding = %d, %q, want 8, l", config.MinLength, config.Encoding)
+	}
+	if !config.PrintOffset || config.Radix != "x" || !config.PrintFileName {
+		t.Errorf("PrintOffset, Radix, PrintFileName = %v, %q, %v", config.PrintOffset, config.Radix, config.PrintFileName)
+	}
+	if len(config.MatchPatterns) != 1 || len(config.ExcludePatterns) != 2 || config.CommonStrings == nil {
+		t.Errorf("filters = %v, %v, %v", config.MatchPatterns, config.ExcludePatterns, config.CommonStrings)
+	}
+}
+
+// TestWithGroups tests that a group option replaces the whole group
+func TestWithGroups(t *testing.T) {
+	o := NewOptions(WithMinLength(8), WithScan(ScanOptions{MinLength: 5, Encoding: "b"}), WithFilter(FilterOptions{FormatStrings: true}))
+	if o.Scan != (ScanOptions{MinLength: 5, Encoding: "b"}) || !o.Filter.FormatStrings {
+		t.Errorf("NewOptions() = %+v", o)
+	}
+}
+
+// TestExtractOptions tests extraction with filter options
+func TestExtractOptions(t *testing.T) {
+	sink := &collected{}
+	data := "version 1.2\x00ok\x00no digits here\x00"
+	if err := Extract(context.Background(), strings.NewReader(data), "", sink, WithMinLength(3), WithMatch(regexp.MustCompile(`\d`))); err != nil {
+		t.Fatal(err)
+	}
+	if got := sink.strings(); !slices.Equal(got, []string{"version 1.2"}) {
+		t.Errorf("strings = %q, want [\"version 1.2\"]", got)
+	}
+}
@@ -0,0 +1,151 @@
+package txtr
+
+import (
+	"bytes"
+	"regexp"
+
+	"github.com/richardwooding/txtr/internal/binary"
+	"github.com/richardwooding/txtr/internal/notify"
+	"github.com/richardwooding/txtr/internal/rank"
+)
+
+// Stage is a middleware of a pipeline: it returns a sink processing each
+// string before passing it (or not) to next. Closing the sink closes next.
+type Stage func(next Sink) Sink
+
+// Pipeline returns sink preceded by stages, the first of which receives the
+// strings first. Closing the pipeline closes sink.
+func Pipeline(sink Sink, stages ...Stage) Sink {
+	for i := len(stages) - 1; i >= 0; i-- {
+		sink = stages[i](sink)
+	}
+	return sink
+}
+
+// mapSink applies fn to each string, passing those it keeps to next
+type mapSink struct {
+	next Sink
+	fn   func(*Result) bool
+}
+
+func (ms *mapSink) Emit(r Result) error {
+	if !ms.fn(&r) {
+		return nil
+	}
+	return ms.next.Emit(r)
+}
+
+func (ms *mapSink) Close() error {
+	return ms.next.Close()
+}
+
+// Map returns a stage applying fn to each string, which may annotate or
+// rewrite it, and dropping those fn returns false for. A rewritten Str must
+// not alias the original, which is only valid until Emit returns.
+func Map(fn func(*Result) bool) Stage {
+	return func(next Sink) Sink {
+		return &mapSink{next: next, fn: fn}
+	}
+}
+
+// Filter returns a stage keeping the strings keep returns true for
+func Filter(keep func(Result) bool) Stage {
+	return Map(func(r *Result) bool {
+		return keep(*r)
+	})
+}
+
+// Match returns a stage keeping the strings matching any of patterns, like
+// the -m option
+func Match(patterns ...*regexp.Regexp) Stage {
+	return Filter(func(r Result) bool {
+		return matchAny(patterns, r.Str)
+	})
+}
+
+// Exclude returns a stage dropping the strings matching any of patterns,
+// like the -M option
+func Exclude(patterns ...*regexp.Regexp) Stage {
+	return Filter(func(r Result) bool {
+		return !matchAny(patterns, r.Str)
+	})
+}
+
+// matchAny reports whether str matches any of patterns
+func matchAny(patterns []*regexp.Regexp, str []byte) bool {
+	for _, pattern := range patterns {
+		if pattern.Match(str) {
+			return true
+		}
+	}
+	return false
+}
+
+// dedupeSink emits the first occurrence of each string of a file to next
+type dedupeSink struct {
+	next Sink
+	file string
+	seen map[string]struct{}
+}
+
+func (ds *dedupeSink) Emit(r Result) error {
+	if r.File != ds.file {
+		ds.file = r.File
+		clear(ds.seen)
+	}
+	if _, ok := ds.seen[string(r.Str)]; ok {
+		return nil
+	}
+	ds.seen[string(r.Str)] = struct{}{}
+	return ds.next.Emit(r)
+}
+
+func (ds *dedupeSink) Close() error {
+	return ds.next.Close()
+}
+
+// Dedupe returns a stage keeping the first occurrence of each distinct
+// string of a file, like --unique. The strings of a file must be emitted
+// together.
+func Dedupe() Stage {
+	return func(next Sink) Sink {
+		return &dedupeSink{next: next, seen: make(map[string]struct{})}
+	}
+}
+
+// Tag returns a stage setting the Tags of each string to the artifact
+// categories it belongs to, e.g. "url" or "secret" (see --top)
+func Tag() Stage {
+	return Map(func(r *Result) bool {
+		r.Tags = rank.Categories(r.Str)
+		return true
+	})
+}
+
+// Entropy returns a stage setting the Entropy of each string, and dropping
+// those above maxEntropy bits per byte, which look random rather than
+// readable (0 = keep all)
+func Entropy(maxEntropy float64) Stage {
+	return Map(func(r *Result) bool {
+		r.Entropy = binary.Entropy(r.Str)
+		return maxEntropy <= 0 || r.Entropy <= maxEntropy
+	})
+}
+
+// Redact returns a stage masking the key material and credentials in
+// strings (see --notify-findings), keeping their first characters only
+func Redact() Stage {
+	return Map(func(r *Result) bool {
+		if !rank.IsSecret(r.Str) {
+			return true
+		}
+		str := bytes.Clone(r.Str)
+		for _, artifact := range rank.Extract(r.Str) {
+			if artifact.Kind == "secret" {
+				str = bytes.ReplaceAll(str, []byte(artifact.Value), []byte(notify.Mask(artifact.Value)))
+			}
+		}
+		r.Str = str
+		return true
+	})
+}
@@ -0,0 +1,100 @@
+// Package txtr is the library API of txtr: it extracts the printable strings
+// of binary data, like the txtr command, and feeds them to sinks through
+// pipelines of composable stages (filtering, deduplication, artifact tagging,
+// entropy scoring and redaction).
+//
+//	sink := txtr.Pipeline(printer, txtr.Exclude(noise), txtr.Dedupe(), txtr.Tag(), txtr.Redact())
+//	err := txtr.Extract(ctx, file, "app.exe", sink, txtr.WithMinLength(6), txtr.WithEncoding("l"))
+package txtr
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/richardwooding/txtr/internal/extractor"
+)
+
+// Result is a string found by extraction, with the annotations of the
+// stages it went through
+type Result struct {
+	Str    []byte // The string, valid only until Emit returns
+	File   string // File the string was found in ("" for standard input)
+	Offset int64  // Offset of the string in the file
+
+	// Annotations of pipeline stages, zero unless a stage set them
+	Tags    []string // Artifact categories of the string, e.g. "url"
+	Entropy float64  // Shannon entropy of the string in bits per byte
+}
+
+// Sink receives the strings found by extraction. An Emit error stops the
+// extraction feeding it. Emit blocks extraction until it returns, so a slow
+// sink slows extraction down rather than buffering strings.
+type Sink interface {
+	Emit(Result) error
+	Close() error
+}
+
+// ReadError is a read error that cut extraction short
+type ReadError = extractor.ReadError
+
+// PrintFunc adapts a func(str, filename, offset) callback to a Sink that
+// never fails. Its Close does nothing.
+type PrintFunc func(str []byte, filename string, offset int64)
+
+// Emit calls f with the result
+func (f PrintFunc) Emit(r Result) error {
+	f(r.Str, r.File, r.Offset)
+	return nil
+}
+
+// Close implements Sink
+func (f PrintFunc) Close() error {
+	return nil
+}
+
+// extractorSink adapts a Sink to the sinks of the extractor
+type extractorSink struct {
+	sink Sink
+}
+
+func (es extractorSink) Emit(r extractor.Result) error {
+	return es.sink.Emit(Result{Str: r.Str, File: r.File, Offset: r.Offset})
+}
+
+func (es extractorSink) Close() error {
+	return es.sink.Close()
+}
+
+// Extract reads from reader and emits its strings to sink, with the defaults
+// of the txtr command changed by opts (see NewOptions). It returns a *ReadError if
+// reading fails, the first Emit error, or the cause of the cancellation of
+// ctx. The sink is not closed.
+func Extract(ctx context.Context, reader io.Reader, filename string, sink Sink, opts ...Option) error {
+	return extractor.ExtractToSink(ctx, reader, filename, NewOptions(opts...).config(), extractorSink{sink})
+}
+
+// teeSink emits each string to every sink
+type teeSink []Sink
+
+// Tee returns a sink emitting each string to all of sinks. Emit and Close
+// reach every sink even if one fails, and return the errors joined.
+func Tee(sinks ...Sink) Sink {
+	return teeSink(sinks)
+}
+
+func (ts teeSink) Emit(r Result) error {
+	var errs []error
+	for _, s := range ts {
+		errs = append(errs, s.Emit(r))
+	}
+	return errors.Join(errs...)
+}
+
+func (ts teeSink) Close() error {
+	var errs []error
+	for _, s := range ts {
+		errs = append(errs, s.Close())
+	}
+	return errors.Join(errs...)
+}